@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,13 +12,23 @@ import (
 	"github.com/Z3DRP/lessor-service/internal/crane"
 	"github.com/Z3DRP/lessor-service/internal/dac"
 	"github.com/Z3DRP/lessor-service/internal/factories"
+	"github.com/Z3DRP/lessor-service/internal/jobs"
 	"github.com/Z3DRP/lessor-service/internal/routes"
 	"github.com/Z3DRP/lessor-service/internal/services/alssr"
+	"github.com/Z3DRP/lessor-service/internal/services/hooks"
+	"github.com/Z3DRP/lessor-service/internal/services/lease"
+	"github.com/Z3DRP/lessor-service/internal/services/ledger"
+	"github.com/Z3DRP/lessor-service/internal/services/maintenance"
 	"github.com/Z3DRP/lessor-service/internal/services/notification"
+	"github.com/Z3DRP/lessor-service/internal/services/payroll"
 	"github.com/Z3DRP/lessor-service/internal/services/prfl"
 	"github.com/Z3DRP/lessor-service/internal/services/property"
 	rentalproperty "github.com/Z3DRP/lessor-service/internal/services/rentalProperty"
+	"github.com/Z3DRP/lessor-service/internal/services/schedule"
 	"github.com/Z3DRP/lessor-service/internal/services/task"
+	"github.com/Z3DRP/lessor-service/internal/services/tenant"
+	"github.com/Z3DRP/lessor-service/internal/services/texts"
+	"github.com/Z3DRP/lessor-service/internal/services/timesheet"
 	"github.com/Z3DRP/lessor-service/internal/services/usr"
 	"github.com/Z3DRP/lessor-service/internal/services/worker"
 	"github.com/joho/godotenv"
@@ -108,6 +119,84 @@ func run() error {
 		return factories.ErrFailedServiceStart{ServiceName: notificationService.ServiceName(), Err: err}
 	}
 
+	scheduleService, _ := factories.ServiceFactory("Schedule", dbStore, crane.DefaultLogger)
+	scheduleHandler, err := factories.HandlerFactory(scheduleService.ServiceName(), scheduleService)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: scheduleService.ServiceName(), Err: err}
+	}
+
+	ledgerService, _ := factories.ServiceFactory("Ledger", dbStore, crane.DefaultLogger)
+	ledgerHandler, err := factories.HandlerFactory(ledgerService.ServiceName(), ledgerService)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: ledgerService.ServiceName(), Err: err}
+	}
+
+	tenantService, _ := factories.ServiceFactory("Tenant", dbStore, crane.DefaultLogger)
+	tenantHandler, err := factories.HandlerFactory(tenantService.ServiceName(), tenantService)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: tenantService.ServiceName(), Err: err}
+	}
+
+	maintenanceService, err := factories.ServiceFactory("Maintenance", dbStore, crane.DefaultLogger)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: "Maintenance", Err: err}
+	}
+
+	maintenanceHandler, err := factories.HandlerFactory(maintenanceService.ServiceName(), maintenanceService)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: maintenanceService.ServiceName(), Err: err}
+	}
+
+	timesheetService, err := factories.ServiceFactory("Timesheet", dbStore, crane.DefaultLogger)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: "Timesheet", Err: err}
+	}
+
+	timesheetHandler, err := factories.HandlerFactory(timesheetService.ServiceName(), timesheetService)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: timesheetService.ServiceName(), Err: err}
+	}
+
+	payrollService, err := factories.ServiceFactory("Payroll", dbStore, crane.DefaultLogger)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: "Payroll", Err: err}
+	}
+
+	payrollHandler, err := factories.HandlerFactory(payrollService.ServiceName(), payrollService)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: payrollService.ServiceName(), Err: err}
+	}
+
+	smsService, err := factories.ServiceFactory("Sms", dbStore, crane.DefaultLogger)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: "Sms", Err: err}
+	}
+
+	smsHandler, err := factories.HandlerFactory(smsService.ServiceName(), smsService)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: smsService.ServiceName(), Err: err}
+	}
+
+	webhookService, err := factories.ServiceFactory("Webhook", dbStore, crane.DefaultLogger)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: "Webhook", Err: err}
+	}
+
+	webhookHandler, err := factories.HandlerFactory(webhookService.ServiceName(), webhookService)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: webhookService.ServiceName(), Err: err}
+	}
+
+	leaseService, err := factories.ServiceFactory("Lease", dbStore, crane.DefaultLogger)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: "Lease", Err: err}
+	}
+
+	leaseHandler, err := factories.HandlerFactory(leaseService.ServiceName(), leaseService)
+	if err != nil {
+		return factories.ErrFailedServiceStart{ServiceName: leaseService.ServiceName(), Err: err}
+	}
+
 	aHandler, ok := alsrHandler.(alssr.AlessorHandler)
 	if !ok {
 		return cmerr.ErrUnexpectedData{Wanted: alssr.AlessorHandler{}, Got: alsrHandler}
@@ -143,12 +232,74 @@ func run() error {
 		return cmerr.ErrUnexpectedData{Wanted: notification.NotificationHandler{}, Got: notificationHandler}
 	}
 
-	zserver, err := routes.NewServer(&apiConfig.ZServer, aHandler, uHandler, pHandler, tHandler, rpHandler, wHandler, nHandler)
+	sHandler, ok := scheduleHandler.(schedule.ScheduleHandler)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: schedule.ScheduleHandler{}, Got: scheduleHandler}
+	}
+
+	lHandler, ok := ledgerHandler.(ledger.LedgerHandler)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: ledger.LedgerHandler{}, Got: ledgerHandler}
+	}
+
+	tnHandler, ok := tenantHandler.(tenant.TenantHandler)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: tenant.TenantHandler{}, Got: tenantHandler}
+	}
+
+	mHandler, ok := maintenanceHandler.(maintenance.MaintenanceHandler)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: maintenance.MaintenanceHandler{}, Got: maintenanceHandler}
+	}
+
+	tsHandler, ok := timesheetHandler.(timesheet.TimesheetHandler)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: timesheet.TimesheetHandler{}, Got: timesheetHandler}
+	}
+
+	prHandler, ok := payrollHandler.(payroll.PayrollHandler)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: payroll.PayrollHandler{}, Got: payrollHandler}
+	}
+
+	smHandler, ok := smsHandler.(texts.SmsHandler)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: texts.SmsHandler{}, Got: smsHandler}
+	}
+
+	whHandler, ok := webhookHandler.(hooks.WebhookHandler)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: hooks.WebhookHandler{}, Got: webhookHandler}
+	}
+
+	lsHandler, ok := leaseHandler.(lease.LeaseHandler)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: lease.LeaseHandler{}, Got: leaseHandler}
+	}
+
+	zserver, err := routes.NewServer(&apiConfig.ZServer, aHandler, uHandler, pHandler, tHandler, rpHandler, wHandler, nHandler, sHandler, lHandler, tnHandler, mHandler, tsHandler, prHandler, smHandler, whHandler, lsHandler)
 	if err != nil {
 		crane.DefaultLogger.MustDebug(fmt.Sprintf("fatal error creating server, %v", err))
 		return err
 	}
 
+	log.Printf("starting background jobs...")
+	runner := jobs.NewRunner(dac.InitJobRepo(dbStore), apiConfig.Jobs, crane.DefaultLogger)
+	if err = registerJobs(runner, apiConfig, nHandler.NotificationService, sHandler.ScheduleService, lHandler.LedgerService, whHandler.WebhookService, lsHandler.LeaseService); err != nil {
+		return err
+	}
+
+	if err = runner.Start(context.Background()); err != nil {
+		crane.DefaultLogger.MustDebug(fmt.Sprintf("fatal error starting background jobs, %v", err))
+		return err
+	}
+
+	shutdown := make(chan struct{})
+	go func() {
+		routes.HandleShutdown(zserver, runner, factories.Outbox(dbStore, crane.DefaultLogger), factories.Stream(dbStore, crane.DefaultLogger))
+		close(shutdown)
+	}()
+
 	log.Println("finished...")
 	crane.DefaultLogger.MustDebug("server is live and running")
 	log.Println("Server is live and running on 8087")
@@ -158,10 +309,53 @@ func run() error {
 		return err
 	}
 
-	routes.HandleShutdown(zserver)
+	<-shutdown
 	return nil
 }
 
+func registerJobs(
+	runner *jobs.Runner,
+	cfg *config.Configurations,
+	notifications notification.NotificationService,
+	schedules schedule.ScheduleService,
+	ledgers ledger.LedgerService,
+	webhooks hooks.WebhookService,
+	leases lease.LeaseService,
+) error {
+	if err := runner.Register("notification-expiry-sweep", "@hourly", notifications.PurgeExpired); err != nil {
+		return err
+	}
+
+	if err := runner.Register("notification-digests", "@hourly", notifications.SendDigests); err != nil {
+		return err
+	}
+
+	// leases are settled before rent so a renewal starting today is charged at its own rent
+	if err := runner.Register("lease-renewals", "5 0 * * *", leases.RunRenewals); err != nil {
+		return err
+	}
+
+	// late fees run after rent so a period charged today is never assessed the same night
+	if err := runner.Register("generate-rent-charges", "15 0 * * *", ledgers.RunRentCharges); err != nil {
+		return err
+	}
+
+	if err := runner.Register("assess-late-fees", "30 0 * * *", ledgers.RunLateFees); err != nil {
+		return err
+	}
+
+	if err := runner.Register("reconcile-processor-payments", "*/15 * * * *", ledgers.ReconcilePending); err != nil {
+		return err
+	}
+
+	if err := runner.Register("send-webhook-deliveries", "* * * * *", webhooks.SendDue); err != nil {
+		return err
+	}
+
+	generator := schedule.NewGenerator(schedules, cfg.Scheduler)
+	return runner.Register("generate-scheduled-tasks", generator.Spec(), generator.Run)
+}
+
 func main() {
 	// load .env file this is not the same as structure config loaded in run
 	if err := godotenv.Load(); err != nil {
@@ -33,12 +33,14 @@ func NewConfigReadError(configObj string, e error) *ErrConfigRead {
 }
 
 type Configurations struct {
-	ZServer        ZServerConfig `mapstructure:"zserver"`
-	DatabaseStore  DbConfig      `mapstructure:"database"`
-	ZypherSettings ZypherConfig  `mapstructure:"zysettings"`
-	ZEmailSettings ZEmailConfig  `mapstructure:"zemailsettings"`
-	AuthKey        string        `mapstructure:"authkey"`
-	Salty          string        `mapstructure:"salty"`
+	ZServer        ZServerConfig   `mapstructure:"zserver"`
+	DatabaseStore  DbConfig        `mapstructure:"database"`
+	ZypherSettings ZypherConfig    `mapstructure:"zysettings"`
+	ZEmailSettings ZEmailConfig    `mapstructure:"zemailsettings"`
+	Scheduler      SchedulerConfig `mapstructure:"scheduler"`
+	Jobs           JobsConfig      `mapstructure:"jobs"`
+	AuthKey        string          `mapstructure:"authkey"`
+	Salty          string          `mapstructure:"salty"`
 }
 
 type ZServerConfig struct {
@@ -82,6 +84,20 @@ type ZEmailConfig struct {
 	SmtpPort        int    `mapstructure:"smtpPort"`
 }
 
+// SchedulerConfig controls how far ahead recurring tasks are generated, interval is in
+// minutes and horizon is in days
+type SchedulerConfig struct {
+	GenerateInterval int `mapstructure:"generateInterval"`
+	HorizonDays      int `mapstructure:"horizonDays"`
+}
+
+// JobsConfig tunes the background job runner, poll interval and lease are in seconds
+type JobsConfig struct {
+	PollInterval int `mapstructure:"pollInterval"`
+	LeaseSeconds int `mapstructure:"leaseSeconds"`
+	MaxAttempts  int `mapstructure:"maxAttempts"`
+}
+
 func ReadConfig(configPath string) (*Configurations, error) {
 	viper.SetConfigType("yaml")
 	viper.SetConfigName("config")
@@ -0,0 +1,25 @@
+package adapters
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+)
+
+func ParseMaintenanceForm(r *http.Request) (*dtos.MaintenanceRequestDto, error) {
+	req := &dtos.MaintenanceRequestDto{
+		Description:       r.FormValue("description"),
+		Urgency:           r.FormValue("urgency"),
+		PermissionToEnter: utils.ParseBool(r.FormValue("permissionToEnter")),
+	}
+
+	if windows := r.FormValue("accessWindows"); windows != "" {
+		if err := json.Unmarshal([]byte(windows), &req.AccessWindows); err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+type Role string
+
+const (
+	Alessor Role = "alessor"
+	Worker  Role = "worker"
+	Tenant  Role = "tenant"
+	Admin   Role = "admin"
+)
+
+type claimsKey struct{}
+
+var ErrNoClaims = errors.New("request does not have user claims")
+
+func WithClaims(ctx context.Context, claims *UserClaims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+func ClaimsFromContext(ctx context.Context) (*UserClaims, error) {
+	claims, ok := ctx.Value(claimsKey{}).(*UserClaims)
+	if !ok || claims == nil {
+		return nil, ErrNoClaims
+	}
+
+	return claims, nil
+}
+
+func (u UserClaims) HasRole(roles ...Role) bool {
+	for _, r := range roles {
+		if Role(u.Role) == r {
+			return true
+		}
+	}
+	return false
+}
+
+// WithSystem is for background work that runs outside of a request, the admin role
+// gives it an unrestricted repository scope
+func WithSystem(ctx context.Context) context.Context {
+	return WithClaims(ctx, &UserClaims{Username: "system", Role: string(Admin)})
+}
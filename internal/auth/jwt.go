@@ -1,12 +1,18 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/Z3DRP/lessor-service/config"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 var (
@@ -17,6 +23,10 @@ var (
 
 var Expirey = time.Now().Add((24 * time.Hour) * 365)
 
+const RefreshTokenTTL = (24 * time.Hour) * 30
+
+const InviteTokenTTL = (24 * time.Hour) * 7
+
 func GetJwtKey() ([]byte, error) {
 	return getKey()
 }
@@ -30,6 +40,7 @@ type UserClaims struct {
 	Id       string `json:"id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	LessorId string `json:"lessorId,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -41,7 +52,19 @@ func (e ErrExpiredToken) Error() string {
 	return fmt.Sprintf("invalid token expired at %v", e.ExpireyDate)
 }
 
-func GenerateToken(id string, username string, role string) (string, error) {
+type ErrInvalidToken struct {
+	Err error
+}
+
+func (e ErrInvalidToken) Error() string {
+	return fmt.Sprintf("invalid token: %v", e.Err)
+}
+
+func (e ErrInvalidToken) Unwrap() error {
+	return e.Err
+}
+
+func GenerateToken(id string, username string, role string, lessorId string) (string, error) {
 	expirationTime := time.Now().Add(2 * time.Hour)
 	//devTime := time.Now().Add((24 * time.Hour) * 365)
 
@@ -49,6 +72,7 @@ func GenerateToken(id string, username string, role string) (string, error) {
 		Id:       id,
 		Username: username,
 		Role:     role,
+		LessorId: lessorId,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
@@ -80,29 +104,90 @@ func NewRefreshToken(claims jwt.RegisteredClaims) (string, error) {
 	return refreshToken.SignedString([]byte(key))
 }
 
-func ParseAuthToken(accessToken string) *UserClaims {
-	parsedAccessToken, _ := jwt.ParseWithClaims(accessToken, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
-		key, err := getKey()
-		if err != nil {
-			return nil, err
-		}
-		return []byte(key), nil
+// GenerateRefreshToken issues a refresh token for the user, each token gets its own id
+// so no two tokens hash the same
+func GenerateRefreshToken(uid string) (string, time.Time, error) {
+	jti, err := uuid.NewRandom()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(RefreshTokenTTL)
+	token, err := NewRefreshToken(jwt.RegisteredClaims{
+		ID:        jti.String(),
+		Subject:   uid,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
 	})
 
-	return parsedAccessToken.Claims.(*UserClaims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// GenerateInviteToken makes the one time token an invited user signs up with, it isn't a
+// jwt since the only thing it carries is the hash it's looked up by
+func GenerateInviteToken() (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), time.Now().Add(InviteTokenTTL), nil
 }
 
-func ParseRefreshToken(refreshToken string) *jwt.RegisteredClaims {
-	parsedRefreshToken, _ := jwt.ParseWithClaims(refreshToken, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+// HashToken is used to store and look up invite and refresh tokens, they are random
+// enough that a plain sha256 is safe to index on
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func ParseAuthToken(accessToken string) (*UserClaims, error) {
+	claims := &UserClaims{}
+	parsedAccessToken, err := jwt.ParseWithClaims(accessToken, claims, keyFor(jwt.SigningMethodHS512))
+	if err != nil {
+		return nil, ErrInvalidToken{Err: err}
+	}
+
+	if !parsedAccessToken.Valid || claims.Id == "" {
+		return nil, ErrInvalidToken{Err: errors.New("token is missing user claims")}
+	}
+
+	return claims, nil
+}
+
+func ParseRefreshToken(refreshToken string) (*jwt.RegisteredClaims, error) {
+	claims := &jwt.RegisteredClaims{}
+	parsedRefreshToken, err := jwt.ParseWithClaims(refreshToken, claims, keyFor(jwt.SigningMethodHS256))
+	if err != nil {
+		return nil, ErrInvalidToken{Err: err}
+	}
+
+	if !parsedRefreshToken.Valid || claims.Subject == "" {
+		return nil, ErrInvalidToken{Err: errors.New("token is missing subject")}
+	}
+
+	return claims, nil
+}
+
+// keyFor only hands out the key when the token was signed with the expected method,
+// which keeps refresh tokens from being accepted as access tokens and vice versa
+func keyFor(method jwt.SigningMethod) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+
 		key, err := getKey()
 		if err != nil {
 			return nil, err
 		}
-
 		return []byte(key), nil
-	})
-
-	return parsedRefreshToken.Claims.(*jwt.RegisteredClaims)
+	}
 }
 
 func getKey() ([]byte, error) {
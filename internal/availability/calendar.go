@@ -0,0 +1,302 @@
+package availability
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+type ConflictReason string
+
+const (
+	Booked      ConflictReason = "booked"
+	OnTimeOff   ConflictReason = "time_off"
+	OffHours    ConflictReason = "outside_hours"
+	NotEmployed ConflictReason = "not_employed"
+)
+
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (s Slot) overlaps(o Slot) bool {
+	return s.Start.Before(o.End) && o.Start.Before(s.End)
+}
+
+// Conflict is one reason a worker can't take a task at the asked for time, booked
+// conflicts name the task that is in the way
+type Conflict struct {
+	WorkerId string         `json:"workerId"`
+	Reason   ConflictReason `json:"reason"`
+	TaskId   string         `json:"taskId,omitempty"`
+	TaskName string         `json:"taskName,omitempty"`
+	Start    time.Time      `json:"start"`
+	End      time.Time      `json:"end"`
+}
+
+type ErrScheduleConflict struct {
+	Conflicts []Conflict
+}
+
+func (e ErrScheduleConflict) Error() string {
+	return fmt.Sprintf("worker is not available at the scheduled time, %v conflicts found", len(e.Conflicts))
+}
+
+type booking struct {
+	Slot
+	taskId uuid.UUID
+	name   string
+}
+
+// Calendar is a worker's hours, time off and booked tasks. A worker without any hours set
+// is treated as available around the clock so existing workers aren't suddenly unbookable
+type Calendar struct {
+	WorkerId  uuid.UUID
+	Location  *time.Location
+	Hours     []model.WorkingHours
+	TimeOff   []Slot
+	StartDate time.Time
+	EndDate   time.Time
+	bookings  []booking
+}
+
+func NewCalendar(wc dac.WorkerCalendar) Calendar {
+	loc, err := time.LoadLocation(wc.Worker.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	cal := Calendar{
+		WorkerId:  wc.Worker.Uid,
+		Location:  loc,
+		Hours:     wc.Hours,
+		TimeOff:   make([]Slot, 0, len(wc.TimeOff)),
+		StartDate: wc.Worker.StartDate,
+		EndDate:   wc.Worker.EndDate,
+		bookings:  make([]booking, 0, len(wc.Tasks)),
+	}
+
+	for _, off := range wc.TimeOff {
+		cal.TimeOff = append(cal.TimeOff, Slot{Start: off.StartAt, End: off.EndAt})
+	}
+
+	for _, tk := range wc.Tasks {
+		start, end := tk.Window()
+		cal.bookings = append(cal.bookings, booking{Slot: Slot{Start: start, End: end}, taskId: tk.Tid, name: tk.Name})
+	}
+
+	return cal
+}
+
+// Booked returns the slots taken up by the worker's tasks
+func (c Calendar) Booked() []Slot {
+	slots := make([]Slot, 0, len(c.bookings))
+	for _, b := range c.bookings {
+		slots = append(slots, b.Slot)
+	}
+	return slots
+}
+
+// Conflicts lists everything stopping the worker from working between start and end
+func (c Calendar) Conflicts(start time.Time, end time.Time) []Conflict {
+	want := Slot{Start: start, End: end}
+	conflicts := make([]Conflict, 0)
+	conflict := func(reason ConflictReason, s Slot) Conflict {
+		return Conflict{WorkerId: c.WorkerId.String(), Reason: reason, Start: s.Start, End: s.End}
+	}
+
+	if (!c.StartDate.IsZero() && start.Before(c.StartDate)) || (!c.EndDate.IsZero() && end.After(c.EndDate)) {
+		conflicts = append(conflicts, conflict(NotEmployed, want))
+	}
+
+	for _, off := range c.TimeOff {
+		if off.overlaps(want) {
+			conflicts = append(conflicts, conflict(OnTimeOff, off))
+		}
+	}
+
+	if len(c.Hours) > 0 {
+		outside := []Slot{want}
+		for _, working := range c.working(start, end) {
+			outside = subtract(outside, working)
+		}
+		for _, s := range outside {
+			conflicts = append(conflicts, conflict(OffHours, s))
+		}
+	}
+
+	for _, b := range c.bookings {
+		if b.overlaps(want) {
+			booked := conflict(Booked, b.Slot)
+			booked.TaskId = b.taskId.String()
+			booked.TaskName = b.name
+			conflicts = append(conflicts, booked)
+		}
+	}
+
+	return conflicts
+}
+
+// Free returns the open slots between from and to, oldest first
+func (c Calendar) Free(from time.Time, to time.Time) []Slot {
+	if !c.StartDate.IsZero() && from.Before(c.StartDate) {
+		from = c.StartDate
+	}
+	if !c.EndDate.IsZero() && to.After(c.EndDate) {
+		to = c.EndDate
+	}
+	if !from.Before(to) {
+		return []Slot{}
+	}
+
+	free := []Slot{{Start: from, End: to}}
+	if len(c.Hours) > 0 {
+		free = c.working(from, to)
+	}
+
+	for _, off := range c.TimeOff {
+		free = subtract(free, off)
+	}
+
+	for _, b := range c.bookings {
+		free = subtract(free, b.Slot)
+	}
+
+	return free
+}
+
+// working expands the weekly hours into the slots that fall between from and to, the day
+// before from is included so hours that started the previous day are not lost
+func (c Calendar) working(from time.Time, to time.Time) []Slot {
+	slots := make([]Slot, 0)
+	local := from.In(c.Location)
+	day := time.Date(local.Year(), local.Month(), local.Day()-1, 0, 0, 0, 0, c.Location)
+
+	for ; day.Before(to); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, c.Location) {
+		for _, h := range c.Hours {
+			if h.Weekday != day.Weekday() {
+				continue
+			}
+
+			s := Slot{
+				Start: time.Date(day.Year(), day.Month(), day.Day(), 0, h.StartMinute, 0, 0, c.Location),
+				End:   time.Date(day.Year(), day.Month(), day.Day(), 0, h.EndMinute, 0, 0, c.Location),
+			}
+			if s.Start.Before(from) {
+				s.Start = from
+			}
+			if s.End.After(to) {
+				s.End = to
+			}
+			if s.Start.Before(s.End) {
+				slots = append(slots, s)
+			}
+		}
+	}
+
+	return merge(slots)
+}
+
+// Check looks up each worker's calendar around the task and returns ErrScheduleConflict
+// when any of them can't take it, unscheduled tasks never conflict
+func Check(ctx context.Context, repo dac.AvailabilityRepo, tk model.Task, workerIds ...uuid.UUID) error {
+	if tk.ScheduledAt.IsZero() {
+		return nil
+	}
+
+	start, end := tk.Window()
+	conflicts := make([]Conflict, 0)
+	for _, wid := range workerIds {
+		if wid == uuid.Nil {
+			continue
+		}
+
+		wc, err := repo.FetchCalendar(ctx, wid, start, end, tk.Tid)
+		if err != nil {
+			return err
+		}
+		conflicts = append(conflicts, NewCalendar(wc).Conflicts(start, end)...)
+	}
+
+	if len(conflicts) > 0 {
+		return ErrScheduleConflict{Conflicts: conflicts}
+	}
+
+	return nil
+}
+
+// CheckReschedule checks the changes about to be written to the current task, a new time or
+// duration is checked against the whole crew and a new lead on its own
+func CheckReschedule(ctx context.Context, repo dac.AvailabilityRepo, current model.Task, changes model.Task) error {
+	merged := current
+	if !changes.ScheduledAt.IsZero() {
+		merged.ScheduledAt = changes.ScheduledAt
+	}
+	if changes.EstimatedMinutes != 0 {
+		merged.EstimatedMinutes = changes.EstimatedMinutes
+	}
+	if changes.WorkerId != uuid.Nil {
+		merged.WorkerId = changes.WorkerId
+	}
+
+	moved := !merged.ScheduledAt.Equal(current.ScheduledAt) || merged.EstimatedMinutes != current.EstimatedMinutes
+	if !moved {
+		if merged.WorkerId == current.WorkerId {
+			return nil
+		}
+		return Check(ctx, repo, merged, merged.WorkerId)
+	}
+
+	workerIds := []uuid.UUID{merged.WorkerId}
+	for _, at := range current.Assignments {
+		if at.Status != model.AssignmentDeclined && at.WorkerId != merged.WorkerId {
+			workerIds = append(workerIds, at.WorkerId)
+		}
+	}
+
+	return Check(ctx, repo, merged, workerIds...)
+}
+
+func subtract(slots []Slot, cut Slot) []Slot {
+	rest := make([]Slot, 0, len(slots))
+	for _, s := range slots {
+		if !s.overlaps(cut) {
+			rest = append(rest, s)
+			continue
+		}
+		if s.Start.Before(cut.Start) {
+			rest = append(rest, Slot{Start: s.Start, End: cut.Start})
+		}
+		if cut.End.Before(s.End) {
+			rest = append(rest, Slot{Start: cut.End, End: s.End})
+		}
+	}
+	return rest
+}
+
+func merge(slots []Slot) []Slot {
+	if len(slots) == 0 {
+		return slots
+	}
+
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	merged := []Slot{slots[0]}
+	for _, s := range slots[1:] {
+		last := &merged[len(merged)-1]
+		if !s.Start.After(last.End) {
+			if s.End.After(last.End) {
+				last.End = s.End
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	return merged
+}
@@ -0,0 +1,111 @@
+package availability
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+// monday is 2025-03-03, the worker works 9 to 5 on weekdays in New York
+func testCalendar(t *testing.T) (Calendar, *time.Location) {
+	t.Helper()
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data not available %v", err)
+	}
+
+	hours := make([]model.WorkingHours, 0)
+	for day := time.Monday; day <= time.Friday; day++ {
+		hours = append(hours, model.WorkingHours{Weekday: day, StartMinute: 9 * 60, EndMinute: 17 * 60})
+	}
+
+	at := func(d, h int) time.Time { return time.Date(2025, time.March, d, h, 0, 0, 0, loc) }
+	cal := NewCalendar(dac.WorkerCalendar{
+		Worker:  model.Worker{Uid: uuid.New(), Timezone: "America/New_York", EndDate: at(7, 12)},
+		Hours:   hours,
+		TimeOff: []model.TimeOff{{StartAt: at(5, 0), EndAt: at(6, 0)}},
+		Tasks: []model.Task{
+			{Tid: uuid.New(), Name: "fix sink", ScheduledAt: at(3, 10), EstimatedMinutes: 90},
+			{Tid: uuid.New(), Name: "paint", ScheduledAt: at(4, 13)},
+		},
+	})
+
+	return cal, loc
+}
+
+func TestConflicts(t *testing.T) {
+	cal, loc := testCalendar(t)
+	at := func(d, h, m int) time.Time { return time.Date(2025, time.March, d, h, m, 0, 0, loc) }
+
+	for _, test := range []struct {
+		name    string
+		start   time.Time
+		end     time.Time
+		reasons []ConflictReason
+	}{
+		{"free slot", at(3, 13, 0), at(3, 14, 0), nil},
+		{"overlaps booked task", at(3, 11, 0), at(3, 12, 0), []ConflictReason{Booked}},
+		{"starts as booked task ends", at(3, 11, 30), at(3, 12, 30), nil},
+		{"runs past working hours", at(4, 16, 0), at(4, 18, 0), []ConflictReason{OffHours}},
+		{"weekend", at(1, 10, 0), at(1, 11, 0), []ConflictReason{OffHours}},
+		{"time off", at(5, 10, 0), at(5, 11, 0), []ConflictReason{OnTimeOff}},
+		{"after end date", at(7, 13, 0), at(7, 14, 0), []ConflictReason{NotEmployed}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conflicts := cal.Conflicts(test.start, test.end)
+			if len(conflicts) != len(test.reasons) {
+				t.Fatalf("expected %v conflicts, got %+v", len(test.reasons), conflicts)
+			}
+			for i, c := range conflicts {
+				if c.Reason != test.reasons[i] {
+					t.Errorf("expected %v, got %v", test.reasons[i], c.Reason)
+				}
+			}
+		})
+	}
+
+	booked := cal.Conflicts(at(3, 11, 0), at(3, 12, 0))
+	if len(booked) == 1 && booked[0].TaskName != "fix sink" {
+		t.Errorf("expected booked conflict to name the task, got %+v", booked[0])
+	}
+}
+
+func TestFree(t *testing.T) {
+	cal, loc := testCalendar(t)
+	at := func(d, h, m int) time.Time { return time.Date(2025, time.March, d, h, m, 0, 0, loc) }
+
+	free := cal.Free(at(3, 0, 0), at(4, 0, 0))
+	want := []Slot{{at(3, 9, 0), at(3, 10, 0)}, {at(3, 11, 30), at(3, 17, 0)}}
+	if len(free) != len(want) {
+		t.Fatalf("expected %v slots, got %+v", len(want), free)
+	}
+	for i := range want {
+		if !free[i].Start.Equal(want[i].Start) || !free[i].End.Equal(want[i].End) {
+			t.Errorf("slot %v expected %v - %v, got %v - %v", i, want[i].Start, want[i].End, free[i].Start, free[i].End)
+		}
+	}
+
+	if slots := cal.Free(at(5, 0, 0), at(6, 0, 0)); len(slots) != 0 {
+		t.Errorf("expected no free time on a day off, got %+v", slots)
+	}
+
+	if slots := cal.Free(at(7, 0, 0), at(8, 0, 0)); len(slots) != 1 || !slots[0].End.Equal(at(7, 12, 0)) {
+		t.Errorf("expected free time to stop at the end date, got %+v", slots)
+	}
+}
+
+func TestNoHoursIsAlwaysAvailable(t *testing.T) {
+	cal := NewCalendar(dac.WorkerCalendar{Worker: model.Worker{Uid: uuid.New()}})
+	start := time.Date(2025, time.March, 2, 3, 0, 0, 0, time.UTC)
+
+	if conflicts := cal.Conflicts(start, start.Add(time.Hour)); len(conflicts) != 0 {
+		t.Errorf("expected no conflicts, got %+v", conflicts)
+	}
+
+	if free := cal.Free(start, start.Add(24*time.Hour)); len(free) != 1 {
+		t.Errorf("expected the whole range to be free, got %+v", free)
+	}
+}
@@ -24,13 +24,20 @@ func InitAlsrRepo(db Persister) AlessorRepo {
 
 func (a *AlessorRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
 	var alsr model.Alessor
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := a.GetBunDB().NewSelect().Model(&alsr).
-		Where("? = ?", bun.Ident("uid"), fltr.Identifier).Limit(limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
+	err = a.GetBunDB().NewSelect().Model(&alsr).
+		Where("? = ?", bun.Ident("uid"), fltr.Identifier).ApplyQueryBuilder(scope.ByLessor("alsr.uid")).
+		Limit(limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, ErrNoResults{Shape: alsr, Identifier: fltr.Identifier, Err: err}
+			return nil, scope.resolveMissing(ctx, a.alessorExists(fltr.Identifier), "Alessor", fltr.Identifier,
+				ErrNoResults{Shape: alsr, Identifier: fltr.Identifier, Err: err})
 		}
 		return nil, ErrFetchFailed{Model: "Alessor", Err: err}
 	}
@@ -40,8 +47,13 @@ func (a *AlessorRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface
 
 func (a *AlessorRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.Alessor, error) {
 	var alsrs []model.Alessor
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := a.GetBunDB().NewSelect().Model(&alsrs).Limit(limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
+	err = a.GetBunDB().NewSelect().Model(&alsrs).ApplyQueryBuilder(scope.ByLessor("alsr.uid")).Limit(limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNoResults{Shape: model.Alessor{}, Identifier: "[fetch-all]", Err: err}
@@ -58,6 +70,15 @@ func (a *AlessorRepo) Insert(ctx context.Context, alsr any) (interface{}, error)
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.Alessor{}, Got: alsr}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scope.Owns(al.Uid) {
+		return nil, ErrCrossTenant{Model: "Alessor", Identifier: al.Uid.String()}
+	}
+
 	tx, err := a.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, ErrTransactionStartFailed{Err: err}
@@ -83,12 +104,18 @@ func (a *AlessorRepo) Update(ctx context.Context, alsr any) (interface{}, error)
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.Alessor{}, Got: alsr}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := a.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, ErrTransactionStartFailed{Err: err}
 	}
 
-	rslt, err := tx.NewUpdate().Model(&al).OmitZero().Where("? = ?", bun.Ident("uid"), al.Uid).Returning("*").Exec(ctx)
+	rslt, err := tx.NewUpdate().Model(&al).OmitZero().Where("? = ?", bun.Ident("uid"), al.Uid).
+		ApplyQueryBuilder(scope.ByLessor("alsr.uid")).Returning("*").Exec(ctx)
 	if err != nil {
 		if err = tx.Rollback(); err != nil {
 			return nil, ErrRollbackFailed{Err: err}
@@ -96,6 +123,14 @@ func (a *AlessorRepo) Update(ctx context.Context, alsr any) (interface{}, error)
 		return nil, ErrUpdateFailed{Model: "Alessor", Err: err}
 	}
 
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if err = tx.Rollback(); err != nil {
+			return nil, ErrRollbackFailed{Err: err}
+		}
+		return nil, scope.resolveMissing(ctx, a.alessorExists(al.Uid.String()), "Alessor", al.Uid.String(),
+			ErrNoResults{Shape: al, Identifier: al.Uid.String(), Err: sql.ErrNoRows})
+	}
+
 	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -108,12 +143,17 @@ func (a *AlessorRepo) Delete(ctx context.Context, alsr any) error {
 		return cmerr.ErrUnexpectedData{Wanted: model.Alessor{}, Got: alsr}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
 	tx, err := a.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return ErrTransactionStartFailed{Err: err}
 	}
 
-	_, err = tx.NewDelete().Model(&al).Where("? = ?", bun.Ident("uid"), al.Uid).Exec(ctx)
+	rslt, err := tx.NewDelete().Model(&al).Where("? = ?", bun.Ident("uid"), al.Uid).ApplyQueryBuilder(scope.ByLessor("alsr.uid")).Exec(ctx)
 	if err != nil {
 		if err = tx.Rollback(); err != nil {
 			return ErrRollbackFailed{Err: err}
@@ -121,8 +161,20 @@ func (a *AlessorRepo) Delete(ctx context.Context, alsr any) error {
 		return ErrDeleteFailed{Model: "Alessor", Err: err}
 	}
 
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if err = tx.Rollback(); err != nil {
+			return ErrRollbackFailed{Err: err}
+		}
+		return scope.resolveMissing(ctx, a.alessorExists(al.Uid.String()), "Alessor", al.Uid.String(),
+			ErrNoResults{Shape: al, Identifier: al.Uid.String(), Err: sql.ErrNoRows})
+	}
+
 	if err = tx.Commit(); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (a *AlessorRepo) alessorExists(uid string) *bun.SelectQuery {
+	return a.GetBunDB().NewSelect().Model((*model.Alessor)(nil)).Where("? = ?", bun.Ident("uid"), uid)
+}
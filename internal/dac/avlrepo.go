@@ -0,0 +1,192 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// WorkerCalendar is everything needed to work out when a worker is free
+type WorkerCalendar struct {
+	Worker  model.Worker
+	Hours   []model.WorkingHours
+	TimeOff []model.TimeOff
+	Tasks   []model.Task
+}
+
+type AvailabilityRepo struct {
+	Persister
+}
+
+func InitAvailabilityRepo(db Persister) AvailabilityRepo {
+	return AvailabilityRepo{
+		Persister: db,
+	}
+}
+
+// FetchCalendar loads the worker's hours along with the time off and open tasks that overlap
+// from and to, exclude keeps a task being rescheduled from conflicting with itself
+func (a *AvailabilityRepo) FetchCalendar(ctx context.Context, workerId uuid.UUID, from time.Time, to time.Time, exclude uuid.UUID) (WorkerCalendar, error) {
+	var cal WorkerCalendar
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return WorkerCalendar{}, err
+	}
+
+	if cal.Worker, err = a.fetchWorker(ctx, scope, workerId); err != nil {
+		return WorkerCalendar{}, err
+	}
+
+	cal.Hours = make([]model.WorkingHours, 0)
+	err = a.GetBunDB().NewSelect().Model(&cal.Hours).Where("? = ?", bun.Ident("wh.worker_id"), workerId).
+		Order("wh.weekday ASC", "wh.start_minute ASC").Scan(ctx, &cal.Hours)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return WorkerCalendar{}, ErrFetchFailed{Model: "Working Hours", Err: err}
+	}
+
+	cal.TimeOff = make([]model.TimeOff, 0)
+	err = a.GetBunDB().NewSelect().Model(&cal.TimeOff).Where("? = ?", bun.Ident("wto.worker_id"), workerId).
+		Where("? < ?", bun.Ident("wto.start_at"), to).Where("? > ?", bun.Ident("wto.end_at"), from).
+		Order("wto.start_at ASC").Scan(ctx, &cal.TimeOff)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return WorkerCalendar{}, ErrFetchFailed{Model: "Time Off", Err: err}
+	}
+
+	cal.Tasks = make([]model.Task, 0)
+	err = a.GetBunDB().NewSelect().Model(&cal.Tasks).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("? = ?", bun.Ident("tsk.worker_id"), workerId).
+				WhereOr("? IN (SELECT task_id FROM assigned_tasks WHERE worker_id = ? AND status <> ?)",
+					bun.Ident("tsk.tid"), workerId, model.AssignmentDeclined)
+		}).
+		Where("? IN (?)", bun.Ident("tsk.status"), bun.In([]model.TaskStatus{model.Scheduled, model.Started, model.Paused})).
+		Where("? <> ?", bun.Ident("tsk.tid"), exclude).
+		Where("? < ?", bun.Ident("tsk.scheduled_at"), to).
+		Where("? + make_interval(mins => COALESCE(?, ?)) > ?", bun.Ident("tsk.scheduled_at"), bun.Ident("tsk.estimated_minutes"), model.DefaultTaskMinutes, from).
+		Order("tsk.scheduled_at ASC").Scan(ctx, &cal.Tasks)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return WorkerCalendar{}, ErrFetchFailed{Model: "Task", Err: err}
+	}
+
+	return cal, nil
+}
+
+// ReplaceHours swaps the worker's weekly hours for the given ones and sets the timezone
+// they are read in
+func (a *AvailabilityRepo) ReplaceHours(ctx context.Context, workerId uuid.UUID, timezone string, hours []model.WorkingHours) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	wrkr, err := a.fetchWorker(ctx, scope, workerId)
+	if err != nil {
+		return err
+	}
+
+	for i := range hours {
+		hours[i].WorkerId = workerId
+		hours[i].LessorId = wrkr.LessorId
+	}
+
+	tx, err := a.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	_, err = tx.NewUpdate().Model((*model.Worker)(nil)).Set("timezone = ?", timezone).
+		Where("? = ?", bun.Ident("uid"), workerId).Exec(ctx)
+
+	if err == nil {
+		_, err = tx.NewDelete().Model((*model.WorkingHours)(nil)).Where("? = ?", bun.Ident("worker_id"), workerId).Exec(ctx)
+	}
+
+	if err == nil && len(hours) > 0 {
+		_, err = tx.NewInsert().Model(&hours).Exec(ctx)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrUpdateFailed{Model: "Working Hours", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+func (a *AvailabilityRepo) InsertTimeOff(ctx context.Context, off *model.TimeOff) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	wrkr, err := a.fetchWorker(ctx, scope, off.WorkerId)
+	if err != nil {
+		return err
+	}
+
+	off.LessorId = wrkr.LessorId
+	if err = a.GetBunDB().NewInsert().Model(off).Returning("*").Scan(ctx, off); err != nil {
+		return ErrInsertFailed{Model: "Time Off", Err: err}
+	}
+
+	return nil
+}
+
+func (a *AvailabilityRepo) DeleteTimeOff(ctx context.Context, workerId uuid.UUID, oid uuid.UUID) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	rslt, err := a.GetBunDB().NewDelete().Model((*model.TimeOff)(nil)).Where("? = ?", bun.Ident("wto.oid"), oid).
+		Where("? = ?", bun.Ident("wto.worker_id"), workerId).ApplyQueryBuilder(scope.ByLessor("wto.lessor_id")).Exec(ctx)
+	if err != nil {
+		return ErrDeleteFailed{Model: "Time Off", Err: err}
+	}
+
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		exists := a.GetBunDB().NewSelect().Model((*model.TimeOff)(nil)).Where("? = ?", bun.Ident("oid"), oid)
+		return scope.resolveMissing(ctx, exists, "Time Off", oid.String(),
+			ErrNoResults{Shape: model.TimeOff{}, Identifier: oid.String(), Err: sql.ErrNoRows})
+	}
+
+	return nil
+}
+
+// FetchWorker loads a worker in the caller's scope, mostly to read their timezone
+func (a *AvailabilityRepo) FetchWorker(ctx context.Context, workerId uuid.UUID) (model.Worker, error) {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Worker{}, err
+	}
+
+	return a.fetchWorker(ctx, scope, workerId)
+}
+
+func (a *AvailabilityRepo) fetchWorker(ctx context.Context, scope TenantScope, workerId uuid.UUID) (model.Worker, error) {
+	var wrkr model.Worker
+	err := a.GetBunDB().NewSelect().Model(&wrkr).Where("? = ?", bun.Ident("w.uid"), workerId).
+		ApplyQueryBuilder(scope.ByLessor("w.lessor_id")).Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			exists := a.GetBunDB().NewSelect().Model((*model.Worker)(nil)).Where("? = ?", bun.Ident("uid"), workerId)
+			return model.Worker{}, scope.resolveMissing(ctx, exists, "worker", workerId.String(),
+				ErrNoResults{Shape: "worker", Identifier: workerId.String(), Err: err})
+		}
+		return model.Worker{}, ErrFetchFailed{Model: "worker", Err: err}
+	}
+
+	return wrkr, nil
+}
@@ -0,0 +1,80 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/uptrace/bun"
+)
+
+// JobRepo is not tenant scoped, jobs belong to the service rather than a lessor
+type JobRepo struct {
+	Persister
+}
+
+func InitJobRepo(db Persister) JobRepo {
+	return JobRepo{
+		Persister: db,
+	}
+}
+
+// Register creates the job row the first time a job is seen, when the job's spec has
+// changed since it was stored the next run is moved to match the new spec
+func (j *JobRepo) Register(ctx context.Context, job *model.Job) error {
+	_, err := j.GetBunDB().NewInsert().Model(job).
+		On("CONFLICT (name) DO UPDATE").
+		Set("next_run_at = CASE WHEN jb.spec = EXCLUDED.spec THEN jb.next_run_at ELSE EXCLUDED.next_run_at END").
+		Set("spec = EXCLUDED.spec").Exec(ctx)
+
+	if err != nil {
+		return ErrInsertFailed{Model: "Job", Err: err}
+	}
+
+	return nil
+}
+
+// Lease takes the most overdue of the named jobs that isn't leased by another replica,
+// SKIP LOCKED keeps two replicas polling at once from taking the same row
+func (j *JobRepo) Lease(ctx context.Context, names []string, owner string, now time.Time, leaseFor time.Duration) (model.Job, error) {
+	var job model.Job
+	due := j.GetBunDB().NewSelect().Model((*model.Job)(nil)).Column("id").
+		Where("? IN (?)", bun.Ident("name"), bun.In(names)).Where("? <= ?", bun.Ident("next_run_at"), now).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("leased_until IS NULL").WhereOr("leased_until < ?", now)
+		}).Order("next_run_at ASC").Limit(1).For("UPDATE SKIP LOCKED")
+
+	err := j.GetBunDB().NewUpdate().Model(&job).Set("leased_by = ?", owner).Set("leased_until = ?", now.Add(leaseFor)).
+		Where("? = (?)", bun.Ident("id"), due).Returning("*").Scan(ctx, &job)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Job{}, ErrNoResults{Shape: job, Identifier: "[due-job]", Err: err}
+		}
+		return model.Job{}, ErrUpdateFailed{Model: "Job", Err: err}
+	}
+
+	return job, nil
+}
+
+// Release records the outcome of a run and gives up the lease. It only applies while the
+// owner still holds the lease, if it ran out and another replica took the job ErrStaleWrite
+// is returned
+func (j *JobRepo) Release(ctx context.Context, job model.Job, owner string) error {
+	rslt, err := j.GetBunDB().NewUpdate().Model(&job).
+		Column("next_run_at", "attempts", "last_error", "last_run_at").
+		Set("leased_by = NULL").Set("leased_until = NULL").
+		Where("? = ?", bun.Ident("id"), job.Id).Where("? = ?", bun.Ident("leased_by"), owner).Exec(ctx)
+
+	if err != nil {
+		return ErrUpdateFailed{Model: "Job", Err: err}
+	}
+
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		return ErrStaleWrite
+	}
+
+	return nil
+}
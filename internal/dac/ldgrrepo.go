@@ -0,0 +1,633 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+)
+
+type LedgerRepo struct {
+	Persister
+}
+
+func InitLedgerRepo(db Persister) LedgerRepo {
+	return LedgerRepo{
+		Persister: db,
+	}
+}
+
+func (l *LedgerRepo) FetchCharge(ctx context.Context, cid string) (model.Charge, error) {
+	var charge model.Charge
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Charge{}, err
+	}
+
+	err = l.GetBunDB().NewSelect().Model(&charge).Where("? = ?", bun.Ident("rc.cid"), cid).
+		ApplyQueryBuilder(scope.ByLessor("rc.lessor_id")).Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Charge{}, scope.resolveMissing(ctx, l.chargeExists(cid), "Charge", cid,
+				ErrNoResults{Shape: charge, Identifier: cid, Err: err})
+		}
+		return model.Charge{}, ErrFetchFailed{Model: "Charge", Err: err}
+	}
+
+	return charge, nil
+}
+
+func (l *LedgerRepo) InsertCharge(ctx context.Context, chrg any) (interface{}, error) {
+	charge, ok := chrg.(*model.Charge)
+	if !ok {
+		return nil, cmerr.ErrUnexpectedData{Wanted: model.Charge{}, Got: chrg}
+	}
+
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = l.checkTenancy(ctx, scope, charge.LessorId, charge.TenantId, charge.RentalId); err != nil {
+		return nil, err
+	}
+
+	if err = l.GetBunDB().NewInsert().Model(charge).Returning("*").Scan(ctx, charge); err != nil {
+		return nil, ErrInsertFailed{Model: "Charge", Err: err}
+	}
+
+	return charge, nil
+}
+
+// InsertGenerated stores charges made by the rent and late fee jobs, charges that were
+// already generated are skipped and only the new ones are returned
+func (l *LedgerRepo) InsertGenerated(ctx context.Context, charges []model.Charge) ([]model.Charge, error) {
+	created := make([]model.Charge, 0, len(charges))
+	if len(charges) == 0 {
+		return created, nil
+	}
+
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, charge := range charges {
+		if !scope.Owns(charge.LessorId) {
+			return nil, ErrCrossTenant{Model: "Charge", Identifier: charge.Cid.String()}
+		}
+	}
+
+	err = l.GetBunDB().NewInsert().Model(&charges).On("CONFLICT DO NOTHING").Returning("*").Scan(ctx, &created)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInsertFailed{Model: "Charge", Err: err}
+	}
+
+	return created, nil
+}
+
+// UpdateCharge writes the description, amount and due date. The amount can't drop below
+// what has already been paid against the charge
+func (l *LedgerRepo) UpdateCharge(ctx context.Context, charge *model.Charge) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = l.GetBunDB().NewUpdate().Model(charge).Column("description", "amount", "due_date").
+		Where("? = ?", bun.Ident("rc.cid"), charge.Cid).Where("rc.voided_at IS NULL").
+		Where("? <= ?", bun.Ident("rc.amount_paid"), charge.Amount).
+		ApplyQueryBuilder(scope.ByLessor("rc.lessor_id")).Returning("*").Scan(ctx, charge)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return l.chargeWriteErr(ctx, scope, charge.Cid.String())
+		}
+		return ErrUpdateFailed{Model: "Charge", Err: err}
+	}
+
+	return nil
+}
+
+// VoidCharge takes a charge off the ledger, charges with payments against them have to
+// have those payments reversed first
+func (l *LedgerRepo) VoidCharge(ctx context.Context, cid string) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	rslt, err := l.GetBunDB().NewUpdate().Model((*model.Charge)(nil)).Set("voided_at = ?", time.Now()).
+		Where("? = ?", bun.Ident("rc.cid"), cid).Where("rc.voided_at IS NULL").Where("rc.amount_paid = 0").
+		ApplyQueryBuilder(scope.ByLessor("rc.lessor_id")).Exec(ctx)
+
+	if err != nil {
+		return ErrUpdateFailed{Model: "Charge", Err: err}
+	}
+
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		return l.chargeWriteErr(ctx, scope, cid)
+	}
+
+	return nil
+}
+
+func (l *LedgerRepo) FetchPayment(ctx context.Context, txid string) (model.Payment, error) {
+	var pmt model.Payment
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Payment{}, err
+	}
+
+	err = l.GetBunDB().NewSelect().Model(&pmt).Where("? = ?", bun.Ident("pmts.txid"), txid).
+		ApplyQueryBuilder(scope.ByLessor("pmts.lessor_id")).Relation("Allocations").Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Payment{}, scope.resolveMissing(ctx, l.paymentExists(txid), "Payment", txid,
+				ErrNoResults{Shape: pmt, Identifier: txid, Err: err})
+		}
+		return model.Payment{}, ErrFetchFailed{Model: "Payment", Err: err}
+	}
+
+	return pmt, nil
+}
+
+// FetchProcessorPayment finds a processor payment by the processor's id, refund callbacks
+// only carry that id
+func (l *LedgerRepo) FetchProcessorPayment(ctx context.Context, processorId string) (model.Payment, error) {
+	var pmt model.Payment
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Payment{}, err
+	}
+
+	err = l.GetBunDB().NewSelect().Model(&pmt).Where("? = ?", bun.Ident("pmts.sqpid"), processorId).
+		Where("? = ?", bun.Ident("pmts.method"), model.Processor).
+		ApplyQueryBuilder(scope.ByLessor("pmts.lessor_id")).Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Payment{}, ErrNoResults{Shape: pmt, Identifier: processorId, Err: err}
+		}
+		return model.Payment{}, ErrFetchFailed{Model: "Payment", Err: err}
+	}
+
+	return pmt, nil
+}
+
+// RecordPayment stores the payment and applies it to the tenant's open charges on the
+// rental if it was accepted, the given charge is paid first and the rest go oldest due first
+func (l *LedgerRepo) RecordPayment(ctx context.Context, pmt *model.Payment, chargeId uuid.UUID) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = l.checkTenancy(ctx, scope, pmt.LessorId, pmt.TenantId, pmt.RentalId); err != nil {
+		return err
+	}
+
+	tx, err := l.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	pmt.Applied = decimal.Zero
+	if err = tx.NewInsert().Model(pmt).Returning("*").Scan(ctx, pmt); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrInsertFailed{Model: "Payment", Err: err}
+	}
+
+	if pmt.TransactionStatus == model.Accepted {
+		if err = allocate(ctx, tx, pmt, chargeId); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return ErrRollbackFailed{rbErr}
+			}
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+// UpdatePayment only writes the note and receipt number, amounts are fixed once recorded
+func (l *LedgerRepo) UpdatePayment(ctx context.Context, pmt *model.Payment) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = l.GetBunDB().NewUpdate().Model(pmt).Column("note", "receit_number").
+		Where("? = ?", bun.Ident("pmts.txid"), pmt.TxId).ApplyQueryBuilder(scope.ByLessor("pmts.lessor_id")).
+		Returning("*").Scan(ctx, pmt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			txid := pmt.TxId.String()
+			return scope.resolveMissing(ctx, l.paymentExists(txid), "Payment", txid,
+				ErrNoResults{Shape: model.Payment{}, Identifier: txid, Err: err})
+		}
+		return ErrUpdateFailed{Model: "Payment", Err: err}
+	}
+
+	return nil
+}
+
+// ReversePayment backs a payment out of the ledger and reopens whatever it paid off
+func (l *LedgerRepo) ReversePayment(ctx context.Context, txid string) error {
+	var pmt model.Payment
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := l.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	err = tx.NewSelect().Model(&pmt).Where("? = ?", bun.Ident("pmts.txid"), txid).
+		ApplyQueryBuilder(scope.ByLessor("pmts.lessor_id")).For("UPDATE").Scan(ctx)
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return scope.resolveMissing(ctx, l.paymentExists(txid), "Payment", txid,
+				ErrNoResults{Shape: pmt, Identifier: txid, Err: err})
+		}
+		return ErrFetchFailed{Model: "Payment", Err: err}
+	}
+
+	if !pmt.ReversedAt.IsZero() {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrPaymentReversed
+	}
+
+	if err = unapply(ctx, tx, &pmt); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+// ApplyProcessorStatus moves a processor payment to the status the processor reports, the
+// payment is found by its txid reference when there is one and by the processor id if not.
+// Repeated and out of order updates leave the payment as it is so callbacks can be retried
+func (l *LedgerRepo) ApplyProcessorStatus(ctx context.Context, reference string, processorId string, status model.PaymentStatus) (model.Payment, bool, error) {
+	var pmt model.Payment
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Payment{}, false, err
+	}
+
+	identifier, column := processorId, "pmts.sqpid"
+	if _, err = uuid.Parse(reference); err == nil {
+		identifier, column = reference, "pmts.txid"
+	}
+
+	tx, err := l.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return model.Payment{}, false, ErrTransactionStartFailed{Err: err}
+	}
+
+	err = tx.NewSelect().Model(&pmt).Where("? = ?", bun.Ident(column), identifier).
+		Where("? = ?", bun.Ident("pmts.method"), model.Processor).
+		ApplyQueryBuilder(scope.ByLessor("pmts.lessor_id")).For("UPDATE").Scan(ctx)
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.Payment{}, false, ErrRollbackFailed{rbErr}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Payment{}, false, ErrNoResults{Shape: pmt, Identifier: identifier, Err: err}
+		}
+		return model.Payment{}, false, ErrFetchFailed{Model: "Payment", Err: err}
+	}
+
+	if pmt.SqPid == "" && processorId != "" {
+		pmt.SqPid = processorId
+	}
+
+	advanced := pmt.TransactionStatus.Advances(status)
+	if advanced {
+		pmt.TransactionStatus = status
+		switch {
+		case status == model.Accepted && pmt.ReversedAt.IsZero():
+			err = allocate(ctx, tx, &pmt, uuid.Nil)
+		case status == model.PaymentRefunded && pmt.ReversedAt.IsZero():
+			err = unapply(ctx, tx, &pmt)
+		}
+	}
+
+	if err == nil {
+		_, err = tx.NewUpdate().Model(&pmt).Column("sqpid", "transaction_status").WherePK().Exec(ctx)
+		if err != nil {
+			err = ErrUpdateFailed{Model: "Payment", Err: err}
+		}
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.Payment{}, false, ErrRollbackFailed{rbErr}
+		}
+		return model.Payment{}, false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.Payment{}, false, ErrTransactionCommitFail{err}
+	}
+
+	return pmt, advanced, nil
+}
+
+// FetchPendingProcessor returns processor payments that have been pending since before the
+// given time, they are checked with the processor in case a callback was missed. Payments
+// without a processor id are included, their charge request never got a response
+func (l *LedgerRepo) FetchPendingProcessor(ctx context.Context, before time.Time) ([]model.Payment, error) {
+	pmts := make([]model.Payment, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.GetBunDB().NewSelect().Model(&pmts).
+		Where("? = ?", bun.Ident("pmts.method"), model.Processor).
+		Where("? = ?", bun.Ident("pmts.transaction_status"), model.PaymentPending).
+		Where("? < ?", bun.Ident("pmts.created_at"), before).
+		ApplyQueryBuilder(scope.ByLessor("pmts.lessor_id")).Scan(ctx, &pmts)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Payment", Err: err}
+	}
+
+	return pmts, nil
+}
+
+// FetchPaymentSettings returns the lessor's processor account and whether payments for them
+// go through the processor
+func (l *LedgerRepo) FetchPaymentSettings(ctx context.Context, lessorId uuid.UUID) (model.Alessor, error) {
+	var alsr model.Alessor
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Alessor{}, err
+	}
+
+	if !scope.Owns(lessorId) {
+		return model.Alessor{}, ErrCrossTenant{Model: "Alessor", Identifier: lessorId.String()}
+	}
+
+	err = l.GetBunDB().NewSelect().Model(&alsr).Column("uid", "square_account", "payment_integration_enabled").
+		Where("? = ?", bun.Ident("alsr.uid"), lessorId).Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Alessor{}, ErrNoResults{Shape: alsr, Identifier: lessorId.String(), Err: err}
+		}
+		return model.Alessor{}, ErrFetchFailed{Model: "Alessor", Err: err}
+	}
+
+	return alsr, nil
+}
+
+// FetchTenantLedger returns the live charges and accepted payments for a tenant
+func (l *LedgerRepo) FetchTenantLedger(ctx context.Context, tenantId string) ([]model.Charge, []model.Payment, error) {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tenant := l.GetBunDB().NewSelect().Model((*model.Tenant)(nil)).Where("? = ?", bun.Ident("tnt.uid"), tenantId)
+	found, err := tenant.ApplyQueryBuilder(scope.ByLessor("tnt.lessor_id")).Exists(ctx)
+	if err != nil {
+		return nil, nil, ErrFetchFailed{Model: "Tenant", Err: err}
+	}
+
+	if !found {
+		exists := l.GetBunDB().NewSelect().Model((*model.Tenant)(nil)).Where("? = ?", bun.Ident("uid"), tenantId)
+		return nil, nil, scope.resolveMissing(ctx, exists, "Tenant", tenantId,
+			ErrNoResults{Shape: model.Tenant{}, Identifier: tenantId, Err: sql.ErrNoRows})
+	}
+
+	return l.fetchEntries(ctx, scope, "tenant_id", tenantId)
+}
+
+// FetchRentalLedger returns the live charges and accepted payments for every tenant of a rental
+func (l *LedgerRepo) FetchRentalLedger(ctx context.Context, rentalId string) ([]model.Charge, []model.Payment, error) {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rental := l.GetBunDB().NewSelect().Model((*model.RentalProperty)(nil)).Where("? = ?", bun.Ident("rp.pid"), rentalId)
+	found, err := rental.ApplyQueryBuilder(scope.ByProperty("rp.pid")).Exists(ctx)
+	if err != nil {
+		return nil, nil, ErrFetchFailed{Model: "Rental Property", Err: err}
+	}
+
+	if !found {
+		exists := l.GetBunDB().NewSelect().Model((*model.RentalProperty)(nil)).Where("? = ?", bun.Ident("pid"), rentalId)
+		return nil, nil, scope.resolveMissing(ctx, exists, "Rental Property", rentalId,
+			ErrNoResults{Shape: model.RentalProperty{}, Identifier: rentalId, Err: sql.ErrNoRows})
+	}
+
+	return l.fetchEntries(ctx, scope, "rental_id", rentalId)
+}
+
+// FetchTenancies returns every tenant that has moved in along with their rental
+func (l *LedgerRepo) FetchTenancies(ctx context.Context) ([]model.Tenant, error) {
+	tenants := make([]model.Tenant, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.GetBunDB().NewSelect().Model(&tenants).Relation("Rental").Where("tnt.move_in_date IS NOT NULL").
+		ApplyQueryBuilder(scope.ByLessor("tnt.lessor_id")).Scan(ctx, &tenants)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Tenant", Err: err}
+	}
+
+	return tenants, nil
+}
+
+// FetchOverdue returns unpaid rent charges due before now that haven't had a late fee
+func (l *LedgerRepo) FetchOverdue(ctx context.Context, now time.Time) ([]model.Charge, error) {
+	charges := make([]model.Charge, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.GetBunDB().NewSelect().Model(&charges).Relation("Rental").
+		Where("? = ?", bun.Ident("rc.kind"), model.RentCharge).Where("rc.voided_at IS NULL").
+		Where("rc.amount_paid < rc.amount").Where("? < ?", bun.Ident("rc.due_date"), now).
+		Where("NOT EXISTS (SELECT 1 FROM rent_charges AS fee WHERE fee.late_fee_for = rc.cid)").
+		ApplyQueryBuilder(scope.ByLessor("rc.lessor_id")).Scan(ctx, &charges)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Charge", Err: err}
+	}
+
+	return charges, nil
+}
+
+func (l *LedgerRepo) fetchEntries(ctx context.Context, scope TenantScope, column string, id string) ([]model.Charge, []model.Payment, error) {
+	charges := make([]model.Charge, 0)
+	err := l.GetBunDB().NewSelect().Model(&charges).Where("? = ?", bun.Ident("rc."+column), id).
+		Where("rc.voided_at IS NULL").ApplyQueryBuilder(scope.ByLessor("rc.lessor_id")).
+		Order("rc.due_date ASC", "rc.id ASC").Scan(ctx, &charges)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrFetchFailed{Model: "Charge", Err: err}
+	}
+
+	payments := make([]model.Payment, 0)
+	err = l.GetBunDB().NewSelect().Model(&payments).Where("? = ?", bun.Ident("pmts."+column), id).
+		Where("? = ?", bun.Ident("pmts.transaction_status"), model.Accepted).Where("pmts.reversed_at IS NULL").
+		ApplyQueryBuilder(scope.ByLessor("pmts.lessor_id")).Order("pmts.received_at ASC", "pmts.id ASC").Scan(ctx, &payments)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrFetchFailed{Model: "Payment", Err: err}
+	}
+
+	return charges, payments, nil
+}
+
+// checkTenancy makes sure the tenant rents the rental from the lessor the record is for
+func (l *LedgerRepo) checkTenancy(ctx context.Context, scope TenantScope, lessorId, tenantId, rentalId uuid.UUID) error {
+	if !scope.Owns(lessorId) {
+		return ErrCrossTenant{Model: "Tenant", Identifier: tenantId.String()}
+	}
+
+	found, err := l.GetBunDB().NewSelect().Model((*model.Tenant)(nil)).
+		Where("? = ?", bun.Ident("uid"), tenantId).Where("? = ?", bun.Ident("property_id"), rentalId).
+		Where("? = ?", bun.Ident("lessor_id"), lessorId).Exists(ctx)
+	if err != nil {
+		return ErrFetchFailed{Model: "Tenant", Err: err}
+	}
+
+	if !found {
+		return ErrCrossTenant{Model: "Tenant", Identifier: tenantId.String()}
+	}
+
+	return nil
+}
+
+// chargeWriteErr explains why a guarded charge write matched nothing
+func (l *LedgerRepo) chargeWriteErr(ctx context.Context, scope TenantScope, cid string) error {
+	inScope, err := l.chargeExists(cid).ApplyQueryBuilder(scope.ByLessor("rc.lessor_id")).Exists(ctx)
+	if err != nil {
+		return ErrFetchFailed{Model: "Charge", Err: err}
+	}
+
+	if inScope {
+		return ErrChargeHasPayments
+	}
+
+	return scope.resolveMissing(ctx, l.chargeExists(cid), "Charge", cid,
+		ErrNoResults{Shape: model.Charge{}, Identifier: cid, Err: sql.ErrNoRows})
+}
+
+func (l *LedgerRepo) chargeExists(cid string) *bun.SelectQuery {
+	return l.GetBunDB().NewSelect().Model((*model.Charge)(nil)).Where("? = ?", bun.Ident("rc.cid"), cid)
+}
+
+func (l *LedgerRepo) paymentExists(txid string) *bun.SelectQuery {
+	return l.GetBunDB().NewSelect().Model((*model.Payment)(nil)).Where("? = ?", bun.Ident("txid"), txid)
+}
+
+// allocate applies the payment to the tenant's open charges on the rental, the charges are
+// locked so two payments can't both pay off the same balance
+func allocate(ctx context.Context, tx bun.Tx, pmt *model.Payment, chargeId uuid.UUID) error {
+	var charges []model.Charge
+	err := tx.NewSelect().Model(&charges).
+		Where("? = ?", bun.Ident("rc.tenant_id"), pmt.TenantId).Where("? = ?", bun.Ident("rc.rental_id"), pmt.RentalId).
+		Where("rc.voided_at IS NULL").Where("rc.amount_paid < rc.amount").
+		OrderExpr("? = ? DESC", bun.Ident("rc.cid"), chargeId).Order("rc.due_date ASC", "rc.id ASC").
+		For("UPDATE").Scan(ctx, &charges)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ErrFetchFailed{Model: "Charge", Err: err}
+	}
+
+	allocations, unapplied := model.AllocatePayment(pmt.TxId, pmt.Amount, charges)
+	pmt.Applied = pmt.Amount.Sub(unapplied)
+	pmt.Allocations = allocations
+	if len(allocations) == 0 {
+		return nil
+	}
+
+	if _, err = tx.NewInsert().Model(&allocations).Exec(ctx); err != nil {
+		return ErrInsertFailed{Model: "Payment Allocation", Err: err}
+	}
+
+	for _, charge := range charges {
+		_, err = tx.NewUpdate().Model((*model.Charge)(nil)).Set("amount_paid = ?", charge.AmountPaid).
+			Where("? = ?", bun.Ident("cid"), charge.Cid).Exec(ctx)
+		if err != nil {
+			return ErrUpdateFailed{Model: "Charge", Err: err}
+		}
+	}
+
+	_, err = tx.NewUpdate().Model((*model.Payment)(nil)).Set("applied = ?", pmt.Applied).
+		Where("? = ?", bun.Ident("txid"), pmt.TxId).Exec(ctx)
+	if err != nil {
+		return ErrUpdateFailed{Model: "Payment", Err: err}
+	}
+
+	return nil
+}
+
+// unapply takes a payment back off the charges it paid and marks it reversed
+func unapply(ctx context.Context, tx bun.Tx, pmt *model.Payment) error {
+	var allocations []model.PaymentAllocation
+	err := tx.NewDelete().Model(&allocations).Where("? = ?", bun.Ident("payment_id"), pmt.TxId).Returning("*").Scan(ctx, &allocations)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ErrDeleteFailed{Model: "Payment Allocation", Err: err}
+	}
+
+	for _, alloc := range allocations {
+		_, err = tx.NewUpdate().Model((*model.Charge)(nil)).Set("amount_paid = amount_paid - ?", alloc.Amount).
+			Where("? = ?", bun.Ident("cid"), alloc.ChargeId).Exec(ctx)
+		if err != nil {
+			return ErrUpdateFailed{Model: "Charge", Err: err}
+		}
+	}
+
+	pmt.ReversedAt = time.Now()
+	pmt.Applied = decimal.Zero
+	pmt.Allocations = nil
+	_, err = tx.NewUpdate().Model((*model.Payment)(nil)).Set("reversed_at = ?", pmt.ReversedAt).Set("applied = 0").
+		Where("? = ?", bun.Ident("txid"), pmt.TxId).Exec(ctx)
+	if err != nil {
+		return ErrUpdateFailed{Model: "Payment", Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,436 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type LeaseRepo struct {
+	Persister
+}
+
+func InitLeaseRepo(db Persister) LeaseRepo {
+	return LeaseRepo{
+		Persister: db,
+	}
+}
+
+func (l *LeaseRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
+	var lease model.Lease
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.GetBunDB().NewSelect().Model(&lease).Where("? = ?", bun.Ident("ls.lid"), fltr.Identifier).
+		ApplyQueryBuilder(scope.ByLessor("ls.lessor_id")).Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, scope.resolveMissing(ctx, l.leaseExists(fltr.Identifier), "Lease", fltr.Identifier,
+				ErrNoResults{Shape: lease, Identifier: fltr.Identifier, Err: err})
+		}
+		return nil, ErrFetchFailed{Model: "Lease", Err: err}
+	}
+
+	return lease, nil
+}
+
+// FetchAll returns the lease history of the rental named by the filter, newest first
+func (l *LeaseRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.Lease, error) {
+	leases := make([]model.Lease, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := utils.DeterminRecordLimit(fltr.Limit)
+	err = l.GetBunDB().NewSelect().Model(&leases).Where("? = ?", bun.Ident("ls.rental_id"), fltr.Identifier).
+		ApplyQueryBuilder(scope.ByLessor("ls.lessor_id")).Order("ls.starts_at DESC").
+		Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &leases)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Lease", Err: err}
+	}
+
+	return leases, nil
+}
+
+// Insert saves a draft lease, a renewal is only saved while the lease it renews is active
+// or expired and doesn't already have a renewal that is still a draft or active
+func (l *LeaseRepo) Insert(ctx context.Context, ls any) (interface{}, error) {
+	lease, ok := ls.(*model.Lease)
+	if !ok {
+		return nil, cmerr.ErrUnexpectedData{Wanted: model.Lease{}, Got: ls}
+	}
+
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = l.checkParties(ctx, scope, lease); err != nil {
+		return nil, err
+	}
+
+	tx, err := l.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, ErrTransactionStartFailed{Err: err}
+	}
+
+	if lease.RenewalOf != uuid.Nil {
+		err = l.checkRenewable(ctx, tx, scope, lease.RenewalOf)
+	}
+
+	if err == nil {
+		if err = tx.NewInsert().Model(lease).Returning("*").Scan(ctx, lease); err != nil {
+			err = ErrInsertFailed{Model: "Lease", Err: err}
+		}
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, ErrTransactionCommitFail{err}
+	}
+
+	return lease, nil
+}
+
+// Update writes the agreed terms of a draft, a lease that has been activated can't be edited
+func (l *LeaseRepo) Update(ctx context.Context, lease *model.Lease) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = l.checkParties(ctx, scope, lease); err != nil {
+		return err
+	}
+
+	return l.changeLease(ctx, lease.Lid, func(tx bun.Tx, current *model.Lease, now time.Time) error {
+		if current.Status != model.LeaseDraft {
+			return ErrLeaseStatus
+		}
+
+		lease.UpdatedAt = now
+		_, err := tx.NewUpdate().Model(lease).
+			Column("tenant_ids", "starts_at", "ends_at", "rent_amount", "deposit", "terms", "updated_at").
+			Where("? = ?", bun.Ident("lid"), lease.Lid).Returning("*").Exec(ctx, lease)
+		if err != nil {
+			return ErrUpdateFailed{Model: "Lease", Err: err}
+		}
+		return nil
+	})
+}
+
+// Activate makes the draft the signed lease for its period. The rental row is locked while
+// checking so two leases covering the same time can't both be activated
+func (l *LeaseRepo) Activate(ctx context.Context, lid uuid.UUID) (model.Lease, error) {
+	var lease model.Lease
+	err := l.changeLease(ctx, lid, func(tx bun.Tx, current *model.Lease, now time.Time) error {
+		if !current.CanMoveTo(model.LeaseActive) {
+			return ErrLeaseStatus
+		}
+
+		var id int64
+		err := tx.NewSelect().Model((*model.RentalProperty)(nil)).Column("rp.id").
+			Where("? = ?", bun.Ident("rp.pid"), current.RentalId).For("UPDATE").Scan(ctx, &id)
+		if err != nil {
+			return ErrFetchFailed{Model: "Rental Property", Err: err}
+		}
+
+		overlaps, err := tx.NewSelect().Model((*model.Lease)(nil)).Where("? = ?", bun.Ident("ls.rental_id"), current.RentalId).
+			Where("? = ?", bun.Ident("ls.status"), model.LeaseActive).Where("? != ?", bun.Ident("ls.lid"), current.Lid).
+			Where("ls.starts_at < ?", current.EndsAt).Where("ls.ends_at > ?", current.StartsAt).Exists(ctx)
+		if err != nil {
+			return ErrFetchFailed{Model: "Lease", Err: err}
+		}
+
+		if overlaps {
+			return ErrLeaseOverlap
+		}
+
+		current.Status = model.LeaseActive
+		current.ActivatedAt = now
+		current.UpdatedAt = now
+		if err = l.writeStatus(ctx, tx, current); err != nil {
+			return err
+		}
+
+		lease = *current
+		return syncRental(ctx, tx, current.RentalId, now)
+	})
+
+	return lease, err
+}
+
+// Terminate ends a lease early or withdraws a draft that was never signed
+func (l *LeaseRepo) Terminate(ctx context.Context, lid uuid.UUID, reason string) (model.Lease, error) {
+	var lease model.Lease
+	err := l.changeLease(ctx, lid, func(tx bun.Tx, current *model.Lease, now time.Time) error {
+		if !current.CanMoveTo(model.LeaseTerminated) {
+			return ErrLeaseStatus
+		}
+
+		wasActive := current.Status == model.LeaseActive
+		current.Status = model.LeaseTerminated
+		current.TerminatedAt = now
+		current.TerminationReason = reason
+		current.UpdatedAt = now
+		if err := l.writeStatus(ctx, tx, current); err != nil {
+			return err
+		}
+
+		lease = *current
+		if !wasActive {
+			return nil
+		}
+		return syncRental(ctx, tx, current.RentalId, now)
+	})
+
+	return lease, err
+}
+
+// Expire moves every active lease that has reached its end date to expired and brings the
+// rentals they were on up to date, the number of leases expired is returned
+func (l *LeaseRepo) Expire(ctx context.Context, now time.Time) (int, error) {
+	expired := make([]model.Lease, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := l.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, ErrTransactionStartFailed{Err: err}
+	}
+
+	err = tx.NewUpdate().Model(&expired).Set("status = ?", model.LeaseExpired).Set("updated_at = ?", now).
+		Where("? = ?", bun.Ident("ls.status"), model.LeaseActive).Where("ls.ends_at <= ?", now).
+		ApplyQueryBuilder(scope.ByLessor("ls.lessor_id")).Returning("*").Scan(ctx, &expired)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	} else if err != nil {
+		err = ErrUpdateFailed{Model: "Lease", Err: err}
+	}
+
+	synced := make(map[uuid.UUID]bool)
+	for _, lease := range expired {
+		if err == nil && !synced[lease.RentalId] {
+			synced[lease.RentalId] = true
+			err = syncRental(ctx, tx, lease.RentalId, now)
+		}
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return 0, ErrRollbackFailed{rbErr}
+		}
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, ErrTransactionCommitFail{err}
+	}
+
+	return len(expired), nil
+}
+
+// FetchRenewalsDue returns the active leases ending within the notice period that haven't
+// been reminded about and don't have an active renewal lined up
+func (l *LeaseRepo) FetchRenewalsDue(ctx context.Context, now time.Time, notice time.Duration) ([]model.Lease, error) {
+	leases := make([]model.Lease, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	renewed := l.GetBunDB().NewSelect().TableExpr("leases AS rnw").ColumnExpr("1").
+		Where("rnw.renewal_of = ls.lid").Where("rnw.status = ?", model.LeaseActive)
+
+	err = l.GetBunDB().NewSelect().Model(&leases).Where("? = ?", bun.Ident("ls.status"), model.LeaseActive).
+		Where("ls.reminded_at IS NULL").Where("ls.ends_at > ?", now).Where("ls.ends_at <= ?", now.Add(notice)).
+		Where("NOT EXISTS (?)", renewed).ApplyQueryBuilder(scope.ByLessor("ls.lessor_id")).
+		Order("ls.ends_at ASC").Scan(ctx, &leases)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Lease", Err: err}
+	}
+
+	return leases, nil
+}
+
+func (l *LeaseRepo) MarkReminded(ctx context.Context, lid uuid.UUID, at time.Time) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.GetBunDB().NewUpdate().Model((*model.Lease)(nil)).Set("reminded_at = ?", at).
+		Where("? = ?", bun.Ident("lid"), lid).ApplyQueryBuilder(scope.ByLessor("ls.lessor_id")).Exec(ctx)
+	if err != nil {
+		return ErrUpdateFailed{Model: "Lease", Err: err}
+	}
+
+	return nil
+}
+
+// changeLease locks the lease and runs the change in one transaction, the transaction is
+// rolled back when the change fails
+func (l *LeaseRepo) changeLease(ctx context.Context, lid uuid.UUID, change func(bun.Tx, *model.Lease, time.Time) error) error {
+	var lease model.Lease
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := l.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	err = tx.NewSelect().Model(&lease).Where("? = ?", bun.Ident("ls.lid"), lid).
+		ApplyQueryBuilder(scope.ByLessor("ls.lessor_id")).For("UPDATE").Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = scope.resolveMissing(ctx, l.leaseExists(lid.String()), "Lease", lid.String(),
+				ErrNoResults{Shape: lease, Identifier: lid.String(), Err: err})
+		} else {
+			err = ErrFetchFailed{Model: "Lease", Err: err}
+		}
+	}
+
+	if err == nil {
+		err = change(tx, &lease, time.Now())
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+func (l *LeaseRepo) writeStatus(ctx context.Context, tx bun.Tx, lease *model.Lease) error {
+	_, err := tx.NewUpdate().Model(lease).
+		Column("status", "activated_at", "terminated_at", "termination_reason", "updated_at").WherePK().Exec(ctx)
+	if err != nil {
+		return ErrUpdateFailed{Model: "Lease", Err: err}
+	}
+	return nil
+}
+
+// checkParties makes sure the rental and every tenant on the lease belong to its lessor
+func (l *LeaseRepo) checkParties(ctx context.Context, scope TenantScope, lease *model.Lease) error {
+	if !scope.Owns(lease.LessorId) {
+		return ErrCrossTenant{Model: "Lease", Identifier: lease.Lid.String()}
+	}
+
+	owned, err := l.GetBunDB().NewSelect().Model((*model.RentalProperty)(nil)).Where("? = ?", bun.Ident("rp.pid"), lease.RentalId).
+		Where("rp.pid IN (SELECT pid FROM properties WHERE lessor_id = ?)", lease.LessorId).Exists(ctx)
+	if err != nil {
+		return ErrFetchFailed{Model: "Rental Property", Err: err}
+	}
+
+	if !owned {
+		return ErrCrossTenant{Model: "Lease", Identifier: lease.RentalId.String()}
+	}
+
+	found, err := l.GetBunDB().NewSelect().Model((*model.Tenant)(nil)).Where("tnt.uid IN (?)", bun.In(lease.TenantIds)).
+		Where("? = ?", bun.Ident("tnt.lessor_id"), lease.LessorId).Count(ctx)
+	if err != nil {
+		return ErrFetchFailed{Model: "Tenant", Err: err}
+	}
+
+	if found != len(lease.TenantIds) {
+		return ErrCrossTenant{Model: "Lease", Identifier: lease.Lid.String()}
+	}
+
+	return nil
+}
+
+// checkRenewable locks the lease being renewed so two renewals of it can't be saved at once
+func (l *LeaseRepo) checkRenewable(ctx context.Context, tx bun.Tx, scope TenantScope, lid uuid.UUID) error {
+	var original model.Lease
+	err := tx.NewSelect().Model(&original).Where("? = ?", bun.Ident("ls.lid"), lid).
+		ApplyQueryBuilder(scope.ByLessor("ls.lessor_id")).For("UPDATE").Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return scope.resolveMissing(ctx, l.leaseExists(lid.String()), "Lease", lid.String(),
+				ErrNoResults{Shape: original, Identifier: lid.String(), Err: err})
+		}
+		return ErrFetchFailed{Model: "Lease", Err: err}
+	}
+
+	if original.Status != model.LeaseActive && original.Status != model.LeaseExpired {
+		return ErrLeaseStatus
+	}
+
+	renewed, err := tx.NewSelect().Model((*model.Lease)(nil)).Where("? = ?", bun.Ident("ls.renewal_of"), lid).
+		Where("ls.status IN (?)", bun.In([]model.LeaseStatus{model.LeaseDraft, model.LeaseActive})).Exists(ctx)
+	if err != nil {
+		return ErrFetchFailed{Model: "Lease", Err: err}
+	}
+
+	if renewed {
+		return ErrLeaseRenewed
+	}
+
+	return nil
+}
+
+func (l *LeaseRepo) leaseExists(lid string) *bun.SelectQuery {
+	return l.GetBunDB().NewSelect().Model((*model.Lease)(nil)).Where("? = ?", bun.Ident("lid"), lid)
+}
+
+// syncRental keeps the lease fields on the rental in step with its current lease, the one
+// that is active and ends soonest. Without one the rental is left unsigned and the rest of
+// the fields keep the last lease's values
+func syncRental(ctx context.Context, tx bun.Tx, rentalId uuid.UUID, now time.Time) error {
+	var current model.Lease
+	err := tx.NewSelect().Model(&current).Where("? = ?", bun.Ident("ls.rental_id"), rentalId).
+		Where("? = ?", bun.Ident("ls.status"), model.LeaseActive).Where("ls.ends_at > ?", now).
+		Order("ls.starts_at ASC").Limit(1).Scan(ctx)
+
+	update := tx.NewUpdate().Model((*model.RentalProperty)(nil)).Where("? = ?", bun.Ident("pid"), rentalId)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		update = update.Set("lease_signed = ?", false)
+	case err != nil:
+		return ErrFetchFailed{Model: "Lease", Err: err}
+	default:
+		update = update.Set("lease_signed = ?", true).Set("lease_duration = ?", current.Months()).
+			Set("lease_renew_date = ?", current.EndsAt).Set("rental_price = ?", current.RentAmount)
+	}
+
+	if _, err = update.Exec(ctx); err != nil {
+		return ErrUpdateFailed{Model: "Rental Property", Err: err}
+	}
+
+	return nil
+}
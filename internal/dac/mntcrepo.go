@@ -0,0 +1,222 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// urgencyOrder puts the most urgent requests at the top of the triage queue
+const urgencyOrder = "CASE mr.urgency WHEN 'immediate' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 ELSE 3 END"
+
+type MaintenanceRepo struct {
+	Persister
+}
+
+func InitMaintenanceRepo(db Persister) MaintenanceRepo {
+	return MaintenanceRepo{
+		Persister: db,
+	}
+}
+
+func (m *MaintenanceRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
+	var req model.MaintenanceRequest
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.GetBunDB().NewSelect().Model(&req).Where("? = ?", bun.Ident("mr.mid"), fltr.Identifier).
+		ApplyQueryBuilder(scope.ByLessor("mr.lessor_id")).ApplyQueryBuilder(scope.ByTenant("mr.tenant_id")).
+		Relation("Tenant").Relation("Property").Relation("Task").Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, m.missing(ctx, scope, fltr.Identifier, err)
+		}
+		return nil, ErrFetchFailed{Model: "Maintenance Request", Err: err}
+	}
+
+	return req, nil
+}
+
+// FetchQueue returns a lessor's requests in the given status, most urgent and then oldest first
+func (m *MaintenanceRepo) FetchQueue(ctx context.Context, fltr filters.Filter, status model.MaintenanceStatus) ([]model.MaintenanceRequest, error) {
+	reqs := make([]model.MaintenanceRequest, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byLessor, err := scope.ForLessor("Maintenance Request", "mr.lessor_id", fltr.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := utils.DeterminRecordLimit(fltr.Limit)
+	err = m.GetBunDB().NewSelect().Model(&reqs).Where("? = ?", bun.Ident("mr.status"), status).
+		ApplyQueryBuilder(byLessor).Relation("Tenant").Relation("Property").Relation("Task").
+		OrderExpr(urgencyOrder).Order("mr.created_at ASC").Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &reqs)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Maintenance Request", Err: err}
+	}
+
+	return reqs, nil
+}
+
+// FetchSubmitted returns everything a tenant has reported, newest first
+func (m *MaintenanceRepo) FetchSubmitted(ctx context.Context, tenantId uuid.UUID, fltr filters.Filter) ([]model.MaintenanceRequest, error) {
+	reqs := make([]model.MaintenanceRequest, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := utils.DeterminRecordLimit(fltr.Limit)
+	err = m.GetBunDB().NewSelect().Model(&reqs).Where("? = ?", bun.Ident("mr.tenant_id"), tenantId).
+		ApplyQueryBuilder(scope.ByLessor("mr.lessor_id")).ApplyQueryBuilder(scope.ByTenant("mr.tenant_id")).
+		Relation("Property").Relation("Task").Order("mr.created_at DESC").Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &reqs)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Maintenance Request", Err: err}
+	}
+
+	return reqs, nil
+}
+
+func (m *MaintenanceRepo) Insert(ctx context.Context, req *model.MaintenanceRequest) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !scope.Owns(req.LessorId) {
+		return ErrCrossTenant{Model: "Maintenance Request", Identifier: req.Mid.String()}
+	}
+
+	if err = m.GetBunDB().NewInsert().Model(req).Returning("*").Scan(ctx, req); err != nil {
+		return ErrInsertFailed{Model: "Maintenance Request", Err: err}
+	}
+
+	return nil
+}
+
+// Convert creates the task for a request and links the two, only a request that is still
+// submitted can be converted so two lessors triaging at once can't make two tasks
+func (m *MaintenanceRepo) Convert(ctx context.Context, mid uuid.UUID, tk *model.Task) (model.MaintenanceRequest, error) {
+	var req model.MaintenanceRequest
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.MaintenanceRequest{}, err
+	}
+
+	if !scope.Owns(tk.LessorId) {
+		return model.MaintenanceRequest{}, ErrCrossTenant{Model: "Task", Identifier: tk.Tid.String()}
+	}
+
+	tx, err := m.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return model.MaintenanceRequest{}, ErrTransactionStartFailed{Err: err}
+	}
+
+	err = tx.NewUpdate().Model(&req).Set("status = ?", model.RequestConverted).Set("task_id = ?", tk.Tid).
+		Set("resolved_at = ?", time.Now()).Where("? = ?", bun.Ident("mr.mid"), mid).
+		Where("? = ?", bun.Ident("mr.status"), model.RequestSubmitted).
+		ApplyQueryBuilder(scope.ByLessor("mr.lessor_id")).Returning("*").Scan(ctx, &req)
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.MaintenanceRequest{}, ErrRollbackFailed{Err: rbErr}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.MaintenanceRequest{}, m.writeErr(ctx, scope, mid.String())
+		}
+		return model.MaintenanceRequest{}, ErrUpdateFailed{Model: "Maintenance Request", Err: err}
+	}
+
+	err = tx.NewInsert().Model(tk).Returning("*").Scan(ctx, tk)
+	if err == nil {
+		created := newTaskEvent(ctx, *tk, model.TaskCreated, map[string]interface{}{
+			"name":      tk.Name,
+			"priority":  tk.Priority,
+			"workerId":  tk.WorkerId,
+			"requestId": req.Mid,
+		})
+		err = insertTaskEvents(ctx, tx, []model.TaskEvent{created})
+	}
+
+	if err == nil && tk.WorkerId != uuid.Nil {
+		err = syncLead(ctx, tx, *tk)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.MaintenanceRequest{}, ErrRollbackFailed{Err: rbErr}
+		}
+		return model.MaintenanceRequest{}, ErrInsertFailed{Model: "Task", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.MaintenanceRequest{}, ErrTransactionCommitFail{Err: err}
+	}
+
+	req.Task = tk
+	return req, nil
+}
+
+// Close declines or withdraws a request that hasn't been converted yet, tenants can only
+// close their own requests
+func (m *MaintenanceRepo) Close(ctx context.Context, mid uuid.UUID, status model.MaintenanceStatus, reason string) (model.MaintenanceRequest, error) {
+	var req model.MaintenanceRequest
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.MaintenanceRequest{}, err
+	}
+
+	err = m.GetBunDB().NewUpdate().Model(&req).Set("status = ?", status).Set("declined_reason = ?", reason).
+		Set("resolved_at = ?", time.Now()).Where("? = ?", bun.Ident("mr.mid"), mid).
+		Where("? = ?", bun.Ident("mr.status"), model.RequestSubmitted).
+		ApplyQueryBuilder(scope.ByLessor("mr.lessor_id")).ApplyQueryBuilder(scope.ByTenant("mr.tenant_id")).
+		Returning("*").Scan(ctx, &req)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.MaintenanceRequest{}, m.writeErr(ctx, scope, mid.String())
+		}
+		return model.MaintenanceRequest{}, ErrUpdateFailed{Model: "Maintenance Request", Err: err}
+	}
+
+	return req, nil
+}
+
+// writeErr explains why a guarded status change matched nothing
+func (m *MaintenanceRepo) writeErr(ctx context.Context, scope TenantScope, mid string) error {
+	inScope, err := m.requestExists(mid).ApplyQueryBuilder(scope.ByLessor("mr.lessor_id")).
+		ApplyQueryBuilder(scope.ByTenant("mr.tenant_id")).Exists(ctx)
+	if err != nil {
+		return ErrFetchFailed{Model: "Maintenance Request", Err: err}
+	}
+
+	if inScope {
+		return ErrRequestResolved
+	}
+
+	return m.missing(ctx, scope, mid, sql.ErrNoRows)
+}
+
+func (m *MaintenanceRepo) missing(ctx context.Context, scope TenantScope, mid string, err error) error {
+	return scope.resolveMissing(ctx, m.requestExists(mid), "Maintenance Request", mid,
+		ErrNoResults{Shape: model.MaintenanceRequest{}, Identifier: mid, Err: err})
+}
+
+func (m *MaintenanceRepo) requestExists(mid string) *bun.SelectQuery {
+	return m.GetBunDB().NewSelect().Model((*model.MaintenanceRequest)(nil)).Where("? = ?", bun.Ident("mid"), mid)
+}
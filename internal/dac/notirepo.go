@@ -3,13 +3,16 @@ package dac
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/Z3DRP/lessor-service/internal/cmerr"
 	"github.com/Z3DRP/lessor-service/internal/filters"
 	"github.com/Z3DRP/lessor-service/internal/model"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 )
 
@@ -26,14 +29,21 @@ func InitNotificationRepo(db Persister) NotificationRepo {
 
 func (n *NotificationRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
 	var noti model.Notification
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := n.GetBunDB().NewSelect().Model(&noti).
-		Where("? = ?", bun.Ident("id"), fltr.Identifier).Relation("User").
+	err = n.GetBunDB().NewSelect().Model(&noti).
+		Where("? = ?", bun.Ident("notif.id"), fltr.Identifier).ApplyQueryBuilder(scope.ByLessor("notif.lessor_id")).
+		ApplyQueryBuilder(byRecipient(scope)).Relation("User").
 		Relation("Property").Relation("Task").Limit(limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, ErrNoResults{Shape: noti, Identifier: fltr.Identifier, Err: err}
+			return nil, scope.resolveMissing(ctx, n.notificationExists(fltr.Identifier), "Notification", fltr.Identifier,
+				ErrNoResults{Shape: noti, Identifier: fltr.Identifier, Err: err})
 		}
 		return nil, ErrFetchFailed{Model: "Notification", Err: err}
 	}
@@ -43,11 +53,15 @@ func (n *NotificationRepo) Fetch(ctx context.Context, fltr filters.Filter) (inte
 
 func (n *NotificationRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.Notification, error) {
 	var notifs []model.Notification
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
 
-	err := n.GetBunDB().NewSelect().Model(&notifs).
-		Where("? = ?", bun.Ident("notif.user_id"), fltr.Identifier).Where("void_at > ?", time.Now()).Where("not viewed").Relation("User").
-		Relation("Property").Relation("Task").Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &notifs)
+	err = unreadNotificationsQuery(n.GetBunDB(), &notifs, scope, fltr.Identifier, time.Now()).
+		Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &notifs)
 
 	// query := n.GetBunDB().NewSelect().Model(&notifs).
 	// 	Where("? = ?", bun.Ident("notif.user_id"), fltr.Identifier).Where("void_at > ?", time.Now()).Where("not viewed").Relation("User").
@@ -64,12 +78,50 @@ func (n *NotificationRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([
 	return notifs, nil
 }
 
+// unreadNotificationsQuery selects the user's unread notifications, the user id comes from
+// the request path so workers and tenants are still limited to their own
+func unreadNotificationsQuery(db bun.IDB, notifs *[]model.Notification, scope TenantScope, uid string, now time.Time) *bun.SelectQuery {
+	return db.NewSelect().Model(notifs).
+		Where("? = ?", bun.Ident("notif.user_id"), uid).ApplyQueryBuilder(scope.ByLessor("notif.lessor_id")).
+		ApplyQueryBuilder(byRecipient(scope)).Where("void_at > ?", now).Where("not viewed").Relation("User").
+		Relation("Property").Relation("Task")
+}
+
+// byRecipient limits workers and tenants to the notifications sent to them, lessors and
+// admins can see every notification in their scope
+func byRecipient(scope TenantScope) func(bun.QueryBuilder) bun.QueryBuilder {
+	return func(q bun.QueryBuilder) bun.QueryBuilder {
+		if scope.UserId == uuid.Nil {
+			return q
+		}
+		return scope.ByUser("notif.user_id")(q)
+	}
+}
+
+// CheckOwner fails unless the request may notify the lessor's users, Insert calls it and
+// notifications that are only sent outside the app check it on their own
+func (n *NotificationRepo) CheckOwner(ctx context.Context, lessorId uuid.UUID) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !scope.Owns(lessorId) {
+		return ErrCrossTenant{Model: "Notification", Identifier: lessorId.String()}
+	}
+	return nil
+}
+
 func (n *NotificationRepo) Insert(ctx context.Context, notif any) (interface{}, error) {
 	noti, ok := notif.(model.Notification)
 	if !ok {
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.Notification{}, Got: notif}
 	}
 
+	if err := n.CheckOwner(ctx, noti.LessorId); err != nil {
+		return nil, err
+	}
+
 	noti.VoidAt = time.Now().AddDate(0, 0, model.TtlDays)
 
 	tx, err := n.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
@@ -86,6 +138,18 @@ func (n *NotificationRepo) Insert(ctx context.Context, notif any) (interface{},
 		return nil, ErrInsertFailed{Model: "Notification", Err: err}
 	}
 
+	err = PublishNotification(ctx, tx, noti)
+	if err == nil {
+		err = EnqueueWebhook(ctx, tx, noti.LessorId, model.NotificationCreatedHook, noti)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, err
+	}
+
 	if err = tx.Commit(); err != nil {
 		log.Printf("error commiting transactio %v", err)
 		return nil, ErrTransactionCommitFail{err}
@@ -96,14 +160,25 @@ func (n *NotificationRepo) Insert(ctx context.Context, notif any) (interface{},
 
 func (n *NotificationRepo) UpdateViewed(ctx context.Context, notifId int) (interface{}, error) {
 	var noti model.Notification
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := n.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, ErrTransactionStartFailed{Err: err}
 	}
 
-	if err = tx.NewUpdate().Model(&noti).OmitZero().Set("viewed = ?", true).Where("? = ?", bun.Ident("id"), notifId).Returning("*").Scan(ctx, &noti); err != nil {
-		if err = tx.Rollback(); err != nil {
-			return nil, ErrRollbackFailed{err}
+	if err = tx.NewUpdate().Model(&noti).OmitZero().Set("viewed = ?", true).Where("? = ?", bun.Ident("id"), notifId).
+		ApplyQueryBuilder(scope.ByLessor("notif.lessor_id")).ApplyQueryBuilder(byRecipient(scope)).Returning("*").Scan(ctx, &noti); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			id := strconv.Itoa(notifId)
+			return nil, scope.resolveMissing(ctx, n.notificationExists(id), "Notification", id,
+				ErrNoResults{Shape: noti, Identifier: id, Err: err})
 		}
 		return nil, ErrUpdateFailed{Model: "Notification", Err: err}
 	}
@@ -114,3 +189,64 @@ func (n *NotificationRepo) UpdateViewed(ctx context.Context, notifId int) (inter
 
 	return noti, nil
 }
+
+// DeleteExpired removes every notification in scope whose void time has passed
+func (n *NotificationRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rslt, err := n.GetBunDB().NewDelete().Model((*model.Notification)(nil)).Where("? <= ?", bun.Ident("void_at"), now).
+		ApplyQueryBuilder(scope.ByLessor("notif.lessor_id")).Exec(ctx)
+	if err != nil {
+		return 0, ErrDeleteFailed{Model: "Notification", Err: err}
+	}
+
+	return rslt.RowsAffected()
+}
+
+// Undelivered returns the user's unread notifications that are waiting on a digest
+func (n *NotificationRepo) Undelivered(ctx context.Context, uid uuid.UUID, now time.Time) ([]model.Notification, error) {
+	var notifs []model.Notification
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = n.GetBunDB().NewSelect().Model(&notifs).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("? = ?", bun.Ident("notif.user_id"), uid).
+				WhereOr("? IS NULL AND ? = ?", bun.Ident("notif.user_id"), bun.Ident("notif.lessor_id"), uid)
+		}).ApplyQueryBuilder(scope.ByLessor("notif.lessor_id")).Where("? IS NULL", bun.Ident("notif.delivered_at")).
+		Where("not viewed").Where("? > ?", bun.Ident("notif.void_at"), now).Order("notif.created_at DESC").Scan(ctx)
+
+	if err != nil {
+		return nil, ErrFetchFailed{Model: "Notification", Err: err}
+	}
+
+	return notifs, nil
+}
+
+// MarkDelivered records that the notifications went out in a digest
+func (n *NotificationRepo) MarkDelivered(ctx context.Context, ids []int, now time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.GetBunDB().NewUpdate().Model((*model.Notification)(nil)).Set("delivered_at = ?", now).
+		Where("? IN (?)", bun.Ident("notif.id"), bun.In(ids)).ApplyQueryBuilder(scope.ByLessor("notif.lessor_id")).Exec(ctx)
+	if err != nil {
+		return ErrUpdateFailed{Model: "Notification", Err: err}
+	}
+	return nil
+}
+
+func (n *NotificationRepo) notificationExists(id string) *bun.SelectQuery {
+	return n.GetBunDB().NewSelect().Model((*model.Notification)(nil)).Where("? = ?", bun.Ident("id"), id)
+}
@@ -0,0 +1,42 @@
+package dac
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestUnreadNotificationsQuery(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	now := time.Date(2025, time.March, 3, 8, 0, 0, 0, time.UTC)
+	lessorId, workerId, otherUser := uuid.New(), uuid.New(), uuid.New()
+
+	var notifs []model.Notification
+
+	// a worker asking for another user's notifications only ever matches their own
+	worker := TenantScope{LessorId: lessorId, UserId: workerId}
+	query := unreadNotificationsQuery(db, &notifs, worker, otherUser.String(), now).String()
+
+	for _, expected := range []string{
+		`"notif"."user_id" = '` + otherUser.String() + `'`,
+		`"notif"."lessor_id" = '` + lessorId.String() + `'`,
+		`"notif"."user_id" = '` + workerId.String() + `'`,
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("expected query to contain %v, got %v", expected, query)
+		}
+	}
+
+	lessor := TenantScope{LessorId: lessorId}
+	query = unreadNotificationsQuery(db, &notifs, lessor, workerId.String(), now).String()
+	_, where, _ := strings.Cut(query, "WHERE")
+	if strings.Count(where, `"notif"."user_id"`) != 1 {
+		t.Errorf("expected a lessor to read any of their users' notifications, got %v", query)
+	}
+}
@@ -0,0 +1,125 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// NotificationSettingsRepo keeps each user's notification preferences, a user without a
+// row gets everything
+type NotificationSettingsRepo struct {
+	Persister
+}
+
+func InitNotificationSettingsRepo(db Persister) NotificationSettingsRepo {
+	return NotificationSettingsRepo{
+		Persister: db,
+	}
+}
+
+// Fetch returns the user's settings for the settings endpoints, users can only see their
+// own while lessors and admins can see the users they manage
+func (n *NotificationSettingsRepo) Fetch(ctx context.Context, uid uuid.UUID) (model.NotificationSettings, error) {
+	if err := n.checkUser(ctx, uid); err != nil {
+		return model.NotificationSettings{}, err
+	}
+
+	prefs, err := n.Preferences(ctx, uid)
+	if err != nil {
+		return model.NotificationSettings{}, err
+	}
+
+	return model.NotificationSettings{Uid: uid, Setting: prefs}, nil
+}
+
+func (n *NotificationSettingsRepo) Upsert(ctx context.Context, settings *model.NotificationSettings) error {
+	if err := n.checkUser(ctx, settings.Uid); err != nil {
+		return err
+	}
+
+	err := n.GetBunDB().NewInsert().Model(settings).
+		On("CONFLICT (uid) DO UPDATE").Set("setting = EXCLUDED.setting").
+		Returning("*").Scan(ctx, settings)
+
+	if err != nil {
+		return ErrUpdateFailed{Model: "Notification Settings", Err: err}
+	}
+	return nil
+}
+
+// Preferences is used when a notification is sent, it isn't scoped since whoever triggers
+// the notification rarely owns the recipient's settings
+func (n *NotificationSettingsRepo) Preferences(ctx context.Context, uid uuid.UUID) (model.NotificationPreferences, error) {
+	var settings model.NotificationSettings
+	err := n.GetBunDB().NewSelect().Model(&settings).Where("? = ?", bun.Ident("ns.uid"), uid).Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.NotificationPreferences{}, nil
+		}
+		return model.NotificationPreferences{}, ErrFetchFailed{Model: "Notification Settings", Err: err}
+	}
+
+	return settings.Setting, nil
+}
+
+// Digesting returns the settings of every user who gets digests, it is only used by the
+// digest job so it isn't scoped
+func (n *NotificationSettingsRepo) Digesting(ctx context.Context) ([]model.NotificationSettings, error) {
+	var settings []model.NotificationSettings
+	err := n.GetBunDB().NewSelect().Model(&settings).
+		Where("? ->> 'digest' IN (?)", bun.Ident("ns.setting"), bun.In([]model.DigestFrequency{model.HourlyDigest, model.DailyDigest})).Scan(ctx)
+
+	if err != nil {
+		return nil, ErrFetchFailed{Model: "Notification Settings", Err: err}
+	}
+	return settings, nil
+}
+
+func (n *NotificationSettingsRepo) DigestSent(ctx context.Context, uid uuid.UUID, at time.Time) error {
+	_, err := n.GetBunDB().NewUpdate().Model((*model.NotificationSettings)(nil)).Set("last_digest_at = ?", at).
+		Where("? = ?", bun.Ident("ns.uid"), uid).Exec(ctx)
+
+	if err != nil {
+		return ErrUpdateFailed{Model: "Notification Settings", Err: err}
+	}
+	return nil
+}
+
+func (n *NotificationSettingsRepo) checkUser(ctx context.Context, uid uuid.UUID) error {
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return ErrMissingScope{Err: err}
+	}
+
+	if claims.Id == uid.String() || claims.HasRole(auth.Admin) {
+		return nil
+	}
+
+	if claims.HasRole(auth.Alessor) {
+		scope, err := NewTenantScope(ctx)
+		if err != nil {
+			return err
+		}
+
+		found, err := n.GetBunDB().NewSelect().Model((*model.User)(nil)).Where("? = ?", bun.Ident("u.uid"), uid).
+			ApplyQueryBuilder(scope.ByUser("u.uid")).Exists(ctx)
+
+		if err != nil {
+			return ErrFetchFailed{Model: "User", Err: err}
+		}
+
+		if found {
+			return nil
+		}
+	}
+
+	return ErrCrossTenant{Model: "Notification Settings", Identifier: uid.String()}
+}
@@ -0,0 +1,205 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// PayrollInputs is everything a pay period is worked out from. Entries are the approved entries
+// not paid by an earlier run and Tasks are the flat fee tasks finished in the period. Earlier are
+// the approved entries, paid or not, from the week before the period so a week the period starts
+// part way through still counts all of its hours towards overtime
+type PayrollInputs struct {
+	Workers []model.Worker
+	Entries []model.TimeEntry
+	Earlier []model.TimeEntry
+	Tasks   []model.Task
+}
+
+type PayrollRepo struct {
+	Persister
+}
+
+func InitPayrollRepo(db Persister) PayrollRepo {
+	return PayrollRepo{
+		Persister: db,
+	}
+}
+
+func (p *PayrollRepo) FetchInputs(ctx context.Context, lessorId uuid.UUID, from time.Time, to time.Time) (PayrollInputs, error) {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return PayrollInputs{}, err
+	}
+
+	if err = scope.Check("Payroll", lessorId.String()); err != nil {
+		return PayrollInputs{}, err
+	}
+
+	in := PayrollInputs{
+		Workers: make([]model.Worker, 0),
+		Entries: make([]model.TimeEntry, 0),
+		Earlier: make([]model.TimeEntry, 0),
+		Tasks:   make([]model.Task, 0),
+	}
+
+	err = p.GetBunDB().NewSelect().Model(&in.Workers).Where("? = ?", bun.Ident("w.lessor_id"), lessorId).
+		Relation("User").Scan(ctx, &in.Workers)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return PayrollInputs{}, ErrFetchFailed{Model: "worker", Err: err}
+	}
+
+	err = p.GetBunDB().NewSelect().Model(&in.Entries).Where("? = ?", bun.Ident("tme.lessor_id"), lessorId).
+		Where("? = ?", bun.Ident("tme.status"), model.EntryApproved).Where("? IS NULL", bun.Ident("tme.payroll_run_id")).
+		Where("? >= ?", bun.Ident("tme.clock_in"), from).Where("? < ?", bun.Ident("tme.clock_in"), to).
+		Relation("Task").Order("tme.clock_in ASC").Scan(ctx, &in.Entries)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return PayrollInputs{}, ErrFetchFailed{Model: "Time Entry", Err: err}
+	}
+
+	// a week never starts more than 7 days before the period in any timezone
+	err = p.GetBunDB().NewSelect().Model(&in.Earlier).Where("? = ?", bun.Ident("tme.lessor_id"), lessorId).
+		Where("? = ?", bun.Ident("tme.status"), model.EntryApproved).
+		Where("? >= ?", bun.Ident("tme.clock_in"), from.AddDate(0, 0, -7)).Where("? < ?", bun.Ident("tme.clock_in"), from).
+		Relation("Task").Scan(ctx, &in.Earlier)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return PayrollInputs{}, ErrFetchFailed{Model: "Time Entry", Err: err}
+	}
+
+	err = p.GetBunDB().NewSelect().Model(&in.Tasks).Where("? = ?", bun.Ident("tsk.lessor_id"), lessorId).
+		Where("? = ?", bun.Ident("tsk.status"), model.Finished).Where("? > 0", bun.Ident("tsk.flat_fee")).
+		Where("? >= ?", bun.Ident("tsk.completed_at"), from).Where("? < ?", bun.Ident("tsk.completed_at"), to).
+		Order("tsk.completed_at ASC").Scan(ctx, &in.Tasks)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return PayrollInputs{}, ErrFetchFailed{Model: "Task", Err: err}
+	}
+
+	return in, nil
+}
+
+// Close stores the run and its items and marks the entries as paid by it. The lessor row is
+// locked so two runs for the same lessor can't both pass the overlap check
+func (p *PayrollRepo) Close(ctx context.Context, run *model.PayrollRun, entryIds []uuid.UUID) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = scope.Check("Payroll", run.LessorId.String()); err != nil {
+		return err
+	}
+
+	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	var id int64
+	err = tx.NewSelect().Model((*model.Alessor)(nil)).Column("alsr.id").Where("? = ?", bun.Ident("alsr.uid"), run.LessorId).
+		For("UPDATE").Scan(ctx, &id)
+	if err != nil {
+		err = ErrFetchFailed{Model: "Alessor", Err: err}
+	}
+
+	if err == nil {
+		var closed bool
+		closed, err = tx.NewSelect().Model((*model.PayrollRun)(nil)).Where("? = ?", bun.Ident("pr.lessor_id"), run.LessorId).
+			Where("? < ?", bun.Ident("pr.period_start"), run.PeriodEnd).Where("? > ?", bun.Ident("pr.period_end"), run.PeriodStart).
+			Exists(ctx)
+		if err != nil {
+			err = ErrFetchFailed{Model: "Payroll Run", Err: err}
+		} else if closed {
+			err = ErrPeriodClosed
+		}
+	}
+
+	if err == nil {
+		if err = tx.NewInsert().Model(run).Returning("*").Scan(ctx, run); err != nil {
+			err = ErrInsertFailed{Model: "Payroll Run", Err: err}
+		}
+	}
+
+	if err == nil && len(run.Items) > 0 {
+		if _, err = tx.NewInsert().Model(&run.Items).Exec(ctx); err != nil {
+			err = ErrInsertFailed{Model: "Payroll Item", Err: err}
+		}
+	}
+
+	if err == nil && len(entryIds) > 0 {
+		var rslt sql.Result
+		rslt, err = tx.NewUpdate().Model((*model.TimeEntry)(nil)).Set("payroll_run_id = ?", run.Rid).
+			Where("? IN (?)", bun.Ident("tme.eid"), bun.In(entryIds)).Where("? IS NULL", bun.Ident("tme.payroll_run_id")).
+			Where("? = ?", bun.Ident("tme.status"), model.EntryApproved).Exec(ctx)
+		if err != nil {
+			err = ErrUpdateFailed{Model: "Time Entry", Err: err}
+		} else if n, _ := rslt.RowsAffected(); int(n) != len(entryIds) {
+			// an entry was paid or changed after the run was worked out
+			err = ErrStaleWrite
+		}
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+func (p *PayrollRepo) FetchRun(ctx context.Context, rid uuid.UUID) (model.PayrollRun, error) {
+	var run model.PayrollRun
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.PayrollRun{}, err
+	}
+
+	err = p.GetBunDB().NewSelect().Model(&run).Where("? = ?", bun.Ident("pr.rid"), rid).
+		ApplyQueryBuilder(scope.ByLessor("pr.lessor_id")).
+		Relation("Items", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("pi.payment_method ASC", "pi.worker_name ASC")
+		}).Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			exists := p.GetBunDB().NewSelect().Model((*model.PayrollRun)(nil)).Where("? = ?", bun.Ident("rid"), rid)
+			return model.PayrollRun{}, scope.resolveMissing(ctx, exists, "Payroll Run", rid.String(),
+				ErrNoResults{Shape: run, Identifier: rid.String(), Err: err})
+		}
+		return model.PayrollRun{}, ErrFetchFailed{Model: "Payroll Run", Err: err}
+	}
+
+	return run, nil
+}
+
+// FetchRuns lists the lessor's closed periods newest first without their items
+func (p *PayrollRepo) FetchRuns(ctx context.Context, lessorId uuid.UUID) ([]model.PayrollRun, error) {
+	runs := make([]model.PayrollRun, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = scope.Check("Payroll", lessorId.String()); err != nil {
+		return nil, err
+	}
+
+	err = p.GetBunDB().NewSelect().Model(&runs).Where("? = ?", bun.Ident("pr.lessor_id"), lessorId).
+		Order("pr.period_start DESC").Scan(ctx, &runs)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Payroll Run", Err: err}
+	}
+
+	return runs, nil
+}
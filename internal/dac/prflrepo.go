@@ -23,8 +23,13 @@ func InitPrflRepo(db Persister) ProfileRepo {
 
 func (p *ProfileRepo) Fetch(ctx context.Context, fltr filters.UuidFilter) (interface{}, error) {
 	var prfl model.Profile
-	err := p.GetBunDB().NewSelect().Model(&prfl).
-		Where("? = ?", bun.Ident("uid"), fltr.Identifier).Limit(p.Limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.GetBunDB().NewSelect().Model(&prfl).
+		Where("? = ?", bun.Ident("uid"), fltr.Identifier).ApplyQueryBuilder(scope.ByUser("prf.uid")).Limit(p.Limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
 	if err != nil {
 		return nil, ErrFetchFailed{Model: "Profile", Err: err}
 	}
@@ -34,7 +39,12 @@ func (p *ProfileRepo) Fetch(ctx context.Context, fltr filters.UuidFilter) (inter
 
 func (p *ProfileRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.Profile, error) {
 	var prfls []model.Profile
-	err := p.GetBunDB().NewSelect().Model(&prfls).Limit(p.Limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.GetBunDB().NewSelect().Model(&prfls).ApplyQueryBuilder(scope.ByUser("prf.uid")).Limit(p.Limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
 
 	if err != nil {
 		return nil, ErrFetchFailed{Model: "Profile", Err: err}
@@ -74,12 +84,17 @@ func (p *ProfileRepo) Update(ctx context.Context, prfl any) (interface{}, error)
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.Profile{}, Got: prfl}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, ErrTransactionStartFailed{Err: err}
 	}
 
-	rslt, err := tx.NewUpdate().Model(&pf).Where("? = ?", bun.Ident("uid"), pf.Uid).Returning("*").Exec(ctx)
+	rslt, err := tx.NewUpdate().Model(&pf).Where("? = ?", bun.Ident("uid"), pf.Uid).ApplyQueryBuilder(scope.ByUser("prf.uid")).Returning("*").Exec(ctx)
 	if err != nil {
 		if err = tx.Rollback(); err != nil {
 			return model.Profile{}, err
@@ -100,12 +115,17 @@ func (p *ProfileRepo) Delete(ctx context.Context, prfl any) error {
 		return cmerr.ErrUnexpectedData{Wanted: model.Profile{}, Got: prfl}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return ErrTransactionStartFailed{Err: err}
 	}
 
-	_, err = tx.NewDelete().Model(&pf).Where("? = ?", bun.Ident("uid"), pf.Uid).Exec(ctx)
+	_, err = tx.NewDelete().Model(&pf).Where("? = ?", bun.Ident("uid"), pf.Uid).ApplyQueryBuilder(scope.ByUser("prf.uid")).Exec(ctx)
 	if err != nil {
 		if err = tx.Rollback(); err != nil {
 			return err
@@ -26,13 +26,20 @@ func InitPrptyRepo(db Persister) PropertyRepo {
 
 func (p *PropertyRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
 	var prpty model.Property
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := p.GetBunDB().NewSelect().Model(&prpty).
-		Where("? = ?", bun.Ident("pid"), fltr.Identifier).Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &prpty)
+	err = p.GetBunDB().NewSelect().Model(&prpty).
+		Where("? = ?", bun.Ident("pid"), fltr.Identifier).ApplyQueryBuilder(scope.ByLessor("p.lessor_id")).
+		Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &prpty)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, ErrNoResults{Shape: "Property", Identifier: fltr.Identifier, Err: err}
+			return nil, scope.resolveMissing(ctx, p.propertyExists(fltr.Identifier), "Property", fltr.Identifier,
+				ErrNoResults{Shape: "Property", Identifier: fltr.Identifier, Err: err})
 		}
 		return nil, ErrFetchFailed{Model: "Property", Err: err}
 	}
@@ -42,8 +49,18 @@ func (p *PropertyRepo) Fetch(ctx context.Context, fltr filters.Filter) (interfac
 
 func (p *PropertyRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.Property, error) {
 	var propertys []model.Property
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byLessor, err := scope.ForLessor("Property", "p.lessor_id", fltr.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := p.GetBunDB().NewSelect().Model(&propertys).Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &propertys)
+	err = p.GetBunDB().NewSelect().Model(&propertys).ApplyQueryBuilder(byLessor).Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &propertys)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -62,6 +79,15 @@ func (p *PropertyRepo) Insert(ctx context.Context, prpty any) (interface{}, erro
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.Property{}, Got: prpty}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scope.Owns(property.LessorId) {
+		return nil, ErrCrossTenant{Model: "Property", Identifier: property.Pid.String()}
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 
 	if err != nil {
@@ -95,6 +121,11 @@ func (p *PropertyRepo) Update(ctx context.Context, prpty any) (interface{}, erro
 		return model.Property{}, cmerr.ErrUnexpectedData{Wanted: model.Property{}, Got: prpty}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Property{}, err
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 
 	if err != nil {
@@ -102,12 +133,17 @@ func (p *PropertyRepo) Update(ctx context.Context, prpty any) (interface{}, erro
 		return model.Property{}, ErrTransactionStartFailed{Err: err}
 	}
 
-	err = tx.NewUpdate().Model(&property).OmitZero().Where("? = ?", bun.Ident("pid"), property.Pid).Returning("*").Scan(ctx, &property)
+	err = tx.NewUpdate().Model(&property).OmitZero().Where("? = ?", bun.Ident("pid"), property.Pid).ApplyQueryBuilder(scope.ByLessor("p.lessor_id")).
+		Returning("*").Scan(ctx, &property)
 
 	if err != nil {
 		log.Printf("failed to update %v", err)
-		if err = tx.Rollback(); err != nil {
-			return nil, err
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, rbErr
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, scope.resolveMissing(ctx, p.propertyExists(property.Pid.String()), "Property", property.Pid.String(),
+				ErrNoResults{Shape: "Property", Identifier: property.Pid.String(), Err: err})
 		}
 		return nil, err
 	}
@@ -115,6 +151,13 @@ func (p *PropertyRepo) Update(ctx context.Context, prpty any) (interface{}, erro
 	log.Printf("property returned from update %v", property)
 	log.Printf("result from property update %v", property)
 
+	if err = EnqueueWebhook(ctx, tx, property.LessorId, model.PropertyUpdatedHook, property); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, err
+	}
+
 	if err = tx.Commit(); err != nil {
 		log.Printf("failed to commit transaction %v", err)
 		return model.Property{}, err
@@ -131,13 +174,18 @@ func (p *PropertyRepo) Delete(ctx context.Context, prpty any) error {
 		return cmerr.ErrUnexpectedData{Wanted: model.Property{}, Got: prpty}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 
 	if err != nil {
 		return ErrTransactionStartFailed{Err: err}
 	}
 
-	_, err = tx.NewDelete().Model(&property).Where("? = ?", bun.Ident("pid"), property.Pid).Exec(ctx)
+	rslt, err := tx.NewDelete().Model(&property).Where("? = ?", bun.Ident("pid"), property.Pid).ApplyQueryBuilder(scope.ByLessor("p.lessor_id")).Exec(ctx)
 	if err != nil {
 		var pgErr pgdriver.Error
 		var sqlErr error
@@ -157,6 +205,14 @@ func (p *PropertyRepo) Delete(ctx context.Context, prpty any) error {
 		return sqlErr
 	}
 
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if err = tx.Rollback(); err != nil {
+			return err
+		}
+		return scope.resolveMissing(ctx, p.propertyExists(property.Pid.String()), "Property", property.Pid.String(),
+			ErrNoResults{Shape: "Property", Identifier: property.Pid.String(), Err: sql.ErrNoRows})
+	}
+
 	if err = tx.Commit(); err != nil {
 		return err
 	}
@@ -165,7 +221,13 @@ func (p *PropertyRepo) Delete(ctx context.Context, prpty any) error {
 
 func (p *PropertyRepo) GetExisting(ctx context.Context, pid string) (model.Property, error) {
 	var property model.Property
-	err := p.GetBunDB().NewSelect().Model(&property).Column("pid", "image", "address").Where("? = ?", bun.Ident("pid"), pid).Scan(ctx, &property)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Property{}, err
+	}
+
+	err = p.GetBunDB().NewSelect().Model(&property).Column("pid", "image", "address").Where("? = ?", bun.Ident("pid"), pid).
+		ApplyQueryBuilder(scope.ByLessor("p.lessor_id")).Scan(ctx, &property)
 
 	if err != nil {
 		return model.Property{}, err
@@ -173,3 +235,7 @@ func (p *PropertyRepo) GetExisting(ctx context.Context, pid string) (model.Prope
 
 	return property, nil
 }
+
+func (p *PropertyRepo) propertyExists(pid string) *bun.SelectQuery {
+	return p.GetBunDB().NewSelect().Model((*model.Property)(nil)).Where("? = ?", bun.Ident("pid"), pid)
+}
@@ -10,6 +10,25 @@ import (
 
 var ErrSqlNoData = errors.New("query returned no roles")
 var ErrIntegrityViolation = errors.New("integrity error")
+var ErrInviteInvalid = errors.New("invite is invalid, expired or already accepted")
+var ErrTokenReused = errors.New("refresh token has already been used")
+var ErrStaleWrite = errors.New("row was changed by another request")
+var ErrChargeHasPayments = errors.New("charge already has payments applied to it")
+var ErrPaymentReversed = errors.New("payment has already been reversed")
+var ErrRequestResolved = errors.New("maintenance request has already been resolved")
+var ErrAlreadyClockedIn = errors.New("worker is already clocked in")
+var ErrNotClockedIn = errors.New("worker is not clocked in on this task")
+var ErrEntryOverlap = errors.New("time entry overlaps another entry for the worker")
+var ErrEntryReviewed = errors.New("time entry has already been reviewed")
+var ErrEntryNotPending = errors.New("only clocked out entries waiting for review can be approved or rejected")
+var ErrBreakState = errors.New("break has already been started or was never started")
+var ErrTaskClosed = errors.New("task is already finished or failed")
+var ErrNotOnCrew = errors.New("worker is not on the task's crew")
+var ErrPeriodClosed = errors.New("payroll period overlaps a period that has already been closed")
+var ErrFeeProcessed = errors.New("fee has already been processed and can no longer change")
+var ErrLeaseOverlap = errors.New("lease overlaps another active lease on the rental")
+var ErrLeaseStatus = errors.New("lease can not be changed from its current status")
+var ErrLeaseRenewed = errors.New("lease already has a renewal")
 
 type Fetcher interface {
 	Fetch(context.Context, filters.Filterer) (interface{}, error)
@@ -61,6 +80,27 @@ func (e ErrNoResults) Unwrap() error {
 	return e.Err
 }
 
+type ErrCrossTenant struct {
+	Model      string
+	Identifier string
+}
+
+func (e ErrCrossTenant) Error() string {
+	return fmt.Sprintf("access denied to %v : %v belongs to another lessor", e.Model, e.Identifier)
+}
+
+type ErrMissingScope struct {
+	Err error
+}
+
+func (e ErrMissingScope) Error() string {
+	return fmt.Sprintf("could not determine lessor scope for request: %v", e.Err)
+}
+
+func (e ErrMissingScope) Unwrap() error {
+	return e.Err
+}
+
 type ErrInsertFailed struct {
 	Model string
 	Err   error
@@ -3,6 +3,7 @@ package dac
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
 
 	"github.com/Z3DRP/lessor-service/internal/cmerr"
@@ -24,13 +25,20 @@ func InitRentalPrptyRepo(db Persister) RentalPropertyRepo {
 
 func (p *RentalPropertyRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
 	var prpty model.RentalProperty
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := p.GetBunDB().NewSelect().Model(&prpty).
-		Where("? = ?", bun.Ident("pid"), fltr.Identifier).Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &prpty)
+	err = p.GetBunDB().NewSelect().Model(&prpty).
+		Where("? = ?", bun.Ident("pid"), fltr.Identifier).ApplyQueryBuilder(scope.ByProperty("rp.pid")).
+		Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &prpty)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, ErrNoResults{Shape: "Rental Property", Identifier: fltr.Identifier, Err: err}
+			return nil, scope.resolveMissing(ctx, p.rentalExists(fltr.Identifier), "Rental Property", fltr.Identifier,
+				ErrNoResults{Shape: "Rental Property", Identifier: fltr.Identifier, Err: err})
 		}
 		return nil, ErrFetchFailed{Model: "Rental Property", Err: err}
 	}
@@ -40,8 +48,13 @@ func (p *RentalPropertyRepo) Fetch(ctx context.Context, fltr filters.Filter) (in
 
 func (p *RentalPropertyRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.RentalProperty, error) {
 	var propertys []model.RentalProperty
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := p.GetBunDB().NewSelect().Model(&propertys).Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &propertys)
+	err = p.GetBunDB().NewSelect().Model(&propertys).ApplyQueryBuilder(scope.ByProperty("rp.pid")).Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &propertys)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -60,6 +73,22 @@ func (p *RentalPropertyRepo) Insert(ctx context.Context, prpty any) (interface{}
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.RentalProperty{}, Got: prpty}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// a rental has no lessor of its own so the property it lists must be in scope
+	owned, err := p.GetBunDB().NewSelect().Model((*model.Property)(nil)).Where("? = ?", bun.Ident("pid"), property.Pid).
+		ApplyQueryBuilder(scope.ByLessor("p.lessor_id")).Exists(ctx)
+	if err != nil {
+		return nil, ErrFetchFailed{Model: "Property", Err: err}
+	}
+
+	if !owned {
+		return nil, ErrCrossTenant{Model: "Rental Property", Identifier: property.Pid.String()}
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 
 	if err != nil {
@@ -93,6 +122,11 @@ func (p *RentalPropertyRepo) Update(ctx context.Context, prpty any) (interface{}
 		return model.RentalProperty{}, cmerr.ErrUnexpectedData{Wanted: model.RentalProperty{}, Got: prpty}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.RentalProperty{}, err
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 
 	if err != nil {
@@ -100,12 +134,17 @@ func (p *RentalPropertyRepo) Update(ctx context.Context, prpty any) (interface{}
 		return model.RentalProperty{}, ErrTransactionStartFailed{Err: err}
 	}
 
-	err = tx.NewUpdate().Model(&property).OmitZero().Where("? = ?", bun.Ident("pid"), property.Pid).Returning("*").Scan(ctx, &property)
+	err = tx.NewUpdate().Model(&property).OmitZero().Where("? = ?", bun.Ident("pid"), property.Pid).ApplyQueryBuilder(scope.ByProperty("rp.pid")).
+		Returning("*").Scan(ctx, &property)
 
 	if err != nil {
 		log.Printf("failed to update %v", err)
-		if err = tx.Rollback(); err != nil {
-			return nil, err
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, rbErr
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, scope.resolveMissing(ctx, p.rentalExists(property.Pid.String()), "Rental Property", property.Pid.String(),
+				ErrNoResults{Shape: "Rental Property", Identifier: property.Pid.String(), Err: err})
 		}
 		return nil, err
 	}
@@ -125,13 +164,18 @@ func (p *RentalPropertyRepo) Delete(ctx context.Context, prpty any) error {
 		return cmerr.ErrUnexpectedData{Wanted: model.RentalProperty{}, Got: prpty}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 
 	if err != nil {
 		return ErrTransactionStartFailed{Err: err}
 	}
 
-	_, err = tx.NewDelete().Model(&property).Where("? = ?", bun.Ident("pid"), property.Pid).Exec(ctx)
+	rslt, err := tx.NewDelete().Model(&property).Where("? = ?", bun.Ident("pid"), property.Pid).ApplyQueryBuilder(scope.ByProperty("rp.pid")).Exec(ctx)
 
 	if err != nil {
 		if err = tx.Rollback(); err != nil {
@@ -140,6 +184,14 @@ func (p *RentalPropertyRepo) Delete(ctx context.Context, prpty any) error {
 		return ErrDeleteFailed{Model: "User", Err: err}
 	}
 
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if err = tx.Rollback(); err != nil {
+			return err
+		}
+		return scope.resolveMissing(ctx, p.rentalExists(property.Pid.String()), "Rental Property", property.Pid.String(),
+			ErrNoResults{Shape: "Rental Property", Identifier: property.Pid.String(), Err: sql.ErrNoRows})
+	}
+
 	if err = tx.Commit(); err != nil {
 		return err
 	}
@@ -148,7 +200,13 @@ func (p *RentalPropertyRepo) Delete(ctx context.Context, prpty any) error {
 
 func (p *RentalPropertyRepo) GetExisting(ctx context.Context, pid string) (model.RentalProperty, error) {
 	var property model.RentalProperty
-	err := p.GetBunDB().NewSelect().Model(&property).Column("pid").Where("? = ?", bun.Ident("pid"), pid).Scan(ctx, &property)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.RentalProperty{}, err
+	}
+
+	err = p.GetBunDB().NewSelect().Model(&property).Column("pid").Where("? = ?", bun.Ident("pid"), pid).
+		ApplyQueryBuilder(scope.ByProperty("rp.pid")).Scan(ctx, &property)
 
 	if err != nil {
 		return model.RentalProperty{}, err
@@ -156,3 +214,7 @@ func (p *RentalPropertyRepo) GetExisting(ctx context.Context, pid string) (model
 
 	return property, nil
 }
+
+func (p *RentalPropertyRepo) rentalExists(pid string) *bun.SelectQuery {
+	return p.GetBunDB().NewSelect().Model((*model.RentalProperty)(nil)).Where("? = ?", bun.Ident("pid"), pid)
+}
@@ -0,0 +1,382 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type ScheduleRepo struct {
+	Persister
+}
+
+func InitScheduleRepo(db Persister) ScheduleRepo {
+	return ScheduleRepo{
+		Persister: db,
+	}
+}
+
+func (s *ScheduleRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
+	var sched model.TaskSchedule
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.GetBunDB().NewSelect().Model(&sched).Where("? = ?", bun.Ident("tsch.sid"), fltr.Identifier).
+		ApplyQueryBuilder(scope.ByLessor("tsch.lessor_id")).Relation("Property").Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, scope.resolveMissing(ctx, s.scheduleExists(fltr.Identifier), "Task Schedule", fltr.Identifier,
+				ErrNoResults{Shape: sched, Identifier: fltr.Identifier, Err: err})
+		}
+		return nil, ErrFetchFailed{Model: "Task Schedule", Err: err}
+	}
+
+	return sched, nil
+}
+
+// FetchAll returns the active schedules for the property named by the filter
+func (s *ScheduleRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.TaskSchedule, error) {
+	scheds := make([]model.TaskSchedule, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := utils.DeterminRecordLimit(fltr.Limit)
+	err = s.GetBunDB().NewSelect().Model(&scheds).Where("? = ?", bun.Ident("tsch.property_id"), fltr.Identifier).
+		Where("tsch.cancelled_at IS NULL").ApplyQueryBuilder(scope.ByLessor("tsch.lessor_id")).
+		Order("tsch.starts_at ASC").Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &scheds)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Task Schedule", Err: err}
+	}
+
+	return scheds, nil
+}
+
+func (s *ScheduleRepo) Insert(ctx context.Context, schd any) (interface{}, error) {
+	sched, ok := schd.(*model.TaskSchedule)
+	if !ok {
+		return nil, cmerr.ErrUnexpectedData{Wanted: model.TaskSchedule{}, Got: schd}
+	}
+
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.checkProperty(ctx, scope, sched); err != nil {
+		return nil, err
+	}
+
+	if err = s.GetBunDB().NewInsert().Model(sched).Returning("*").Scan(ctx, sched); err != nil {
+		return nil, ErrInsertFailed{Model: "Task Schedule", Err: err}
+	}
+
+	return sched, nil
+}
+
+// FetchDue returns every active schedule that hasn't been generated through the horizon yet
+func (s *ScheduleRepo) FetchDue(ctx context.Context, horizon time.Time) ([]model.TaskSchedule, error) {
+	scheds := make([]model.TaskSchedule, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.GetBunDB().NewSelect().Model(&scheds).Where("tsch.cancelled_at IS NULL").
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("tsch.generated_through IS NULL").WhereOr("tsch.generated_through < ?", horizon)
+		}).ApplyQueryBuilder(scope.ByLessor("tsch.lessor_id")).Scan(ctx, &scheds)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Task Schedule", Err: err}
+	}
+
+	return scheds, nil
+}
+
+// Materialize inserts the generated tasks and moves the schedule's generated through mark,
+// occurrences that already have a task are skipped. The tasks actually created are returned
+func (s *ScheduleRepo) Materialize(ctx context.Context, sched model.TaskSchedule, tasks []model.Task, through time.Time) ([]model.Task, error) {
+	created := make([]model.Task, 0, len(tasks))
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scope.Owns(sched.LessorId) {
+		return nil, ErrCrossTenant{Model: "Task Schedule", Identifier: sched.Sid.String()}
+	}
+
+	tx, err := s.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, ErrTransactionStartFailed{Err: err}
+	}
+
+	if len(tasks) > 0 {
+		err = tx.NewInsert().Model(&tasks).On("CONFLICT (schedule_id, occurrence_at) DO NOTHING").
+			Returning("*").Scan(ctx, &created)
+
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return nil, ErrRollbackFailed{rbErr}
+			}
+			return nil, ErrInsertFailed{Model: "Task", Err: err}
+		}
+	}
+
+	events := make([]model.TaskEvent, 0, len(created))
+	for _, tk := range created {
+		events = append(events, newTaskEvent(ctx, tk, model.TaskCreated, map[string]interface{}{
+			"scheduleId":   tk.ScheduleId,
+			"occurrenceAt": tk.OccurrenceAt,
+		}))
+	}
+
+	if err = insertTaskEvents(ctx, tx, events); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, err
+	}
+
+	// the schedule's worker leads each occurrence, so they need a crew row to respond with
+	for _, tk := range created {
+		if tk.WorkerId == uuid.Nil {
+			continue
+		}
+
+		if err = syncLead(ctx, tx, tk); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return nil, ErrRollbackFailed{rbErr}
+			}
+			return nil, err
+		}
+	}
+
+	_, err = tx.NewUpdate().Model((*model.TaskSchedule)(nil)).Set("generated_through = ?", through).
+		Where("? = ?", bun.Ident("sid"), sched.Sid).Exec(ctx)
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, ErrUpdateFailed{Model: "Task Schedule", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, ErrTransactionCommitFail{err}
+	}
+
+	return created, nil
+}
+
+func (s *ScheduleRepo) FetchOccurrence(ctx context.Context, sid uuid.UUID, occurrence time.Time) (model.Task, error) {
+	var tsk model.Task
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	err = s.GetBunDB().NewSelect().Model(&tsk).Where("? = ?", bun.Ident("tsk.schedule_id"), sid).
+		Where("? = ?", bun.Ident("tsk.occurrence_at"), occurrence).ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Task{}, ErrNoResults{Shape: tsk, Identifier: occurrence.String(), Err: err}
+		}
+		return model.Task{}, ErrFetchFailed{Model: "Task", Err: err}
+	}
+
+	return tsk, nil
+}
+
+// Exclude cancels a single occurrence, its task is removed if work hasn't started on it
+func (s *ScheduleRepo) Exclude(ctx context.Context, sched model.TaskSchedule, occurrence time.Time) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	rslt, err := tx.NewUpdate().Model((*model.TaskSchedule)(nil)).
+		Set("ex_dates = array_append(ex_dates, ?)", occurrence).Set("updated_at = ?", time.Now()).
+		Where("? = ?", bun.Ident("sid"), sched.Sid).ApplyQueryBuilder(scope.ByLessor("tsch.lessor_id")).Exec(ctx)
+
+	if err = s.checkWrite(ctx, tx, scope, sched.Sid, rslt, err); err != nil {
+		return err
+	}
+
+	if err = dropOccurrences(ctx, tx, sched.Sid, "=", occurrence); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+// Truncate ends the series before the from occurrence, the schedule's rule and cancelled at
+// are written as given and any task not yet started from that point on is removed
+func (s *ScheduleRepo) Truncate(ctx context.Context, sched *model.TaskSchedule, from time.Time) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	if err = s.endSeries(ctx, tx, scope, sched, from); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+// Split ends the current series before the from occurrence and starts next in its place
+func (s *ScheduleRepo) Split(ctx context.Context, current *model.TaskSchedule, next *model.TaskSchedule, from time.Time) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = s.checkProperty(ctx, scope, next); err != nil {
+		return err
+	}
+
+	tx, err := s.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	if err = s.endSeries(ctx, tx, scope, current, from); err != nil {
+		return err
+	}
+
+	if err = tx.NewInsert().Model(next).Returning("*").Scan(ctx, next); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrInsertFailed{Model: "Task Schedule", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+// endSeries rolls the transaction back itself on failure
+func (s *ScheduleRepo) endSeries(ctx context.Context, tx bun.Tx, scope TenantScope, sched *model.TaskSchedule, from time.Time) error {
+	sched.UpdatedAt = time.Now()
+	rslt, err := tx.NewUpdate().Model(sched).Column("rule", "cancelled_at", "updated_at").
+		Where("? = ?", bun.Ident("sid"), sched.Sid).ApplyQueryBuilder(scope.ByLessor("tsch.lessor_id")).Exec(ctx)
+
+	if err = s.checkWrite(ctx, tx, scope, sched.Sid, rslt, err); err != nil {
+		return err
+	}
+
+	if err = dropOccurrences(ctx, tx, sched.Sid, ">=", from); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// checkWrite rolls back and reports a schedule write that failed or matched nothing in scope
+func (s *ScheduleRepo) checkWrite(ctx context.Context, tx bun.Tx, scope TenantScope, sid uuid.UUID, rslt sql.Result, err error) error {
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrUpdateFailed{Model: "Task Schedule", Err: err}
+	}
+
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return scope.resolveMissing(ctx, s.scheduleExists(sid.String()), "Task Schedule", sid.String(),
+			ErrNoResults{Shape: model.TaskSchedule{}, Identifier: sid.String(), Err: sql.ErrNoRows})
+	}
+
+	return nil
+}
+
+// a schedule has to belong to the same lessor as the property it generates tasks for
+func (s *ScheduleRepo) checkProperty(ctx context.Context, scope TenantScope, sched *model.TaskSchedule) error {
+	if !scope.Owns(sched.LessorId) {
+		return ErrCrossTenant{Model: "Task Schedule", Identifier: sched.Sid.String()}
+	}
+
+	owned, err := s.GetBunDB().NewSelect().Model((*model.Property)(nil)).Where("? = ?", bun.Ident("pid"), sched.PropertyId).
+		Where("? = ?", bun.Ident("p.lessor_id"), sched.LessorId).Exists(ctx)
+	if err != nil {
+		return ErrFetchFailed{Model: "Property", Err: err}
+	}
+
+	if !owned {
+		return ErrCrossTenant{Model: "Task Schedule", Identifier: sched.PropertyId.String()}
+	}
+
+	return nil
+}
+
+func (s *ScheduleRepo) scheduleExists(sid string) *bun.SelectQuery {
+	return s.GetBunDB().NewSelect().Model((*model.TaskSchedule)(nil)).Where("? = ?", bun.Ident("sid"), sid)
+}
+
+// dropOccurrences deletes a schedule's generated tasks that are still waiting to be started,
+// tasks that are underway or done are left alone
+func dropOccurrences(ctx context.Context, tx bun.Tx, sid uuid.UUID, op string, occurrence time.Time) error {
+	dropped := make([]model.Task, 0)
+	err := tx.NewDelete().Model(&dropped).Where("? = ?", bun.Ident("schedule_id"), sid).
+		Where("? "+op+" ?", bun.Ident("occurrence_at"), occurrence).Where("? = ?", bun.Ident("status"), model.Scheduled).
+		Returning("*").Scan(ctx, &dropped)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ErrDeleteFailed{Model: "Task", Err: err}
+	}
+
+	events := make([]model.TaskEvent, 0, len(dropped))
+	for _, tk := range dropped {
+		events = append(events, newTaskEvent(ctx, tk, model.TaskDeleted, map[string]interface{}{
+			"scheduleId":   sid,
+			"occurrenceAt": tk.OccurrenceAt,
+		}))
+	}
+
+	return insertTaskEvents(ctx, tx, events)
+}
@@ -0,0 +1,150 @@
+package dac
+
+import (
+	"context"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// TenantScope limits repository queries to the rows owned by a single lessor,
+// admins get an unrestricted scope
+type TenantScope struct {
+	LessorId uuid.UUID
+	TenantId uuid.UUID
+	// UserId is set for workers and tenants, who only ever see their own user
+	UserId       uuid.UUID
+	Unrestricted bool
+}
+
+// NewTenantScope derives the scope from the user claims on the request context,
+// it fails closed when there are no claims or the claims have no lessor
+func NewTenantScope(ctx context.Context) (TenantScope, error) {
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return TenantScope{}, ErrMissingScope{Err: err}
+	}
+
+	if claims.HasRole(auth.Admin) {
+		return TenantScope{Unrestricted: true}, nil
+	}
+
+	lessorId := claims.LessorId
+	if lessorId == "" && claims.HasRole(auth.Alessor) {
+		lessorId = claims.Id
+	}
+
+	lid, err := uuid.Parse(lessorId)
+	if err != nil || lid == uuid.Nil {
+		return TenantScope{}, ErrMissingScope{Err: err}
+	}
+
+	scope := TenantScope{LessorId: lid}
+	if claims.HasRole(auth.Tenant) || claims.HasRole(auth.Worker) {
+		if scope.UserId, err = uuid.Parse(claims.Id); err != nil || scope.UserId == uuid.Nil {
+			return TenantScope{}, ErrMissingScope{Err: err}
+		}
+	}
+
+	if claims.HasRole(auth.Tenant) {
+		scope.TenantId = scope.UserId
+	}
+
+	return scope, nil
+}
+
+func (s TenantScope) Owns(lessorId uuid.UUID) bool {
+	return s.Unrestricted || s.LessorId == lessorId
+}
+
+// Check is used when a request names the lessor it wants data for
+func (s TenantScope) Check(model string, lessorId string) error {
+	if s.Unrestricted {
+		return nil
+	}
+
+	lid, err := uuid.Parse(lessorId)
+	if err != nil || lid != s.LessorId {
+		return ErrCrossTenant{Model: model, Identifier: lessorId}
+	}
+
+	return nil
+}
+
+// Where adds the predicate to the query unless the scope is unrestricted
+func (s TenantScope) Where(query string, args ...interface{}) func(bun.QueryBuilder) bun.QueryBuilder {
+	return func(q bun.QueryBuilder) bun.QueryBuilder {
+		if s.Unrestricted {
+			return q
+		}
+		return q.Where(query, args...)
+	}
+}
+
+// ByLessor scopes a query on a table that has its own lessor column
+func (s TenantScope) ByLessor(column string) func(bun.QueryBuilder) bun.QueryBuilder {
+	return s.Where("? = ?", bun.Ident(column), s.LessorId)
+}
+
+// ForLessor scopes a fetch all to the lessor named by the request, asking for
+// another lessor's rows fails with ErrCrossTenant
+func (s TenantScope) ForLessor(model string, column string, lessorId string) (func(bun.QueryBuilder) bun.QueryBuilder, error) {
+	if lessorId == "" {
+		return s.ByLessor(column), nil
+	}
+
+	if err := s.Check(model, lessorId); err != nil {
+		return nil, err
+	}
+
+	return func(q bun.QueryBuilder) bun.QueryBuilder {
+		return q.Where("? = ?", bun.Ident(column), lessorId)
+	}, nil
+}
+
+// ByProperty scopes a query on a table that only references a property
+func (s TenantScope) ByProperty(column string) func(bun.QueryBuilder) bun.QueryBuilder {
+	return s.Where("? IN (SELECT pid FROM properties WHERE lessor_id = ?)", bun.Ident(column), s.LessorId)
+}
+
+// ByUser scopes the users table to the lessor, their workers and their tenants. Workers
+// and tenants are limited to their own user
+func (s TenantScope) ByUser(column string) func(bun.QueryBuilder) bun.QueryBuilder {
+	if s.UserId != uuid.Nil {
+		return s.Where("? = ?", bun.Ident(column), s.UserId)
+	}
+
+	return s.Where(
+		"(? = ? OR ? IN (SELECT uid FROM workers WHERE lessor_id = ?) OR ? IN (SELECT uid FROM tenants WHERE lessor_id = ?))",
+		bun.Ident(column), s.LessorId,
+		bun.Ident(column), s.LessorId,
+		bun.Ident(column), s.LessorId,
+	)
+}
+
+// ByTenant limits a tenant to the rows they own on top of their lessor's scope, other
+// roles are left as they are
+func (s TenantScope) ByTenant(column string) func(bun.QueryBuilder) bun.QueryBuilder {
+	return func(q bun.QueryBuilder) bun.QueryBuilder {
+		if s.TenantId == uuid.Nil {
+			return q
+		}
+		return q.Where("? = ?", bun.Ident(column), s.TenantId)
+	}
+}
+
+// resolveMissing is called after a scoped query found nothing, if the row exists
+// outside of the scope the request is rejected instead of reported as missing
+func (s TenantScope) resolveMissing(ctx context.Context, exists *bun.SelectQuery, model string, identifier string, notFound error) error {
+	if s.Unrestricted {
+		return notFound
+	}
+
+	found, err := exists.Exists(ctx)
+	if err != nil || !found {
+		return notFound
+	}
+
+	return ErrCrossTenant{Model: model, Identifier: identifier}
+}
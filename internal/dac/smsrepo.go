@@ -0,0 +1,124 @@
+package dac
+
+import (
+	"context"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const maxSmsMessages = 200
+
+// SmsRepo keeps what was texted and who opted out. Opt outs aren't tenant scoped, a STOP
+// applies to our number whichever lessor the text was for
+type SmsRepo struct {
+	Persister
+}
+
+func InitSmsRepo(db Persister) SmsRepo {
+	return SmsRepo{
+		Persister: db,
+	}
+}
+
+func (s *SmsRepo) InsertMessage(ctx context.Context, msg *model.SmsMessage) error {
+	if err := s.GetBunDB().NewInsert().Model(msg).Returning("*").Scan(ctx, msg); err != nil {
+		return ErrInsertFailed{Model: "Sms Message", Err: err}
+	}
+	return nil
+}
+
+// UpdateMessage records the outcome of a send attempt
+func (s *SmsRepo) UpdateMessage(ctx context.Context, msg *model.SmsMessage) error {
+	msg.UpdatedAt = time.Now()
+	_, err := s.GetBunDB().NewUpdate().Model(msg).Column("provider_id", "status", "error", "attempts", "updated_at").
+		Where("? = ?", bun.Ident("sid"), msg.Sid).Exec(ctx)
+
+	if err != nil {
+		return ErrUpdateFailed{Model: "Sms Message", Err: err}
+	}
+	return nil
+}
+
+// ApplyStatus moves the message on from a provider callback, it returns false when the
+// message already has a later status or isn't one of ours
+func (s *SmsRepo) ApplyStatus(ctx context.Context, ref uuid.UUID, providerId string, status model.SmsStatus, reason string) (bool, error) {
+	replaces := status.Replaces()
+	if len(replaces) == 0 {
+		return false, nil
+	}
+
+	query := s.GetBunDB().NewUpdate().Model((*model.SmsMessage)(nil)).Set("status = ?", status).Set("updated_at = ?", time.Now()).
+		Where("? IN (?)", bun.Ident("status"), bun.In(replaces))
+
+	if ref != uuid.Nil {
+		query = query.Where("? = ?", bun.Ident("sid"), ref)
+	} else {
+		query = query.Where("? = ?", bun.Ident("provider_id"), providerId)
+	}
+
+	if providerId != "" {
+		query = query.Set("provider_id = ?", providerId)
+	}
+
+	if reason != "" {
+		query = query.Set("error = ?", reason)
+	}
+
+	rslt, err := query.Exec(ctx)
+	if err != nil {
+		return false, ErrUpdateFailed{Model: "Sms Message", Err: err}
+	}
+
+	n, _ := rslt.RowsAffected()
+	return n > 0, nil
+}
+
+// FetchMessages returns the lessor's most recent texts newest first
+func (s *SmsRepo) FetchMessages(ctx context.Context, lessorId uuid.UUID) ([]model.SmsMessage, error) {
+	msgs := make([]model.SmsMessage, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scope.Owns(lessorId) {
+		return nil, ErrCrossTenant{Model: "Sms Message", Identifier: lessorId.String()}
+	}
+
+	err = s.GetBunDB().NewSelect().Model(&msgs).Where("? = ?", bun.Ident("sms.lessor_id"), lessorId).
+		Order("sms.created_at DESC").Limit(maxSmsMessages).Scan(ctx, &msgs)
+	if err != nil {
+		return nil, ErrFetchFailed{Model: "Sms Message", Err: err}
+	}
+
+	return msgs, nil
+}
+
+// OptOut is safe to repeat, carriers resend STOP replies
+func (s *SmsRepo) OptOut(ctx context.Context, phone string, keyword string) error {
+	_, err := s.GetBunDB().NewInsert().Model(&model.SmsOptOut{Phone: phone, Keyword: keyword, CreatedAt: time.Now()}).
+		On("CONFLICT (phone) DO NOTHING").Exec(ctx)
+	if err != nil {
+		return ErrInsertFailed{Model: "Sms Opt Out", Err: err}
+	}
+	return nil
+}
+
+func (s *SmsRepo) OptIn(ctx context.Context, phone string) error {
+	_, err := s.GetBunDB().NewDelete().Model((*model.SmsOptOut)(nil)).Where("? = ?", bun.Ident("phone"), phone).Exec(ctx)
+	if err != nil {
+		return ErrDeleteFailed{Model: "Sms Opt Out", Err: err}
+	}
+	return nil
+}
+
+func (s *SmsRepo) IsOptedOut(ctx context.Context, phone string) (bool, error) {
+	exists, err := s.GetBunDB().NewSelect().Model((*model.SmsOptOut)(nil)).Where("? = ?", bun.Ident("phone"), phone).Exists(ctx)
+	if err != nil {
+		return false, ErrFetchFailed{Model: "Sms Opt Out", Err: err}
+	}
+	return exists, nil
+}
@@ -0,0 +1,150 @@
+package dac
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// StreamChannel is the postgres channel new notifications and task status changes are
+// announced on, every replica listens so a user gets them whichever one they are connected to
+const StreamChannel = "lessor_stream"
+
+type StreamKind string
+
+const (
+	StreamNotification StreamKind = "notification"
+	StreamTask         StreamKind = "task"
+)
+
+// StreamSignal is the NOTIFY payload, it only carries enough to route the event and the
+// listener loads the row itself since payloads are capped at 8000 bytes
+type StreamSignal struct {
+	Kind     StreamKind `json:"kind"`
+	Id       int64      `json:"id"`
+	UserId   uuid.UUID  `json:"userId"`
+	LessorId uuid.UUID  `json:"lessorId"`
+	TaskId   uuid.UUID  `json:"taskId"`
+}
+
+// PublishNotification announces a saved notification, called inside the insert's
+// transaction postgres only delivers it once the transaction commits
+func PublishNotification(ctx context.Context, db bun.IDB, n model.Notification) error {
+	return publish(ctx, db, StreamSignal{Kind: StreamNotification, Id: int64(n.Id), UserId: n.Recipient(), LessorId: n.LessorId})
+}
+
+func publishTaskEvents(ctx context.Context, db bun.IDB, events []model.TaskEvent) error {
+	for _, e := range events {
+		if !e.EventType.StatusChange() {
+			continue
+		}
+
+		if err := publish(ctx, db, StreamSignal{Kind: StreamTask, Id: e.Id, LessorId: e.LessorId, TaskId: e.TaskId}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func publish(ctx context.Context, db bun.IDB, signal StreamSignal) error {
+	payload, err := json.Marshal(signal)
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.NewRaw("SELECT pg_notify(?, ?)", StreamChannel, string(payload)).Exec(ctx); err != nil {
+		return ErrInsertFailed{Model: "Stream Signal", Err: err}
+	}
+	return nil
+}
+
+// StreamRepo loads what the stream pushes. It isn't scoped, the stream only asks for rows
+// that belong to the connected user
+type StreamRepo struct {
+	Persister
+}
+
+func InitStreamRepo(db Persister) StreamRepo {
+	return StreamRepo{
+		Persister: db,
+	}
+}
+
+func (s *StreamRepo) Notification(ctx context.Context, id int64) (model.Notification, error) {
+	var noti model.Notification
+	if err := s.GetBunDB().NewSelect().Model(&noti).Where("? = ?", bun.Ident("notif.id"), id).Scan(ctx); err != nil {
+		return model.Notification{}, ErrFetchFailed{Model: "Notification", Err: err}
+	}
+	return noti, nil
+}
+
+func (s *StreamRepo) TaskEvent(ctx context.Context, id int64) (model.TaskEvent, error) {
+	var event model.TaskEvent
+	if err := s.GetBunDB().NewSelect().Model(&event).Where("? = ?", bun.Ident("te.id"), id).Scan(ctx); err != nil {
+		return model.TaskEvent{}, ErrFetchFailed{Model: "Task Event", Err: err}
+	}
+	return event, nil
+}
+
+// Crew is the workers on the task including the lead
+func (s *StreamRepo) Crew(ctx context.Context, taskId uuid.UUID) ([]uuid.UUID, error) {
+	var crew []uuid.UUID
+	err := s.GetBunDB().NewRaw(
+		"SELECT worker_id FROM assigned_tasks WHERE task_id = ? UNION SELECT worker_id FROM tasks WHERE tid = ? AND worker_id IS NOT NULL",
+		taskId, taskId,
+	).Scan(ctx, &crew)
+
+	if err != nil {
+		return nil, ErrFetchFailed{Model: "Task Crew", Err: err}
+	}
+	return crew, nil
+}
+
+// Latest returns the newest notification and task event ids, a new connection starts there
+func (s *StreamRepo) Latest(ctx context.Context) (int64, int64, error) {
+	var notifications, events int64
+	err := s.GetBunDB().NewRaw(
+		"SELECT (SELECT COALESCE(MAX(id), 0) FROM notifications), (SELECT COALESCE(MAX(id), 0) FROM task_events)",
+	).Scan(ctx, &notifications, &events)
+
+	if err != nil {
+		return 0, 0, ErrFetchFailed{Model: "Stream Cursor", Err: err}
+	}
+	return notifications, events, nil
+}
+
+// NotificationsSince returns the user's notifications after the id oldest first
+func (s *StreamRepo) NotificationsSince(ctx context.Context, uid uuid.UUID, after int64, limit int) ([]model.Notification, error) {
+	notis := make([]model.Notification, 0)
+	err := s.GetBunDB().NewSelect().Model(&notis).
+		Where("? > ?", bun.Ident("notif.id"), after).
+		Where("(? = ? OR (? IS NULL AND ? = ?))", bun.Ident("notif.user_id"), uid, bun.Ident("notif.user_id"), bun.Ident("notif.lessor_id"), uid).
+		Order("notif.id ASC").Limit(limit).Scan(ctx)
+
+	if err != nil {
+		return nil, ErrFetchFailed{Model: "Notification", Err: err}
+	}
+	return notis, nil
+}
+
+// TaskEventsSince returns the status changes after the id for the lessor's tasks or the
+// tasks the user works on, oldest first
+func (s *StreamRepo) TaskEventsSince(ctx context.Context, uid uuid.UUID, after int64, limit int) ([]model.TaskEvent, error) {
+	events := make([]model.TaskEvent, 0)
+	err := s.GetBunDB().NewSelect().Model(&events).
+		Where("? > ?", bun.Ident("te.id"), after).
+		Where("? IN (?)", bun.Ident("te.event_type"), bun.In(model.TaskStatusEvents)).
+		Where(
+			"(? = ? OR ? IN (SELECT task_id FROM assigned_tasks WHERE worker_id = ?) OR ? IN (SELECT tid FROM tasks WHERE worker_id = ?))",
+			bun.Ident("te.lessor_id"), uid, bun.Ident("te.task_id"), uid, bun.Ident("te.task_id"), uid,
+		).
+		Order("te.id ASC").Limit(limit).Scan(ctx)
+
+	if err != nil {
+		return nil, ErrFetchFailed{Model: "Task Event", Err: err}
+	}
+	return events, nil
+}
@@ -0,0 +1,482 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// TimeQuery narrows a timesheet, zero fields are ignored
+type TimeQuery struct {
+	WorkerId uuid.UUID
+	TaskId   uuid.UUID
+	From     time.Time
+	To       time.Time
+	Status   model.TimeEntryStatus
+}
+
+type TimeRepo struct {
+	Persister
+}
+
+func InitTimeRepo(db Persister) TimeRepo {
+	return TimeRepo{
+		Persister: db,
+	}
+}
+
+func (t *TimeRepo) Fetch(ctx context.Context, eid uuid.UUID) (model.TimeEntry, error) {
+	var entry model.TimeEntry
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	err = t.GetBunDB().NewSelect().Model(&entry).Where("? = ?", bun.Ident("tme.eid"), eid).
+		ApplyQueryBuilder(scope.ByLessor("tme.lessor_id")).Relation("Task").Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TimeEntry{}, t.missing(ctx, scope, eid, err)
+		}
+		return model.TimeEntry{}, ErrFetchFailed{Model: "Time Entry", Err: err}
+	}
+
+	return entry, nil
+}
+
+// FetchEntries returns the entries matching the query oldest first, From and To bound the
+// clock in time
+func (t *TimeRepo) FetchEntries(ctx context.Context, q TimeQuery) ([]model.TimeEntry, error) {
+	entries := make([]model.TimeEntry, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := t.GetBunDB().NewSelect().Model(&entries).ApplyQueryBuilder(scope.ByLessor("tme.lessor_id")).Relation("Task")
+	if q.WorkerId != uuid.Nil {
+		query = query.Where("? = ?", bun.Ident("tme.worker_id"), q.WorkerId)
+	}
+	if q.TaskId != uuid.Nil {
+		query = query.Where("? = ?", bun.Ident("tme.task_id"), q.TaskId)
+	}
+	if !q.From.IsZero() {
+		query = query.Where("? >= ?", bun.Ident("tme.clock_in"), q.From)
+	}
+	if !q.To.IsZero() {
+		query = query.Where("? < ?", bun.Ident("tme.clock_in"), q.To)
+	}
+	if q.Status != "" {
+		query = query.Where("? = ?", bun.Ident("tme.status"), q.Status)
+	}
+
+	if err = query.Order("tme.clock_in ASC").Scan(ctx, &entries); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Time Entry", Err: err}
+	}
+
+	return entries, nil
+}
+
+// ClockIn opens an entry for the signed in worker on the task. The worker row is locked while
+// checking so two requests can't both open an entry
+func (t *TimeRepo) ClockIn(ctx context.Context, tid uuid.UUID, notes string) (model.TimeEntry, error) {
+	scope, workerId, err := clockScope(ctx)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return model.TimeEntry{}, ErrTransactionStartFailed{Err: err}
+	}
+
+	entry := model.TimeEntry{Eid: uuid.New(), WorkerId: workerId, TaskId: tid, ClockIn: time.Now(), Notes: notes, Status: model.EntryOpen}
+	tk, err := t.lockCrewTask(ctx, tx, scope, tid, workerId)
+
+	if err == nil {
+		var id int64
+		err = tx.NewSelect().Model((*model.Worker)(nil)).Column("w.id").Where("? = ?", bun.Ident("w.uid"), workerId).
+			For("UPDATE").Scan(ctx, &id)
+		if err != nil {
+			err = ErrFetchFailed{Model: "worker", Err: err}
+		}
+	}
+
+	if err == nil {
+		var open bool
+		open, err = tx.NewSelect().Model((*model.TimeEntry)(nil)).Where("? = ?", bun.Ident("tme.worker_id"), workerId).
+			Where("? = ?", bun.Ident("tme.status"), model.EntryOpen).Exists(ctx)
+		if err != nil {
+			err = ErrFetchFailed{Model: "Time Entry", Err: err}
+		} else if open {
+			err = ErrAlreadyClockedIn
+		}
+	}
+
+	if err == nil {
+		entry.LessorId = tk.LessorId
+		if err = tx.NewInsert().Model(&entry).Returning("*").Scan(ctx, &entry); err != nil {
+			err = ErrInsertFailed{Model: "Time Entry", Err: err}
+		}
+	}
+
+	if err == nil {
+		err = insertTaskEvents(ctx, tx, []model.TaskEvent{
+			newTaskEvent(ctx, tk, model.TaskClockedIn, map[string]interface{}{"workerId": workerId, "entryId": entry.Eid}),
+		})
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.TimeEntry{}, ErrRollbackFailed{rbErr}
+		}
+		return model.TimeEntry{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.TimeEntry{}, ErrTransactionCommitFail{err}
+	}
+
+	return entry, nil
+}
+
+// ClockOut closes the worker's open entry on the task and sends it for review, a break that
+// is still running ends with it
+func (t *TimeRepo) ClockOut(ctx context.Context, tid uuid.UUID, notes string) (model.TimeEntry, error) {
+	return t.changeOpenEntry(ctx, tid, func(entry *model.TimeEntry, now time.Time) error {
+		endBreak(entry, now)
+		entry.ClockOut = now
+		entry.Status = model.EntryPending
+		if notes != "" {
+			entry.Notes = joinNotes(entry.Notes, notes)
+		}
+		return nil
+	})
+}
+
+func (t *TimeRepo) StartBreak(ctx context.Context, tid uuid.UUID) (model.TimeEntry, error) {
+	return t.changeOpenEntry(ctx, tid, func(entry *model.TimeEntry, now time.Time) error {
+		if !entry.BreakStartedAt.IsZero() {
+			return ErrBreakState
+		}
+		entry.BreakStartedAt = now
+		return nil
+	})
+}
+
+func (t *TimeRepo) EndBreak(ctx context.Context, tid uuid.UUID) (model.TimeEntry, error) {
+	return t.changeOpenEntry(ctx, tid, func(entry *model.TimeEntry, now time.Time) error {
+		if entry.BreakStartedAt.IsZero() {
+			return ErrBreakState
+		}
+		endBreak(entry, now)
+		return nil
+	})
+}
+
+// Adjust lets a lessor correct an entry before it is approved, the new times can't overlap
+// any of the worker's other entries. Closing an open entry sends it for review
+func (t *TimeRepo) Adjust(ctx context.Context, eid uuid.UUID, clockIn time.Time, clockOut time.Time, breakMinutes int, note string) (model.TimeEntry, error) {
+	var entry model.TimeEntry
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return model.TimeEntry{}, ErrTransactionStartFailed{Err: err}
+	}
+
+	if entry, err = t.lockEntry(ctx, tx, scope, eid); err == nil && entry.Status == model.EntryApproved {
+		err = ErrEntryReviewed
+	}
+
+	if err == nil {
+		entry.ClockIn = clockIn
+		entry.ClockOut = clockOut
+		entry.BreakMinutes = breakMinutes
+		if !clockOut.IsZero() {
+			entry.BreakStartedAt = time.Time{}
+			entry.Status = model.EntryPending
+		}
+		if note != "" {
+			entry.ReviewNote = note
+		}
+
+		err = t.checkOverlap(ctx, tx, entry)
+	}
+
+	if err == nil {
+		_, err = tx.NewUpdate().Model(&entry).Column("clock_in", "clock_out", "break_started_at", "break_minutes", "status", "review_note").
+			WherePK().Exec(ctx)
+		if err != nil {
+			err = ErrUpdateFailed{Model: "Time Entry", Err: err}
+		}
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.TimeEntry{}, ErrRollbackFailed{rbErr}
+		}
+		return model.TimeEntry{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.TimeEntry{}, ErrTransactionCommitFail{err}
+	}
+
+	return entry, nil
+}
+
+// Review approves or rejects a single entry that is waiting for review
+func (t *TimeRepo) Review(ctx context.Context, eid uuid.UUID, status model.TimeEntryStatus, note string) (model.TimeEntry, error) {
+	var entry model.TimeEntry
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	reviewer, err := claimsId(ctx)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	err = t.GetBunDB().NewUpdate().Model(&entry).Set("status = ?", status).Set("reviewed_by = ?", reviewer).
+		Set("reviewed_at = ?", time.Now()).Set("review_note = ?", note).
+		Where("? = ?", bun.Ident("tme.eid"), eid).Where("? = ?", bun.Ident("tme.status"), model.EntryPending).
+		ApplyQueryBuilder(scope.ByLessor("tme.lessor_id")).Returning("*").Scan(ctx, &entry)
+
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return model.TimeEntry{}, ErrUpdateFailed{Model: "Time Entry", Err: err}
+		}
+		if _, err = t.Fetch(ctx, eid); err != nil {
+			return model.TimeEntry{}, err
+		}
+		return model.TimeEntry{}, ErrEntryNotPending
+	}
+
+	return entry, nil
+}
+
+// ApproveRange approves every entry waiting for review that the worker clocked in between from
+// and to, it returns how many were approved
+func (t *TimeRepo) ApproveRange(ctx context.Context, workerId uuid.UUID, from time.Time, to time.Time, note string) (int, error) {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	reviewer, err := claimsId(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rslt, err := t.GetBunDB().NewUpdate().Model((*model.TimeEntry)(nil)).Set("status = ?", model.EntryApproved).
+		Set("reviewed_by = ?", reviewer).Set("reviewed_at = ?", time.Now()).Set("review_note = ?", note).
+		Where("? = ?", bun.Ident("tme.worker_id"), workerId).Where("? = ?", bun.Ident("tme.status"), model.EntryPending).
+		Where("? >= ?", bun.Ident("tme.clock_in"), from).Where("? < ?", bun.Ident("tme.clock_in"), to).
+		ApplyQueryBuilder(scope.ByLessor("tme.lessor_id")).Exec(ctx)
+
+	if err != nil {
+		return 0, ErrUpdateFailed{Model: "Time Entry", Err: err}
+	}
+
+	n, _ := rslt.RowsAffected()
+	return int(n), nil
+}
+
+// changeOpenEntry locks the signed in worker's open entry on the task, applies change and
+// saves it, clocking out also goes on the task timeline
+func (t *TimeRepo) changeOpenEntry(ctx context.Context, tid uuid.UUID, change func(*model.TimeEntry, time.Time) error) (model.TimeEntry, error) {
+	var entry model.TimeEntry
+	scope, workerId, err := clockScope(ctx)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return model.TimeEntry{}, ErrTransactionStartFailed{Err: err}
+	}
+
+	err = tx.NewSelect().Model(&entry).Where("? = ?", bun.Ident("tme.task_id"), tid).
+		Where("? = ?", bun.Ident("tme.worker_id"), workerId).Where("? = ?", bun.Ident("tme.status"), model.EntryOpen).
+		ApplyQueryBuilder(scope.ByLessor("tme.lessor_id")).For("UPDATE").Scan(ctx, &entry)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNotClockedIn
+		} else {
+			err = ErrFetchFailed{Model: "Time Entry", Err: err}
+		}
+	}
+
+	if err == nil {
+		err = change(&entry, time.Now())
+	}
+
+	if err == nil {
+		_, err = tx.NewUpdate().Model(&entry).Column("clock_out", "break_started_at", "break_minutes", "notes", "status").
+			WherePK().Exec(ctx)
+		if err != nil {
+			err = ErrUpdateFailed{Model: "Time Entry", Err: err}
+		}
+	}
+
+	if err == nil && entry.Status == model.EntryPending {
+		var tk model.Task
+		if tk, err = lockTask(ctx, tx, scope, tid); err != nil {
+			err = ErrFetchFailed{Model: "Task", Err: err}
+		} else {
+			err = insertTaskEvents(ctx, tx, []model.TaskEvent{
+				newTaskEvent(ctx, tk, model.TaskClockedOut, map[string]interface{}{
+					"workerId": workerId, "entryId": entry.Eid, "minutes": entry.Minutes(entry.ClockOut),
+				}),
+			})
+		}
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.TimeEntry{}, ErrRollbackFailed{rbErr}
+		}
+		return model.TimeEntry{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.TimeEntry{}, ErrTransactionCommitFail{err}
+	}
+
+	return entry, nil
+}
+
+// lockCrewTask locks the task and makes sure the worker can log time on it
+func (t *TimeRepo) lockCrewTask(ctx context.Context, tx bun.Tx, scope TenantScope, tid uuid.UUID, workerId uuid.UUID) (model.Task, error) {
+	tk, err := lockTask(ctx, tx, scope, tid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			exists := t.GetBunDB().NewSelect().Model((*model.Task)(nil)).Where("? = ?", bun.Ident("tid"), tid)
+			return model.Task{}, scope.resolveMissing(ctx, exists, "Task", tid.String(),
+				ErrNoResults{Shape: model.Task{}, Identifier: tid.String(), Err: err})
+		}
+		return model.Task{}, ErrFetchFailed{Model: "Task", Err: err}
+	}
+
+	if tk.Status == model.Finished || tk.Status == model.Failed {
+		return model.Task{}, ErrTaskClosed
+	}
+
+	if tk.WorkerId == workerId {
+		return tk, nil
+	}
+
+	onCrew, err := tx.NewSelect().Model((*model.AssignedTask)(nil)).Where("? = ?", bun.Ident("at.task_id"), tid).
+		Where("? = ?", bun.Ident("at.worker_id"), workerId).Where("? <> ?", bun.Ident("at.status"), model.AssignmentDeclined).
+		Exists(ctx)
+	if err != nil {
+		return model.Task{}, ErrFetchFailed{Model: "Assigned Task", Err: err}
+	}
+
+	if !onCrew {
+		return model.Task{}, ErrNotOnCrew
+	}
+
+	return tk, nil
+}
+
+func (t *TimeRepo) lockEntry(ctx context.Context, tx bun.Tx, scope TenantScope, eid uuid.UUID) (model.TimeEntry, error) {
+	var entry model.TimeEntry
+	err := tx.NewSelect().Model(&entry).Where("? = ?", bun.Ident("tme.eid"), eid).
+		ApplyQueryBuilder(scope.ByLessor("tme.lessor_id")).For("UPDATE").Scan(ctx, &entry)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TimeEntry{}, t.missing(ctx, scope, eid, err)
+		}
+		return model.TimeEntry{}, ErrFetchFailed{Model: "Time Entry", Err: err}
+	}
+
+	return entry, nil
+}
+
+// checkOverlap fails when the entry's time runs into another of the worker's entries, open
+// entries run until they are closed
+func (t *TimeRepo) checkOverlap(ctx context.Context, tx bun.Tx, entry model.TimeEntry) error {
+	q := tx.NewSelect().Model((*model.TimeEntry)(nil)).Where("? = ?", bun.Ident("tme.worker_id"), entry.WorkerId).
+		Where("? <> ?", bun.Ident("tme.eid"), entry.Eid).Where("? <> ?", bun.Ident("tme.status"), model.EntryRejected).
+		Where("COALESCE(?, 'infinity') > ?", bun.Ident("tme.clock_out"), entry.ClockIn)
+
+	if !entry.ClockOut.IsZero() {
+		q = q.Where("? < ?", bun.Ident("tme.clock_in"), entry.ClockOut)
+	}
+
+	overlaps, err := q.Exists(ctx)
+	if err != nil {
+		return ErrFetchFailed{Model: "Time Entry", Err: err}
+	}
+
+	if overlaps {
+		return ErrEntryOverlap
+	}
+
+	return nil
+}
+
+func (t *TimeRepo) missing(ctx context.Context, scope TenantScope, eid uuid.UUID, err error) error {
+	exists := t.GetBunDB().NewSelect().Model((*model.TimeEntry)(nil)).Where("? = ?", bun.Ident("eid"), eid)
+	return scope.resolveMissing(ctx, exists, "Time Entry", eid.String(),
+		ErrNoResults{Shape: model.TimeEntry{}, Identifier: eid.String(), Err: err})
+}
+
+// clockScope is the scope and worker id for the signed in worker
+func clockScope(ctx context.Context) (TenantScope, uuid.UUID, error) {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return TenantScope{}, uuid.Nil, err
+	}
+
+	workerId, err := claimsId(ctx)
+	if err != nil {
+		return TenantScope{}, uuid.Nil, err
+	}
+
+	return scope, workerId, nil
+}
+
+func claimsId(ctx context.Context) (uuid.UUID, error) {
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return uuid.Nil, ErrMissingScope{Err: err}
+	}
+
+	id, err := uuid.Parse(claims.Id)
+	if err != nil {
+		return uuid.Nil, ErrMissingScope{Err: err}
+	}
+
+	return id, nil
+}
+
+func endBreak(entry *model.TimeEntry, now time.Time) {
+	if entry.BreakStartedAt.IsZero() {
+		return
+	}
+	entry.BreakMinutes += int(now.Sub(entry.BreakStartedAt).Minutes())
+	entry.BreakStartedAt = time.Time{}
+}
+
+func joinNotes(existing string, notes string) string {
+	if existing == "" {
+		return notes
+	}
+	return existing + "\n" + notes
+}
@@ -0,0 +1,126 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RefreshTokenRepo is not tenant scoped, the refresh token is what authenticates the
+// request so there are no claims to scope with yet
+type RefreshTokenRepo struct {
+	Persister
+}
+
+func InitRefreshTokenRepo(db Persister) RefreshTokenRepo {
+	return RefreshTokenRepo{
+		Persister: db,
+	}
+}
+
+func (r *RefreshTokenRepo) FetchByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+	var tkn model.RefreshToken
+	err := r.GetBunDB().NewSelect().Model(&tkn).Where("? = ?", bun.Ident("token_hash"), hash).Scan(ctx, &tkn)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.RefreshToken{}, ErrNoResults{Shape: tkn, Identifier: "[token-hash]", Err: err}
+		}
+		return model.RefreshToken{}, ErrFetchFailed{Model: "Refresh Token", Err: err}
+	}
+
+	return tkn, nil
+}
+
+func (r *RefreshTokenRepo) Insert(ctx context.Context, token any) (interface{}, error) {
+	tkn, ok := token.(*model.RefreshToken)
+	if !ok {
+		return nil, cmerr.ErrUnexpectedData{Wanted: model.RefreshToken{}, Got: token}
+	}
+
+	tx, err := r.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, ErrTransactionStartFailed{Err: err}
+	}
+
+	// a device only ever holds one live token, signing in again replaces it
+	_, err = tx.NewUpdate().Model((*model.RefreshToken)(nil)).Set("revoked_at = ?", time.Now()).
+		Where("? = ?", bun.Ident("uid"), tkn.Uid).Where("? = ?", bun.Ident("device_id"), tkn.DeviceId).
+		Where("revoked_at IS NULL").Exec(ctx)
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, ErrUpdateFailed{Model: "Refresh Token", Err: err}
+	}
+
+	if err = tx.NewInsert().Model(tkn).Returning("*").Scan(ctx, tkn); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, ErrInsertFailed{Model: "Refresh Token", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, ErrTransactionCommitFail{err}
+	}
+
+	return tkn, nil
+}
+
+// Rotate marks the current token as used and stores its replacement, if the current token
+// was already used or revoked by the time the update runs ErrTokenReused is returned
+func (r *RefreshTokenRepo) Rotate(ctx context.Context, current model.RefreshToken, next *model.RefreshToken) error {
+	tx, err := r.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	rslt, err := tx.NewUpdate().Model((*model.RefreshToken)(nil)).Set("rotated_at = ?", time.Now()).
+		Where("? = ?", bun.Ident("id"), current.Id).Where("rotated_at IS NULL").Where("revoked_at IS NULL").Exec(ctx)
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrUpdateFailed{Model: "Refresh Token", Err: err}
+	}
+
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrTokenReused
+	}
+
+	if err = tx.NewInsert().Model(next).Returning("*").Scan(ctx, next); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrInsertFailed{Model: "Refresh Token", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+func (r *RefreshTokenRepo) RevokeFamily(ctx context.Context, familyId uuid.UUID) error {
+	_, err := r.GetBunDB().NewUpdate().Model((*model.RefreshToken)(nil)).Set("revoked_at = ?", time.Now()).
+		Where("? = ?", bun.Ident("family_id"), familyId).Where("revoked_at IS NULL").Exec(ctx)
+
+	if err != nil {
+		return ErrUpdateFailed{Model: "Refresh Token", Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,329 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type TenantRepo struct {
+	Persister
+}
+
+func InitTenantRepo(db Persister) TenantRepo {
+	return TenantRepo{
+		Persister: db,
+	}
+}
+
+func (t *TenantRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
+	var tnt model.Tenant
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.GetBunDB().NewSelect().Model(&tnt).Where("? = ?", bun.Ident("tnt.uid"), fltr.Identifier).
+		ApplyQueryBuilder(scope.ByLessor("tnt.lessor_id")).ApplyQueryBuilder(scope.ByTenant("tnt.uid")).
+		Relation("User").Relation("Lessor").Relation("Property").Relation("Rental").Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, scope.resolveMissing(ctx, t.tenantExists(fltr.Identifier), "Tenant", fltr.Identifier,
+				ErrNoResults{Shape: tnt, Identifier: fltr.Identifier, Err: err})
+		}
+		return nil, ErrFetchFailed{Model: "Tenant", Err: err}
+	}
+
+	return tnt, nil
+}
+
+func (t *TenantRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.Tenant, error) {
+	tenants := make([]model.Tenant, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byLessor, err := scope.ForLessor("Tenant", "tnt.lessor_id", fltr.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := utils.DeterminRecordLimit(fltr.Limit)
+	err = t.GetBunDB().NewSelect().Model(&tenants).ApplyQueryBuilder(byLessor).Relation("User").Relation("Property").
+		Order("tnt.id ASC").Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &tenants)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Tenant", Err: err}
+	}
+
+	return tenants, nil
+}
+
+// Invite creates the tenant's user, the tenant and their invite together, the user stays
+// inactive without a password until the invite is accepted
+func (t *TenantRepo) Invite(ctx context.Context, usr *model.User, tnt *model.Tenant, invite *model.TenantInvite) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = t.checkProperty(ctx, scope, tnt.LessorId, tnt.PropertyId); err != nil {
+		return err
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	if err = tx.NewInsert().Model(usr).Returning("*").Scan(ctx, usr); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{Err: rbErr}
+		}
+		return ErrInsertFailed{Model: "User", Err: err}
+	}
+
+	if err = tx.NewInsert().Model(tnt).Returning("*").Scan(ctx, tnt); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{Err: rbErr}
+		}
+		return ErrInsertFailed{Model: "Tenant", Err: err}
+	}
+
+	if _, err = tx.NewInsert().Model(invite).Exec(ctx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{Err: rbErr}
+		}
+		return ErrInsertFailed{Model: "Tenant Invite", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{Err: err}
+	}
+
+	tnt.User = usr
+	return nil
+}
+
+// ReissueInvite replaces any invite the tenant hasn't accepted yet with a new one
+func (t *TenantRepo) ReissueInvite(ctx context.Context, invite *model.TenantInvite) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !scope.Owns(invite.LessorId) {
+		return ErrCrossTenant{Model: "Tenant", Identifier: invite.Uid.String()}
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	_, err = deleteTenantInvitesQuery(tx, invite.Uid).Exec(ctx)
+
+	if err == nil {
+		_, err = tx.NewInsert().Model(invite).Exec(ctx)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{Err: rbErr}
+		}
+		return ErrInsertFailed{Model: "Tenant Invite", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{Err: err}
+	}
+
+	return nil
+}
+
+// AcceptInvite sets the username and password on an invited tenant's user and activates it.
+// Like GetCredentials it runs before there are claims, the token is what authorizes it
+func (t *TenantRepo) AcceptInvite(ctx context.Context, tokenHash string, username string, password string, now time.Time) (model.User, error) {
+	var invite model.TenantInvite
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return model.User{}, ErrTransactionStartFailed{Err: err}
+	}
+
+	err = openTenantInviteQuery(tx, &invite, tokenHash, now).Scan(ctx)
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.User{}, ErrRollbackFailed{Err: rbErr}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, ErrInviteInvalid
+		}
+		return model.User{}, ErrFetchFailed{Model: "Tenant Invite", Err: err}
+	}
+
+	usr, err := activateUser(ctx, tx, invite.Uid, username, password, now)
+	if err == nil {
+		_, err = tx.NewUpdate().Model(&invite).Set("accepted_at = ?", now).WherePK().Exec(ctx)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.User{}, ErrRollbackFailed{Err: rbErr}
+		}
+		return model.User{}, ErrUpdateFailed{Model: "User", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.User{}, ErrTransactionCommitFail{Err: err}
+	}
+
+	return usr, nil
+}
+
+// Update changes the tenant's property and move dates, a new property has to belong to
+// the tenant's lessor
+func (t *TenantRepo) Update(ctx context.Context, tnt any) (interface{}, error) {
+	tenant, ok := tnt.(model.Tenant)
+	if !ok {
+		return nil, cmerr.ErrUnexpectedData{Wanted: model.Tenant{}, Got: tnt}
+	}
+
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := t.Fetch(ctx, filters.Filter{Identifier: tenant.Uid.String(), Page: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	if tenant.PropertyId != uuid.Nil {
+		if err = t.checkProperty(ctx, scope, existing.(model.Tenant).LessorId, tenant.PropertyId); err != nil {
+			return nil, err
+		}
+	}
+
+	err = t.GetBunDB().NewUpdate().Model(&tenant).Column("property_id", "move_in_date", "move_out_date").OmitZero().
+		Where("? = ?", bun.Ident("tnt.uid"), tenant.Uid).ApplyQueryBuilder(scope.ByLessor("tnt.lessor_id")).
+		Returning("*").Scan(ctx, &tenant)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, scope.resolveMissing(ctx, t.tenantExists(tenant.Uid.String()), "Tenant", tenant.Uid.String(),
+				ErrNoResults{Shape: tenant, Identifier: tenant.Uid.String(), Err: err})
+		}
+		return nil, ErrUpdateFailed{Model: "Tenant", Err: err}
+	}
+
+	return tenant, nil
+}
+
+// Delete removes the tenant and any open invite, the user is deactivated rather than
+// deleted so their ledger history still has someone to point at
+func (t *TenantRepo) Delete(ctx context.Context, tnt any) error {
+	tenant, ok := tnt.(model.Tenant)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: model.Tenant{}, Got: tnt}
+	}
+
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	rslt, err := tx.NewDelete().Model(&tenant).Where("? = ?", bun.Ident("tnt.uid"), tenant.Uid).
+		ApplyQueryBuilder(scope.ByLessor("tnt.lessor_id")).Exec(ctx)
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{Err: rbErr}
+		}
+		return ErrDeleteFailed{Model: "Tenant", Err: err}
+	}
+
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{Err: rbErr}
+		}
+		return scope.resolveMissing(ctx, t.tenantExists(tenant.Uid.String()), "Tenant", tenant.Uid.String(),
+			ErrNoResults{Shape: tenant, Identifier: tenant.Uid.String(), Err: sql.ErrNoRows})
+	}
+
+	_, err = deleteTenantInvitesQuery(tx, tenant.Uid).Exec(ctx)
+
+	if err == nil {
+		_, err = tx.NewUpdate().Model((*model.User)(nil)).Set("is_active = ?", false).
+			Where("? = ?", bun.Ident("uid"), tenant.Uid).Exec(ctx)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{Err: rbErr}
+		}
+		return ErrDeleteFailed{Model: "Tenant", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{Err: err}
+	}
+
+	return nil
+}
+
+func (t *TenantRepo) checkProperty(ctx context.Context, scope TenantScope, lessorId uuid.UUID, propertyId uuid.UUID) error {
+	if !scope.Owns(lessorId) {
+		return ErrCrossTenant{Model: "Tenant", Identifier: lessorId.String()}
+	}
+
+	owned, err := ownedPropertyQuery(t.GetBunDB(), lessorId, propertyId).Exists(ctx)
+	if err != nil {
+		return ErrFetchFailed{Model: "Property", Err: err}
+	}
+
+	if !owned {
+		return ErrCrossTenant{Model: "Tenant", Identifier: propertyId.String()}
+	}
+
+	return nil
+}
+
+// openTenantInviteQuery locks the invite for the token as long as it hasn't expired or
+// been accepted, so a token only ever signs up one user
+func openTenantInviteQuery(db bun.IDB, invite *model.TenantInvite, tokenHash string, now time.Time) *bun.SelectQuery {
+	return db.NewSelect().Model(invite).Where("? = ?", bun.Ident("ti.token_hash"), tokenHash).
+		Where("ti.accepted_at IS NULL").Where("? > ?", bun.Ident("ti.expires_at"), now).For("UPDATE")
+}
+
+// deleteTenantInvitesQuery drops the tenant's open invites, accepted ones are kept as the
+// record of when the tenant signed up
+func deleteTenantInvitesQuery(db bun.IDB, uid uuid.UUID) *bun.DeleteQuery {
+	return db.NewDelete().Model((*model.TenantInvite)(nil)).Where("? = ?", bun.Ident("uid"), uid).
+		Where("accepted_at IS NULL")
+}
+
+func ownedPropertyQuery(db bun.IDB, lessorId uuid.UUID, propertyId uuid.UUID) *bun.SelectQuery {
+	return db.NewSelect().Model((*model.Property)(nil)).Where("? = ?", bun.Ident("pid"), propertyId).
+		Where("? = ?", bun.Ident("p.lessor_id"), lessorId)
+}
+
+func (t *TenantRepo) tenantExists(uid string) *bun.SelectQuery {
+	return t.GetBunDB().NewSelect().Model((*model.Tenant)(nil)).Where("? = ?", bun.Ident("uid"), uid)
+}
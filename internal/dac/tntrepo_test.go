@@ -0,0 +1,78 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestOpenTenantInviteQuery(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	now := time.Date(2025, time.March, 3, 8, 0, 0, 0, time.UTC)
+
+	query := openTenantInviteQuery(db, &model.TenantInvite{}, "abc123", now).String()
+
+	for _, expected := range []string{
+		`"ti"."token_hash" = 'abc123'`,
+		// an accepted token can't be used again
+		`ti.accepted_at IS NULL`,
+		// an expired token can't be used at all
+		`"ti"."expires_at" > '2025-03-03 08:00:00+00:00'`,
+		`FOR UPDATE`,
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("expected query to contain %v, got %v", expected, query)
+		}
+	}
+}
+
+func TestDeleteTenantInvitesQuery(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	uid := uuid.New()
+
+	query := deleteTenantInvitesQuery(db, uid).String()
+
+	for _, expected := range []string{
+		`DELETE FROM "tenant_invites"`,
+		`"uid" = '` + uid.String() + `'`,
+		`accepted_at IS NULL`,
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("expected query to contain %v, got %v", expected, query)
+		}
+	}
+}
+
+func TestTenantPropertyCrossLessor(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	lessorId, propertyId := uuid.New(), uuid.New()
+
+	query := ownedPropertyQuery(db, lessorId, propertyId).String()
+	if !strings.Contains(query, `"p"."lessor_id" = '`+lessorId.String()+`'`) {
+		t.Errorf("expected the property to be checked against the tenant's lessor, got %v", query)
+	}
+
+	// a lessor naming someone else's lessor id is turned away before the property is looked up
+	repo := TenantRepo{}
+	scope := TenantScope{LessorId: uuid.New()}
+
+	var crossTenant ErrCrossTenant
+	if err := repo.checkProperty(context.Background(), scope, lessorId, propertyId); !errors.As(err, &crossTenant) {
+		t.Errorf("expected a tenant for another lessor to fail with %T, got %v", crossTenant, err)
+	}
+
+	ctx := auth.WithClaims(context.Background(), &auth.UserClaims{Id: scope.LessorId.String(), Role: string(auth.Alessor)})
+	err := repo.ReissueInvite(ctx, &model.TenantInvite{Uid: uuid.New(), LessorId: lessorId})
+	if !errors.As(err, &crossTenant) {
+		t.Errorf("expected reissuing another lessor's invite to fail with %T, got %v", crossTenant, err)
+	}
+}
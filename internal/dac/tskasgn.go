@@ -0,0 +1,454 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// FetchAssignments returns the task's crew with the lead first
+func (t *TaskRepo) FetchAssignments(ctx context.Context, tid uuid.UUID) ([]model.AssignedTask, error) {
+	assignments := make([]model.AssignedTask, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.GetBunDB().NewSelect().Model(&assignments).Where("? = ?", bun.Ident("at.task_id"), tid).
+		ApplyQueryBuilder(scope.ByLessor("at.lessor_id")).Relation("Worker").Relation("Worker.User").
+		Order("at.is_lead DESC", "at.assigned_at ASC").Scan(ctx, &assignments)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Assigned Task", Err: err}
+	}
+
+	if len(assignments) == 0 {
+		exists, err := t.taskExists(tid.String()).ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).Exists(ctx)
+		if err != nil {
+			return nil, ErrFetchFailed{Model: "Task", Err: err}
+		}
+		if !exists {
+			return nil, t.updateErr(ctx, scope, tid.String(), sql.ErrNoRows)
+		}
+	}
+
+	return assignments, nil
+}
+
+// FetchWorkerTasks returns every task the worker is on that they haven't declined, tasks
+// only assigned through the old worker id are included too
+func (t *TaskRepo) FetchWorkerTasks(ctx context.Context, workerId uuid.UUID, fltr filters.Filter) ([]model.Task, error) {
+	tasks := make([]model.Task, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := utils.DeterminRecordLimit(fltr.Limit)
+	err = t.GetBunDB().NewSelect().Model(&tasks).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("? = ?", bun.Ident("tsk.worker_id"), workerId).
+				WhereOr("? IN (SELECT task_id FROM assigned_tasks WHERE worker_id = ? AND status <> ?)",
+					bun.Ident("tsk.tid"), workerId, model.AssignmentDeclined)
+		}).
+		ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).Relation("Property").Relation("Assignments").
+		Order("tsk.scheduled_at ASC").Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &tasks)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Task", Err: err}
+	}
+
+	return tasks, nil
+}
+
+// FetchWorkerDay returns the worker's open tasks scheduled between from and to with their
+// properties loaded so they can be routed
+func (t *TaskRepo) FetchWorkerDay(ctx context.Context, workerId uuid.UUID, from time.Time, to time.Time) ([]model.Task, error) {
+	tasks := make([]model.Task, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.GetBunDB().NewSelect().Model(&tasks).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("? = ?", bun.Ident("tsk.worker_id"), workerId).
+				WhereOr("? IN (SELECT task_id FROM assigned_tasks WHERE worker_id = ? AND status <> ?)",
+					bun.Ident("tsk.tid"), workerId, model.AssignmentDeclined)
+		}).
+		Where("? IN (?)", bun.Ident("tsk.status"), bun.In([]model.TaskStatus{model.Scheduled, model.Started, model.Paused})).
+		Where("? >= ?", bun.Ident("tsk.scheduled_at"), from).Where("? < ?", bun.Ident("tsk.scheduled_at"), to).
+		ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).Relation("Property").
+		Order("tsk.scheduled_at ASC").Scan(ctx, &tasks)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Task", Err: err}
+	}
+
+	return tasks, nil
+}
+
+// AddWorkers puts workers on the task's crew, workers already on it are left alone. When
+// lead is set that worker becomes the lead and the task's worker id, a lead that isn't one
+// of the workers has to be on the crew already
+func (t *TaskRepo) AddWorkers(ctx context.Context, tid uuid.UUID, workerIds []uuid.UUID, lead uuid.UUID) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	tk, err := lockTask(ctx, tx, scope, tid)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return t.updateErr(ctx, scope, tid.String(), err)
+	}
+
+	workerIds = uniqueIds(workerIds)
+	count, err := tx.NewSelect().Model((*model.Worker)(nil)).Where("? IN (?)", bun.Ident("w.uid"), bun.In(workerIds)).
+		Where("? = ?", bun.Ident("w.lessor_id"), tk.LessorId).Count(ctx)
+	if err != nil {
+		err = ErrFetchFailed{Model: "Worker", Err: err}
+	} else if count != len(workerIds) {
+		err = ErrCrossTenant{Model: "Worker", Identifier: tid.String()}
+	}
+
+	if err == nil && lead != uuid.Nil && !slices.Contains(workerIds, lead) {
+		var leadOnCrew bool
+		if leadOnCrew, err = onCrew(ctx, tx, tid, lead); err == nil && !leadOnCrew {
+			err = ErrNoResults{Shape: model.AssignedTask{}, Identifier: lead.String(), Err: sql.ErrNoRows}
+		}
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return err
+	}
+
+	assignments := make([]model.AssignedTask, 0, len(workerIds))
+	for _, wid := range workerIds {
+		assignments = append(assignments, model.AssignedTask{
+			TaskId:     tid,
+			WorkerId:   wid,
+			LessorId:   tk.LessorId,
+			Status:     model.AssignmentPending,
+			AssignedAt: time.Now(),
+		})
+	}
+
+	var added []model.AssignedTask
+	err = tx.NewInsert().Model(&assignments).On("CONFLICT (task_id, worker_id) DO NOTHING").
+		Returning("worker_id").Scan(ctx, &added)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		err = ErrInsertFailed{Model: "Assigned Task", Err: err}
+	} else {
+		err = nil
+	}
+
+	if err == nil && lead != uuid.Nil && lead != tk.WorkerId {
+		err = t.moveLead(ctx, tx, &tk, lead)
+	}
+
+	if err == nil {
+		events := make([]model.TaskEvent, 0, len(added))
+		for _, at := range added {
+			events = append(events, newTaskEvent(ctx, tk, model.TaskWorkerAdded, map[string]interface{}{"workerId": at.WorkerId}))
+		}
+		err = insertTaskEvents(ctx, tx, events)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+// SetLead makes a worker already on the crew the lead
+func (t *TaskRepo) SetLead(ctx context.Context, tid uuid.UUID, workerId uuid.UUID) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	tk, err := lockTask(ctx, tx, scope, tid)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return t.updateErr(ctx, scope, tid.String(), err)
+	}
+
+	leadOnCrew, err := onCrew(ctx, tx, tid, workerId)
+
+	if err == nil && !leadOnCrew {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrNoResults{Shape: model.AssignedTask{}, Identifier: workerId.String(), Err: sql.ErrNoRows}
+	}
+
+	if err == nil {
+		err = t.moveLead(ctx, tx, &tk, workerId)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+// RemoveWorker takes a worker off the crew, removing the lead leaves the task without one
+func (t *TaskRepo) RemoveWorker(ctx context.Context, tid uuid.UUID, workerId uuid.UUID) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	tk, err := lockTask(ctx, tx, scope, tid)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return t.updateErr(ctx, scope, tid.String(), err)
+	}
+
+	rslt, err := tx.NewDelete().Model((*model.AssignedTask)(nil)).Where("? = ?", bun.Ident("task_id"), tid).
+		Where("? = ?", bun.Ident("worker_id"), workerId).Exec(ctx)
+	if err != nil {
+		err = ErrDeleteFailed{Model: "Assigned Task", Err: err}
+	}
+
+	if err == nil {
+		if n, _ := rslt.RowsAffected(); n == 0 && tk.WorkerId != workerId {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return ErrRollbackFailed{rbErr}
+			}
+			return ErrNoResults{Shape: model.AssignedTask{}, Identifier: workerId.String(), Err: sql.ErrNoRows}
+		}
+	}
+
+	if err == nil && tk.WorkerId == workerId {
+		err = clearLead(ctx, tx, tid)
+	}
+
+	if err == nil {
+		err = insertTaskEvents(ctx, tx, []model.TaskEvent{
+			newTaskEvent(ctx, tk, model.TaskWorkerRemoved, map[string]interface{}{"workerId": workerId}),
+		})
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+
+	return nil
+}
+
+// Respond records the signed in worker accepting or declining their place on a task, a
+// lead that declines stops being the lead
+func (t *TaskRepo) Respond(ctx context.Context, tid uuid.UUID, status model.AssignmentStatus, reason string) (model.AssignedTask, error) {
+	var at model.AssignedTask
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.AssignedTask{}, err
+	}
+
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return model.AssignedTask{}, ErrMissingScope{Err: err}
+	}
+
+	workerId, err := uuid.Parse(claims.Id)
+	if err != nil {
+		return model.AssignedTask{}, ErrMissingScope{Err: err}
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return model.AssignedTask{}, ErrTransactionStartFailed{Err: err}
+	}
+
+	tk, err := lockTask(ctx, tx, scope, tid)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.AssignedTask{}, ErrRollbackFailed{rbErr}
+		}
+		return model.AssignedTask{}, t.updateErr(ctx, scope, tid.String(), err)
+	}
+
+	err = tx.NewUpdate().Model(&at).Set("status = ?", status).Set("declined_reason = ?", reason).
+		Set("responded_at = ?", time.Now()).Where("? = ?", bun.Ident("at.task_id"), tid).
+		Where("? = ?", bun.Ident("at.worker_id"), workerId).Returning("*").Scan(ctx, &at)
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.AssignedTask{}, ErrRollbackFailed{rbErr}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.AssignedTask{}, ErrNoResults{Shape: at, Identifier: tid.String(), Err: err}
+		}
+		return model.AssignedTask{}, ErrUpdateFailed{Model: "Assigned Task", Err: err}
+	}
+
+	eventType := model.TaskWorkerAccepted
+	if status == model.AssignmentDeclined {
+		eventType = model.TaskWorkerDeclined
+		if at.IsLead || tk.WorkerId == workerId {
+			err = clearLead(ctx, tx, tid)
+			at.IsLead = false
+		}
+	}
+
+	if err == nil {
+		err = insertTaskEvents(ctx, tx, []model.TaskEvent{
+			newTaskEvent(ctx, tk, eventType, map[string]interface{}{"workerId": workerId, "reason": reason}),
+		})
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.AssignedTask{}, ErrRollbackFailed{rbErr}
+		}
+		return model.AssignedTask{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.AssignedTask{}, ErrTransactionCommitFail{err}
+	}
+
+	return at, nil
+}
+
+// onCrew is whether the worker is on the task's crew and hasn't declined it
+func onCrew(ctx context.Context, tx bun.Tx, tid uuid.UUID, workerId uuid.UUID) (bool, error) {
+	exists, err := tx.NewSelect().Model((*model.AssignedTask)(nil)).Where("? = ?", bun.Ident("at.task_id"), tid).
+		Where("? = ?", bun.Ident("at.worker_id"), workerId).Where("? <> ?", bun.Ident("at.status"), model.AssignmentDeclined).
+		Exists(ctx)
+	if err != nil {
+		return false, ErrFetchFailed{Model: "Assigned Task", Err: err}
+	}
+
+	return exists, nil
+}
+
+// moveLead points the task's worker id at the new lead and flags their assignment, the
+// change shows up on the timeline as a reassignment
+func (t *TaskRepo) moveLead(ctx context.Context, tx bun.Tx, tk *model.Task, lead uuid.UUID) error {
+	from := tk.WorkerId
+	_, err := tx.NewUpdate().Model((*model.Task)(nil)).Set("worker_id = ?", lead).
+		Where("? = ?", bun.Ident("tid"), tk.Tid).Exec(ctx)
+	if err != nil {
+		return ErrUpdateFailed{Model: "Task", Err: err}
+	}
+
+	tk.WorkerId = lead
+	if err = syncLead(ctx, tx, *tk); err != nil {
+		return err
+	}
+
+	return insertTaskEvents(ctx, tx, []model.TaskEvent{
+		newTaskEvent(ctx, *tk, model.TaskReassigned, map[string]interface{}{"from": from, "to": lead}),
+	})
+}
+
+// syncLead keeps the crew in step with Task.WorkerId, it is called wherever the worker id is
+// written so setting it directly still makes that worker the lead
+func syncLead(ctx context.Context, tx bun.Tx, tk model.Task) error {
+	_, err := tx.NewUpdate().Model((*model.AssignedTask)(nil)).Set("is_lead = ?", false).
+		Where("? = ?", bun.Ident("task_id"), tk.Tid).Where("is_lead").Exec(ctx)
+	if err != nil {
+		return ErrUpdateFailed{Model: "Assigned Task", Err: err}
+	}
+
+	if tk.WorkerId == uuid.Nil {
+		return nil
+	}
+
+	lead := &model.AssignedTask{
+		TaskId:     tk.Tid,
+		WorkerId:   tk.WorkerId,
+		LessorId:   tk.LessorId,
+		IsLead:     true,
+		Status:     model.AssignmentPending,
+		AssignedAt: time.Now(),
+	}
+
+	_, err = tx.NewInsert().Model(lead).On("CONFLICT (task_id, worker_id) DO UPDATE").Set("is_lead = EXCLUDED.is_lead").Exec(ctx)
+	if err != nil {
+		return ErrUpdateFailed{Model: "Assigned Task", Err: err}
+	}
+
+	return nil
+}
+
+func clearLead(ctx context.Context, tx bun.Tx, tid uuid.UUID) error {
+	_, err := tx.NewUpdate().Model((*model.Task)(nil)).Set("worker_id = NULL").Where("? = ?", bun.Ident("tid"), tid).Exec(ctx)
+	if err != nil {
+		return ErrUpdateFailed{Model: "Task", Err: err}
+	}
+
+	return syncLead(ctx, tx, model.Task{Tid: tid})
+}
+
+// uniqueIds drops repeated ids, keeping the order they were first given in
+func uniqueIds(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
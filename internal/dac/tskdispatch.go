@@ -0,0 +1,105 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// DispatchWorker is a worker who could take a task along with what dispatch needs to rank
+// them, LastAddress is the property of their most recent job before the task
+type DispatchWorker struct {
+	Worker      model.Worker
+	OpenTasks   int
+	LastAddress json.RawMessage
+}
+
+// workerTasksJoin joins the tasks a worker leads or is on the crew for without declining
+const workerTasksJoin = "JOIN tasks AS tsk ON (tsk.worker_id = w.uid OR tsk.tid IN (SELECT task_id FROM assigned_tasks WHERE worker_id = w.uid AND status <> ?))"
+
+// FetchDispatchPool returns the task lessor's current workers with their open workload and
+// where they were last working
+func (t *TaskRepo) FetchDispatchPool(ctx context.Context, tk model.Task) ([]DispatchWorker, error) {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = scope.Check("Task", tk.LessorId.String()); err != nil {
+		return nil, err
+	}
+
+	workers := make([]model.Worker, 0)
+	err = t.GetBunDB().NewSelect().Model(&workers).Where("? = ?", bun.Ident("w.lessor_id"), tk.LessorId).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("? IS NULL", bun.Ident("w.end_date")).WhereOr("? > now()", bun.Ident("w.end_date"))
+		}).Relation("User").Scan(ctx, &workers)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "worker", Err: err}
+	}
+
+	if len(workers) == 0 {
+		return []DispatchWorker{}, nil
+	}
+
+	var loads []struct {
+		WorkerId uuid.UUID `bun:"worker_id"`
+		Open     int       `bun:"open"`
+	}
+	err = t.GetBunDB().NewSelect().TableExpr("workers AS w").
+		ColumnExpr("w.uid AS worker_id").ColumnExpr("count(DISTINCT tsk.tid) AS open").
+		Join(workerTasksJoin, model.AssignmentDeclined).
+		Where("w.lessor_id = ?", tk.LessorId).Where("tsk.tid <> ?", tk.Tid).
+		Where("tsk.status IN (?)", bun.In([]model.TaskStatus{model.Scheduled, model.Started, model.Paused})).
+		Group("w.uid").Scan(ctx, &loads)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Task", Err: err}
+	}
+
+	before := tk.ScheduledAt
+	if before.IsZero() {
+		before = time.Now()
+	}
+
+	var last []struct {
+		WorkerId uuid.UUID       `bun:"worker_id"`
+		Address  json.RawMessage `bun:"address"`
+	}
+	err = t.GetBunDB().NewSelect().TableExpr("workers AS w").DistinctOn("w.uid").
+		ColumnExpr("w.uid AS worker_id").ColumnExpr("p.address").
+		Join(workerTasksJoin, model.AssignmentDeclined).
+		Join("JOIN properties AS p ON p.pid = tsk.property_id").
+		Where("w.lessor_id = ?", tk.LessorId).Where("tsk.tid <> ?", tk.Tid).
+		Where("tsk.status <> ?", model.Failed).Where("tsk.scheduled_at <= ?", before).
+		OrderExpr("w.uid, tsk.scheduled_at DESC").Scan(ctx, &last)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Task", Err: err}
+	}
+
+	pool := make([]DispatchWorker, 0, len(workers))
+	for _, w := range workers {
+		dw := DispatchWorker{Worker: w}
+		for _, l := range loads {
+			if l.WorkerId == w.Uid {
+				dw.OpenTasks = l.Open
+			}
+		}
+		for _, l := range last {
+			if l.WorkerId == w.Uid {
+				dw.LastAddress = l.Address
+			}
+		}
+		pool = append(pool, dw)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,132 @@
+package dac
+
+import (
+	"context"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// newTaskEvent stamps the event with whoever made the request, jobs running without
+// claims are recorded with no actor
+func newTaskEvent(ctx context.Context, tk model.Task, eventType model.TaskEventType, details map[string]interface{}) model.TaskEvent {
+	event := model.TaskEvent{
+		TaskId:    tk.Tid,
+		LessorId:  tk.LessorId,
+		EventType: eventType,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+
+	if claims, err := auth.ClaimsFromContext(ctx); err == nil {
+		event.ActorId, _ = uuid.Parse(claims.Id)
+		event.ActorName = claims.Username
+		event.ActorRole = claims.Role
+	}
+
+	return event
+}
+
+func insertTaskEvents(ctx context.Context, tx bun.Tx, events []model.TaskEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if _, err := tx.NewInsert().Model(&events).Exec(ctx); err != nil {
+		return ErrInsertFailed{Model: "Task Event", Err: err}
+	}
+
+	if err := publishTaskEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	return enqueueTaskWebhooks(ctx, tx, events)
+}
+
+// lockTask reads the task as it is before a write so the change can be diffed,
+// the row stays locked until the transaction ends
+func lockTask(ctx context.Context, tx bun.Tx, scope TenantScope, tid uuid.UUID) (model.Task, error) {
+	var before model.Task
+	err := tx.NewSelect().Model(&before).Where("? = ?", bun.Ident("tsk.tid"), tid).
+		ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).For("UPDATE").Scan(ctx, &before)
+
+	return before, err
+}
+
+// taskChanges turns the difference between two versions of a task into timeline events
+func taskChanges(ctx context.Context, before, after model.Task) []model.TaskEvent {
+	var events []model.TaskEvent
+
+	if before.WorkerId != after.WorkerId {
+		events = append(events, newTaskEvent(ctx, after, model.TaskReassigned, map[string]interface{}{
+			"from": before.WorkerId,
+			"to":   after.WorkerId,
+		}))
+	}
+
+	if before.Priority != after.Priority {
+		events = append(events, newTaskEvent(ctx, after, model.TaskPriorityChanged, map[string]interface{}{
+			"from": before.Priority,
+			"to":   after.Priority,
+		}))
+	}
+
+	if before.Notes != after.Notes || before.Details != after.Details {
+		events = append(events, newTaskEvent(ctx, after, model.TaskNotesEdited, map[string]interface{}{
+			"notes":   after.Notes,
+			"details": after.Details,
+		}))
+	}
+
+	if !before.EstimatedCost.Equal(after.EstimatedCost) || !before.ActualCost.Equal(after.ActualCost) {
+		events = append(events, newTaskEvent(ctx, after, model.TaskCostChanged, map[string]interface{}{
+			"estimatedCost": map[string]interface{}{"from": before.EstimatedCost, "to": after.EstimatedCost},
+			"actualCost":    map[string]interface{}{"from": before.ActualCost, "to": after.ActualCost},
+		}))
+	}
+
+	var fields []string
+	if before.Name != after.Name {
+		fields = append(fields, "name")
+	}
+	if before.Category != after.Category {
+		fields = append(fields, "category")
+	}
+	if before.PropertyId != after.PropertyId {
+		fields = append(fields, "propertyId")
+	}
+	if !before.ScheduledAt.Equal(after.ScheduledAt) {
+		fields = append(fields, "scheduledAt")
+	}
+	if before.Image != after.Image {
+		fields = append(fields, "image")
+	}
+
+	if len(fields) > 0 {
+		events = append(events, newTaskEvent(ctx, after, model.TaskUpdated, map[string]interface{}{
+			"fields": fields,
+		}))
+	}
+
+	return events
+}
+
+func transitionEvent(ctx context.Context, from model.TaskStatus, tk model.Task) model.TaskEvent {
+	switch tk.Status {
+	case model.Started:
+		if from == model.Paused {
+			return newTaskEvent(ctx, tk, model.TaskResumed, nil)
+		}
+		return newTaskEvent(ctx, tk, model.TaskStarted, map[string]interface{}{"workerId": tk.WorkerId})
+	case model.Paused:
+		return newTaskEvent(ctx, tk, model.TaskPaused, map[string]interface{}{"reason": tk.PausedReason})
+	case model.Finished:
+		return newTaskEvent(ctx, tk, model.TaskCompleted, nil)
+	case model.Failed:
+		return newTaskEvent(ctx, tk, model.TaskFailed, map[string]interface{}{"reason": tk.FailedReason})
+	default:
+		return newTaskEvent(ctx, tk, model.TaskUpdated, map[string]interface{}{"status": tk.Status})
+	}
+}
@@ -0,0 +1,204 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+)
+
+// FetchFees returns the task's line items oldest first
+func (t *TaskRepo) FetchFees(ctx context.Context, tid uuid.UUID) ([]model.TaskFee, error) {
+	fees := make([]model.TaskFee, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.GetBunDB().NewSelect().Model(&fees).Where("? = ?", bun.Ident("tf.task_id"), tid).
+		ApplyQueryBuilder(scope.ByLessor("tf.lessor_id")).Order("tf.created_at ASC").Scan(ctx, &fees)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Task Fee", Err: err}
+	}
+
+	if len(fees) == 0 {
+		exists, err := t.taskExists(tid.String()).ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).Exists(ctx)
+		if err != nil {
+			return nil, ErrFetchFailed{Model: "Task", Err: err}
+		}
+		if !exists {
+			return nil, t.updateErr(ctx, scope, tid.String(), sql.ErrNoRows)
+		}
+	}
+
+	return fees, nil
+}
+
+// AddFee adds a line item to the task and rolls its cost up into the task
+func (t *TaskRepo) AddFee(ctx context.Context, fee *model.TaskFee) (model.Task, error) {
+	return t.changeFees(ctx, fee.TaskId, func(tx bun.Tx, tk model.Task) error {
+		fee.LessorId = tk.LessorId
+		if err := tx.NewInsert().Model(fee).Returning("*").Scan(ctx, fee); err != nil {
+			return ErrInsertFailed{Model: "Task Fee", Err: err}
+		}
+		return nil
+	})
+}
+
+// UpdateFee rewrites an unprocessed line item and rolls the change up into the task
+func (t *TaskRepo) UpdateFee(ctx context.Context, fee *model.TaskFee) (model.Task, error) {
+	return t.changeFees(ctx, fee.TaskId, func(tx bun.Tx, tk model.Task) error {
+		if _, err := lockFee(ctx, tx, fee.TaskId, fee.Fid); err != nil {
+			return err
+		}
+
+		err := tx.NewUpdate().Model(fee).Column("kind", "material", "quantity", "unit_cost", "cost", "details").
+			Where("? = ?", bun.Ident("tf.fid"), fee.Fid).Returning("*").Scan(ctx, fee)
+		if err != nil {
+			return ErrUpdateFailed{Model: "Task Fee", Err: err}
+		}
+		return nil
+	})
+}
+
+func (t *TaskRepo) RemoveFee(ctx context.Context, tid uuid.UUID, fid uuid.UUID) (model.Task, error) {
+	return t.changeFees(ctx, tid, func(tx bun.Tx, tk model.Task) error {
+		if _, err := lockFee(ctx, tx, tid, fid); err != nil {
+			return err
+		}
+
+		if _, err := tx.NewDelete().Model((*model.TaskFee)(nil)).Where("? = ?", bun.Ident("tf.fid"), fid).Exec(ctx); err != nil {
+			return ErrDeleteFailed{Model: "Task Fee", Err: err}
+		}
+		return nil
+	})
+}
+
+// ProcessFee marks the line item as paid out or billed, after that it is locked. The cost
+// doesn't change so the task is left alone
+func (t *TaskRepo) ProcessFee(ctx context.Context, tid uuid.UUID, fid uuid.UUID) (model.TaskFee, error) {
+	var fee model.TaskFee
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.TaskFee{}, err
+	}
+
+	err = t.GetBunDB().NewUpdate().Model(&fee).Set("processed = ?", true).Set("processed_at = ?", time.Now()).
+		Where("? = ?", bun.Ident("tf.fid"), fid).Where("? = ?", bun.Ident("tf.task_id"), tid).
+		Where("? = ?", bun.Ident("tf.processed"), false).ApplyQueryBuilder(scope.ByLessor("tf.lessor_id")).
+		Returning("*").Scan(ctx, &fee)
+
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return model.TaskFee{}, ErrUpdateFailed{Model: "Task Fee", Err: err}
+		}
+
+		exists := t.GetBunDB().NewSelect().Model((*model.TaskFee)(nil)).Where("? = ?", bun.Ident("fid"), fid).
+			Where("? = ?", bun.Ident("task_id"), tid)
+		if err = scope.resolveMissing(ctx, exists, "Task Fee", fid.String(),
+			ErrNoResults{Shape: model.TaskFee{}, Identifier: fid.String(), Err: err}); err != nil {
+			return model.TaskFee{}, err
+		}
+		return model.TaskFee{}, ErrFeeProcessed
+	}
+
+	return fee, nil
+}
+
+// changeFees locks the task, applies the change to its fees and then rolls the fees back up
+// into the task's actual cost and profit in the same transaction
+func (t *TaskRepo) changeFees(ctx context.Context, tid uuid.UUID, change func(bun.Tx, model.Task) error) (model.Task, error) {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return model.Task{}, ErrTransactionStartFailed{Err: err}
+	}
+
+	before, err := lockTask(ctx, tx, scope, tid)
+	if err != nil {
+		err = t.updateErr(ctx, scope, tid.String(), err)
+	}
+
+	if err == nil {
+		err = change(tx, before)
+	}
+
+	after := before
+	if err == nil {
+		after, err = rollupCosts(ctx, tx, before)
+	}
+
+	if err == nil {
+		err = insertTaskEvents(ctx, tx, taskChanges(ctx, before, after))
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.Task{}, ErrRollbackFailed{rbErr}
+		}
+		return model.Task{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return model.Task{}, ErrTransactionCommitFail{err}
+	}
+
+	return after, nil
+}
+
+// lockFee fails once the fee has been processed, it has been paid out or billed as it is
+func lockFee(ctx context.Context, tx bun.Tx, tid uuid.UUID, fid uuid.UUID) (model.TaskFee, error) {
+	var fee model.TaskFee
+	err := tx.NewSelect().Model(&fee).Where("? = ?", bun.Ident("tf.fid"), fid).
+		Where("? = ?", bun.Ident("tf.task_id"), tid).For("UPDATE").Scan(ctx, &fee)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TaskFee{}, ErrNoResults{Shape: fee, Identifier: fid.String(), Err: err}
+		}
+		return model.TaskFee{}, ErrFetchFailed{Model: "Task Fee", Err: err}
+	}
+
+	if fee.Processed {
+		return model.TaskFee{}, ErrFeeProcessed
+	}
+
+	return fee, nil
+}
+
+// rollupCosts sets the task's actual cost to the total of its fees and works out the profit
+// against what was billed
+func rollupCosts(ctx context.Context, tx bun.Tx, tk model.Task) (model.Task, error) {
+	var total decimal.Decimal
+	err := tx.NewSelect().Model((*model.TaskFee)(nil)).ColumnExpr("COALESCE(SUM(?), 0)", bun.Ident("tf.cost")).
+		Where("? = ?", bun.Ident("tf.task_id"), tk.Tid).Scan(ctx, &total)
+	if err != nil {
+		return model.Task{}, ErrFetchFailed{Model: "Task Fee", Err: err}
+	}
+
+	tk.ActualCost = total
+	if err = setProfit(ctx, tx, &tk); err != nil {
+		return model.Task{}, err
+	}
+
+	return tk, nil
+}
+
+func setProfit(ctx context.Context, tx bun.Tx, tk *model.Task) error {
+	tk.Profit = tk.BilledAmount.Sub(tk.ActualCost)
+	_, err := tx.NewUpdate().Model(tk).Column("actual_cost", "profit").Where("? = ?", bun.Ident("tid"), tk.Tid).Exec(ctx)
+	if err != nil {
+		return ErrUpdateFailed{Model: "Task", Err: err}
+	}
+	return nil
+}
@@ -3,12 +3,16 @@ package dac
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
+	"slices"
 
 	"github.com/Z3DRP/lessor-service/internal/cmerr"
 	"github.com/Z3DRP/lessor-service/internal/filters"
 	"github.com/Z3DRP/lessor-service/internal/model"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"github.com/uptrace/bun"
 )
 
@@ -25,13 +29,20 @@ func InitTskRepo(db Persister) TaskRepo {
 
 func (t *TaskRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
 	var tsk model.Task
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := t.GetBunDB().NewSelect().Model(&tsk).
-		Where("? = ?", bun.Ident("tid"), fltr.Identifier).Relation("Worker").Relation("Property").Limit(limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
+	err = t.GetBunDB().NewSelect().Model(&tsk).
+		Where("? = ?", bun.Ident("tsk.tid"), fltr.Identifier).ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).
+		Relation("Worker").Relation("Property").Relation("Assignments").Limit(limit).Offset(10 * (fltr.Page - 1)).Scan(ctx)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, ErrNoResults{Shape: tsk, Identifier: fltr.Identifier, Err: err}
+			return nil, scope.resolveMissing(ctx, t.taskExists(fltr.Identifier), "Task", fltr.Identifier,
+				ErrNoResults{Shape: tsk, Identifier: fltr.Identifier, Err: err})
 		}
 
 		return nil, ErrFetchFailed{Model: "Task", Err: err}
@@ -43,11 +54,21 @@ func (t *TaskRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{},
 func (t TaskRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.Task, error) {
 	var tsks []model.Task
 	log.Printf("making db  call now")
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byLessor, err := scope.ForLessor("Task", "tsk.lessor_id", fltr.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
 
 	log.Printf("limit is %v", limit)
 
-	err := t.GetBunDB().NewSelect().Model(&tsks).Relation("Property").Relation("Alessor").Relation("Worker").Relation("Worker.User").Scan(ctx, &tsks)
+	err = t.GetBunDB().NewSelect().Model(&tsks).ApplyQueryBuilder(byLessor).Relation("Property").Relation("Alessor").Relation("Worker").Relation("Worker.User").Scan(ctx, &tsks)
 	for _, tk := range tsks {
 		log.Println("task worker data: ")
 		log.Printf("worker: %v", tk.Worker)
@@ -74,12 +95,24 @@ func (t *TaskRepo) Insert(ctx context.Context, tsk any) (interface{}, error) {
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scope.Owns(tk.LessorId) {
+		return nil, ErrCrossTenant{Model: "Task", Identifier: tk.Tid.String()}
+	}
+
 	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		log.Printf("failed to start transaction %v", err)
 		return nil, ErrTransactionStartFailed{Err: err}
 	}
 
+	// a new task has no fees yet so it has cost nothing
+	tk.ActualCost = decimal.Zero
+	tk.Profit = tk.BilledAmount
 	err = tx.NewInsert().Model(tk).Returning("*").Scan(ctx, tk)
 	if err != nil {
 		if rbErr := tx.Rollback(); rbErr != nil {
@@ -88,6 +121,22 @@ func (t *TaskRepo) Insert(ctx context.Context, tsk any) (interface{}, error) {
 		return nil, ErrInsertFailed{Model: "Task", Err: err}
 	}
 
+	created := newTaskEvent(ctx, *tk, model.TaskCreated, map[string]interface{}{
+		"name":     tk.Name,
+		"priority": tk.Priority,
+		"workerId": tk.WorkerId,
+	})
+	if err = insertTaskEvents(ctx, tx, []model.TaskEvent{created}); err == nil && tk.WorkerId != uuid.Nil {
+		err = syncLead(ctx, tx, *tk)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, err
+	}
+
 	if err = tx.Commit(); err != nil {
 		log.Printf("error committing %v", err)
 		return nil, ErrTransactionCommitFail{err}
@@ -102,97 +151,95 @@ func (t *TaskRepo) Update(ctx context.Context, tsk any) (interface{}, error) {
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, ErrTransactionStartFailed{Err: err}
 	}
 
-	err = tx.NewUpdate().Model(tk).OmitZero().Where("? = ?", bun.Ident("tid"), tk.Tid).Returning("*").Scan(ctx, tk)
+	before, err := lockTask(ctx, tx, scope, tk.Tid)
 	if err != nil {
-		if err = tx.Rollback(); err != nil {
-			return nil, ErrRollbackFailed{err}
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
 		}
-		return nil, ErrUpdateFailed{Model: "Task", Err: err}
+		return nil, t.updateErr(ctx, scope, tk.Tid.String(), err)
 	}
 
-	if err = tx.Commit(); err != nil {
-		return nil, ErrTransactionCommitFail{err}
+	err = tx.NewUpdate().Model(tk).OmitZero().Where("? = ?", bun.Ident("tid"), tk.Tid).ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).Returning("*").Scan(ctx, tk)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, t.updateErr(ctx, scope, tk.Tid.String(), err)
 	}
-	return tk, nil
-}
 
-func (t *TaskRepo) UpdatePriority(ctx context.Context, tsk interface{}) (interface{}, error) {
-	tk, ok := tsk.(model.Task)
-	if !ok {
-		return nil, cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
+	if !before.BilledAmount.Equal(tk.BilledAmount) {
+		err = setProfit(ctx, tx, tk)
 	}
 
-	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return nil, ErrTransactionStartFailed{Err: err}
+	if err == nil {
+		err = insertTaskEvents(ctx, tx, taskChanges(ctx, before, *tk))
 	}
 
-	err = tx.NewUpdate().Model(&tk).OmitZero().Where("? = ?", bun.Ident("tid"), tk.Tid).Set("priority = ?", tk.Priority).Returning("*").Scan(ctx, &tk)
+	if err == nil && before.WorkerId != tk.WorkerId {
+		err = syncLead(ctx, tx, *tk)
+	}
 
 	if err != nil {
-		if err = tx.Rollback(); err != nil {
-			return nil, ErrRollbackFailed{err}
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
 		}
-		return nil, ErrUpdateFailed{Model: "Task", Err: err}
+		return nil, err
 	}
 
 	if err = tx.Commit(); err != nil {
 		return nil, ErrTransactionCommitFail{err}
 	}
-
 	return tk, nil
 }
 
-func (t *TaskRepo) UpdateStartedAt(ctx context.Context, tsk interface{}) (interface{}, error) {
+func (t *TaskRepo) UpdatePriority(ctx context.Context, tsk interface{}) (interface{}, error) {
 	tk, ok := tsk.(model.Task)
 	if !ok {
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, ErrTransactionStartFailed{Err: err}
 	}
 
-	err = tx.NewUpdate().Model(&tk).OmitZero().Where("? = ?", bun.Ident("tid"), tk.Tid).Set("started_at = ?", tk.StartedAt).Returning("*").Scan(ctx, &tk)
-
+	before, err := lockTask(ctx, tx, scope, tk.Tid)
 	if err != nil {
-		if err = tx.Rollback(); err != nil {
-			return nil, ErrRollbackFailed{err}
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
 		}
-		return nil, ErrUpdateFailed{Model: "Task", Err: err}
-	}
-
-	if err = tx.Commit(); err != nil {
-		return nil, ErrTransactionCommitFail{err}
+		return nil, t.updateErr(ctx, scope, tk.Tid.String(), err)
 	}
 
-	return tk, nil
-}
-
-func (t *TaskRepo) UpdateCompletedAt(ctx context.Context, tsk interface{}) (interface{}, error) {
-	tk, ok := tsk.(model.Task)
-	if !ok {
-		return nil, cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
-	}
+	err = tx.NewUpdate().Model(&tk).OmitZero().Where("? = ?", bun.Ident("tid"), tk.Tid).ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).Set("priority = ?", tk.Priority).Returning("*").Scan(ctx, &tk)
 
-	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		return nil, ErrTransactionStartFailed{Err: err}
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, t.updateErr(ctx, scope, tk.Tid.String(), err)
 	}
 
-	err = tx.NewUpdate().Model(&tk).OmitZero().Where("? = ?", bun.Ident("tid"), tk.Tid).Set("completed_at = ?", tk.CompletedAt).Returning("*").Scan(ctx, &tk)
-
-	if err != nil {
-		if err = tx.Rollback(); err != nil {
-			return nil, ErrRollbackFailed{err}
+	if err = insertTaskEvents(ctx, tx, taskChanges(ctx, before, tk)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
 		}
-		return nil, ErrUpdateFailed{Model: "Task", Err: err}
+		return nil, err
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -202,44 +249,64 @@ func (t *TaskRepo) UpdateCompletedAt(ctx context.Context, tsk interface{}) (inte
 	return tk, nil
 }
 
-func (t *TaskRepo) UpdatePausedAt(ctx context.Context, tsk interface{}) (interface{}, error) {
-	tk, ok := tsk.(model.Task)
-	if !ok {
-		return nil, cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
+// Transition writes the task's new status along with the given columns. The update only
+// applies while the task is still in the from status so two requests racing to move the
+// same task can't both succeed, the loser gets ErrStaleWrite
+func (t *TaskRepo) Transition(ctx context.Context, tk *model.Task, from model.TaskStatus, columns ...string) (model.Task, error) {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Task{}, err
 	}
 
 	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		return nil, ErrTransactionStartFailed{Err: err}
+		return model.Task{}, ErrTransactionStartFailed{Err: err}
 	}
 
-	err = tx.NewUpdate().Model(&tk).OmitZero().Where("? = ?", bun.Ident("tid"), tk.Tid).Set("paused_at = ?", tk.PausedAt).Returning("*").Scan(ctx, &tk)
+	err = tx.NewUpdate().Model(tk).Column(append([]string{"status"}, columns...)...).
+		Where("? = ?", bun.Ident("tid"), tk.Tid).Where("? = ?", bun.Ident("tsk.status"), from).
+		ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).Returning("*").Scan(ctx, tk)
 
 	if err != nil {
-		if err = tx.Rollback(); err != nil {
-			return nil, ErrRollbackFailed{err}
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.Task{}, ErrRollbackFailed{rbErr}
 		}
-		return nil, ErrUpdateFailed{Model: "Task", Err: err}
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Task{}, ErrStaleWrite
+		}
+		return model.Task{}, ErrUpdateFailed{Model: "Task", Err: err}
+	}
+
+	if err = insertTaskEvents(ctx, tx, []model.TaskEvent{transitionEvent(ctx, from, *tk)}); err == nil && slices.Contains(columns, "worker_id") {
+		err = syncLead(ctx, tx, *tk)
+	}
+
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return model.Task{}, ErrRollbackFailed{rbErr}
+		}
+		return model.Task{}, err
 	}
 
 	if err = tx.Commit(); err != nil {
-		return nil, ErrTransactionCommitFail{err}
+		return model.Task{}, ErrTransactionCommitFail{err}
 	}
 
-	return tk, nil
+	return *tk, nil
 }
 
 func (t *TaskRepo) BulkPriorityUpdate(ctx context.Context, tasks []interface{}) ([]model.Task, error) {
-	var err error
 	uTasks := make([]model.Task, 0)
+	rows := make([]model.Task, 0, len(tasks))
 	for _, tsk := range tasks {
-		_, ok := tsk.(model.Task)
+		tk, ok := tsk.(model.Task)
 		if !ok {
-			err = cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
-			break
+			return nil, cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
 		}
+		rows = append(rows, tk)
 	}
 
+	scope, err := NewTenantScope(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -249,31 +316,60 @@ func (t *TaskRepo) BulkPriorityUpdate(ctx context.Context, tasks []interface{})
 		return nil, ErrTransactionStartFailed{Err: err}
 	}
 
-	values := t.GetBunDB().NewValues(&tasks)
-	err = tx.NewUpdate().With("_data", values).
-		Model((*model.Task)(nil)).TableExpr("_data").
-		Set("task.priority = _data.priority").Where("task.tid = _data.tid").
-		Returning("*").Scan(ctx, &uTasks)
+	if err = bulkPriorityQuery(tx, &rows, scope).Scan(ctx, &uTasks); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
+		return nil, ErrUpdateFailed{Model: "Task", Err: err}
+	}
 
-	if err != nil {
+	// the previous priorities aren't returned by the bulk update so only the new one is recorded
+	events := make([]model.TaskEvent, 0, len(uTasks))
+	for _, tk := range uTasks {
+		events = append(events, newTaskEvent(ctx, tk, model.TaskPriorityChanged, map[string]interface{}{"to": tk.Priority}))
+	}
+
+	if err = insertTaskEvents(ctx, tx, events); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, ErrRollbackFailed{rbErr}
+		}
 		return nil, err
 	}
 
+	if err = tx.Commit(); err != nil {
+		return nil, ErrTransactionCommitFail{err}
+	}
+
 	return uTasks, nil
 }
 
+// bulkPriorityQuery sets each task's priority from the rows joined in as _data, postgres
+// doesn't allow the alias on the set column so only the where is qualified
+func bulkPriorityQuery(db bun.IDB, rows *[]model.Task, scope TenantScope) *bun.UpdateQuery {
+	return db.NewUpdate().With("_data", db.NewValues(rows)).
+		Model((*model.Task)(nil)).TableExpr("_data").
+		Set("priority = _data.priority").Where("tsk.tid = _data.tid").
+		ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).
+		Returning("tsk.*")
+}
+
 func (t *TaskRepo) Delete(ctx context.Context, tsk any) error {
 	tk, ok := tsk.(model.Task)
 	if !ok {
 		return cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
 	tx, err := t.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return ErrTransactionStartFailed{Err: err}
 	}
 
-	_, err = tx.NewDelete().Model(&tk).Where("? = ?", bun.Ident("tid"), tk.Tid).Exec(ctx)
+	rslt, err := tx.NewDelete().Model(&tk).Where("? = ?", bun.Ident("tid"), tk.Tid).ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).Returning("*").Exec(ctx)
 	if err != nil {
 		if err = tx.Rollback(); err != nil {
 			return ErrRollbackFailed{err}
@@ -281,8 +377,64 @@ func (t *TaskRepo) Delete(ctx context.Context, tsk any) error {
 		return err
 	}
 
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if err = tx.Rollback(); err != nil {
+			return ErrRollbackFailed{err}
+		}
+		return t.updateErr(ctx, scope, tk.Tid.String(), sql.ErrNoRows)
+	}
+
+	if err = insertTaskEvents(ctx, tx, []model.TaskEvent{newTaskEvent(ctx, tk, model.TaskDeleted, nil)}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return ErrTransactionCommitFail{err}
 	}
 	return nil
 }
+
+// FetchTimeline returns a task's events oldest first, events outlive the task so a deleted
+// task's timeline can still be read
+func (t *TaskRepo) FetchTimeline(ctx context.Context, tid string) ([]model.TaskEvent, error) {
+	events := make([]model.TaskEvent, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.GetBunDB().NewSelect().Model(&events).Where("? = ?", bun.Ident("te.task_id"), tid).
+		ApplyQueryBuilder(scope.ByLessor("te.lessor_id")).Order("te.created_at ASC", "te.id ASC").Scan(ctx, &events)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Task Event", Err: err}
+	}
+
+	if len(events) == 0 {
+		exists, err := t.taskExists(tid).ApplyQueryBuilder(scope.ByLessor("tsk.lessor_id")).Exists(ctx)
+		if err != nil {
+			return nil, ErrFetchFailed{Model: "Task", Err: err}
+		}
+		if !exists {
+			return nil, scope.resolveMissing(ctx, t.taskExists(tid), "Task", tid,
+				ErrNoResults{Shape: model.Task{}, Identifier: tid, Err: sql.ErrNoRows})
+		}
+	}
+
+	return events, nil
+}
+
+func (t *TaskRepo) taskExists(tid string) *bun.SelectQuery {
+	return t.GetBunDB().NewSelect().Model((*model.Task)(nil)).Where("? = ?", bun.Ident("tid"), tid)
+}
+
+// updateErr reports a write that matched no rows in scope as either missing or owned by another lessor
+func (t *TaskRepo) updateErr(ctx context.Context, scope TenantScope, tid string, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return scope.resolveMissing(ctx, t.taskExists(tid), "Task", tid, ErrNoResults{Shape: model.Task{}, Identifier: tid, Err: err})
+	}
+	return ErrUpdateFailed{Model: "Task", Err: err}
+}
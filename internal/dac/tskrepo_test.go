@@ -0,0 +1,166 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+func TestBulkPriorityQuery(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	lessorId := uuid.New()
+	rows := []model.Task{
+		{Tid: uuid.New(), LessorId: lessorId, Priority: model.High},
+		{Tid: uuid.New(), LessorId: lessorId, Priority: model.Low},
+	}
+
+	query := bulkPriorityQuery(db, &rows, TenantScope{LessorId: lessorId}).String()
+
+	for _, expected := range []string{
+		`UPDATE "tasks" AS "tsk"`,
+		`SET priority = _data.priority`,
+		`tsk.tid = _data.tid`,
+		`"tsk"."lessor_id" = '` + lessorId.String() + `'`,
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("expected query to contain %v, got %v", expected, query)
+		}
+	}
+
+	if strings.Contains(query, "task.") {
+		t.Errorf("query uses the table name instead of the tsk alias, got %v", query)
+	}
+}
+
+func TestBulkPriorityQueryUnrestricted(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+	rows := []model.Task{{Tid: uuid.New(), Priority: model.High}}
+
+	query := bulkPriorityQuery(db, &rows, TenantScope{Unrestricted: true}).String()
+
+	if strings.Contains(query, "lessor_id\" =") {
+		t.Errorf("admin update should not be scoped to a lessor, got %v", query)
+	}
+}
+
+func TestTaskChanges(t *testing.T) {
+	scheduledAt := time.Date(2025, time.March, 3, 8, 0, 0, 0, time.UTC)
+	before := model.Task{
+		Tid:         uuid.New(),
+		Name:        "Fix sink",
+		Priority:    model.Low,
+		Status:      model.Scheduled,
+		ScheduledAt: scheduledAt,
+		WorkerId:    uuid.New(),
+	}
+
+	tests := []struct {
+		name   string
+		change func(tk *model.Task)
+		want   []model.TaskEventType
+		fields []string
+	}{
+		{
+			name:   "unchanged",
+			change: func(tk *model.Task) {},
+		},
+		{
+			name: "same time in another zone",
+			change: func(tk *model.Task) {
+				tk.ScheduledAt = scheduledAt.In(time.FixedZone("CST", -6*60*60))
+			},
+		},
+		{
+			name:   "worker",
+			change: func(tk *model.Task) { tk.WorkerId = uuid.New() },
+			want:   []model.TaskEventType{model.TaskReassigned},
+		},
+		{
+			name:   "schedule",
+			change: func(tk *model.Task) { tk.ScheduledAt = scheduledAt.Add(2 * time.Hour) },
+			want:   []model.TaskEventType{model.TaskUpdated},
+			fields: []string{"scheduledAt"},
+		},
+		{
+			name: "worker and priority",
+			change: func(tk *model.Task) {
+				tk.WorkerId = uuid.New()
+				tk.Priority = model.High
+			},
+			want: []model.TaskEventType{model.TaskReassigned, model.TaskPriorityChanged},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := before
+			tt.change(&after)
+
+			events := taskChanges(context.Background(), before, after)
+
+			var got []model.TaskEventType
+			for _, event := range events {
+				got = append(got, event.EventType)
+				if event.TaskId != before.Tid {
+					t.Errorf("expected event for task %v, got %v", before.Tid, event.TaskId)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected events %v, got %v", tt.want, got)
+			}
+
+			if tt.fields != nil && !reflect.DeepEqual(events[0].Details["fields"], tt.fields) {
+				t.Errorf("expected changed fields %v, got %v", tt.fields, events[0].Details["fields"])
+			}
+		})
+	}
+
+	t.Run("worker details", func(t *testing.T) {
+		after := before
+		after.WorkerId = uuid.New()
+
+		events := taskChanges(context.Background(), before, after)
+		if events[0].Details["from"] != before.WorkerId || events[0].Details["to"] != after.WorkerId {
+			t.Errorf("expected reassignment from %v to %v, got %v", before.WorkerId, after.WorkerId, events[0].Details)
+		}
+	})
+}
+
+func TestTransitionEvent(t *testing.T) {
+	tests := []struct {
+		from model.TaskStatus
+		to   model.TaskStatus
+		want model.TaskEventType
+	}{
+		{from: model.Scheduled, to: model.Started, want: model.TaskStarted},
+		{from: model.Paused, to: model.Started, want: model.TaskResumed},
+		{from: model.Started, to: model.Paused, want: model.TaskPaused},
+		{from: model.Started, to: model.Finished, want: model.TaskCompleted},
+		{from: model.Started, to: model.Failed, want: model.TaskFailed},
+		{from: model.Paused, to: model.Scheduled, want: model.TaskUpdated},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			tk := model.Task{Tid: uuid.New(), Status: tt.to, PausedReason: "parts", FailedReason: "no access"}
+
+			event := transitionEvent(context.Background(), tt.from, tk)
+			if event.EventType != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, event.EventType)
+			}
+
+			if tt.to == model.Paused && event.Details["reason"] != tk.PausedReason {
+				t.Errorf("expected the pause reason on the event, got %v", event.Details)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/Z3DRP/lessor-service/internal/cmerr"
 	"github.com/Z3DRP/lessor-service/internal/filters"
@@ -27,12 +28,18 @@ func InitUsrRepo(db Persister) UserRepo {
 
 func (u *UserRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
 	var usr model.User
-	err := u.GetBunDB().NewSelect().Model(&usr).
-		Where("? = ?", bun.Ident("uid"), fltr.Identifier).Scan(ctx, &usr)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.GetBunDB().NewSelect().Model(&usr).
+		Where("? = ?", bun.Ident("uid"), fltr.Identifier).ApplyQueryBuilder(scope.ByUser("u.uid")).Scan(ctx, &usr)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, ErrNoResults{Shape: usr, Identifier: fltr.Identifier, Err: err}
+			return nil, scope.resolveMissing(ctx, u.userExists(fltr.Identifier), "User", fltr.Identifier,
+				ErrNoResults{Shape: usr, Identifier: fltr.Identifier, Err: err})
 		}
 		return nil, ErrFetchFailed{Model: "User", Err: err}
 	}
@@ -60,10 +67,14 @@ func (u *UserRepo) GetCredentials(ctx context.Context, email string) (interface{
 }
 
 func (u *UserRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.User, error) {
-	// need to add a company or lessor identifier
 	var usrs []model.User
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := u.GetBunDB().NewSelect().Model(&usrs).Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, usrs)
+	err = u.GetBunDB().NewSelect().Model(&usrs).ApplyQueryBuilder(scope.ByUser("u.uid")).Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, usrs)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -184,18 +195,79 @@ func (u *UserRepo) GetWorker(ctx context.Context, uid uuid.UUID) (model.Worker,
 	return worker, nil
 }
 
+func (u *UserRepo) GetTenant(ctx context.Context, uid uuid.UUID) (model.Tenant, error) {
+	var tenant model.Tenant
+	err := u.GetBunDB().NewSelect().Model(&tenant).Where("? = ?", bun.Ident("uid"), uid).Scan(ctx, &tenant)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Tenant{}, ErrNoResults{Shape: tenant, Identifier: uid.String(), Err: err}
+		}
+		return model.Tenant{}, ErrFetchFailed{Model: "Tenant", Err: err}
+	}
+
+	return tenant, nil
+}
+
+// GetTokenUser loads the user a refresh token was issued to, like GetCredentials it runs
+// before there are claims to scope with
+func (u *UserRepo) GetTokenUser(ctx context.Context, uid uuid.UUID) (model.User, error) {
+	var usr model.User
+	err := u.GetBunDB().NewSelect().Model(&usr).
+		Column("uid", "first_name", "last_name", "username", "email", "phone", "profile_type", "is_active").
+		Where("? = ?", bun.Ident("uid"), uid).Scan(ctx, &usr)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, ErrNoResults{Shape: usr, Identifier: uid.String(), Err: err}
+		}
+		return model.User{}, ErrFetchFailed{Model: "User", Err: err}
+	}
+
+	return usr, nil
+}
+
+// GetContact loads how to reach a user, only lessors pick a communication preference so
+// everyone else comes back with email
+func (u *UserRepo) GetContact(ctx context.Context, uid uuid.UUID) (model.User, model.CommunicationPreference, error) {
+	usr, err := u.GetTokenUser(ctx, uid)
+	if err != nil {
+		return model.User{}, "", err
+	}
+
+	var pref model.CommunicationPreference
+	err = u.GetBunDB().NewSelect().Model((*model.Alessor)(nil)).Column("communication_preference").
+		Where("? = ?", bun.Ident("uid"), uid).Scan(ctx, &pref)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return model.User{}, "", ErrFetchFailed{Model: "Alessor", Err: err}
+	}
+
+	if pref == "" {
+		pref = model.Email
+	}
+
+	return usr, pref, nil
+}
+
 func (u *UserRepo) Update(ctx context.Context, usr any) (interface{}, error) {
 	pf, ok := usr.(model.User)
 	if !ok {
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.User{}, Got: usr}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := u.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, ErrTransactionStartFailed{Err: err}
 	}
 
-	rslt, err := tx.NewUpdate().Model(&pf).OmitZero().Where("? = ?", bun.Ident("uid"), pf.Uid).Returning("*").Exec(ctx, &pf)
+	rslt, err := tx.NewUpdate().Model(&pf).OmitZero().Where("? = ?", bun.Ident("uid"), pf.Uid).
+		ApplyQueryBuilder(scope.ByUser("u.uid")).Returning("*").Exec(ctx, &pf)
 	if err != nil {
 		if err = tx.Rollback(); err != nil {
 			return nil, err
@@ -203,6 +275,14 @@ func (u *UserRepo) Update(ctx context.Context, usr any) (interface{}, error) {
 		return nil, ErrUpdateFailed{Model: "User", Err: err}
 	}
 
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if err = tx.Rollback(); err != nil {
+			return nil, err
+		}
+		return nil, scope.resolveMissing(ctx, u.userExists(pf.Uid.String()), "User", pf.Uid.String(),
+			ErrNoResults{Shape: pf, Identifier: pf.Uid.String(), Err: sql.ErrNoRows})
+	}
+
 	if err = tx.Commit(); err != nil {
 		return model.User{}, err
 	}
@@ -210,7 +290,7 @@ func (u *UserRepo) Update(ctx context.Context, usr any) (interface{}, error) {
 	log.Printf("user result from update %v", pf)
 	log.Printf("result from usr update %v", rslt)
 
-	return rslt, nil
+	return pf, nil
 }
 
 func (u *UserRepo) Delete(ctx context.Context, usr any) error {
@@ -219,12 +299,17 @@ func (u *UserRepo) Delete(ctx context.Context, usr any) error {
 		return cmerr.ErrUnexpectedData{Wanted: model.User{}, Got: usr}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
 	tx, err := u.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return ErrTransactionStartFailed{Err: err}
 	}
 
-	_, err = tx.NewDelete().Model(&pf).Where("? = ?", bun.Ident("uid"), pf.Uid).Exec(ctx)
+	rslt, err := tx.NewDelete().Model(&pf).Where("? = ?", bun.Ident("uid"), pf.Uid).ApplyQueryBuilder(scope.ByUser("u.uid")).Exec(ctx)
 	if err != nil {
 		if err = tx.Rollback(); err != nil {
 			return err
@@ -232,8 +317,30 @@ func (u *UserRepo) Delete(ctx context.Context, usr any) error {
 		return ErrDeleteFailed{Model: "User", Err: err}
 	}
 
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if err = tx.Rollback(); err != nil {
+			return err
+		}
+		return scope.resolveMissing(ctx, u.userExists(pf.Uid.String()), "User", pf.Uid.String(),
+			ErrNoResults{Shape: pf, Identifier: pf.Uid.String(), Err: sql.ErrNoRows})
+	}
+
 	if err = tx.Commit(); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (u *UserRepo) userExists(uid string) *bun.SelectQuery {
+	return u.GetBunDB().NewSelect().Model((*model.User)(nil)).Where("? = ?", bun.Ident("uid"), uid)
+}
+
+// activateUser gives an invited user the credentials they chose and lets them sign in
+func activateUser(ctx context.Context, tx bun.Tx, uid uuid.UUID, username string, password string, now time.Time) (model.User, error) {
+	var usr model.User
+	err := tx.NewUpdate().Model(&usr).Set("username = ?", username).Set("password = ?", password).
+		Set("is_active = ?", true).Set("updated_at = ?", now).Where("? = ?", bun.Ident("uid"), uid).
+		Returning("uid, username, email, first_name, last_name, phone, profile_type, is_active").Scan(ctx, &usr)
+
+	return usr, err
+}
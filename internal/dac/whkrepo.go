@@ -0,0 +1,341 @@
+package dac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/webhook"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// maxWebhookDeliveries is how much of a subscription's delivery log is listed
+const maxWebhookDeliveries = 100
+
+// EnqueueWebhook queues the event for each of the lessor's active subscriptions that want it.
+// It is called inside the transaction that made the change so nothing is sent for a
+// change that was rolled back
+func EnqueueWebhook(ctx context.Context, db bun.IDB, lessorId uuid.UUID, event model.WebhookEvent, data any) error {
+	subs := make([]model.WebhookSubscription, 0)
+	err := db.NewSelect().Model(&subs).Column("wid").Where("? = ?", bun.Ident("whs.lessor_id"), lessorId).
+		Where("whs.active").Where("? = ANY(?)", event, bun.Ident("whs.events")).Scan(ctx, &subs)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ErrFetchFailed{Model: "Webhook Subscription", Err: err}
+	}
+
+	if len(subs) == 0 {
+		return nil
+	}
+
+	payload, err := webhook.NewPayload(event, lessorId, data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]model.WebhookDelivery, 0, len(subs))
+	for _, sub := range subs {
+		deliveries = append(deliveries, model.WebhookDelivery{
+			Did:            uuid.New(),
+			SubscriptionId: sub.Wid,
+			LessorId:       lessorId,
+			Event:          event,
+			Payload:        payload,
+			Status:         model.DeliveryPending,
+			NextAttemptAt:  now,
+		})
+	}
+
+	if _, err = db.NewInsert().Model(&deliveries).Exec(ctx); err != nil {
+		return ErrInsertFailed{Model: "Webhook Delivery", Err: err}
+	}
+	return nil
+}
+
+func enqueueTaskWebhooks(ctx context.Context, db bun.IDB, events []model.TaskEvent) error {
+	for _, e := range events {
+		event, ok := model.TaskWebhookEvent(e.EventType)
+		if !ok {
+			continue
+		}
+
+		if err := EnqueueWebhook(ctx, db, e.LessorId, event, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type WebhookRepo struct {
+	Persister
+}
+
+func InitWebhookRepo(db Persister) WebhookRepo {
+	return WebhookRepo{
+		Persister: db,
+	}
+}
+
+func (w *WebhookRepo) Fetch(ctx context.Context, wid uuid.UUID) (model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return sub, err
+	}
+
+	err = w.GetBunDB().NewSelect().Model(&sub).Where("? = ?", bun.Ident("whs.wid"), wid).
+		ApplyQueryBuilder(scope.ByLessor("whs.lessor_id")).Scan(ctx)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sub, scope.resolveMissing(ctx, w.subscriptionExists(wid), "Webhook Subscription", wid.String(),
+				ErrNoResults{Shape: sub, Identifier: wid.String(), Err: err})
+		}
+		return sub, ErrFetchFailed{Model: "Webhook Subscription", Err: err}
+	}
+
+	return sub, nil
+}
+
+func (w *WebhookRepo) FetchAll(ctx context.Context, lessorId uuid.UUID) ([]model.WebhookSubscription, error) {
+	subs := make([]model.WebhookSubscription, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scope.Owns(lessorId) {
+		return nil, ErrCrossTenant{Model: "Webhook Subscription", Identifier: lessorId.String()}
+	}
+
+	err = w.GetBunDB().NewSelect().Model(&subs).Where("? = ?", bun.Ident("whs.lessor_id"), lessorId).
+		Order("whs.created_at ASC").Scan(ctx, &subs)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Webhook Subscription", Err: err}
+	}
+
+	return subs, nil
+}
+
+func (w *WebhookRepo) Insert(ctx context.Context, sub *model.WebhookSubscription) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !scope.Owns(sub.LessorId) {
+		return ErrCrossTenant{Model: "Webhook Subscription", Identifier: sub.LessorId.String()}
+	}
+
+	if err = w.GetBunDB().NewInsert().Model(sub).Returning("*").Scan(ctx, sub); err != nil {
+		return ErrInsertFailed{Model: "Webhook Subscription", Err: err}
+	}
+	return nil
+}
+
+// Update writes the subscription's editable columns, turning it back on clears the failures
+// that disabled it and its pending deliveries pick up where they stopped
+func (w *WebhookRepo) Update(ctx context.Context, sub *model.WebhookSubscription) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	sub.UpdatedAt = time.Now()
+	query := w.GetBunDB().NewUpdate().Model(sub).Set("url = ?url").Set("events = ?events").Set("description = ?description").
+		Set("active = ?active").Set("secret = ?secret").Set("updated_at = ?updated_at")
+	if sub.Active {
+		query = query.Set("failures = 0").Set("disabled_at = NULL").Set("disabled_reason = NULL")
+	}
+
+	err = query.Where("? = ?", bun.Ident("wid"), sub.Wid).ApplyQueryBuilder(scope.ByLessor("whs.lessor_id")).
+		Returning("*").Scan(ctx, sub)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return scope.resolveMissing(ctx, w.subscriptionExists(sub.Wid), "Webhook Subscription", sub.Wid.String(),
+				ErrNoResults{Shape: *sub, Identifier: sub.Wid.String(), Err: err})
+		}
+		return ErrUpdateFailed{Model: "Webhook Subscription", Err: err}
+	}
+	return nil
+}
+
+// Delete removes the subscription along with its delivery log
+func (w *WebhookRepo) Delete(ctx context.Context, wid uuid.UUID) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := w.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	rslt, err := tx.NewDelete().Model((*model.WebhookSubscription)(nil)).Where("? = ?", bun.Ident("wid"), wid).
+		ApplyQueryBuilder(scope.ByLessor("whs.lessor_id")).Exec(ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrDeleteFailed{Model: "Webhook Subscription", Err: err}
+	}
+
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return scope.resolveMissing(ctx, w.subscriptionExists(wid), "Webhook Subscription", wid.String(),
+			ErrNoResults{Shape: model.WebhookSubscription{}, Identifier: wid.String(), Err: sql.ErrNoRows})
+	}
+
+	_, err = tx.NewDelete().Model((*model.WebhookDelivery)(nil)).Where("? = ?", bun.Ident("subscription_id"), wid).Exec(ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrDeleteFailed{Model: "Webhook Delivery", Err: err}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+	return nil
+}
+
+// FetchDeliveries returns the subscription's most recent deliveries newest first
+func (w *WebhookRepo) FetchDeliveries(ctx context.Context, wid uuid.UUID) ([]model.WebhookDelivery, error) {
+	deliveries := make([]model.WebhookDelivery, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.GetBunDB().NewSelect().Model(&deliveries).Where("? = ?", bun.Ident("whd.subscription_id"), wid).
+		ApplyQueryBuilder(scope.ByLessor("whd.lessor_id")).Order("whd.created_at DESC").Limit(maxWebhookDeliveries).Scan(ctx, &deliveries)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Webhook Delivery", Err: err}
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver queues a new delivery with the same payload as an earlier one, the earlier
+// delivery's log is left as it was
+func (w *WebhookRepo) Redeliver(ctx context.Context, wid uuid.UUID, did uuid.UUID) (model.WebhookDelivery, error) {
+	var original model.WebhookDelivery
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return original, err
+	}
+
+	err = w.GetBunDB().NewSelect().Model(&original).Where("? = ?", bun.Ident("whd.did"), did).
+		Where("? = ?", bun.Ident("whd.subscription_id"), wid).ApplyQueryBuilder(scope.ByLessor("whd.lessor_id")).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			exists := w.GetBunDB().NewSelect().Model((*model.WebhookDelivery)(nil)).Where("? = ?", bun.Ident("did"), did).
+				Where("? = ?", bun.Ident("subscription_id"), wid)
+			return original, scope.resolveMissing(ctx, exists, "Webhook Delivery", did.String(),
+				ErrNoResults{Shape: original, Identifier: did.String(), Err: err})
+		}
+		return original, ErrFetchFailed{Model: "Webhook Delivery", Err: err}
+	}
+
+	redelivery := model.WebhookDelivery{
+		Did:            uuid.New(),
+		SubscriptionId: original.SubscriptionId,
+		LessorId:       original.LessorId,
+		Event:          original.Event,
+		Payload:        original.Payload,
+		Status:         model.DeliveryPending,
+		NextAttemptAt:  time.Now(),
+		RedeliveryOf:   original.Did,
+	}
+
+	if err = w.GetBunDB().NewInsert().Model(&redelivery).Returning("*").Scan(ctx, &redelivery); err != nil {
+		return redelivery, ErrInsertFailed{Model: "Webhook Delivery", Err: err}
+	}
+	return redelivery, nil
+}
+
+// FetchDue returns pending deliveries whose next attempt has come for subscriptions that
+// are still active, oldest first
+func (w *WebhookRepo) FetchDue(ctx context.Context, now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	deliveries := make([]model.WebhookDelivery, 0)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.GetBunDB().NewSelect().Model(&deliveries).Relation("Subscription").
+		Where("? = ?", bun.Ident("whd.status"), model.DeliveryPending).Where("? <= ?", bun.Ident("whd.next_attempt_at"), now).
+		Where("? IS TRUE", bun.Ident("subscription.active")).ApplyQueryBuilder(scope.ByLessor("whd.lessor_id")).
+		Order("whd.next_attempt_at ASC").Limit(limit).Scan(ctx, &deliveries)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFetchFailed{Model: "Webhook Delivery", Err: err}
+	}
+
+	return deliveries, nil
+}
+
+// Settle saves an attempt's outcome. The subscription's failures are counted in the update
+// itself since several of its deliveries can be settled at once, it is disabled when a
+// delivery gives up once too often in a row
+func (w *WebhookRepo) Settle(ctx context.Context, d *model.WebhookDelivery, outcome webhook.Outcome) error {
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !scope.Owns(d.LessorId) {
+		return ErrCrossTenant{Model: "Webhook Delivery", Identifier: d.Did.String()}
+	}
+
+	tx, err := w.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ErrTransactionStartFailed{Err: err}
+	}
+
+	_, err = tx.NewUpdate().Model(d).Column("status", "attempts", "status_code", "next_attempt_at", "delivered_at").
+		Where("? = ?", bun.Ident("did"), d.Did).Exec(ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return ErrRollbackFailed{rbErr}
+		}
+		return ErrUpdateFailed{Model: "Webhook Delivery", Err: err}
+	}
+
+	if outcome != webhook.Retry {
+		query := tx.NewUpdate().Model((*model.WebhookSubscription)(nil)).Where("? = ?", bun.Ident("wid"), d.SubscriptionId)
+		if outcome == webhook.Delivered {
+			query = query.Set("failures = 0").Where("failures > 0")
+		} else {
+			query = query.Set("failures = failures + 1").
+				Set("active = active AND failures + 1 < ?", webhook.DisableAfter).
+				Set("disabled_at = CASE WHEN active AND failures + 1 >= ? THEN ? ELSE disabled_at END", webhook.DisableAfter, time.Now()).
+				Set("disabled_reason = CASE WHEN active AND failures + 1 >= ? THEN ? ELSE disabled_reason END",
+					webhook.DisableAfter, "disabled after repeated failed deliveries")
+		}
+
+		if _, err = query.Exec(ctx); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return ErrRollbackFailed{rbErr}
+			}
+			return ErrUpdateFailed{Model: "Webhook Subscription", Err: err}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ErrTransactionCommitFail{err}
+	}
+	return nil
+}
+
+func (w *WebhookRepo) subscriptionExists(wid uuid.UUID) *bun.SelectQuery {
+	return w.GetBunDB().NewSelect().Model((*model.WebhookSubscription)(nil)).Where("? = ?", bun.Ident("wid"), wid)
+}
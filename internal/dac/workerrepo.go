@@ -3,6 +3,7 @@ package dac
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
 
 	"github.com/Z3DRP/lessor-service/internal/cmerr"
@@ -24,13 +25,20 @@ func InitWorkerRepo(db Persister) WorkerRepo {
 
 func (p *WorkerRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
 	var wrkr model.Worker
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := p.GetBunDB().NewSelect().Model(&wrkr).
-		Where("? = ?", bun.Ident("pid"), fltr.Identifier).Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &wrkr)
+	err = p.GetBunDB().NewSelect().Model(&wrkr).
+		Where("? = ?", bun.Ident("w.uid"), fltr.Identifier).ApplyQueryBuilder(scope.ByLessor("w.lessor_id")).
+		Relation("User").Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &wrkr)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, ErrNoResults{Shape: "worker", Identifier: fltr.Identifier, Err: err}
+			return nil, scope.resolveMissing(ctx, p.workerExists(fltr.Identifier), "worker", fltr.Identifier,
+				ErrNoResults{Shape: "worker", Identifier: fltr.Identifier, Err: err})
 		}
 		return nil, ErrFetchFailed{Model: "worker", Err: err}
 	}
@@ -40,8 +48,18 @@ func (p *WorkerRepo) Fetch(ctx context.Context, fltr filters.Filter) (interface{
 
 func (p *WorkerRepo) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.Worker, error) {
 	var workers []model.Worker
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byLessor, err := scope.ForLessor("worker", "w.lessor_id", fltr.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := utils.DeterminRecordLimit(fltr.Limit)
-	err := p.GetBunDB().NewSelect().Model(&workers).Relation("User").Relation("Alessor").Relation("Alessor.User").Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &workers)
+	err = p.GetBunDB().NewSelect().Model(&workers).ApplyQueryBuilder(byLessor).Relation("User").Relation("Alessor").Relation("Alessor.User").Limit(limit).Offset(10*(fltr.Page-1)).Scan(ctx, &workers)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -60,6 +78,15 @@ func (p *WorkerRepo) Insert(ctx context.Context, wrkr any) (interface{}, error)
 		return nil, cmerr.ErrUnexpectedData{Wanted: model.Worker{}, Got: wrkr}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scope.Owns(worker.LessorId) {
+		return nil, ErrCrossTenant{Model: "worker", Identifier: worker.Uid.String()}
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 
 	if err != nil {
@@ -93,6 +120,11 @@ func (p *WorkerRepo) Update(ctx context.Context, wrkr any) (interface{}, error)
 		return model.Worker{}, cmerr.ErrUnexpectedData{Wanted: model.Worker{}, Got: wrkr}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Worker{}, err
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 
 	if err != nil {
@@ -100,12 +132,17 @@ func (p *WorkerRepo) Update(ctx context.Context, wrkr any) (interface{}, error)
 		return model.Worker{}, ErrTransactionStartFailed{Err: err}
 	}
 
-	err = tx.NewUpdate().Model(&worker).OmitZero().Where("? = ?", bun.Ident("uid"), worker.Uid).Returning("*").Scan(ctx, &worker)
+	err = tx.NewUpdate().Model(&worker).OmitZero().Where("? = ?", bun.Ident("uid"), worker.Uid).ApplyQueryBuilder(scope.ByLessor("w.lessor_id")).
+		Returning("*").Scan(ctx, &worker)
 
 	if err != nil {
 		log.Printf("failed to update %v", err)
-		if err = tx.Rollback(); err != nil {
-			return nil, err
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, rbErr
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, scope.resolveMissing(ctx, p.workerExists(worker.Uid.String()), "worker", worker.Uid.String(),
+				ErrNoResults{Shape: "worker", Identifier: worker.Uid.String(), Err: err})
 		}
 		return nil, err
 	}
@@ -129,13 +166,18 @@ func (p *WorkerRepo) Delete(ctx context.Context, wrkr any) error {
 		return cmerr.ErrUnexpectedData{Wanted: model.Worker{}, Got: wrkr}
 	}
 
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
 	tx, err := p.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 
 	if err != nil {
 		return ErrTransactionStartFailed{Err: err}
 	}
 
-	_, err = tx.NewDelete().Model(&worker).Where("? = ?", bun.Ident("Uid"), worker.Uid).Exec(ctx)
+	rslt, err := tx.NewDelete().Model(&worker).Where("? = ?", bun.Ident("Uid"), worker.Uid).ApplyQueryBuilder(scope.ByLessor("w.lessor_id")).Exec(ctx)
 
 	if err != nil {
 		if err = tx.Rollback(); err != nil {
@@ -144,6 +186,14 @@ func (p *WorkerRepo) Delete(ctx context.Context, wrkr any) error {
 		return ErrDeleteFailed{Model: "User", Err: err}
 	}
 
+	if n, _ := rslt.RowsAffected(); n == 0 {
+		if err = tx.Rollback(); err != nil {
+			return err
+		}
+		return scope.resolveMissing(ctx, p.workerExists(worker.Uid.String()), "worker", worker.Uid.String(),
+			ErrNoResults{Shape: "worker", Identifier: worker.Uid.String(), Err: sql.ErrNoRows})
+	}
+
 	if err = tx.Commit(); err != nil {
 		return err
 	}
@@ -152,7 +202,13 @@ func (p *WorkerRepo) Delete(ctx context.Context, wrkr any) error {
 
 func (p *WorkerRepo) GetExisting(ctx context.Context, pid string) (model.Worker, error) {
 	var worker model.Worker
-	err := p.GetBunDB().NewSelect().Model(&worker).Column("Uid", "image").Where("? = ?", bun.Ident("Uid"), pid).Scan(ctx, &worker)
+	scope, err := NewTenantScope(ctx)
+	if err != nil {
+		return model.Worker{}, err
+	}
+
+	err = p.GetBunDB().NewSelect().Model(&worker).Column("Uid", "image").Where("? = ?", bun.Ident("Uid"), pid).
+		ApplyQueryBuilder(scope.ByLessor("w.lessor_id")).Scan(ctx, &worker)
 
 	if err != nil {
 		return model.Worker{}, err
@@ -160,3 +216,7 @@ func (p *WorkerRepo) GetExisting(ctx context.Context, pid string) (model.Worker,
 
 	return worker, nil
 }
+
+func (p *WorkerRepo) workerExists(uid string) *bun.SelectQuery {
+	return p.GetBunDB().NewSelect().Model((*model.Worker)(nil)).Where("? = ?", bun.Ident("uid"), uid)
+}
@@ -0,0 +1,246 @@
+package dtos
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ledger entry kinds, charges raise the balance and payments lower it
+const (
+	ChargeEntry  = "charge"
+	PaymentEntry = "payment"
+)
+
+type ChargeResponse struct {
+	Cid         string          `json:"cid"`
+	LessorId    string          `json:"lessorId"`
+	TenantId    string          `json:"tenantId"`
+	RentalId    string          `json:"rentalId"`
+	Kind        string          `json:"kind"`
+	PeriodStart time.Time       `json:"periodStart"`
+	LateFeeFor  string          `json:"lateFeeFor"`
+	Description string          `json:"description"`
+	Amount      decimal.Decimal `json:"amount"`
+	AmountPaid  decimal.Decimal `json:"amountPaid"`
+	Outstanding decimal.Decimal `json:"outstanding"`
+	DueDate     time.Time       `json:"dueDate"`
+	VoidedAt    time.Time       `json:"voidedAt"`
+}
+
+func (c ChargeResponse) Validate() error {
+	return nil
+}
+
+func NewChargeResponse(c model.Charge) ChargeResponse {
+	res := ChargeResponse{
+		Cid:         c.Cid.String(),
+		LessorId:    c.LessorId.String(),
+		TenantId:    c.TenantId.String(),
+		RentalId:    c.RentalId.String(),
+		Kind:        string(c.Kind),
+		PeriodStart: c.PeriodStart,
+		Description: c.Description,
+		Amount:      c.Amount,
+		AmountPaid:  c.AmountPaid,
+		Outstanding: c.Outstanding(),
+		DueDate:     c.DueDate,
+		VoidedAt:    c.VoidedAt,
+	}
+
+	if c.LateFeeFor != uuid.Nil {
+		res.LateFeeFor = c.LateFeeFor.String()
+	}
+
+	return res
+}
+
+type ChargeRequest struct {
+	LessorId    string          `json:"lessorId"`
+	TenantId    string          `json:"tenantId"`
+	RentalId    string          `json:"rentalId"`
+	Kind        string          `json:"kind"`
+	Description string          `json:"description"`
+	Amount      decimal.Decimal `json:"amount"`
+	DueDate     time.Time       `json:"dueDate"`
+}
+
+func (c ChargeRequest) Validate() error {
+	if c.LessorId == "" || c.TenantId == "" || c.RentalId == "" {
+		return errors.New("a charge requires a lessor, tenant and rental")
+	}
+
+	if !c.Amount.IsPositive() {
+		return errors.New("a charge amount must be greater than zero")
+	}
+
+	if c.DueDate.IsZero() {
+		return errors.New("a charge requires a due date")
+	}
+
+	switch model.ChargeKind(c.Kind) {
+	case model.RentCharge, model.LateFeeCharge, model.OtherCharge, "":
+		return nil
+	default:
+		return errors.New("charge kind must be rent, late_fee or other")
+	}
+}
+
+// ChargeModRequest fields left empty keep their current value
+type ChargeModRequest struct {
+	Description string          `json:"description"`
+	Amount      decimal.Decimal `json:"amount"`
+	DueDate     time.Time       `json:"dueDate"`
+}
+
+func (c ChargeModRequest) Validate() error {
+	if c.Amount.IsNegative() {
+		return errors.New("a charge amount can not be negative")
+	}
+
+	return nil
+}
+
+type AllocationResponse struct {
+	ChargeId string          `json:"chargeId"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+type PaymentResponse struct {
+	TxId              string               `json:"txid"`
+	LessorId          string               `json:"lessorId"`
+	TenantId          string               `json:"tenantId"`
+	RentalId          string               `json:"rentalId"`
+	Amount            decimal.Decimal      `json:"amount"`
+	Applied           decimal.Decimal      `json:"applied"`
+	Unapplied         decimal.Decimal      `json:"unapplied"`
+	CurrencyCode      string               `json:"currencyCode"`
+	TransactionStatus string               `json:"transactionStatus"`
+	Method            string               `json:"method"`
+	ProcessorId       string               `json:"processorId"`
+	Note              string               `json:"note"`
+	ReceiptNumber     string               `json:"receiptNumber"`
+	Allocations       []AllocationResponse `json:"allocations"`
+	ReceivedAt        time.Time            `json:"receivedAt"`
+	ReversedAt        time.Time            `json:"reversedAt"`
+}
+
+func (p PaymentResponse) Validate() error {
+	return nil
+}
+
+func NewPaymentResponse(p model.Payment) PaymentResponse {
+	allocations := make([]AllocationResponse, 0, len(p.Allocations))
+	for _, a := range p.Allocations {
+		allocations = append(allocations, AllocationResponse{ChargeId: a.ChargeId.String(), Amount: a.Amount})
+	}
+
+	unapplied := decimal.Zero
+	if p.TransactionStatus == model.Accepted && p.ReversedAt.IsZero() {
+		unapplied = p.Amount.Sub(p.Applied)
+	}
+
+	return PaymentResponse{
+		TxId:              p.TxId.String(),
+		LessorId:          p.LessorId.String(),
+		TenantId:          p.TenantId.String(),
+		RentalId:          p.RentalId.String(),
+		Amount:            p.Amount,
+		Applied:           p.Applied,
+		Unapplied:         unapplied,
+		CurrencyCode:      p.CurrenyCode,
+		TransactionStatus: string(p.TransactionStatus),
+		Method:            string(p.Method),
+		ProcessorId:       p.SqPid,
+		Note:              p.Note,
+		ReceiptNumber:     p.ReceitNumber,
+		Allocations:       allocations,
+		ReceivedAt:        p.ReceivedAt,
+		ReversedAt:        p.ReversedAt,
+	}
+}
+
+// PaymentRequest records a payment, ChargeId is optional and names the charge to pay
+// first, anything left over goes to the tenant's oldest open charges. Processor payments
+// are charged to the card or bank token in SourceId
+type PaymentRequest struct {
+	LessorId          string          `json:"lessorId"`
+	TenantId          string          `json:"tenantId"`
+	RentalId          string          `json:"rentalId"`
+	ChargeId          string          `json:"chargeId"`
+	Amount            decimal.Decimal `json:"amount"`
+	CurrencyCode      string          `json:"currencyCode"`
+	TransactionStatus string          `json:"transactionStatus"`
+	Method            string          `json:"method"`
+	SourceId          string          `json:"sourceId"`
+	Note              string          `json:"note"`
+	ReceiptNumber     string          `json:"receiptNumber"`
+	ReceivedAt        time.Time       `json:"receivedAt"`
+}
+
+func (p PaymentRequest) Validate() error {
+	if p.LessorId == "" || p.TenantId == "" || p.RentalId == "" {
+		return errors.New("a payment requires a lessor, tenant and rental")
+	}
+
+	if !p.Amount.IsPositive() {
+		return errors.New("a payment amount must be greater than zero")
+	}
+
+	switch model.PaymentStatus(p.TransactionStatus) {
+	case model.Accepted, model.Rejected, "":
+	default:
+		return errors.New("transaction status must be accepted or rejected")
+	}
+
+	switch model.MethodOfPayment(p.Method) {
+	case model.Cash, model.Check:
+		return nil
+	case model.Processor:
+		if p.SourceId == "" {
+			return errors.New("a processor payment requires a payment source")
+		}
+		return nil
+	default:
+		return errors.New("payment method must be cash, check or processor")
+	}
+}
+
+type PaymentModRequest struct {
+	Note          string `json:"note"`
+	ReceiptNumber string `json:"receiptNumber"`
+}
+
+func (p PaymentModRequest) Validate() error {
+	return nil
+}
+
+// LedgerEntry is one line of a ledger, Balance is what is owed after the entry and goes
+// negative when the tenant has credit
+type LedgerEntry struct {
+	Date        time.Time       `json:"date"`
+	Type        string          `json:"type"`
+	Id          string          `json:"id"`
+	TenantId    string          `json:"tenantId"`
+	Kind        string          `json:"kind"`
+	Description string          `json:"description"`
+	Amount      decimal.Decimal `json:"amount"`
+	Balance     decimal.Decimal `json:"balance"`
+}
+
+type LedgerResponse struct {
+	TenantId     string          `json:"tenantId,omitempty"`
+	RentalId     string          `json:"rentalId,omitempty"`
+	TotalCharged decimal.Decimal `json:"totalCharged"`
+	TotalPaid    decimal.Decimal `json:"totalPaid"`
+	Balance      decimal.Decimal `json:"balance"`
+	PastDue      decimal.Decimal `json:"pastDue"`
+	Entries      []LedgerEntry   `json:"entries"`
+}
+
+func (l LedgerResponse) Validate() error {
+	return nil
+}
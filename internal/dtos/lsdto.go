@@ -0,0 +1,178 @@
+package dtos
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type LeaseRequest struct {
+	LessorId   string          `json:"lessorId"`
+	RentalId   string          `json:"rentalId"`
+	TenantIds  []string        `json:"tenantIds"`
+	StartsAt   time.Time       `json:"startsAt"`
+	EndsAt     time.Time       `json:"endsAt"`
+	RentAmount decimal.Decimal `json:"rentAmount"`
+	Deposit    decimal.Decimal `json:"deposit"`
+	Terms      string          `json:"terms"`
+}
+
+func (l LeaseRequest) Validate() error {
+	if l.LessorId == "" || l.RentalId == "" {
+		return errors.New("a lease requires a lessor and rental")
+	}
+
+	if len(l.TenantIds) == 0 {
+		return errors.New("a lease requires at least one tenant")
+	}
+
+	if err := validLeaseTenants(l.TenantIds); err != nil {
+		return err
+	}
+
+	if l.StartsAt.IsZero() || !l.EndsAt.After(l.StartsAt) {
+		return errors.New("a lease requires a start and an end after it")
+	}
+
+	if !l.RentAmount.IsPositive() {
+		return errors.New("rent amount must be greater than zero")
+	}
+
+	if l.Deposit.IsNegative() {
+		return errors.New("deposit can not be negative")
+	}
+
+	return nil
+}
+
+// LeaseModRequest edits a draft or describes its renewal, fields left empty keep the value
+// of the lease being edited or renewed
+type LeaseModRequest struct {
+	TenantIds  []string         `json:"tenantIds"`
+	StartsAt   time.Time        `json:"startsAt"`
+	EndsAt     time.Time        `json:"endsAt"`
+	RentAmount decimal.Decimal  `json:"rentAmount"`
+	Deposit    *decimal.Decimal `json:"deposit"`
+	Terms      *string          `json:"terms"`
+}
+
+func (l LeaseModRequest) Validate() error {
+	if l.TenantIds != nil {
+		if len(l.TenantIds) == 0 {
+			return errors.New("a lease requires at least one tenant")
+		}
+
+		if err := validLeaseTenants(l.TenantIds); err != nil {
+			return err
+		}
+	}
+
+	if l.RentAmount.IsNegative() {
+		return errors.New("rent amount must be greater than zero")
+	}
+
+	if l.Deposit != nil && l.Deposit.IsNegative() {
+		return errors.New("deposit can not be negative")
+	}
+
+	return nil
+}
+
+type LeaseTerminateRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (l LeaseTerminateRequest) Validate() error {
+	if l.Reason == "" {
+		return errors.New("a reason for ending the lease is required")
+	}
+
+	if len(l.Reason) > 255 {
+		return errors.New("reason can not be longer than 255 characters")
+	}
+
+	return nil
+}
+
+type LeaseResponse struct {
+	Lid               string          `json:"lid"`
+	LessorId          string          `json:"lessorId"`
+	RentalId          string          `json:"rentalId"`
+	TenantIds         []string        `json:"tenantIds"`
+	StartsAt          time.Time       `json:"startsAt"`
+	EndsAt            time.Time       `json:"endsAt"`
+	RentAmount        decimal.Decimal `json:"rentAmount"`
+	Deposit           decimal.Decimal `json:"deposit"`
+	Terms             string          `json:"terms"`
+	Status            string          `json:"status"`
+	RenewalOf         string          `json:"renewalOf"`
+	RemindedAt        time.Time       `json:"remindedAt"`
+	ActivatedAt       time.Time       `json:"activatedAt"`
+	TerminatedAt      time.Time       `json:"terminatedAt"`
+	TerminationReason string          `json:"terminationReason"`
+	CreatedAt         time.Time       `json:"createdAt"`
+	UpdatedAt         time.Time       `json:"updatedAt"`
+}
+
+func NewLeaseResponse(l model.Lease) LeaseResponse {
+	tenantIds := make([]string, 0, len(l.TenantIds))
+	for _, tid := range l.TenantIds {
+		tenantIds = append(tenantIds, tid.String())
+	}
+
+	var renewalOf string
+	if l.RenewalOf != uuid.Nil {
+		renewalOf = l.RenewalOf.String()
+	}
+
+	return LeaseResponse{
+		Lid:               l.Lid.String(),
+		LessorId:          l.LessorId.String(),
+		RentalId:          l.RentalId.String(),
+		TenantIds:         tenantIds,
+		StartsAt:          l.StartsAt,
+		EndsAt:            l.EndsAt,
+		RentAmount:        l.RentAmount,
+		Deposit:           l.Deposit,
+		Terms:             l.Terms,
+		Status:            string(l.Status),
+		RenewalOf:         renewalOf,
+		RemindedAt:        l.RemindedAt,
+		ActivatedAt:       l.ActivatedAt,
+		TerminatedAt:      l.TerminatedAt,
+		TerminationReason: l.TerminationReason,
+		CreatedAt:         l.CreatedAt,
+		UpdatedAt:         l.UpdatedAt,
+	}
+}
+
+func NewLeaseResponses(ls []model.Lease) []LeaseResponse {
+	leases := make([]LeaseResponse, 0, len(ls))
+	for _, l := range ls {
+		leases = append(leases, NewLeaseResponse(l))
+	}
+
+	return leases
+}
+
+func validLeaseTenants(tenantIds []string) error {
+	parsed := make([]uuid.UUID, 0, len(tenantIds))
+	for _, tid := range tenantIds {
+		id, err := uuid.Parse(tid)
+		if err != nil {
+			return fmt.Errorf("invalid tenant id %v", tid)
+		}
+
+		if slices.Contains(parsed, id) {
+			return fmt.Errorf("tenant %v is listed twice", tid)
+		}
+		parsed = append(parsed, id)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package dtos
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	maxDescriptionLen = 2000
+	maxAccessWindows  = 10
+	// MaxRequestPhotos is how many photos a tenant can attach to one request
+	MaxRequestPhotos = 5
+)
+
+type MaintenanceResponse struct {
+	Mid               string               `json:"mid"`
+	TenantId          string               `json:"tenantId"`
+	Tenant            *model.User          `json:"tenant"`
+	LessorId          string               `json:"lessorId"`
+	PropertyId        string               `json:"propertyId"`
+	Property          *model.Property      `json:"property"`
+	Description       string               `json:"description"`
+	Urgency           string               `json:"urgency"`
+	Photos            []string             `json:"photos"`
+	AccessWindows     []model.AccessWindow `json:"accessWindows"`
+	PermissionToEnter bool                 `json:"permissionToEnter"`
+	Status            string               `json:"status"`
+	DeclinedReason    string               `json:"declinedReason"`
+	TaskId            string               `json:"taskId"`
+	TaskStatus        string               `json:"taskStatus"`
+	ScheduledAt       time.Time            `json:"scheduledAt"`
+	CompletedAt       time.Time            `json:"completedAt"`
+	ResolvedAt        time.Time            `json:"resolvedAt"`
+	CreatedAt         time.Time            `json:"createdAt"`
+}
+
+func (m MaintenanceResponse) Validate() error {
+	return nil
+}
+
+// NewMaintenanceResponse takes the photo urls separately since the stored keys have to be
+// signed before they can be handed out
+func NewMaintenanceResponse(m model.MaintenanceRequest, photoUrls []string) MaintenanceResponse {
+	res := MaintenanceResponse{
+		Mid:               m.Mid.String(),
+		TenantId:          m.TenantId.String(),
+		Tenant:            m.Tenant,
+		LessorId:          m.LessorId.String(),
+		PropertyId:        m.PropertyId.String(),
+		Property:          m.Property,
+		Description:       m.Description,
+		Urgency:           string(m.Urgency),
+		Photos:            photoUrls,
+		AccessWindows:     m.AccessWindows,
+		PermissionToEnter: m.PermissionToEnter,
+		Status:            string(m.Status),
+		DeclinedReason:    m.DeclinedReason,
+		ResolvedAt:        m.ResolvedAt,
+		CreatedAt:         m.CreatedAt,
+	}
+
+	if m.Task != nil {
+		res.TaskId = m.Task.Tid.String()
+		res.TaskStatus = string(m.Task.Status)
+		res.ScheduledAt = m.Task.ScheduledAt
+		res.CompletedAt = m.Task.CompletedAt
+	}
+
+	return res
+}
+
+// MaintenanceRequestDto is what a tenant submits, the property and lessor come from their
+// tenancy rather than the request
+type MaintenanceRequestDto struct {
+	Description       string               `json:"description"`
+	Urgency           string               `json:"urgency"`
+	AccessWindows     []model.AccessWindow `json:"accessWindows"`
+	PermissionToEnter bool                 `json:"permissionToEnter"`
+}
+
+func (m MaintenanceRequestDto) Validate() error {
+	if m.Description == "" || utils.CharCount(m.Description) > maxDescriptionLen {
+		return fmt.Errorf("description is required and can be at most %v characters", maxDescriptionLen)
+	}
+
+	if !validPriority(m.Urgency) {
+		return fmt.Errorf("invalid urgency %v", m.Urgency)
+	}
+
+	if len(m.AccessWindows) > maxAccessWindows {
+		return fmt.Errorf("at most %v access windows can be given", maxAccessWindows)
+	}
+
+	for _, window := range m.AccessWindows {
+		if window.Start.IsZero() || !window.End.After(window.Start) {
+			return errors.New("access windows must end after they start")
+		}
+	}
+
+	return nil
+}
+
+// MaintenanceConvertRequest overrides what the task is filled in with, anything left
+// empty is taken from the maintenance request
+type MaintenanceConvertRequest struct {
+	Name          string          `json:"name"`
+	Priority      string          `json:"priority"`
+	Category      string          `json:"category"`
+	Notes         string          `json:"notes"`
+	WorkerId      string          `json:"workerId"`
+	ScheduledAt   time.Time       `json:"scheduledAt"`
+	EstimatedCost decimal.Decimal `json:"estimatedCost"`
+}
+
+func (m MaintenanceConvertRequest) Validate() error {
+	if m.Priority != "" && !validPriority(m.Priority) {
+		return fmt.Errorf("invalid priority %v", m.Priority)
+	}
+
+	if m.EstimatedCost.IsNegative() {
+		return errors.New("estimated cost can not be negative")
+	}
+
+	return nil
+}
+
+type MaintenanceDeclineRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (m MaintenanceDeclineRequest) Validate() error {
+	if m.Reason == "" || utils.CharCount(m.Reason) > 255 {
+		return errors.New("a reason of at most 255 characters is required")
+	}
+	return nil
+}
+
+func validPriority(priority string) bool {
+	switch model.PriorityLevel(priority) {
+	case model.Low, model.Medium, model.High, model.Immediate:
+		return true
+	default:
+		return false
+	}
+}
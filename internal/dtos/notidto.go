@@ -2,6 +2,8 @@ package dtos
 
 import (
 	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/Z3DRP/lessor-service/internal/model"
@@ -19,6 +21,7 @@ type NotificationDto struct {
 	PropertyId string          `json:"propertyId"`
 	Property   *model.Property `json:"property"`
 	Category   string          `json:"category"`
+	Priority   string          `json:"priority"`
 	Viewed     bool            `json:"viewed"`
 	CreatedAt  time.Time       `json:"createdAt"`
 	VoidAt     time.Time       `json:"voidAt"`
@@ -37,9 +40,13 @@ func (n NotificationDto) Validate() error {
 		return errors.New("error missing category")
 	}
 
-	if n.Category != "property" && n.Category != "task" && n.Category != "user" && n.Category != "wo	rker" && n.Category != "tenant" && n.Category != "general" {
+	if n.Category != "property" && n.Category != "task" && n.Category != "user" && n.Category != "worker" && n.Category != "tenant" && n.Category != "general" {
 		return errors.New("error invalid category")
 	}
+
+	if n.Priority != "" && !validPriority(n.Priority) {
+		return errors.New("error invalid priority")
+	}
 	return nil
 }
 
@@ -56,6 +63,7 @@ func NewNotificationDto(n model.Notification) *NotificationDto {
 		PropertyId: n.PropertyId.String(),
 		Property:   n.Property,
 		Category:   string(n.Category),
+		Priority:   string(n.Priority),
 		Viewed:     n.Viewed,
 		CreatedAt:  n.CreatedAt,
 		VoidAt:     n.VoidAt,
@@ -75,6 +83,7 @@ func GenNotificationDto(n model.Notification) NotificationDto {
 		PropertyId: n.PropertyId.String(),
 		Property:   n.Property,
 		Category:   string(n.Category),
+		Priority:   string(n.Priority),
 		Viewed:     n.Viewed,
 		CreatedAt:  n.CreatedAt,
 		VoidAt:     n.VoidAt,
@@ -90,3 +99,124 @@ func NewNotificationDtoList(n []model.Notification) []NotificationDto {
 	}
 	return responses
 }
+
+type SmsMessageResponse struct {
+	Sid            string    `json:"sid"`
+	UserId         string    `json:"userId"`
+	NotificationId int       `json:"notificationId"`
+	To             string    `json:"to"`
+	Body           string    `json:"body"`
+	Provider       string    `json:"provider"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error"`
+	Attempts       int       `json:"attempts"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func NewSmsMessageResponse(m model.SmsMessage) SmsMessageResponse {
+	return SmsMessageResponse{
+		Sid:            m.Sid.String(),
+		UserId:         m.UserId.String(),
+		NotificationId: m.NotificationId,
+		To:             m.To,
+		Body:           m.Body,
+		Provider:       m.Provider,
+		Status:         string(m.Status),
+		Error:          m.Error,
+		Attempts:       m.Attempts,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+}
+
+var notificationCategories = []model.NotificationType{
+	model.PropertyAlert, model.TaskAlert, model.UserAlert, model.WorkerAlert, model.TenantAlert, model.GeneralAlert,
+}
+
+// NotificationSettingsRequest replaces the user's preferences, a category left out gets
+// everything and a category without channels gets every channel. Digest is hourly, daily
+// or empty to send each notification as it happens, a daily digest goes out at DigestHour
+type NotificationSettingsRequest struct {
+	Timezone   string                                              `json:"timezone"`
+	Categories map[model.NotificationType]model.CategoryPreference `json:"categories"`
+	Digest     model.DigestFrequency                               `json:"digest"`
+	DigestHour int                                                 `json:"digestHour"`
+}
+
+func (n NotificationSettingsRequest) Validate() error {
+	if _, err := time.LoadLocation(n.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %v", n.Timezone)
+	}
+
+	switch n.Digest {
+	case model.NoDigest, model.HourlyDigest, model.DailyDigest:
+	default:
+		return fmt.Errorf("invalid digest %v, expected hourly or daily", n.Digest)
+	}
+
+	if n.DigestHour < 0 || n.DigestHour > 23 {
+		return fmt.Errorf("invalid digest hour %v, expected 0 to 23", n.DigestHour)
+	}
+
+	for category, pref := range n.Categories {
+		if !slices.Contains(notificationCategories, category) {
+			return fmt.Errorf("invalid notification category %v", category)
+		}
+
+		for i, channel := range pref.Channels {
+			if !slices.Contains(model.NotificationChannels, channel) {
+				return fmt.Errorf("invalid channel %v for %v", channel, category)
+			}
+
+			if slices.Contains(pref.Channels[:i], channel) {
+				return fmt.Errorf("channel %v is listed twice for %v", channel, category)
+			}
+		}
+
+		if pref.MinPriority != "" && !validPriority(string(pref.MinPriority)) {
+			return fmt.Errorf("invalid min priority %v for %v", pref.MinPriority, category)
+		}
+
+		if q := pref.QuietHours; q != nil {
+			start, err := time.Parse("15:04", q.Start)
+			if err != nil {
+				return fmt.Errorf("invalid quiet hours start %v for %v, expected HH:MM", q.Start, category)
+			}
+
+			end, err := time.Parse("15:04", q.End)
+			if err != nil {
+				return fmt.Errorf("invalid quiet hours end %v for %v, expected HH:MM", q.End, category)
+			}
+
+			if start.Equal(end) {
+				return fmt.Errorf("quiet hours for %v start and end at the same time", category)
+			}
+		}
+	}
+
+	return nil
+}
+
+type NotificationSettingsResponse struct {
+	UserId     string                                              `json:"userId"`
+	Timezone   string                                              `json:"timezone"`
+	Categories map[model.NotificationType]model.CategoryPreference `json:"categories"`
+	Digest     model.DigestFrequency                               `json:"digest"`
+	DigestHour int                                                 `json:"digestHour"`
+}
+
+func NewNotificationSettingsResponse(s model.NotificationSettings) NotificationSettingsResponse {
+	categories := s.Setting.Categories
+	if categories == nil {
+		categories = make(map[model.NotificationType]model.CategoryPreference)
+	}
+
+	return NotificationSettingsResponse{
+		UserId:     s.Uid.String(),
+		Timezone:   s.Setting.Timezone,
+		Categories: categories,
+		Digest:     s.Setting.Digest,
+		DigestHour: s.Setting.DigestHour,
+	}
+}
@@ -0,0 +1,97 @@
+package dtos
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+// MaxPayrollDays keeps a run to at most a monthly pay period
+const MaxPayrollDays = 31
+
+// PayrollRequest is the period to pay, the overtime rules fall back to the service defaults
+// when left empty
+type PayrollRequest struct {
+	PeriodStart        time.Time       `json:"periodStart"`
+	PeriodEnd          time.Time       `json:"periodEnd"`
+	OvertimeAfter      decimal.Decimal `json:"overtimeAfter"`
+	OvertimeMultiplier decimal.Decimal `json:"overtimeMultiplier"`
+}
+
+func (p PayrollRequest) Validate() error {
+	if p.PeriodStart.IsZero() || p.PeriodEnd.IsZero() {
+		return errors.New("a payroll run needs a period start and end")
+	}
+
+	if !p.PeriodEnd.After(p.PeriodStart) {
+		return errors.New("period end must be after period start")
+	}
+
+	if p.PeriodEnd.Sub(p.PeriodStart) > MaxPayrollDays*24*time.Hour {
+		return errors.New("a payroll period can be at most 31 days")
+	}
+
+	if p.PeriodEnd.After(time.Now()) {
+		return errors.New("a payroll period can't end in the future")
+	}
+
+	if p.OvertimeAfter.IsNegative() {
+		return errors.New("overtime after cannot be negative")
+	}
+
+	if !p.OvertimeMultiplier.IsZero() && p.OvertimeMultiplier.LessThan(decimal.NewFromInt(1)) {
+		return errors.New("overtime multiplier must be at least 1")
+	}
+
+	return nil
+}
+
+type PayrollRunResponse struct {
+	Rid                string                     `json:"rid,omitempty"`
+	LessorId           string                     `json:"lessorId"`
+	PeriodStart        time.Time                  `json:"periodStart"`
+	PeriodEnd          time.Time                  `json:"periodEnd"`
+	OvertimeAfter      decimal.Decimal            `json:"overtimeAfter"`
+	OvertimeMultiplier decimal.Decimal            `json:"overtimeMultiplier"`
+	RegularHours       decimal.Decimal            `json:"regularHours"`
+	OvertimeHours      decimal.Decimal            `json:"overtimeHours"`
+	FlatFees           decimal.Decimal            `json:"flatFees"`
+	Gross              decimal.Decimal            `json:"gross"`
+	ByMethod           map[string]decimal.Decimal `json:"byMethod"`
+	Closed             bool                       `json:"closed"`
+	CreatedAt          *time.Time                 `json:"createdAt,omitempty"`
+	Items              []model.PayrollItem        `json:"items,omitempty"`
+}
+
+// NewPayrollRunResponse totals the items by payment method, a run that hasn't been saved is
+// a preview and isn't closed
+func NewPayrollRunResponse(run model.PayrollRun) PayrollRunResponse {
+	response := PayrollRunResponse{
+		LessorId:           run.LessorId.String(),
+		PeriodStart:        run.PeriodStart,
+		PeriodEnd:          run.PeriodEnd,
+		OvertimeAfter:      run.OvertimeAfter,
+		OvertimeMultiplier: run.OvertimeMultiplier,
+		RegularHours:       run.RegularHours,
+		OvertimeHours:      run.OvertimeHours,
+		FlatFees:           run.FlatFees,
+		Gross:              run.Gross,
+		ByMethod:           make(map[string]decimal.Decimal),
+		Closed:             run.Id != 0,
+		CreatedAt:          optionalTime(run.CreatedAt),
+		Items:              run.Items,
+	}
+
+	if response.Closed {
+		response.Rid = run.Rid.String()
+	}
+
+	for _, item := range run.Items {
+		method := string(item.PaymentMethod)
+		response.ByMethod[method] = response.ByMethod[method].Add(item.Gross)
+	}
+
+	return response
+}
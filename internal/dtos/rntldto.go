@@ -19,6 +19,8 @@ type RentalPropertyDto struct {
 	PetFriendly       bool            `json:"petFriendly"`
 	NeedsEviction     bool            `json:"needsEviction"`
 	EvictionStartDate time.Time       `json:"evictionStartDate"`
+	LateFee           decimal.Decimal `json:"lateFee"`
+	LateFeeGraceDays  int             `json:"lateFeeGraceDays"`
 }
 
 func (r *RentalPropertyDto) Validte() error {
@@ -37,6 +39,8 @@ func NewRentalPropertyDto(r model.RentalProperty) RentalPropertyDto {
 		PetFriendly:       r.PetFriendly,
 		NeedsEviction:     r.NeedsEviction,
 		EvictionStartDate: r.EvictionStartDate,
+		LateFee:           r.LateFee,
+		LateFeeGraceDays:  r.LateFeeGraceDays,
 	}
 }
 
@@ -52,6 +56,8 @@ func NewRentalPropertyDtoFrmPtr(r *model.RentalProperty) RentalPropertyDto {
 		PetFriendly:       r.PetFriendly,
 		NeedsEviction:     r.NeedsEviction,
 		EvictionStartDate: r.EvictionStartDate,
+		LateFee:           r.LateFee,
+		LateFeeGraceDays:  r.LateFeeGraceDays,
 	}
 }
 
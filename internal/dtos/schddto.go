@@ -0,0 +1,116 @@
+package dtos
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+// edits and cancellations apply to either the one occurrence or it and every one after it
+const (
+	ThisOccurrence    = "this"
+	FutureOccurrences = "future"
+)
+
+type ScheduleResponse struct {
+	Sid           string          `json:"sid"`
+	LessorId      string          `json:"lessorId"`
+	PropertyId    string          `json:"propertyId"`
+	Property      *model.Property `json:"property"`
+	Name          string          `json:"name"`
+	Details       string          `json:"details"`
+	Notes         string          `json:"notes"`
+	Priority      string          `json:"priority"`
+	Category      string          `json:"category"`
+	WorkerId      string          `json:"workerId"`
+	EstimatedCost decimal.Decimal `json:"estimatedCost"`
+	Rule          string          `json:"rule"`
+	StartsAt      time.Time       `json:"startsAt"`
+	ExDates       []time.Time     `json:"exDates"`
+	CancelledAt   time.Time       `json:"cancelledAt"`
+	Upcoming      []time.Time     `json:"upcoming"`
+}
+
+func (s ScheduleResponse) Validate() error {
+	return nil
+}
+
+func NewScheduleResponse(s model.TaskSchedule, upcoming []time.Time) ScheduleResponse {
+	return ScheduleResponse{
+		Sid:           s.Sid.String(),
+		LessorId:      s.LessorId.String(),
+		PropertyId:    s.PropertyId.String(),
+		Property:      s.Property,
+		Name:          s.Name,
+		Details:       s.Details,
+		Notes:         s.Notes,
+		Priority:      string(s.Priority),
+		Category:      string(s.Category),
+		WorkerId:      s.WorkerId.String(),
+		EstimatedCost: s.EstimatedCost,
+		Rule:          s.Rule,
+		StartsAt:      s.StartsAt,
+		ExDates:       s.ExDates,
+		CancelledAt:   s.CancelledAt,
+		Upcoming:      upcoming,
+	}
+}
+
+type ScheduleRequest struct {
+	LessorId      string          `json:"lessorId"`
+	PropertyId    string          `json:"propertyId"`
+	Name          string          `json:"name"`
+	Details       string          `json:"details"`
+	Notes         string          `json:"notes"`
+	Priority      string          `json:"priority"`
+	Category      string          `json:"category"`
+	WorkerId      string          `json:"workerId"`
+	EstimatedCost decimal.Decimal `json:"estimatedCost"`
+	Rule          string          `json:"rule"`
+	StartsAt      time.Time       `json:"startsAt"`
+}
+
+func (s ScheduleRequest) Validate() error {
+	if s.PropertyId == "" || s.LessorId == "" {
+		return errors.New("a schedule requires a lessor and property")
+	}
+
+	if s.Rule == "" || s.StartsAt.IsZero() {
+		return errors.New("a schedule requires a recurrence rule and start time")
+	}
+
+	return nil
+}
+
+// ScheduleModRequest edits one occurrence or splits the series at OccurrenceAt, fields
+// left empty keep their current value. Rule and StartsAt only apply to future edits
+type ScheduleModRequest struct {
+	Scope         string          `json:"scope"`
+	OccurrenceAt  time.Time       `json:"occurrenceAt"`
+	Name          string          `json:"name"`
+	Details       string          `json:"details"`
+	Notes         string          `json:"notes"`
+	Priority      string          `json:"priority"`
+	Category      string          `json:"category"`
+	WorkerId      string          `json:"workerId"`
+	EstimatedCost decimal.Decimal `json:"estimatedCost"`
+	ScheduledAt   time.Time       `json:"scheduledAt"`
+	Rule          string          `json:"rule"`
+	StartsAt      time.Time       `json:"startsAt"`
+}
+
+func (s ScheduleModRequest) Validate() error {
+	switch s.Scope {
+	case ThisOccurrence:
+		if s.OccurrenceAt.IsZero() {
+			return errors.New("the occurrence to edit is required")
+		}
+		return nil
+	case FutureOccurrences:
+		return nil
+	default:
+		return errors.New("scope must be this or future")
+	}
+}
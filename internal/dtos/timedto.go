@@ -0,0 +1,157 @@
+package dtos
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+type ClockRequest struct {
+	Notes string `json:"notes"`
+}
+
+func (c ClockRequest) Validate() error {
+	if len(c.Notes) > 2000 {
+		return errors.New("notes can be at most 2000 characters")
+	}
+	return nil
+}
+
+// TimeAdjustRequest corrects an entry, leaving clockOut empty keeps an open entry open
+type TimeAdjustRequest struct {
+	ClockIn      time.Time `json:"clockIn"`
+	ClockOut     time.Time `json:"clockOut"`
+	BreakMinutes int       `json:"breakMinutes"`
+	Note         string    `json:"note"`
+}
+
+func (t TimeAdjustRequest) Validate() error {
+	if t.ClockIn.IsZero() {
+		return errors.New("clock in time is required")
+	}
+
+	if !t.ClockOut.IsZero() && !t.ClockOut.After(t.ClockIn) {
+		return errors.New("clock out must be after clock in")
+	}
+
+	if t.BreakMinutes < 0 {
+		return errors.New("break minutes cannot be negative")
+	}
+
+	if !t.ClockOut.IsZero() && float64(t.BreakMinutes) >= t.ClockOut.Sub(t.ClockIn).Minutes() {
+		return errors.New("breaks must be shorter than the entry")
+	}
+
+	if len(t.Note) > 255 {
+		return errors.New("note can be at most 255 characters")
+	}
+
+	return nil
+}
+
+type TimeReviewRequest struct {
+	Note string `json:"note"`
+}
+
+func (t TimeReviewRequest) Validate() error {
+	if len(t.Note) > 255 {
+		return errors.New("note can be at most 255 characters")
+	}
+	return nil
+}
+
+type TimesheetApproveRequest struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	Note string    `json:"note"`
+}
+
+func (t TimesheetApproveRequest) Validate() error {
+	if t.From.IsZero() || t.To.IsZero() {
+		return errors.New("approval needs a from and to")
+	}
+
+	if !t.To.After(t.From) {
+		return errors.New("to must be after from")
+	}
+
+	if len(t.Note) > 255 {
+		return errors.New("note can be at most 255 characters")
+	}
+
+	return nil
+}
+
+type TimeEntryResponse struct {
+	Eid            string          `json:"eid"`
+	WorkerId       string          `json:"workerId"`
+	TaskId         string          `json:"taskId"`
+	TaskName       string          `json:"taskName"`
+	ClockIn        time.Time       `json:"clockIn"`
+	ClockOut       *time.Time      `json:"clockOut"`
+	BreakStartedAt *time.Time      `json:"breakStartedAt"`
+	BreakMinutes   int             `json:"breakMinutes"`
+	Minutes        int             `json:"minutes"`
+	Hours          decimal.Decimal `json:"hours"`
+	Notes          string          `json:"notes"`
+	Status         string          `json:"status"`
+	ReviewedBy     string          `json:"reviewedBy,omitempty"`
+	ReviewedAt     *time.Time      `json:"reviewedAt"`
+	ReviewNote     string          `json:"reviewNote"`
+}
+
+// NewTimeEntryResponse works out the hours as of now so open entries show time so far
+func NewTimeEntryResponse(e model.TimeEntry, now time.Time) TimeEntryResponse {
+	response := TimeEntryResponse{
+		Eid:            e.Eid.String(),
+		WorkerId:       e.WorkerId.String(),
+		TaskId:         e.TaskId.String(),
+		ClockIn:        e.ClockIn,
+		ClockOut:       optionalTime(e.ClockOut),
+		BreakStartedAt: optionalTime(e.BreakStartedAt),
+		BreakMinutes:   e.BreakMinutes,
+		Minutes:        e.Minutes(now),
+		Hours:          e.Hours(now),
+		Notes:          e.Notes,
+		Status:         string(e.Status),
+		ReviewedAt:     optionalTime(e.ReviewedAt),
+		ReviewNote:     e.ReviewNote,
+	}
+
+	if e.Task != nil {
+		response.TaskName = e.Task.Name
+	}
+
+	if !e.ReviewedAt.IsZero() {
+		response.ReviewedBy = e.ReviewedBy.String()
+	}
+
+	return response
+}
+
+// TimeTotals are hours by review state, rejected time is left out of the total
+type TimeTotals struct {
+	Hours         decimal.Decimal `json:"hours"`
+	OpenHours     decimal.Decimal `json:"openHours"`
+	PendingHours  decimal.Decimal `json:"pendingHours"`
+	ApprovedHours decimal.Decimal `json:"approvedHours"`
+	RejectedHours decimal.Decimal `json:"rejectedHours"`
+}
+
+type TimesheetResponse struct {
+	WorkerId string              `json:"workerId,omitempty"`
+	TaskId   string              `json:"taskId,omitempty"`
+	From     *time.Time          `json:"from,omitempty"`
+	To       *time.Time          `json:"to,omitempty"`
+	Totals   TimeTotals          `json:"totals"`
+	Entries  []TimeEntryResponse `json:"entries"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
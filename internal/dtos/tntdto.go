@@ -0,0 +1,170 @@
+package dtos
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+)
+
+type TenantResponse struct {
+	Uid         string                `json:"uid"`
+	LessorId    string                `json:"lessorId"`
+	FirstName   string                `json:"firstName"`
+	LastName    string                `json:"lastName"`
+	Email       string                `json:"email"`
+	Phone       string                `json:"phone"`
+	IsActive    bool                  `json:"isActive"`
+	PropertyId  string                `json:"propertyId"`
+	Property    *model.Property       `json:"property"`
+	Rental      *model.RentalProperty `json:"rental"`
+	MoveInDate  time.Time             `json:"moveInDate"`
+	MoveOutDate time.Time             `json:"moveOutDate"`
+}
+
+func (t TenantResponse) Validate() error {
+	return nil
+}
+
+func NewTenantResponse(t model.Tenant) TenantResponse {
+	res := TenantResponse{
+		Uid:         t.Uid.String(),
+		LessorId:    t.LessorId.String(),
+		PropertyId:  t.PropertyId.String(),
+		Property:    t.Property,
+		Rental:      t.Rental,
+		MoveInDate:  t.MoveInDate,
+		MoveOutDate: t.MoveOutDate,
+	}
+
+	if t.User != nil {
+		res.FirstName = t.User.FirstName
+		res.LastName = t.User.LastName
+		res.Email = t.User.Email
+		res.Phone = t.User.Phone
+		res.IsActive = t.User.IsActive
+	}
+
+	return res
+}
+
+// TenantInviteResponse is returned when a tenant is invited, the token is only ever
+// handed out here and has to be passed on to the tenant to sign up with
+type TenantInviteResponse struct {
+	Tenant      TenantResponse `json:"tenant"`
+	InviteToken string         `json:"inviteToken"`
+	ExpiresAt   time.Time      `json:"expiresAt"`
+}
+
+// LessorContact is what a tenant is shown about who they rent from
+type LessorContact struct {
+	Uid       string `json:"uid"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+}
+
+// TenantHomeResponse is a signed in tenant's view of their own tenancy
+type TenantHomeResponse struct {
+	TenantResponse
+	Lessor *LessorContact `json:"lessor"`
+}
+
+func NewTenantHomeResponse(t model.Tenant) TenantHomeResponse {
+	res := TenantHomeResponse{TenantResponse: NewTenantResponse(t)}
+	if t.Lessor != nil {
+		res.Lessor = &LessorContact{
+			Uid:       t.Lessor.Uid.String(),
+			FirstName: t.Lessor.FirstName,
+			LastName:  t.Lessor.LastName,
+			Email:     t.Lessor.Email,
+			Phone:     t.Lessor.Phone,
+		}
+	}
+
+	return res
+}
+
+type TenantRequest struct {
+	LessorId    string    `json:"lessorId"`
+	PropertyId  string    `json:"propertyId"`
+	FirstName   string    `json:"firstName"`
+	LastName    string    `json:"lastName"`
+	Email       string    `json:"email"`
+	Phone       string    `json:"phone"`
+	MoveInDate  time.Time `json:"moveInDate"`
+	MoveOutDate time.Time `json:"moveOutDate"`
+}
+
+func (t TenantRequest) Validate() error {
+	if t.LessorId == "" || t.PropertyId == "" {
+		return errors.New("a tenant requires a lessor and property")
+	}
+
+	if utils.CharCount(t.FirstName) < minStrLen || utils.CharCount(t.FirstName) > maxFnameLen {
+		return fmt.Errorf("first name must be between %v and %v characters", minStrLen, maxFnameLen)
+	}
+
+	if utils.CharCount(t.LastName) < minStrLen || utils.CharCount(t.LastName) > maxLnameLen {
+		return fmt.Errorf("last name must be between %v and %v characters", minStrLen, maxLnameLen)
+	}
+
+	if t.Email == "" || utils.CharCount(t.Email) > maxEmalLen {
+		return ErrMaxLength{Field: "email", MaxLen: maxEmalLen}
+	}
+
+	if utils.CharCount(t.Phone) != maxPhneLen {
+		return fmt.Errorf("invalid phone length, must be %v digits", maxPhneLen)
+	}
+
+	return checkMoveDates(t.MoveInDate, t.MoveOutDate)
+}
+
+// TenantModRequest moves a tenant to another property or changes their dates, fields left
+// empty keep their current value
+type TenantModRequest struct {
+	PropertyId  string    `json:"propertyId"`
+	MoveInDate  time.Time `json:"moveInDate"`
+	MoveOutDate time.Time `json:"moveOutDate"`
+}
+
+func (t TenantModRequest) Validate() error {
+	if t.PropertyId == "" && t.MoveInDate.IsZero() && t.MoveOutDate.IsZero() {
+		return errors.New("nothing to update")
+	}
+
+	return checkMoveDates(t.MoveInDate, t.MoveOutDate)
+}
+
+type TenantSignupRequest struct {
+	InviteToken string `json:"inviteToken"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DeviceId    string `json:"deviceId"`
+}
+
+func (t TenantSignupRequest) Validate() error {
+	if t.InviteToken == "" {
+		return errors.New("invite token is required")
+	}
+
+	if utils.CharCount(t.Username) < minStrLen || utils.CharCount(t.Username) > maxUsrnameLen {
+		return fmt.Errorf("username must be between %v and %v characters", minStrLen, maxUsrnameLen)
+	}
+
+	if utils.CharCount(t.Password) < minPwdLen || utils.CharCount(t.Password) > maxPwdLen {
+		return fmt.Errorf("password must be between %v and %v characters", minPwdLen, maxPwdLen)
+	}
+
+	return nil
+}
+
+func checkMoveDates(moveIn, moveOut time.Time) error {
+	if !moveIn.IsZero() && !moveOut.IsZero() && moveOut.Before(moveIn) {
+		return errors.New("move out date can not be before the move in date")
+	}
+	return nil
+}
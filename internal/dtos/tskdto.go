@@ -1,35 +1,49 @@
 package dtos
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/Z3DRP/lessor-service/internal/availability"
 	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 type TaskResponse struct {
-	Tid           string          `json:"tid"`
-	Name          string          `json:"name"`
-	LessorId      string          `json:"lessorId"`
-	Details       string          `json:"details"`
-	Notes         string          `json:"notes"`
-	PropertyId    string          `json:"propertyId"`
-	Property      *model.Property `json:"property"`
-	Category      string          `json:"category"`
-	ScheduledAt   time.Time       `json:"scheduledAt"`
-	StartedAt     time.Time       `json:"startedAt"`
-	CompletedAt   time.Time       `json:"completedAt"`
-	PausedAt      time.Time       `json:"pausedAt"`
-	PausedReason  string          `json:"pausedReason"`
-	FailedAt      time.Time       `json:"failedAt"`
-	FailedReason  string          `json:"failedReason"`
-	WorkerId      string          `json:"workerId"`
-	Worker        *model.Worker   `json:"worker"`
-	EstimatedCost float64         `json:"estimatedCost"`
-	ActualCost    float64         `json:"actualCost"`
-	Profit        float64         `json:"profit"`
-	Priority      string          `json:"priority"`
-	Image         string          `json:"image"`
-	ImageUrl      *string         `json:"imageUrl"`
+	Tid              string               `json:"tid"`
+	Name             string               `json:"name"`
+	LessorId         string               `json:"lessorId"`
+	Details          string               `json:"details"`
+	Notes            string               `json:"notes"`
+	PropertyId       string               `json:"propertyId"`
+	Property         *model.Property      `json:"property"`
+	Category         string               `json:"category"`
+	Status           string               `json:"status"`
+	ScheduledAt      time.Time            `json:"scheduledAt"`
+	EstimatedMinutes int                  `json:"estimatedMinutes"`
+	Appointment      bool                 `json:"appointment"`
+	StartedAt        time.Time            `json:"startedAt"`
+	CompletedAt      time.Time            `json:"completedAt"`
+	PausedAt         time.Time            `json:"pausedAt"`
+	PausedReason     string               `json:"pausedReason"`
+	FailedAt         time.Time            `json:"failedAt"`
+	FailedReason     string               `json:"failedReason"`
+	WorkerId         string               `json:"workerId"`
+	Worker           *model.Worker        `json:"worker"`
+	EstimatedCost    decimal.Decimal      `json:"estimatedCost"`
+	ActualCost       decimal.Decimal      `json:"actualCost"`
+	BilledAmount     decimal.Decimal      `json:"billedAmount"`
+	Profit           decimal.Decimal      `json:"profit"`
+	FlatFee          decimal.Decimal      `json:"flatFee"`
+	Priority         string               `json:"priority"`
+	Image            string               `json:"image"`
+	ImageUrl         *string              `json:"imageUrl"`
+	ScheduleId       string               `json:"scheduleId"`
+	OccurrenceAt     time.Time            `json:"occurrenceAt"`
+	Assignments      []AssignmentResponse `json:"assignments"`
 }
 
 func (t TaskResponse) Validate() error {
@@ -38,29 +52,37 @@ func (t TaskResponse) Validate() error {
 
 func NewTaskResposne(t *model.Task, url *string) TaskResponse {
 	return TaskResponse{
-		Tid:           t.Tid.String(),
-		Name:          t.Name,
-		LessorId:      t.LessorId.String(),
-		Details:       t.Details,
-		Notes:         t.Notes,
-		PropertyId:    t.PropertyId.String(),
-		Property:      t.Property,
-		Category:      string(t.Category),
-		ScheduledAt:   t.ScheduledAt,
-		StartedAt:     t.StartedAt,
-		CompletedAt:   t.CompletedAt,
-		PausedAt:      t.PausedAt,
-		PausedReason:  t.PausedReason,
-		FailedAt:      t.FailedAt,
-		FailedReason:  t.FailedReason,
-		WorkerId:      t.WorkerId.String(),
-		Worker:        t.Worker,
-		EstimatedCost: t.EstimatedCost,
-		ActualCost:    t.ActualCost,
-		Priority:      string(t.Priority),
-		Profit:        t.Profit,
-		Image:         t.Image,
-		ImageUrl:      url,
+		Tid:              t.Tid.String(),
+		Name:             t.Name,
+		LessorId:         t.LessorId.String(),
+		Details:          t.Details,
+		Notes:            t.Notes,
+		PropertyId:       t.PropertyId.String(),
+		Property:         t.Property,
+		Category:         string(t.Category),
+		Status:           string(t.Status),
+		ScheduledAt:      t.ScheduledAt,
+		EstimatedMinutes: t.EstimatedMinutes,
+		Appointment:      t.Appointment,
+		StartedAt:        t.StartedAt,
+		CompletedAt:      t.CompletedAt,
+		PausedAt:         t.PausedAt,
+		PausedReason:     t.PausedReason,
+		FailedAt:         t.FailedAt,
+		FailedReason:     t.FailedReason,
+		WorkerId:         t.WorkerId.String(),
+		Worker:           t.Worker,
+		EstimatedCost:    t.EstimatedCost,
+		ActualCost:       t.ActualCost,
+		BilledAmount:     t.BilledAmount,
+		Priority:         string(t.Priority),
+		Profit:           t.Profit,
+		FlatFee:          t.FlatFee,
+		Image:            t.Image,
+		ImageUrl:         url,
+		ScheduleId:       t.ScheduleId.String(),
+		OccurrenceAt:     t.OccurrenceAt,
+		Assignments:      NewAssignmentResponseList(t.Assignments),
 	}
 }
 
@@ -75,75 +97,357 @@ func NewTaskResponseList(t []model.Task) []TaskResponse {
 
 func NewTaskResposneFrmPntr(t *model.Task, url *string) TaskResponse {
 	return TaskResponse{
-		Tid:           t.Tid.String(),
-		LessorId:      t.LessorId.String(),
-		Name:          t.Name,
-		Details:       t.Details,
-		Notes:         t.Notes,
-		PropertyId:    t.PropertyId.String(),
-		Category:      string(t.Category),
-		ScheduledAt:   t.ScheduledAt,
-		StartedAt:     t.StartedAt,
-		CompletedAt:   t.CompletedAt,
-		PausedAt:      t.PausedAt,
-		PausedReason:  t.PausedReason,
-		FailedAt:      t.FailedAt,
-		FailedReason:  t.FailedReason,
-		WorkerId:      t.WorkerId.String(),
-		Worker:        t.Worker,
-		EstimatedCost: t.EstimatedCost,
-		ActualCost:    t.ActualCost,
-		Priority:      string(t.Priority),
-		Profit:        t.Profit,
-		Image:         t.Image,
-		ImageUrl:      url,
+		Tid:              t.Tid.String(),
+		LessorId:         t.LessorId.String(),
+		Name:             t.Name,
+		Details:          t.Details,
+		Notes:            t.Notes,
+		PropertyId:       t.PropertyId.String(),
+		Category:         string(t.Category),
+		Status:           string(t.Status),
+		ScheduledAt:      t.ScheduledAt,
+		EstimatedMinutes: t.EstimatedMinutes,
+		Appointment:      t.Appointment,
+		StartedAt:        t.StartedAt,
+		CompletedAt:      t.CompletedAt,
+		PausedAt:         t.PausedAt,
+		PausedReason:     t.PausedReason,
+		FailedAt:         t.FailedAt,
+		FailedReason:     t.FailedReason,
+		WorkerId:         t.WorkerId.String(),
+		Worker:           t.Worker,
+		EstimatedCost:    t.EstimatedCost,
+		ActualCost:       t.ActualCost,
+		BilledAmount:     t.BilledAmount,
+		Priority:         string(t.Priority),
+		Profit:           t.Profit,
+		FlatFee:          t.FlatFee,
+		Image:            t.Image,
+		ImageUrl:         url,
+		ScheduleId:       t.ScheduleId.String(),
+		OccurrenceAt:     t.OccurrenceAt,
 	}
 }
 
 type TaskRequest struct {
-	Tid          string    `json:"tid"`
-	LessorId     string    `json:"lessorId"`
-	Name         string    `json:"name"`
-	Details      string    `json:"details"`
-	Notes        string    `json:"notes"`
-	PropertyId   string    `json:"propertyId"`
-	Category     string    `json:"category"`
-	ScheduledAt  time.Time `json:"scheduledAt"`
-	WorkerId     string    `json:"workerId"`
-	EstimateCost float64   `json:"estimatedCost"`
-	ActualCost   float64   `json:"actualCost"`
-	Profit       float64   `json:"profit"`
-	Image        string    `json:"image"`
-	Priority     string    `json:"priority"`
+	Tid              string          `json:"tid"`
+	LessorId         string          `json:"lessorId"`
+	Name             string          `json:"name"`
+	Details          string          `json:"details"`
+	Notes            string          `json:"notes"`
+	PropertyId       string          `json:"propertyId"`
+	Category         string          `json:"category"`
+	ScheduledAt      time.Time       `json:"scheduledAt"`
+	EstimatedMinutes int             `json:"estimatedMinutes"`
+	Appointment      bool            `json:"appointment"`
+	WorkerId         string          `json:"workerId"`
+	EstimateCost     decimal.Decimal `json:"estimatedCost"`
+	BilledAmount     decimal.Decimal `json:"billedAmount"`
+	FlatFee          decimal.Decimal `json:"flatFee"`
+	Image            string          `json:"image"`
+	Priority         string          `json:"priority"`
 }
 
 func (t TaskRequest) Validate() error {
+	if t.EstimatedMinutes < 0 {
+		return errors.New("estimated minutes cannot be negative")
+	}
+	if t.FlatFee.IsNegative() || t.BilledAmount.IsNegative() {
+		return errors.New("flat fee and billed amount cannot be negative")
+	}
 	return nil
 }
 
 type TaskModRequest struct {
-	Tid           string    `json:"tid"`
-	LessorId      string    `json:"alessorId"`
-	Name          string    `json:"name"`
-	Details       string    `json:"details"`
-	Notes         string    `json:"notes"`
-	PropertyId    string    `json:"propertyId"`
-	Category      string    `json:"category"`
-	ScheduledAt   time.Time `json:"scheduledAt"`
-	StartedAt     time.Time `json:"startedAt"`
-	CompletedAt   time.Time `json:"completedAt"`
-	PausedAt      time.Time `json:"pausedAt"`
-	PausedReason  string    `json:"pausedReason"`
-	FailedAt      time.Time `json:"failedAt"`
-	FailedReason  string    `json:"failedReason"`
-	WorkerId      string    `json:"workerId"`
-	EstimatedCost float64   `json:"estimatedCost"`
-	ActualCost    float64   `json:"actualCost"`
-	Profit        float64   `json:"profit"`
-	Image         string    `json:"image"`
-	Priority      string    `json:"priority"`
+	Tid              string          `json:"tid"`
+	LessorId         string          `json:"alessorId"`
+	Name             string          `json:"name"`
+	Details          string          `json:"details"`
+	Notes            string          `json:"notes"`
+	PropertyId       string          `json:"propertyId"`
+	Category         string          `json:"category"`
+	ScheduledAt      time.Time       `json:"scheduledAt"`
+	EstimatedMinutes int             `json:"estimatedMinutes"`
+	Appointment      bool            `json:"appointment"`
+	StartedAt        time.Time       `json:"startedAt"`
+	CompletedAt      time.Time       `json:"completedAt"`
+	PausedAt         time.Time       `json:"pausedAt"`
+	PausedReason     string          `json:"pausedReason"`
+	FailedAt         time.Time       `json:"failedAt"`
+	FailedReason     string          `json:"failedReason"`
+	WorkerId         string          `json:"workerId"`
+	EstimatedCost    decimal.Decimal `json:"estimatedCost"`
+	BilledAmount     decimal.Decimal `json:"billedAmount"`
+	FlatFee          decimal.Decimal `json:"flatFee"`
+	Image            string          `json:"image"`
+	Priority         string          `json:"priority"`
 }
 
 func (t TaskModRequest) Validate() error {
+	if t.EstimatedMinutes < 0 {
+		return errors.New("estimated minutes cannot be negative")
+	}
+	if t.FlatFee.IsNegative() || t.BilledAmount.IsNegative() {
+		return errors.New("flat fee and billed amount cannot be negative")
+	}
+	return nil
+}
+
+type TaskFailRequest struct {
+	FailedReason string `json:"failedReason"`
+}
+
+func (t TaskFailRequest) Validate() error {
+	if t.FailedReason == "" {
+		return errors.New("a reason is required to fail a task")
+	}
+	return nil
+}
+
+type TaskEventResponse struct {
+	TaskId    string                 `json:"taskId"`
+	EventType string                 `json:"eventType"`
+	Actor     TaskEventActor         `json:"actor"`
+	Details   map[string]interface{} `json:"details"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+type TaskEventActor struct {
+	Id       string `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func NewTaskEventResponse(e model.TaskEvent) TaskEventResponse {
+	return TaskEventResponse{
+		TaskId:    e.TaskId.String(),
+		EventType: string(e.EventType),
+		Actor: TaskEventActor{
+			Id:       e.ActorId.String(),
+			Username: e.ActorName,
+			Role:     e.ActorRole,
+		},
+		Details:   e.Details,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+func NewTaskEventResponseList(events []model.TaskEvent) []TaskEventResponse {
+	response := make([]TaskEventResponse, 0, len(events))
+	for _, e := range events {
+		response = append(response, NewTaskEventResponse(e))
+	}
+
+	return response
+}
+
+type AssignmentResponse struct {
+	TaskId         string        `json:"taskId"`
+	WorkerId       string        `json:"workerId"`
+	Worker         *model.Worker `json:"worker"`
+	IsLead         bool          `json:"isLead"`
+	Status         string        `json:"status"`
+	DeclinedReason string        `json:"declinedReason"`
+	RespondedAt    time.Time     `json:"respondedAt"`
+	AssignedAt     time.Time     `json:"assignedAt"`
+}
+
+func NewAssignmentResponse(a model.AssignedTask) AssignmentResponse {
+	return AssignmentResponse{
+		TaskId:         a.TaskId.String(),
+		WorkerId:       a.WorkerId.String(),
+		Worker:         a.Worker,
+		IsLead:         a.IsLead,
+		Status:         string(a.Status),
+		DeclinedReason: a.DeclinedReason,
+		RespondedAt:    a.RespondedAt,
+		AssignedAt:     a.AssignedAt,
+	}
+}
+
+func NewAssignmentResponseList(assignments []model.AssignedTask) []AssignmentResponse {
+	response := make([]AssignmentResponse, 0, len(assignments))
+	for _, a := range assignments {
+		response = append(response, NewAssignmentResponse(a))
+	}
+	return response
+}
+
+// TaskCrewRequest adds workers to a task, LeadId has to be one of them or already on the crew
+type TaskCrewRequest struct {
+	WorkerIds []string `json:"workerIds"`
+	LeadId    string   `json:"leadId"`
+}
+
+func (t TaskCrewRequest) Validate() error {
+	if len(t.WorkerIds) == 0 && t.LeadId == "" {
+		return errors.New("at least one worker is required")
+	}
+
+	for _, id := range append(t.WorkerIds, t.LeadId) {
+		if id == "" {
+			continue
+		}
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("invalid worker id %v", id)
+		}
+	}
+
+	return nil
+}
+
+type TaskLeadRequest struct {
+	WorkerId string `json:"workerId"`
+}
+
+func (t TaskLeadRequest) Validate() error {
+	if _, err := uuid.Parse(t.WorkerId); err != nil {
+		return errors.New("a valid worker id is required")
+	}
+	return nil
+}
+
+type AssignmentDeclineRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (a AssignmentDeclineRequest) Validate() error {
+	if utils.CharCount(a.Reason) > 255 {
+		return errors.New("reason can be at most 255 characters")
+	}
 	return nil
 }
+
+// CandidateResponse is a worker dispatch suggests for a task, factors explain how the score
+// was reached
+type CandidateResponse struct {
+	WorkerId      string                  `json:"workerId"`
+	Name          string                  `json:"name"`
+	Specilization string                  `json:"specilization"`
+	Score         float64                 `json:"score"`
+	Available     bool                    `json:"available"`
+	OpenTasks     int                     `json:"openTasks"`
+	DistanceKm    *float64                `json:"distanceKm"`
+	Factors       []ScoreFactor           `json:"factors"`
+	Conflicts     []availability.Conflict `json:"conflicts"`
+}
+
+type ScoreFactor struct {
+	Factor string  `json:"factor"`
+	Points float64 `json:"points"`
+	Max    float64 `json:"max"`
+	Detail string  `json:"detail"`
+}
+
+type RouteStop struct {
+	Sequence    int       `json:"sequence"`
+	TaskId      string    `json:"taskId"`
+	Name        string    `json:"name"`
+	Priority    string    `json:"priority"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+	Appointment bool      `json:"appointment"`
+	PropertyId  string    `json:"propertyId"`
+	Lat         float64   `json:"lat"`
+	Lng         float64   `json:"lng"`
+	LegKm       float64   `json:"legKm"`
+}
+
+// LineString is a GeoJSON geometry, each coordinate is [lng, lat]
+type LineString struct {
+	Type        string      `json:"type"`
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+// RouteResponse is a worker's day in visiting order, ScheduledKm is what driving the tasks in
+// scheduled order would have been for comparison. Tasks without coordinates are in Unrouted
+type RouteResponse struct {
+	WorkerId    string      `json:"workerId"`
+	Date        string      `json:"date"`
+	Timezone    string      `json:"timezone"`
+	Stops       []RouteStop `json:"stops"`
+	Unrouted    []RouteStop `json:"unrouted"`
+	TotalKm     float64     `json:"totalKm"`
+	ScheduledKm float64     `json:"scheduledKm"`
+	Path        LineString  `json:"path"`
+}
+
+// TaskFeeRequest is a material or labor line item, quantity defaults to 1 and for labor is
+// the hours worked with the unit cost as the hourly rate
+type TaskFeeRequest struct {
+	Kind     string          `json:"kind"`
+	Material string          `json:"material"`
+	Quantity decimal.Decimal `json:"quantity"`
+	UnitCost decimal.Decimal `json:"unitCost"`
+	Details  string          `json:"details"`
+}
+
+func (t TaskFeeRequest) Validate() error {
+	switch model.FeeKind(t.Kind) {
+	case "", model.MaterialFee, model.LaborFee:
+	default:
+		return fmt.Errorf("invalid fee kind %v", t.Kind)
+	}
+
+	if t.Material == "" {
+		return errors.New("a fee needs a description of the material or labor")
+	}
+
+	if len(t.Material) > 255 || len(t.Details) > 255 {
+		return errors.New("material and details can be at most 255 characters")
+	}
+
+	if t.Quantity.IsNegative() || t.UnitCost.IsNegative() {
+		return errors.New("quantity and unit cost cannot be negative")
+	}
+
+	return nil
+}
+
+type TaskFeeResponse struct {
+	Fid         string          `json:"fid"`
+	TaskId      string          `json:"taskId"`
+	Kind        string          `json:"kind"`
+	Material    string          `json:"material"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	UnitCost    decimal.Decimal `json:"unitCost"`
+	Cost        decimal.Decimal `json:"cost"`
+	Details     string          `json:"details"`
+	Processed   bool            `json:"processed"`
+	ProcessedAt time.Time       `json:"processedAt"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+func NewTaskFeeResponse(f model.TaskFee) TaskFeeResponse {
+	return TaskFeeResponse{
+		Fid:         f.Fid.String(),
+		TaskId:      f.TaskId.String(),
+		Kind:        string(f.Kind),
+		Material:    f.Material,
+		Quantity:    f.Quantity,
+		UnitCost:    f.UnitCost,
+		Cost:        f.Cost,
+		Details:     f.Details,
+		Processed:   f.Processed,
+		ProcessedAt: f.ProcessedAt,
+		CreatedAt:   f.CreatedAt,
+	}
+}
+
+// TaskCostsResponse is the task's rolled up cost after its fees change
+type TaskCostsResponse struct {
+	TaskId        string          `json:"taskId"`
+	EstimatedCost decimal.Decimal `json:"estimatedCost"`
+	ActualCost    decimal.Decimal `json:"actualCost"`
+	BilledAmount  decimal.Decimal `json:"billedAmount"`
+	Profit        decimal.Decimal `json:"profit"`
+}
+
+func NewTaskCostsResponse(t model.Task) TaskCostsResponse {
+	return TaskCostsResponse{
+		TaskId:        t.Tid.String(),
+		EstimatedCost: t.EstimatedCost,
+		ActualCost:    t.ActualCost,
+		BilledAmount:  t.BilledAmount,
+		Profit:        t.Profit,
+	}
+}
@@ -1,6 +1,7 @@
 package dtos
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -28,10 +29,12 @@ type UserSignupRequest struct {
 	Phone       string
 	Email       string
 	Password    string
+	DeviceId    string
 }
 
 func (u *UserSignupRequest) Validate() error {
-	if u.ProfileType != "alessor" && u.ProfileType != "worker" {
+	// workers and tenants only sign up through the invite their lessor sent them
+	if u.ProfileType != "alessor" {
 		return fmt.Errorf("invalid profile type %v not supported", u.ProfileType)
 	}
 	return baseUserValidate(UserRequest{
@@ -57,6 +60,7 @@ type WorkerUserSignupRequest struct {
 	LessorId      string          `json:"lessorId"`
 	PayRate       decimal.Decimal `json:"payRate"`
 	PaymentMethod string          `json:"paymentMethod"`
+	DeviceId      string          `json:"deviceId"`
 }
 
 func (u *WorkerUserSignupRequest) Validate() error {
@@ -108,6 +112,11 @@ type UserRequest struct {
 }
 
 func (u *UserRequest) Validate() error {
+	// an update can leave the password out, it's only checked when it's being changed
+	if u.Password == "" {
+		return checkUserFields(*u)
+	}
+
 	return baseUserValidate(UserRequest{
 		FirstName: u.FirstName,
 		LastName:  u.LastName,
@@ -119,6 +128,22 @@ func (u *UserRequest) Validate() error {
 }
 
 func baseUserValidate(u UserRequest) error {
+	if err := checkUserFields(u); err != nil {
+		return err
+	}
+
+	if utils.CharCount(u.Password) > maxPwdLen {
+		return ErrMaxLength{Field: "password", MaxLen: maxPwdLen}
+	}
+
+	if utils.CharCount(u.Password) < minPwdLen {
+		return ErrMinLength{Field: "password", MinLen: maxPwdLen}
+	}
+
+	return nil
+}
+
+func checkUserFields(u UserRequest) error {
 	if utils.CharCount(u.FirstName) > maxFnameLen {
 		return ErrMaxLength{Field: "first name", MaxLen: maxFnameLen}
 	}
@@ -150,14 +175,6 @@ func baseUserValidate(u UserRequest) error {
 	// 	return errors.New("invalid phone number")
 	// }
 
-	if utils.CharCount(u.Password) > maxPwdLen {
-		return ErrMaxLength{Field: "password", MaxLen: maxPwdLen}
-	}
-
-	if utils.CharCount(u.Password) < minPwdLen {
-		return ErrMinLength{Field: "password", MinLen: maxPwdLen}
-	}
-
 	if utils.CharCount(u.Username) > maxUsrnameLen {
 		return ErrMaxLength{Field: "username", MaxLen: maxUsrnameLen}
 	}
@@ -168,3 +185,14 @@ func baseUserValidate(u UserRequest) error {
 
 	return nil
 }
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (r RefreshTokenRequest) Validate() error {
+	if r.RefreshToken == "" {
+		return errors.New("refresh token is required")
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package dtos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/webhook"
+	"github.com/google/uuid"
+)
+
+type WebhookRequest struct {
+	LessorId    string               `json:"lessorId"`
+	Url         string               `json:"url"`
+	Events      []model.WebhookEvent `json:"events"`
+	Description string               `json:"description"`
+}
+
+func (w WebhookRequest) Validate() error {
+	if w.LessorId == "" {
+		return errors.New("a webhook requires a lessor")
+	}
+
+	if err := validWebhookUrl(w.Url); err != nil {
+		return err
+	}
+
+	return validWebhookEvents(w.Events)
+}
+
+// WebhookModRequest edits a subscription, fields left empty keep their current value.
+// Setting active turns a disabled subscription back on and RotateSecret issues a new secret
+type WebhookModRequest struct {
+	Url          string               `json:"url"`
+	Events       []model.WebhookEvent `json:"events"`
+	Description  *string              `json:"description"`
+	Active       *bool                `json:"active"`
+	RotateSecret bool                 `json:"rotateSecret"`
+}
+
+func (w WebhookModRequest) Validate() error {
+	if w.Url != "" {
+		if err := validWebhookUrl(w.Url); err != nil {
+			return err
+		}
+	}
+
+	if w.Events != nil {
+		return validWebhookEvents(w.Events)
+	}
+
+	return nil
+}
+
+// WebhookResponse only carries the secret when it was just created or rotated
+type WebhookResponse struct {
+	Wid            string               `json:"wid"`
+	LessorId       string               `json:"lessorId"`
+	Url            string               `json:"url"`
+	Events         []model.WebhookEvent `json:"events"`
+	Description    string               `json:"description"`
+	Secret         string               `json:"secret,omitempty"`
+	Active         bool                 `json:"active"`
+	Failures       int                  `json:"failures"`
+	DisabledAt     time.Time            `json:"disabledAt"`
+	DisabledReason string               `json:"disabledReason"`
+	CreatedAt      time.Time            `json:"createdAt"`
+	UpdatedAt      time.Time            `json:"updatedAt"`
+}
+
+func NewWebhookResponse(w model.WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		Wid:            w.Wid.String(),
+		LessorId:       w.LessorId.String(),
+		Url:            w.Url,
+		Events:         w.Events,
+		Description:    w.Description,
+		Active:         w.Active,
+		Failures:       w.Failures,
+		DisabledAt:     w.DisabledAt,
+		DisabledReason: w.DisabledReason,
+		CreatedAt:      w.CreatedAt,
+		UpdatedAt:      w.UpdatedAt,
+	}
+}
+
+type WebhookDeliveryResponse struct {
+	Did            string                 `json:"did"`
+	SubscriptionId string                 `json:"subscriptionId"`
+	Event          string                 `json:"event"`
+	Status         string                 `json:"status"`
+	StatusCode     int                    `json:"statusCode"`
+	Attempts       []model.WebhookAttempt `json:"attempts"`
+	NextAttemptAt  time.Time              `json:"nextAttemptAt"`
+	DeliveredAt    time.Time              `json:"deliveredAt"`
+	RedeliveryOf   string                 `json:"redeliveryOf"`
+	Payload        json.RawMessage        `json:"payload"`
+	CreatedAt      time.Time              `json:"createdAt"`
+}
+
+func NewWebhookDeliveryResponse(d model.WebhookDelivery) WebhookDeliveryResponse {
+	attempts := d.Attempts
+	if attempts == nil {
+		attempts = make([]model.WebhookAttempt, 0)
+	}
+
+	var redeliveryOf string
+	if d.RedeliveryOf != uuid.Nil {
+		redeliveryOf = d.RedeliveryOf.String()
+	}
+
+	return WebhookDeliveryResponse{
+		Did:            d.Did.String(),
+		SubscriptionId: d.SubscriptionId.String(),
+		Event:          string(d.Event),
+		Status:         string(d.Status),
+		StatusCode:     d.StatusCode,
+		Attempts:       attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		DeliveredAt:    d.DeliveredAt,
+		RedeliveryOf:   redeliveryOf,
+		Payload:        d.Payload,
+		CreatedAt:      d.CreatedAt,
+	}
+}
+
+// webhooks are only posted over https so the payload and signature can't be read in transit
+func validWebhookUrl(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid webhook url %v, expected an https url", raw)
+	}
+
+	if u.User != nil {
+		return errors.New("webhook urls can not carry credentials, use the signing secret instead")
+	}
+
+	// hostnames are checked again when the webhook is sent since they can resolve anywhere
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("invalid webhook url %v, it has to be publicly reachable", raw)
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil && webhook.Blocked(addr) {
+		return fmt.Errorf("invalid webhook url %v, it has to be publicly reachable", raw)
+	}
+
+	return nil
+}
+
+func validWebhookEvents(events []model.WebhookEvent) error {
+	if len(events) == 0 {
+		return errors.New("a webhook requires at least one event")
+	}
+
+	for i, event := range events {
+		if !slices.Contains(model.WebhookEvents, event) {
+			return fmt.Errorf("invalid webhook event %v", event)
+		}
+
+		if slices.Contains(events[:i], event) {
+			return fmt.Errorf("webhook event %v is listed twice", event)
+		}
+	}
+
+	return nil
+}
@@ -1,8 +1,11 @@
 package dtos
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/Z3DRP/lessor-service/internal/availability"
 	"github.com/Z3DRP/lessor-service/internal/model"
 	"github.com/shopspring/decimal"
 )
@@ -17,6 +20,7 @@ type WorkerDto struct {
 	PayRate       decimal.Decimal `json:"payRate"`
 	LessorId      string          `json:"lessorId"`
 	PaymentMethod string          `json:"paymentMethod"`
+	Timezone      string          `json:"timezone"`
 	Image         string          `json:"image"`
 	ImageUrl      string          `json:"imageUrl"`
 }
@@ -36,6 +40,7 @@ func NewWorkerDto(w model.Worker, url *string) WorkerDto {
 		PayRate:       w.PayRate,
 		LessorId:      w.LessorId.String(),
 		PaymentMethod: string(w.PaymentMethod),
+		Timezone:      w.Timezone,
 		Image:         w.Image,
 	}
 }
@@ -51,6 +56,125 @@ func NewWorkerDtoFrmPtr(w *model.Worker, url *string) WorkerDto {
 		PayRate:       w.PayRate,
 		LessorId:      w.LessorId.String(),
 		PaymentMethod: string(w.PaymentMethod),
+		Timezone:      w.Timezone,
 		Image:         w.Image,
 	}
 }
+
+// WorkingHoursDto is one weekly block, start and end are HH:MM in the worker's timezone
+// with 24:00 allowed as an end
+type WorkingHoursDto struct {
+	Weekday int    `json:"weekday"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+func (w WorkingHoursDto) Minutes() (int, int, error) {
+	start, err := clockMinutes(w.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := clockMinutes(w.End)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+func NewWorkingHoursDto(h model.WorkingHours) WorkingHoursDto {
+	return WorkingHoursDto{
+		Weekday: int(h.Weekday),
+		Start:   fmt.Sprintf("%02d:%02d", h.StartMinute/60, h.StartMinute%60),
+		End:     fmt.Sprintf("%02d:%02d", h.EndMinute/60, h.EndMinute%60),
+	}
+}
+
+type WorkingHoursRequest struct {
+	Timezone string            `json:"timezone"`
+	Hours    []WorkingHoursDto `json:"hours"`
+}
+
+func (w WorkingHoursRequest) Validate() error {
+	if _, err := time.LoadLocation(w.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %v", w.Timezone)
+	}
+
+	for _, h := range w.Hours {
+		if h.Weekday < int(time.Sunday) || h.Weekday > int(time.Saturday) {
+			return fmt.Errorf("invalid weekday %v, expected 0 (sunday) to 6 (saturday)", h.Weekday)
+		}
+
+		start, end, err := h.Minutes()
+		if err != nil {
+			return err
+		}
+
+		if end <= start {
+			return fmt.Errorf("hours on weekday %v must end after they start", h.Weekday)
+		}
+	}
+
+	return nil
+}
+
+type TimeOffRequest struct {
+	StartAt time.Time `json:"startAt"`
+	EndAt   time.Time `json:"endAt"`
+	Reason  string    `json:"reason"`
+}
+
+func (t TimeOffRequest) Validate() error {
+	if t.StartAt.IsZero() || t.EndAt.IsZero() {
+		return errors.New("time off needs a start and end")
+	}
+
+	if !t.EndAt.After(t.StartAt) {
+		return errors.New("time off must end after it starts")
+	}
+
+	return nil
+}
+
+type TimeOffResponse struct {
+	Oid      string    `json:"oid"`
+	WorkerId string    `json:"workerId"`
+	StartAt  time.Time `json:"startAt"`
+	EndAt    time.Time `json:"endAt"`
+	Reason   string    `json:"reason"`
+}
+
+func NewTimeOffResponse(t model.TimeOff) TimeOffResponse {
+	return TimeOffResponse{
+		Oid:      t.Oid.String(),
+		WorkerId: t.WorkerId.String(),
+		StartAt:  t.StartAt,
+		EndAt:    t.EndAt,
+		Reason:   t.Reason,
+	}
+}
+
+type AvailabilityResponse struct {
+	WorkerId string              `json:"workerId"`
+	Timezone string              `json:"timezone"`
+	From     time.Time           `json:"from"`
+	To       time.Time           `json:"to"`
+	Hours    []WorkingHoursDto   `json:"hours"`
+	TimeOff  []TimeOffResponse   `json:"timeOff"`
+	Booked   []availability.Slot `json:"booked"`
+	Free     []availability.Slot `json:"free"`
+}
+
+func clockMinutes(clock string) (int, error) {
+	if clock == "24:00" {
+		return 24 * 60, nil
+	}
+
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
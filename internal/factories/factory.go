@@ -5,20 +5,77 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
+	"github.com/Z3DRP/lessor-service/config"
 	"github.com/Z3DRP/lessor-service/internal/api"
 	"github.com/Z3DRP/lessor-service/internal/crane"
 	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/mail"
+	"github.com/Z3DRP/lessor-service/internal/notify"
+	"github.com/Z3DRP/lessor-service/internal/payments"
 	"github.com/Z3DRP/lessor-service/internal/services"
 	"github.com/Z3DRP/lessor-service/internal/services/alssr"
+	"github.com/Z3DRP/lessor-service/internal/services/hooks"
+	"github.com/Z3DRP/lessor-service/internal/services/lease"
+	"github.com/Z3DRP/lessor-service/internal/services/ledger"
+	"github.com/Z3DRP/lessor-service/internal/services/maintenance"
 	"github.com/Z3DRP/lessor-service/internal/services/notification"
+	"github.com/Z3DRP/lessor-service/internal/services/payroll"
 	"github.com/Z3DRP/lessor-service/internal/services/property"
 	rentalproperty "github.com/Z3DRP/lessor-service/internal/services/rentalProperty"
+	"github.com/Z3DRP/lessor-service/internal/services/schedule"
 	"github.com/Z3DRP/lessor-service/internal/services/task"
+	"github.com/Z3DRP/lessor-service/internal/services/tenant"
+	"github.com/Z3DRP/lessor-service/internal/services/texts"
+	"github.com/Z3DRP/lessor-service/internal/services/timesheet"
 	"github.com/Z3DRP/lessor-service/internal/services/usr"
 	"github.com/Z3DRP/lessor-service/internal/services/worker"
+	"github.com/Z3DRP/lessor-service/internal/sms"
+	"github.com/Z3DRP/lessor-service/internal/stream"
+	"github.com/Z3DRP/lessor-service/internal/webhook"
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/bun/driver/pgdriver"
 )
 
+var (
+	outbox     *notify.Outbox
+	outboxOnce sync.Once
+	hub        *stream.Hub
+	hubOnce    sync.Once
+	// smsProvider is shared so the fake sees the callbacks for the texts it sent
+	smsProvider = sync.OnceValue(sms.NewProvider)
+)
+
+// Outbox is shared by every service that sends notifications, email is built from
+// ZEmailSettings. It is started the first time it is asked for
+func Outbox(store dac.Persister, logger *crane.Zlogrus) *notify.Outbox {
+	outboxOnce.Do(func() {
+		cfg, err := config.ReadEmailConfig()
+		if err != nil {
+			logger.LogFields(logrus.Fields{"msg": "failed to read email config", "err": err})
+			cfg = &config.ZEmailConfig{}
+		}
+
+		users := dac.InitUsrRepo(store)
+		texts := dac.InitSmsRepo(store)
+		outbox = notify.NewOutbox(mail.NewMailer(*cfg), smsProvider(), &users, &texts, logger)
+		outbox.Start()
+	})
+	return outbox
+}
+
+// Stream is the notification stream hub every replica runs, it listens on its own
+// database connection and is started the first time it is asked for
+func Stream(store dac.Persister, logger *crane.Zlogrus) *stream.Hub {
+	hubOnce.Do(func() {
+		repo := dac.InitStreamRepo(store)
+		hub = stream.NewHub(&repo, pgdriver.NewListener(store.GetBunDB()), logger)
+		hub.Start(context.Background())
+	})
+	return hub
+}
+
 func ServiceFactory(serviceName string, store dac.Persister, logger *crane.Zlogrus) (services.Service, error) {
 	switch strings.ToLower(serviceName) {
 	case "alessor":
@@ -26,7 +83,9 @@ func ServiceFactory(serviceName string, store dac.Persister, logger *crane.Zlogr
 		return alssr.NewAlsrService(repo, logger), nil
 	case "user":
 		repo := dac.InitUsrRepo(store)
-		return usr.NewUserService(repo, logger), nil
+		tokens := dac.InitRefreshTokenRepo(store)
+		tenants := dac.InitTenantRepo(store)
+		return usr.NewUserService(repo, &tokens, tenants, logger), nil
 	case "property":
 		// needs to update to use actor inbox to send msg and init actor
 		repo := dac.InitPrptyRepo(store)
@@ -56,16 +115,56 @@ func ServiceFactory(serviceName string, store dac.Persister, logger *crane.Zlogr
 		// 	return nil, err
 		// }
 
-		return task.NewTaskService(repo, logger), nil
+		return task.NewTaskService(repo, dac.InitAvailabilityRepo(store), dac.InitNotificationSettingsRepo(store), Outbox(store, logger), logger), nil
 	case "rental property":
 		repo := dac.InitRentalPrptyRepo(store)
 		return rentalproperty.NewRentalPropertyService(repo, logger), nil
 	case "worker":
 		repo := dac.InitWorkerRepo(store)
-		return worker.NewWorkerService(repo, logger), nil
+		return worker.NewWorkerService(repo, dac.InitAvailabilityRepo(store), logger), nil
 	case "notification":
 		repo := dac.InitNotificationRepo(store)
-		return notification.NewNotificationService(repo, logger), nil
+		return notification.NewNotificationService(repo, dac.InitNotificationSettingsRepo(store), Outbox(store, logger), Stream(store, logger), logger), nil
+	case "schedule":
+		repo := dac.InitScheduleRepo(store)
+		tasks := dac.InitTskRepo(store)
+		return schedule.NewScheduleService(repo, tasks, dac.InitAvailabilityRepo(store), logger), nil
+	case "ledger":
+		repo := dac.InitLedgerRepo(store)
+		return ledger.NewLedgerService(repo, payments.NewProcessor(), logger), nil
+	case "tenant":
+		repo := dac.InitTenantRepo(store)
+		return tenant.NewTenantService(repo, logger), nil
+	case "maintenance":
+		repo := dac.InitMaintenanceRepo(store)
+		tenants := dac.InitTenantRepo(store)
+		s3Dir, err := ServiceS3Dir(serviceName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		actor, err := api.NewS3Actor(context.TODO(), s3Dir)
+
+		if err != nil {
+			return nil, err
+		}
+		return maintenance.NewMaintenanceService(repo, tenants, dac.InitAvailabilityRepo(store), actor, logger), nil
+	case "timesheet":
+		repo := dac.InitTimeRepo(store)
+		return timesheet.NewTimesheetService(repo, logger), nil
+	case "payroll":
+		repo := dac.InitPayrollRepo(store)
+		return payroll.NewPayrollService(repo, logger), nil
+	case "sms":
+		repo := dac.InitSmsRepo(store)
+		return texts.NewSmsService(repo, smsProvider(), logger), nil
+	case "webhook":
+		repo := dac.InitWebhookRepo(store)
+		return hooks.NewWebhookService(repo, webhook.NewSender(), logger), nil
+	case "lease":
+		repo := dac.InitLeaseRepo(store)
+		return lease.NewLeaseService(repo, dac.InitNotificationRepo(store), dac.InitNotificationSettingsRepo(store), Outbox(store, logger), logger), nil
 	default:
 		return nil, errors.New("factory does not support service")
 	}
@@ -115,6 +214,60 @@ func HandlerFactory(handlerName string, service services.Service) (services.Hand
 			return nil, ErrWrongServiceInject{ServiceName: notificationService.ServiceName(), HandlerName: "notification"}
 		}
 		return notification.NewHandler(notificationService), nil
+	case "schedule":
+		scheduleService, ok := service.(schedule.ScheduleService)
+		if !ok {
+			return nil, ErrWrongServiceInject{ServiceName: service.ServiceName(), HandlerName: "schedule"}
+		}
+		return schedule.NewHandler(scheduleService), nil
+	case "ledger":
+		ledgerService, ok := service.(ledger.LedgerService)
+		if !ok {
+			return nil, ErrWrongServiceInject{ServiceName: service.ServiceName(), HandlerName: "ledger"}
+		}
+		return ledger.NewHandler(ledgerService), nil
+	case "tenant":
+		tenantService, ok := service.(tenant.TenantService)
+		if !ok {
+			return nil, ErrWrongServiceInject{ServiceName: service.ServiceName(), HandlerName: "tenant"}
+		}
+		return tenant.NewHandler(tenantService), nil
+	case "maintenance":
+		maintenanceService, ok := service.(maintenance.MaintenanceService)
+		if !ok {
+			return nil, ErrWrongServiceInject{ServiceName: service.ServiceName(), HandlerName: "maintenance"}
+		}
+		return maintenance.NewHandler(maintenanceService), nil
+	case "timesheet":
+		timesheetService, ok := service.(timesheet.TimesheetService)
+		if !ok {
+			return nil, ErrWrongServiceInject{ServiceName: service.ServiceName(), HandlerName: "timesheet"}
+		}
+		return timesheet.NewHandler(timesheetService), nil
+	case "payroll":
+		payrollService, ok := service.(payroll.PayrollService)
+		if !ok {
+			return nil, ErrWrongServiceInject{ServiceName: service.ServiceName(), HandlerName: "payroll"}
+		}
+		return payroll.NewHandler(payrollService), nil
+	case "sms":
+		smsService, ok := service.(texts.SmsService)
+		if !ok {
+			return nil, ErrWrongServiceInject{ServiceName: service.ServiceName(), HandlerName: "sms"}
+		}
+		return texts.NewHandler(smsService), nil
+	case "webhook":
+		webhookService, ok := service.(hooks.WebhookService)
+		if !ok {
+			return nil, ErrWrongServiceInject{ServiceName: service.ServiceName(), HandlerName: "webhook"}
+		}
+		return hooks.NewHandler(webhookService), nil
+	case "lease":
+		leaseService, ok := service.(lease.LeaseService)
+		if !ok {
+			return nil, ErrWrongServiceInject{ServiceName: service.ServiceName(), HandlerName: "lease"}
+		}
+		return lease.NewHandler(leaseService), nil
 	default:
 		return nil, fmt.Errorf("handler not found for %v", handlerName)
 	}
@@ -126,7 +279,7 @@ func ServiceS3Dir(service string) (string, error) {
 		return "USERS_DIR", nil
 	case "property":
 		return "PROPERTIES_DIR", nil
-	case "task":
+	case "task", "maintenance":
 		return "TASK_DIR", nil
 	default:
 		return "", fmt.Errorf("service %v does not have a s3 location", service)
@@ -233,6 +233,7 @@ func GenFilterWithNoSearch(r *http.Request) (Filter, error) {
 type Creds struct {
 	Email    string
 	Password string
+	DeviceId string
 }
 
 func (c Creds) Validate() error {
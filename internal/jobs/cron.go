@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule works out when a job runs next after the given time
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+type ErrInvalidSchedule struct {
+	Spec string
+	Err  error
+}
+
+func (e ErrInvalidSchedule) Error() string {
+	return fmt.Sprintf("invalid job schedule %q: %v", e.Spec, e.Err)
+}
+
+func (e ErrInvalidSchedule) Unwrap() error {
+	return e.Err
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type bounds struct {
+	min, max int
+}
+
+// minute, hour, day of month, month, day of week where sunday can be 0 or 7
+var fieldBounds = [5]bounds{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// searchLimit stops Next from looking forever for a date that never comes like feb 30
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+// ParseSchedule accepts a five field cron expression, one of the @ descriptors
+// or @every followed by a duration
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d < time.Second {
+			return nil, ErrInvalidSchedule{Spec: spec, Err: fmt.Errorf("every needs a duration of at least a second")}
+		}
+		return every(d), nil
+	}
+
+	expr := spec
+	if d, ok := descriptors[spec]; ok {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, ErrInvalidSchedule{Spec: spec, Err: fmt.Errorf("expected 5 fields got %v", len(fields))}
+	}
+
+	var c cron
+	for i, field := range fields {
+		bits, err := parseField(field, fieldBounds[i])
+		if err != nil {
+			return nil, ErrInvalidSchedule{Spec: spec, Err: err}
+		}
+		c.fields[i] = bits
+	}
+
+	if has(c.fields[4], 7) {
+		c.fields[4] |= 1
+	}
+
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return c, nil
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+type cron struct {
+	fields [5]uint64
+	domAny bool
+	dowAny bool
+}
+
+// Next walks forward skipping whole months, days and hours that can't match
+func (c cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(searchLimit)
+
+	for t.Before(limit) {
+		if !has(c.fields[3], int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !has(c.fields[1], t.Hour()) {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+
+		if !has(c.fields[0], t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// like cron, when both day fields are restricted either one matching is enough
+func (c cron) dayMatches(t time.Time) bool {
+	dom := has(c.fields[2], t.Day())
+	dow := has(c.fields[4], int(t.Weekday()))
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(bits uint64, n int) bool {
+	return bits&(1<<uint(n)) != 0
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := b.min, b.max
+		if rng != "*" {
+			start, end, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseValue(start, b); err != nil {
+				return 0, err
+			}
+
+			hi = lo
+			if isRange {
+				if hi, err = parseValue(end, b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = b.max
+			}
+
+			if hi < lo {
+				return 0, fmt.Errorf("range %q runs backwards", rng)
+			}
+		}
+
+		for n := lo; n <= hi; n += step {
+			bits |= 1 << uint(n)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseValue(value string, b bounds) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < b.min || n > b.max {
+		return 0, fmt.Errorf("%q is out of range %v-%v", value, b.min, b.max)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	for _, spec := range []string{"*/15 * * * *", "0 9 * * 1-5", "30 2 1,15 * *", "@hourly", "@every 90s", "0 0 * * 7"} {
+		if _, err := ParseSchedule(spec); err != nil {
+			t.Errorf("expected %v to parse, got %v", spec, err)
+		}
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "0 0 32 * *", "5-1 * * * *", "*/0 * * * *", "@every 1ms", "@often"} {
+		var invalid ErrInvalidSchedule
+		if _, err := ParseSchedule(spec); !errors.As(err, &invalid) {
+			t.Errorf("expected %v to be rejected, got %v", spec, err)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	from := time.Date(2026, time.October, 17, 10, 7, 30, 0, time.UTC)
+
+	for _, test := range []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, time.October, 17, 10, 15, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, time.October, 17, 11, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2026, time.October, 23, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	} {
+		t.Run(test.spec, func(t *testing.T) {
+			sched, err := ParseSchedule(test.spec)
+			if err != nil {
+				t.Fatalf("failed to parse %v", err)
+			}
+
+			if got := sched.Next(from); !got.Equal(test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/config"
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPollInterval = 15 * time.Second
+	defaultLease        = 5 * time.Minute
+	defaultMaxAttempts  = 5
+	baseBackoff         = 30 * time.Second
+	maxBackoff          = 30 * time.Minute
+)
+
+// Func is the work a job does, it runs with admin claims so repositories aren't
+// limited to a single lessor
+type Func func(ctx context.Context) error
+
+type job struct {
+	spec     string
+	schedule Schedule
+	run      Func
+}
+
+// Runner polls the job table and runs whichever registered job is due. Each run is
+// leased so when several replicas share the table only one of them runs a job at a time
+type Runner struct {
+	repo        dac.JobRepo
+	jobs        map[string]job
+	owner       string
+	poll        time.Duration
+	lease       time.Duration
+	maxAttempts int
+	logger      *crane.Zlogrus
+
+	stopPolling context.CancelFunc
+	abortRuns   context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+func NewRunner(repo dac.JobRepo, cfg config.JobsConfig, logr *crane.Zlogrus) *Runner {
+	host, _ := os.Hostname()
+	r := &Runner{
+		repo:        repo,
+		jobs:        make(map[string]job),
+		owner:       fmt.Sprintf("%v-%v-%v", host, os.Getpid(), uuid.NewString()[:8]),
+		poll:        time.Duration(cfg.PollInterval) * time.Second,
+		lease:       time.Duration(cfg.LeaseSeconds) * time.Second,
+		maxAttempts: cfg.MaxAttempts,
+		logger:      logr,
+	}
+
+	if r.poll <= 0 {
+		r.poll = defaultPollInterval
+	}
+
+	if r.lease <= 0 {
+		r.lease = defaultLease
+	}
+
+	if r.maxAttempts <= 0 {
+		r.maxAttempts = defaultMaxAttempts
+	}
+
+	return r
+}
+
+// Register adds a job, it has to be called before Start
+func (r *Runner) Register(name string, spec string, run Func) error {
+	sched, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := r.jobs[name]; exists {
+		return fmt.Errorf("job %v is already registered", name)
+	}
+
+	r.jobs[name] = job{spec: spec, schedule: sched, run: run}
+	return nil
+}
+
+// Start stores the registered jobs and begins polling for due ones
+func (r *Runner) Start(ctx context.Context) error {
+	now := time.Now().UTC()
+	for name, j := range r.jobs {
+		if err := r.repo.Register(ctx, &model.Job{Name: name, Spec: j.spec, NextRunAt: j.schedule.Next(now)}); err != nil {
+			return err
+		}
+	}
+
+	var pollCtx, runCtx context.Context
+	pollCtx, r.stopPolling = context.WithCancel(ctx)
+	runCtx, r.abortRuns = context.WithCancel(auth.WithSystem(context.WithoutCancel(ctx)))
+
+	r.wg.Add(1)
+	go r.loop(pollCtx, runCtx)
+	return nil
+}
+
+// Stop stops polling and waits for a running job to finish, if the context ends first
+// the job's context is cancelled and its lease is left to expire
+func (r *Runner) Stop(ctx context.Context) error {
+	if r.stopPolling == nil {
+		return nil
+	}
+
+	r.stopPolling()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.abortRuns()
+		return nil
+	case <-ctx.Done():
+		r.abortRuns()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) loop(pollCtx, runCtx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.poll)
+	defer ticker.Stop()
+
+	names := make([]string, 0, len(r.jobs))
+	for name := range r.jobs {
+		names = append(names, name)
+	}
+
+	for {
+		// keep taking jobs until nothing is due so a backlog drains in one poll
+		for pollCtx.Err() == nil {
+			leased, err := r.repo.Lease(runCtx, names, r.owner, time.Now().UTC(), r.lease)
+			if err != nil {
+				var noResults dac.ErrNoResults
+				if !errors.As(err, &noResults) {
+					r.logger.LogFields(logrus.Fields{"msg": "failed to lease job", "err": err})
+				}
+				break
+			}
+
+			r.run(runCtx, leased)
+		}
+
+		select {
+		case <-pollCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) run(ctx context.Context, leased model.Job) {
+	j := r.jobs[leased.Name]
+	runCtx, cancel := context.WithTimeout(ctx, r.lease)
+	err := r.safeRun(runCtx, j.run)
+	cancel()
+
+	now := time.Now().UTC()
+	leased.LastRunAt = now
+	if err == nil {
+		leased.Attempts = 0
+		leased.LastError = ""
+		leased.NextRunAt = j.schedule.Next(now)
+	} else {
+		leased.Attempts++
+		leased.LastError = err.Error()
+		leased.NextRunAt = now.Add(Backoff(leased.Attempts))
+
+		fields := logrus.Fields{"msg": "job failed", "job": leased.Name, "attempt": leased.Attempts, "err": err}
+		if leased.Attempts >= r.maxAttempts {
+			// give up on this run and wait for the next scheduled one
+			fields["msg"] = "job failed too many times, skipping to next scheduled run"
+			leased.Attempts = 0
+			leased.NextRunAt = j.schedule.Next(now)
+		}
+		r.logger.LogFields(fields)
+	}
+
+	// the outcome is written even when stopping so the lease doesn't have to run out
+	if err = r.repo.Release(context.WithoutCancel(ctx), leased, r.owner); err != nil {
+		r.logger.LogFields(logrus.Fields{"msg": "failed to release job", "job": leased.Name, "err": err})
+	}
+}
+
+// safeRun keeps a panicking job from taking the runner down with it
+func (r *Runner) safeRun(ctx context.Context, run Func) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job panicked: %v", rec)
+		}
+	}()
+
+	return run(ctx)
+}
+
+// Backoff doubles the wait after each failed attempt up to maxBackoff
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		return baseBackoff
+	}
+
+	wait := baseBackoff
+	for i := 1; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+
+	return min(wait, maxBackoff)
+}
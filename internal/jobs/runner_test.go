@@ -0,0 +1,20 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		7:  30 * time.Minute,
+		20: 30 * time.Minute,
+	} {
+		if got := Backoff(attempt); got != want {
+			t.Errorf("attempt %v expected %v, got %v", attempt, want, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const localSender = "lessor@localhost"
+
+// File writes every message to its own .eml file so it can be opened in a mail client
+type File struct {
+	from string
+	dir  string
+}
+
+func NewFile(from string, dir string) File {
+	if from == "" {
+		from = localSender
+	}
+
+	return File{from: from, dir: dir}
+}
+
+func (f File) Name() string {
+	return "file"
+}
+
+func (f File) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	body, err := buildMessage(f.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(f.dir, 0o755); err != nil {
+		return ErrSendFailed{Mailer: f.Name(), Err: err}
+	}
+
+	name := fmt.Sprintf("%v-%v.eml", now.UTC().Format("20060102T150405"), uuid.NewString()[:8])
+	if err = os.WriteFile(filepath.Join(f.dir, name), body, 0o644); err != nil {
+		return ErrSendFailed{Mailer: f.Name(), Err: err}
+	}
+
+	return nil
+}
+
+// Memory keeps what it is sent, tests read it back with Sent
+type Memory struct {
+	mu   *sync.Mutex
+	sent *[]Message
+}
+
+func NewMemory() Memory {
+	return Memory{
+		mu:   &sync.Mutex{},
+		sent: &[]Message{},
+	}
+}
+
+func (m Memory) Name() string {
+	return "memory"
+}
+
+func (m Memory) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipients
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	*m.sent = append(*m.sent, msg)
+	return nil
+}
+
+func (m Memory) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), *m.sent...)
+}
@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+)
+
+func TestBuildMessage(t *testing.T) {
+	msg := Message{To: []string{"ana@lessor.test"}, Subject: "Réparation done", Text: "plain body", Html: "<p>html body</p>"}
+	body, err := buildMessage("ops@lessor.test", msg, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if subject != msg.Subject {
+		t.Errorf("expected subject %q, got %q", msg.Subject, subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %v %v", mediaType, err)
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain", msg.Text},
+		{"text/html", msg.Html},
+	} {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, _ := io.ReadAll(part)
+		if !strings.HasPrefix(part.Header.Get("Content-Type"), want.contentType) || string(content) != want.content {
+			t.Errorf("expected %v part %q, got %v %q", want.contentType, want.content, part.Header.Get("Content-Type"), content)
+		}
+	}
+}
+
+func TestNotificationMessage(t *testing.T) {
+	msg, err := NotificationMessage(model.User{FirstName: "Ana", Email: "ana@lessor.test"},
+		model.Notification{Title: "Task finished", Message: "<b>Gutters</b> cleaned", Category: model.TaskAlert})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(msg.Text, "<b>Gutters</b> cleaned") {
+		t.Errorf("expected the text version to keep the message as is, got %q", msg.Text)
+	}
+
+	if strings.Contains(msg.Html, "<b>Gutters</b>") || !strings.Contains(msg.Html, "&lt;b&gt;Gutters") {
+		t.Errorf("expected the html version to escape the message, got %q", msg.Html)
+	}
+
+	if _, err = NotificationMessage(model.User{}, model.Notification{}); !errors.Is(err, ErrNoRecipients) {
+		t.Errorf("expected a user without an email to fail, got %v", err)
+	}
+}
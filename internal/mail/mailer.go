@@ -0,0 +1,86 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/textproto"
+	"os"
+	"strings"
+
+	"github.com/Z3DRP/lessor-service/config"
+)
+
+const defaultMailDir = "./mail"
+
+var (
+	ErrNotConfigured = errors.New("mailer is not configured")
+	ErrNoRecipients  = errors.New("message has no recipients")
+)
+
+// ErrSendFailed is returned when the mail server refuses or can't take a message
+type ErrSendFailed struct {
+	Mailer string
+	Err    error
+}
+
+func (e ErrSendFailed) Error() string {
+	return fmt.Sprintf("%v failed to send message: %v", e.Mailer, e.Err)
+}
+
+func (e ErrSendFailed) Unwrap() error {
+	return e.Err
+}
+
+// Message is a single email, Text is the plain version of Html for clients that won't
+// show html
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	Html    string
+}
+
+// Mailer delivers a message straight away, use notify.Outbox to send without waiting on it
+type Mailer interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer picks the mailer from MAILER, smtp is the default. file writes each message to
+// MAIL_DIR and memory keeps them, both are only meant for local development and tests
+func NewMailer(cfg config.ZEmailConfig) Mailer {
+	switch strings.ToLower(os.Getenv("MAILER")) {
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = defaultMailDir
+		}
+		log.Printf("WARNING using the file mailer emails are written to %v and not sent", dir)
+		return NewFile(cfg.SenderAddress, dir)
+	case "memory":
+		log.Printf("WARNING using the memory mailer emails are not sent")
+		return NewMemory()
+	default:
+		if cfg.SmtpServer == "" || cfg.SenderAddress == "" {
+			log.Printf("WARNING smtp server or sender address is not set emails will fail")
+		}
+		return NewSMTP(cfg)
+	}
+}
+
+// Retryable is false for failures that will happen again however many times the message
+// is sent, smtp 5xx replies are permanent
+func Retryable(err error) bool {
+	if errors.Is(err, ErrNotConfigured) || errors.Is(err, ErrNoRecipients) {
+		return false
+	}
+
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code < 500
+	}
+
+	return true
+}
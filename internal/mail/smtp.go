@@ -0,0 +1,205 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/config"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSmtpPort = 587
+	implicitTlsPort = 465
+	smtpTimeout     = 30 * time.Second
+)
+
+// SMTP sends through the server in ZEmailSettings. Port 465 is implicit tls, any other
+// port is upgraded with STARTTLS when the server offers it
+type SMTP struct {
+	cfg config.ZEmailConfig
+}
+
+func NewSMTP(cfg config.ZEmailConfig) SMTP {
+	if cfg.SmtpPort == 0 {
+		cfg.SmtpPort = defaultSmtpPort
+	}
+
+	return SMTP{cfg: cfg}
+}
+
+func (s SMTP) Name() string {
+	return "smtp"
+}
+
+func (s SMTP) Send(ctx context.Context, msg Message) error {
+	if s.cfg.SmtpServer == "" || s.cfg.SenderAddress == "" {
+		return ErrNotConfigured
+	}
+
+	if len(msg.To) == 0 {
+		return ErrNoRecipients
+	}
+
+	body, err := buildMessage(s.cfg.SenderAddress, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err = s.deliver(ctx, msg.To, body); err != nil {
+		return ErrSendFailed{Mailer: s.Name(), Err: err}
+	}
+
+	return nil
+}
+
+func (s SMTP) deliver(ctx context.Context, to []string, body []byte) error {
+	host := s.cfg.SmtpServer
+	addr := net.JoinHostPort(host, strconv.Itoa(s.cfg.SmtpPort))
+	tlsConfig := &tls.Config{ServerName: host}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if s.cfg.SmtpPort == implicitTlsPort {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if s.cfg.SmtpPort != implicitTlsPort {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.cfg.SenderPwd != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err = client.Auth(smtp.PlainAuth("", s.cfg.SenderAddress, s.cfg.SenderPwd, host)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = client.Mail(s.cfg.SenderAddress); err != nil {
+		return err
+	}
+
+	for _, rcpt := range to {
+		if err = client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(body); err != nil {
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// buildMessage writes the message as multipart/alternative with the plain text first so
+// clients fall back to it
+func buildMessage(from string, msg Message, now time.Time) ([]byte, error) {
+	if len(msg.To) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	var buf bytes.Buffer
+	parts := multipart.NewWriter(&buf)
+
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%v: %v\r\n", key, value)
+	}
+
+	header("From", from)
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%v@%v>", uuid.NewString(), domainOf(from)))
+	header("MIME-Version", "1.0")
+	header("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", parts.Boundary()))
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain", msg.Text},
+		{"text/html", msg.Html},
+	} {
+		if part.content == "" {
+			continue
+		}
+
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err = qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func domainOf(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return strings.Trim(address[at+1:], ">")
+	}
+	return "localhost"
+}
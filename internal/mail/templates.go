@@ -0,0 +1,85 @@
+package mail
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+)
+
+const notificationText = `Hi {{.Name}},
+
+{{.Title}}
+
+{{.Message}}
+
+Sent {{.Sent}}, sign in to Lessor to see more.
+`
+
+const notificationHtml = `<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #222;">
+	<p>Hi {{.Name}},</p>
+	<h2 style="margin-bottom: 4px;">{{.Title}}</h2>
+	<p style="color: #666; font-size: 12px; margin-top: 0;">{{.Category}} &middot; {{.Sent}}</p>
+	<p style="white-space: pre-line;">{{.Message}}</p>
+	<p style="color: #666; font-size: 12px;">Sign in to Lessor to see more.</p>
+</body>
+</html>
+`
+
+var (
+	notificationTextTmpl = texttemplate.Must(texttemplate.New("notification").Parse(notificationText))
+	notificationHtmlTmpl = htmltemplate.Must(htmltemplate.New("notification").Parse(notificationHtml))
+)
+
+type notificationData struct {
+	Name     string
+	Title    string
+	Message  string
+	Category string
+	Sent     string
+}
+
+// NotificationMessage renders the notification as an email to the user, the html version
+// escapes whatever the notification says
+func NotificationMessage(to model.User, n model.Notification) (Message, error) {
+	if to.Email == "" {
+		return Message{}, ErrNoRecipients
+	}
+
+	sent := n.CreatedAt
+	if sent.IsZero() {
+		sent = time.Now()
+	}
+
+	data := notificationData{
+		Name:     to.FirstName,
+		Title:    n.Title,
+		Message:  n.Message,
+		Category: string(n.Category),
+		Sent:     sent.Format("Jan 2, 2006 3:04 PM MST"),
+	}
+
+	if data.Name == "" {
+		data.Name = "there"
+	}
+
+	var text, html bytes.Buffer
+	if err := notificationTextTmpl.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+
+	if err := notificationHtmlTmpl.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      []string{to.Email},
+		Subject: n.Title,
+		Text:    text.String(),
+		Html:    html.String(),
+	}, nil
+}
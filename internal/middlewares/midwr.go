@@ -23,3 +23,8 @@ func (w *WrappedWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 	w.StatusCode = status
 }
+
+// Unwrap lets http.ResponseController reach the flusher and hijacker underneath
+func (w *WrappedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -2,19 +2,36 @@ package model
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 )
 
+type AssignmentStatus string
+
+const (
+	AssignmentPending  AssignmentStatus = "pending"
+	AssignmentAccepted AssignmentStatus = "accepted"
+	AssignmentDeclined AssignmentStatus = "declined"
+)
+
+// AssignedTask puts a worker on a task's crew, the lead's id is mirrored onto Task.WorkerId
+// so clients that only know about one worker per task keep working
 type AssignedTask struct {
 	bun.BaseModel `bun:"table:assigned_tasks,alias:at"`
 
-	Id       int64     `bun:"column:id,pk,autoincrement"`
-	TaskId   uuid.UUID `bun:"type:uuid,notnull"`
-	Task     *Task     `bun:"rel:belongs-to,join:task_id=tid"`
-	WorkerId uuid.UUID `bun:"type:uuid,notnull"`
-	Worker   *Worker   `bun:"rel:belongs-to,join:worker_id=uid"`
+	Id             int64            `bun:"column:id,pk,autoincrement" json:"-"`
+	TaskId         uuid.UUID        `bun:"type:uuid,notnull,unique:task_worker" json:"taskId"`
+	Task           *Task            `bun:"rel:belongs-to,join:task_id=tid" json:"task"`
+	WorkerId       uuid.UUID        `bun:"type:uuid,notnull,unique:task_worker" json:"workerId"`
+	Worker         *Worker          `bun:"rel:belongs-to,join:worker_id=uid" json:"worker"`
+	LessorId       uuid.UUID        `bun:"type:uuid,notnull" json:"lessorId"`
+	IsLead         bool             `bun:"type:boolean,notnull,default:false" json:"isLead"`
+	Status         AssignmentStatus `bun:"type:varchar(20),notnull,default:'pending'" json:"status"`
+	DeclinedReason string           `bun:"type:varchar(255),nullzero" json:"declinedReason"`
+	RespondedAt    time.Time        `bun:"type:timestamptz,nullzero" json:"respondedAt"`
+	AssignedAt     time.Time        `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"assignedAt"`
 }
 
 func (a AssignedTask) Info() string {
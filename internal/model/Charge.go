@@ -0,0 +1,48 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+)
+
+type ChargeKind string
+
+const (
+	RentCharge    ChargeKind = "rent"
+	LateFeeCharge ChargeKind = "late_fee"
+	OtherCharge   ChargeKind = "other"
+)
+
+// Charge is an amount a tenant owes on a rental. Rent is charged once per period and a
+// late fee once per overdue charge, the unique groups keep the generators from doubling up
+type Charge struct {
+	bun.BaseModel `bun:"table:rent_charges,alias:rc"`
+
+	Id          int64           `bun:"column:id,pk,autoincrement" json:"-"`
+	Cid         uuid.UUID       `bun:"type:uuid,notnull,unique" json:"cid"`
+	LessorId    uuid.UUID       `bun:"type:uuid,notnull" json:"lessorId"`
+	TenantId    uuid.UUID       `bun:"type:uuid,notnull,unique:charge_period" json:"tenantId"`
+	RentalId    uuid.UUID       `bun:"type:uuid,notnull,unique:charge_period" json:"rentalId"`
+	Rental      *RentalProperty `bun:"rel:belongs-to,join:rental_id=pid" json:"-"`
+	Kind        ChargeKind      `bun:"type:varchar(20),notnull,unique:charge_period" json:"kind"`
+	PeriodStart time.Time       `bun:"type:timestamptz,nullzero,unique:charge_period" json:"periodStart"`
+	LateFeeFor  uuid.UUID       `bun:"type:uuid,nullzero,unique" json:"lateFeeFor"`
+	Description string          `bun:"type:varchar(255)" json:"description"`
+	Amount      decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"amount"`
+	AmountPaid  decimal.Decimal `bun:"type:numeric(12,2),notnull,default:0" json:"amountPaid"`
+	DueDate     time.Time       `bun:"type:timestamptz,notnull" json:"dueDate"`
+	VoidedAt    time.Time       `bun:"type:timestamptz,nullzero" json:"voidedAt"`
+	CreatedAt   time.Time       `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (c Charge) Info() string {
+	return fmt.Sprintf("%#v\n", c)
+}
+
+func (c Charge) Outstanding() decimal.Decimal {
+	return c.Amount.Sub(c.AmountPaid)
+}
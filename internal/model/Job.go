@@ -0,0 +1,29 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Job is the shared state of a background job. A replica takes the job by setting a
+// lease, while LeasedUntil is in the future no other replica will run it
+type Job struct {
+	bun.BaseModel `bun:"table:jobs,alias:jb"`
+
+	Id          int64     `bun:"column:id,pk,autoincrement" json:"-"`
+	Name        string    `bun:"type:varchar(100),notnull,unique" json:"name"`
+	Spec        string    `bun:"type:varchar(100),notnull" json:"spec"`
+	NextRunAt   time.Time `bun:"type:timestamptz,notnull" json:"nextRunAt"`
+	LeasedBy    string    `bun:"type:varchar(150),nullzero" json:"leasedBy"`
+	LeasedUntil time.Time `bun:"type:timestamptz,nullzero" json:"leasedUntil"`
+	Attempts    int       `bun:"type:integer,notnull,default:0" json:"attempts"`
+	LastError   string    `bun:"type:text,nullzero" json:"lastError"`
+	LastRunAt   time.Time `bun:"type:timestamptz,nullzero" json:"lastRunAt"`
+	CreatedAt   time.Time `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (j Job) Info() string {
+	return fmt.Sprintf("%#v\n", j)
+}
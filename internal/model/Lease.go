@@ -0,0 +1,77 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+)
+
+type LeaseStatus string
+
+const (
+	LeaseDraft      LeaseStatus = "draft"
+	LeaseActive     LeaseStatus = "active"
+	LeaseExpired    LeaseStatus = "expired"
+	LeaseTerminated LeaseStatus = "terminated"
+)
+
+// Lease is one agreement to rent a unit. It runs from StartsAt up to but not including
+// EndsAt, a renewal is a new lease that points back at the one it renews
+type Lease struct {
+	bun.BaseModel `bun:"table:leases,alias:ls"`
+
+	Id                int64           `bun:"column:id,pk,autoincrement" json:"-"`
+	Lid               uuid.UUID       `bun:"type:uuid,notnull,unique" json:"lid"`
+	LessorId          uuid.UUID       `bun:"type:uuid,notnull" json:"lessorId"`
+	RentalId          uuid.UUID       `bun:"type:uuid,notnull" json:"rentalId"`
+	Rental            *RentalProperty `bun:"rel:belongs-to,join:rental_id=pid" json:"rental"`
+	TenantIds         []uuid.UUID     `bun:"type:uuid[],array,notnull" json:"tenantIds"`
+	StartsAt          time.Time       `bun:"type:timestamptz,notnull" json:"startsAt"`
+	EndsAt            time.Time       `bun:"type:timestamptz,notnull" json:"endsAt"`
+	RentAmount        decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"rentAmount"`
+	Deposit           decimal.Decimal `bun:"type:numeric(12,2),notnull,default:0" json:"deposit"`
+	Terms             string          `bun:"type:text" json:"terms"`
+	Status            LeaseStatus     `bun:"type:varchar(20),notnull,default:'draft'" json:"status"`
+	RenewalOf         uuid.UUID       `bun:"type:uuid,nullzero" json:"renewalOf"`
+	RemindedAt        time.Time       `bun:"type:timestamptz,nullzero" json:"remindedAt"`
+	ActivatedAt       time.Time       `bun:"type:timestamptz,nullzero" json:"activatedAt"`
+	TerminatedAt      time.Time       `bun:"type:timestamptz,nullzero" json:"terminatedAt"`
+	TerminationReason string          `bun:"type:varchar(255),nullzero" json:"terminationReason"`
+	CreatedAt         time.Time       `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt         time.Time       `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"updatedAt"`
+}
+
+func (l Lease) Info() string {
+	return fmt.Sprintf("%#v\n", l)
+}
+
+// Overlaps reports whether the two leases cover any of the same time, a lease ending
+// when the other starts doesn't overlap it
+func (l Lease) Overlaps(other Lease) bool {
+	return l.StartsAt.Before(other.EndsAt) && other.StartsAt.Before(l.EndsAt)
+}
+
+// Months is the lease term in whole months, a partial month counts as a month
+func (l Lease) Months() int {
+	months := (l.EndsAt.Year()-l.StartsAt.Year())*12 + int(l.EndsAt.Month()-l.StartsAt.Month())
+	if l.StartsAt.AddDate(0, months, 0).Before(l.EndsAt) {
+		months++
+	}
+	return months
+}
+
+// CanMoveTo reports whether the lease can go from its status to the next one, expired and
+// terminated leases are kept as history and can't change again
+func (l Lease) CanMoveTo(next LeaseStatus) bool {
+	switch l.Status {
+	case LeaseDraft:
+		return next == LeaseActive || next == LeaseTerminated
+	case LeaseActive:
+		return next == LeaseExpired || next == LeaseTerminated
+	default:
+		return false
+	}
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type MaintenanceStatus string
+
+const (
+	RequestSubmitted MaintenanceStatus = "submitted"
+	RequestConverted MaintenanceStatus = "converted"
+	RequestDeclined  MaintenanceStatus = "declined"
+	RequestWithdrawn MaintenanceStatus = "withdrawn"
+)
+
+// AccessWindow is a time the tenant would like work done in
+type AccessWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// MaintenanceRequest is a problem reported by a tenant, it waits in the lessor's queue
+// until it is converted into a task or declined
+type MaintenanceRequest struct {
+	bun.BaseModel `bun:"table:maintenance_requests,alias:mr"`
+
+	Id                int64             `bun:"column:id,pk,autoincrement" json:"-"`
+	Mid               uuid.UUID         `bun:"type:uuid,notnull,unique" json:"mid"`
+	TenantId          uuid.UUID         `bun:"type:uuid,notnull" json:"tenantId"`
+	Tenant            *User             `bun:"rel:belongs-to,join:tenant_id=uid" json:"tenant"`
+	LessorId          uuid.UUID         `bun:"type:uuid,notnull" json:"lessorId"`
+	PropertyId        uuid.UUID         `bun:"type:uuid,notnull" json:"propertyId"`
+	Property          *Property         `bun:"rel:belongs-to,join:property_id=pid" json:"property"`
+	Description       string            `bun:"type:text,notnull" json:"description"`
+	Urgency           PriorityLevel     `bun:"type:priority_level,notnull" json:"urgency"`
+	Photos            []string          `bun:"type:jsonb" json:"photos"`
+	AccessWindows     []AccessWindow    `bun:"type:jsonb" json:"accessWindows"`
+	PermissionToEnter bool              `bun:"type:boolean,notnull,default:false" json:"permissionToEnter"`
+	Status            MaintenanceStatus `bun:"type:varchar(20),notnull,default:'submitted'" json:"status"`
+	DeclinedReason    string            `bun:"type:varchar(255),nullzero" json:"declinedReason"`
+	TaskId            uuid.UUID         `bun:"type:uuid,nullzero,unique" json:"taskId"`
+	Task              *Task             `bun:"rel:belongs-to,join:task_id=tid" json:"task"`
+	ResolvedAt        time.Time         `bun:"type:timestamptz,nullzero" json:"resolvedAt"`
+	CreatedAt         time.Time         `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (m MaintenanceRequest) Info() string {
+	return fmt.Sprintf("%#v\n", m)
+}
@@ -34,9 +34,21 @@ type Notification struct {
 	PropertyId    uuid.UUID        `bun:"type:uuid,nullzero" json:"propertyId"`
 	Property      *Property        `bun:"rel:belongs-to,join:property_id=pid" json:"property"`
 	Category      NotificationType `bun:"type:notification_type,notnull,nullzero,default:general" json:"category"`
+	Priority      PriorityLevel    `bun:"type:priority_level,notnull,nullzero,default:'medium'" json:"priority"`
 	Viewed        bool             `bun:"type:boolean,notnull,nullzero,default:false" json:"viewed"`
-	CreatedAt     time.Time        `bun:"type:timestamptz,notnull,nullzero" json:"createdAt"`
-	VoidAt        time.Time        `bun:"type:timestamptz,notnull" json:"voidAt"`
+	// DeliveredAt is set once the notification has been sent outside the app or has nowhere
+	// else to go, it stays empty while the notification waits for a digest
+	DeliveredAt time.Time `bun:"type:timestamptz,nullzero" json:"deliveredAt"`
+	CreatedAt   time.Time `bun:"type:timestamptz,notnull,nullzero" json:"createdAt"`
+	VoidAt      time.Time `bun:"type:timestamptz,notnull" json:"voidAt"`
+}
+
+// Recipient is the user the notification is for, the lessor when it isn't for a particular user
+func (n Notification) Recipient() uuid.UUID {
+	if n.UserId != uuid.Nil {
+		return n.UserId
+	}
+	return n.LessorId
 }
 
 func (n Notification) Str() string {
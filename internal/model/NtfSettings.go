@@ -2,18 +2,149 @@ package model
 
 import (
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 )
 
+type NotificationChannel string
+
+const (
+	InAppChannel NotificationChannel = "in_app"
+	EmailChannel NotificationChannel = "email"
+	SmsChannel   NotificationChannel = "sms"
+)
+
+// NotificationChannels is every channel, it is what a user without settings gets
+var NotificationChannels = []NotificationChannel{InAppChannel, EmailChannel, SmsChannel}
+
+type DigestFrequency string
+
+const (
+	// NoDigest sends every notification as it happens
+	NoDigest     DigestFrequency = ""
+	HourlyDigest DigestFrequency = "hourly"
+	DailyDigest  DigestFrequency = "daily"
+)
+
+// digestSlack lets an hourly run that starts a little early still send
+const digestSlack = 10 * time.Minute
+
+const quietHoursLayout = "15:04"
+
+// QuietHours are HH:MM times in the user's timezone, an end before the start runs past midnight
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Contains reports whether the clock time of now falls inside the window, the end is exclusive
+func (q QuietHours) Contains(now time.Time) bool {
+	start, err := time.Parse(quietHoursLayout, q.Start)
+	if err != nil {
+		return false
+	}
+
+	end, err := time.Parse(quietHoursLayout, q.End)
+	if err != nil {
+		return false
+	}
+
+	from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	at := now.Hour()*60 + now.Minute()
+	if from <= to {
+		return at >= from && at < to
+	}
+	return at >= from || at < to
+}
+
+// CategoryPreference is what a user wants for one category of notification, nil channels
+// means every channel and an empty min priority lets everything through
+type CategoryPreference struct {
+	Channels    []NotificationChannel `json:"channels"`
+	MinPriority PriorityLevel         `json:"minPriority"`
+	QuietHours  *QuietHours           `json:"quietHours,omitempty"`
+}
+
+// NotificationPreferences are a user's notification settings, with a digest anything that
+// isn't immediate is saved in the app and sent outside of it as a summary, DigestHour is
+// the local hour a daily digest goes out
+type NotificationPreferences struct {
+	Timezone   string                                  `json:"timezone"`
+	Categories map[NotificationType]CategoryPreference `json:"categories"`
+	Digest     DigestFrequency                         `json:"digest"`
+	DigestHour int                                     `json:"digestHour"`
+}
+
+// Channels returns where the notification may go. Anything below the category's minimum
+// priority goes nowhere and during quiet hours only the in app notification is kept,
+// immediate notifications ignore quiet hours
+func (p NotificationPreferences) Channels(n Notification, now time.Time) []NotificationChannel {
+	pref, ok := p.Categories[n.Category]
+	if !ok {
+		return NotificationChannels
+	}
+
+	if pref.MinPriority != "" && n.Priority.Rank() < pref.MinPriority.Rank() {
+		return nil
+	}
+
+	channels := pref.Channels
+	if channels == nil {
+		channels = NotificationChannels
+	}
+
+	if pref.QuietHours != nil && n.Priority != Immediate && pref.QuietHours.Contains(now.In(p.location())) {
+		if slices.Contains(channels, InAppChannel) {
+			return []NotificationChannel{InAppChannel}
+		}
+		return nil
+	}
+
+	return channels
+}
+
+// Batches reports whether the notification waits for the next digest instead of being sent
+func (p NotificationPreferences) Batches(n Notification) bool {
+	return p.Digest != NoDigest && n.Priority != Immediate
+}
+
+// DigestDue reports whether a digest should go out given when the last one was sent
+func (p NotificationPreferences) DigestDue(last time.Time, now time.Time) bool {
+	switch p.Digest {
+	case HourlyDigest:
+		return now.Sub(last) >= time.Hour-digestSlack
+	case DailyDigest:
+		local := now.In(p.location())
+		due := time.Date(local.Year(), local.Month(), local.Day(), p.DigestHour, 0, 0, 0, local.Location())
+		if local.Before(due) {
+			due = due.AddDate(0, 0, -1)
+		}
+		return last.Before(due)
+	default:
+		return false
+	}
+}
+
+func (p NotificationPreferences) location() *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 type NotificationSettings struct {
 	bun.BaseModel `bun:"table:notification_settings,alias:ns"`
 
-	Id      int64                  `bun:"column:id,pk,autoincrement"`
-	Uid     uuid.UUID              `bun:"type:uuid,notnull,unique"`
-	User    *User                  `bun:"rel:belongs-to,join:uid=uid"`
-	Setting map[string]interface{} `bun:"type:jsonb,json_use_number"`
+	Id      int64                   `bun:"column:id,pk,autoincrement"`
+	Uid     uuid.UUID               `bun:"type:uuid,notnull,unique"`
+	User    *User                   `bun:"rel:belongs-to,join:uid=uid"`
+	Setting NotificationPreferences `bun:"type:jsonb"`
+	// LastDigestAt is when the user was last sent a digest
+	LastDigestAt time.Time `bun:"type:timestamptz,nullzero"`
 }
 
 func (n NotificationSettings) Info() string {
@@ -14,22 +14,48 @@ type PaymentStatus string
 const (
 	Rejected PaymentStatus = "rejected"
 	Accepted PaymentStatus = "accepted"
+	// PaymentPending and PaymentRefunded are only used by processor payments, cash and
+	// check are accepted or rejected when they are recorded
+	PaymentPending  PaymentStatus = "pending"
+	PaymentRefunded PaymentStatus = "refunded"
 )
 
+// Advances reports whether a processor update from s to next moves the payment forward,
+// updates that arrive late or repeat the current status are ignored
+func (s PaymentStatus) Advances(next PaymentStatus) bool {
+	switch s {
+	case PaymentPending:
+		return next == Accepted || next == Rejected
+	case Accepted:
+		return next == PaymentRefunded
+	default:
+		return false
+	}
+}
+
+// Payment is money received from a tenant, what it paid off is recorded in its
+// PaymentAllocations and anything left over stays on the tenant's ledger as credit
 type Payment struct {
 	bun.BaseModel `bun:"table:payments,alias:pmts"`
 
-	Id                int64           `bun:"column:id,pk,autoincrement"`
-	TxId              uuid.UUID       `bun:"column_name:txid,type:uuid,notnull,unique"`
-	SqPid             string          `bun:"column_name:sqpid,type:varchar(50),nullzero"`
-	Amount            decimal.Decimal `bun:"type:money,notnull,nullzero"`
-	CurrenyCode       string          `bun:"type:char(3),nullzero"`
-	TransactionStatus PaymentStatus   `bun:"type:payment_status,notnull"`
-	Note              string          `bun:"type:varchar(50)"`
-	ReceitNumber      string          `bun:"type:varchar(100)"`
-	TenantId          uuid.UUID       `bun:"type:uuid,notnull"`
-	Tenant            *Tenant         `bun:"rel:belongs-to,join:tenant_id=uid"`
-	CreatedAt         time.Time       `bun:"type:timestamptz,notnull,nullzero,default:current_timestamp"`
+	Id                int64               `bun:"column:id,pk,autoincrement"`
+	TxId              uuid.UUID           `bun:"txid,type:uuid,notnull,unique"`
+	SqPid             string              `bun:"sqpid,type:varchar(50),nullzero"`
+	Amount            decimal.Decimal     `bun:"type:numeric(12,2),notnull"`
+	Applied           decimal.Decimal     `bun:"type:numeric(12,2),notnull,default:0"`
+	CurrenyCode       string              `bun:"type:char(3),nullzero"`
+	TransactionStatus PaymentStatus       `bun:"type:payment_status,notnull"`
+	Method            MethodOfPayment     `bun:"type:method_of_payment,notnull"`
+	Note              string              `bun:"type:varchar(50)"`
+	ReceitNumber      string              `bun:"type:varchar(100)"`
+	LessorId          uuid.UUID           `bun:"type:uuid,notnull"`
+	TenantId          uuid.UUID           `bun:"type:uuid,notnull"`
+	Tenant            *Tenant             `bun:"rel:belongs-to,join:tenant_id=uid"`
+	RentalId          uuid.UUID           `bun:"type:uuid,notnull"`
+	Allocations       []PaymentAllocation `bun:"rel:has-many,join:txid=payment_id"`
+	ReceivedAt        time.Time           `bun:"type:timestamptz,notnull"`
+	ReversedAt        time.Time           `bun:"type:timestamptz,nullzero"`
+	CreatedAt         time.Time           `bun:"type:timestamptz,notnull,nullzero,default:current_timestamp"`
 }
 
 func (p Payment) Info() string {
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+)
+
+// PaymentAllocation is the part of a payment applied to one charge
+type PaymentAllocation struct {
+	bun.BaseModel `bun:"table:payment_allocations,alias:pa"`
+
+	Id        int64           `bun:"column:id,pk,autoincrement" json:"-"`
+	PaymentId uuid.UUID       `bun:"type:uuid,notnull" json:"paymentId"`
+	ChargeId  uuid.UUID       `bun:"type:uuid,notnull" json:"chargeId"`
+	Amount    decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"amount"`
+	CreatedAt time.Time       `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (p PaymentAllocation) Info() string {
+	return fmt.Sprintf("%#v\n", p)
+}
+
+// AllocatePayment applies the amount to the charges in the order given until either runs
+// out, the charges' AmountPaid is updated in place and whatever isn't applied is returned
+func AllocatePayment(paymentId uuid.UUID, amount decimal.Decimal, charges []Charge) ([]PaymentAllocation, decimal.Decimal) {
+	allocations := make([]PaymentAllocation, 0)
+	for i := range charges {
+		if !amount.IsPositive() {
+			break
+		}
+
+		owed := charges[i].Outstanding()
+		if !owed.IsPositive() {
+			continue
+		}
+
+		applied := decimal.Min(owed, amount)
+		charges[i].AmountPaid = charges[i].AmountPaid.Add(applied)
+		amount = amount.Sub(applied)
+		allocations = append(allocations, PaymentAllocation{
+			PaymentId: paymentId,
+			ChargeId:  charges[i].Cid,
+			Amount:    applied,
+		})
+	}
+
+	return allocations, amount
+}
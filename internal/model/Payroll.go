@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+)
+
+// PayrollRun is a closed pay period for a lessor, it is written once along with its items and
+// never changed. The overtime rules used are kept on the run so it can always be explained
+type PayrollRun struct {
+	bun.BaseModel `bun:"table:payroll_runs,alias:pr"`
+
+	Id                 int64           `bun:"column:id,pk,autoincrement" json:"-"`
+	Rid                uuid.UUID       `bun:"type:uuid,notnull,unique" json:"rid"`
+	LessorId           uuid.UUID       `bun:"type:uuid,notnull,unique:payroll_period" json:"lessorId"`
+	PeriodStart        time.Time       `bun:"type:timestamptz,notnull,unique:payroll_period" json:"periodStart"`
+	PeriodEnd          time.Time       `bun:"type:timestamptz,notnull" json:"periodEnd"`
+	OvertimeAfter      decimal.Decimal `bun:"type:numeric(6,2),notnull" json:"overtimeAfter"`
+	OvertimeMultiplier decimal.Decimal `bun:"type:numeric(4,2),notnull" json:"overtimeMultiplier"`
+	RegularHours       decimal.Decimal `bun:"type:numeric(10,2),notnull" json:"regularHours"`
+	OvertimeHours      decimal.Decimal `bun:"type:numeric(10,2),notnull" json:"overtimeHours"`
+	FlatFees           decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"flatFees"`
+	Gross              decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"gross"`
+	CreatedBy          uuid.UUID       `bun:"type:uuid,nullzero" json:"createdBy"`
+	CreatedAt          time.Time       `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+	Items              []PayrollItem   `bun:"rel:has-many,join:rid=run_id" json:"items"`
+}
+
+func (p PayrollRun) Info() string {
+	return fmt.Sprintf("%#v\n", p)
+}
+
+// PayrollItem is what one worker is owed for a run, the worker's rate and payment method are
+// copied so later changes to the worker don't change a closed period
+type PayrollItem struct {
+	bun.BaseModel `bun:"table:payroll_items,alias:pi"`
+
+	Id            int64           `bun:"column:id,pk,autoincrement" json:"-"`
+	RunId         uuid.UUID       `bun:"type:uuid,notnull,unique:payroll_worker" json:"runId"`
+	WorkerId      uuid.UUID       `bun:"type:uuid,notnull,unique:payroll_worker" json:"workerId"`
+	WorkerName    string          `bun:"type:varchar(255)" json:"workerName"`
+	PaymentMethod MethodOfPayment `bun:"type:varchar(20),nullzero" json:"paymentMethod"`
+	PayRate       decimal.Decimal `bun:"type:numeric(10,2),notnull" json:"payRate"`
+	RegularHours  decimal.Decimal `bun:"type:numeric(10,2),notnull" json:"regularHours"`
+	OvertimeHours decimal.Decimal `bun:"type:numeric(10,2),notnull" json:"overtimeHours"`
+	RegularPay    decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"regularPay"`
+	OvertimePay   decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"overtimePay"`
+	FlatFeeTasks  int             `bun:"type:integer,notnull,default:0" json:"flatFeeTasks"`
+	FlatFees      decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"flatFees"`
+	Gross         decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"gross"`
+}
+
+func (p PayrollItem) Info() string {
+	return fmt.Sprintf("%#v\n", p)
+}
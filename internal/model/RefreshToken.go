@@ -0,0 +1,34 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RefreshToken only keeps a hash of the token handed to the client. Every token issued
+// from the same sign in shares a FamilyId so a replayed token can revoke the whole chain
+type RefreshToken struct {
+	bun.BaseModel `bun:"table:refresh_tokens,alias:rt"`
+
+	Id        int64     `bun:"column:id,pk,autoincrement" json:"-"`
+	Uid       uuid.UUID `bun:"type:uuid,notnull" json:"uid"`
+	User      *User     `bun:"rel:belongs-to,join:uid=uid" json:"-"`
+	FamilyId  uuid.UUID `bun:"type:uuid,notnull" json:"familyId"`
+	DeviceId  string    `bun:"type:varchar(100),notnull" json:"deviceId"`
+	TokenHash string    `bun:"type:varchar(64),notnull,unique" json:"-"`
+	ExpiresAt time.Time `bun:"type:timestamptz,notnull" json:"expiresAt"`
+	RotatedAt time.Time `bun:"type:timestamptz,nullzero" json:"rotatedAt"`
+	RevokedAt time.Time `bun:"type:timestamptz,nullzero" json:"revokedAt"`
+	CreatedAt time.Time `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (r RefreshToken) Info() string {
+	return fmt.Sprintf("%#v\n", r)
+}
+
+func (r RefreshToken) Expired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
@@ -9,13 +9,15 @@ import (
 	"github.com/uptrace/bun"
 )
 
+// RentalProperty keeps LeaseSigned, LeaseDuration and LeaseRenewDate in step with its current
+// lease, the leases themselves and their history are in the leases table
 type RentalProperty struct {
 	bun.BaseModel `bun:"table:rental_properties,alias:rp"`
 
 	Id                int64           `bun:"column:id,pk,autoincrement" json:"-"`
 	Pid               uuid.UUID       `bun:"type:uuid,notnull,unique" json:"pid"`
 	Property          *Property       `bun:"rel:belongs-to,join:pid=pid" json:"property"`
-	RentalPrice       decimal.Decimal `bun:"type:money,notnull" json:"rentalPrice"`
+	RentalPrice       decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"rentalPrice"`
 	RentDueDate       time.Time       `bun:"type:timestamptz,nullzero" json:"rentDueDate"`
 	LeaseSigned       bool            `bun:"type:boolean,nullzero,notnull,default:false" json:"leaseSigned"`
 	LeaseDuration     int             `bun:",nullzero" json:"leaseDuration"`
@@ -24,6 +26,8 @@ type RentalProperty struct {
 	PetFriendly       bool            `bun:"type:boolean,nullzero,notnull,default:false" json:"petFriendly"`
 	NeedsEviction     bool            `bun:"type:boolean,nullzero,notnull,default:false" json:"needsEviction"`
 	EvictionStartDate time.Time       `bun:"type:timestamptz,nullzero,notnull,default:false" json:"evictionStartDate"`
+	LateFee           decimal.Decimal `bun:"type:numeric(12,2),notnull,default:0" json:"lateFee"`
+	LateFeeGraceDays  int             `bun:"type:integer,notnull,default:0" json:"lateFeeGraceDays"`
 }
 
 func (r RentalProperty) Info() string {
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type SmsStatus string
+
+const (
+	SmsQueued    SmsStatus = "queued"
+	SmsSent      SmsStatus = "sent"
+	SmsDelivered SmsStatus = "delivered"
+	SmsFailed    SmsStatus = "failed"
+)
+
+// Replaces lists the statuses this one can overwrite, provider callbacks arrive out of order
+// so a late "sent" must not undo "delivered"
+func (s SmsStatus) Replaces() []SmsStatus {
+	switch s {
+	case SmsSent:
+		return []SmsStatus{SmsQueued}
+	case SmsDelivered, SmsFailed:
+		return []SmsStatus{SmsQueued, SmsSent}
+	default:
+		return nil
+	}
+}
+
+// SmsMessage is one text sent to a user, Sid is sent to the provider as the callback
+// reference so delivery updates find their way back
+type SmsMessage struct {
+	bun.BaseModel `bun:"table:sms_messages,alias:sms"`
+
+	Id             int64     `bun:"column:id,pk,autoincrement" json:"-"`
+	Sid            uuid.UUID `bun:"type:uuid,notnull,unique" json:"sid"`
+	LessorId       uuid.UUID `bun:"type:uuid,notnull" json:"lessorId"`
+	UserId         uuid.UUID `bun:"type:uuid,nullzero" json:"userId"`
+	NotificationId int       `bun:"type:int,nullzero" json:"notificationId"`
+	To             string    `bun:"type:varchar(16),notnull" json:"to"`
+	Body           string    `bun:"type:varchar(1600),notnull" json:"body"`
+	Provider       string    `bun:"type:varchar(20),notnull" json:"provider"`
+	ProviderId     string    `bun:"type:varchar(64),nullzero" json:"providerId"`
+	Status         SmsStatus `bun:"type:varchar(20),notnull,default:'queued'" json:"status"`
+	Error          string    `bun:"type:varchar(255),nullzero" json:"error"`
+	Attempts       int       `bun:"type:int,notnull,default:0" json:"attempts"`
+	CreatedAt      time.Time `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt      time.Time `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"updatedAt"`
+}
+
+func (s SmsMessage) Info() string {
+	return fmt.Sprintf("%#v\n", s)
+}
+
+// SmsOptOut is a number that replied STOP, nothing is texted to it until it replies START
+type SmsOptOut struct {
+	bun.BaseModel `bun:"table:sms_opt_outs,alias:soo"`
+
+	Id        int64     `bun:"column:id,pk,autoincrement" json:"-"`
+	Phone     string    `bun:"type:varchar(16),notnull,unique" json:"phone"`
+	Keyword   string    `bun:"type:varchar(20)" json:"keyword"`
+	CreatedAt time.Time `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (s SmsOptOut) Info() string {
+	return fmt.Sprintf("%#v\n", s)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"github.com/uptrace/bun"
 )
 
@@ -34,33 +35,65 @@ const (
 type Task struct {
 	bun.BaseModel `bun:"table:tasks,alias:tsk"`
 
-	Id             int64         `bun:"column:id,pk,autoincrement" json:"-"`
-	Tid            uuid.UUID     `bun:"type:uuid,notnull,unique" json:"tid"`
-	Name           string        `bun:"type:varchar(255)" json:"name"`
-	LessorId       uuid.UUID     `bun:"type:uuid,notnull" json:"lessorId"`
-	Alessor        *Alessor      `bun:"rel:belongs-to,join:lessor_id=uid" json:"alessor"`
-	Details        string        `bun:"type:text,notnull" json:"details"`
-	Notes          string        `bun:"type:text" json:"notes"`
-	Priority       PriorityLevel `bun:"type:priority_level,notnull" json:"priority"`
-	TakePrecedence bool          `bun:"type:bool" json:"takePrecedence"`
-	PropertyId     uuid.UUID     `bun:"type:uuid,nullzero" json:"propertyId"`
-	Property       *Property     `bun:"rel:belongs-to,join:property_id=pid" json:"property"`
-	Category       TaskCategory  `bun:"type:task_categories" json:"category"`
-	ScheduledAt    time.Time     `bun:"type:timestamptz,nullzero" json:"scheduledAt"`
-	StartedAt      time.Time     `bun:"type:timestamptz,nullzero" json:"startedAt"`
-	CompletedAt    time.Time     `bun:"type:timestamptz,nullzero" json:"completedAt"`
-	PausedAt       time.Time     `bun:"type:timestamptz,nullzero" json:"pausedAt"`
-	PausedReason   string        `bun:"type:varchar(255)" json:"pausedReason"`
-	FailedAt       time.Time     `bun:"type:timestamptz,nullzero" json:"failedAt"`
-	FailedReason   string        `bun:"type:varchar(255)" json:"failedReason"`
-	WorkerId       uuid.UUID     `bun:"type:uuid,nullzero" json:"workerId"`
-	Worker         *Worker       `bun:"rel:belongs-to,join:worker_id=uid" json:"worker"`
-	EstimatedCost  float64       `bun:"type:numeric(10,2)" json:"estimatedCost"`
-	ActualCost     float64       `bun:"type:numeric(10,2)" json:"actualCost"`
-	Profit         float64       `bun:"type:numeric(10,2)" json:"profit"`
-	Image          string        `bun:"type:text,nullzero" json:"image"`
+	Id               int64           `bun:"column:id,pk,autoincrement" json:"-"`
+	Tid              uuid.UUID       `bun:"type:uuid,notnull,unique" json:"tid"`
+	Name             string          `bun:"type:varchar(255)" json:"name"`
+	LessorId         uuid.UUID       `bun:"type:uuid,notnull" json:"lessorId"`
+	Alessor          *Alessor        `bun:"rel:belongs-to,join:lessor_id=uid" json:"alessor"`
+	Details          string          `bun:"type:text,notnull" json:"details"`
+	Notes            string          `bun:"type:text" json:"notes"`
+	Priority         PriorityLevel   `bun:"type:priority_level,notnull" json:"priority"`
+	TakePrecedence   bool            `bun:"type:bool" json:"takePrecedence"`
+	PropertyId       uuid.UUID       `bun:"type:uuid,nullzero" json:"propertyId"`
+	Property         *Property       `bun:"rel:belongs-to,join:property_id=pid" json:"property"`
+	Category         TaskCategory    `bun:"type:task_categories" json:"category"`
+	Status           TaskStatus      `bun:"type:varchar(20),notnull,default:'scheduled'" json:"status"`
+	ScheduledAt      time.Time       `bun:"type:timestamptz,nullzero" json:"scheduledAt"`
+	EstimatedMinutes int             `bun:"type:integer,nullzero" json:"estimatedMinutes"`
+	Appointment      bool            `bun:"type:bool,notnull,default:false" json:"appointment"`
+	StartedAt        time.Time       `bun:"type:timestamptz,nullzero" json:"startedAt"`
+	CompletedAt      time.Time       `bun:"type:timestamptz,nullzero" json:"completedAt"`
+	PausedAt         time.Time       `bun:"type:timestamptz,nullzero" json:"pausedAt"`
+	PausedReason     string          `bun:"type:varchar(255)" json:"pausedReason"`
+	FailedAt         time.Time       `bun:"type:timestamptz,nullzero" json:"failedAt"`
+	FailedReason     string          `bun:"type:varchar(255)" json:"failedReason"`
+	WorkerId         uuid.UUID       `bun:"type:uuid,nullzero" json:"workerId"`
+	Worker           *Worker         `bun:"rel:belongs-to,join:worker_id=uid" json:"worker"`
+	Assignments      []AssignedTask  `bun:"rel:has-many,join:tid=task_id" json:"assignments"`
+	EstimatedCost    decimal.Decimal `bun:"type:numeric(10,2)" json:"estimatedCost"`
+	ActualCost       decimal.Decimal `bun:"type:numeric(10,2)" json:"actualCost"`
+	BilledAmount     decimal.Decimal `bun:"type:numeric(10,2)" json:"billedAmount"`
+	Profit           decimal.Decimal `bun:"type:numeric(10,2)" json:"profit"`
+	FlatFee          decimal.Decimal `bun:"type:numeric(10,2),nullzero" json:"flatFee"`
+	Fees             []TaskFee       `bun:"rel:has-many,join:tid=task_id" json:"fees,omitempty"`
+	Image            string          `bun:"type:text,nullzero" json:"image"`
+	ScheduleId       uuid.UUID       `bun:"type:uuid,nullzero,unique:task_occurrence" json:"scheduleId"`
+	OccurrenceAt     time.Time       `bun:"type:timestamptz,nullzero,unique:task_occurrence" json:"occurrenceAt"`
+}
+
+// Window is when the task is expected to keep its workers busy
+func (t Task) Window() (time.Time, time.Time) {
+	minutes := t.EstimatedMinutes
+	if minutes <= 0 {
+		minutes = DefaultTaskMinutes
+	}
+	return t.ScheduledAt, t.ScheduledAt.Add(time.Duration(minutes) * time.Minute)
 }
 
 func (t Task) Info() string {
 	return fmt.Sprintf("%#v\n", t)
 }
+
+// Rank orders priorities from low to immediate, an unset priority counts as medium
+func (p PriorityLevel) Rank() int {
+	switch p {
+	case Low:
+		return 1
+	case High:
+		return 3
+	case Immediate:
+		return 4
+	default:
+		return 2
+	}
+}
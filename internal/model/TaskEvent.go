@@ -0,0 +1,60 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type TaskEventType string
+
+const (
+	TaskCreated         TaskEventType = "created"
+	TaskUpdated         TaskEventType = "updated"
+	TaskReassigned      TaskEventType = "reassigned"
+	TaskPriorityChanged TaskEventType = "priority_changed"
+	TaskNotesEdited     TaskEventType = "notes_edited"
+	TaskCostChanged     TaskEventType = "cost_changed"
+	TaskStarted         TaskEventType = "started"
+	TaskPaused          TaskEventType = "paused"
+	TaskResumed         TaskEventType = "resumed"
+	TaskCompleted       TaskEventType = "completed"
+	TaskFailed          TaskEventType = "failed"
+	TaskDeleted         TaskEventType = "deleted"
+	TaskWorkerAdded     TaskEventType = "worker_added"
+	TaskWorkerRemoved   TaskEventType = "worker_removed"
+	TaskWorkerAccepted  TaskEventType = "worker_accepted"
+	TaskWorkerDeclined  TaskEventType = "worker_declined"
+	TaskClockedIn       TaskEventType = "clocked_in"
+	TaskClockedOut      TaskEventType = "clocked_out"
+)
+
+// TaskEvent is an append only record of a change to a task, rows are never updated
+// and are kept after the task itself is deleted
+type TaskEvent struct {
+	bun.BaseModel `bun:"table:task_events,alias:te"`
+
+	Id        int64                  `bun:"column:id,pk,autoincrement" json:"-"`
+	TaskId    uuid.UUID              `bun:"type:uuid,notnull" json:"taskId"`
+	LessorId  uuid.UUID              `bun:"type:uuid,notnull" json:"lessorId"`
+	EventType TaskEventType          `bun:"type:varchar(30),notnull" json:"eventType"`
+	ActorId   uuid.UUID              `bun:"type:uuid,nullzero" json:"actorId"`
+	ActorName string                 `bun:"type:varchar(150),nullzero" json:"actorName"`
+	ActorRole string                 `bun:"type:varchar(20),nullzero" json:"actorRole"`
+	Details   map[string]interface{} `bun:"type:jsonb" json:"details"`
+	CreatedAt time.Time              `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (t TaskEvent) Info() string {
+	return fmt.Sprintf("%#v\n", t)
+}
+
+// TaskStatusEvents move the task through its lifecycle
+var TaskStatusEvents = []TaskEventType{TaskStarted, TaskPaused, TaskResumed, TaskCompleted, TaskFailed}
+
+func (t TaskEventType) StatusChange() bool {
+	return slices.Contains(TaskStatusEvents, t)
+}
@@ -1,13 +1,42 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+)
+
+type FeeKind string
+
+const (
+	MaterialFee FeeKind = "material"
+	LaborFee    FeeKind = "labor"
+)
+
+// TaskFee is a line item of what a task cost, Cost is always Quantity times UnitCost. A
+// processed fee has been paid out or billed and can't change any more
 type TaskFee struct {
-	Id        int64     `bun:"column:id,pk,autoincrement" json:"-"`
-	TaskId    uuid.UUID `bun:"type:uuid,notnull,unique" json:"taskId"`
-	Task      *Task     `bun:"rel:belongs-to,join:task_id=tid" json:"task"`
-	Material  string    `bun:"type:varchar(255)" json:"material"`
-	Cost      float64   `bun:"type:money,notnull,nullzero" json:"cost"`
-	Details   string    `bun:"type:varchar(255)" json:"details"`
-	Processed bool      `bun:"type:boolean,notnull,nullzero,default:false" json:"processed"`
+	bun.BaseModel `bun:"table:task_fees,alias:tf"`
+
+	Id          int64           `bun:"column:id,pk,autoincrement" json:"-"`
+	Fid         uuid.UUID       `bun:"type:uuid,notnull,unique" json:"fid"`
+	TaskId      uuid.UUID       `bun:"type:uuid,notnull" json:"taskId"`
+	Task        *Task           `bun:"rel:belongs-to,join:task_id=tid" json:"task,omitempty"`
+	LessorId    uuid.UUID       `bun:"type:uuid,notnull" json:"lessorId"`
+	Kind        FeeKind         `bun:"type:varchar(20),notnull,default:'material'" json:"kind"`
+	Material    string          `bun:"type:varchar(255)" json:"material"`
+	Quantity    decimal.Decimal `bun:"type:numeric(10,2),notnull" json:"quantity"`
+	UnitCost    decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"unitCost"`
+	Cost        decimal.Decimal `bun:"type:numeric(12,2),notnull" json:"cost"`
+	Details     string          `bun:"type:varchar(255)" json:"details"`
+	Processed   bool            `bun:"type:boolean,notnull,default:false" json:"processed"`
+	ProcessedAt time.Time       `bun:"type:timestamptz,nullzero" json:"processedAt"`
+	CreatedAt   time.Time       `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (t TaskFee) Info() string {
+	return fmt.Sprintf("%#v\n", t)
 }
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+)
+
+// TaskSchedule is the template a recurring task is generated from. Rule holds an RRULE
+// that is expanded from StartsAt, tasks are generated up to GeneratedThrough and any
+// occurrence listed in ExDates has been cancelled on its own
+type TaskSchedule struct {
+	bun.BaseModel `bun:"table:task_schedules,alias:tsch"`
+
+	Id               int64           `bun:"column:id,pk,autoincrement" json:"-"`
+	Sid              uuid.UUID       `bun:"type:uuid,notnull,unique" json:"sid"`
+	LessorId         uuid.UUID       `bun:"type:uuid,notnull" json:"lessorId"`
+	PropertyId       uuid.UUID       `bun:"type:uuid,notnull" json:"propertyId"`
+	Property         *Property       `bun:"rel:belongs-to,join:property_id=pid" json:"property"`
+	Name             string          `bun:"type:varchar(255)" json:"name"`
+	Details          string          `bun:"type:text,notnull" json:"details"`
+	Notes            string          `bun:"type:text" json:"notes"`
+	Priority         PriorityLevel   `bun:"type:priority_level,notnull" json:"priority"`
+	Category         TaskCategory    `bun:"type:task_categories" json:"category"`
+	WorkerId         uuid.UUID       `bun:"type:uuid,nullzero" json:"workerId"`
+	EstimatedCost    decimal.Decimal `bun:"type:numeric(10,2)" json:"estimatedCost"`
+	Rule             string          `bun:"type:varchar(255),notnull" json:"rule"`
+	StartsAt         time.Time       `bun:"type:timestamptz,notnull" json:"startsAt"`
+	ExDates          []time.Time     `bun:"type:timestamptz[],array" json:"exDates"`
+	GeneratedThrough time.Time       `bun:"type:timestamptz,nullzero" json:"generatedThrough"`
+	CancelledAt      time.Time       `bun:"type:timestamptz,nullzero" json:"cancelledAt"`
+	CreatedAt        time.Time       `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt        time.Time       `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"updatedAt"`
+}
+
+func (t TaskSchedule) Info() string {
+	return fmt.Sprintf("%#v\n", t)
+}
+
+// Excluded reports whether the occurrence was cancelled on its own
+func (t TaskSchedule) Excluded(occurrence time.Time) bool {
+	for _, ex := range t.ExDates {
+		if ex.Equal(occurrence) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewOccurrence builds the task for one occurrence of the schedule
+func (t TaskSchedule) NewOccurrence(occurrence time.Time) Task {
+	return Task{
+		Tid:           uuid.New(),
+		Name:          t.Name,
+		LessorId:      t.LessorId,
+		Details:       t.Details,
+		Notes:         t.Notes,
+		Priority:      t.Priority,
+		PropertyId:    t.PropertyId,
+		Category:      t.Category,
+		Status:        Scheduled,
+		ScheduledAt:   occurrence,
+		WorkerId:      t.WorkerId,
+		EstimatedCost: t.EstimatedCost,
+		ScheduleId:    t.Sid,
+		OccurrenceAt:  occurrence,
+	}
+}
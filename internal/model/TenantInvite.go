@@ -0,0 +1,27 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// TenantInvite lets a tenant a lessor added pick their own username and password, like
+// refresh tokens only a hash of the token handed out is kept
+type TenantInvite struct {
+	bun.BaseModel `bun:"table:tenant_invites,alias:ti"`
+
+	Id         int64     `bun:"column:id,pk,autoincrement" json:"-"`
+	Uid        uuid.UUID `bun:"type:uuid,notnull" json:"uid"`
+	LessorId   uuid.UUID `bun:"type:uuid,notnull" json:"lessorId"`
+	TokenHash  string    `bun:"type:varchar(64),notnull,unique" json:"-"`
+	ExpiresAt  time.Time `bun:"type:timestamptz,notnull" json:"expiresAt"`
+	AcceptedAt time.Time `bun:"type:timestamptz,nullzero" json:"acceptedAt"`
+	CreatedAt  time.Time `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (t TenantInvite) Info() string {
+	return fmt.Sprintf("%#v\n", t)
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+)
+
+type TimeEntryStatus string
+
+const (
+	EntryOpen     TimeEntryStatus = "open"
+	EntryPending  TimeEntryStatus = "pending"
+	EntryApproved TimeEntryStatus = "approved"
+	EntryRejected TimeEntryStatus = "rejected"
+)
+
+// TimeEntry is one stretch of a worker's time on a task from clock in to clock out, breaks
+// are tracked on the entry and taken out of the time worked
+type TimeEntry struct {
+	bun.BaseModel `bun:"table:time_entries,alias:tme"`
+
+	Id             int64           `bun:"column:id,pk,autoincrement" json:"-"`
+	Eid            uuid.UUID       `bun:"type:uuid,notnull,unique" json:"eid"`
+	WorkerId       uuid.UUID       `bun:"type:uuid,notnull" json:"workerId"`
+	Worker         *Worker         `bun:"rel:belongs-to,join:worker_id=uid" json:"worker"`
+	LessorId       uuid.UUID       `bun:"type:uuid,notnull" json:"lessorId"`
+	TaskId         uuid.UUID       `bun:"type:uuid,notnull" json:"taskId"`
+	Task           *Task           `bun:"rel:belongs-to,join:task_id=tid" json:"task"`
+	ClockIn        time.Time       `bun:"type:timestamptz,notnull" json:"clockIn"`
+	ClockOut       time.Time       `bun:"type:timestamptz,nullzero" json:"clockOut"`
+	BreakStartedAt time.Time       `bun:"type:timestamptz,nullzero" json:"breakStartedAt"`
+	BreakMinutes   int             `bun:"type:integer,notnull,default:0" json:"breakMinutes"`
+	Notes          string          `bun:"type:text,nullzero" json:"notes"`
+	Status         TimeEntryStatus `bun:"type:varchar(20),notnull,default:'open'" json:"status"`
+	ReviewedBy     uuid.UUID       `bun:"type:uuid,nullzero" json:"reviewedBy"`
+	ReviewedAt     time.Time       `bun:"type:timestamptz,nullzero" json:"reviewedAt"`
+	ReviewNote     string          `bun:"type:varchar(255),nullzero" json:"reviewNote"`
+	PayrollRunId   uuid.UUID       `bun:"type:uuid,nullzero" json:"payrollRunId"`
+	CreatedAt      time.Time       `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+// Minutes is the time worked less breaks, an open entry counts up to now and so does a break
+// that hasn't ended
+func (e TimeEntry) Minutes(now time.Time) int {
+	end := e.ClockOut
+	if end.IsZero() {
+		end = now
+	}
+
+	breaks := e.BreakMinutes
+	if !e.BreakStartedAt.IsZero() {
+		breaks += int(end.Sub(e.BreakStartedAt).Minutes())
+	}
+
+	worked := int(end.Sub(e.ClockIn).Minutes()) - breaks
+	if worked < 0 {
+		return 0
+	}
+	return worked
+}
+
+func (e TimeEntry) Hours(now time.Time) decimal.Decimal {
+	return decimal.NewFromInt(int64(e.Minutes(now))).Div(decimal.NewFromInt(60)).Round(2)
+}
+
+func (e TimeEntry) Info() string {
+	return fmt.Sprintf("%#v\n", e)
+}
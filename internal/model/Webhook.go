@@ -0,0 +1,101 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type WebhookEvent string
+
+const (
+	TaskStartedHook         WebhookEvent = "task.started"
+	TaskPausedHook          WebhookEvent = "task.paused"
+	TaskResumedHook         WebhookEvent = "task.resumed"
+	TaskCompletedHook       WebhookEvent = "task.completed"
+	TaskFailedHook          WebhookEvent = "task.failed"
+	PropertyUpdatedHook     WebhookEvent = "property.updated"
+	NotificationCreatedHook WebhookEvent = "notification.created"
+)
+
+var WebhookEvents = []WebhookEvent{
+	TaskStartedHook, TaskPausedHook, TaskResumedHook, TaskCompletedHook, TaskFailedHook,
+	PropertyUpdatedHook, NotificationCreatedHook,
+}
+
+// TaskWebhookEvent is the webhook event a task event is sent as, only status changes are sent
+func TaskWebhookEvent(t TaskEventType) (WebhookEvent, bool) {
+	if !t.StatusChange() {
+		return "", false
+	}
+	return WebhookEvent("task." + string(t)), true
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookSubscription is an endpoint a lessor wants events posted to, Failures counts the
+// deliveries in a row that gave up and the subscription is disabled once it gets too high
+type WebhookSubscription struct {
+	bun.BaseModel `bun:"table:webhook_subscriptions,alias:whs"`
+
+	Id             int64          `bun:"column:id,pk,autoincrement" json:"-"`
+	Wid            uuid.UUID      `bun:"type:uuid,notnull,unique" json:"wid"`
+	LessorId       uuid.UUID      `bun:"type:uuid,notnull" json:"lessorId"`
+	Url            string         `bun:"type:varchar(2048),notnull" json:"url"`
+	Secret         string         `bun:"type:varchar(100),notnull" json:"-"`
+	Events         []WebhookEvent `bun:"type:text[],array,notnull" json:"events"`
+	Description    string         `bun:"type:varchar(255)" json:"description"`
+	Active         bool           `bun:"type:boolean,notnull,default:true" json:"active"`
+	Failures       int            `bun:"type:int,notnull,default:0" json:"failures"`
+	DisabledAt     time.Time      `bun:"type:timestamptz,nullzero" json:"disabledAt"`
+	DisabledReason string         `bun:"type:varchar(255),nullzero" json:"disabledReason"`
+	CreatedAt      time.Time      `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+	UpdatedAt      time.Time      `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"updatedAt"`
+}
+
+func (w WebhookSubscription) Info() string {
+	return fmt.Sprintf("%#v\n", w)
+}
+
+// WebhookAttempt is one try at posting a delivery, StatusCode is empty when no response came back
+type WebhookAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode"`
+	Response   string    `json:"response"`
+	Error      string    `json:"error"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// WebhookDelivery is one event posted to one subscription, Payload is built when the event
+// happens so a retry or redelivery sends exactly what the first attempt did
+type WebhookDelivery struct {
+	bun.BaseModel `bun:"table:webhook_deliveries,alias:whd"`
+
+	Id             int64                `bun:"column:id,pk,autoincrement" json:"-"`
+	Did            uuid.UUID            `bun:"type:uuid,notnull,unique" json:"did"`
+	SubscriptionId uuid.UUID            `bun:"type:uuid,notnull" json:"subscriptionId"`
+	Subscription   *WebhookSubscription `bun:"rel:belongs-to,join:subscription_id=wid" json:"-"`
+	LessorId       uuid.UUID            `bun:"type:uuid,notnull" json:"lessorId"`
+	Event          WebhookEvent         `bun:"type:varchar(50),notnull" json:"event"`
+	Payload        json.RawMessage      `bun:"type:jsonb,notnull" json:"payload"`
+	Status         DeliveryStatus       `bun:"type:varchar(20),notnull,default:'pending'" json:"status"`
+	Attempts       []WebhookAttempt     `bun:"type:jsonb,nullzero" json:"attempts"`
+	StatusCode     int                  `bun:"type:int,nullzero" json:"statusCode"`
+	NextAttemptAt  time.Time            `bun:"type:timestamptz,nullzero" json:"nextAttemptAt"`
+	DeliveredAt    time.Time            `bun:"type:timestamptz,nullzero" json:"deliveredAt"`
+	RedeliveryOf   uuid.UUID            `bun:"type:uuid,nullzero" json:"redeliveryOf"`
+	CreatedAt      time.Time            `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (w WebhookDelivery) Info() string {
+	return fmt.Sprintf("%#v\n", w)
+}
@@ -14,6 +14,8 @@ type MethodOfPayment string
 const (
 	Check MethodOfPayment = "check"
 	Cash  MethodOfPayment = "cash"
+	// Processor payments are taken by an external card or bank processor
+	Processor MethodOfPayment = "processor"
 )
 
 type Worker struct {
@@ -27,10 +29,12 @@ type Worker struct {
 	Title         string          `bun:"type:varchar(100),nullzero" json:"title"`
 	Specilization string          `bun:"type:varchar(255),nullzero" json:"specilization"`
 	PayRate       decimal.Decimal `bun:"type:numeric(10,2),nullzero" json:"payRate"`
-	LessorId      uuid.UUID       `bun:"type:uuid,notnull,unique" json:"lessorId"`
+	LessorId      uuid.UUID       `bun:"type:uuid,notnull" json:"lessorId"`
 	Alessor       *Alessor        `bun:"rel:belongs-to,join:lessor_id=uid" json:"alessor"`
 	PaymentMethod MethodOfPayment `bun:"type:method_of_payment" json:"-"`
-	Image         string          `bun:"type:text" json:"iamge"`
+	// Timezone is the IANA name working hours are read in, empty means UTC
+	Timezone string `bun:"type:varchar(64),nullzero" json:"timezone"`
+	Image    string `bun:"type:text" json:"iamge"`
 }
 
 func (w Worker) Info() string {
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// DefaultTaskMinutes is how long a task is assumed to take when it has no estimate
+const DefaultTaskMinutes = 60
+
+// WorkingHours is a block of time a worker is available every week, the minutes are
+// counted from midnight in the worker's timezone
+type WorkingHours struct {
+	bun.BaseModel `bun:"table:worker_hours,alias:wh"`
+
+	Id          int64        `bun:"column:id,pk,autoincrement" json:"-"`
+	WorkerId    uuid.UUID    `bun:"type:uuid,notnull" json:"workerId"`
+	LessorId    uuid.UUID    `bun:"type:uuid,notnull" json:"lessorId"`
+	Weekday     time.Weekday `bun:"type:smallint,notnull" json:"weekday"`
+	StartMinute int          `bun:"type:smallint,notnull" json:"startMinute"`
+	EndMinute   int          `bun:"type:smallint,notnull" json:"endMinute"`
+}
+
+func (w WorkingHours) Info() string {
+	return fmt.Sprintf("%#v\n", w)
+}
+
+// TimeOff blocks out a worker for vacations, sick days and the like
+type TimeOff struct {
+	bun.BaseModel `bun:"table:worker_time_off,alias:wto"`
+
+	Id        int64     `bun:"column:id,pk,autoincrement" json:"-"`
+	Oid       uuid.UUID `bun:"type:uuid,notnull,unique" json:"oid"`
+	WorkerId  uuid.UUID `bun:"type:uuid,notnull" json:"workerId"`
+	LessorId  uuid.UUID `bun:"type:uuid,notnull" json:"lessorId"`
+	StartAt   time.Time `bun:"type:timestamptz,notnull" json:"startAt"`
+	EndAt     time.Time `bun:"type:timestamptz,notnull" json:"endAt"`
+	Reason    string    `bun:"type:varchar(255),nullzero" json:"reason"`
+	CreatedAt time.Time `bun:"type:timestamptz,nullzero,notnull,default:current_timestamp" json:"createdAt"`
+}
+
+func (t TimeOff) Info() string {
+	return fmt.Sprintf("%#v\n", t)
+}
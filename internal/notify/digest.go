@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+// digestLines is how many items a digest lists before it tells the user to open the app
+const digestLines = 10
+
+// DigestItem is one line of a digest, repeated updates about the same task or property
+// are collapsed into the latest one
+type DigestItem struct {
+	Category   model.NotificationType
+	TaskId     uuid.UUID
+	PropertyId uuid.UUID
+	Title      string
+	Message    string
+	Count      int
+	Latest     time.Time
+}
+
+type digestKey struct {
+	category   model.NotificationType
+	taskId     uuid.UUID
+	propertyId uuid.UUID
+	title      string
+}
+
+// Collapse groups the notifications by what they are about, newest first
+func Collapse(notis []model.Notification) []DigestItem {
+	items := make(map[digestKey]*DigestItem)
+	for _, n := range notis {
+		key := digestKey{category: n.Category, taskId: n.TaskId, propertyId: n.PropertyId}
+		// without a task or property the title is the only thing tying updates together
+		if n.TaskId == uuid.Nil && n.PropertyId == uuid.Nil {
+			key.title = n.Title
+		}
+
+		item, ok := items[key]
+		if !ok {
+			item = &DigestItem{Category: n.Category, TaskId: n.TaskId, PropertyId: n.PropertyId}
+			items[key] = item
+		}
+
+		item.Count++
+		if item.Count == 1 || n.CreatedAt.After(item.Latest) {
+			item.Title, item.Message, item.Latest = n.Title, n.Message, n.CreatedAt
+		}
+	}
+
+	collapsed := make([]DigestItem, 0, len(items))
+	for _, item := range items {
+		collapsed = append(collapsed, *item)
+	}
+
+	slices.SortFunc(collapsed, func(a, b DigestItem) int {
+		return cmp.Or(b.Latest.Compare(a.Latest), strings.Compare(a.Title, b.Title))
+	})
+	return collapsed
+}
+
+// Digest builds the summary notification sent in place of the collapsed ones, it is never
+// saved so it only carries what the channels need to reach the recipient
+func Digest(frequency model.DigestFrequency, recipient model.Notification, items []DigestItem, total int) model.Notification {
+	updates := "updates"
+	if total == 1 {
+		updates = "update"
+	}
+
+	var msg strings.Builder
+	for i, item := range items {
+		if i == digestLines {
+			fmt.Fprintf(&msg, "and %v more, sign in to see them all", len(items)-digestLines)
+			break
+		}
+
+		line := item.Title
+		if item.Count > 1 {
+			line = fmt.Sprintf("%v (%v updates)", line, item.Count)
+		}
+
+		if item.Message != "" {
+			line = fmt.Sprintf("%v: %v", line, item.Message)
+		}
+		fmt.Fprintln(&msg, line)
+	}
+
+	return model.Notification{
+		Title:     fmt.Sprintf("Your %v summary: %v %v", frequency, total, updates),
+		Message:   strings.TrimSpace(msg.String()),
+		LessorId:  recipient.LessorId,
+		UserId:    recipient.UserId,
+		Category:  model.GeneralAlert,
+		Priority:  model.Medium,
+		CreatedAt: time.Now(),
+	}
+}
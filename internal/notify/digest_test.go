@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestDigestDue(t *testing.T) {
+	// 7:30am in new york
+	now := time.Date(2026, time.January, 5, 12, 30, 0, 0, time.UTC)
+	hourly := model.NotificationPreferences{Digest: model.HourlyDigest}
+	daily := model.NotificationPreferences{Digest: model.DailyDigest, DigestHour: 7, Timezone: "America/New_York"}
+
+	for _, test := range []struct {
+		name  string
+		prefs model.NotificationPreferences
+		last  time.Time
+		want  bool
+	}{
+		{"never sent", hourly, time.Time{}, true},
+		{"hourly run a little early", hourly, now.Add(-55 * time.Minute), true},
+		{"hourly sent recently", hourly, now.Add(-20 * time.Minute), false},
+		{"daily not sent since yesterday", daily, now.Add(-25 * time.Hour), true},
+		{"daily sent this morning", daily, now.Add(-10 * time.Minute), false},
+		{"daily sent before this morning", daily, now.Add(-40 * time.Minute), true},
+		{"digests off", model.NotificationPreferences{}, time.Time{}, false},
+	} {
+		if got := test.prefs.DigestDue(test.last, now); got != test.want {
+			t.Errorf("%v: expected %v got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	start := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+	task, property := uuid.New(), uuid.New()
+	notis := []model.Notification{
+		{Category: model.TaskAlert, TaskId: task, Title: "Task started", CreatedAt: start},
+		{Category: model.TaskAlert, TaskId: task, Title: "Task completed", CreatedAt: start.Add(2 * time.Hour)},
+		{Category: model.TaskAlert, TaskId: task, Title: "Task paused", CreatedAt: start.Add(time.Hour)},
+		{Category: model.PropertyAlert, PropertyId: property, Title: "Inspection due", CreatedAt: start.Add(30 * time.Minute)},
+		{Category: model.GeneralAlert, Title: "Welcome", CreatedAt: start.Add(10 * time.Minute)},
+		{Category: model.GeneralAlert, Title: "Maintenance window", CreatedAt: start.Add(20 * time.Minute)},
+	}
+
+	items := Collapse(notis)
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items got %+v", items)
+	}
+
+	if items[0].Title != "Task completed" || items[0].Count != 3 {
+		t.Errorf("expected the task updates collapsed into the latest, got %+v", items[0])
+	}
+
+	if items[1].Title != "Inspection due" || items[3].Title != "Welcome" {
+		t.Errorf("expected newest first, got %+v", items)
+	}
+
+	digest := Digest(model.HourlyDigest, notis[0], items, len(notis))
+	if digest.Title != "Your hourly summary: 6 updates" || !strings.HasPrefix(digest.Message, "Task completed (3 updates)\n") {
+		t.Errorf("unexpected digest %q %q", digest.Title, digest.Message)
+	}
+}
@@ -0,0 +1,349 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/mail"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/sms"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultWorkers     = 2
+	defaultQueueSize   = 256
+	defaultMaxAttempts = 5
+	baseRetry          = 2 * time.Second
+	sendTimeout        = 45 * time.Second
+	maxReasonLength    = 255
+)
+
+var (
+	ErrOutboxFull   = errors.New("notification outbox is full")
+	ErrOutboxClosed = errors.New("notification outbox is closed")
+)
+
+// Recipients looks up how to reach the user a notification is for, dac.UserRepo satisfies it
+type Recipients interface {
+	GetContact(ctx context.Context, uid uuid.UUID) (model.User, model.CommunicationPreference, error)
+}
+
+// SmsLog records texts and opt outs, dac.SmsRepo satisfies it
+type SmsLog interface {
+	IsOptedOut(ctx context.Context, phone string) (bool, error)
+	OptOut(ctx context.Context, phone string, keyword string) error
+	InsertMessage(ctx context.Context, msg *model.SmsMessage) error
+	UpdateMessage(ctx context.Context, msg *model.SmsMessage) error
+}
+
+type delivery struct {
+	email        mail.Message
+	notification *model.Notification
+	channels     []model.NotificationChannel
+	// set once the recipient is known so a retry doesn't look them up again
+	user *model.User
+	text *model.SmsMessage
+}
+
+// Outbox sends notifications in the background so requests never wait on a mail server or
+// sms provider. Each notification goes out on the recipient's preferred channel, failed
+// sends are retried with a doubling wait and anything still queued when it stops is sent
+// before Stop returns unless its context ends first
+type Outbox struct {
+	mailer      mail.Mailer
+	texts       sms.Provider
+	users       Recipients
+	smsLog      SmsLog
+	queue       chan *delivery
+	workers     int
+	maxAttempts int
+	retryWait   time.Duration
+	logger      *crane.Zlogrus
+
+	mu     sync.RWMutex
+	closed bool
+	abort  chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewOutbox(mailer mail.Mailer, texts sms.Provider, users Recipients, smsLog SmsLog, logr *crane.Zlogrus) *Outbox {
+	return &Outbox{
+		mailer:      mailer,
+		texts:       texts,
+		users:       users,
+		smsLog:      smsLog,
+		queue:       make(chan *delivery, defaultQueueSize),
+		workers:     defaultWorkers,
+		maxAttempts: defaultMaxAttempts,
+		retryWait:   baseRetry,
+		logger:      logr,
+		abort:       make(chan struct{}),
+	}
+}
+
+func (o *Outbox) Start() {
+	ctx := auth.WithSystem(context.Background())
+	for range o.workers {
+		o.wg.Add(1)
+		go o.work(ctx)
+	}
+}
+
+// Send queues an email, it only fails when the queue is full or stopped
+func (o *Outbox) Send(msg mail.Message) error {
+	if len(msg.To) == 0 {
+		return mail.ErrNoRecipients
+	}
+	return o.enqueue(&delivery{email: msg})
+}
+
+// Notify queues the notification for its recipient on the channels their settings allow.
+// How they are reached is looked up when it is sent
+func (o *Outbox) Notify(n model.Notification, channels []model.NotificationChannel) error {
+	return o.enqueue(&delivery{notification: &n, channels: channels})
+}
+
+func (o *Outbox) enqueue(d *delivery) error {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if o.closed {
+		return ErrOutboxClosed
+	}
+
+	select {
+	case o.queue <- d:
+		return nil
+	default:
+		return ErrOutboxFull
+	}
+}
+
+func (o *Outbox) Stop(ctx context.Context) error {
+	o.mu.Lock()
+	if !o.closed {
+		o.closed = true
+		close(o.queue)
+	}
+	o.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		close(o.abort)
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (o *Outbox) work(ctx context.Context) {
+	defer o.wg.Done()
+	for d := range o.queue {
+		o.deliver(ctx, d)
+	}
+}
+
+func (o *Outbox) deliver(ctx context.Context, d *delivery) {
+	wait := o.retryWait
+	for attempt := 1; ; attempt++ {
+		err := o.attempt(ctx, d)
+		if err == nil {
+			return
+		}
+
+		fields := logrus.Fields{"msg": "failed to send notification", "attempt": attempt, "err": err}
+		if !retryable(err) || attempt >= o.maxAttempts {
+			fields["msg"] = "giving up on notification"
+			o.logger.LogFields(fields)
+			o.giveUp(ctx, d, err)
+			return
+		}
+		o.logger.LogFields(fields)
+
+		select {
+		case <-time.After(wait):
+			wait *= 2
+		case <-o.abort:
+			o.logger.LogFields(logrus.Fields{"msg": "outbox stopped before notification was sent", "err": err})
+			o.giveUp(ctx, d, err)
+			return
+		}
+	}
+}
+
+func (o *Outbox) attempt(ctx context.Context, d *delivery) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	if d.notification == nil {
+		return o.mailer.Send(ctx, d.email)
+	}
+
+	if d.user == nil {
+		if err := o.route(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	if d.text != nil {
+		return o.sendText(ctx, d)
+	}
+	return o.sendEmail(ctx, d)
+}
+
+// route picks the channel. A text is sent when it is the preferred channel or email isn't
+// allowed, and when the number can't be texted or has opted out it falls back to email
+func (o *Outbox) route(ctx context.Context, d *delivery) error {
+	n := d.notification
+	usr, pref, err := o.users.GetContact(ctx, n.Recipient())
+	if err != nil {
+		var noResults dac.ErrNoResults
+		if errors.As(err, &noResults) {
+			return mail.ErrNoRecipients
+		}
+		return err
+	}
+
+	if d.allows(model.SmsChannel) && (wantsText(pref) || !d.allows(model.EmailChannel)) {
+		phone, err := sms.Normalize(usr.Phone)
+		if err == nil {
+			optedOut, err := o.smsLog.IsOptedOut(ctx, phone)
+			if err != nil {
+				return err
+			}
+
+			if !optedOut {
+				text := &model.SmsMessage{
+					Sid:            uuid.New(),
+					LessorId:       n.LessorId,
+					UserId:         usr.Uid,
+					NotificationId: n.Id,
+					To:             phone,
+					Body:           sms.NotificationBody(*n),
+					Provider:       o.texts.Name(),
+					Status:         model.SmsQueued,
+					CreatedAt:      time.Now(),
+				}
+
+				if err = o.smsLog.InsertMessage(ctx, text); err != nil {
+					return err
+				}
+				d.text = text
+			}
+		}
+	}
+
+	d.user = &usr
+	return nil
+}
+
+func (o *Outbox) sendText(ctx context.Context, d *delivery) error {
+	text := d.text
+	text.Attempts++
+
+	res, err := o.texts.Send(ctx, sms.Message{To: text.To, Body: text.Body, Reference: text.Sid.String()})
+	if err != nil {
+		text.Error = reason(err)
+		if o.texts.OptedOut(err) {
+			// the carrier knows about a STOP we never saw, remember it and email instead
+			text.Status = model.SmsFailed
+			if optErr := o.smsLog.OptOut(ctx, text.To, "carrier"); optErr != nil {
+				o.logger.LogFields(logrus.Fields{"msg": "failed to record sms opt out", "err": optErr})
+			}
+			d.text = nil
+		}
+
+		if updateErr := o.smsLog.UpdateMessage(ctx, text); updateErr != nil {
+			o.logger.LogFields(logrus.Fields{"msg": "failed to record sms attempt", "sid": text.Sid, "err": updateErr})
+		}
+
+		if d.text == nil {
+			return o.sendEmail(ctx, d)
+		}
+		return err
+	}
+
+	text.ProviderId = res.ProviderId
+	text.Status = res.Status
+	text.Error = ""
+	if text.Status == "" {
+		text.Status = model.SmsQueued
+	}
+
+	if err = o.smsLog.UpdateMessage(ctx, text); err != nil {
+		// the text went out, a retry would send it twice
+		o.logger.LogFields(logrus.Fields{"msg": "failed to record sent sms", "sid": text.Sid, "err": err})
+	}
+	return nil
+}
+
+// sendEmail is skipped when the user's settings don't allow email
+func (o *Outbox) sendEmail(ctx context.Context, d *delivery) error {
+	if !d.allows(model.EmailChannel) {
+		return nil
+	}
+
+	msg, err := mail.NotificationMessage(*d.user, *d.notification)
+	if err != nil {
+		return err
+	}
+	return o.mailer.Send(ctx, msg)
+}
+
+// giveUp marks a text that never went out as failed so its status doesn't stay queued
+func (o *Outbox) giveUp(ctx context.Context, d *delivery, err error) {
+	if d.text == nil {
+		return
+	}
+
+	d.text.Status = model.SmsFailed
+	d.text.Error = reason(err)
+	if updateErr := o.smsLog.UpdateMessage(context.WithoutCancel(ctx), d.text); updateErr != nil {
+		o.logger.LogFields(logrus.Fields{"msg": "failed to record failed sms", "sid": d.text.Sid, "err": updateErr})
+	}
+}
+
+func (d *delivery) allows(channel model.NotificationChannel) bool {
+	return slices.Contains(d.channels, channel)
+}
+
+// wantsText is true for the phone preference too, there is no voice channel so a text is
+// the closest thing to a call
+func wantsText(pref model.CommunicationPreference) bool {
+	return pref == model.Text || pref == model.Phone
+}
+
+func retryable(err error) bool {
+	var failed sms.ErrProviderFailed
+	if errors.As(err, &failed) {
+		return failed.Retryable()
+	}
+
+	if errors.Is(err, sms.ErrNotConfigured) {
+		return false
+	}
+
+	return mail.Retryable(err)
+}
+
+func reason(err error) string {
+	if msg := []rune(err.Error()); len(msg) > maxReasonLength {
+		return string(msg[:maxReasonLength])
+	}
+	return err.Error()
+}
@@ -0,0 +1,220 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/mail"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/sms"
+	"github.com/google/uuid"
+)
+
+type flakyMailer struct {
+	mail.Memory
+	mu       *sync.Mutex
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyMailer) Send(ctx context.Context, msg mail.Message) error {
+	f.mu.Lock()
+	f.calls++
+	fail := f.calls <= f.failures
+	f.mu.Unlock()
+
+	if fail {
+		return f.err
+	}
+	return f.Memory.Send(ctx, msg)
+}
+
+type contact struct {
+	user model.User
+	pref model.CommunicationPreference
+}
+
+type contacts map[uuid.UUID]contact
+
+func (c contacts) GetContact(ctx context.Context, uid uuid.UUID) (model.User, model.CommunicationPreference, error) {
+	return c[uid].user, c[uid].pref, nil
+}
+
+type smsLog struct {
+	mu       sync.Mutex
+	optedOut map[string]bool
+	messages map[uuid.UUID]model.SmsMessage
+}
+
+func newSmsLog(optedOut ...string) *smsLog {
+	l := &smsLog{optedOut: make(map[string]bool), messages: make(map[uuid.UUID]model.SmsMessage)}
+	for _, phone := range optedOut {
+		l.optedOut[phone] = true
+	}
+	return l
+}
+
+func (l *smsLog) IsOptedOut(ctx context.Context, phone string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.optedOut[phone], nil
+}
+
+func (l *smsLog) OptOut(ctx context.Context, phone string, keyword string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.optedOut[phone] = true
+	return nil
+}
+
+func (l *smsLog) InsertMessage(ctx context.Context, msg *model.SmsMessage) error {
+	return l.UpdateMessage(ctx, msg)
+}
+
+func (l *smsLog) UpdateMessage(ctx context.Context, msg *model.SmsMessage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages[msg.Sid] = *msg
+	return nil
+}
+
+func testLogger(t *testing.T) *crane.Zlogrus {
+	return crane.NewLogger(crane.NewLogFile(crane.WithFilename(filepath.Join(t.TempDir(), "notify.log"))))
+}
+
+func TestOutboxRetries(t *testing.T) {
+	lessor := uuid.New()
+	for _, test := range []struct {
+		name     string
+		failures int
+		err      error
+		sent     int
+		calls    int
+	}{
+		{"retries until sent", 2, errors.New("connection reset"), 1, 3},
+		{"gives up after max attempts", 10, errors.New("connection reset"), 0, 3},
+		{"permanent failure is not retried", 10, &textproto.Error{Code: 550, Msg: "no such user"}, 0, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mailer := &flakyMailer{Memory: mail.NewMemory(), mu: &sync.Mutex{}, failures: test.failures, err: test.err}
+			users := contacts{lessor: {user: model.User{FirstName: "Ana", Email: "ana@lessor.test"}, pref: model.Email}}
+			outbox := NewOutbox(mailer, sms.NewFake(""), users, newSmsLog(), testLogger(t))
+			outbox.maxAttempts, outbox.retryWait = 3, time.Millisecond
+			outbox.Start()
+
+			if err := outbox.Notify(model.Notification{LessorId: lessor, Title: "Rent is late"}, model.NotificationChannels); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := outbox.Stop(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if mailer.calls != test.calls || len(mailer.Sent()) != test.sent {
+				t.Errorf("expected %v calls and %v sent, got %v and %v", test.calls, test.sent, mailer.calls, len(mailer.Sent()))
+			}
+
+			if err := outbox.Send(mail.Message{To: []string{"ana@lessor.test"}}); !errors.Is(err, ErrOutboxClosed) {
+				t.Errorf("expected a stopped outbox to refuse mail, got %v", err)
+			}
+		})
+	}
+}
+
+// Send is the outbox's plain email path from before notifications were routed by channel
+func TestOutboxSend(t *testing.T) {
+	mailer := mail.NewMemory()
+	outbox := NewOutbox(mailer, sms.NewFake(""), contacts{}, newSmsLog(), testLogger(t))
+	outbox.queue = make(chan *delivery, 1)
+
+	if err := outbox.Send(mail.Message{}); !errors.Is(err, mail.ErrNoRecipients) {
+		t.Errorf("expected a message without recipients to be refused, got %v", err)
+	}
+
+	if err := outbox.Send(mail.Message{To: []string{"ana@lessor.test"}, Subject: "Lease renewal"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := outbox.Send(mail.Message{To: []string{"ana@lessor.test"}}); !errors.Is(err, ErrOutboxFull) {
+		t.Errorf("expected a full queue to refuse mail, got %v", err)
+	}
+
+	outbox.Start()
+	if err := outbox.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if sent := mailer.Sent(); len(sent) != 1 || sent[0].Subject != "Lease renewal" {
+		t.Errorf("expected the queued message to be sent before stopping, got %+v", sent)
+	}
+}
+
+func TestOutboxRouting(t *testing.T) {
+	texter, emailer, blocked, opted, quiet := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	users := contacts{
+		texter:  {user: model.User{Uid: texter, Email: "t@lessor.test", Phone: "555-000-0001"}, pref: model.Text},
+		emailer: {user: model.User{Uid: emailer, Email: "e@lessor.test", Phone: "555-000-0002"}, pref: model.Email},
+		blocked: {user: model.User{Uid: blocked, Email: "b@lessor.test", Phone: "555-000-0003"}, pref: model.Phone},
+		opted:   {user: model.User{Uid: opted, Email: "o@lessor.test", Phone: "555-000-0004"}, pref: model.Text},
+		quiet:   {user: model.User{Uid: quiet, Email: "q@lessor.test", Phone: "555-000-0005"}, pref: model.Text},
+	}
+
+	texts := sms.NewFake("")
+	// the carrier has a STOP for this number that never reached us
+	texts.Reply("+15550000003", "STOP")
+	log := newSmsLog("+15550000004")
+	mailer := mail.NewMemory()
+
+	outbox := NewOutbox(mailer, texts, users, log, testLogger(t))
+	outbox.retryWait = time.Millisecond
+	outbox.Start()
+
+	for _, uid := range []uuid.UUID{texter, emailer, blocked, opted} {
+		if err := outbox.Notify(model.Notification{LessorId: uid, UserId: uid, Title: "Task finished"}, model.NotificationChannels); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// texts are turned off in their settings so the preference for them is ignored
+	if err := outbox.Notify(model.Notification{LessorId: quiet, UserId: quiet, Title: "Task finished"}, []model.NotificationChannel{model.EmailChannel}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := outbox.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	sentTexts := texts.Sent()
+	if len(sentTexts) != 1 || sentTexts[0].To != "+15550000001" {
+		t.Errorf("expected one text to the texter, got %+v", sentTexts)
+	}
+
+	emailed := make(map[string]bool)
+	for _, msg := range mailer.Sent() {
+		emailed[msg.To[0]] = true
+	}
+	for _, to := range []string{"e@lessor.test", "b@lessor.test", "o@lessor.test", "q@lessor.test"} {
+		if !emailed[to] {
+			t.Errorf("expected %v to be emailed, got %v", to, emailed)
+		}
+	}
+
+	if !log.optedOut["+15550000003"] {
+		t.Errorf("expected the carrier opt out to be recorded")
+	}
+
+	statuses := make(map[string]model.SmsStatus)
+	for _, msg := range log.messages {
+		statuses[msg.To] = msg.Status
+	}
+	if statuses["+15550000001"] != model.SmsSent || statuses["+15550000003"] != model.SmsFailed {
+		t.Errorf("expected the texter's text sent and the blocked one failed, got %v", statuses)
+	}
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+// Preferences looks up a user's notification settings, dac.NotificationSettingsRepo satisfies it
+type Preferences interface {
+	Preferences(ctx context.Context, uid uuid.UUID) (model.NotificationPreferences, error)
+}
+
+// Delivery is where the recipient's settings let a notification go right now, no channels
+// means it is dropped and Digest means the external channels wait for the next digest
+type Delivery struct {
+	Channels []model.NotificationChannel
+	Digest   bool
+}
+
+// Plan works out the delivery for a new notification
+func Plan(ctx context.Context, prefs Preferences, n model.Notification) (Delivery, error) {
+	p, err := prefs.Preferences(ctx, n.Recipient())
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	d := Delivery{Channels: p.Channels(n, time.Now())}
+	// a digest is read from the app so only notifications that are kept there can wait for one
+	d.Digest = p.Batches(n) && d.InApp() && External(d.Channels)
+	return d, nil
+}
+
+// InApp reports whether the notification is kept in the app
+func (d Delivery) InApp() bool {
+	return slices.Contains(d.Channels, model.InAppChannel)
+}
+
+// Sends reports whether the notification goes out now
+func (d Delivery) Sends() bool {
+	return !d.Digest && External(d.Channels)
+}
+
+// External reports whether the notification goes anywhere besides the app
+func External(channels []model.NotificationChannel) bool {
+	for _, c := range channels {
+		if c != model.InAppChannel {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+)
+
+func TestChannels(t *testing.T) {
+	// noon in new york, inside the working day quiet hours
+	noon := time.Date(2026, time.January, 5, 17, 0, 0, 0, time.UTC)
+	workday := &model.QuietHours{Start: "09:00", End: "17:00"}
+	prefs := model.NotificationPreferences{
+		Timezone: "America/New_York",
+		Categories: map[model.NotificationType]model.CategoryPreference{
+			model.TaskAlert:     {Channels: []model.NotificationChannel{model.InAppChannel, model.SmsChannel}, MinPriority: model.High},
+			model.PropertyAlert: {QuietHours: workday},
+			model.TenantAlert:   {Channels: []model.NotificationChannel{model.EmailChannel}, QuietHours: workday},
+		},
+	}
+
+	for _, test := range []struct {
+		name     string
+		n        model.Notification
+		want     []model.NotificationChannel
+		external bool
+	}{
+		{"unset category gets everything", model.Notification{Category: model.GeneralAlert}, model.NotificationChannels, true},
+		{"below min priority is dropped", model.Notification{Category: model.TaskAlert, Priority: model.Medium}, nil, false},
+		{"unset priority counts as medium", model.Notification{Category: model.TaskAlert}, nil, false},
+		{"only enabled channels", model.Notification{Category: model.TaskAlert, Priority: model.High}, []model.NotificationChannel{model.InAppChannel, model.SmsChannel}, true},
+		{"quiet hours keep in app", model.Notification{Category: model.PropertyAlert, Priority: model.High}, []model.NotificationChannel{model.InAppChannel}, false},
+		{"quiet hours without in app drop it", model.Notification{Category: model.TenantAlert}, nil, false},
+		{"immediate ignores quiet hours", model.Notification{Category: model.TenantAlert, Priority: model.Immediate}, []model.NotificationChannel{model.EmailChannel}, true},
+	} {
+		got := prefs.Channels(test.n, noon)
+
+		if !slices.Equal(got, test.want) || External(got) != test.external {
+			t.Errorf("%v: expected %v external %v, got %v", test.name, test.want, test.external, got)
+		}
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	overnight := model.QuietHours{Start: "22:00", End: "07:00"}
+	for clock, want := range map[string]bool{"23:30": true, "03:00": true, "07:00": false, "12:00": false, "22:00": true} {
+		at, _ := time.Parse("15:04", clock)
+		if overnight.Contains(at) != want {
+			t.Errorf("%v expected quiet %v", clock, want)
+		}
+	}
+
+	// the quiet hours are read in the user's timezone, 9pm utc is 4pm in new york
+	prefs := model.NotificationPreferences{Categories: map[model.NotificationType]model.CategoryPreference{model.GeneralAlert: {QuietHours: &model.QuietHours{Start: "20:00", End: "23:00"}}}}
+	evening := time.Date(2026, time.January, 5, 21, 0, 0, 0, time.UTC)
+	if got := prefs.Channels(model.Notification{Category: model.GeneralAlert}, evening); len(got) != 1 {
+		t.Errorf("expected utc quiet hours without a timezone, got %v", got)
+	}
+
+	prefs.Timezone = "America/New_York"
+	if got := prefs.Channels(model.Notification{Category: model.GeneralAlert}, evening); len(got) != len(model.NotificationChannels) {
+		t.Errorf("expected new york to be outside quiet hours, got %v", got)
+	}
+}
@@ -0,0 +1,190 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	fakeSignatureHeader = "X-Fake-Signature"
+	// FakeDeclinedSource is a source id the fake always declines
+	FakeDeclinedSource = "fake-declined"
+	// FakePendingSource is a source id the fake leaves pending until it is settled
+	FakePendingSource = "fake-pending"
+)
+
+type fakePayment struct {
+	id        string
+	reference string
+	amount    decimal.Decimal
+	refunded  decimal.Decimal
+	status    model.PaymentStatus
+}
+
+// Fake is an in memory processor for tests and local development, charges complete
+// straight away unless they use one of the fake source ids
+type Fake struct {
+	secret   string
+	mu       *sync.Mutex
+	payments map[string]*fakePayment
+	keys     map[string]string
+}
+
+func NewFake(secret string) Fake {
+	return Fake{
+		secret:   secret,
+		mu:       &sync.Mutex{},
+		payments: make(map[string]*fakePayment),
+		keys:     make(map[string]string),
+	}
+}
+
+func (f Fake) Name() string {
+	return "fake"
+}
+
+func (f Fake) CreateCharge(ctx context.Context, req ChargeRequest) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if id, ok := f.keys[req.IdempotencyKey]; ok {
+		return Result{ProcessorId: id, Status: f.payments[id].status}, nil
+	}
+
+	pmt := &fakePayment{id: uuid.NewString(), reference: req.Reference, amount: req.Amount, status: model.Accepted}
+	switch req.SourceId {
+	case FakeDeclinedSource:
+		pmt.status = model.Rejected
+	case FakePendingSource:
+		pmt.status = model.PaymentPending
+	}
+
+	f.payments[pmt.id] = pmt
+	f.keys[req.IdempotencyKey] = pmt.id
+	return Result{ProcessorId: pmt.id, Status: pmt.status}, nil
+}
+
+func (f Fake) Refund(ctx context.Context, processorId string, idempotencyKey string, amount decimal.Decimal, currency string) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pmt, ok := f.payments[processorId]
+	if !ok {
+		return Result{}, ErrProcessorFailed{Processor: f.Name(), Code: "NOT_FOUND", Err: fmt.Errorf("payment %v does not exist", processorId)}
+	}
+
+	if _, ok := f.keys[idempotencyKey]; ok {
+		return Result{ProcessorId: pmt.id, Status: pmt.status}, nil
+	}
+
+	if pmt.status != model.Accepted || pmt.refunded.Add(amount).GreaterThan(pmt.amount) {
+		return Result{}, ErrProcessorFailed{Processor: f.Name(), Code: "REFUND_DECLINED", Err: fmt.Errorf("payment %v can not be refunded", processorId)}
+	}
+
+	f.keys[idempotencyKey] = pmt.id
+	pmt.refunded = pmt.refunded.Add(amount)
+	pmt.status = model.PaymentRefunded
+	return Result{ProcessorId: pmt.id, Status: pmt.status}, nil
+}
+
+func (f Fake) FetchStatus(ctx context.Context, processorId string) (model.PaymentStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pmt, ok := f.payments[processorId]
+	if !ok {
+		return "", ErrProcessorFailed{Processor: f.Name(), Code: "NOT_FOUND", Err: fmt.Errorf("payment %v does not exist", processorId)}
+	}
+
+	return pmt.status, nil
+}
+
+func (f Fake) FindByReference(ctx context.Context, account string, reference string, createdAt time.Time) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, pmt := range f.payments {
+		if pmt.reference == reference {
+			return Result{ProcessorId: pmt.id, Status: pmt.status}, nil
+		}
+	}
+
+	return Result{}, ErrPaymentNotFound
+}
+
+// Settle moves a pending payment on and returns the webhook the fake would have sent, a
+// refund is always for the whole payment
+func (f Fake) Settle(processorId string, status model.PaymentStatus) ([]byte, http.Header, error) {
+	var refunded decimal.Decimal
+	f.mu.Lock()
+	pmt, ok := f.payments[processorId]
+	if ok {
+		pmt.status = status
+		if status == model.PaymentRefunded {
+			pmt.refunded = pmt.amount
+			refunded = pmt.refunded
+		}
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		return nil, nil, fmt.Errorf("payment %v does not exist", processorId)
+	}
+
+	body, err := json.Marshal(fakeEvent{Id: uuid.NewString(), ProcessorId: pmt.id, Reference: pmt.reference, Status: status, Amount: refunded})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set(fakeSignatureHeader, f.sign(body))
+	return body, header, nil
+}
+
+func (f Fake) VerifyWebhook(header http.Header, body []byte) error {
+	signature, err := hex.DecodeString(header.Get(fakeSignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return ErrInvalidSignature
+	}
+
+	expected, _ := hex.DecodeString(f.sign(body))
+	if !hmac.Equal(signature, expected) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+type fakeEvent struct {
+	Id          string              `json:"id"`
+	ProcessorId string              `json:"processorId"`
+	Reference   string              `json:"reference"`
+	Status      model.PaymentStatus `json:"status"`
+	Amount      decimal.Decimal     `json:"amount"`
+}
+
+func (f Fake) ParseWebhook(body []byte) (Event, error) {
+	var e fakeEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return Event{}, fmt.Errorf("failed to decode fake webhook %v", err)
+	}
+
+	return Event{Id: e.Id, ProcessorId: e.ProcessorId, Reference: e.Reference, Status: e.Status, Amount: e.Amount}, nil
+}
+
+func (f Fake) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(f.secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
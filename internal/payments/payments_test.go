@@ -0,0 +1,222 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+func TestSquareVerifyWebhook(t *testing.T) {
+	square := NewSquare(SquareConfig{WebhookKey: "key", WebhookUrl: "https://lessor.test/webhooks/payments"})
+	body := []byte(`{"event_id":"evt"}`)
+
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte("https://lessor.test/webhooks/payments"))
+	mac.Write(body)
+	header := http.Header{}
+	header.Set(squareSignatureHeader, base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	if err := square.VerifyWebhook(header, body); err != nil {
+		t.Errorf("expected a valid signature got %v", err)
+	}
+
+	if err := square.VerifyWebhook(header, []byte(`{"event_id":"other"}`)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected a changed body to fail got %v", err)
+	}
+
+	if err := square.VerifyWebhook(http.Header{}, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected a missing signature to fail got %v", err)
+	}
+}
+
+func TestSquareParseWebhook(t *testing.T) {
+	square := NewSquare(SquareConfig{})
+	for _, test := range []struct {
+		name      string
+		body      string
+		processor string
+		reference string
+		status    model.PaymentStatus
+		amount    string
+	}{
+		{
+			"completed payment",
+			`{"event_id":"1","type":"payment.updated","data":{"object":{"payment":{"id":"sq1","status":"COMPLETED","reference_id":"ref"}}}}`,
+			"sq1", "ref", model.Accepted, "0",
+		},
+		{
+			"failed payment",
+			`{"event_id":"2","type":"payment.updated","data":{"object":{"payment":{"id":"sq2","status":"FAILED"}}}}`,
+			"sq2", "", model.Rejected, "0",
+		},
+		{
+			"completed refund",
+			`{"event_id":"3","type":"refund.updated","data":{"object":{"refund":{"id":"r1","payment_id":"sq3","status":"COMPLETED","amount_money":{"amount":125051,"currency":"USD"}}}}}`,
+			"sq3", "", model.PaymentRefunded, "1250.51",
+		},
+		{
+			"pending refund",
+			`{"event_id":"4","type":"refund.created","data":{"object":{"refund":{"id":"r2","payment_id":"sq4","status":"PENDING"}}}}`,
+			"sq4", "", "", "0",
+		},
+		{
+			"unrelated event",
+			`{"event_id":"5","type":"customer.created","data":{"object":{}}}`,
+			"", "", "", "0",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			event, err := square.ParseWebhook([]byte(test.body))
+			if err != nil {
+				t.Fatalf("failed to parse webhook %v", err)
+			}
+
+			if event.ProcessorId != test.processor || event.Reference != test.reference || event.Status != test.status {
+				t.Errorf("expected %v %v %v got %+v", test.processor, test.reference, test.status, event)
+			}
+
+			// a refund's amount is what decides whether it reverses the whole payment
+			if !event.Amount.Equal(decimal.RequireFromString(test.amount)) {
+				t.Errorf("expected a refunded amount of %v got %v", test.amount, event.Amount)
+			}
+		})
+	}
+}
+
+func TestSquareCreateCharge(t *testing.T) {
+	var sent map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("expected the access token to be sent")
+		}
+
+		json.NewDecoder(r.Body).Decode(&sent)
+		if sent["source_id"] == "declined" {
+			w.WriteHeader(http.StatusPaymentRequired)
+			w.Write([]byte(`{"errors":[{"category":"PAYMENT_METHOD_ERROR","code":"CARD_DECLINED","detail":"declined"}]}`))
+			return
+		}
+		w.Write([]byte(`{"payment":{"id":"sq1","status":"COMPLETED"}}`))
+	}))
+	defer server.Close()
+
+	square := NewSquare(SquareConfig{BaseUrl: server.URL, AccessToken: "token"})
+	res, err := square.CreateCharge(context.Background(), ChargeRequest{
+		IdempotencyKey: "key",
+		SourceId:       "cnon",
+		Amount:         decimal.RequireFromString("1250.505"),
+		Account:        "loc",
+	})
+
+	if err != nil || res.ProcessorId != "sq1" || res.Status != model.Accepted {
+		t.Fatalf("expected an accepted payment got %+v %v", res, err)
+	}
+
+	money := sent["amount_money"].(map[string]interface{})
+	if money["amount"] != float64(125051) || money["currency"] != "USD" {
+		t.Errorf("expected 125051 USD cents got %v", money)
+	}
+
+	_, err = square.CreateCharge(context.Background(), ChargeRequest{IdempotencyKey: "key2", SourceId: "declined"})
+	var failed ErrProcessorFailed
+	if !errors.As(err, &failed) || failed.Code != "CARD_DECLINED" {
+		t.Errorf("expected a declined card got %v", err)
+	}
+}
+
+func TestSquareFindByReference(t *testing.T) {
+	created := time.Date(2025, time.March, 3, 8, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("location_id") != "loc" || query.Get("begin_time") != "2025-03-03T07:00:00Z" {
+			t.Errorf("expected the location's payments around the payment to be listed got %v", query)
+		}
+
+		if query.Get("cursor") == "" {
+			w.Write([]byte(`{"payments":[{"id":"sq1","status":"COMPLETED","reference_id":"other"}],"cursor":"next"}`))
+			return
+		}
+		w.Write([]byte(`{"payments":[{"id":"sq2","status":"COMPLETED","reference_id":"ref"}]}`))
+	}))
+	defer server.Close()
+
+	square := NewSquare(SquareConfig{BaseUrl: server.URL, AccessToken: "token"})
+	res, err := square.FindByReference(context.Background(), "loc", "ref", created)
+	if err != nil || res.ProcessorId != "sq2" || res.Status != model.Accepted {
+		t.Fatalf("expected the accepted payment on the second page got %+v %v", res, err)
+	}
+
+	if _, err = square.FindByReference(context.Background(), "loc", "missing", created); !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("expected an unknown reference to be not found got %v", err)
+	}
+}
+
+func TestFakeProcessor(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake("secret")
+	amount := decimal.RequireFromString("100")
+
+	first, _ := fake.CreateCharge(ctx, ChargeRequest{IdempotencyKey: "a", SourceId: "card", Amount: amount})
+	again, _ := fake.CreateCharge(ctx, ChargeRequest{IdempotencyKey: "a", SourceId: "card", Amount: amount})
+	if first.Status != model.Accepted || first.ProcessorId != again.ProcessorId {
+		t.Errorf("expected a retried charge to return the first payment got %+v %+v", first, again)
+	}
+
+	declined, _ := fake.CreateCharge(ctx, ChargeRequest{IdempotencyKey: "b", SourceId: FakeDeclinedSource, Amount: amount})
+	if declined.Status != model.Rejected {
+		t.Errorf("expected a declined charge got %v", declined.Status)
+	}
+
+	pending, _ := fake.CreateCharge(ctx, ChargeRequest{IdempotencyKey: "c", SourceId: FakePendingSource, Amount: amount, Reference: "ref"})
+	body, header, err := fake.Settle(pending.ProcessorId, model.Accepted)
+	if err != nil {
+		t.Fatalf("failed to settle payment %v", err)
+	}
+
+	if err = fake.VerifyWebhook(header, body); err != nil {
+		t.Errorf("expected the fake's webhook to verify got %v", err)
+	}
+
+	event, _ := fake.ParseWebhook(body)
+	if event.Reference != "ref" || event.Status != model.Accepted {
+		t.Errorf("expected an accepted event for ref got %+v", event)
+	}
+
+	if _, err = fake.Refund(ctx, first.ProcessorId, "r", amount.Add(amount), ""); err == nil {
+		t.Error("expected refunding more than was paid to fail")
+	}
+
+	refund, err := fake.Refund(ctx, first.ProcessorId, "r", amount, "")
+	if err != nil || refund.Status != model.PaymentRefunded {
+		t.Errorf("expected a refunded payment got %+v %v", refund, err)
+	}
+}
+
+func TestStatusAdvances(t *testing.T) {
+	for _, test := range []struct {
+		from, to model.PaymentStatus
+		advances bool
+	}{
+		{model.PaymentPending, model.Accepted, true},
+		{model.PaymentPending, model.Rejected, true},
+		{model.Accepted, model.PaymentRefunded, true},
+		{model.Accepted, model.Accepted, false},
+		{model.Accepted, model.PaymentPending, false},
+		{model.Rejected, model.Accepted, false},
+		{model.PaymentRefunded, model.Accepted, false},
+	} {
+		if got := test.from.Advances(test.to); got != test.advances {
+			t.Errorf("%v to %v expected %v got %v", test.from, test.to, test.advances, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+const defaultCurrency = "USD"
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	ErrNotConfigured    = errors.New("payment processor is not configured")
+	ErrPaymentNotFound  = errors.New("processor has no payment for the reference")
+)
+
+// ErrProcessorFailed is returned when the processor refuses or can't complete a request,
+// Code is the processor's own error code when it sent one
+type ErrProcessorFailed struct {
+	Processor string
+	Code      string
+	Err       error
+}
+
+func (e ErrProcessorFailed) Error() string {
+	return fmt.Sprintf("%v request failed %v: %v", e.Processor, e.Code, e.Err)
+}
+
+func (e ErrProcessorFailed) Unwrap() error {
+	return e.Err
+}
+
+// ChargeRequest takes a payment from the card or bank token in SourceId. The key makes
+// retries safe and Reference is sent back on webhooks so the payment can be found again
+type ChargeRequest struct {
+	IdempotencyKey string
+	SourceId       string
+	Amount         decimal.Decimal
+	Currency       string
+	Account        string
+	Reference      string
+	Note           string
+}
+
+// Result is where the processor says a payment or refund stands
+type Result struct {
+	ProcessorId string
+	Status      model.PaymentStatus
+}
+
+// Event is a payment status change sent by the processor, events for anything other than
+// payments and refunds come back with no status and are ignored. Amount is what a refund
+// gave back, it is zero for payment events
+type Event struct {
+	Id          string
+	ProcessorId string
+	Reference   string
+	Status      model.PaymentStatus
+	Amount      decimal.Decimal
+}
+
+// PaymentProcessor takes payments on behalf of lessors that have payment integration
+// turned on, amounts are decimals and converted to the processor's units by each client
+type PaymentProcessor interface {
+	Name() string
+	CreateCharge(ctx context.Context, req ChargeRequest) (Result, error)
+	Refund(ctx context.Context, processorId string, idempotencyKey string, amount decimal.Decimal, currency string) (Result, error)
+	FetchStatus(ctx context.Context, processorId string) (model.PaymentStatus, error)
+	FindByReference(ctx context.Context, account string, reference string, createdAt time.Time) (Result, error)
+	VerifyWebhook(header http.Header, body []byte) error
+	ParseWebhook(body []byte) (Event, error)
+}
+
+// NewProcessor picks the processor from PAYMENT_PROCESSOR, square is the default and
+// fake is only meant for local development
+func NewProcessor() PaymentProcessor {
+	switch strings.ToLower(os.Getenv("PAYMENT_PROCESSOR")) {
+	case "fake":
+		log.Printf("WARNING using the fake payment processor no money will move")
+		return NewFake(os.Getenv("PAYMENT_WEBHOOK_KEY"))
+	default:
+		square := NewSquare(SquareConfig{
+			BaseUrl:     os.Getenv("SQUARE_BASE_URL"),
+			AccessToken: os.Getenv("SQUARE_ACCESS_TOKEN"),
+			WebhookKey:  os.Getenv("SQUARE_WEBHOOK_KEY"),
+			WebhookUrl:  os.Getenv("SQUARE_WEBHOOK_URL"),
+		})
+		if square.cfg.AccessToken == "" {
+			log.Printf("WARNING square access token is not set processor payments will fail")
+		}
+		return square
+	}
+}
+
+func currencyOrDefault(currency string) string {
+	if currency == "" {
+		return defaultCurrency
+	}
+	return strings.ToUpper(currency)
+}
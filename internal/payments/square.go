@@ -0,0 +1,305 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	squareBaseUrl         = "https://connect.squareup.com"
+	squareVersion         = "2025-01-23"
+	squareSignatureHeader = "X-Square-Hmacsha256-Signature"
+	squareTimeout         = 20 * time.Second
+	// squareSearchWindow is how far either side of when a payment was recorded square is
+	// searched for it, the charge is sent straight after the payment is recorded
+	squareSearchWindow = time.Hour
+)
+
+// SquareConfig holds the api credentials, the webhook url has to be exactly the one
+// registered with square since it is part of the signed payload
+type SquareConfig struct {
+	BaseUrl     string
+	AccessToken string
+	WebhookKey  string
+	WebhookUrl  string
+}
+
+type Square struct {
+	cfg    SquareConfig
+	client *http.Client
+}
+
+func NewSquare(cfg SquareConfig) Square {
+	if cfg.BaseUrl == "" {
+		cfg.BaseUrl = squareBaseUrl
+	}
+
+	return Square{
+		cfg:    cfg,
+		client: &http.Client{Timeout: squareTimeout},
+	}
+}
+
+func (s Square) Name() string {
+	return "square"
+}
+
+type squareMoney struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type squarePayment struct {
+	Id          string `json:"id"`
+	Status      string `json:"status"`
+	ReferenceId string `json:"reference_id"`
+}
+
+type squareRefund struct {
+	Id          string      `json:"id"`
+	Status      string      `json:"status"`
+	PaymentId   string      `json:"payment_id"`
+	AmountMoney squareMoney `json:"amount_money"`
+}
+
+type squareError struct {
+	Category string `json:"category"`
+	Code     string `json:"code"`
+	Detail   string `json:"detail"`
+}
+
+type squareResponse struct {
+	Payment  *squarePayment  `json:"payment"`
+	Payments []squarePayment `json:"payments"`
+	Refund   *squareRefund   `json:"refund"`
+	Errors   []squareError   `json:"errors"`
+	Cursor   string          `json:"cursor"`
+}
+
+func (s Square) CreateCharge(ctx context.Context, req ChargeRequest) (Result, error) {
+	body := map[string]interface{}{
+		"idempotency_key": req.IdempotencyKey,
+		"source_id":       req.SourceId,
+		"amount_money":    squareMoney{Amount: toMinorUnits(req.Amount), Currency: currencyOrDefault(req.Currency)},
+		"location_id":     req.Account,
+		"reference_id":    req.Reference,
+		"autocomplete":    true,
+	}
+
+	if req.Note != "" {
+		body["note"] = req.Note
+	}
+
+	res, err := s.do(ctx, http.MethodPost, "/v2/payments", body)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if res.Payment == nil {
+		return Result{}, ErrProcessorFailed{Processor: s.Name(), Err: fmt.Errorf("response did not include a payment")}
+	}
+
+	return Result{ProcessorId: res.Payment.Id, Status: squarePaymentStatus(res.Payment.Status)}, nil
+}
+
+func (s Square) Refund(ctx context.Context, processorId string, idempotencyKey string, amount decimal.Decimal, currency string) (Result, error) {
+	body := map[string]interface{}{
+		"idempotency_key": idempotencyKey,
+		"payment_id":      processorId,
+		"amount_money":    squareMoney{Amount: toMinorUnits(amount), Currency: currencyOrDefault(currency)},
+	}
+
+	res, err := s.do(ctx, http.MethodPost, "/v2/refunds", body)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if res.Refund == nil {
+		return Result{}, ErrProcessorFailed{Processor: s.Name(), Err: fmt.Errorf("response did not include a refund")}
+	}
+
+	return Result{ProcessorId: res.Refund.PaymentId, Status: squareRefundStatus(res.Refund.Status)}, nil
+}
+
+func (s Square) FetchStatus(ctx context.Context, processorId string) (model.PaymentStatus, error) {
+	res, err := s.do(ctx, http.MethodGet, "/v2/payments/"+url.PathEscape(processorId), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if res.Payment == nil {
+		return "", ErrProcessorFailed{Processor: s.Name(), Err: fmt.Errorf("response did not include a payment")}
+	}
+
+	return squarePaymentStatus(res.Payment.Status), nil
+}
+
+// FindByReference looks for a payment whose charge request never got a response, square
+// can't search by reference so the location's payments around the time it was recorded are
+// listed and matched on it
+func (s Square) FindByReference(ctx context.Context, account string, reference string, createdAt time.Time) (Result, error) {
+	query := url.Values{}
+	query.Set("location_id", account)
+	query.Set("begin_time", createdAt.Add(-squareSearchWindow).UTC().Format(time.RFC3339))
+	query.Set("end_time", createdAt.Add(squareSearchWindow).UTC().Format(time.RFC3339))
+
+	for {
+		res, err := s.do(ctx, http.MethodGet, "/v2/payments?"+query.Encode(), nil)
+		if err != nil {
+			return Result{}, err
+		}
+
+		for _, pmt := range res.Payments {
+			if pmt.ReferenceId == reference {
+				return Result{ProcessorId: pmt.Id, Status: squarePaymentStatus(pmt.Status)}, nil
+			}
+		}
+
+		if res.Cursor == "" {
+			return Result{}, ErrPaymentNotFound
+		}
+		query.Set("cursor", res.Cursor)
+	}
+}
+
+// VerifyWebhook checks the base64 hmac square sends, it is signed over the notification
+// url followed by the raw body
+func (s Square) VerifyWebhook(header http.Header, body []byte) error {
+	if s.cfg.WebhookKey == "" {
+		return ErrNotConfigured
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(header.Get(squareSignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.cfg.WebhookKey))
+	mac.Write([]byte(s.cfg.WebhookUrl))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (s Square) ParseWebhook(body []byte) (Event, error) {
+	var notice struct {
+		EventId string `json:"event_id"`
+		Type    string `json:"type"`
+		Data    struct {
+			Object squareResponse `json:"object"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &notice); err != nil {
+		return Event{}, fmt.Errorf("failed to decode square webhook %v", err)
+	}
+
+	event := Event{Id: notice.EventId}
+	switch {
+	case strings.HasPrefix(notice.Type, "payment.") && notice.Data.Object.Payment != nil:
+		pmt := notice.Data.Object.Payment
+		event.ProcessorId = pmt.Id
+		event.Reference = pmt.ReferenceId
+		event.Status = squarePaymentStatus(pmt.Status)
+	case strings.HasPrefix(notice.Type, "refund.") && notice.Data.Object.Refund != nil:
+		// only a finished refund changes the payment, pending ones are left alone
+		refund := notice.Data.Object.Refund
+		event.ProcessorId = refund.PaymentId
+		event.Amount = fromMinorUnits(refund.AmountMoney.Amount)
+		if status := squareRefundStatus(refund.Status); status == model.PaymentRefunded {
+			event.Status = status
+		}
+	}
+
+	return event, nil
+}
+
+func (s Square) do(ctx context.Context, method, path string, body interface{}) (squareResponse, error) {
+	if s.cfg.AccessToken == "" {
+		return squareResponse{}, ErrNotConfigured
+	}
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return squareResponse{}, fmt.Errorf("failed to encode square request %v", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.BaseUrl+path, &payload)
+	if err != nil {
+		return squareResponse{}, fmt.Errorf("failed to create square request %v", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.cfg.AccessToken)
+	req.Header.Set("Square-Version", squareVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return squareResponse{}, ErrProcessorFailed{Processor: s.Name(), Err: err}
+	}
+	defer res.Body.Close()
+
+	var decoded squareResponse
+	if err = json.NewDecoder(res.Body).Decode(&decoded); err != nil {
+		return squareResponse{}, ErrProcessorFailed{Processor: s.Name(), Err: fmt.Errorf("failed to decode response %v", err)}
+	}
+
+	if len(decoded.Errors) > 0 {
+		e := decoded.Errors[0]
+		return squareResponse{}, ErrProcessorFailed{Processor: s.Name(), Code: e.Code, Err: fmt.Errorf("%v %v", e.Category, e.Detail)}
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return squareResponse{}, ErrProcessorFailed{Processor: s.Name(), Err: fmt.Errorf("unexpected status %v", res.Status)}
+	}
+
+	return decoded, nil
+}
+
+func squarePaymentStatus(status string) model.PaymentStatus {
+	switch status {
+	case "COMPLETED":
+		return model.Accepted
+	case "CANCELED", "FAILED":
+		return model.Rejected
+	default:
+		return model.PaymentPending
+	}
+}
+
+func squareRefundStatus(status string) model.PaymentStatus {
+	switch status {
+	case "COMPLETED":
+		return model.PaymentRefunded
+	case "REJECTED", "FAILED":
+		return model.Rejected
+	default:
+		return model.PaymentPending
+	}
+}
+
+// toMinorUnits turns dollars into cents, processors only take whole minor units
+func toMinorUnits(amount decimal.Decimal) int64 {
+	return amount.Shift(2).Round(0).IntPart()
+}
+
+func fromMinorUnits(amount int64) decimal.Decimal {
+	return decimal.New(amount, -2)
+}
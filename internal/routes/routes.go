@@ -7,21 +7,33 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/Z3DRP/lessor-service/config"
 	"github.com/Z3DRP/lessor-service/internal/auth"
 	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/jobs"
 	"github.com/Z3DRP/lessor-service/internal/middlewares"
+	"github.com/Z3DRP/lessor-service/internal/notify"
 	"github.com/Z3DRP/lessor-service/internal/services/alssr"
+	"github.com/Z3DRP/lessor-service/internal/services/hooks"
+	"github.com/Z3DRP/lessor-service/internal/services/lease"
+	"github.com/Z3DRP/lessor-service/internal/services/ledger"
+	"github.com/Z3DRP/lessor-service/internal/services/maintenance"
 	"github.com/Z3DRP/lessor-service/internal/services/notification"
+	"github.com/Z3DRP/lessor-service/internal/services/payroll"
 	"github.com/Z3DRP/lessor-service/internal/services/property"
 	rentalproperty "github.com/Z3DRP/lessor-service/internal/services/rentalProperty"
+	"github.com/Z3DRP/lessor-service/internal/services/schedule"
 	"github.com/Z3DRP/lessor-service/internal/services/task"
+	"github.com/Z3DRP/lessor-service/internal/services/tenant"
+	"github.com/Z3DRP/lessor-service/internal/services/texts"
+	"github.com/Z3DRP/lessor-service/internal/services/timesheet"
 	"github.com/Z3DRP/lessor-service/internal/services/usr"
 	"github.com/Z3DRP/lessor-service/internal/services/worker"
+	"github.com/Z3DRP/lessor-service/internal/stream"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
-	"github.com/golang-jwt/jwt/v4"
 )
 
 func NewServer(
@@ -33,6 +45,15 @@ func NewServer(
 	rentalPropertyHndlr rentalproperty.RentalPropertyHandler,
 	workerHndlr worker.WorkerHandler,
 	notificationHndlr notification.NotificationHandler,
+	scheduleHndlr schedule.ScheduleHandler,
+	ledgerHndlr ledger.LedgerHandler,
+	tenantHndlr tenant.TenantHandler,
+	maintenanceHndlr maintenance.MaintenanceHandler,
+	timesheetHndlr timesheet.TimesheetHandler,
+	payrollHndlr payroll.PayrollHandler,
+	smsHndlr texts.SmsHandler,
+	webhookHndlr hooks.WebhookHandler,
+	leaseHndlr lease.LeaseHandler,
 ) (*http.Server, error) {
 
 	mux := http.NewServeMux()
@@ -45,6 +66,15 @@ func NewServer(
 		rentalPropertyHndlr,
 		workerHndlr,
 		notificationHndlr,
+		scheduleHndlr,
+		ledgerHndlr,
+		tenantHndlr,
+		maintenanceHndlr,
+		timesheetHndlr,
+		payrollHndlr,
+		smsHndlr,
+		webhookHndlr,
+		leaseHndlr,
 	)
 
 	mwChain := middlewares.MiddlewareChain(handlePanic, loggerMiddleware, headerMiddleware, contextMiddleware)
@@ -67,92 +97,236 @@ func registerRoutes(
 	rpHandler rentalproperty.RentalPropertyHandler,
 	wHandler worker.WorkerHandler,
 	nHandler notification.NotificationHandler,
+	sHandler schedule.ScheduleHandler,
+	lHandler ledger.LedgerHandler,
+	tnHandler tenant.TenantHandler,
+	mHandler maintenance.MaintenanceHandler,
+	tsHandler timesheet.TimesheetHandler,
+	prHandler payroll.PayrollHandler,
+	smHandler texts.SmsHandler,
+	whHandler hooks.WebhookHandler,
+	lsHandler lease.LeaseHandler,
 ) {
 	mux.HandleFunc("POST /sign-in", uHandler.HandleLogin)
 	mux.HandleFunc("POST /sign-up", uHandler.HandleSignUp)
 	mux.HandleFunc("POST /sign-up/worker", uHandler.HandleSignUpWorker)
+	mux.HandleFunc("POST /sign-up/tenant", uHandler.HandleSignUpTenant)
+	mux.HandleFunc("POST /token/refresh", uHandler.HandleRefreshToken)
+	mux.HandleFunc("POST /sign-out", uHandler.HandleSignOut)
+	mux.HandleFunc("POST /webhooks/payments", lHandler.HandlePaymentWebhook)
+	mux.HandleFunc("POST /webhooks/sms/status", smHandler.HandleStatusWebhook)
+	mux.HandleFunc("POST /webhooks/sms/inbound", smHandler.HandleInboundWebhook)
 
-	mux.HandleFunc("GET /alessor", aHandler.HandleGetAlessors)
-	mux.HandleFunc("GET /alessor/{id}", aHandler.HandleGetAlessor)
-	mux.HandleFunc("POST /alessor/{id}", aHandler.HandleCreateAlessor)
-	mux.HandleFunc("PUT /alessor/{id}", aHandler.HandleUpdateAlessor)
-	mux.HandleFunc("DELETE /alessor/{id}", aHandler.HandleDeleteAlessor)
-	mux.HandleFunc("GET /alessor/{id}/task", tHandler.HandleGetTasks)
-	mux.HandleFunc("GET /alessor/{id}/worker", wHandler.HandleGetWorkers)
-	mux.HandleFunc("GET /alessor/{id}/notifications", nHandler.HandleGetNotifications)
+	mux.Handle("GET /alessor", secure(aHandler.HandleGetAlessors, auth.Admin))
+	mux.Handle("GET /alessor/{id}", secure(aHandler.HandleGetAlessor, auth.Admin, auth.Alessor))
+	mux.Handle("POST /alessor/{id}", secure(aHandler.HandleCreateAlessor, auth.Admin))
+	mux.Handle("PUT /alessor/{id}", secure(aHandler.HandleUpdateAlessor, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /alessor/{id}", secure(aHandler.HandleDeleteAlessor, auth.Admin))
+	mux.Handle("GET /alessor/{id}/task", secure(tHandler.HandleGetTasks, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("GET /alessor/{id}/worker", secure(wHandler.HandleGetWorkers, auth.Admin, auth.Alessor))
+	mux.Handle("GET /alessor/{id}/tenant", secure(tnHandler.HandleGetTenants, auth.Admin, auth.Alessor))
+	mux.Handle("GET /alessor/{id}/maintenance", secure(mHandler.HandleGetQueue, auth.Admin, auth.Alessor))
+	mux.Handle("GET /alessor/{id}/payroll", secure(prHandler.HandleGetRuns, auth.Admin, auth.Alessor))
+	mux.Handle("POST /alessor/{id}/payroll", secure(prHandler.HandleRunPayroll, auth.Admin, auth.Alessor))
+	mux.Handle("POST /alessor/{id}/payroll/preview", secure(prHandler.HandlePreviewPayroll, auth.Admin, auth.Alessor))
+	mux.Handle("GET /alessor/{id}/sms", secure(smHandler.HandleGetMessages, auth.Admin, auth.Alessor))
+	mux.Handle("GET /alessor/{id}/notifications", secure(nHandler.HandleGetNotifications))
+	mux.Handle("GET /alessor/{id}/webhooks", secure(whHandler.HandleGetWebhooks, auth.Admin, auth.Alessor))
 	// need to add this and remove from below and change to property
 	//mux.HandleFunc("GET alessor/{id}/property", pHandler.HandleGetProperties)
 
-	mux.HandleFunc("GET /user", uHandler.HandleGetUsers) // admin or alessors route only
-	mux.HandleFunc("GET /user/{id}", uHandler.HandleGetUser)
-	mux.HandleFunc("POST /user/{id}", uHandler.HandleCreateUser)
-	mux.HandleFunc("PUT /user/{id}", uHandler.HandleUpdateUser)
-	mux.HandleFunc("DELETE /user/{id}", uHandler.HandleDeleteUser)
-	mux.HandleFunc("GET /user-details", uHandler.HandleGetDetails)
+	mux.Handle("GET /user", secure(uHandler.HandleGetUsers, auth.Admin, auth.Alessor))
+	mux.Handle("GET /user/{id}", secure(uHandler.HandleGetUser, auth.Admin, auth.Alessor, auth.Worker, auth.Tenant))
+	mux.Handle("POST /user/{id}", secure(uHandler.HandleCreateUser, auth.Admin))
+	mux.Handle("PUT /user/{id}", secure(uHandler.HandleUpdateUser, auth.Admin, auth.Alessor, auth.Worker, auth.Tenant))
+	mux.Handle("DELETE /user/{id}", secure(uHandler.HandleDeleteUser, auth.Admin))
+	mux.Handle("GET /user/{id}/notification-settings", secure(nHandler.HandleGetSettings))
+	mux.Handle("PUT /user/{id}/notification-settings", secure(nHandler.HandleUpdateSettings))
+	mux.Handle("GET /user-details", secure(uHandler.HandleGetDetails))
 
 	// need to update this to be more restful properties needs to be property/alsrId but
 	// but alessor id is needed and is triggering property/pid api doesnt
 	// know if its alessor id or propertyId so for now just use /properties/id
-	mux.HandleFunc("GET /property", pHandler.HandleGetProperty)
-	mux.HandleFunc("GET /properties/{id}", pHandler.HandleGetProperties)
-	mux.HandleFunc("GET /property/{id}", pHandler.HandleGetProperty)
-	mux.HandleFunc("POST /property", pHandler.HandleCreateProperty)
-	mux.HandleFunc("PUT /property/{id}", pHandler.HandleUpdateProperty)
-	mux.HandleFunc("DELETE /property/{id}", pHandler.HandleDeleteProperty)
-
-	mux.HandleFunc("GET /task/{id}", tHandler.HandleGetTask)
-	mux.HandleFunc("POST /task", tHandler.HandleCreateTask)
-	mux.HandleFunc("PUT /task/{id}", tHandler.HandleUpdateTask)
-	mux.HandleFunc("DELETE /task/{id}", tHandler.HandleDeleteTask)
-	mux.HandleFunc("PUT /task/{id}/priority", tHandler.HandleUpdatePriority)
-	mux.HandleFunc("PUT /task/{id}/assign", tHandler.HandleAssignTask)
-	mux.HandleFunc("PUT /task/{id}/complete", tHandler.HandleCompleteTask)
-	mux.HandleFunc("PUT /task/{id}/pause", tHandler.HandlePauseTask)
-	mux.HandleFunc("PUT /task/{id}/unpause", tHandler.HandleUnPauseTask)
-
-	mux.HandleFunc("GET /rental-property", rpHandler.HandleGetRentalProperties)
-	mux.HandleFunc("GET /rental-property/{id}", rpHandler.HandleDeleteRentalProperty)
-	mux.HandleFunc("POST /rental", rpHandler.HandleCreateRentalProperty)
-	mux.HandleFunc("PUT /rental/{id}", rpHandler.HandleUpdateRentalProperty)
-	mux.HandleFunc("DELETE /rental/{id}", rpHandler.HandleDeleteRentalProperty)
-
-	mux.HandleFunc("POST /worker", wHandler.HandleCreateWorker)
-	mux.HandleFunc("GET /worker/{id}", wHandler.HandleGetWorker)
-	mux.HandleFunc("PUT /worker/{id}", wHandler.HandleUpdateWorker)
-	mux.HandleFunc("DELETE /worker/{id}", wHandler.HandleDeleteWorker)
-
-	mux.HandleFunc("POST /notifications", nHandler.HandleCreateNotification)
-	mux.HandleFunc("PATCH /notifications/{id}", nHandler.HandleUpdateViewed)
+	mux.Handle("GET /property", secure(pHandler.HandleGetProperty, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("GET /properties/{id}", secure(pHandler.HandleGetProperties, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("GET /property/{id}", secure(pHandler.HandleGetProperty, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("POST /property", secure(pHandler.HandleCreateProperty, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /property/{id}", secure(pHandler.HandleUpdateProperty, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /property/{id}", secure(pHandler.HandleDeleteProperty, auth.Admin, auth.Alessor))
+	mux.Handle("GET /property/{id}/schedule", secure(sHandler.HandleGetSchedules, auth.Admin, auth.Alessor))
+
+	mux.Handle("POST /schedule", secure(sHandler.HandleCreateSchedule, auth.Admin, auth.Alessor))
+	mux.Handle("GET /schedule/{id}", secure(sHandler.HandleGetSchedule, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /schedule/{id}", secure(sHandler.HandleUpdateSchedule, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /schedule/{id}", secure(sHandler.HandleCancelSchedule, auth.Admin, auth.Alessor))
+
+	mux.Handle("GET /task/{id}", secure(tHandler.HandleGetTask, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("POST /task", secure(tHandler.HandleCreateTask, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /task/{id}", secure(tHandler.HandleUpdateTask, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /task/{id}", secure(tHandler.HandleDeleteTask, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /task/{id}/priority", secure(tHandler.HandleUpdatePriority, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /task/{id}/assign", secure(tHandler.HandleAssignTask, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /task/{id}/complete", secure(tHandler.HandleCompleteTask, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("PUT /task/{id}/pause", secure(tHandler.HandlePauseTask, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("PUT /task/{id}/unpause", secure(tHandler.HandleUnPauseTask, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("PUT /task/{id}/fail", secure(tHandler.HandleFailTask, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("GET /task/{id}/timeline", secure(tHandler.HandleGetTimeline, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("GET /task/{id}/crew", secure(tHandler.HandleGetCrew, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("POST /task/{id}/crew", secure(tHandler.HandleAddWorkers, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /task/{id}/crew/{workerId}", secure(tHandler.HandleRemoveWorker, auth.Admin, auth.Alessor))
+	mux.Handle("GET /task/{id}/candidates", secure(tHandler.HandleGetCandidates, auth.Admin, auth.Alessor))
+	mux.Handle("POST /task/{id}/dispatch", secure(tHandler.HandleDispatchTask, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /task/{id}/lead", secure(tHandler.HandleSetLead, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /task/{id}/accept", secure(tHandler.HandleAcceptAssignment, auth.Worker))
+	mux.Handle("PUT /task/{id}/decline", secure(tHandler.HandleDeclineAssignment, auth.Worker))
+	mux.Handle("GET /task/{id}/fees", secure(tHandler.HandleGetFees, auth.Admin, auth.Alessor))
+	mux.Handle("POST /task/{id}/fees", secure(tHandler.HandleAddFee, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /task/{id}/fees/{feeId}", secure(tHandler.HandleUpdateFee, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /task/{id}/fees/{feeId}", secure(tHandler.HandleRemoveFee, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /task/{id}/fees/{feeId}/processed", secure(tHandler.HandleProcessFee, auth.Admin, auth.Alessor))
+	mux.Handle("POST /task/{id}/clock-in", secure(tsHandler.HandleClockIn, auth.Worker))
+	mux.Handle("PUT /task/{id}/clock-out", secure(tsHandler.HandleClockOut, auth.Worker))
+	mux.Handle("PUT /task/{id}/break/start", secure(tsHandler.HandleStartBreak, auth.Worker))
+	mux.Handle("PUT /task/{id}/break/end", secure(tsHandler.HandleEndBreak, auth.Worker))
+	mux.Handle("GET /task/{id}/time-entries", secure(tsHandler.HandleGetTaskEntries, auth.Admin, auth.Alessor, auth.Worker))
+
+	mux.Handle("GET /rental-property", secure(rpHandler.HandleGetRentalProperties, auth.Admin, auth.Alessor))
+	mux.Handle("GET /rental-property/{id}", secure(rpHandler.HandleGetRentalProperty, auth.Admin, auth.Alessor))
+	mux.Handle("POST /rental", secure(rpHandler.HandleCreateRentalProperty, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /rental/{id}", secure(rpHandler.HandleUpdateRentalProperty, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /rental/{id}", secure(rpHandler.HandleDeleteRentalProperty, auth.Admin, auth.Alessor))
+	mux.Handle("GET /rental/{id}/ledger", secure(lHandler.HandleGetRentalLedger, auth.Admin, auth.Alessor))
+	mux.Handle("GET /rental/{id}/leases", secure(lsHandler.HandleGetLeases, auth.Admin, auth.Alessor))
+
+	mux.Handle("POST /lease", secure(lsHandler.HandleCreateLease, auth.Admin, auth.Alessor))
+	mux.Handle("GET /lease/{id}", secure(lsHandler.HandleGetLease, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /lease/{id}", secure(lsHandler.HandleUpdateLease, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /lease/{id}/activate", secure(lsHandler.HandleActivateLease, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /lease/{id}/terminate", secure(lsHandler.HandleTerminateLease, auth.Admin, auth.Alessor))
+	mux.Handle("POST /lease/{id}/renew", secure(lsHandler.HandleRenewLease, auth.Admin, auth.Alessor))
+
+	mux.Handle("POST /tenant", secure(tnHandler.HandleInviteTenant, auth.Admin, auth.Alessor))
+	mux.Handle("GET /tenant/me", secure(tnHandler.HandleGetHome, auth.Tenant))
+	mux.Handle("GET /tenant/me/maintenance", secure(mHandler.HandleGetSubmitted, auth.Tenant))
+	mux.Handle("GET /tenant/{id}", secure(tnHandler.HandleGetTenant, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /tenant/{id}", secure(tnHandler.HandleUpdateTenant, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /tenant/{id}", secure(tnHandler.HandleDeleteTenant, auth.Admin, auth.Alessor))
+	mux.Handle("POST /tenant/{id}/invite", secure(tnHandler.HandleResendInvite, auth.Admin, auth.Alessor))
+	mux.Handle("GET /tenant/{id}/ledger", secure(lHandler.HandleGetTenantLedger, auth.Admin, auth.Alessor, auth.Tenant))
+
+	mux.Handle("POST /maintenance", secure(mHandler.HandleSubmitRequest, auth.Tenant))
+	mux.Handle("GET /maintenance/{id}", secure(mHandler.HandleGetRequest, auth.Admin, auth.Alessor, auth.Tenant))
+	mux.Handle("DELETE /maintenance/{id}", secure(mHandler.HandleWithdrawRequest, auth.Tenant))
+	mux.Handle("POST /maintenance/{id}/convert", secure(mHandler.HandleConvertRequest, auth.Admin, auth.Alessor))
+	mux.Handle("POST /maintenance/{id}/decline", secure(mHandler.HandleDeclineRequest, auth.Admin, auth.Alessor))
+
+	mux.Handle("POST /charge", secure(lHandler.HandleCreateCharge, auth.Admin, auth.Alessor))
+	mux.Handle("GET /charge/{id}", secure(lHandler.HandleGetCharge, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /charge/{id}", secure(lHandler.HandleUpdateCharge, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /charge/{id}", secure(lHandler.HandleVoidCharge, auth.Admin, auth.Alessor))
+
+	mux.Handle("POST /payment", secure(lHandler.HandleRecordPayment, auth.Admin, auth.Alessor))
+	mux.Handle("GET /payment/{id}", secure(lHandler.HandleGetPayment, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /payment/{id}", secure(lHandler.HandleUpdatePayment, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /payment/{id}", secure(lHandler.HandleReversePayment, auth.Admin, auth.Alessor))
+
+	mux.Handle("POST /worker", secure(wHandler.HandleCreateWorker, auth.Admin, auth.Alessor))
+	mux.Handle("GET /worker/{id}", secure(wHandler.HandleGetWorker, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("GET /worker/{id}/tasks", secure(tHandler.HandleGetWorkerTasks, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("GET /worker/{id}/route", secure(tHandler.HandleGetWorkerRoute, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("PUT /worker/{id}", secure(wHandler.HandleUpdateWorker, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /worker/{id}", secure(wHandler.HandleDeleteWorker, auth.Admin, auth.Alessor))
+	mux.Handle("GET /worker/{id}/availability", secure(wHandler.HandleGetAvailability, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("PUT /worker/{id}/hours", secure(wHandler.HandleSetWorkingHours, auth.Admin, auth.Alessor))
+	mux.Handle("POST /worker/{id}/time-off", secure(wHandler.HandleAddTimeOff, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("DELETE /worker/{id}/time-off/{offId}", secure(wHandler.HandleRemoveTimeOff, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("GET /worker/{id}/timesheet", secure(tsHandler.HandleGetTimesheet, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("PUT /worker/{id}/timesheet/approve", secure(tsHandler.HandleApproveTimesheet, auth.Admin, auth.Alessor))
+
+	mux.Handle("PUT /time-entry/{id}", secure(tsHandler.HandleAdjustEntry, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /time-entry/{id}/approve", secure(tsHandler.HandleApproveEntry, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /time-entry/{id}/reject", secure(tsHandler.HandleRejectEntry, auth.Admin, auth.Alessor))
+
+	mux.Handle("GET /payroll/{id}", secure(prHandler.HandleGetRun, auth.Admin, auth.Alessor))
+	mux.Handle("GET /payroll/{id}/export", secure(prHandler.HandleExportRun, auth.Admin, auth.Alessor))
+
+	mux.Handle("POST /webhook", secure(whHandler.HandleCreateWebhook, auth.Admin, auth.Alessor))
+	mux.Handle("GET /webhook/{id}", secure(whHandler.HandleGetWebhook, auth.Admin, auth.Alessor))
+	mux.Handle("PUT /webhook/{id}", secure(whHandler.HandleUpdateWebhook, auth.Admin, auth.Alessor))
+	mux.Handle("DELETE /webhook/{id}", secure(whHandler.HandleDeleteWebhook, auth.Admin, auth.Alessor))
+	mux.Handle("GET /webhook/{id}/deliveries", secure(whHandler.HandleGetDeliveries, auth.Admin, auth.Alessor))
+	mux.Handle("POST /webhook/{id}/deliveries/{deliveryId}/redeliver", secure(whHandler.HandleRedeliver, auth.Admin, auth.Alessor))
+
+	mux.Handle("POST /notifications", secure(nHandler.HandleCreateNotification, auth.Admin, auth.Alessor, auth.Worker))
+	mux.Handle("GET "+notificationStreamPath, secureStream(nHandler.HandleStream))
+	mux.Handle("PATCH /notifications/{id}", secure(nHandler.HandleUpdateViewed))
+}
+
+// secure wraps a handler so it requires a valid bearer token, if roles are given
+// the user's role from the token claims must be one of them
+func secure(h http.HandlerFunc, roles ...auth.Role) http.Handler {
+	return middlewares.MiddlewareChain(authenticate, authorize(roles...))(h)
 }
 
-// make this unexported after jwt in use
-func Authenticate(next http.Handler) http.HandlerFunc {
+// secureStream is secure for the stream endpoints, EventSource and WebSocket can't set
+// headers in a browser so the token may also come from the access_token query parameter
+func secureStream(h http.HandlerFunc, roles ...auth.Role) http.Handler {
+	return middlewares.MiddlewareChain(queryToken, authenticate, authorize(roles...))(h)
+}
+
+func queryToken(next http.Handler) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cook, err := r.Cookie("token")
-		if err != nil {
-			if err == http.ErrNoCookie {
-				utils.WriteErr(w, http.StatusUnauthorized, err)
-				return
-			}
-			utils.WriteErr(w, http.StatusBadRequest, err)
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func authenticate(next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			utils.WriteErr(w, http.StatusUnauthorized, errors.New("request did not have auth token"))
 			return
 		}
 
-		token := cook.Value
-		claims := &auth.UserClaims{}
-		tkn, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-			return auth.GetJwtKey()
-		})
+		tokenParts := strings.Split(authHeader, " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			utils.WriteErr(w, http.StatusUnauthorized, errors.New("malformed authorization header"))
+			return
+		}
 
-		if err != nil || !tkn.Valid {
-			utils.WriteErr(w, http.StatusUnauthorized, errors.New("unathorized"))
+		claims, err := auth.ParseAuthToken(tokenParts[1])
+		if err != nil {
+			crane.DefaultLogger.MustDebug(fmt.Sprintf("invalid auth token %v", err))
+			utils.WriteErr(w, http.StatusUnauthorized, errors.New("unauthorized"))
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
 	})
 }
 
+func authorize(roles ...auth.Role) middlewares.Middleware {
+	return func(next http.Handler) http.HandlerFunc {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := auth.ClaimsFromContext(r.Context())
+			if err != nil {
+				utils.WriteErr(w, http.StatusUnauthorized, err)
+				return
+			}
+
+			if len(roles) > 0 && !claims.HasRole(roles...) {
+				utils.WriteErr(w, http.StatusForbidden, fmt.Errorf("role %v is not allowed to access this resource", claims.Role))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func headerMiddleware(next http.Handler) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
@@ -163,7 +337,7 @@ func headerMiddleware(next http.Handler) http.HandlerFunc {
 		if config.IsValidOrigin(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID")
 		}
 
 		if r.Method == http.MethodOptions {
@@ -176,8 +350,21 @@ func headerMiddleware(next http.Handler) http.HandlerFunc {
 	})
 }
 
+const notificationStreamPath = "/notifications/stream"
+
+// streamPaths stay open for as long as the client listens so they don't get the request
+// timeout, shutdown is what closes them
+var streamPaths = map[string]bool{
+	notificationStreamPath: true,
+}
+
 func contextMiddleware(next http.Handler) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if streamPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		timeout := 10 * time.Minute
 		ctx, cancel := context.WithTimeout(r.Context(), timeout)
 		defer cancel()
@@ -194,11 +381,14 @@ func loggerMiddleware(next http.Handler) http.HandlerFunc {
 			StatusCode:     http.StatusOK,
 		}
 		next.ServeHTTP(wrapped, r)
-		crane.DefaultLogger.MustDebug(fmt.Sprintf("Method: %s, URI: %s, IP: %s, Duration: %v, Status: %v", r.Method, r.RequestURI, r.RemoteAddr, start, wrapped.StatusCode))
+		// the path only, stream requests can carry their token in the query
+		crane.DefaultLogger.MustDebug(fmt.Sprintf("Method: %s, URI: %s, IP: %s, Duration: %v, Status: %v", r.Method, r.URL.Path, r.RemoteAddr, start, wrapped.StatusCode))
 	})
 }
 
-func HandleShutdown(server *http.Server) {
+// HandleShutdown waits for an interrupt then stops taking requests and lets any running
+// background job finish and queued email go out before returning
+func HandleShutdown(server *http.Server, runner *jobs.Runner, outbox *notify.Outbox, hub *stream.Hub) {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt)
 	<-stop
@@ -206,10 +396,32 @@ func HandleShutdown(server *http.Server) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// open streams never finish on their own so they are closed before the server waits on them
+	if err := hub.Stop(); err != nil {
+		crane.DefaultLogger.MustDebug(fmt.Sprintf("Notification stream failed to stop: %v", err))
+	}
+
 	if err := server.Shutdown(ctx); err != nil {
 		crane.DefaultLogger.MustFatal(fmt.Sprintf("Server forced shutdown: %v", err))
 	}
 	crane.DefaultLogger.MustDebug("Server exited")
+
+	jobsCtx, cancelJobs := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelJobs()
+
+	if err := runner.Stop(jobsCtx); err != nil {
+		crane.DefaultLogger.MustDebug(fmt.Sprintf("Background jobs forced to stop: %v", err))
+	}
+	crane.DefaultLogger.MustDebug("Background jobs stopped")
+
+	// jobs can still queue email while they stop so the outbox goes last
+	mailCtx, cancelMail := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelMail()
+
+	if err := outbox.Stop(mailCtx); err != nil {
+		crane.DefaultLogger.MustDebug(fmt.Sprintf("Email outbox forced to stop: %v", err))
+	}
+	crane.DefaultLogger.MustDebug("Email outbox stopped")
 }
 
 func handlePanic(next http.Handler) http.HandlerFunc {
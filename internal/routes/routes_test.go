@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// TestMain runs from a temp dir with a config file so the signing key loads the same way
+// it does for the server
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "routes")
+	if err != nil {
+		panic(err)
+	}
+
+	if err = os.MkdirAll(filepath.Join(dir, "config"), 0o755); err != nil {
+		panic(err)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "config", "config.yaml"), []byte("authKey: test\n"), 0o644); err != nil {
+		panic(err)
+	}
+
+	if err = os.Chdir(dir); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func signedToken(t *testing.T, method jwt.SigningMethod, role auth.Role, expiresAt time.Time) string {
+	t.Helper()
+	key, err := auth.GetJwtKey()
+	if err != nil {
+		t.Fatalf("failed to load signing key %v", err)
+	}
+
+	claims := &auth.UserClaims{
+		Id:       uuid.NewString(),
+		Username: "user@test.com",
+		Role:     string(role),
+		LessorId: uuid.NewString(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token %v", err)
+	}
+
+	return token
+}
+
+func testMux() *http.ServeMux {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := auth.ClaimsFromContext(r.Context()); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /property", secure(ok, auth.Admin, auth.Alessor))
+	mux.Handle("GET /user-details", secure(ok))
+	mux.Handle("GET "+notificationStreamPath, secureStream(ok))
+	return mux
+}
+
+func TestSecure(t *testing.T) {
+	valid := signedToken(t, jwt.SigningMethodHS512, auth.Alessor, time.Now().Add(time.Hour))
+	worker := signedToken(t, jwt.SigningMethodHS512, auth.Worker, time.Now().Add(time.Hour))
+	expired := signedToken(t, jwt.SigningMethodHS512, auth.Alessor, time.Now().Add(-time.Hour))
+	wrongAlg := signedToken(t, jwt.SigningMethodHS256, auth.Alessor, time.Now().Add(time.Hour))
+
+	for _, test := range []struct {
+		name   string
+		target string
+		header string
+		status int
+	}{
+		{"valid token", "/property", "Bearer " + valid, http.StatusOK},
+		{"missing header", "/property", "", http.StatusUnauthorized},
+		{"missing bearer scheme", "/property", valid, http.StatusUnauthorized},
+		{"wrong scheme", "/property", "Basic " + valid, http.StatusUnauthorized},
+		{"extra header parts", "/property", "Bearer " + valid + " extra", http.StatusUnauthorized},
+		{"garbage token", "/property", "Bearer not-a-token", http.StatusUnauthorized},
+		{"expired token", "/property", "Bearer " + expired, http.StatusUnauthorized},
+		{"hs256 access token", "/property", "Bearer " + wrongAlg, http.StatusUnauthorized},
+		{"role denied", "/property", "Bearer " + worker, http.StatusForbidden},
+		{"any role allowed", "/user-details", "Bearer " + worker, http.StatusOK},
+		{"query token off the stream path", "/user-details?access_token=" + valid, "", http.StatusUnauthorized},
+		{"query token on the stream path", notificationStreamPath + "?access_token=" + valid, "", http.StatusOK},
+		{"expired query token on the stream path", notificationStreamPath + "?access_token=" + expired, "", http.StatusUnauthorized},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, test.target, nil)
+			if test.header != "" {
+				r.Header.Set("Authorization", test.header)
+			}
+
+			w := httptest.NewRecorder()
+			testMux().ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("expected status %v, got %v: %v", test.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/Z3DRP/lessor-service/internal/dtos"
 	"github.com/Z3DRP/lessor-service/internal/filters"
 	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
 	"github.com/Z3DRP/lessor-service/internal/ztype"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
 )
@@ -54,7 +55,7 @@ func (a AlessorHandler) HandleCreateAlessor(w http.ResponseWriter, r *http.Reque
 		log.Println("returned from service")
 		if err != nil {
 			a.logger.MustDebug(fmt.Sprintf("database err, %v", err))
-			utils.WriteErr(w, http.StatusBadRequest, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusBadRequest), err)
 			return
 		}
 
@@ -114,7 +115,7 @@ func (a AlessorHandler) HandleGetAlessor(w http.ResponseWriter, r *http.Request)
 				}
 			}
 			a.logger.MustDebug(fmt.Sprintf("failed to fetch alessor, %v", err))
-			utils.WriteErr(w, http.StatusBadRequest, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusBadRequest), err)
 			return
 		}
 
@@ -161,7 +162,7 @@ func (a AlessorHandler) HandleGetAlessors(w http.ResponseWriter, r *http.Request
 		alsrs, err := a.GetAlsrs(r.Context(), fltr)
 		if err != nil {
 			a.logger.MustDebug(fmt.Sprintf("database err, %v", err))
-			utils.WriteErr(w, http.StatusBadRequest, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusBadRequest), err)
 			return
 		}
 
@@ -200,7 +201,7 @@ func (a AlessorHandler) HandleUpdateAlessor(w http.ResponseWriter, r *http.Reque
 		updatedAlsr, err := a.ModifyAlsr(r.Context(), alsrPayload)
 		if err != nil {
 			a.logger.MustDebug(utils.FormatErrMsg("database err, %v", err))
-			utils.WriteErr(w, http.StatusBadRequest, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusBadRequest), err)
 			return
 		}
 
@@ -247,7 +248,7 @@ func (a AlessorHandler) HandleDeleteAlessor(w http.ResponseWriter, r *http.Reque
 
 		if err := a.DeleteAlsr(r.Context(), req); err != nil {
 			a.logger.MustDebug(err.Error())
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
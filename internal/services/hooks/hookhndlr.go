@@ -0,0 +1,300 @@
+package hooks
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/ztype"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type WebhookHandler struct {
+	WebhookService
+}
+
+func NewHandler(service WebhookService) WebhookHandler {
+	return WebhookHandler{
+		WebhookService: service,
+	}
+}
+
+func (wh WebhookHandler) HandlerName() string {
+	return "Webhook"
+}
+
+func (wh WebhookHandler) HandleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		wh.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		hooks, err := wh.GetWebhooks(r.Context(), r.PathValue("id"))
+
+		if err != nil {
+			log.Printf("failed to fetch webhooks %v", err)
+			utils.WriteErr(w, webhookErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"webhooks": hooks,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (wh WebhookHandler) HandleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		wh.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		wid := r.PathValue("id")
+		if wid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing webhook id in url"))
+			return
+		}
+
+		hook, err := wh.GetWebhook(r.Context(), wid)
+
+		if err != nil {
+			log.Printf("failed to fetch webhook %v", err)
+			utils.WriteErr(w, webhookErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"webhook": hook,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleCreateWebhook answers with the signing secret, it is the only time it is shown
+func (wh WebhookHandler) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		wh.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		payload := &dtos.WebhookRequest{}
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		hook, err := wh.CreateWebhook(r.Context(), payload)
+
+		if err != nil {
+			log.Printf("failed to create webhook %v", err)
+			utils.WriteErr(w, webhookErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"webhook": hook,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (wh WebhookHandler) HandleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		wh.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		wid := r.PathValue("id")
+		if wid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing webhook id in url"))
+			return
+		}
+
+		payload := &dtos.WebhookModRequest{}
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		hook, err := wh.ModifyWebhook(r.Context(), wid, payload)
+
+		if err != nil {
+			log.Printf("failed to update webhook %v", err)
+			utils.WriteErr(w, webhookErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"webhook": hook,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (wh WebhookHandler) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		wh.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		wid := r.PathValue("id")
+		if wid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing webhook id in url"))
+			return
+		}
+
+		err := wh.DeleteWebhook(r.Context(), wid)
+
+		if err != nil {
+			log.Printf("failed to delete webhook %v", err)
+			utils.WriteErr(w, webhookErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"webhookId": wid,
+			"success":   true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (wh WebhookHandler) HandleGetDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		wh.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		wid := r.PathValue("id")
+		if wid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing webhook id in url"))
+			return
+		}
+
+		deliveries, err := wh.GetDeliveries(r.Context(), wid)
+
+		if err != nil {
+			log.Printf("failed to fetch webhook deliveries %v", err)
+			utils.WriteErr(w, webhookErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"deliveries": deliveries,
+			"success":    true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleRedeliver queues a past delivery to be sent again as a new delivery
+func (wh WebhookHandler) HandleRedeliver(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		wh.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		wid := r.PathValue("id")
+		if wid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing webhook id in url"))
+			return
+		}
+
+		did := r.PathValue("deliveryId")
+		if did == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing delivery id in url"))
+			return
+		}
+
+		delivery, err := wh.Redeliver(r.Context(), wid, did)
+
+		if err != nil {
+			log.Printf("failed to redeliver webhook %v", err)
+			utils.WriteErr(w, webhookErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"delivery": delivery,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func webhookErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
@@ -0,0 +1,243 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/webhook"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// dueBatch is how many deliveries one run of the delivery job sends
+	dueBatch = 50
+	// senders is how many deliveries are posted at once so one slow endpoint can't hold up the rest
+	senders = 8
+)
+
+type WebhookService struct {
+	repo   dac.WebhookRepo
+	sender *webhook.Sender
+	logger *crane.Zlogrus
+}
+
+func (w WebhookService) ServiceName() string {
+	return "Webhook"
+}
+
+func NewWebhookService(repo dac.WebhookRepo, sender *webhook.Sender, logr *crane.Zlogrus) WebhookService {
+	return WebhookService{
+		repo:   repo,
+		sender: sender,
+		logger: logr,
+	}
+}
+
+func (w WebhookService) GetWebhooks(ctx context.Context, lessorId string) ([]dtos.WebhookResponse, error) {
+	id, err := uuid.Parse(lessorId)
+	if err != nil {
+		return nil, services.ErrInvalidRequest{ServiceType: w.ServiceName(), RequestType: "list", Err: err}
+	}
+
+	subs, err := w.repo.FetchAll(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dtos.WebhookResponse, 0, len(subs))
+	for _, sub := range subs {
+		res = append(res, dtos.NewWebhookResponse(sub))
+	}
+
+	return res, nil
+}
+
+func (w WebhookService) GetWebhook(ctx context.Context, wid string) (dtos.WebhookResponse, error) {
+	sub, err := w.fetch(ctx, wid)
+	if err != nil {
+		return dtos.WebhookResponse{}, err
+	}
+
+	return dtos.NewWebhookResponse(sub), nil
+}
+
+// CreateWebhook returns the signing secret, it isn't shown again unless it is rotated
+func (w WebhookService) CreateWebhook(ctx context.Context, req *dtos.WebhookRequest) (dtos.WebhookResponse, error) {
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return dtos.WebhookResponse{}, err
+	}
+
+	sub := &model.WebhookSubscription{
+		Wid:         uuid.New(),
+		LessorId:    utils.ParseUuid(req.LessorId),
+		Url:         req.Url,
+		Secret:      secret,
+		Events:      req.Events,
+		Description: req.Description,
+		Active:      true,
+	}
+
+	if err = w.repo.Insert(ctx, sub); err != nil {
+		return dtos.WebhookResponse{}, err
+	}
+
+	res := dtos.NewWebhookResponse(*sub)
+	res.Secret = secret
+	return res, nil
+}
+
+func (w WebhookService) ModifyWebhook(ctx context.Context, wid string, req *dtos.WebhookModRequest) (dtos.WebhookResponse, error) {
+	sub, err := w.fetch(ctx, wid)
+	if err != nil {
+		return dtos.WebhookResponse{}, err
+	}
+
+	if req.Url != "" {
+		sub.Url = req.Url
+	}
+
+	if req.Events != nil {
+		sub.Events = req.Events
+	}
+
+	if req.Description != nil {
+		sub.Description = *req.Description
+	}
+
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+
+	if req.RotateSecret {
+		if sub.Secret, err = webhook.NewSecret(); err != nil {
+			return dtos.WebhookResponse{}, err
+		}
+	}
+
+	if err = w.repo.Update(ctx, &sub); err != nil {
+		return dtos.WebhookResponse{}, err
+	}
+
+	res := dtos.NewWebhookResponse(sub)
+	if req.RotateSecret {
+		res.Secret = sub.Secret
+	}
+	return res, nil
+}
+
+func (w WebhookService) DeleteWebhook(ctx context.Context, wid string) error {
+	id, err := uuid.Parse(wid)
+	if err != nil {
+		return services.ErrInvalidRequest{ServiceType: w.ServiceName(), RequestType: "delete", Err: err}
+	}
+
+	return w.repo.Delete(ctx, id)
+}
+
+func (w WebhookService) GetDeliveries(ctx context.Context, wid string) ([]dtos.WebhookDeliveryResponse, error) {
+	id, err := uuid.Parse(wid)
+	if err != nil {
+		return nil, services.ErrInvalidRequest{ServiceType: w.ServiceName(), RequestType: "deliveries", Err: err}
+	}
+
+	deliveries, err := w.repo.FetchDeliveries(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dtos.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		res = append(res, dtos.NewWebhookDeliveryResponse(d))
+	}
+
+	return res, nil
+}
+
+// Redeliver queues the delivery's payload to be sent again on the next run of the delivery job
+func (w WebhookService) Redeliver(ctx context.Context, wid string, did string) (dtos.WebhookDeliveryResponse, error) {
+	subId, err := uuid.Parse(wid)
+	if err != nil {
+		return dtos.WebhookDeliveryResponse{}, services.ErrInvalidRequest{ServiceType: w.ServiceName(), RequestType: "redeliver", Err: err}
+	}
+
+	deliveryId, err := uuid.Parse(did)
+	if err != nil {
+		return dtos.WebhookDeliveryResponse{}, services.ErrInvalidRequest{ServiceType: w.ServiceName(), RequestType: "redeliver", Err: err}
+	}
+
+	redelivery, err := w.repo.Redeliver(ctx, subId, deliveryId)
+	if err != nil {
+		return dtos.WebhookDeliveryResponse{}, err
+	}
+
+	return dtos.NewWebhookDeliveryResponse(redelivery), nil
+}
+
+// SendDue is run by the webhook delivery job, each due delivery gets one attempt and a
+// failed one is scheduled again with backoff
+func (w WebhookService) SendDue(ctx context.Context) error {
+	due, err := w.repo.FetchDue(ctx, time.Now(), dueBatch)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+
+	slots := make(chan struct{}, senders)
+	for _, d := range due {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			if err := w.send(ctx, d); err != nil {
+				w.logger.LogFields(logrus.Fields{"msg": "failed to record webhook delivery", "delivery": d.Did, "err": err})
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("delivery %v: %w", d.Did, err))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (w WebhookService) send(ctx context.Context, d model.WebhookDelivery) error {
+	attempt := w.sender.Send(ctx, *d.Subscription, d)
+	outcome := webhook.Settle(&d, attempt)
+
+	if outcome == webhook.GaveUp {
+		w.logger.LogFields(logrus.Fields{"msg": "giving up on webhook delivery", "delivery": d.Did, "subscription": d.SubscriptionId, "status": attempt.StatusCode, "err": attempt.Error})
+	}
+
+	// the outcome is saved even when the job is stopping so the attempt isn't made twice
+	return w.repo.Settle(context.WithoutCancel(ctx), &d, outcome)
+}
+
+func (w WebhookService) fetch(ctx context.Context, wid string) (model.WebhookSubscription, error) {
+	id, err := uuid.Parse(wid)
+	if err != nil {
+		return model.WebhookSubscription{}, services.ErrInvalidRequest{ServiceType: w.ServiceName(), RequestType: "fetch", Err: err}
+	}
+
+	return w.repo.Fetch(ctx, id)
+}
@@ -0,0 +1,331 @@
+package lease
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/ztype"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type LeaseHandler struct {
+	LeaseService
+}
+
+func NewHandler(service LeaseService) LeaseHandler {
+	return LeaseHandler{
+		LeaseService: service,
+	}
+}
+
+func (l LeaseHandler) HandlerName() string {
+	return "Lease"
+}
+
+func (l LeaseHandler) HandleCreateLease(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		payload := &dtos.LeaseRequest{}
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		lease, err := l.CreateLease(r.Context(), payload)
+
+		if err != nil {
+			log.Printf("failed to create lease %v", err)
+			utils.WriteErr(w, leaseErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"lease":   lease,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (l LeaseHandler) HandleGetLease(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		lid := r.PathValue("id")
+		if lid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing lease id in url"))
+			return
+		}
+
+		lease, err := l.GetLease(r.Context(), lid)
+
+		if err != nil {
+			log.Printf("database err failed to fetch lease %v", err)
+			utils.WriteErr(w, leaseErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"lease":   lease,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleGetLeases lists the lease history of a rental
+func (l LeaseHandler) HandleGetLeases(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		fltr, err := filters.GenFilter(r)
+		if err != nil {
+			log.Printf("failed to gen filter %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		leases, err := l.GetLeases(r.Context(), fltr)
+
+		if err != nil {
+			log.Printf("database err failed to fetch leases %v", err)
+			utils.WriteErr(w, leaseErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"leases":  leases,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleUpdateLease edits a lease that is still a draft
+func (l LeaseHandler) HandleUpdateLease(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		lid := r.PathValue("id")
+		if lid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing lease id in url"))
+			return
+		}
+
+		payload := &dtos.LeaseModRequest{}
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		lease, err := l.ModifyLease(r.Context(), lid, payload)
+
+		if err != nil {
+			log.Printf("failed to update lease %v", err)
+			utils.WriteErr(w, leaseErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"lease":   lease,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (l LeaseHandler) HandleActivateLease(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		lid := r.PathValue("id")
+		if lid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing lease id in url"))
+			return
+		}
+
+		lease, err := l.ActivateLease(r.Context(), lid)
+
+		if err != nil {
+			log.Printf("failed to activate lease %v", err)
+			utils.WriteErr(w, leaseErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"lease":   lease,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (l LeaseHandler) HandleTerminateLease(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		lid := r.PathValue("id")
+		if lid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing lease id in url"))
+			return
+		}
+
+		payload := &dtos.LeaseTerminateRequest{}
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		lease, err := l.TerminateLease(r.Context(), lid, payload.Reason)
+
+		if err != nil {
+			log.Printf("failed to terminate lease %v", err)
+			utils.WriteErr(w, leaseErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"lease":   lease,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleRenewLease drafts the renewal, a request with no fields set renews on the same terms
+func (l LeaseHandler) HandleRenewLease(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		lid := r.PathValue("id")
+		if lid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing lease id in url"))
+			return
+		}
+
+		payload := &dtos.LeaseModRequest{}
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		lease, err := l.RenewLease(r.Context(), lid, payload)
+
+		if err != nil {
+			log.Printf("failed to renew lease %v", err)
+			utils.WriteErr(w, leaseErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"lease":   lease,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func leaseErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	if errors.Is(err, dac.ErrLeaseOverlap) || errors.Is(err, dac.ErrLeaseStatus) || errors.Is(err, dac.ErrLeaseRenewed) {
+		return http.StatusConflict
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
@@ -0,0 +1,247 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/notify"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type LeaseService struct {
+	repo     dac.LeaseRepo
+	notices  dac.NotificationRepo
+	settings dac.NotificationSettingsRepo
+	outbox   *notify.Outbox
+	logger   *crane.Zlogrus
+}
+
+func (l LeaseService) ServiceName() string {
+	return "Lease"
+}
+
+func NewLeaseService(repo dac.LeaseRepo, notices dac.NotificationRepo, settings dac.NotificationSettingsRepo, outbox *notify.Outbox, logr *crane.Zlogrus) LeaseService {
+	return LeaseService{
+		repo:     repo,
+		notices:  notices,
+		settings: settings,
+		outbox:   outbox,
+		logger:   logr,
+	}
+}
+
+func (l LeaseService) GetLease(ctx context.Context, lid string) (*dtos.LeaseResponse, error) {
+	lease, err := l.fetch(ctx, lid)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewLeaseResponse(lease)
+	return &response, nil
+}
+
+// GetLeases is the lease history of the rental named by the filter
+func (l LeaseService) GetLeases(ctx context.Context, fltr filters.Filter) ([]dtos.LeaseResponse, error) {
+	leases, err := l.repo.FetchAll(ctx, fltr)
+	if err != nil {
+		return nil, err
+	}
+
+	return dtos.NewLeaseResponses(leases), nil
+}
+
+// CreateLease saves the lease as a draft, it only takes effect once it is activated
+func (l LeaseService) CreateLease(ctx context.Context, req *dtos.LeaseRequest) (*dtos.LeaseResponse, error) {
+	lease := &model.Lease{
+		Lid:        uuid.New(),
+		LessorId:   utils.ParseUuid(req.LessorId),
+		RentalId:   utils.ParseUuid(req.RentalId),
+		StartsAt:   req.StartsAt,
+		EndsAt:     req.EndsAt,
+		RentAmount: req.RentAmount,
+		Deposit:    req.Deposit,
+		Terms:      req.Terms,
+		Status:     model.LeaseDraft,
+	}
+	applyChanges(lease, dtos.LeaseModRequest{TenantIds: req.TenantIds})
+
+	if _, err := l.repo.Insert(ctx, lease); err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewLeaseResponse(*lease)
+	return &response, nil
+}
+
+func (l LeaseService) ModifyLease(ctx context.Context, lid string, req *dtos.LeaseModRequest) (*dtos.LeaseResponse, error) {
+	lease, err := l.fetch(ctx, lid)
+	if err != nil {
+		return nil, err
+	}
+
+	if lease.Status != model.LeaseDraft {
+		return nil, dac.ErrLeaseStatus
+	}
+
+	applyChanges(&lease, *req)
+	if err = l.checkTerm(lease, "modify"); err != nil {
+		return nil, err
+	}
+
+	if err = l.repo.Update(ctx, &lease); err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewLeaseResponse(lease)
+	return &response, nil
+}
+
+// ActivateLease signs the draft, it fails when another active lease on the rental covers
+// any of the same time
+func (l LeaseService) ActivateLease(ctx context.Context, lid string) (*dtos.LeaseResponse, error) {
+	lease, err := l.fetch(ctx, lid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !lease.EndsAt.After(time.Now()) {
+		return nil, services.ErrInvalidRequest{ServiceType: l.ServiceName(), RequestType: "activate", Err: errors.New("lease has already ended")}
+	}
+
+	activated, err := l.repo.Activate(ctx, lease.Lid)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewLeaseResponse(activated)
+	return &response, nil
+}
+
+func (l LeaseService) TerminateLease(ctx context.Context, lid string, reason string) (*dtos.LeaseResponse, error) {
+	id, err := uuid.Parse(lid)
+	if err != nil {
+		return nil, services.ErrInvalidRequest{ServiceType: l.ServiceName(), RequestType: "terminate", Err: err}
+	}
+
+	terminated, err := l.repo.Terminate(ctx, id, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewLeaseResponse(terminated)
+	return &response, nil
+}
+
+// RenewLease drafts the lease that follows an active or expired one, the draft is signed
+// with ActivateLease like any other
+func (l LeaseService) RenewLease(ctx context.Context, lid string, req *dtos.LeaseModRequest) (*dtos.LeaseResponse, error) {
+	lease, err := l.fetch(ctx, lid)
+	if err != nil {
+		return nil, err
+	}
+
+	renewal := NewRenewal(lease, *req)
+	if err = l.checkTerm(renewal, "renew"); err != nil {
+		return nil, err
+	}
+
+	if _, err = l.repo.Insert(ctx, &renewal); err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewLeaseResponse(renewal)
+	return &response, nil
+}
+
+// RunRenewals is the daily lease job, leases that have ended are expired and the parties on
+// leases ending within the notice period are reminded once to renew
+func (l LeaseService) RunRenewals(ctx context.Context) error {
+	now := time.Now()
+	expired, err := l.repo.Expire(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	if expired > 0 {
+		l.logger.LogFields(logrus.Fields{"msg": "expired leases", "expired": expired})
+	}
+
+	due, err := l.repo.FetchRenewalsDue(ctx, now, renewalNotice)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, lease := range due {
+		if err = l.remind(ctx, lease, now); err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to send lease renewal reminder", "lease": lease.Lid, "err": err})
+			errs = append(errs, fmt.Errorf("lease %v: %w", lease.Lid, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// remind notifies the lessor and every tenant on the lease, the lease is only marked as
+// reminded once all of them were so after a failure the whole reminder goes out again
+func (l LeaseService) remind(ctx context.Context, lease model.Lease, now time.Time) error {
+	recipients := append([]uuid.UUID{lease.LessorId}, lease.TenantIds...)
+	for _, recipient := range recipients {
+		noti := renewalReminder(lease, recipient, now)
+		delivery, err := notify.Plan(ctx, &l.settings, noti)
+		if err != nil {
+			return err
+		}
+
+		if !delivery.Digest {
+			noti.DeliveredAt = now
+		}
+
+		if delivery.InApp() {
+			if _, err = l.notices.Insert(ctx, noti); err != nil {
+				return err
+			}
+		}
+
+		// sending is best effort, the notification is already saved
+		if delivery.Sends() {
+			if err = l.outbox.Notify(noti, delivery.Channels); err != nil {
+				l.logger.LogFields(logrus.Fields{"msg": "failed to queue notification", "err": err})
+			}
+		}
+	}
+
+	return l.repo.MarkReminded(ctx, lease.Lid, now)
+}
+
+func (l LeaseService) fetch(ctx context.Context, lid string) (model.Lease, error) {
+	lease, err := l.repo.Fetch(ctx, filters.Filter{Identifier: lid, Page: 1})
+	if err != nil {
+		return model.Lease{}, err
+	}
+
+	ls, ok := lease.(model.Lease)
+	if !ok {
+		return model.Lease{}, cmerr.ErrUnexpectedData{Wanted: model.Lease{}, Got: lease}
+	}
+
+	return ls, nil
+}
+
+func (l LeaseService) checkTerm(lease model.Lease, request string) error {
+	if !lease.EndsAt.After(lease.StartsAt) {
+		return services.ErrInvalidRequest{ServiceType: l.ServiceName(), RequestType: request, Err: errors.New("lease has to end after it starts")}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package lease
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// renewalNotice is how far ahead of a lease's end the lessor and tenants are reminded to renew
+const renewalNotice = 60 * 24 * time.Hour
+
+// NewRenewal drafts the lease that follows the given one. It starts when the lease ends and
+// runs for the same number of months, anything set on the request replaces the carried over terms
+func NewRenewal(lease model.Lease, req dtos.LeaseModRequest) model.Lease {
+	renewal := model.Lease{
+		Lid:        uuid.New(),
+		LessorId:   lease.LessorId,
+		RentalId:   lease.RentalId,
+		TenantIds:  lease.TenantIds,
+		StartsAt:   lease.EndsAt,
+		EndsAt:     lease.EndsAt.AddDate(0, lease.Months(), 0),
+		RentAmount: lease.RentAmount,
+		Deposit:    lease.Deposit,
+		Terms:      lease.Terms,
+		Status:     model.LeaseDraft,
+		RenewalOf:  lease.Lid,
+	}
+
+	// a new start without a new end keeps the term the same length
+	if !req.StartsAt.IsZero() && req.EndsAt.IsZero() {
+		req.EndsAt = req.StartsAt.AddDate(0, lease.Months(), 0)
+	}
+
+	applyChanges(&renewal, req)
+	return renewal
+}
+
+func applyChanges(lease *model.Lease, req dtos.LeaseModRequest) {
+	if req.TenantIds != nil {
+		lease.TenantIds = make([]uuid.UUID, 0, len(req.TenantIds))
+		for _, tid := range req.TenantIds {
+			lease.TenantIds = append(lease.TenantIds, utils.ParseUuid(tid))
+		}
+	}
+	if !req.StartsAt.IsZero() {
+		lease.StartsAt = req.StartsAt
+	}
+	if !req.EndsAt.IsZero() {
+		lease.EndsAt = req.EndsAt
+	}
+	if !req.RentAmount.IsZero() {
+		lease.RentAmount = req.RentAmount
+	}
+	if req.Deposit != nil {
+		lease.Deposit = *req.Deposit
+	}
+	if req.Terms != nil {
+		lease.Terms = *req.Terms
+	}
+}
+
+// renewalReminder is the notification sent to one party on a lease that is about to end
+func renewalReminder(lease model.Lease, recipient uuid.UUID, now time.Time) model.Notification {
+	days := int(lease.EndsAt.Sub(now).Hours() / 24)
+	return model.Notification{
+		Title: "Lease ending soon",
+		Message: fmt.Sprintf("The lease ends on %v, %d days from now. Renew it before then or it will expire",
+			lease.EndsAt.Format("January 2, 2006"), days),
+		UserId:     recipient,
+		LessorId:   lease.LessorId,
+		PropertyId: lease.RentalId,
+		Category:   model.TenantAlert,
+		Priority:   model.Medium,
+		CreatedAt:  now,
+		VoidAt:     now.AddDate(0, 0, model.TtlDays),
+	}
+}
@@ -0,0 +1,117 @@
+package lease
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestOverlaps(t *testing.T) {
+	year := model.Lease{StartsAt: date(2026, 1, 1), EndsAt: date(2027, 1, 1)}
+	for _, test := range []struct {
+		name     string
+		other    model.Lease
+		expected bool
+	}{
+		{"same period", model.Lease{StartsAt: date(2026, 1, 1), EndsAt: date(2027, 1, 1)}, true},
+		{"inside", model.Lease{StartsAt: date(2026, 3, 1), EndsAt: date(2026, 6, 1)}, true},
+		{"runs past the end", model.Lease{StartsAt: date(2026, 12, 1), EndsAt: date(2027, 6, 1)}, true},
+		{"starts when it ends", model.Lease{StartsAt: date(2027, 1, 1), EndsAt: date(2028, 1, 1)}, false},
+		{"ends when it starts", model.Lease{StartsAt: date(2025, 1, 1), EndsAt: date(2026, 1, 1)}, false},
+	} {
+		if year.Overlaps(test.other) != test.expected || test.other.Overlaps(year) != test.expected {
+			t.Errorf("%v: expected overlap %v", test.name, test.expected)
+		}
+	}
+}
+
+func TestMonths(t *testing.T) {
+	for _, test := range []struct {
+		start, end time.Time
+		expected   int
+	}{
+		{date(2026, 1, 1), date(2027, 1, 1), 12},
+		{date(2026, 1, 15), date(2026, 7, 15), 6},
+		{date(2026, 1, 15), date(2026, 7, 20), 7},
+		{date(2026, 11, 1), date(2027, 2, 1), 3},
+	} {
+		lease := model.Lease{StartsAt: test.start, EndsAt: test.end}
+		if got := lease.Months(); got != test.expected {
+			t.Errorf("%v to %v: expected %d months, got %d", test.start, test.end, test.expected, got)
+		}
+	}
+}
+
+func TestCanMoveTo(t *testing.T) {
+	for _, test := range []struct {
+		from, to model.LeaseStatus
+		expected bool
+	}{
+		{model.LeaseDraft, model.LeaseActive, true},
+		{model.LeaseDraft, model.LeaseTerminated, true},
+		{model.LeaseDraft, model.LeaseExpired, false},
+		{model.LeaseActive, model.LeaseExpired, true},
+		{model.LeaseActive, model.LeaseTerminated, true},
+		{model.LeaseActive, model.LeaseActive, false},
+		{model.LeaseExpired, model.LeaseActive, false},
+		{model.LeaseTerminated, model.LeaseActive, false},
+	} {
+		if got := (model.Lease{Status: test.from}).CanMoveTo(test.to); got != test.expected {
+			t.Errorf("%v to %v: expected %v, got %v", test.from, test.to, test.expected, got)
+		}
+	}
+}
+
+func TestNewRenewal(t *testing.T) {
+	lease := model.Lease{
+		Lid:        uuid.New(),
+		LessorId:   uuid.New(),
+		RentalId:   uuid.New(),
+		TenantIds:  []uuid.UUID{uuid.New(), uuid.New()},
+		StartsAt:   date(2026, 1, 1),
+		EndsAt:     date(2027, 1, 1),
+		RentAmount: decimal.NewFromInt(1200),
+		Deposit:    decimal.NewFromInt(1200),
+		Terms:      "no smoking",
+		Status:     model.LeaseActive,
+	}
+
+	renewal := NewRenewal(lease, dtos.LeaseModRequest{})
+	if renewal.Lid == lease.Lid || renewal.RenewalOf != lease.Lid || renewal.Status != model.LeaseDraft {
+		t.Errorf("expected a new draft pointing at the renewed lease, got %+v", renewal)
+	}
+
+	if !renewal.StartsAt.Equal(lease.EndsAt) || !renewal.EndsAt.Equal(date(2028, 1, 1)) {
+		t.Errorf("expected the renewal to run for another year, got %v to %v", renewal.StartsAt, renewal.EndsAt)
+	}
+
+	if renewal.Overlaps(lease) || !renewal.RentAmount.Equal(lease.RentAmount) || len(renewal.TenantIds) != 2 {
+		t.Errorf("expected the renewal to follow on the same terms, got %+v", renewal)
+	}
+
+	tenant := uuid.New()
+	terms := "pets allowed"
+	changed := NewRenewal(lease, dtos.LeaseModRequest{
+		TenantIds:  []string{tenant.String()},
+		StartsAt:   date(2027, 2, 1),
+		RentAmount: decimal.NewFromInt(1300),
+		Terms:      &terms,
+	})
+
+	if !changed.EndsAt.Equal(date(2028, 2, 1)) {
+		t.Errorf("expected a moved start to keep the term length, got %v", changed.EndsAt)
+	}
+
+	if !changed.RentAmount.Equal(decimal.NewFromInt(1300)) || changed.Terms != terms ||
+		len(changed.TenantIds) != 1 || changed.TenantIds[0] != tenant || !changed.Deposit.Equal(lease.Deposit) {
+		t.Errorf("expected the requested changes to replace the carried over terms, got %+v", changed)
+	}
+}
@@ -0,0 +1,493 @@
+package ledger
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/payments"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/ztype"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// maxWebhookBody caps how much of a callback is read before the signature is checked
+const maxWebhookBody = 1 << 20
+
+type LedgerHandler struct {
+	LedgerService
+}
+
+func NewHandler(service LedgerService) LedgerHandler {
+	return LedgerHandler{
+		LedgerService: service,
+	}
+}
+
+func (l LedgerHandler) HandlerName() string {
+	return "Ledger"
+}
+
+func (l LedgerHandler) HandleCreateCharge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		payload := &dtos.ChargeRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to parse request body", "err": err})
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		charge, err := l.CreateCharge(r.Context(), payload)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to create charge", "err": err})
+			log.Printf("failed to create charge %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"charge":  charge,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (l LedgerHandler) HandleGetCharge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing cid in url"))
+			return
+		}
+
+		charge, err := l.GetCharge(r.Context(), id)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to fetch charge", "err": err})
+			log.Printf("failed to fetch charge %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"charge":  charge,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (l LedgerHandler) HandleUpdateCharge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		payload := &dtos.ChargeModRequest{}
+
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing cid in url"))
+			return
+		}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to parse request body", "err": err})
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		charge, err := l.ModifyCharge(r.Context(), id, payload)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to update charge", "err": err})
+			log.Printf("failed to update charge %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"charge":  charge,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleVoidCharge voids rather than deletes so the charge stays in the history
+func (l LedgerHandler) HandleVoidCharge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing cid in url"))
+			return
+		}
+
+		err := l.VoidCharge(r.Context(), id)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to void charge", "err": err})
+			log.Printf("failed to void charge %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"chargeId": id,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (l LedgerHandler) HandleRecordPayment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		payload := &dtos.PaymentRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to parse request body", "err": err})
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		payment, err := l.RecordPayment(r.Context(), payload)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to record payment", "err": err})
+			log.Printf("failed to record payment %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"payment": payment,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (l LedgerHandler) HandleGetPayment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing txid in url"))
+			return
+		}
+
+		payment, err := l.GetPayment(r.Context(), id)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to fetch payment", "err": err})
+			log.Printf("failed to fetch payment %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"payment": payment,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (l LedgerHandler) HandleUpdatePayment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		payload := &dtos.PaymentModRequest{}
+
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing txid in url"))
+			return
+		}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to parse request body", "err": err})
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		payment, err := l.ModifyPayment(r.Context(), id, payload)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to update payment", "err": err})
+			log.Printf("failed to update payment %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"payment": payment,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleReversePayment backs the payment out of the ledger, the record is kept
+func (l LedgerHandler) HandleReversePayment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing txid in url"))
+			return
+		}
+
+		err := l.ReversePayment(r.Context(), id)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to reverse payment", "err": err})
+			log.Printf("failed to reverse payment %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"paymentId": id,
+			"success":   true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (l LedgerHandler) HandleGetTenantLedger(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tenant id in url"))
+			return
+		}
+
+		ledger, err := l.GetTenantLedger(r.Context(), id)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to fetch tenant ledger", "err": err})
+			log.Printf("failed to fetch tenant ledger %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"ledger":  ledger,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (l LedgerHandler) HandleGetRentalLedger(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing rental id in url"))
+			return
+		}
+
+		ledger, err := l.GetRentalLedger(r.Context(), id)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to fetch rental ledger", "err": err})
+			log.Printf("failed to fetch rental ledger %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"ledger":  ledger,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandlePaymentWebhook takes status callbacks from the payment processor, it isn't behind
+// auth so every callback has to carry a valid signature
+func (l LedgerHandler) HandlePaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		l.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		updated, err := l.HandleProcessorEvent(r.Context(), r.Header, body)
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to handle payment webhook", "err": err})
+			log.Printf("failed to handle payment webhook %v", err)
+			utils.WriteErr(w, ledgerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"updated": updated,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func ledgerErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	if errors.Is(err, dac.ErrChargeHasPayments) || errors.Is(err, dac.ErrPaymentReversed) {
+		return http.StatusConflict
+	}
+
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		return http.StatusUnauthorized
+	}
+
+	if errors.Is(err, payments.ErrNotConfigured) {
+		return http.StatusServiceUnavailable
+	}
+
+	var failed payments.ErrProcessorFailed
+	if errors.As(err, &failed) {
+		return http.StatusBadGateway
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
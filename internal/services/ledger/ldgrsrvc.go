@@ -0,0 +1,514 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/payments"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// pendingGrace is how long a processor payment can stay pending before it is checked
+	pendingGrace = 10 * time.Minute
+	// pendingAbandon is how long a payment the processor has no record of is kept pending,
+	// card tokens expire within a day so the charge can't show up after that
+	pendingAbandon = 24 * time.Hour
+)
+
+type LedgerService struct {
+	repo      dac.LedgerRepo
+	processor payments.PaymentProcessor
+	logger    *crane.Zlogrus
+}
+
+func (l LedgerService) ServiceName() string {
+	return "Ledger"
+}
+
+func NewLedgerService(repo dac.LedgerRepo, processor payments.PaymentProcessor, logr *crane.Zlogrus) LedgerService {
+	return LedgerService{
+		repo:      repo,
+		processor: processor,
+		logger:    logr,
+	}
+}
+
+func (l LedgerService) GetCharge(ctx context.Context, cid string) (*dtos.ChargeResponse, error) {
+	charge, err := l.repo.FetchCharge(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	res := dtos.NewChargeResponse(charge)
+	return &res, nil
+}
+
+func (l LedgerService) CreateCharge(ctx context.Context, cdata *dtos.ChargeRequest) (*dtos.ChargeResponse, error) {
+	kind := model.ChargeKind(cdata.Kind)
+	if kind == "" {
+		kind = model.OtherCharge
+	}
+
+	charge := &model.Charge{
+		Cid:         uuid.New(),
+		LessorId:    utils.ParseUuid(cdata.LessorId),
+		TenantId:    utils.ParseUuid(cdata.TenantId),
+		RentalId:    utils.ParseUuid(cdata.RentalId),
+		Kind:        kind,
+		Description: cdata.Description,
+		Amount:      cdata.Amount.Round(2),
+		DueDate:     cdata.DueDate,
+	}
+
+	// manual rent charges still take up their period so the generator skips it
+	if kind == model.RentCharge {
+		charge.PeriodStart = cdata.DueDate
+	}
+
+	if _, err := l.repo.InsertCharge(ctx, charge); err != nil {
+		return nil, err
+	}
+
+	res := dtos.NewChargeResponse(*charge)
+	return &res, nil
+}
+
+func (l LedgerService) ModifyCharge(ctx context.Context, cid string, cdata *dtos.ChargeModRequest) (*dtos.ChargeResponse, error) {
+	charge, err := l.repo.FetchCharge(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !charge.VoidedAt.IsZero() {
+		return nil, services.ErrInvalidRequest{ServiceType: l.ServiceName(), RequestType: "update", Err: errors.New("charge has been voided")}
+	}
+
+	if cdata.Description != "" {
+		charge.Description = cdata.Description
+	}
+
+	if cdata.Amount.IsPositive() {
+		charge.Amount = cdata.Amount.Round(2)
+	}
+
+	if !cdata.DueDate.IsZero() {
+		charge.DueDate = cdata.DueDate
+	}
+
+	if err = l.repo.UpdateCharge(ctx, &charge); err != nil {
+		return nil, err
+	}
+
+	res := dtos.NewChargeResponse(charge)
+	return &res, nil
+}
+
+func (l LedgerService) VoidCharge(ctx context.Context, cid string) error {
+	return l.repo.VoidCharge(ctx, cid)
+}
+
+func (l LedgerService) GetPayment(ctx context.Context, txid string) (*dtos.PaymentResponse, error) {
+	pmt, err := l.repo.FetchPayment(ctx, txid)
+	if err != nil {
+		return nil, err
+	}
+
+	res := dtos.NewPaymentResponse(pmt)
+	return &res, nil
+}
+
+// RecordPayment stores a cash, check or processor payment and applies it to the tenant's
+// open charges, rejected payments are kept for the record but never applied. Processor
+// payments are only taken for lessors with payment integration turned on
+func (l LedgerService) RecordPayment(ctx context.Context, pdata *dtos.PaymentRequest) (*dtos.PaymentResponse, error) {
+	status := model.PaymentStatus(pdata.TransactionStatus)
+	if status == "" {
+		status = model.Accepted
+	}
+
+	receivedAt := pdata.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+
+	var chargeId uuid.UUID
+	if pdata.ChargeId != "" {
+		var err error
+		if chargeId, err = uuid.Parse(pdata.ChargeId); err != nil {
+			return nil, services.ErrInvalidRequest{ServiceType: l.ServiceName(), RequestType: "create", Err: fmt.Errorf("invalid charge id %v", err)}
+		}
+	}
+
+	pmt := &model.Payment{
+		TxId:              uuid.New(),
+		Amount:            pdata.Amount.Round(2),
+		CurrenyCode:       pdata.CurrencyCode,
+		TransactionStatus: status,
+		Method:            model.MethodOfPayment(pdata.Method),
+		Note:              pdata.Note,
+		ReceitNumber:      pdata.ReceiptNumber,
+		LessorId:          utils.ParseUuid(pdata.LessorId),
+		TenantId:          utils.ParseUuid(pdata.TenantId),
+		RentalId:          utils.ParseUuid(pdata.RentalId),
+		ReceivedAt:        receivedAt,
+	}
+
+	if pmt.Method == model.Processor {
+		return l.chargeProcessor(ctx, pmt, chargeId, pdata.SourceId)
+	}
+
+	if err := l.repo.RecordPayment(ctx, pmt, chargeId); err != nil {
+		return nil, err
+	}
+
+	res := dtos.NewPaymentResponse(*pmt)
+	return &res, nil
+}
+
+// chargeProcessor records the payment as pending before charging it so a charge that goes
+// through is never lost, the txid is the idempotency key and the reference the processor
+// sends back with its callbacks
+func (l LedgerService) chargeProcessor(ctx context.Context, pmt *model.Payment, chargeId uuid.UUID, sourceId string) (*dtos.PaymentResponse, error) {
+	settings, err := l.repo.FetchPaymentSettings(ctx, pmt.LessorId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !settings.PaymentIntegrationEnabled || settings.SquareAccount == "" {
+		return nil, services.ErrInvalidRequest{
+			ServiceType: l.ServiceName(),
+			RequestType: "create",
+			Err:         errors.New("lessor does not have payment integration enabled"),
+		}
+	}
+
+	pmt.TransactionStatus = model.PaymentPending
+	if err = l.repo.RecordPayment(ctx, pmt, chargeId); err != nil {
+		return nil, err
+	}
+
+	txid := pmt.TxId.String()
+	result, err := l.processor.CreateCharge(ctx, payments.ChargeRequest{
+		IdempotencyKey: txid,
+		SourceId:       sourceId,
+		Amount:         pmt.Amount,
+		Currency:       pmt.CurrenyCode,
+		Account:        settings.SquareAccount,
+		Reference:      txid,
+		Note:           pmt.Note,
+	})
+
+	if err != nil {
+		// a refusal is final but a failed request may still have charged, that payment is
+		// left pending for the processor's callback or ReconcilePending to settle
+		var failed payments.ErrProcessorFailed
+		if !errors.As(err, &failed) || failed.Code == "" {
+			return nil, err
+		}
+
+		l.logger.LogFields(logrus.Fields{"msg": "processor refused payment", "payment": txid, "code": failed.Code, "err": err})
+		result = payments.Result{Status: model.Rejected}
+	}
+
+	if _, _, err = l.repo.ApplyProcessorStatus(ctx, txid, result.ProcessorId, result.Status); err != nil {
+		return nil, err
+	}
+
+	return l.GetPayment(ctx, txid)
+}
+
+func (l LedgerService) ModifyPayment(ctx context.Context, txid string, pdata *dtos.PaymentModRequest) (*dtos.PaymentResponse, error) {
+	pmt, err := l.repo.FetchPayment(ctx, txid)
+	if err != nil {
+		return nil, err
+	}
+
+	if pdata.Note != "" {
+		pmt.Note = pdata.Note
+	}
+
+	if pdata.ReceiptNumber != "" {
+		pmt.ReceitNumber = pdata.ReceiptNumber
+	}
+
+	if err = l.repo.UpdatePayment(ctx, &pmt); err != nil {
+		return nil, err
+	}
+
+	res := dtos.NewPaymentResponse(pmt)
+	return &res, nil
+}
+
+// ReversePayment backs a payment out of the ledger, accepted processor payments are
+// refunded through the processor first
+func (l LedgerService) ReversePayment(ctx context.Context, txid string) error {
+	pmt, err := l.repo.FetchPayment(ctx, txid)
+	if err != nil {
+		return err
+	}
+
+	if pmt.Method != model.Processor || !pmt.ReversedAt.IsZero() {
+		return l.repo.ReversePayment(ctx, txid)
+	}
+
+	if pmt.TransactionStatus == model.PaymentPending {
+		return services.ErrInvalidRequest{
+			ServiceType: l.ServiceName(),
+			RequestType: "reverse",
+			Err:         errors.New("payment is still pending with the processor"),
+		}
+	}
+
+	var refund payments.Result
+	if pmt.TransactionStatus == model.Accepted {
+		// the key is derived from the txid so retrying a reversal can't refund twice
+		key := uuid.NewSHA1(pmt.TxId, []byte("refund")).String()
+		refund, err = l.processor.Refund(ctx, pmt.SqPid, key, pmt.Amount, pmt.CurrenyCode)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = l.repo.ReversePayment(ctx, txid); err != nil {
+		return err
+	}
+
+	if refund.Status == model.PaymentRefunded {
+		_, _, err = l.repo.ApplyProcessorStatus(ctx, txid, pmt.SqPid, refund.Status)
+	}
+
+	return err
+}
+
+// HandleProcessorEvent applies a verified processor callback, callbacks for payments this
+// service didn't take are ignored
+func (l LedgerService) HandleProcessorEvent(ctx context.Context, header http.Header, body []byte) (bool, error) {
+	if err := l.processor.VerifyWebhook(header, body); err != nil {
+		return false, err
+	}
+
+	event, err := l.processor.ParseWebhook(body)
+	if err != nil {
+		return false, services.ErrInvalidRequest{ServiceType: l.ServiceName(), RequestType: "webhook", Err: err}
+	}
+
+	if event.Status == "" {
+		return false, nil
+	}
+
+	ctx = auth.WithSystem(ctx)
+	if event.Status == model.PaymentRefunded {
+		full, err := l.fullRefund(ctx, event)
+		if err != nil || !full {
+			return false, err
+		}
+	}
+
+	_, advanced, err := l.repo.ApplyProcessorStatus(ctx, event.Reference, event.ProcessorId, event.Status)
+	if err != nil {
+		var noResults dac.ErrNoResults
+		if errors.As(err, &noResults) {
+			l.logger.LogFields(logrus.Fields{"msg": "ignoring callback for unknown payment", "event": event.Id, "processorId": event.ProcessorId})
+			return false, nil
+		}
+		return false, err
+	}
+
+	return advanced, nil
+}
+
+// fullRefund is whether a refund callback gave back the whole payment. The ledger only
+// reverses whole payments so a partial refund made outside the service is logged and left
+// for the lessor to record against the tenant
+func (l LedgerService) fullRefund(ctx context.Context, event payments.Event) (bool, error) {
+	pmt, err := l.repo.FetchProcessorPayment(ctx, event.ProcessorId)
+	if err != nil {
+		var noResults dac.ErrNoResults
+		if errors.As(err, &noResults) {
+			l.logger.LogFields(logrus.Fields{"msg": "ignoring refund for unknown payment", "event": event.Id, "processorId": event.ProcessorId})
+			return false, nil
+		}
+		return false, err
+	}
+
+	if event.Amount.LessThan(pmt.Amount) {
+		l.logger.LogFields(logrus.Fields{
+			"msg":      "ignoring partial refund",
+			"event":    event.Id,
+			"payment":  pmt.TxId,
+			"refunded": event.Amount.String(),
+			"amount":   pmt.Amount.String(),
+		})
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// ReconcilePending asks the processor about payments that have been pending too long in
+// case a callback never arrived
+func (l LedgerService) ReconcilePending(ctx context.Context) error {
+	pending, err := l.repo.FetchPendingProcessor(ctx, time.Now().Add(-pendingGrace))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, pmt := range pending {
+		var err error
+		if pmt.SqPid == "" {
+			err = l.reconcileUnsent(ctx, pmt)
+		} else {
+			var status model.PaymentStatus
+			status, err = l.processor.FetchStatus(ctx, pmt.SqPid)
+			if err == nil {
+				_, _, err = l.repo.ApplyProcessorStatus(ctx, pmt.TxId.String(), pmt.SqPid, status)
+			}
+		}
+
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to reconcile payment", "payment": pmt.TxId, "err": err})
+			errs = append(errs, fmt.Errorf("payment %v: %w", pmt.TxId, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// reconcileUnsent settles a payment whose charge request failed before the processor
+// answered, it is looked up by its txid reference and rejected once it's too old to
+// have gone through
+func (l LedgerService) reconcileUnsent(ctx context.Context, pmt model.Payment) error {
+	settings, err := l.repo.FetchPaymentSettings(ctx, pmt.LessorId)
+	if err != nil {
+		return err
+	}
+
+	txid := pmt.TxId.String()
+	result, err := l.processor.FindByReference(ctx, settings.SquareAccount, txid, pmt.CreatedAt)
+	if errors.Is(err, payments.ErrPaymentNotFound) {
+		if time.Since(pmt.CreatedAt) < pendingAbandon {
+			return nil
+		}
+
+		l.logger.LogFields(logrus.Fields{"msg": "rejecting payment the processor never received", "payment": txid})
+		result, err = payments.Result{Status: model.Rejected}, nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, _, err = l.repo.ApplyProcessorStatus(ctx, txid, result.ProcessorId, result.Status)
+	return err
+}
+
+// GetTenantLedger returns a tenant's ledger, tenants can only read their own
+func (l LedgerService) GetTenantLedger(ctx context.Context, tenantId string) (*dtos.LedgerResponse, error) {
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return nil, dac.ErrMissingScope{Err: err}
+	}
+
+	if claims.HasRole(auth.Tenant) && claims.Id != tenantId {
+		return nil, dac.ErrCrossTenant{Model: "Tenant", Identifier: tenantId}
+	}
+
+	charges, payments, err := l.repo.FetchTenantLedger(ctx, tenantId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := BuildLedger(charges, payments, time.Now())
+	res.TenantId = tenantId
+	return &res, nil
+}
+
+func (l LedgerService) GetRentalLedger(ctx context.Context, rentalId string) (*dtos.LedgerResponse, error) {
+	charges, payments, err := l.repo.FetchRentalLedger(ctx, rentalId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := BuildLedger(charges, payments, time.Now())
+	res.RentalId = rentalId
+	return &res, nil
+}
+
+// GenerateRentCharges creates every rent charge that has come due for tenants that have
+// moved in, periods that were already charged are skipped
+func (l LedgerService) GenerateRentCharges(ctx context.Context, now time.Time) (int, error) {
+	tenants, err := l.repo.FetchTenancies(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	generated := 0
+	for _, tenant := range tenants {
+		created, err := l.repo.InsertGenerated(ctx, RentCharges(tenant, now))
+		if err != nil {
+			l.logger.LogFields(logrus.Fields{"msg": "failed to generate rent charges", "tenant": tenant.Uid, "err": err})
+			errs = append(errs, fmt.Errorf("tenant %v: %w", tenant.Uid, err))
+			continue
+		}
+
+		generated += len(created)
+	}
+
+	return generated, errors.Join(errs...)
+}
+
+// AssessLateFees charges the rental's late fee on rent that is still unpaid after the
+// grace period, each charge only ever gets one fee
+func (l LedgerService) AssessLateFees(ctx context.Context, now time.Time) (int, error) {
+	overdue, err := l.repo.FetchOverdue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	fees := make([]model.Charge, 0)
+	for _, charge := range overdue {
+		if fee, ok := LateFee(charge, now); ok {
+			fees = append(fees, fee)
+		}
+	}
+
+	created, err := l.repo.InsertGenerated(ctx, fees)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(created), nil
+}
+
+// RunRentCharges and RunLateFees are the background jobs for the generators
+func (l LedgerService) RunRentCharges(ctx context.Context) error {
+	generated, err := l.GenerateRentCharges(ctx, time.Now())
+	if generated > 0 {
+		l.logger.LogFields(logrus.Fields{"msg": "generated rent charges", "generated": generated})
+	}
+
+	return err
+}
+
+func (l LedgerService) RunLateFees(ctx context.Context) error {
+	assessed, err := l.AssessLateFees(ctx, time.Now())
+	if assessed > 0 {
+		l.logger.LogFields(logrus.Fields{"msg": "assessed late fees", "assessed": assessed})
+	}
+
+	return err
+}
@@ -0,0 +1,161 @@
+package ledger
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// DueDates lists the monthly due dates from through through, rent falls on the same day
+// of the month as anchor and on the last day of shorter months
+func DueDates(anchor, from, through time.Time) []time.Time {
+	dates := make([]time.Time, 0)
+	if anchor.IsZero() {
+		return dates
+	}
+
+	from = from.In(anchor.Location())
+	year, month := from.Year(), from.Month()
+	due := dueIn(anchor, year, month)
+	if due.Before(from) {
+		month++
+		due = dueIn(anchor, year, month)
+	}
+
+	for !due.After(through) {
+		dates = append(dates, due)
+		month++
+		due = dueIn(anchor, year, month)
+	}
+
+	return dates
+}
+
+// dueIn is the anchor's day in the given month, time.Date normalizes months past december
+func dueIn(anchor time.Time, year int, month time.Month) time.Time {
+	first := time.Date(year, month, 1, anchor.Hour(), anchor.Minute(), 0, 0, anchor.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(anchor.Day(), last)-1)
+}
+
+// RentCharges builds the rent charges a tenant owes through the given time, starting
+// from whichever is later of the move in date and the rental's first due date
+func RentCharges(tenant model.Tenant, through time.Time) []model.Charge {
+	charges := make([]model.Charge, 0)
+	rental := tenant.Rental
+	if rental == nil || rental.RentDueDate.IsZero() || !rental.RentalPrice.IsPositive() {
+		return charges
+	}
+
+	from := rental.RentDueDate
+	if tenant.MoveInDate.After(from) {
+		from = tenant.MoveInDate
+	}
+
+	if !tenant.MoveOutDate.IsZero() && tenant.MoveOutDate.Before(through) {
+		through = tenant.MoveOutDate
+	}
+
+	for _, due := range DueDates(rental.RentDueDate, from, through) {
+		charges = append(charges, model.Charge{
+			Cid:         uuid.New(),
+			LessorId:    tenant.LessorId,
+			TenantId:    tenant.Uid,
+			RentalId:    tenant.PropertyId,
+			Kind:        model.RentCharge,
+			PeriodStart: due,
+			Description: "Rent for " + due.Format("January 2006"),
+			Amount:      rental.RentalPrice,
+			DueDate:     due,
+		})
+	}
+
+	return charges
+}
+
+// LateFee builds the fee for an overdue charge once its grace period is over, rentals
+// without a late fee never get one
+func LateFee(charge model.Charge, now time.Time) (model.Charge, bool) {
+	rental := charge.Rental
+	if rental == nil || !rental.LateFee.IsPositive() || !charge.Outstanding().IsPositive() {
+		return model.Charge{}, false
+	}
+
+	late := charge.DueDate.AddDate(0, 0, rental.LateFeeGraceDays)
+	if !now.After(late) {
+		return model.Charge{}, false
+	}
+
+	return model.Charge{
+		Cid:         uuid.New(),
+		LessorId:    charge.LessorId,
+		TenantId:    charge.TenantId,
+		RentalId:    charge.RentalId,
+		Kind:        model.LateFeeCharge,
+		LateFeeFor:  charge.Cid,
+		Description: "Late fee for " + charge.Description,
+		Amount:      rental.LateFee,
+		DueDate:     now,
+	}, true
+}
+
+// BuildLedger orders the charges and payments by date and keeps a running balance, on
+// the same day charges come before the payments made against them
+func BuildLedger(charges []model.Charge, payments []model.Payment, now time.Time) dtos.LedgerResponse {
+	res := dtos.LedgerResponse{
+		TotalCharged: decimal.Zero,
+		TotalPaid:    decimal.Zero,
+		Balance:      decimal.Zero,
+		PastDue:      decimal.Zero,
+		Entries:      make([]dtos.LedgerEntry, 0, len(charges)+len(payments)),
+	}
+
+	for _, c := range charges {
+		res.TotalCharged = res.TotalCharged.Add(c.Amount)
+		if c.DueDate.Before(now) {
+			res.PastDue = res.PastDue.Add(c.Outstanding())
+		}
+
+		res.Entries = append(res.Entries, dtos.LedgerEntry{
+			Date:        c.DueDate,
+			Type:        dtos.ChargeEntry,
+			Id:          c.Cid.String(),
+			TenantId:    c.TenantId.String(),
+			Kind:        string(c.Kind),
+			Description: c.Description,
+			Amount:      c.Amount,
+		})
+	}
+
+	for _, p := range payments {
+		res.TotalPaid = res.TotalPaid.Add(p.Amount)
+		res.Entries = append(res.Entries, dtos.LedgerEntry{
+			Date:        p.ReceivedAt,
+			Type:        dtos.PaymentEntry,
+			Id:          p.TxId.String(),
+			TenantId:    p.TenantId.String(),
+			Kind:        string(p.Method),
+			Description: p.Note,
+			Amount:      p.Amount.Neg(),
+		})
+	}
+
+	sort.SliceStable(res.Entries, func(i, j int) bool {
+		a, b := res.Entries[i], res.Entries[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Type == dtos.ChargeEntry && b.Type == dtos.PaymentEntry
+	})
+
+	for i := range res.Entries {
+		res.Balance = res.Balance.Add(res.Entries[i].Amount)
+		res.Entries[i].Balance = res.Balance
+	}
+
+	return res
+}
@@ -0,0 +1,162 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDueDates(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		anchor   time.Time
+		from     time.Time
+		through  time.Time
+		expected []time.Time
+	}{
+		{
+			"first of the month",
+			date(2026, 1, 1), date(2026, 1, 1), date(2026, 3, 15),
+			[]time.Time{date(2026, 1, 1), date(2026, 2, 1), date(2026, 3, 1)},
+		},
+		{
+			"mid month move in",
+			date(2026, 1, 1), date(2026, 1, 20), date(2026, 3, 1),
+			[]time.Time{date(2026, 2, 1), date(2026, 3, 1)},
+		},
+		{
+			"clamped to month end",
+			date(2026, 1, 31), date(2026, 1, 31), date(2026, 4, 30),
+			[]time.Time{date(2026, 1, 31), date(2026, 2, 28), date(2026, 3, 31), date(2026, 4, 30)},
+		},
+		{
+			"across the year",
+			date(2025, 11, 15), date(2025, 11, 15), date(2026, 1, 15),
+			[]time.Time{date(2025, 11, 15), date(2025, 12, 15), date(2026, 1, 15)},
+		},
+		{
+			"nothing due yet",
+			date(2026, 1, 10), date(2026, 1, 11), date(2026, 2, 9),
+			[]time.Time{},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := DueDates(test.anchor, test.from, test.through)
+			if len(got) != len(test.expected) {
+				t.Fatalf("expected %v got %v", test.expected, got)
+			}
+
+			for i := range got {
+				if !got[i].Equal(test.expected[i]) {
+					t.Errorf("expected %v got %v", test.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestRentChargesStopAtMoveOut(t *testing.T) {
+	tenant := model.Tenant{
+		Uid:         uuid.New(),
+		LessorId:    uuid.New(),
+		PropertyId:  uuid.New(),
+		MoveInDate:  date(2026, 1, 1),
+		MoveOutDate: date(2026, 2, 15),
+		Rental:      &model.RentalProperty{RentalPrice: decimal.RequireFromString("1200.00"), RentDueDate: date(2025, 6, 1)},
+	}
+
+	charges := RentCharges(tenant, date(2026, 6, 1))
+	if len(charges) != 2 {
+		t.Fatalf("expected 2 charges got %v", len(charges))
+	}
+
+	if !charges[0].DueDate.Equal(date(2026, 1, 1)) || charges[0].Kind != model.RentCharge {
+		t.Errorf("expected january rent got %v", charges[0].DueDate)
+	}
+}
+
+func TestLateFee(t *testing.T) {
+	rental := &model.RentalProperty{LateFee: decimal.RequireFromString("50"), LateFeeGraceDays: 5}
+	charge := model.Charge{
+		Cid:     uuid.New(),
+		Amount:  decimal.RequireFromString("1000"),
+		DueDate: date(2026, 3, 1),
+		Rental:  rental,
+	}
+
+	if _, ok := LateFee(charge, date(2026, 3, 5)); ok {
+		t.Error("expected no fee during the grace period")
+	}
+
+	fee, ok := LateFee(charge, date(2026, 3, 7))
+	if !ok || !fee.Amount.Equal(rental.LateFee) || fee.LateFeeFor != charge.Cid {
+		t.Errorf("expected a late fee for the charge got %v", fee)
+	}
+
+	charge.AmountPaid = charge.Amount
+	if _, ok := LateFee(charge, date(2026, 3, 7)); ok {
+		t.Error("expected no fee on a paid charge")
+	}
+}
+
+func TestAllocatePartialPayments(t *testing.T) {
+	charges := []model.Charge{
+		{Cid: uuid.New(), Amount: decimal.RequireFromString("100.00")},
+		{Cid: uuid.New(), Amount: decimal.RequireFromString("100.00"), AmountPaid: decimal.RequireFromString("40.00")},
+		{Cid: uuid.New(), Amount: decimal.RequireFromString("100.00")},
+	}
+
+	allocations, left := model.AllocatePayment(uuid.New(), decimal.RequireFromString("150.10"), charges)
+	if len(allocations) != 2 || !left.IsZero() {
+		t.Fatalf("expected 2 allocations and nothing left got %v %v", len(allocations), left)
+	}
+
+	if !charges[1].AmountPaid.Equal(decimal.RequireFromString("90.10")) {
+		t.Errorf("expected the second charge to be partly paid got %v", charges[1].AmountPaid)
+	}
+
+	_, left = model.AllocatePayment(uuid.New(), decimal.RequireFromString("200"), charges)
+	if !left.Equal(decimal.RequireFromString("90.10")) {
+		t.Errorf("expected 90.10 left as credit got %v", left)
+	}
+}
+
+func TestBuildLedger(t *testing.T) {
+	charges := []model.Charge{
+		{Cid: uuid.New(), Kind: model.RentCharge, Amount: decimal.RequireFromString("1000"), DueDate: date(2026, 2, 1)},
+		{Cid: uuid.New(), Kind: model.RentCharge, Amount: decimal.RequireFromString("1000"), AmountPaid: decimal.RequireFromString("1000"), DueDate: date(2026, 1, 1)},
+	}
+	payments := []model.Payment{
+		{TxId: uuid.New(), Amount: decimal.RequireFromString("1000"), Method: model.Check, ReceivedAt: date(2026, 1, 1)},
+		{TxId: uuid.New(), Amount: decimal.RequireFromString("250.50"), Method: model.Cash, ReceivedAt: date(2026, 2, 3)},
+	}
+
+	ledger := BuildLedger(charges, payments, date(2026, 2, 10))
+	expected := []string{"1000", "0", "1000", "749.5"}
+	if len(ledger.Entries) != len(expected) {
+		t.Fatalf("expected %v entries got %v", len(expected), len(ledger.Entries))
+	}
+
+	for i, balance := range expected {
+		if !ledger.Entries[i].Balance.Equal(decimal.RequireFromString(balance)) {
+			t.Errorf("entry %v expected balance %v got %v", i, balance, ledger.Entries[i].Balance)
+		}
+	}
+
+	if ledger.Entries[1].Type != dtos.PaymentEntry {
+		t.Errorf("expected the payment after the charge due the same day")
+	}
+
+	if !ledger.Balance.Equal(decimal.RequireFromString("749.50")) || !ledger.PastDue.Equal(decimal.RequireFromString("1000")) {
+		t.Errorf("expected balance 749.50 and past due 1000 got %v %v", ledger.Balance, ledger.PastDue)
+	}
+}
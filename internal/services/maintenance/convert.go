@@ -0,0 +1,80 @@
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+const maxTaskNameLen = 60
+
+// newTask fills in a task from a tenant's request, anything set on conv overrides what
+// would be taken from the request
+func newTask(req model.MaintenanceRequest, conv dtos.MaintenanceConvertRequest) *model.Task {
+	tk := &model.Task{
+		Tid:           uuid.New(),
+		Name:          conv.Name,
+		LessorId:      req.LessorId,
+		PropertyId:    req.PropertyId,
+		Details:       req.Description,
+		Notes:         taskNotes(req, conv.Notes),
+		Priority:      req.Urgency,
+		Category:      model.Maintenance,
+		Status:        model.Scheduled,
+		ScheduledAt:   conv.ScheduledAt,
+		WorkerId:      utils.ParseUuid(conv.WorkerId),
+		EstimatedCost: conv.EstimatedCost,
+	}
+
+	if tk.Name == "" {
+		tk.Name = taskName(req.Description)
+	}
+
+	if conv.Priority != "" {
+		tk.Priority = model.PriorityLevel(conv.Priority)
+	}
+
+	if conv.Category != "" {
+		tk.Category = model.TaskCategory(conv.Category)
+	}
+
+	return tk
+}
+
+// taskName is the first line of the description cut down to fit a task name
+func taskName(description string) string {
+	name := strings.TrimSpace(strings.SplitN(description, "\n", 2)[0])
+	runes := []rune(name)
+	if len(runes) > maxTaskNameLen {
+		return strings.TrimSpace(string(runes[:maxTaskNameLen-3])) + "..."
+	}
+	return name
+}
+
+// taskNotes carries over what a worker needs to know to get into the unit
+func taskNotes(req model.MaintenanceRequest, notes string) string {
+	lines := make([]string, 0, len(req.AccessWindows)+3)
+	if notes != "" {
+		lines = append(lines, notes)
+	}
+
+	if req.PermissionToEnter {
+		lines = append(lines, "tenant has given permission to enter")
+	} else {
+		lines = append(lines, "tenant must be present, do not enter without them")
+	}
+
+	if len(req.AccessWindows) > 0 {
+		lines = append(lines, "preferred access windows:")
+		for _, window := range req.AccessWindows {
+			lines = append(lines, fmt.Sprintf("- %v to %v", window.Start.Format(time.RFC1123), window.End.Format(time.RFC1123)))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
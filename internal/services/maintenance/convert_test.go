@@ -0,0 +1,79 @@
+package maintenance
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestNewTask(t *testing.T) {
+	start := time.Date(2025, time.March, 3, 9, 0, 0, 0, time.UTC)
+	req := model.MaintenanceRequest{
+		Mid:               uuid.New(),
+		LessorId:          uuid.New(),
+		PropertyId:        uuid.New(),
+		Description:       "kitchen sink is leaking\nwater is pooling under the cabinet",
+		Urgency:           model.High,
+		PermissionToEnter: true,
+		AccessWindows:     []model.AccessWindow{{Start: start, End: start.Add(3 * time.Hour)}},
+	}
+
+	t.Run("defaults from request", func(t *testing.T) {
+		tk := newTask(req, dtos.MaintenanceConvertRequest{})
+
+		if tk.Tid == uuid.Nil {
+			t.Error("expected task to get a new tid")
+		}
+		if tk.LessorId != req.LessorId || tk.PropertyId != req.PropertyId {
+			t.Errorf("expected lessor and property to carry over, got %v %v", tk.LessorId, tk.PropertyId)
+		}
+		if tk.Category != model.Maintenance || tk.Priority != model.High || tk.Status != model.Scheduled {
+			t.Errorf("unexpected defaults category %v priority %v status %v", tk.Category, tk.Priority, tk.Status)
+		}
+		if tk.Name != "kitchen sink is leaking" {
+			t.Errorf("expected name from first line of description, got %q", tk.Name)
+		}
+		if tk.Details != req.Description {
+			t.Errorf("expected details to be the description, got %q", tk.Details)
+		}
+		if !strings.Contains(tk.Notes, "permission to enter") || !strings.Contains(tk.Notes, "access windows") {
+			t.Errorf("expected access details in notes, got %q", tk.Notes)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		workerId := uuid.New()
+		tk := newTask(req, dtos.MaintenanceConvertRequest{
+			Name:     "replace sink trap",
+			Priority: string(model.Immediate),
+			Category: string(model.Service),
+			WorkerId: workerId.String(),
+			Notes:    "bring a new trap",
+		})
+
+		if tk.Name != "replace sink trap" || tk.Priority != model.Immediate || tk.Category != model.Service {
+			t.Errorf("expected overrides to be kept, got %v %v %v", tk.Name, tk.Priority, tk.Category)
+		}
+		if tk.WorkerId != workerId {
+			t.Errorf("expected worker %v, got %v", workerId, tk.WorkerId)
+		}
+		if !strings.HasPrefix(tk.Notes, "bring a new trap") {
+			t.Errorf("expected lessor notes first, got %q", tk.Notes)
+		}
+	})
+}
+
+func TestTaskName(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	if name := taskName(long); len([]rune(name)) != maxTaskNameLen || !strings.HasSuffix(name, "...") {
+		t.Errorf("expected long description to be cut to %v, got %q", maxTaskNameLen, name)
+	}
+
+	if name := taskName("  no heat  "); name != "no heat" {
+		t.Errorf("expected trimmed name, got %q", name)
+	}
+}
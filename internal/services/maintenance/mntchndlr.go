@@ -0,0 +1,393 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Z3DRP/lessor-service/internal/adapters"
+	"github.com/Z3DRP/lessor-service/internal/availability"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/ztype"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type MaintenanceHandler struct {
+	MaintenanceService
+}
+
+func NewHandler(service MaintenanceService) MaintenanceHandler {
+	return MaintenanceHandler{
+		MaintenanceService: service,
+	}
+}
+
+func (m MaintenanceHandler) HandlerName() string {
+	return "Maintenance"
+}
+
+func (m MaintenanceHandler) HandleSubmitRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		m.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		var (
+			payload *dtos.MaintenanceRequestDto
+			photos  []*ztype.FileUploadDto
+		)
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			headers, err := utils.ParseFiles(r, "photos")
+			if err != nil {
+				m.logger.LogFields(logrus.Fields{"msg": "error occurred while parsing photos from request", "err": err})
+				utils.WriteErr(w, http.StatusBadRequest, err)
+				return
+			}
+
+			if len(headers) > dtos.MaxRequestPhotos {
+				utils.WriteErr(w, http.StatusBadRequest, fmt.Errorf("at most %v photos can be attached", dtos.MaxRequestPhotos))
+				return
+			}
+
+			payload, err = adapters.ParseMaintenanceForm(r)
+			if err != nil {
+				log.Printf("failed to parse maintenance form %v", err)
+				utils.WriteErr(w, http.StatusBadRequest, err)
+				return
+			}
+
+			for i, header := range headers {
+				file, err := header.Open()
+				if err != nil {
+					utils.WriteErr(w, http.StatusBadRequest, err)
+					return
+				}
+				defer file.Close()
+
+				photo := &ztype.FileUploadDto{File: file, FileKey: fmt.Sprintf("photo%v", i+1), Header: header}
+				if err = photo.Validate(); err != nil {
+					utils.WriteErr(w, http.StatusBadRequest, err)
+					return
+				}
+				photos = append(photos, photo)
+			}
+		} else {
+			payload = &dtos.MaintenanceRequestDto{}
+			if err := utils.ParseJSON(r, payload); err != nil {
+				m.logger.LogFields(logrus.Fields{"msg": "failed to parse request body", "err": err})
+				utils.WriteErr(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		req, err := m.SubmitRequest(r.Context(), payload, photos)
+
+		if err != nil {
+			m.logger.LogFields(logrus.Fields{"msg": "failed to submit maintenance request", "err": err})
+			log.Printf("failed to submit maintenance request %v", err)
+			utils.WriteErr(w, maintenanceErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"request": req,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (m MaintenanceHandler) HandleGetRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		m.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing maintenance request id in url"))
+			return
+		}
+
+		req, err := m.GetRequest(r.Context(), filters.Filter{Identifier: id, Page: 1})
+
+		if err != nil {
+			m.logger.LogFields(logrus.Fields{"msg": "failed to fetch maintenance request", "err": err})
+			log.Printf("failed to fetch maintenance request %v", err)
+			utils.WriteErr(w, maintenanceErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"request": req,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (m MaintenanceHandler) HandleGetQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		m.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		fltr, err := filters.GenFilter(r)
+
+		if err != nil {
+			log.Printf("failed to gen filter %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		reqs, err := m.GetQueue(r.Context(), fltr, r.URL.Query().Get("status"))
+
+		if err != nil {
+			m.logger.LogFields(logrus.Fields{"msg": "failed to fetch maintenance queue", "err": err})
+			log.Printf("failed to fetch maintenance queue %v", err)
+			utils.WriteErr(w, maintenanceErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"requests": reqs,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (m MaintenanceHandler) HandleGetSubmitted(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		m.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		fltr, err := filters.GenFilterWithNoSearch(r)
+
+		if err != nil {
+			log.Printf("failed to gen filter %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		reqs, err := m.GetSubmitted(r.Context(), fltr)
+
+		if err != nil {
+			m.logger.LogFields(logrus.Fields{"msg": "failed to fetch submitted maintenance requests", "err": err})
+			log.Printf("failed to fetch submitted maintenance requests %v", err)
+			utils.WriteErr(w, maintenanceErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"requests": reqs,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (m MaintenanceHandler) HandleConvertRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		m.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing maintenance request id in url"))
+			return
+		}
+
+		payload := &dtos.MaintenanceConvertRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			m.logger.LogFields(logrus.Fields{"msg": "failed to parse request body", "err": err})
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		req, err := m.ConvertRequest(r.Context(), id, payload)
+
+		if err != nil {
+			m.logger.LogFields(logrus.Fields{"msg": "failed to convert maintenance request", "err": err})
+			log.Printf("failed to convert maintenance request %v", err)
+			writeMaintenanceErr(w, maintenanceErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"request": req,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (m MaintenanceHandler) HandleDeclineRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		m.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing maintenance request id in url"))
+			return
+		}
+
+		payload := &dtos.MaintenanceDeclineRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			m.logger.LogFields(logrus.Fields{"msg": "failed to parse request body", "err": err})
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		req, err := m.DeclineRequest(r.Context(), id, payload.Reason)
+
+		if err != nil {
+			m.logger.LogFields(logrus.Fields{"msg": "failed to decline maintenance request", "err": err})
+			log.Printf("failed to decline maintenance request %v", err)
+			utils.WriteErr(w, maintenanceErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"request": req,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (m MaintenanceHandler) HandleWithdrawRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		m.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing maintenance request id in url"))
+			return
+		}
+
+		req, err := m.WithdrawRequest(r.Context(), id)
+
+		if err != nil {
+			m.logger.LogFields(logrus.Fields{"msg": "failed to withdraw maintenance request", "err": err})
+			log.Printf("failed to withdraw maintenance request %v", err)
+			utils.WriteErr(w, maintenanceErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"request": req,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func maintenanceErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	if errors.Is(err, dac.ErrRequestResolved) {
+		return http.StatusConflict
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
+
+// writeMaintenanceErr sends the conflicts stopping the worker from taking the converted task
+// back as a 409 so the lessor can pick another time or worker
+func writeMaintenanceErr(w http.ResponseWriter, status int, err error) {
+	var conflict availability.ErrScheduleConflict
+	if errors.As(err, &conflict) {
+		res := ztype.JsonResponse{
+			"error":     err.Error(),
+			"conflicts": conflict.Conflicts,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusConflict, res); err != nil {
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteErr(w, status, err)
+}
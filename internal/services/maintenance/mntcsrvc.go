@@ -0,0 +1,235 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Z3DRP/lessor-service/internal/api"
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/availability"
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/ztype"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type MaintenanceService struct {
+	repo         dac.MaintenanceRepo
+	tenants      dac.TenantRepo
+	availability dac.AvailabilityRepo
+	s3Actor      api.FilePersister
+	logger       *crane.Zlogrus
+}
+
+func (m MaintenanceService) ServiceName() string {
+	return "Maintenance"
+}
+
+func NewMaintenanceService(repo dac.MaintenanceRepo, tenants dac.TenantRepo, avail dac.AvailabilityRepo, actor api.FilePersister, logr *crane.Zlogrus) MaintenanceService {
+	return MaintenanceService{
+		repo:         repo,
+		tenants:      tenants,
+		availability: avail,
+		s3Actor:      actor,
+		logger:       logr,
+	}
+}
+
+// SubmitRequest files a request against the signed in tenant's tenancy, the photos are
+// stored under the lessor so they sit alongside the rest of the lessor's files
+func (m MaintenanceService) SubmitRequest(ctx context.Context, mdata *dtos.MaintenanceRequestDto, photos []*ztype.FileUploadDto) (*dtos.MaintenanceResponse, error) {
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return nil, dac.ErrMissingScope{Err: err}
+	}
+
+	if len(photos) > dtos.MaxRequestPhotos {
+		return nil, services.ErrInvalidRequest{
+			ServiceType: m.ServiceName(),
+			RequestType: "submit",
+			Err:         fmt.Errorf("at most %v photos can be attached", dtos.MaxRequestPhotos),
+		}
+	}
+
+	found, err := m.tenants.Fetch(ctx, filters.Filter{Identifier: claims.Id, Page: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	tnt, ok := found.(model.Tenant)
+	if !ok {
+		return nil, cmerr.ErrUnexpectedData{Wanted: model.Tenant{}, Got: found}
+	}
+
+	req := &model.MaintenanceRequest{
+		Mid:               uuid.New(),
+		TenantId:          tnt.Uid,
+		LessorId:          tnt.LessorId,
+		PropertyId:        tnt.PropertyId,
+		Description:       mdata.Description,
+		Urgency:           model.PriorityLevel(mdata.Urgency),
+		Photos:            make([]string, 0, len(photos)),
+		AccessWindows:     mdata.AccessWindows,
+		PermissionToEnter: mdata.PermissionToEnter,
+		Status:            model.RequestSubmitted,
+	}
+
+	for _, photo := range photos {
+		key, err := m.s3Actor.Upload(ctx, req.LessorId.String(), req.Mid.String(), photo)
+		if err != nil {
+			return nil, err
+		}
+		req.Photos = append(req.Photos, key)
+	}
+
+	if err = m.repo.Insert(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return m.newResponse(ctx, *req)
+}
+
+func (m MaintenanceService) GetRequest(ctx context.Context, fltr filters.Filter) (*dtos.MaintenanceResponse, error) {
+	req, err := m.fetch(ctx, fltr.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.newResponse(ctx, req)
+}
+
+// GetQueue is the lessor's triage queue, it shows submitted requests unless another
+// status is asked for
+func (m MaintenanceService) GetQueue(ctx context.Context, fltr filters.Filter, status string) ([]dtos.MaintenanceResponse, error) {
+	queueStatus := model.RequestSubmitted
+	if status != "" {
+		queueStatus = model.MaintenanceStatus(status)
+	}
+
+	switch queueStatus {
+	case model.RequestSubmitted, model.RequestConverted, model.RequestDeclined, model.RequestWithdrawn:
+	default:
+		return nil, services.ErrInvalidRequest{ServiceType: m.ServiceName(), RequestType: "queue", Err: fmt.Errorf("invalid status %v", status)}
+	}
+
+	reqs, err := m.repo.FetchQueue(ctx, fltr, queueStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.newResponses(ctx, reqs)
+}
+
+// GetSubmitted is every request the signed in tenant has made so they can follow them
+func (m MaintenanceService) GetSubmitted(ctx context.Context, fltr filters.Filter) ([]dtos.MaintenanceResponse, error) {
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return nil, dac.ErrMissingScope{Err: err}
+	}
+
+	reqs, err := m.repo.FetchSubmitted(ctx, utils.ParseUuid(claims.Id), fltr)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.newResponses(ctx, reqs)
+}
+
+// ConvertRequest turns a submitted request into a scheduled task and links the two, the
+// worker has to be free at the time it's scheduled for
+func (m MaintenanceService) ConvertRequest(ctx context.Context, mid string, conv *dtos.MaintenanceConvertRequest) (*dtos.MaintenanceResponse, error) {
+	req, err := m.fetch(ctx, mid)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Status != model.RequestSubmitted {
+		return nil, dac.ErrRequestResolved
+	}
+
+	tk := newTask(req, *conv)
+	if err = availability.Check(ctx, m.availability, *tk, tk.WorkerId); err != nil {
+		return nil, err
+	}
+
+	converted, err := m.repo.Convert(ctx, req.Mid, tk)
+	if err != nil {
+		return nil, err
+	}
+
+	converted.Tenant = req.Tenant
+	converted.Property = req.Property
+	return m.newResponse(ctx, converted)
+}
+
+func (m MaintenanceService) DeclineRequest(ctx context.Context, mid string, reason string) (*dtos.MaintenanceResponse, error) {
+	return m.close(ctx, mid, model.RequestDeclined, reason)
+}
+
+// WithdrawRequest lets a tenant take back a request the lessor hasn't acted on yet
+func (m MaintenanceService) WithdrawRequest(ctx context.Context, mid string) (*dtos.MaintenanceResponse, error) {
+	return m.close(ctx, mid, model.RequestWithdrawn, "")
+}
+
+func (m MaintenanceService) close(ctx context.Context, mid string, status model.MaintenanceStatus, reason string) (*dtos.MaintenanceResponse, error) {
+	id := utils.ParseUuid(mid)
+	if id == uuid.Nil {
+		return nil, services.ErrInvalidRequest{ServiceType: m.ServiceName(), RequestType: string(status), Err: errors.New("invalid request id")}
+	}
+
+	req, err := m.repo.Close(ctx, id, status, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.newResponse(ctx, req)
+}
+
+func (m MaintenanceService) fetch(ctx context.Context, mid string) (model.MaintenanceRequest, error) {
+	found, err := m.repo.Fetch(ctx, filters.Filter{Identifier: mid, Page: 1})
+	if err != nil {
+		return model.MaintenanceRequest{}, err
+	}
+
+	req, ok := found.(model.MaintenanceRequest)
+	if !ok {
+		return model.MaintenanceRequest{}, cmerr.ErrUnexpectedData{Wanted: model.MaintenanceRequest{}, Got: found}
+	}
+
+	return req, nil
+}
+
+// newResponse swaps the stored photo keys for presigned urls
+func (m MaintenanceService) newResponse(ctx context.Context, req model.MaintenanceRequest) (*dtos.MaintenanceResponse, error) {
+	urls := make([]string, 0, len(req.Photos))
+	for _, key := range req.Photos {
+		url, err := m.s3Actor.GetFile(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+
+	response := dtos.NewMaintenanceResponse(req, urls)
+	return &response, nil
+}
+
+func (m MaintenanceService) newResponses(ctx context.Context, reqs []model.MaintenanceRequest) ([]dtos.MaintenanceResponse, error) {
+	responses := make([]dtos.MaintenanceResponse, 0, len(reqs))
+	for _, req := range reqs {
+		response, err := m.newResponse(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, *response)
+	}
+
+	return responses, nil
+}
@@ -1,14 +1,19 @@
 package notification
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"net/http"
 
+	"github.com/Z3DRP/lessor-service/internal/auth"
 	"github.com/Z3DRP/lessor-service/internal/dtos"
 	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/stream"
 	"github.com/Z3DRP/lessor-service/internal/ztype"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -42,15 +47,23 @@ func (n NotificationHandler) HandleCreateNotification(w http.ResponseWriter, r *
 			return
 		}
 
-		notif, err := n.CreateNotification(r.Context(), payload)
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		notif, channels, err := n.CreateNotification(r.Context(), payload)
 		if err != nil {
 			n.logger.LogFields(logrus.Fields{"msg": "failed to create notification", "err": err})
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
+		// a notification the recipient's settings drop entirely is reported instead of failing
 		res := ztype.JsonResponse{
 			"notification": notif,
+			"channels":     channels,
+			"suppressed":   len(channels) == 0,
 			"success":      true,
 		}
 
@@ -91,7 +104,7 @@ func (n NotificationHandler) HandleGetNotifications(w http.ResponseWriter, r *ht
 					return
 				}
 			}
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -132,7 +145,8 @@ func (n NotificationHandler) HandleUpdateViewed(w http.ResponseWriter, r *http.R
 		noti, err := n.UpdateViewed(r.Context(), ntfId)
 
 		if err != nil {
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
+			return
 		}
 
 		res := ztype.JsonResponse{
@@ -149,3 +163,115 @@ func (n NotificationHandler) HandleUpdateViewed(w http.ResponseWriter, r *http.R
 		}
 	}
 }
+
+func (n NotificationHandler) HandleGetSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		n.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		settings, err := n.GetSettings(r.Context(), r.PathValue("id"))
+		if err != nil {
+			n.logger.LogFields(logrus.Fields{"msg": "failed to fetch notification settings", "err": err})
+			utils.WriteErr(w, settingsErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"settings": settings,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			n.logger.LogFields(logrus.Fields{"msg": "failed to write json response", "err": err})
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (n NotificationHandler) HandleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		n.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		var payload dtos.NotificationSettingsRequest
+
+		if err := utils.ParseJSON(r, &payload); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		settings, err := n.ModifySettings(r.Context(), r.PathValue("id"), payload)
+		if err != nil {
+			n.logger.LogFields(logrus.Fields{"msg": "failed to update notification settings", "err": err})
+			utils.WriteErr(w, settingsErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"settings": settings,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			n.logger.LogFields(logrus.Fields{"msg": "failed to write json response", "err": err})
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleStream pushes the user's new notifications and task status changes as server sent
+// events, or over a websocket when the request asks to upgrade
+func (n NotificationHandler) HandleStream(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.ClaimsFromContext(r.Context())
+	if err != nil {
+		utils.WriteErr(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	uid, err := uuid.Parse(claims.Id)
+	if err != nil {
+		utils.WriteErr(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	// the stream ends when the token does so the client has to reconnect with a fresh one,
+	// a removed or deactivated user can't keep listening on an old connection
+	if claims.ExpiresAt == nil {
+		utils.WriteErr(w, http.StatusUnauthorized, errors.New("token does not expire"))
+		return
+	}
+
+	ctx, cancel := context.WithDeadline(r.Context(), claims.ExpiresAt.Time)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	if stream.IsWebSocket(r) {
+		err = stream.ServeWebSocket(w, r, n.stream, uid)
+	} else {
+		err = stream.ServeSSE(w, r, n.stream, uid)
+	}
+
+	if err != nil {
+		n.logger.LogFields(logrus.Fields{"msg": "notification stream ended", "uid": uid, "err": err})
+	}
+}
+
+func settingsErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
@@ -3,6 +3,10 @@ package notification
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/Z3DRP/lessor-service/internal/cmerr"
 	"github.com/Z3DRP/lessor-service/internal/crane"
@@ -10,22 +14,33 @@ import (
 	"github.com/Z3DRP/lessor-service/internal/dtos"
 	"github.com/Z3DRP/lessor-service/internal/filters"
 	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/notify"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/stream"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 )
 
 type NotificationService struct {
-	repo   dac.NotificationRepo
-	logger *crane.Zlogrus
+	repo     dac.NotificationRepo
+	settings dac.NotificationSettingsRepo
+	outbox   *notify.Outbox
+	stream   *stream.Hub
+	logger   *crane.Zlogrus
 }
 
 func (n NotificationService) ServiceName() string {
 	return "Notification"
 }
 
-func NewNotificationService(repo dac.NotificationRepo, logr *crane.Zlogrus) NotificationService {
+func NewNotificationService(repo dac.NotificationRepo, settings dac.NotificationSettingsRepo, outbox *notify.Outbox, hub *stream.Hub, logr *crane.Zlogrus) NotificationService {
 	return NotificationService{
-		repo:   repo,
-		logger: logr,
+		repo:     repo,
+		settings: settings,
+		outbox:   outbox,
+		stream:   hub,
+		logger:   logr,
 	}
 }
 
@@ -73,19 +88,86 @@ func (n NotificationService) GetNotifications(ctx context.Context, fltr filters.
 	return response, nil
 }
 
-func (n NotificationService) CreateNotification(ctx context.Context, data *dtos.NotificationDto) (*dtos.NotificationDto, error) {
-	notif := newNotification(data)
-	nwNotif, err := n.repo.Insert(ctx, notif)
+// CreateNotification only saves and sends what the recipient's settings allow, the
+// notification is nil when it isn't kept in the app and the channels say where it went.
+// A notification for a user on digests is sent with their next digest instead
+func (n NotificationService) CreateNotification(ctx context.Context, data *dtos.NotificationDto) (*dtos.NotificationDto, []model.NotificationChannel, error) {
+	noti := newNotification(data)
+	delivery, err := notify.Plan(ctx, &n.settings, noti)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	noti, ok := nwNotif.(model.Notification)
-	if !ok {
-		return nil, cmerr.ErrUnexpectedData{Wanted: model.Notification{}, Got: noti}
+	if !delivery.Digest {
+		noti.DeliveredAt = time.Now()
 	}
 
-	return dtos.NewNotificationDto(noti), nil
+	var res *dtos.NotificationDto
+	if delivery.InApp() {
+		nwNotif, err := n.repo.Insert(ctx, noti)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var ok bool
+		if noti, ok = nwNotif.(model.Notification); !ok {
+			return nil, nil, cmerr.ErrUnexpectedData{Wanted: model.Notification{}, Got: nwNotif}
+		}
+		res = dtos.NewNotificationDto(noti)
+	} else if err = n.repo.CheckOwner(ctx, noti.LessorId); err != nil {
+		return nil, nil, err
+	}
+
+	// sending is best effort, the notification is already saved
+	if delivery.Sends() {
+		if err = n.outbox.Notify(noti, delivery.Channels); err != nil {
+			n.logger.LogFields(logrus.Fields{"msg": "failed to queue notification", "err": err})
+		}
+	}
+
+	channels := delivery.Channels
+	if channels == nil {
+		channels = make([]model.NotificationChannel, 0)
+	}
+
+	return res, channels, nil
+}
+
+func (n NotificationService) GetSettings(ctx context.Context, uid string) (dtos.NotificationSettingsResponse, error) {
+	id, err := uuid.Parse(uid)
+	if err != nil {
+		return dtos.NotificationSettingsResponse{}, services.ErrInvalidRequest{ServiceType: n.ServiceName(), RequestType: "settings", Err: err}
+	}
+
+	settings, err := n.settings.Fetch(ctx, id)
+	if err != nil {
+		return dtos.NotificationSettingsResponse{}, err
+	}
+
+	return dtos.NewNotificationSettingsResponse(settings), nil
+}
+
+func (n NotificationService) ModifySettings(ctx context.Context, uid string, req dtos.NotificationSettingsRequest) (dtos.NotificationSettingsResponse, error) {
+	id, err := uuid.Parse(uid)
+	if err != nil {
+		return dtos.NotificationSettingsResponse{}, services.ErrInvalidRequest{ServiceType: n.ServiceName(), RequestType: "settings", Err: err}
+	}
+
+	settings := &model.NotificationSettings{
+		Uid: id,
+		Setting: model.NotificationPreferences{
+			Timezone:   req.Timezone,
+			Categories: req.Categories,
+			Digest:     req.Digest,
+			DigestHour: req.DigestHour,
+		},
+	}
+
+	if err = n.settings.Upsert(ctx, settings); err != nil {
+		return dtos.NotificationSettingsResponse{}, err
+	}
+
+	return dtos.NewNotificationSettingsResponse(*settings), nil
 }
 
 func (n NotificationService) UpdateViewed(ctx context.Context, nid int) (*dtos.NotificationDto, error) {
@@ -103,6 +185,80 @@ func (n NotificationService) UpdateViewed(ctx context.Context, nid int) (*dtos.N
 	return dtos.NewNotificationDto(noti), nil
 }
 
+// PurgeExpired is run by the notification expiry sweep job
+func (n NotificationService) PurgeExpired(ctx context.Context) error {
+	purged, err := n.repo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		n.logger.LogFields(logrus.Fields{"msg": "purged expired notifications", "count": purged})
+	}
+
+	return nil
+}
+
+// SendDigests is run hourly by the digest job, each user who is due gets their waiting
+// notifications collapsed into one summary on the channels those notifications were allowed
+func (n NotificationService) SendDigests(ctx context.Context) error {
+	digesting, err := n.settings.Digesting(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var errs []error
+	for _, settings := range digesting {
+		if !settings.Setting.DigestDue(settings.LastDigestAt, now) {
+			continue
+		}
+
+		if err = n.sendDigest(ctx, settings, now); err != nil {
+			n.logger.LogFields(logrus.Fields{"msg": "failed to send digest", "user": settings.Uid, "err": err})
+			errs = append(errs, fmt.Errorf("user %v: %w", settings.Uid, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (n NotificationService) sendDigest(ctx context.Context, settings model.NotificationSettings, now time.Time) error {
+	pending, err := n.repo.Undelivered(ctx, settings.Uid, now)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	var channels []model.NotificationChannel
+	for _, noti := range pending {
+		for _, c := range settings.Setting.Channels(noti, now) {
+			if c != model.InAppChannel && !slices.Contains(channels, c) {
+				channels = append(channels, c)
+			}
+		}
+	}
+
+	// every channel is in quiet hours, the next run picks them up
+	if len(channels) == 0 {
+		return nil
+	}
+
+	digest := notify.Digest(settings.Setting.Digest, pending[0], notify.Collapse(pending), len(pending))
+	if err = n.outbox.Notify(digest, channels); err != nil {
+		return err
+	}
+
+	ids := make([]int, len(pending))
+	for i, noti := range pending {
+		ids[i] = noti.Id
+	}
+
+	if err = n.repo.MarkDelivered(ctx, ids, now); err != nil {
+		return err
+	}
+	return n.settings.DigestSent(ctx, settings.Uid, now)
+}
+
 func newNotification(n *dtos.NotificationDto) model.Notification {
 	return model.Notification{
 		Title:      n.Title,
@@ -112,5 +268,6 @@ func newNotification(n *dtos.NotificationDto) model.Notification {
 		UserId:     utils.ParseUuid(n.UserId),
 		PropertyId: utils.ParseUuid(n.PropertyId),
 		Category:   model.NotificationType(n.Category),
+		Priority:   model.PriorityLevel(n.Priority),
 	}
 }
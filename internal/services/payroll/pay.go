@@ -0,0 +1,191 @@
+package payroll
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	defaultOvertimeAfter      = decimal.NewFromInt(40)
+	defaultOvertimeMultiplier = decimal.NewFromFloat(1.5)
+	minutesPerHour            = decimal.NewFromInt(60)
+)
+
+var csvHeader = []string{
+	"worker_id", "worker_name", "payment_method", "pay_rate", "regular_hours", "overtime_hours",
+	"regular_pay", "overtime_pay", "flat_fee_tasks", "flat_fees", "gross",
+}
+
+type workerPay struct {
+	regular  int64
+	overtime int64
+	fees     decimal.Decimal
+	feeTasks int
+}
+
+// computeRun works out what each worker is owed for the period. Hours over the overtime limit in
+// a week, Monday to Sunday in the worker's timezone, are paid at the multiplier, hours worked
+// earlier in the week the period starts in count towards the limit. Flat fee tasks pay their fee
+// to the lead and the time logged on them isn't paid hourly. It also returns the entries the run
+// pays for so they can't be paid twice
+func computeRun(run model.PayrollRun, in dac.PayrollInputs) (model.PayrollRun, []uuid.UUID) {
+	workers := make(map[uuid.UUID]model.Worker, len(in.Workers))
+	for _, w := range in.Workers {
+		workers[w.Uid] = w
+	}
+
+	entries := append([]model.TimeEntry(nil), in.Entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ClockIn.Before(entries[j].ClockIn)
+	})
+
+	limit := run.OvertimeAfter.Mul(minutesPerHour).IntPart()
+	weeks := make(map[uuid.UUID]map[string]int64)
+	pay := make(map[uuid.UUID]*workerPay)
+	paid := make([]uuid.UUID, 0, len(entries))
+	worked := func(wid uuid.UUID) map[string]int64 {
+		if weeks[wid] == nil {
+			weeks[wid] = make(map[string]int64)
+		}
+		return weeks[wid]
+	}
+	owed := func(wid uuid.UUID) *workerPay {
+		if pay[wid] == nil {
+			pay[wid] = &workerPay{fees: decimal.Zero}
+		}
+		return pay[wid]
+	}
+
+	for _, e := range in.Earlier {
+		if e.Task != nil && e.Task.FlatFee.IsPositive() {
+			continue
+		}
+		worked(e.WorkerId)[weekOf(e.ClockIn, workerLocation(workers[e.WorkerId]))] += int64(e.Minutes(e.ClockOut))
+	}
+
+	for _, e := range entries {
+		paid = append(paid, e.Eid)
+		if e.Task != nil && e.Task.FlatFee.IsPositive() {
+			continue
+		}
+
+		wp := owed(e.WorkerId)
+		week := weekOf(e.ClockIn, workerLocation(workers[e.WorkerId]))
+		minutes := int64(e.Minutes(e.ClockOut))
+		regular := max(0, min(minutes, limit-worked(e.WorkerId)[week]))
+
+		weeks[e.WorkerId][week] += minutes
+		wp.regular += regular
+		wp.overtime += minutes - regular
+	}
+
+	for _, tk := range in.Tasks {
+		if tk.WorkerId == uuid.Nil || !tk.FlatFee.IsPositive() {
+			continue
+		}
+
+		wp := owed(tk.WorkerId)
+		wp.fees = wp.fees.Add(tk.FlatFee)
+		wp.feeTasks++
+	}
+
+	run.Items = make([]model.PayrollItem, 0, len(pay))
+	run.RegularHours, run.OvertimeHours, run.FlatFees, run.Gross = decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero
+	for wid, wp := range pay {
+		w := workers[wid]
+		item := model.PayrollItem{
+			RunId:         run.Rid,
+			WorkerId:      wid,
+			PaymentMethod: w.PaymentMethod,
+			PayRate:       w.PayRate,
+			RegularHours:  hours(wp.regular),
+			OvertimeHours: hours(wp.overtime),
+			RegularPay:    w.PayRate.Mul(decimal.NewFromInt(wp.regular)).Div(minutesPerHour).Round(2),
+			OvertimePay:   w.PayRate.Mul(run.OvertimeMultiplier).Mul(decimal.NewFromInt(wp.overtime)).Div(minutesPerHour).Round(2),
+			FlatFeeTasks:  wp.feeTasks,
+			FlatFees:      wp.fees.Round(2),
+		}
+
+		if w.User != nil {
+			item.WorkerName = model.FullName(w.User)
+		}
+
+		item.Gross = item.RegularPay.Add(item.OvertimePay).Add(item.FlatFees)
+		run.Items = append(run.Items, item)
+
+		run.RegularHours = run.RegularHours.Add(item.RegularHours)
+		run.OvertimeHours = run.OvertimeHours.Add(item.OvertimeHours)
+		run.FlatFees = run.FlatFees.Add(item.FlatFees)
+		run.Gross = run.Gross.Add(item.Gross)
+	}
+
+	sort.Slice(run.Items, func(i, j int) bool {
+		a, b := run.Items[i], run.Items[j]
+		if a.PaymentMethod != b.PaymentMethod {
+			return a.PaymentMethod < b.PaymentMethod
+		}
+		if a.WorkerName != b.WorkerName {
+			return a.WorkerName < b.WorkerName
+		}
+		return a.WorkerId.String() < b.WorkerId.String()
+	})
+
+	return run, paid
+}
+
+// writeCSV writes one row per worker in the run's item order
+func writeCSV(w io.Writer, run model.PayrollRun) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, item := range run.Items {
+		row := []string{
+			item.WorkerId.String(),
+			item.WorkerName,
+			string(item.PaymentMethod),
+			item.PayRate.StringFixed(2),
+			item.RegularHours.StringFixed(2),
+			item.OvertimeHours.StringFixed(2),
+			item.RegularPay.StringFixed(2),
+			item.OvertimePay.StringFixed(2),
+			strconv.Itoa(item.FlatFeeTasks),
+			item.FlatFees.StringFixed(2),
+			item.Gross.StringFixed(2),
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+func hours(minutes int64) decimal.Decimal {
+	return decimal.NewFromInt(minutes).Div(minutesPerHour).Round(2)
+}
+
+// weekOf keys a time by the date of the Monday that starts its week
+func weekOf(t time.Time, loc *time.Location) string {
+	local := t.In(loc)
+	offset := (int(local.Weekday()) + 6) % 7
+	return local.AddDate(0, 0, -offset).Format(time.DateOnly)
+}
+
+func workerLocation(w model.Worker) *time.Location {
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
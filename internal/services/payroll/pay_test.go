@@ -0,0 +1,150 @@
+package payroll
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestComputeRun(t *testing.T) {
+	monday := time.Date(2025, time.March, 3, 8, 0, 0, 0, time.UTC)
+	hourly, cash := uuid.New(), uuid.New()
+	flatTask := &model.Task{Tid: uuid.New(), FlatFee: decimal.NewFromInt(150), WorkerId: hourly}
+
+	entry := func(wid uuid.UUID, day int, hrs int, tk *model.Task) model.TimeEntry {
+		in := monday.AddDate(0, 0, day)
+		return model.TimeEntry{Eid: uuid.New(), WorkerId: wid, ClockIn: in, ClockOut: in.Add(time.Duration(hrs) * time.Hour), Task: tk}
+	}
+
+	in := dac.PayrollInputs{
+		Workers: []model.Worker{
+			{Uid: hourly, PayRate: decimal.NewFromInt(20), PaymentMethod: model.Check, User: &model.User{FirstName: "Ana", LastName: "Diaz"}},
+			{Uid: cash, PayRate: decimal.RequireFromString("15.50"), PaymentMethod: model.Cash, User: &model.User{FirstName: "Bo", LastName: "Li"}},
+		},
+		Entries: []model.TimeEntry{
+			entry(hourly, 0, 8, nil), entry(hourly, 1, 8, nil), entry(hourly, 2, 8, nil),
+			entry(hourly, 3, 8, nil), entry(hourly, 4, 8, nil), entry(hourly, 5, 2, nil),
+			// a new week starts the overtime count over
+			entry(hourly, 7, 1, nil),
+			entry(hourly, 8, 3, flatTask),
+			entry(cash, 2, 2, nil),
+		},
+		Tasks: []model.Task{*flatTask},
+	}
+
+	run, paid := computeRun(model.PayrollRun{Rid: uuid.New(), OvertimeAfter: defaultOvertimeAfter, OvertimeMultiplier: defaultOvertimeMultiplier}, in)
+
+	if len(paid) != len(in.Entries) {
+		t.Errorf("expected every entry to be marked paid, got %v of %v", len(paid), len(in.Entries))
+	}
+
+	if len(run.Items) != 2 {
+		t.Fatalf("expected 2 items, got %v", len(run.Items))
+	}
+
+	if run.Items[0].WorkerId != cash {
+		t.Errorf("expected cash workers to be listed first")
+	}
+
+	money := func(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+	a, b := run.Items[1], run.Items[0]
+	checks := []struct {
+		name string
+		got  decimal.Decimal
+		want decimal.Decimal
+	}{
+		{"regular hours", a.RegularHours, money("41")},
+		{"overtime hours", a.OvertimeHours, money("2")},
+		{"regular pay", a.RegularPay, money("820")},
+		{"overtime pay", a.OvertimePay, money("60")},
+		{"flat fees", a.FlatFees, money("150")},
+		{"gross", a.Gross, money("1030")},
+		{"cash gross", b.Gross, money("31")},
+		{"run gross", run.Gross, money("1061")},
+	}
+
+	for _, c := range checks {
+		if !c.got.Equal(c.want) {
+			t.Errorf("expected %v %v, got %v", c.name, c.want, c.got)
+		}
+	}
+
+	if a.FlatFeeTasks != 1 {
+		t.Errorf("expected 1 flat fee task, got %v", a.FlatFeeTasks)
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeCSV(&buf, run); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("expected a header and 2 rows, got %v lines", len(lines))
+		}
+
+		want := hourly.String() + ",Ana Diaz,check,20.00,41.00,2.00,820.00,60.00,1,150.00,1030.00"
+		if lines[2] != want {
+			t.Errorf("expected row %q, got %q", want, lines[2])
+		}
+	})
+}
+
+func TestComputeRunStartingMidWeek(t *testing.T) {
+	monday := time.Date(2025, time.March, 3, 8, 0, 0, 0, time.UTC)
+	hourly, idle := uuid.New(), uuid.New()
+	flatTask := &model.Task{Tid: uuid.New(), FlatFee: decimal.NewFromInt(150), WorkerId: hourly}
+
+	entry := func(wid uuid.UUID, day int, hrs int, tk *model.Task) model.TimeEntry {
+		in := monday.AddDate(0, 0, day)
+		return model.TimeEntry{Eid: uuid.New(), WorkerId: wid, ClockIn: in, ClockOut: in.Add(time.Duration(hrs) * time.Hour), Task: tk}
+	}
+
+	// the period starts on thursday, monday to wednesday were paid by the last run
+	in := dac.PayrollInputs{
+		Workers: []model.Worker{
+			{Uid: hourly, PayRate: decimal.NewFromInt(20), PaymentMethod: model.Check},
+			{Uid: idle, PayRate: decimal.NewFromInt(20), PaymentMethod: model.Check},
+		},
+		Earlier: []model.TimeEntry{
+			entry(hourly, 0, 10, nil), entry(hourly, 1, 10, nil), entry(hourly, 2, 10, nil),
+			// flat fee time and the week before don't count towards this week's limit
+			entry(hourly, 2, 4, flatTask),
+			entry(hourly, -3, 10, nil),
+			entry(idle, 1, 8, nil),
+		},
+		Entries: []model.TimeEntry{
+			entry(hourly, 3, 8, nil), entry(hourly, 4, 8, nil),
+		},
+	}
+
+	run, paid := computeRun(model.PayrollRun{Rid: uuid.New(), OvertimeAfter: defaultOvertimeAfter, OvertimeMultiplier: defaultOvertimeMultiplier}, in)
+
+	if len(paid) != len(in.Entries) {
+		t.Errorf("expected only the period's entries to be marked paid, got %v of %v", len(paid), len(in.Entries))
+	}
+
+	if len(run.Items) != 1 {
+		t.Fatalf("expected only workers with time in the period to be paid, got %v items", len(run.Items))
+	}
+
+	item := run.Items[0]
+	if !item.RegularHours.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("expected 10 regular hours, got %v", item.RegularHours)
+	}
+
+	if !item.OvertimeHours.Equal(decimal.NewFromInt(6)) {
+		t.Errorf("expected 6 overtime hours, got %v", item.OvertimeHours)
+	}
+
+	if !item.Gross.Equal(decimal.NewFromInt(380)) {
+		t.Errorf("expected gross 380, got %v", item.Gross)
+	}
+}
@@ -0,0 +1,211 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/ztype"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type PayrollHandler struct {
+	PayrollService
+}
+
+func NewHandler(service PayrollService) PayrollHandler {
+	return PayrollHandler{
+		PayrollService: service,
+	}
+}
+
+func (p PayrollHandler) HandlerName() string {
+	return "Payroll"
+}
+
+func (p PayrollHandler) HandlePreviewPayroll(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		p.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.PayrollRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		run, err := p.Preview(r.Context(), r.PathValue("id"), *payload)
+
+		if err != nil {
+			log.Printf("failed to preview payroll %v", err)
+			utils.WriteErr(w, payrollErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"payroll": run,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (p PayrollHandler) HandleRunPayroll(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		p.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.PayrollRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		run, err := p.RunPayroll(r.Context(), r.PathValue("id"), *payload)
+
+		if err != nil {
+			p.logger.LogFields(logrus.Fields{"msg": "failed to run payroll", "err": err})
+			log.Printf("failed to run payroll %v", err)
+			utils.WriteErr(w, payrollErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"payroll": run,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusCreated, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (p PayrollHandler) HandleGetRuns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		p.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		runs, err := p.GetRuns(r.Context(), r.PathValue("id"))
+
+		if err != nil {
+			log.Printf("failed to fetch payroll runs %v", err)
+			utils.WriteErr(w, payrollErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"payrolls": runs,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (p PayrollHandler) HandleGetRun(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		p.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		run, err := p.GetRun(r.Context(), r.PathValue("id"))
+
+		if err != nil {
+			log.Printf("failed to fetch payroll run %v", err)
+			utils.WriteErr(w, payrollErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"payroll": run,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleExportRun sends the run as a csv download, errors still go out as json
+func (p PayrollHandler) HandleExportRun(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		p.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		file, name, err := p.ExportRun(r.Context(), r.PathValue("id"))
+
+		if err != nil {
+			log.Printf("failed to export payroll run %v", err)
+			utils.WriteErr(w, payrollErrStatus(err), err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		w.WriteHeader(http.StatusOK)
+		if _, err = w.Write(file); err != nil {
+			log.Printf("failed writing csv response %v", err)
+		}
+	}
+}
+
+func payrollErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	if errors.Is(err, dac.ErrPeriodClosed) || errors.Is(err, dac.ErrStaleWrite) {
+		return http.StatusConflict
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
@@ -0,0 +1,157 @@
+package payroll
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/google/uuid"
+)
+
+type PayrollService struct {
+	repo   dac.PayrollRepo
+	logger *crane.Zlogrus
+}
+
+func (p PayrollService) ServiceName() string {
+	return "Payroll"
+}
+
+func NewPayrollService(repo dac.PayrollRepo, logr *crane.Zlogrus) PayrollService {
+	return PayrollService{
+		repo:   repo,
+		logger: logr,
+	}
+}
+
+// Preview works out the period without closing it so the lessor can check it first
+func (p PayrollService) Preview(ctx context.Context, lessorId string, req dtos.PayrollRequest) (*dtos.PayrollRunResponse, error) {
+	run, _, err := p.compute(ctx, lessorId, req, "preview")
+	if err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewPayrollRunResponse(run)
+	return &response, nil
+}
+
+// RunPayroll closes the period, periods that overlap one already closed are rejected
+func (p PayrollService) RunPayroll(ctx context.Context, lessorId string, req dtos.PayrollRequest) (*dtos.PayrollRunResponse, error) {
+	run, paid, err := p.compute(ctx, lessorId, req, "run")
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return nil, dac.ErrMissingScope{Err: err}
+	}
+	run.CreatedBy, _ = uuid.Parse(claims.Id)
+
+	if err = p.repo.Close(ctx, &run, paid); err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewPayrollRunResponse(run)
+	return &response, nil
+}
+
+func (p PayrollService) GetRun(ctx context.Context, rid string) (*dtos.PayrollRunResponse, error) {
+	run, err := p.fetch(ctx, rid, "get")
+	if err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewPayrollRunResponse(run)
+	return &response, nil
+}
+
+func (p PayrollService) GetRuns(ctx context.Context, lessorId string) ([]dtos.PayrollRunResponse, error) {
+	lid, err := p.parseId(lessorId, "list")
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := p.repo.FetchRuns(ctx, lid)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.PayrollRunResponse, 0, len(runs))
+	for _, run := range runs {
+		responses = append(responses, dtos.NewPayrollRunResponse(run))
+	}
+
+	return responses, nil
+}
+
+// ExportRun is the closed run as a csv file along with a name for it
+func (p PayrollService) ExportRun(ctx context.Context, rid string) ([]byte, string, error) {
+	run, err := p.fetch(ctx, rid, "export")
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	if err = writeCSV(&buf, run); err != nil {
+		return nil, "", err
+	}
+
+	name := fmt.Sprintf("payroll_%v_%v.csv", run.PeriodStart.Format("20060102"), run.PeriodEnd.Format("20060102"))
+	return buf.Bytes(), name, nil
+}
+
+func (p PayrollService) compute(ctx context.Context, lessorId string, req dtos.PayrollRequest, requestType string) (model.PayrollRun, []uuid.UUID, error) {
+	lid, err := p.parseId(lessorId, requestType)
+	if err != nil {
+		return model.PayrollRun{}, nil, err
+	}
+
+	in, err := p.repo.FetchInputs(ctx, lid, req.PeriodStart, req.PeriodEnd)
+	if err != nil {
+		return model.PayrollRun{}, nil, err
+	}
+
+	run := model.PayrollRun{
+		Rid:                uuid.New(),
+		LessorId:           lid,
+		PeriodStart:        req.PeriodStart,
+		PeriodEnd:          req.PeriodEnd,
+		OvertimeAfter:      req.OvertimeAfter,
+		OvertimeMultiplier: req.OvertimeMultiplier,
+	}
+
+	if run.OvertimeAfter.IsZero() {
+		run.OvertimeAfter = defaultOvertimeAfter
+	}
+
+	if run.OvertimeMultiplier.IsZero() {
+		run.OvertimeMultiplier = defaultOvertimeMultiplier
+	}
+
+	run, paid := computeRun(run, in)
+	return run, paid, nil
+}
+
+func (p PayrollService) fetch(ctx context.Context, rid string, requestType string) (model.PayrollRun, error) {
+	id, err := p.parseId(rid, requestType)
+	if err != nil {
+		return model.PayrollRun{}, err
+	}
+
+	return p.repo.FetchRun(ctx, id)
+}
+
+func (p PayrollService) parseId(id string, requestType string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, services.ErrInvalidRequest{ServiceType: p.ServiceName(), RequestType: requestType, Err: err}
+	}
+	return parsed, nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/Z3DRP/lessor-service/internal/cmerr"
 	"github.com/Z3DRP/lessor-service/internal/dtos"
 	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/services"
 	"github.com/Z3DRP/lessor-service/internal/ztype"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
 )
@@ -200,7 +201,7 @@ func (p ProfileHandler) HandleDeleteProfile(w http.ResponseWriter, r *http.Reque
 
 		if err = p.DeletePrfl(r.Context(), req); err != nil {
 			p.logger.MustDebug(fmt.Sprintf("database err, %v", err))
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -12,6 +12,7 @@ import (
 	"github.com/Z3DRP/lessor-service/internal/dac"
 	"github.com/Z3DRP/lessor-service/internal/dtos"
 	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/services"
 	"github.com/Z3DRP/lessor-service/internal/ztype"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -100,7 +101,7 @@ func (p PropertyHandler) HandleCreateProperty(w http.ResponseWriter, r *http.Req
 				"msg": "failed to create property",
 				"err": err,
 			})
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -236,7 +237,7 @@ func (p PropertyHandler) HandleGetProperties(w http.ResponseWriter, r *http.Requ
 				"msg": "service err",
 				"err": err,
 			})
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -326,7 +327,7 @@ func (p PropertyHandler) HandleUpdateProperty(w http.ResponseWriter, r *http.Req
 
 		if err != nil {
 			log.Printf("failed to update property, %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -358,7 +359,7 @@ func (p *PropertyHandler) HandleDeleteProperty(w http.ResponseWriter, r *http.Re
 
 		if err != nil {
 			log.Printf("error deleting property: %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
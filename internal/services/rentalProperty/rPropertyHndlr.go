@@ -8,6 +8,7 @@ import (
 	"github.com/Z3DRP/lessor-service/internal/dac"
 	"github.com/Z3DRP/lessor-service/internal/dtos"
 	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/services"
 	"github.com/Z3DRP/lessor-service/internal/ztype"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -47,7 +48,7 @@ func (p RentalPropertyHandler) HandleCreateRentalProperty(w http.ResponseWriter,
 				"msg": "failed to create property",
 				"err": err,
 			})
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -155,7 +156,7 @@ func (p RentalPropertyHandler) HandleGetRentalProperties(w http.ResponseWriter,
 				"msg": "service err",
 				"err": err,
 			})
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -197,7 +198,7 @@ func (p RentalPropertyHandler) HandleUpdateRentalProperty(w http.ResponseWriter,
 
 		if err != nil {
 			log.Printf("failed to update property")
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -229,7 +230,7 @@ func (p *RentalPropertyHandler) HandleDeleteRentalProperty(w http.ResponseWriter
 
 		if err != nil {
 			log.Printf("error deleting rental property: %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 		}
 
 		res := ztype.JsonResponse{
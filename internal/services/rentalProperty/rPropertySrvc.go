@@ -167,6 +167,8 @@ func newPropertyRequest(data *dtos.RentalPropertyDto) *model.RentalProperty {
 		PetFriendly:       data.PetFriendly,
 		NeedsEviction:     data.NeedsEviction,
 		EvictionStartDate: data.EvictionStartDate,
+		LateFee:           data.LateFee,
+		LateFeeGraceDays:  data.LateFeeGraceDays,
 	}
 }
 
@@ -182,5 +184,7 @@ func newPropertyModRequest(data *dtos.RentalPropertyDto) model.RentalProperty {
 		PetFriendly:       data.PetFriendly,
 		NeedsEviction:     data.NeedsEviction,
 		EvictionStartDate: data.EvictionStartDate,
+		LateFee:           data.LateFee,
+		LateFeeGraceDays:  data.LateFeeGraceDays,
 	}
 }
@@ -0,0 +1,54 @@
+package schedule
+
+import (
+	"context"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultGenerateInterval = time.Hour
+	defaultHorizon          = 60 * 24 * time.Hour
+)
+
+// Generator keeps every schedule's tasks created a horizon ahead of time, it runs as a
+// background job every interval
+type Generator struct {
+	service  ScheduleService
+	interval time.Duration
+	horizon  time.Duration
+}
+
+func NewGenerator(service ScheduleService, cfg config.SchedulerConfig) Generator {
+	g := Generator{
+		service:  service,
+		interval: time.Duration(cfg.GenerateInterval) * time.Minute,
+		horizon:  time.Duration(cfg.HorizonDays) * 24 * time.Hour,
+	}
+
+	if g.interval <= 0 {
+		g.interval = defaultGenerateInterval
+	}
+
+	if g.horizon <= 0 {
+		g.horizon = defaultHorizon
+	}
+
+	return g
+}
+
+// Spec is the job schedule the generator runs on
+func (g Generator) Spec() string {
+	return "@every " + g.interval.String()
+}
+
+func (g Generator) Run(ctx context.Context) error {
+	generated, err := g.service.Generate(ctx, time.Now(), g.horizon)
+	if generated > 0 {
+		g.service.logger.LogFields(logrus.Fields{"msg": "generated scheduled tasks", "generated": generated})
+	}
+
+	return err
+}
@@ -0,0 +1,304 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+const (
+	untilLayout     = "20060102T150405Z"
+	untilDateLayout = "20060102"
+	// maxPeriods stops a rule that never produces an occurrence from looping forever
+	maxPeriods = 20000
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+type ErrInvalidRule struct {
+	Rule string
+	Err  error
+}
+
+func (e ErrInvalidRule) Error() string {
+	return fmt.Sprintf("invalid recurrence rule %q: %v", e.Rule, e.Err)
+}
+
+func (e ErrInvalidRule) Unwrap() error {
+	return e.Err
+}
+
+// Rule is the subset of an RFC 5545 RRULE the schedules support, BYDAY only takes
+// plain weekday codes and is not allowed on yearly rules
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []time.Weekday
+	Until    time.Time
+	Count    int
+}
+
+func ParseRule(rule string) (Rule, error) {
+	r := Rule{Interval: 1}
+	invalid := func(err error) (Rule, error) {
+		return Rule{}, ErrInvalidRule{Rule: rule, Err: err}
+	}
+
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return invalid(fmt.Errorf("malformed part %q", part))
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+			if !slices.Contains([]Frequency{Daily, Weekly, Monthly, Yearly}, r.Freq) {
+				return invalid(fmt.Errorf("unsupported frequency %v", value))
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return invalid(errors.New("interval must be a positive number"))
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[strings.ToUpper(code)]
+				if !ok {
+					return invalid(fmt.Errorf("unsupported weekday %v", code))
+				}
+				if !slices.Contains(r.ByDay, day) {
+					r.ByDay = append(r.ByDay, day)
+				}
+			}
+		case "UNTIL":
+			until, err := time.Parse(untilLayout, value)
+			if err != nil {
+				if until, err = time.Parse(untilDateLayout, value); err != nil {
+					return invalid(errors.New("until must be formatted as YYYYMMDD or YYYYMMDDTHHMMSSZ"))
+				}
+				// a date only until includes the whole day
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			r.Until = until
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return invalid(errors.New("count must be a positive number"))
+			}
+			r.Count = n
+		default:
+			return invalid(fmt.Errorf("unsupported part %v", key))
+		}
+	}
+
+	if r.Freq == "" {
+		return invalid(errors.New("freq is required"))
+	}
+
+	if !r.Until.IsZero() && r.Count > 0 {
+		return invalid(errors.New("until and count cannot both be set"))
+	}
+
+	if r.Freq == Yearly && len(r.ByDay) > 0 {
+		return invalid(errors.New("byday is not supported on yearly rules"))
+	}
+
+	return r, nil
+}
+
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			codes = append(codes, strings.ToUpper(day.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Between returns the occurrences of a series starting at dtstart that fall after the
+// after time and on or before the before time
+func (r Rule) Between(dtstart, after, before time.Time) []time.Time {
+	var occurrences []time.Time
+	r.each(dtstart, func(t time.Time) bool {
+		if t.After(before) {
+			return false
+		}
+		if t.After(after) {
+			occurrences = append(occurrences, t)
+		}
+		return true
+	})
+
+	return occurrences
+}
+
+// Includes reports whether t is one of the series occurrences
+func (r Rule) Includes(dtstart, t time.Time) bool {
+	found := false
+	r.each(dtstart, func(o time.Time) bool {
+		found = o.Equal(t)
+		return o.Before(t)
+	})
+
+	return found
+}
+
+// CountBefore is the number of occurrences that happen before t
+func (r Rule) CountBefore(dtstart, t time.Time) int {
+	n := 0
+	r.each(dtstart, func(o time.Time) bool {
+		if !o.Before(t) {
+			return false
+		}
+		n++
+		return true
+	})
+
+	return n
+}
+
+// Split ends the rule just before from and returns the rule the rest of the series
+// continues with, a count is carried over as whatever is left of it
+func (r Rule) Split(dtstart, from time.Time) (Rule, Rule) {
+	head, tail := r, r
+	head.Count = 0
+	head.Until = from.Add(-time.Second)
+
+	if r.Count > 0 {
+		tail.Count = max(r.Count-r.CountBefore(dtstart, from), 1)
+	}
+
+	return head, tail
+}
+
+// each walks the occurrences in order until yield returns false or the rule runs out
+func (r Rule) each(dtstart time.Time, yield func(time.Time) bool) {
+	interval := max(r.Interval, 1)
+	n := 0
+
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.period(dtstart, period*interval) {
+			if t.Before(dtstart) {
+				continue
+			}
+
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return
+			}
+
+			n++
+			if r.Count > 0 && n > r.Count {
+				return
+			}
+
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// period lists the candidate occurrences for the period offset frequency units from dtstart
+func (r Rule) period(dtstart time.Time, offset int) []time.Time {
+	y, m, d := dtstart.Date()
+	hr, min, sec := dtstart.Clock()
+	loc := dtstart.Location()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hr, min, sec, 0, loc)
+	}
+
+	switch r.Freq {
+	case Daily:
+		t := at(y, m, d+offset)
+		if len(r.ByDay) > 0 && !slices.Contains(r.ByDay, t.Weekday()) {
+			return nil
+		}
+		return []time.Time{t}
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{dtstart.Weekday()}
+		}
+
+		// weeks start on monday like the RRULE default
+		monday := d - fromMonday(dtstart.Weekday()) + 7*offset
+		occurrences := make([]time.Time, 0, len(days))
+		for _, day := range days {
+			occurrences = append(occurrences, at(y, m, monday+fromMonday(day)))
+		}
+		slices.SortFunc(occurrences, func(a, b time.Time) int { return a.Compare(b) })
+		return occurrences
+	case Monthly:
+		first := at(y, m+time.Month(offset), 1)
+		if len(r.ByDay) == 0 {
+			// months without the day are skipped rather than rolled into the next month
+			t := at(first.Year(), first.Month(), d)
+			if t.Month() != first.Month() {
+				return nil
+			}
+			return []time.Time{t}
+		}
+
+		var occurrences []time.Time
+		for t := first; t.Month() == first.Month(); t = at(t.Year(), t.Month(), t.Day()+1) {
+			if slices.Contains(r.ByDay, t.Weekday()) {
+				occurrences = append(occurrences, t)
+			}
+		}
+		return occurrences
+	case Yearly:
+		t := at(y+offset, m, d)
+		if t.Month() != m {
+			return nil
+		}
+		return []time.Time{t}
+	default:
+		return nil
+	}
+}
+
+func fromMonday(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
@@ -0,0 +1,147 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 9, 0, 0, 0, time.UTC)
+}
+
+func TestParseRule(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		rule  string
+		valid bool
+	}{
+		{"weekly on days", "FREQ=WEEKLY;BYDAY=MO,WE", true},
+		{"rrule prefix", "RRULE:FREQ=DAILY;INTERVAL=90", true},
+		{"date only until", "FREQ=MONTHLY;UNTIL=20261231", true},
+		{"missing freq", "INTERVAL=2", false},
+		{"unknown freq", "FREQ=HOURLY", false},
+		{"zero interval", "FREQ=DAILY;INTERVAL=0", false},
+		{"until and count", "FREQ=DAILY;COUNT=3;UNTIL=20261231", false},
+		{"ordinal weekday", "FREQ=MONTHLY;BYDAY=1MO", false},
+		{"yearly by day", "FREQ=YEARLY;BYDAY=MO", false},
+		{"unsupported part", "FREQ=DAILY;BYHOUR=9", false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseRule(test.rule)
+			if test.valid && err != nil {
+				t.Errorf("expected %v to parse, got %v", test.rule, err)
+			}
+
+			var invalid ErrInvalidRule
+			if !test.valid && !errors.As(err, &invalid) {
+				t.Errorf("expected %v to be rejected, got %v", test.rule, err)
+			}
+		})
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	rule := "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;UNTIL=20261231T000000Z"
+	r, err := ParseRule(rule)
+	if err != nil {
+		t.Fatalf("failed to parse rule %v", err)
+	}
+
+	if r.String() != rule {
+		t.Errorf("expected %v, got %v", rule, r.String())
+	}
+}
+
+func TestBetween(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		after   time.Time
+		before  time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "every 90 days",
+			rule:    "FREQ=DAILY;INTERVAL=90",
+			dtstart: date(2026, time.January, 1),
+			after:   date(2025, time.December, 31),
+			before:  date(2026, time.December, 31),
+			want:    []time.Time{date(2026, time.January, 1), date(2026, time.April, 1), date(2026, time.June, 30), date(2026, time.September, 28), date(2026, time.December, 27)},
+		},
+		{
+			name:    "weekly on days skips days before start",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,TH",
+			dtstart: date(2026, time.October, 14),
+			after:   date(2026, time.October, 1),
+			before:  date(2026, time.October, 27),
+			want:    []time.Time{date(2026, time.October, 15), date(2026, time.October, 19), date(2026, time.October, 22), date(2026, time.October, 26)},
+		},
+		{
+			name:    "monthly skips short months",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: date(2026, time.January, 31),
+			after:   date(2026, time.January, 1),
+			before:  date(2027, time.January, 1),
+			want:    []time.Time{date(2026, time.January, 31), date(2026, time.March, 31), date(2026, time.May, 31)},
+		},
+		{
+			name:    "yearly until",
+			rule:    "FREQ=YEARLY;UNTIL=20281001",
+			dtstart: date(2026, time.October, 1),
+			after:   date(2026, time.January, 1),
+			before:  date(2035, time.January, 1),
+			want:    []time.Time{date(2026, time.October, 1), date(2027, time.October, 1), date(2028, time.October, 1)},
+		},
+		{
+			name:    "count is taken from the start of the series",
+			rule:    "FREQ=DAILY;COUNT=4",
+			dtstart: date(2026, time.March, 1),
+			after:   date(2026, time.March, 2),
+			before:  date(2026, time.April, 1),
+			want:    []time.Time{date(2026, time.March, 3), date(2026, time.March, 4)},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := ParseRule(test.rule)
+			if err != nil {
+				t.Fatalf("failed to parse rule %v", err)
+			}
+
+			got := r.Between(test.dtstart, test.after, test.before)
+			if len(got) != len(test.want) {
+				t.Fatalf("expected %v occurrences, got %v", test.want, got)
+			}
+
+			for i := range got {
+				if !got[i].Equal(test.want[i]) {
+					t.Errorf("occurrence %v expected %v, got %v", i, test.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	r, err := ParseRule("FREQ=WEEKLY;COUNT=10")
+	if err != nil {
+		t.Fatalf("failed to parse rule %v", err)
+	}
+
+	dtstart := date(2026, time.January, 5)
+	from := date(2026, time.February, 2)
+	head, tail := r.Split(dtstart, from)
+
+	if n := len(head.Between(dtstart, dtstart.Add(-time.Second), date(2027, time.January, 1))); n != 4 {
+		t.Errorf("expected the head to keep 4 occurrences, got %v", n)
+	}
+
+	if tail.Count != 6 {
+		t.Errorf("expected the tail to carry 6 occurrences, got %v", tail.Count)
+	}
+
+	if !r.Includes(dtstart, from) || r.Includes(dtstart, from.Add(24*time.Hour)) {
+		t.Errorf("expected only weekly occurrences to be included")
+	}
+}
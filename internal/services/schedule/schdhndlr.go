@@ -0,0 +1,290 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/availability"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/ztype"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type ScheduleHandler struct {
+	ScheduleService
+}
+
+func NewHandler(service ScheduleService) ScheduleHandler {
+	return ScheduleHandler{
+		ScheduleService: service,
+	}
+}
+
+func (s ScheduleHandler) HandlerName() string {
+	return "Schedule"
+}
+
+func (s ScheduleHandler) HandleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		s.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		payload := &dtos.ScheduleRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			s.logger.LogFields(logrus.Fields{"msg": "failed to parse request body", "err": err})
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		sched, err := s.CreateSchedule(r.Context(), payload)
+
+		if err != nil {
+			s.logger.LogFields(logrus.Fields{"msg": "failed to create schedule", "err": err})
+			log.Printf("failed to create schedule %v", err)
+			utils.WriteErr(w, scheduleErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"schedule": sched,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (s ScheduleHandler) HandleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		s.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		sid := r.PathValue("id")
+		if sid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing sid in url"))
+			return
+		}
+
+		sched, err := s.GetSchedule(r.Context(), filters.Filter{Identifier: sid, Page: 1})
+
+		if err != nil {
+			log.Printf("database err failed to fetch schedule %v", err)
+			utils.WriteErr(w, scheduleErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"schedule": sched,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (s ScheduleHandler) HandleGetSchedules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		s.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		fltr, err := filters.GenFilter(r)
+
+		if err != nil {
+			log.Printf("failed to gen filter %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		scheds, err := s.GetSchedules(r.Context(), fltr)
+
+		if err != nil {
+			log.Printf("database err failed to fetch schedules %v", err)
+			utils.WriteErr(w, scheduleErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"schedules": scheds,
+			"success":   true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleUpdateSchedule edits either one occurrence or every occurrence from the given one on
+func (s ScheduleHandler) HandleUpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		s.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		var payload dtos.ScheduleModRequest
+
+		sid := r.PathValue("id")
+		if sid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing sid in url"))
+			return
+		}
+
+		if err := utils.ParseJSON(r, &payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		var res ztype.JsonResponse
+		if payload.Scope == dtos.ThisOccurrence {
+			task, err := s.ModifyOccurrence(r.Context(), sid, &payload)
+			if err != nil {
+				log.Printf("database err failed to modify occurrence %v", err)
+				writeScheduleErr(w, scheduleErrStatus(err), err)
+				return
+			}
+			res = ztype.JsonResponse{"task": task, "success": true}
+		} else {
+			sched, err := s.ModifyFuture(r.Context(), sid, &payload)
+			if err != nil {
+				log.Printf("database err failed to modify schedule %v", err)
+				utils.WriteErr(w, scheduleErrStatus(err), err)
+				return
+			}
+			res = ztype.JsonResponse{"schedule": sched, "success": true}
+		}
+
+		if err := utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleCancelSchedule reads the scope and occurrence from the query, with no scope the
+// whole series is cancelled
+func (s ScheduleHandler) HandleCancelSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		s.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		sid := r.PathValue("id")
+		if sid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing sid in url"))
+			return
+		}
+
+		query := r.URL.Query()
+		var occurrence time.Time
+		if value := query.Get("occurrence"); value != "" {
+			var err error
+			if occurrence, err = time.Parse(time.RFC3339, value); err != nil {
+				utils.WriteErr(w, http.StatusBadRequest, fmt.Errorf("occurrence must be an RFC 3339 time %v", err))
+				return
+			}
+		}
+
+		var err error
+		switch query.Get("scope") {
+		case dtos.ThisOccurrence:
+			if occurrence.IsZero() {
+				utils.WriteErr(w, http.StatusBadRequest, errors.New("the occurrence to cancel is required"))
+				return
+			}
+			err = s.CancelOccurrence(r.Context(), sid, occurrence)
+		case dtos.FutureOccurrences, "":
+			err = s.CancelFuture(r.Context(), sid, occurrence)
+		default:
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("scope must be this or future"))
+			return
+		}
+
+		if err != nil {
+			log.Printf("database err failed to cancel schedule %v", err)
+			utils.WriteErr(w, scheduleErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"scheduleId": sid,
+			"success":    true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func scheduleErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
+
+// writeScheduleErr sends the tasks an occurrence would overlap back as a 409 so the caller
+// can pick another time, everything else is written as usual
+func writeScheduleErr(w http.ResponseWriter, status int, err error) {
+	var conflict availability.ErrScheduleConflict
+	if errors.As(err, &conflict) {
+		res := ztype.JsonResponse{
+			"error":     err.Error(),
+			"conflicts": conflict.Conflicts,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusConflict, res); err != nil {
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteErr(w, status, err)
+}
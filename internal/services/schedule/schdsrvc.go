@@ -0,0 +1,436 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/availability"
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// upcomingCount is how many future occurrences are listed with a schedule
+const upcomingCount = 5
+
+type ScheduleService struct {
+	repo         dac.ScheduleRepo
+	tasks        dac.TaskRepo
+	availability dac.AvailabilityRepo
+	logger       *crane.Zlogrus
+}
+
+func (s ScheduleService) ServiceName() string {
+	return "Schedule"
+}
+
+func NewScheduleService(repo dac.ScheduleRepo, tasks dac.TaskRepo, avail dac.AvailabilityRepo, logr *crane.Zlogrus) ScheduleService {
+	return ScheduleService{
+		repo:         repo,
+		tasks:        tasks,
+		availability: avail,
+		logger:       logr,
+	}
+}
+
+func (s ScheduleService) GetSchedule(ctx context.Context, fltr filters.Filter) (*dtos.ScheduleResponse, error) {
+	sched, err := s.fetch(ctx, fltr.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.newResponse(sched)
+	return &response, nil
+}
+
+func (s ScheduleService) GetSchedules(ctx context.Context, fltr filters.Filter) ([]dtos.ScheduleResponse, error) {
+	scheds, err := s.repo.FetchAll(ctx, fltr)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.ScheduleResponse, 0, len(scheds))
+	for _, sched := range scheds {
+		responses = append(responses, s.newResponse(sched))
+	}
+
+	return responses, nil
+}
+
+func (s ScheduleService) CreateSchedule(ctx context.Context, sdata *dtos.ScheduleRequest) (*dtos.ScheduleResponse, error) {
+	rule, err := ParseRule(sdata.Rule)
+	if err != nil {
+		return nil, services.ErrInvalidRequest{ServiceType: s.ServiceName(), RequestType: "create", Err: err}
+	}
+
+	sched := &model.TaskSchedule{
+		Sid:           uuid.New(),
+		LessorId:      utils.ParseUuid(sdata.LessorId),
+		PropertyId:    utils.ParseUuid(sdata.PropertyId),
+		Name:          sdata.Name,
+		Details:       sdata.Details,
+		Notes:         sdata.Notes,
+		Priority:      model.PriorityLevel(sdata.Priority),
+		Category:      model.TaskCategory(sdata.Category),
+		WorkerId:      utils.ParseUuid(sdata.WorkerId),
+		EstimatedCost: sdata.EstimatedCost,
+		Rule:          rule.String(),
+		StartsAt:      sdata.StartsAt,
+	}
+
+	if _, err = s.repo.Insert(ctx, sched); err != nil {
+		return nil, err
+	}
+
+	response := s.newResponse(*sched)
+	return &response, nil
+}
+
+// ModifyOccurrence edits the task for a single occurrence. Occurrences past the generated
+// horizon don't have a task yet so one is created for them, the generator then skips it.
+// Moving the occurrence or handing it to another worker is checked like any task update
+func (s ScheduleService) ModifyOccurrence(ctx context.Context, sid string, req *dtos.ScheduleModRequest) (*dtos.TaskResponse, error) {
+	sched, rule, err := s.fetchWithRule(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.checkOccurrence(sched, rule, req.OccurrenceAt, "modify"); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FetchOccurrence(ctx, sched.Sid, req.OccurrenceAt)
+	if err != nil {
+		var noResults dac.ErrNoResults
+		if !errors.As(err, &noResults) {
+			return nil, err
+		}
+
+		tsk := sched.NewOccurrence(req.OccurrenceAt)
+		applyOccurrenceChanges(&tsk, req)
+
+		if err = availability.Check(ctx, s.availability, tsk, tsk.WorkerId); err != nil {
+			return nil, err
+		}
+
+		created, err := s.tasks.Insert(ctx, &tsk)
+		if err != nil {
+			return nil, err
+		}
+
+		tk, ok := created.(*model.Task)
+		if !ok {
+			return nil, cmerr.ErrUnexpectedData{Wanted: &model.Task{}, Got: created}
+		}
+
+		response := dtos.NewTaskResposne(tk, nil)
+		return &response, nil
+	}
+
+	// only the changed columns are written so a status change running alongside isn't undone
+	changes := model.Task{Tid: existing.Tid}
+	applyOccurrenceChanges(&changes, req)
+
+	if err = s.checkReschedule(ctx, changes); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.tasks.Update(ctx, &changes)
+	if err != nil {
+		return nil, err
+	}
+
+	tk, ok := updated.(*model.Task)
+	if !ok {
+		return nil, cmerr.ErrUnexpectedData{Wanted: &model.Task{}, Got: updated}
+	}
+
+	response := dtos.NewTaskResposne(tk, nil)
+	return &response, nil
+}
+
+// ModifyFuture ends the series before the occurrence and continues it as a new schedule
+// with the changes applied, without an occurrence the whole series is replaced
+func (s ScheduleService) ModifyFuture(ctx context.Context, sid string, req *dtos.ScheduleModRequest) (*dtos.ScheduleResponse, error) {
+	sched, rule, err := s.fetchWithRule(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+
+	from := req.OccurrenceAt
+	if from.IsZero() {
+		from = sched.StartsAt
+	}
+
+	if err = s.checkOccurrence(sched, rule, from, "modify"); err != nil {
+		return nil, err
+	}
+
+	head, tail := rule.Split(sched.StartsAt, from)
+	if req.Rule != "" {
+		if tail, err = ParseRule(req.Rule); err != nil {
+			return nil, services.ErrInvalidRequest{ServiceType: s.ServiceName(), RequestType: "modify", Err: err}
+		}
+	}
+
+	next := sched
+	next.Id = 0
+	next.Sid = uuid.New()
+	next.Property = nil
+	next.Rule = tail.String()
+	next.StartsAt = from
+	next.GeneratedThrough = time.Time{}
+	next.CreatedAt = time.Time{}
+	next.UpdatedAt = time.Time{}
+	next.ExDates = nil
+	for _, ex := range sched.ExDates {
+		if !ex.Before(from) {
+			next.ExDates = append(next.ExDates, ex)
+		}
+	}
+
+	if !req.StartsAt.IsZero() {
+		next.StartsAt = req.StartsAt
+	}
+	applyTemplateChanges(&next, req)
+
+	current := sched
+	current.Rule = head.String()
+	if !from.After(sched.StartsAt) {
+		current.CancelledAt = time.Now()
+	}
+
+	if err = s.repo.Split(ctx, &current, &next, from); err != nil {
+		return nil, err
+	}
+
+	response := s.newResponse(next)
+	return &response, nil
+}
+
+func (s ScheduleService) CancelOccurrence(ctx context.Context, sid string, occurrence time.Time) error {
+	sched, rule, err := s.fetchWithRule(ctx, sid)
+	if err != nil {
+		return err
+	}
+
+	if err = s.checkOccurrence(sched, rule, occurrence, "cancel"); err != nil {
+		return err
+	}
+
+	return s.repo.Exclude(ctx, sched, occurrence)
+}
+
+// CancelFuture ends the series before the occurrence, without an occurrence the whole
+// series is cancelled
+func (s ScheduleService) CancelFuture(ctx context.Context, sid string, from time.Time) error {
+	sched, rule, err := s.fetchWithRule(ctx, sid)
+	if err != nil {
+		return err
+	}
+
+	if from.IsZero() {
+		from = sched.StartsAt
+	}
+
+	if err = s.checkOccurrence(sched, rule, from, "cancel"); err != nil {
+		return err
+	}
+
+	head, _ := rule.Split(sched.StartsAt, from)
+	sched.Rule = head.String()
+	if !from.After(sched.StartsAt) {
+		sched.CancelledAt = time.Now()
+	}
+
+	return s.repo.Truncate(ctx, &sched, from)
+}
+
+// Generate creates the tasks for every occurrence between each schedule's last generated
+// point and the horizon. A schedule that fails is logged and retried on the next run
+func (s ScheduleService) Generate(ctx context.Context, now time.Time, horizon time.Duration) (int, error) {
+	through := now.Add(horizon)
+	scheds, err := s.repo.FetchDue(ctx, through)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	generated := 0
+	for _, sched := range scheds {
+		rule, err := ParseRule(sched.Rule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %v: %w", sched.Sid, err))
+			continue
+		}
+
+		after := sched.GeneratedThrough
+		if after.IsZero() {
+			after = sched.StartsAt.Add(-time.Nanosecond)
+		}
+
+		tasks := make([]model.Task, 0)
+		for _, occurrence := range rule.Between(sched.StartsAt, after, through) {
+			if !sched.Excluded(occurrence) {
+				tasks = append(tasks, sched.NewOccurrence(occurrence))
+			}
+		}
+
+		created, err := s.repo.Materialize(ctx, sched, tasks, through)
+		if err != nil {
+			s.logger.LogFields(logrus.Fields{"msg": "failed to generate scheduled tasks", "schedule": sched.Sid, "err": err})
+			errs = append(errs, fmt.Errorf("schedule %v: %w", sched.Sid, err))
+			continue
+		}
+
+		generated += len(created)
+	}
+
+	return generated, errors.Join(errs...)
+}
+
+func (s ScheduleService) fetch(ctx context.Context, sid string) (model.TaskSchedule, error) {
+	sched, err := s.repo.Fetch(ctx, filters.Filter{Identifier: sid, Page: 1})
+	if err != nil {
+		return model.TaskSchedule{}, err
+	}
+
+	schd, ok := sched.(model.TaskSchedule)
+	if !ok {
+		return model.TaskSchedule{}, cmerr.ErrUnexpectedData{Wanted: model.TaskSchedule{}, Got: sched}
+	}
+
+	return schd, nil
+}
+
+func (s ScheduleService) fetchWithRule(ctx context.Context, sid string) (model.TaskSchedule, Rule, error) {
+	sched, err := s.fetch(ctx, sid)
+	if err != nil {
+		return model.TaskSchedule{}, Rule{}, err
+	}
+
+	rule, err := ParseRule(sched.Rule)
+	if err != nil {
+		log.Printf("stored schedule %v has an invalid rule %v", sched.Sid, err)
+		return model.TaskSchedule{}, Rule{}, err
+	}
+
+	return sched, rule, nil
+}
+
+func (s ScheduleService) checkOccurrence(sched model.TaskSchedule, rule Rule, occurrence time.Time, request string) error {
+	if !sched.CancelledAt.IsZero() {
+		return services.ErrInvalidRequest{ServiceType: s.ServiceName(), RequestType: request, Err: errors.New("schedule has been cancelled")}
+	}
+
+	if !rule.Includes(sched.StartsAt, occurrence) || sched.Excluded(occurrence) {
+		return services.ErrInvalidRequest{
+			ServiceType: s.ServiceName(),
+			RequestType: request,
+			Err:         fmt.Errorf("%v is not an occurrence of the schedule", occurrence),
+		}
+	}
+
+	return nil
+}
+
+func (s ScheduleService) newResponse(sched model.TaskSchedule) dtos.ScheduleResponse {
+	var upcoming []time.Time
+	if rule, err := ParseRule(sched.Rule); err == nil && sched.CancelledAt.IsZero() {
+		after := time.Now()
+		if sched.StartsAt.After(after) {
+			after = sched.StartsAt.Add(-time.Nanosecond)
+		}
+
+		rule.each(sched.StartsAt, func(t time.Time) bool {
+			if t.After(after) && !sched.Excluded(t) {
+				upcoming = append(upcoming, t)
+			}
+			return len(upcoming) < upcomingCount
+		})
+	}
+
+	return dtos.NewScheduleResponse(sched, upcoming)
+}
+
+// checkReschedule loads the occurrence's task with its crew when the changes move it or
+// hand it to another worker
+func (s ScheduleService) checkReschedule(ctx context.Context, changes model.Task) error {
+	if changes.WorkerId == uuid.Nil && changes.ScheduledAt.IsZero() {
+		return nil
+	}
+
+	current, err := s.tasks.Fetch(ctx, filters.Filter{Identifier: changes.Tid.String(), Page: 1, Limit: 1})
+	if err != nil {
+		return err
+	}
+
+	tsk, ok := current.(model.Task)
+	if !ok {
+		return cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: current}
+	}
+
+	return availability.CheckReschedule(ctx, s.availability, tsk, changes)
+}
+
+func applyOccurrenceChanges(tk *model.Task, req *dtos.ScheduleModRequest) {
+	if req.Name != "" {
+		tk.Name = req.Name
+	}
+	if req.Details != "" {
+		tk.Details = req.Details
+	}
+	if req.Notes != "" {
+		tk.Notes = req.Notes
+	}
+	if req.Priority != "" {
+		tk.Priority = model.PriorityLevel(req.Priority)
+	}
+	if req.Category != "" {
+		tk.Category = model.TaskCategory(req.Category)
+	}
+	if req.WorkerId != "" {
+		tk.WorkerId = utils.ParseUuid(req.WorkerId)
+	}
+	if !req.EstimatedCost.IsZero() {
+		tk.EstimatedCost = req.EstimatedCost
+	}
+	// moving an occurrence keeps its occurrence time so it is still matched to the series
+	if !req.ScheduledAt.IsZero() {
+		tk.ScheduledAt = req.ScheduledAt
+	}
+}
+
+func applyTemplateChanges(sched *model.TaskSchedule, req *dtos.ScheduleModRequest) {
+	if req.Name != "" {
+		sched.Name = req.Name
+	}
+	if req.Details != "" {
+		sched.Details = req.Details
+	}
+	if req.Notes != "" {
+		sched.Notes = req.Notes
+	}
+	if req.Priority != "" {
+		sched.Priority = model.PriorityLevel(req.Priority)
+	}
+	if req.Category != "" {
+		sched.Category = model.TaskCategory(req.Category)
+	}
+	if req.WorkerId != "" {
+		sched.WorkerId = utils.ParseUuid(req.WorkerId)
+	}
+	if !req.EstimatedCost.IsZero() {
+		sched.EstimatedCost = req.EstimatedCost
+	}
+}
@@ -1,6 +1,12 @@
 package services
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+)
 
 type Service interface {
 	ServiceName() string
@@ -19,3 +25,18 @@ func (e ErrInvalidRequest) Error() string {
 func (e ErrInvalidRequest) Unwrap() error {
 	return e.Err
 }
+
+// StatusFor maps repo scoping errors to their response status, any other error uses the fallback
+func StatusFor(err error, fallback int) int {
+	var crossTenant dac.ErrCrossTenant
+	if errors.As(err, &crossTenant) {
+		return http.StatusForbidden
+	}
+
+	var missingScope dac.ErrMissingScope
+	if errors.As(err, &missingScope) {
+		return http.StatusUnauthorized
+	}
+
+	return fallback
+}
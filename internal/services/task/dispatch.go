@@ -0,0 +1,283 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Z3DRP/lessor-service/internal/availability"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/pkg/geo"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+var ErrNoCandidate = errors.New("no available worker to dispatch")
+
+// the factor weights add up to 100, a worker that is booked still gets ranked but is listed
+// after every available worker
+const (
+	skillPoints        = 40.0
+	availabilityPoints = 30.0
+	workloadPoints     = 20.0
+	distancePoints     = 10.0
+	pointsPerOpenTask  = 5.0
+	maxDispatchKm      = 50.0
+)
+
+// categoryKeywords are the words in a worker's specialization that count as a match for
+// the category
+var categoryKeywords = map[model.TaskCategory][]string{
+	model.Maintenance:        {"maintenance", "repair", "plumb", "electric", "hvac"},
+	model.Service:            {"service", "clean", "landscap", "pest"},
+	model.Installation:       {"install", "electric", "plumb", "hvac"},
+	model.Project:            {"project", "renovat", "construct", "carpent"},
+	model.ClientService:      {"service", "clean", "customer"},
+	model.ClientInstallation: {"install"},
+}
+
+var generalistKeywords = []string{"general", "handyman"}
+
+// GetCandidates ranks the lessor's workers for the task, workers already on the crew are left out
+func (t TaskService) GetCandidates(ctx context.Context, tid string) ([]dtos.CandidateResponse, error) {
+	if _, err := t.parseTid(tid, "candidates"); err != nil {
+		return nil, err
+	}
+
+	tsk, err := t.fetchTask(ctx, tid)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.candidates(ctx, tsk)
+}
+
+// Dispatch makes the best available candidate the lead, it is only for immediate tasks that
+// nobody has been put on yet
+func (t TaskService) Dispatch(ctx context.Context, tid string) (*dtos.CandidateResponse, error) {
+	id, err := t.parseTid(tid, "dispatch")
+	if err != nil {
+		return nil, err
+	}
+
+	tsk, err := t.fetchTask(ctx, tid)
+	if err != nil {
+		return nil, err
+	}
+
+	if tsk.Priority != model.Immediate {
+		return nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "dispatch", Err: errors.New("only immediate tasks can be auto assigned")}
+	}
+
+	if tsk.WorkerId != uuid.Nil {
+		return nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "dispatch", Err: errors.New("task already has a lead worker")}
+	}
+
+	candidates, err := t.candidates(ctx, tsk)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(candidates) == 0 || !candidates[0].Available {
+		return nil, ErrNoCandidate
+	}
+
+	best := candidates[0]
+	wid := utils.ParseUuid(best.WorkerId)
+	if err = t.repo.AddWorkers(ctx, id, []uuid.UUID{wid}, wid); err != nil {
+		return nil, err
+	}
+
+	return &best, nil
+}
+
+func (t TaskService) candidates(ctx context.Context, tsk model.Task) ([]dtos.CandidateResponse, error) {
+	pool, err := t.repo.FetchDispatchPool(ctx, tsk)
+	if err != nil {
+		return nil, err
+	}
+
+	onCrew := make(map[uuid.UUID]bool)
+	for _, at := range tsk.Assignments {
+		if at.Status != model.AssignmentDeclined {
+			onCrew[at.WorkerId] = true
+		}
+	}
+
+	taskAt := propertyLocation(tsk.Property)
+	start, end := tsk.Window()
+	candidates := make([]dtos.CandidateResponse, 0, len(pool))
+	for _, dw := range pool {
+		if onCrew[dw.Worker.Uid] || dw.Worker.Uid == tsk.WorkerId {
+			continue
+		}
+
+		var conflicts []availability.Conflict
+		if !tsk.ScheduledAt.IsZero() {
+			wc, err := t.availability.FetchCalendar(ctx, dw.Worker.Uid, start, end, tsk.Tid)
+			if err != nil {
+				return nil, err
+			}
+			conflicts = availability.NewCalendar(wc).Conflicts(start, end)
+		}
+
+		candidates = append(candidates, rankCandidate(tsk, taskAt, dw, conflicts))
+	}
+
+	sortCandidates(candidates)
+	return candidates, nil
+}
+
+// rankCandidate scores one worker for the task, conflicts are the worker's conflicts at the
+// task's scheduled time and taskAt is where the task is if its property has been geocoded
+func rankCandidate(tk model.Task, taskAt geo.Location, dw dac.DispatchWorker, conflicts []availability.Conflict) dtos.CandidateResponse {
+	if conflicts == nil {
+		conflicts = []availability.Conflict{}
+	}
+
+	candidate := dtos.CandidateResponse{
+		WorkerId:      dw.Worker.Uid.String(),
+		Specilization: dw.Worker.Specilization,
+		Available:     len(conflicts) == 0,
+		OpenTasks:     dw.OpenTasks,
+		Conflicts:     conflicts,
+	}
+
+	if dw.Worker.User != nil {
+		candidate.Name = model.FullName(dw.Worker.User)
+	}
+
+	candidate.Factors = []dtos.ScoreFactor{
+		skillFactor(dw.Worker.Specilization, tk.Category),
+		availabilityFactor(tk, conflicts),
+		workloadFactor(dw.OpenTasks),
+	}
+
+	distance, km := distanceFactor(taskAt, addressLocation(dw.LastAddress))
+	candidate.Factors = append(candidate.Factors, distance)
+	candidate.DistanceKm = km
+
+	for _, f := range candidate.Factors {
+		candidate.Score += f.Points
+	}
+	candidate.Score = math.Round(candidate.Score*10) / 10
+
+	return candidate
+}
+
+// sortCandidates puts available workers first, then the highest score, then the lightest load
+func sortCandidates(candidates []dtos.CandidateResponse) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Available != b.Available {
+			return a.Available
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.OpenTasks < b.OpenTasks
+	})
+}
+
+func skillFactor(specialization string, category model.TaskCategory) dtos.ScoreFactor {
+	factor := dtos.ScoreFactor{Factor: "skill", Max: skillPoints}
+	spec := strings.ToLower(strings.TrimSpace(specialization))
+
+	switch {
+	case spec == "":
+		factor.Points = skillPoints / 4
+		factor.Detail = "no specialization on file"
+	case containsAny(spec, categoryKeywords[category]):
+		factor.Points = skillPoints
+		factor.Detail = fmt.Sprintf("%v matches %v work", specialization, category)
+	case containsAny(spec, generalistKeywords):
+		factor.Points = skillPoints / 2
+		factor.Detail = fmt.Sprintf("%v can cover %v work", specialization, category)
+	default:
+		factor.Detail = fmt.Sprintf("%v does not match %v work", specialization, category)
+	}
+
+	return factor
+}
+
+func availabilityFactor(tk model.Task, conflicts []availability.Conflict) dtos.ScoreFactor {
+	factor := dtos.ScoreFactor{Factor: "availability", Max: availabilityPoints}
+
+	switch {
+	case tk.ScheduledAt.IsZero():
+		factor.Points = availabilityPoints
+		factor.Detail = "task is not scheduled yet"
+	case len(conflicts) == 0:
+		factor.Points = availabilityPoints
+		factor.Detail = "free at the scheduled time"
+	default:
+		reasons := make([]string, 0, len(conflicts))
+		for _, c := range conflicts {
+			if c.TaskName != "" {
+				reasons = append(reasons, fmt.Sprintf("%v (%v)", c.Reason, c.TaskName))
+				continue
+			}
+			reasons = append(reasons, string(c.Reason))
+		}
+		factor.Detail = "not available, " + strings.Join(reasons, ", ")
+	}
+
+	return factor
+}
+
+func workloadFactor(open int) dtos.ScoreFactor {
+	return dtos.ScoreFactor{
+		Factor: "workload",
+		Points: math.Max(0, workloadPoints-float64(open)*pointsPerOpenTask),
+		Max:    workloadPoints,
+		Detail: fmt.Sprintf("%v open tasks", open),
+	}
+}
+
+// distanceFactor gives full points next door down to none at maxDispatchKm, when either end
+// has no coordinates the worker gets half so they aren't punished for missing data
+func distanceFactor(taskAt geo.Location, lastAt geo.Location) (dtos.ScoreFactor, *float64) {
+	factor := dtos.ScoreFactor{Factor: "distance", Max: distancePoints}
+
+	if !taskAt.Known() || !lastAt.Known() {
+		factor.Points = distancePoints / 2
+		factor.Detail = "no location to compare"
+		return factor, nil
+	}
+
+	km := math.Round(geo.Distance(taskAt, lastAt)*10) / 10
+	factor.Points = math.Round(distancePoints*math.Max(0, 1-km/maxDispatchKm)*10) / 10
+	factor.Detail = fmt.Sprintf("%v km from their last job", km)
+	return factor, &km
+}
+
+func propertyLocation(p *model.Property) geo.Location {
+	if p == nil {
+		return geo.Location{}
+	}
+	return addressLocation(p.Address)
+}
+
+func addressLocation(address json.RawMessage) geo.Location {
+	var addr model.Address
+	if len(address) == 0 || json.Unmarshal(address, &addr) != nil {
+		return geo.Location{}
+	}
+	return geo.Location{Latitude: addr.Lat, Longitude: addr.Lng}
+}
+
+func containsAny(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
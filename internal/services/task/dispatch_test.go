@@ -0,0 +1,86 @@
+package task
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/availability"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/pkg/geo"
+	"github.com/google/uuid"
+)
+
+func dispatchWorker(spec string, open int, lat, lng float64) dac.DispatchWorker {
+	addr, _ := json.Marshal(model.Address{Lat: lat, Lng: lng})
+	return dac.DispatchWorker{
+		Worker:      model.Worker{Uid: uuid.New(), Specilization: spec},
+		OpenTasks:   open,
+		LastAddress: addr,
+	}
+}
+
+func TestRankCandidate(t *testing.T) {
+	tk := model.Task{Category: model.Maintenance, ScheduledAt: time.Now()}
+	taskAt := geo.Location{Latitude: 40.7128, Longitude: -74.0060}
+
+	best := rankCandidate(tk, taskAt, dispatchWorker("Plumbing and repair", 0, 40.7128, -74.0060), nil)
+	if best.Score != 100 {
+		t.Errorf("expected a perfect score, got %v %+v", best.Score, best.Factors)
+	}
+
+	far := rankCandidate(tk, taskAt, dispatchWorker("Painter", 2, 40.0, -75.0), nil)
+	if far.Score >= best.Score {
+		t.Errorf("expected the unmatched, busy, far worker to score lower, got %v", far.Score)
+	}
+	if far.DistanceKm == nil || *far.DistanceKm < 100 {
+		t.Errorf("expected a distance over 100km, got %v", far.DistanceKm)
+	}
+
+	unknown := rankCandidate(tk, geo.Location{}, dac.DispatchWorker{Worker: model.Worker{Uid: uuid.New()}}, nil)
+	if unknown.DistanceKm != nil || unknown.Factors[3].Points != distancePoints/2 {
+		t.Errorf("expected half distance points without a location, got %+v", unknown.Factors[3])
+	}
+
+	booked := rankCandidate(tk, taskAt, dispatchWorker("repair", 0, 40.7128, -74.0060), []availability.Conflict{{Reason: availability.Booked, TaskName: "fix sink"}})
+	if booked.Available || booked.Factors[1].Points != 0 {
+		t.Errorf("expected a booked worker to be unavailable with no availability points, got %+v", booked)
+	}
+}
+
+func TestSkillFactor(t *testing.T) {
+	for _, test := range []struct {
+		spec     string
+		category model.TaskCategory
+		points   float64
+	}{
+		{"Licensed Electrician", model.Installation, skillPoints},
+		{"general handyman", model.Project, skillPoints / 2},
+		{"", model.Service, skillPoints / 4},
+		{"Landscaping", model.Installation, 0},
+	} {
+		if f := skillFactor(test.spec, test.category); f.Points != test.points {
+			t.Errorf("%q for %v expected %v points, got %v", test.spec, test.category, test.points, f.Points)
+		}
+	}
+}
+
+func TestSortCandidates(t *testing.T) {
+	candidates := []dtos.CandidateResponse{
+		{WorkerId: "booked", Score: 90, Available: false},
+		{WorkerId: "busy", Score: 70, Available: true, OpenTasks: 3},
+		{WorkerId: "best", Score: 80, Available: true},
+		{WorkerId: "idle", Score: 70, Available: true, OpenTasks: 0},
+	}
+
+	sortCandidates(candidates)
+
+	want := []string{"best", "idle", "busy", "booked"}
+	for i, id := range want {
+		if candidates[i].WorkerId != id {
+			t.Errorf("position %v expected %v, got %v", i, id, candidates[i].WorkerId)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package task
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/pkg/geo"
+)
+
+type stop struct {
+	task model.Task
+	at   geo.Location
+}
+
+// GetWorkerRoute orders the worker's tasks for the day into a driving sequence, date is
+// YYYY-MM-DD in the worker's timezone and defaults to today
+func (t TaskService) GetWorkerRoute(ctx context.Context, workerId string, date string) (*dtos.RouteResponse, error) {
+	wid, err := t.workerFor(ctx, workerId, "route")
+	if err != nil {
+		return nil, err
+	}
+
+	wrkr, err := t.availability.FetchWorker(ctx, wid)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(wrkr.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	day := time.Now().In(loc)
+	if date != "" {
+		if day, err = time.ParseInLocation(time.DateOnly, date, loc); err != nil {
+			return nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "route", Err: err}
+		}
+	}
+
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	tasks, err := t.repo.FetchWorkerDay(ctx, wid, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	route, unrouted := planRoute(tasks)
+	response := dtos.RouteResponse{
+		WorkerId:    wid.String(),
+		Date:        from.Format(time.DateOnly),
+		Timezone:    loc.String(),
+		Stops:       make([]dtos.RouteStop, 0, len(route)),
+		Unrouted:    make([]dtos.RouteStop, 0, len(unrouted)),
+		TotalKm:     roundKm(routeKm(nil, route, nil)),
+		ScheduledKm: roundKm(routeKm(nil, scheduledOrder(route), nil)),
+		Path:        dtos.LineString{Type: "LineString", Coordinates: make([][]float64, 0, len(route))},
+	}
+
+	for i, s := range route {
+		rs := routeStop(s)
+		rs.Sequence = i + 1
+		if i > 0 {
+			rs.LegKm = roundKm(geo.Distance(route[i-1].at, s.at))
+		}
+		response.Stops = append(response.Stops, rs)
+		// geojson positions are longitude first
+		response.Path.Coordinates = append(response.Path.Coordinates, []float64{s.at.Longitude, s.at.Latitude})
+	}
+
+	for _, s := range unrouted {
+		response.Unrouted = append(response.Unrouted, routeStop(s))
+	}
+
+	return &response, nil
+}
+
+// planRoute sequences the day's stops. Appointments stay at their time and split the day into
+// legs, the other tasks stay in the leg they were scheduled in and are visited by priority,
+// with each priority ordered nearest neighbour first then tidied up with 2-opt. Tasks whose
+// property has no coordinates come back as unrouted
+func planRoute(tasks []model.Task) ([]stop, []stop) {
+	stops := make([]stop, 0, len(tasks))
+	unrouted := make([]stop, 0)
+	for _, tk := range tasks {
+		s := stop{task: tk, at: propertyLocation(tk.Property)}
+		if !s.at.Known() {
+			unrouted = append(unrouted, s)
+			continue
+		}
+		stops = append(stops, s)
+	}
+
+	stops = scheduledOrder(stops)
+	route := make([]stop, 0, len(stops))
+	pending := make([]stop, 0)
+	for _, s := range stops {
+		if !s.task.Appointment {
+			pending = append(pending, s)
+			continue
+		}
+
+		route = append(route, planLeg(lastStop(route), pending, &s.at)...)
+		route = append(route, s)
+		pending = pending[:0]
+	}
+
+	route = append(route, planLeg(lastStop(route), pending, nil)...)
+	return route, unrouted
+}
+
+// planLeg orders the stops between from and to, either end can be nil at the start or end of
+// the day
+func planLeg(from *geo.Location, stops []stop, to *geo.Location) []stop {
+	tiers := make(map[int][]stop)
+	for _, s := range stops {
+		rank := priorityRank(s.task.Priority)
+		tiers[rank] = append(tiers[rank], s)
+	}
+
+	ranks := make([]int, 0, len(tiers))
+	for rank := range tiers {
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+
+	leg := make([]stop, 0, len(stops))
+	for i, rank := range ranks {
+		var end *geo.Location
+		if i == len(ranks)-1 {
+			end = to
+		}
+
+		ordered := twoOpt(from, nearestNeighbour(from, tiers[rank]), end)
+		leg = append(leg, ordered...)
+		from = lastStop(leg)
+	}
+
+	return leg
+}
+
+// nearestNeighbour starts from the earliest scheduled stop when there is nowhere to start from
+func nearestNeighbour(from *geo.Location, stops []stop) []stop {
+	left := append([]stop(nil), stops...)
+	ordered := make([]stop, 0, len(stops))
+
+	for len(left) > 0 {
+		next := 0
+		if from != nil {
+			best := math.Inf(1)
+			for i, s := range left {
+				if d := geo.Distance(*from, s.at); d < best {
+					best, next = d, i
+				}
+			}
+		}
+
+		at := left[next].at
+		ordered = append(ordered, left[next])
+		from = &at
+		left = append(left[:next], left[next+1:]...)
+	}
+
+	return ordered
+}
+
+// twoOpt keeps reversing stretches of the route while doing so makes it shorter
+func twoOpt(from *geo.Location, route []stop, to *geo.Location) []stop {
+	best := routeKm(from, route, to)
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(route)-1; i++ {
+			for j := i + 1; j < len(route); j++ {
+				candidate := append([]stop(nil), route...)
+				for l, r := i, j; l < r; l, r = l+1, r-1 {
+					candidate[l], candidate[r] = candidate[r], candidate[l]
+				}
+
+				if km := routeKm(from, candidate, to); km < best-1e-9 {
+					route, best, improved = candidate, km, true
+				}
+			}
+		}
+	}
+
+	return route
+}
+
+func routeKm(from *geo.Location, route []stop, to *geo.Location) float64 {
+	km := 0.0
+	prev := from
+	for i := range route {
+		if prev != nil {
+			km += geo.Distance(*prev, route[i].at)
+		}
+		prev = &route[i].at
+	}
+
+	if prev != nil && to != nil {
+		km += geo.Distance(*prev, *to)
+	}
+
+	return km
+}
+
+func scheduledOrder(stops []stop) []stop {
+	ordered := append([]stop(nil), stops...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].task.ScheduledAt.Before(ordered[j].task.ScheduledAt)
+	})
+	return ordered
+}
+
+func lastStop(route []stop) *geo.Location {
+	if len(route) == 0 {
+		return nil
+	}
+	return &route[len(route)-1].at
+}
+
+func priorityRank(p model.PriorityLevel) int {
+	switch p {
+	case model.Immediate:
+		return 0
+	case model.High:
+		return 1
+	case model.Low:
+		return 3
+	default:
+		return 2
+	}
+}
+
+func routeStop(s stop) dtos.RouteStop {
+	return dtos.RouteStop{
+		TaskId:      s.task.Tid.String(),
+		Name:        s.task.Name,
+		Priority:    string(s.task.Priority),
+		ScheduledAt: s.task.ScheduledAt,
+		Appointment: s.task.Appointment,
+		PropertyId:  s.task.PropertyId.String(),
+		Lat:         s.at.Latitude,
+		Lng:         s.at.Longitude,
+	}
+}
+
+func roundKm(km float64) float64 {
+	return math.Round(km*100) / 100
+}
@@ -0,0 +1,97 @@
+package task
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+var routeDay = time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+
+// routeTask puts the task on the equator, x is degrees of longitude so stops are easy to picture
+func routeTask(name string, x float64, hour int, priority model.PriorityLevel, appointment bool) model.Task {
+	addr, _ := json.Marshal(model.Address{Lat: 0.001, Lng: x})
+	return model.Task{
+		Tid:         uuid.New(),
+		Name:        name,
+		Priority:    priority,
+		Appointment: appointment,
+		ScheduledAt: routeDay.Add(time.Duration(hour) * time.Hour),
+		Property:    &model.Property{Address: addr},
+	}
+}
+
+func routeNames(route []stop) []string {
+	names := make([]string, 0, len(route))
+	for _, s := range route {
+		names = append(names, s.task.Name)
+	}
+	return names
+}
+
+func expectRoute(t *testing.T, route []stop, want ...string) {
+	t.Helper()
+	got := routeNames(route)
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestPlanRouteShortensZigzag(t *testing.T) {
+	tasks := []model.Task{
+		routeTask("a", 0, 8, model.Medium, false),
+		routeTask("d", 0.3, 9, model.Medium, false),
+		routeTask("b", 0.1, 10, model.Medium, false),
+		routeTask("c", 0.2, 11, model.Medium, false),
+	}
+
+	route, unrouted := planRoute(tasks)
+	expectRoute(t, route, "a", "b", "c", "d")
+
+	if len(unrouted) != 0 {
+		t.Errorf("expected every task to be routed, got %v", routeNames(unrouted))
+	}
+
+	if planned, scheduled := routeKm(nil, route, nil), routeKm(nil, scheduledOrder(route), nil); planned >= scheduled {
+		t.Errorf("expected the planned route %v to be shorter than the scheduled order %v", planned, scheduled)
+	}
+}
+
+func TestPlanRouteKeepsAppointments(t *testing.T) {
+	tasks := []model.Task{
+		routeTask("a", 0, 8, model.Medium, false),
+		routeTask("appointment", 0.5, 9, model.Medium, true),
+		routeTask("after", 0.01, 10, model.Medium, false),
+	}
+
+	route, _ := planRoute(tasks)
+	expectRoute(t, route, "a", "appointment", "after")
+}
+
+func TestPlanRouteVisitsPriorityFirst(t *testing.T) {
+	tasks := []model.Task{
+		routeTask("near", 0, 8, model.Medium, false),
+		routeTask("nearer", 0.01, 9, model.Low, false),
+		routeTask("urgent", 0.5, 10, model.Immediate, false),
+	}
+
+	route, _ := planRoute(tasks)
+	expectRoute(t, route, "urgent", "near", "nearer")
+}
+
+func TestPlanRouteSkipsUnlocated(t *testing.T) {
+	unlocated := routeTask("nowhere", 0, 9, model.Medium, false)
+	unlocated.Property = nil
+
+	route, unrouted := planRoute([]model.Task{routeTask("a", 0, 8, model.Medium, false), unlocated})
+	expectRoute(t, route, "a")
+	expectRoute(t, unrouted, "nowhere")
+}
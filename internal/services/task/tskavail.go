@@ -0,0 +1,62 @@
+package task
+
+import (
+	"context"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/availability"
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/google/uuid"
+)
+
+// checkReschedule looks for conflicts when an update moves the task or hands it to another
+// worker, the task is only loaded when the update touches its time or worker
+func (t TaskService) checkReschedule(ctx context.Context, changes model.Task) error {
+	if changes.WorkerId == uuid.Nil && changes.ScheduledAt.IsZero() && changes.EstimatedMinutes == 0 {
+		return nil
+	}
+
+	current, err := t.fetchTask(ctx, changes.Tid.String())
+	if err != nil {
+		return err
+	}
+
+	return availability.CheckReschedule(ctx, t.availability, current, changes)
+}
+
+func (t TaskService) fetchTask(ctx context.Context, tid string) (model.Task, error) {
+	current, err := t.repo.Fetch(ctx, filters.Filter{Identifier: tid, Page: 1, Limit: 1})
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	tsk, ok := current.(model.Task)
+	if !ok {
+		return model.Task{}, cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: current}
+	}
+
+	return tsk, nil
+}
+
+// workerFor parses the worker id and keeps workers to their own schedule
+func (t TaskService) workerFor(ctx context.Context, workerId string, requestType string) (uuid.UUID, error) {
+	wid, err := uuid.Parse(workerId)
+	if err != nil {
+		return uuid.Nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: requestType, Err: err}
+	}
+
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return uuid.Nil, dac.ErrMissingScope{Err: err}
+	}
+
+	if claims.HasRole(auth.Worker) && claims.Id != wid.String() {
+		return uuid.Nil, dac.ErrCrossTenant{Model: "Task", Identifier: workerId}
+	}
+
+	return wid, nil
+}
@@ -0,0 +1,159 @@
+package task
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/availability"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+func (t TaskService) GetCrew(ctx context.Context, tid string) ([]dtos.AssignmentResponse, error) {
+	id, err := t.parseTid(tid, "crew")
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := t.repo.FetchAssignments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return dtos.NewAssignmentResponseList(assignments), nil
+}
+
+// AddWorkers adds to the crew without touching who is already on it, a lead that isn't in
+// WorkerIds has to be on the crew already
+func (t TaskService) AddWorkers(ctx context.Context, tid string, req *dtos.TaskCrewRequest) ([]dtos.AssignmentResponse, error) {
+	id, err := t.parseTid(tid, "crew")
+	if err != nil {
+		return nil, err
+	}
+
+	workerIds := make([]uuid.UUID, 0, len(req.WorkerIds))
+	for _, wid := range req.WorkerIds {
+		workerIds = append(workerIds, utils.ParseUuid(wid))
+	}
+
+	lead := utils.ParseUuid(req.LeadId)
+	if len(workerIds) > 0 {
+		tsk, err := t.fetchTask(ctx, tid)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = availability.Check(ctx, t.availability, tsk, workerIds...); err != nil {
+			return nil, err
+		}
+
+		// the lead goes in with the workers so the crew never changes without it
+		if err = t.repo.AddWorkers(ctx, id, workerIds, lead); err != nil {
+			return nil, err
+		}
+	} else if lead != uuid.Nil {
+		if err = t.repo.SetLead(ctx, id, lead); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.GetCrew(ctx, tid)
+}
+
+func (t TaskService) SetLead(ctx context.Context, tid string, req *dtos.TaskLeadRequest) ([]dtos.AssignmentResponse, error) {
+	id, err := t.parseTid(tid, "lead")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.repo.SetLead(ctx, id, utils.ParseUuid(req.WorkerId)); err != nil {
+		return nil, err
+	}
+
+	return t.GetCrew(ctx, tid)
+}
+
+func (t TaskService) RemoveWorker(ctx context.Context, tid string, workerId string) ([]dtos.AssignmentResponse, error) {
+	id, err := t.parseTid(tid, "crew")
+	if err != nil {
+		return nil, err
+	}
+
+	wid := utils.ParseUuid(workerId)
+	if wid == uuid.Nil {
+		return nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "crew", Err: errors.New("invalid worker id")}
+	}
+
+	if err = t.repo.RemoveWorker(ctx, id, wid); err != nil {
+		return nil, err
+	}
+
+	return t.GetCrew(ctx, tid)
+}
+
+// AcceptAssignment and DeclineAssignment answer for the signed in worker only
+func (t TaskService) AcceptAssignment(ctx context.Context, tid string) (*dtos.AssignmentResponse, error) {
+	return t.respond(ctx, tid, model.AssignmentAccepted, "")
+}
+
+func (t TaskService) DeclineAssignment(ctx context.Context, tid string, reason string) (*dtos.AssignmentResponse, error) {
+	return t.respond(ctx, tid, model.AssignmentDeclined, reason)
+}
+
+// GetWorkerTasks is everything a worker is on, workers can only list their own
+func (t TaskService) GetWorkerTasks(ctx context.Context, fltr filters.Filter) ([]dtos.TaskResponse, error) {
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return nil, dac.ErrMissingScope{Err: err}
+	}
+
+	wid := utils.ParseUuid(fltr.Identifier)
+	if wid == uuid.Nil {
+		return nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "worker tasks", Err: errors.New("invalid worker id")}
+	}
+
+	if claims.HasRole(auth.Worker) && claims.Id != wid.String() {
+		return nil, dac.ErrCrossTenant{Model: "Task", Identifier: fltr.Identifier}
+	}
+
+	tasks, err := t.repo.FetchWorkerTasks(ctx, wid, fltr)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.TaskResponse, 0, len(tasks))
+	for _, tk := range tasks {
+		responses = append(responses, dtos.NewTaskResposne(&tk, nil))
+	}
+
+	return responses, nil
+}
+
+func (t TaskService) respond(ctx context.Context, tid string, status model.AssignmentStatus, reason string) (*dtos.AssignmentResponse, error) {
+	id, err := t.parseTid(tid, string(status))
+	if err != nil {
+		return nil, err
+	}
+
+	at, err := t.repo.Respond(ctx, id, status, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewAssignmentResponse(at)
+	return &response, nil
+}
+
+func (t TaskService) parseTid(tid string, requestType string) (uuid.UUID, error) {
+	id, err := uuid.Parse(tid)
+	if err != nil {
+		return uuid.Nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: requestType, Err: err}
+	}
+	return id, nil
+}
@@ -0,0 +1,131 @@
+package task
+
+import (
+	"context"
+
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func (t TaskService) GetFees(ctx context.Context, tid string) ([]dtos.TaskFeeResponse, error) {
+	id, err := t.parseTid(tid, "fees")
+	if err != nil {
+		return nil, err
+	}
+
+	fees, err := t.repo.FetchFees(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dtos.TaskFeeResponse, 0, len(fees))
+	for _, f := range fees {
+		res = append(res, dtos.NewTaskFeeResponse(f))
+	}
+
+	return res, nil
+}
+
+func (t TaskService) AddFee(ctx context.Context, tid string, req dtos.TaskFeeRequest) (dtos.TaskFeeResponse, dtos.TaskCostsResponse, error) {
+	id, err := t.parseTid(tid, "add fee")
+	if err != nil {
+		return dtos.TaskFeeResponse{}, dtos.TaskCostsResponse{}, err
+	}
+
+	fee := newFee(req)
+	fee.Fid = uuid.New()
+	fee.TaskId = id
+
+	tk, err := t.repo.AddFee(ctx, &fee)
+	if err != nil {
+		return dtos.TaskFeeResponse{}, dtos.TaskCostsResponse{}, err
+	}
+
+	return dtos.NewTaskFeeResponse(fee), dtos.NewTaskCostsResponse(tk), nil
+}
+
+func (t TaskService) UpdateFee(ctx context.Context, tid, fid string, req dtos.TaskFeeRequest) (dtos.TaskFeeResponse, dtos.TaskCostsResponse, error) {
+	id, feeId, err := t.parseFeeIds(tid, fid, "update fee")
+	if err != nil {
+		return dtos.TaskFeeResponse{}, dtos.TaskCostsResponse{}, err
+	}
+
+	fee := newFee(req)
+	fee.Fid = feeId
+	fee.TaskId = id
+
+	tk, err := t.repo.UpdateFee(ctx, &fee)
+	if err != nil {
+		return dtos.TaskFeeResponse{}, dtos.TaskCostsResponse{}, err
+	}
+
+	return dtos.NewTaskFeeResponse(fee), dtos.NewTaskCostsResponse(tk), nil
+}
+
+func (t TaskService) RemoveFee(ctx context.Context, tid, fid string) (dtos.TaskCostsResponse, error) {
+	id, feeId, err := t.parseFeeIds(tid, fid, "remove fee")
+	if err != nil {
+		return dtos.TaskCostsResponse{}, err
+	}
+
+	tk, err := t.repo.RemoveFee(ctx, id, feeId)
+	if err != nil {
+		return dtos.TaskCostsResponse{}, err
+	}
+
+	return dtos.NewTaskCostsResponse(tk), nil
+}
+
+func (t TaskService) ProcessFee(ctx context.Context, tid, fid string) (dtos.TaskFeeResponse, error) {
+	id, feeId, err := t.parseFeeIds(tid, fid, "process fee")
+	if err != nil {
+		return dtos.TaskFeeResponse{}, err
+	}
+
+	fee, err := t.repo.ProcessFee(ctx, id, feeId)
+	if err != nil {
+		return dtos.TaskFeeResponse{}, err
+	}
+
+	return dtos.NewTaskFeeResponse(fee), nil
+}
+
+func (t TaskService) parseFeeIds(tid, fid, requestType string) (uuid.UUID, uuid.UUID, error) {
+	id, err := t.parseTid(tid, requestType)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	feeId, err := uuid.Parse(fid)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: requestType, Err: err}
+	}
+
+	return id, feeId, nil
+}
+
+// newFee prices the line item, the cost is worked out here so it always matches the
+// quantity and unit cost it was built from
+func newFee(req dtos.TaskFeeRequest) model.TaskFee {
+	kind := model.FeeKind(req.Kind)
+	if kind == "" {
+		kind = model.MaterialFee
+	}
+
+	quantity := req.Quantity
+	if quantity.IsZero() {
+		quantity = decimal.NewFromInt(1)
+	}
+
+	return model.TaskFee{
+		Kind:     kind,
+		Material: req.Material,
+		Quantity: quantity,
+		UnitCost: req.UnitCost,
+		Cost:     quantity.Mul(req.UnitCost).Round(2),
+		Details:  req.Details,
+	}
+}
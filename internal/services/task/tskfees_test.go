@@ -0,0 +1,34 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+func TestNewFee(t *testing.T) {
+	money := func(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+	for _, test := range []struct {
+		name string
+		req  dtos.TaskFeeRequest
+		kind model.FeeKind
+		cost decimal.Decimal
+	}{
+		{"defaults to one material", dtos.TaskFeeRequest{Material: "filter", UnitCost: money("12.50")}, model.MaterialFee, money("12.50")},
+		{"labor hours at a rate", dtos.TaskFeeRequest{Kind: "labor", Material: "electrician", Quantity: money("2.5"), UnitCost: money("85")}, model.LaborFee, money("212.50")},
+		{"rounds to cents", dtos.TaskFeeRequest{Material: "paint", Quantity: money("3.33"), UnitCost: money("1.99")}, model.MaterialFee, money("6.63")},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fee := newFee(test.req)
+			if fee.Kind != test.kind {
+				t.Errorf("expected kind %v, got %v", test.kind, fee.Kind)
+			}
+			if !fee.Cost.Equal(test.cost) {
+				t.Errorf("expected cost %v, got %v", test.cost, fee.Cost)
+			}
+		})
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Z3DRP/lessor-service/internal/availability"
 	"github.com/Z3DRP/lessor-service/internal/dac"
 	"github.com/Z3DRP/lessor-service/internal/dtos"
 	"github.com/Z3DRP/lessor-service/internal/filters"
 	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
 	"github.com/Z3DRP/lessor-service/internal/ztype"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -50,12 +52,17 @@ func (t TaskHandler) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 		log.Println()
 		log.Printf("payload %#v\n", payload)
 
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
 		task, err := t.CreateTask(r.Context(), payload)
 
 		if err != nil {
 			t.logger.LogFields(logrus.Fields{"msg": "failed to create task", "err": err})
 			log.Printf("failed to create task db err %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			writeTaskErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -68,7 +75,7 @@ func (t TaskHandler) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			t.logger.LogFields(logrus.Fields{"msg": "failed to fetch newest task", "err": err})
 			log.Printf("failed to fetch new task %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -119,7 +126,7 @@ func (t TaskHandler) HandleGetTask(w http.ResponseWriter, r *http.Request) {
 			}
 			t.logger.LogFields(logrus.Fields{"msg": "database err", "err": err})
 			log.Printf("database err %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -134,6 +141,49 @@ func (t TaskHandler) HandleGetTask(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (t TaskHandler) HandleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{
+			"msg": "request timeout",
+			"err": err,
+		})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		tid := r.PathValue("id")
+		if tid == "" {
+			log.Println("invalid request missing tid path value")
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		events, err := t.GetTimeline(r.Context(), tid)
+
+		if err != nil {
+			log.Printf("database err failed to fetch task timeline %v", err)
+			var noResults dac.ErrNoResults
+			if errors.As(err, &noResults) {
+				utils.WriteErr(w, http.StatusNotFound, err)
+				return
+			}
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"timeline": events,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
 func (t TaskHandler) HandleGetTasks(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	select {
@@ -170,7 +220,7 @@ func (t TaskHandler) HandleGetTasks(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 			log.Printf("database err failed to fetch tasks %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -215,7 +265,7 @@ func (t TaskHandler) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 
 		if err != nil {
 			log.Printf("database error failed to update task %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			writeTaskErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -230,6 +280,7 @@ func (t TaskHandler) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 			task.LessorId,
 			task.PropertyId,
 			model.TaskAlert,
+			model.PriorityLevel(task.Priority),
 			"Task Updated",
 			fmt.Sprintf("Task %v has been updated", task.Name),
 		); err != nil {
@@ -301,7 +352,7 @@ func (t TaskHandler) HandleUpdatePriority(w http.ResponseWriter, r *http.Request
 
 		if err != nil {
 			log.Printf("database error failed to update task %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			writeTaskErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -311,6 +362,7 @@ func (t TaskHandler) HandleUpdatePriority(w http.ResponseWriter, r *http.Request
 				task.LessorId,
 				task.PropertyId,
 				model.TaskAlert,
+				model.PriorityLevel(task.Priority),
 				"Task Updated",
 				fmt.Sprintf("Task %v priority has been changed to %v", task.Name, task.Priority),
 			); err != nil {
@@ -372,7 +424,7 @@ func (t TaskHandler) HandleAssignTask(w http.ResponseWriter, r *http.Request) {
 
 		if err != nil {
 			log.Printf("database error failed to update task %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			writeTaskErr(w, transitionErrStatus(err), err)
 			return
 		}
 
@@ -382,8 +434,9 @@ func (t TaskHandler) HandleAssignTask(w http.ResponseWriter, r *http.Request) {
 				task.LessorId,
 				task.PropertyId,
 				model.TaskAlert,
+				model.PriorityLevel(task.Priority),
 				"Task Assigned",
-				fmt.Sprintf("Task %v has been assigned to %v", task.Name, model.FullName(task.Worker.User)),
+				fmt.Sprintf("Task %v has been assigned to %v", task.Name, assigneeName(task)),
 			); err != nil {
 				log.Printf("failed to create notification %v", err)
 				t.logger.Zlog(map[string]interface{}{
@@ -443,7 +496,7 @@ func (t TaskHandler) HandleCompleteTask(w http.ResponseWriter, r *http.Request)
 
 		if err != nil {
 			log.Printf("database error failed to update task %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
 			return
 		}
 
@@ -497,7 +550,7 @@ func (t TaskHandler) HandlePauseTask(w http.ResponseWriter, r *http.Request) {
 
 		if err != nil {
 			log.Printf("database error failed to update task %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
 			return
 		}
 
@@ -551,7 +604,7 @@ func (t TaskHandler) HandleUnPauseTask(w http.ResponseWriter, r *http.Request) {
 
 		if err != nil {
 			log.Printf("database error failed to update task %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
 			return
 		}
 
@@ -568,6 +621,69 @@ func (t TaskHandler) HandleUnPauseTask(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func (t TaskHandler) HandleFailTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{
+			"msg": "request timeout",
+			"err": err,
+		})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		var payload dtos.TaskFailRequest
+
+		tid := r.PathValue("id")
+		if tid == "" {
+			log.Println("invalid request missing tid path value")
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		if err := utils.ParseJSON(r, &payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		task, err := t.FailTask(r.Context(), tid, payload)
+
+		if err != nil {
+			log.Printf("database error failed to update task %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		if err = t.CreateNotification(
+			r.Context(),
+			task.LessorId,
+			task.PropertyId,
+			model.TaskAlert,
+			model.PriorityLevel(task.Priority),
+			"Task Failed",
+			fmt.Sprintf("Task %v failed: %v", task.Name, task.FailedReason),
+		); err != nil {
+			log.Printf("failed to create notification %v", err)
+		}
+
+		res := ztype.JsonResponse{
+			"task":    task,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
 func (t TaskHandler) HandleBulkPriorityUpdate(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	select {
@@ -603,7 +719,7 @@ func (t TaskHandler) HandleBulkPriorityUpdate(w http.ResponseWriter, r *http.Req
 
 		if err != nil {
 			log.Printf("db error %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -635,7 +751,7 @@ func (t TaskHandler) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
 
 		if err != nil {
 			log.Printf("database err failed to delete task %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -651,7 +767,647 @@ func (t TaskHandler) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func DetermineTaskStatus(t *dtos.TaskResponse) model.TaskStatus {
+func (t TaskHandler) HandleGetCrew(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		tid := r.PathValue("id")
+		if tid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		crew, err := t.GetCrew(r.Context(), tid)
+
+		if err != nil {
+			log.Printf("failed to fetch task crew %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"crew":    crew,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleAddWorkers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.TaskCrewRequest{}
+
+		tid := r.PathValue("id")
+		if tid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		crew, err := t.AddWorkers(r.Context(), tid, payload)
+
+		if err != nil {
+			log.Printf("failed to add workers to task %v", err)
+			writeTaskErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"crew":    crew,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleSetLead(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.TaskLeadRequest{}
+
+		tid := r.PathValue("id")
+		if tid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		crew, err := t.SetLead(r.Context(), tid, payload)
+
+		if err != nil {
+			log.Printf("failed to set task lead %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"crew":    crew,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleRemoveWorker(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		tid := r.PathValue("id")
+		workerId := r.PathValue("workerId")
+		if tid == "" || workerId == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid or worker id in url"))
+			return
+		}
+
+		crew, err := t.RemoveWorker(r.Context(), tid, workerId)
+
+		if err != nil {
+			log.Printf("failed to remove worker from task %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"crew":    crew,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleAcceptAssignment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		tid := r.PathValue("id")
+		if tid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		assignment, err := t.AcceptAssignment(r.Context(), tid)
+
+		if err != nil {
+			log.Printf("failed to accept assignment %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"assignment": assignment,
+			"success":    true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleDeclineAssignment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.AssignmentDeclineRequest{}
+
+		tid := r.PathValue("id")
+		if tid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		assignment, err := t.DeclineAssignment(r.Context(), tid, payload.Reason)
+
+		if err != nil {
+			log.Printf("failed to decline assignment %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"assignment": assignment,
+			"success":    true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleGetWorkerTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		fltr, err := filters.GenFilter(r)
+
+		if err != nil {
+			log.Printf("failed to gen filter %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		tasks, err := t.GetWorkerTasks(r.Context(), fltr)
+
+		if err != nil {
+			log.Printf("failed to fetch worker tasks %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"tasks":   tasks,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// transitionErrStatus answers illegal or lost status changes with a conflict
+func (t TaskHandler) HandleGetCandidates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		tid := r.PathValue("id")
+		if tid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		candidates, err := t.GetCandidates(r.Context(), tid)
+
+		if err != nil {
+			log.Printf("failed to rank task candidates %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"candidates": candidates,
+			"success":    true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleDispatchTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		tid := r.PathValue("id")
+		if tid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		assigned, err := t.Dispatch(r.Context(), tid)
+
+		if err != nil {
+			log.Printf("failed to dispatch task %v", err)
+			if errors.Is(err, ErrNoCandidate) {
+				utils.WriteErr(w, http.StatusConflict, err)
+				return
+			}
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		crew, err := t.GetCrew(r.Context(), tid)
+
+		if err != nil {
+			log.Printf("failed to fetch task crew %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"assigned": assigned,
+			"crew":     crew,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleGetWorkerRoute(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		route, err := t.GetWorkerRoute(r.Context(), r.PathValue("id"), r.URL.Query().Get("date"))
+
+		if err != nil {
+			log.Printf("failed to plan worker route %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"route":   route,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleGetFees(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		tid := r.PathValue("id")
+		if tid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		fees, err := t.GetFees(r.Context(), tid)
+
+		if err != nil {
+			log.Printf("failed to fetch task fees %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"fees":    fees,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleAddFee(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.TaskFeeRequest{}
+
+		tid := r.PathValue("id")
+		if tid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid in url"))
+			return
+		}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		fee, costs, err := t.AddFee(r.Context(), tid, *payload)
+
+		if err != nil {
+			log.Printf("failed to add task fee %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"fee":     fee,
+			"costs":   costs,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusCreated, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleUpdateFee(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.TaskFeeRequest{}
+
+		tid := r.PathValue("id")
+		fid := r.PathValue("feeId")
+		if tid == "" || fid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid or fee id in url"))
+			return
+		}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		fee, costs, err := t.UpdateFee(r.Context(), tid, fid, *payload)
+
+		if err != nil {
+			log.Printf("failed to update task fee %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"fee":     fee,
+			"costs":   costs,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleRemoveFee(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		tid := r.PathValue("id")
+		fid := r.PathValue("feeId")
+		if tid == "" || fid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid or fee id in url"))
+			return
+		}
+
+		costs, err := t.RemoveFee(r.Context(), tid, fid)
+
+		if err != nil {
+			log.Printf("failed to remove task fee %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"costs":   costs,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TaskHandler) HandleProcessFee(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		tid := r.PathValue("id")
+		fid := r.PathValue("feeId")
+		if tid == "" || fid == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tid or fee id in url"))
+			return
+		}
+
+		fee, err := t.ProcessFee(r.Context(), tid, fid)
+
+		if err != nil {
+			log.Printf("failed to process task fee %v", err)
+			utils.WriteErr(w, transitionErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"fee":     fee,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func transitionErrStatus(err error) int {
+	var invalid ErrInvalidTransition
+	if errors.As(err, &invalid) || errors.Is(err, dac.ErrStaleWrite) || errors.Is(err, dac.ErrFeeProcessed) {
+		return http.StatusConflict
+	}
+
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
+
+// writeTaskErr sends schedule conflicts back as a 409 listing what is in the way so the caller
+// can pick another time, everything else is written as usual
+func writeTaskErr(w http.ResponseWriter, status int, err error) {
+	var conflict availability.ErrScheduleConflict
+	if errors.As(err, &conflict) {
+		res := ztype.JsonResponse{
+			"error":     err.Error(),
+			"conflicts": conflict.Conflicts,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusConflict, res); err != nil {
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteErr(w, status, err)
+}
+
+func assigneeName(t *dtos.TaskResponse) string {
+	if t.Worker == nil || t.Worker.User == nil {
+		return "a worker"
+	}
+	return model.FullName(t.Worker.User)
+}
+
+func DetermineTaskStatus(t *dtos.TaskResponse) model.TaskStatus {
+	if t.Status != "" {
+		return model.TaskStatus(t.Status)
+	}
+
 	if isNotZero(t.FailedAt) {
 		return model.Failed
 	}
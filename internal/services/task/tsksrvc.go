@@ -8,20 +8,26 @@ import (
 	"log"
 	"time"
 
+	"github.com/Z3DRP/lessor-service/internal/availability"
 	"github.com/Z3DRP/lessor-service/internal/cmerr"
 	"github.com/Z3DRP/lessor-service/internal/crane"
 	"github.com/Z3DRP/lessor-service/internal/dac"
 	"github.com/Z3DRP/lessor-service/internal/dtos"
 	"github.com/Z3DRP/lessor-service/internal/filters"
 	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/notify"
 	"github.com/Z3DRP/lessor-service/internal/services"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 )
 
 type TaskService struct {
-	repo   dac.TaskRepo
-	logger *crane.Zlogrus
+	repo         dac.TaskRepo
+	availability dac.AvailabilityRepo
+	settings     dac.NotificationSettingsRepo
+	outbox       *notify.Outbox
+	logger       *crane.Zlogrus
 	//s3Actor api.FilePersister
 }
 
@@ -29,9 +35,12 @@ func (t TaskService) ServiceName() string {
 	return "Task"
 }
 
-func NewTaskService(repo dac.TaskRepo, logr *crane.Zlogrus) TaskService {
+func NewTaskService(repo dac.TaskRepo, avail dac.AvailabilityRepo, settings dac.NotificationSettingsRepo, outbox *notify.Outbox, logr *crane.Zlogrus) TaskService {
 	return TaskService{
-		repo: repo,
+		repo:         repo,
+		availability: avail,
+		settings:     settings,
+		outbox:       outbox,
 		//s3Actor: actr,
 		logger: logr,
 	}
@@ -72,6 +81,15 @@ func (t TaskService) GetTask(ctx context.Context, fltr filters.Filterer) (*dtos.
 	return &tskDto, nil
 }
 
+func (t TaskService) GetTimeline(ctx context.Context, tid string) ([]dtos.TaskEventResponse, error) {
+	events, err := t.repo.FetchTimeline(ctx, tid)
+	if err != nil {
+		return nil, err
+	}
+
+	return dtos.NewTaskEventResponseList(events), nil
+}
+
 func (t TaskService) GetTasks(ctx context.Context, fltr filters.Filterer) ([]dtos.TaskResponse, error) {
 	var tskReponses []dtos.TaskResponse
 	filter, ok := fltr.(filters.Filter)
@@ -128,6 +146,12 @@ func (t TaskService) CreateTask(ctx context.Context, tdata *dtos.TaskRequest) (*
 		return nil, err
 	}
 
+	tsk.Status = model.Scheduled
+
+	if err = availability.Check(ctx, t.availability, *tsk, tsk.WorkerId); err != nil {
+		return nil, err
+	}
+
 	log.Printf("created tid %v", tsk.Tid)
 	// if fileData != nil && fileData.File != nil && fileData.Header != nil {
 	// 	var fileName string
@@ -181,6 +205,10 @@ func (t TaskService) ModifyTask(ctx context.Context, tdto *dtos.TaskModRequest)
 		return nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "update"}
 	}
 
+	if err := t.checkReschedule(ctx, *tsk); err != nil {
+		return nil, err
+	}
+
 	updatedTask, err := t.repo.Update(ctx, tsk)
 
 	if err != nil {
@@ -201,12 +229,12 @@ func (t TaskService) ModifyTask(ctx context.Context, tdto *dtos.TaskModRequest)
 }
 
 func (t TaskService) ModifyTaskPririty(ctx context.Context, tdo *dtos.TaskModRequest) (*dtos.TaskResponse, error) {
-	pid, err := uuid.Parse(tdo.Tid)
+	tid, err := uuid.Parse(tdo.Tid)
 	if err != nil {
 		return nil, fmt.Errorf("invalid task id %v", err)
 	}
 
-	tsk, err := t.repo.UpdatePriority(ctx, model.Task{PropertyId: pid, Priority: model.PriorityLevel(tdo.Priority)})
+	tsk, err := t.repo.UpdatePriority(ctx, model.Task{Tid: tid, Priority: model.PriorityLevel(tdo.Priority)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to modify priority %v", err)
 	}
@@ -223,102 +251,94 @@ func (t TaskService) ModifyTaskPririty(ctx context.Context, tdo *dtos.TaskModReq
 }
 
 func (t TaskService) AssignTask(ctx context.Context, tdo *dtos.TaskModRequest) (*dtos.TaskResponse, error) {
-	pid, err := uuid.Parse(tdo.Tid)
-	if err != nil {
-		return nil, fmt.Errorf("invalid task id %v", err)
-	}
-
 	wid, err := uuid.Parse(tdo.WorkerId)
 	if err != nil {
-		return nil, fmt.Errorf("")
+		return nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "assign", Err: err}
 	}
 
-	tsk, err := t.repo.UpdateStartedAt(ctx, model.Task{PropertyId: pid, WorkerId: wid, StartedAt: time.Now()})
+	tsk, err := t.fetchTask(ctx, tdo.Tid)
 	if err != nil {
-		return nil, fmt.Errorf("failed to modify priority %v", err)
+		return nil, err
 	}
 
-	task, ok := tsk.(model.Task)
-	if !ok {
-		err = cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
-		log.Printf("type assertion failed %v", err)
+	if err = availability.Check(ctx, t.availability, tsk, wid); err != nil {
 		return nil, err
 	}
 
-	response := dtos.NewTaskResposne(&task, nil)
-	return &response, nil
+	return t.transition(ctx, tdo.Tid, model.Started, func(tsk *model.Task) []string {
+		tsk.WorkerId = wid
+		tsk.StartedAt = time.Now()
+		return []string{"worker_id", "started_at"}
+	})
 }
 
 func (t TaskService) CompleteTask(ctx context.Context, tdo *dtos.TaskModRequest) (*dtos.TaskResponse, error) {
-	pid, err := uuid.Parse(tdo.Tid)
-	if err != nil {
-		return nil, fmt.Errorf("invalid task id %v", err)
-	}
+	return t.transition(ctx, tdo.Tid, model.Finished, func(tsk *model.Task) []string {
+		tsk.CompletedAt = time.Now()
+		return []string{"completed_at"}
+	})
+}
 
-	tsk, err := t.repo.UpdateCompletedAt(ctx, model.Task{PropertyId: pid, CompletedAt: time.Now()})
-	if err != nil {
-		return nil, fmt.Errorf("failed to modify priority %v", err)
-	}
+func (t TaskService) PauseTask(ctx context.Context, tdo *dtos.TaskModRequest) (*dtos.TaskResponse, error) {
+	return t.transition(ctx, tdo.Tid, model.Paused, func(tsk *model.Task) []string {
+		tsk.PausedAt = time.Now()
+		tsk.PausedReason = tdo.PausedReason
+		return []string{"paused_at", "paused_reason"}
+	})
+}
 
-	task, ok := tsk.(model.Task)
-	if !ok {
-		err = cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
-		log.Printf("type assertion failed %v", err)
-		return nil, err
-	}
+func (t TaskService) UnPauseTask(ctx context.Context, tdo *dtos.TaskModRequest) (*dtos.TaskResponse, error) {
+	return t.transition(ctx, tdo.Tid, model.Started, func(tsk *model.Task) []string {
+		tsk.PausedAt = time.Time{}
+		tsk.PausedReason = ""
+		return []string{"paused_at", "paused_reason"}
+	})
+}
 
-	response := dtos.NewTaskResposne(&task, nil)
-	return &response, nil
+func (t TaskService) FailTask(ctx context.Context, tid string, tdo dtos.TaskFailRequest) (*dtos.TaskResponse, error) {
+	return t.transition(ctx, tid, model.Failed, func(tsk *model.Task) []string {
+		tsk.FailedAt = time.Now()
+		tsk.FailedReason = tdo.FailedReason
+		return []string{"failed_at", "failed_reason"}
+	})
 }
 
-func (t TaskService) PauseTask(ctx context.Context, tdo *dtos.TaskModRequest) (*dtos.TaskResponse, error) {
-	pid, err := uuid.Parse(tdo.Tid)
-	if err != nil {
-		return nil, fmt.Errorf("invalid task id %v", err)
+// transition moves the task to the given status if the transition table allows it, apply sets
+// the fields that go along with the new status and returns the columns it touched
+func (t TaskService) transition(ctx context.Context, tid string, to model.TaskStatus, apply func(*model.Task) []string) (*dtos.TaskResponse, error) {
+	if _, err := uuid.Parse(tid); err != nil {
+		return nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: string(to), Err: err}
 	}
 
-	tsk, err := t.repo.UpdatePausedAt(ctx, model.Task{PropertyId: pid, StartedAt: time.Now()})
+	fltr := filters.Filter{Identifier: tid, Page: 1, Limit: 1}
+	current, err := t.repo.Fetch(ctx, fltr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to modify priority %v", err)
+		return nil, err
 	}
 
-	task, ok := tsk.(model.Task)
+	tsk, ok := current.(model.Task)
 	if !ok {
-		err = cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
-		log.Printf("type assertion failed %v", err)
-		return nil, err
+		return nil, cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: current}
 	}
 
-	response := dtos.NewTaskResposne(&task, nil)
-	return &response, nil
-}
-
-func (t TaskService) UnPauseTask(ctx context.Context, tdo *dtos.TaskModRequest) (*dtos.TaskResponse, error) {
-	pid, err := uuid.Parse(tdo.Tid)
-	if err != nil {
-		return nil, fmt.Errorf("invalid task id %v", err)
+	from := tsk.Status
+	if err = CanTransition(from, to); err != nil {
+		return nil, err
 	}
 
-	var nilTime time.Time
-
-	tsk, err := t.repo.UpdatePausedAt(ctx, model.Task{PropertyId: pid, StartedAt: time.Now(), PausedAt: nilTime})
-	if err != nil {
-		return nil, fmt.Errorf("failed to modify priority %v", err)
-	}
+	tsk.Status = to
+	columns := apply(&tsk)
 
-	task, ok := tsk.(model.Task)
-	if !ok {
-		err = cmerr.ErrUnexpectedData{Wanted: model.Task{}, Got: tsk}
-		log.Printf("type assertion failed %v", err)
+	if _, err = t.repo.Transition(ctx, &tsk, from, columns...); err != nil {
 		return nil, err
 	}
 
-	response := dtos.NewTaskResposne(&task, nil)
-	return &response, nil
+	// fetch again so the response has the worker and property relations loaded
+	return t.GetTask(ctx, fltr)
 }
 
 func (t TaskService) UpdatePriorities(ctx context.Context, tdata []*dtos.TaskModRequest) (*[]dtos.TaskResponse, error) {
-	tasks := make([]any, len(tdata))
+	tasks := make([]any, 0, len(tdata))
 	for _, tres := range tdata {
 		tasks = append(tasks, *newTaskFrmModRequest(tres))
 	}
@@ -354,18 +374,48 @@ func (t TaskService) DeleteTask(ctx context.Context, f filters.Filterer) error {
 	return nil
 }
 
-func (t TaskService) CreateNotification(ctx context.Context, lessorId, pid string, category model.NotificationType, title, message string) error {
+// CreateNotification tells the lessor about a task, it is only saved and sent where the
+// lessor's notification settings allow and waits for their digest when they get one
+func (t TaskService) CreateNotification(ctx context.Context, lessorId, pid string, category model.NotificationType, priority model.PriorityLevel, title, message string) error {
 	noti := model.Notification{
 		Title:      title,
 		Message:    message,
 		UserId:     utils.ParseUuid(lessorId),
+		LessorId:   utils.ParseUuid(lessorId),
 		PropertyId: utils.ParseUuid(pid),
-		Category:   model.TaskAlert,
+		Category:   category,
+		Priority:   priority,
 		Viewed:     false,
 		CreatedAt:  time.Now(),
 		VoidAt:     time.Now().AddDate(0, 0, model.TtlDays),
 	}
 
+	delivery, err := notify.Plan(ctx, &t.settings, noti)
+	if err != nil {
+		t.logger.LogFields(logrus.Fields{"msg": "failed to load notification settings", "err": err})
+		return err
+	}
+
+	if !delivery.Digest {
+		noti.DeliveredAt = time.Now()
+	}
+
+	if delivery.InApp() {
+		if err = t.saveNotification(ctx, &noti); err != nil {
+			return err
+		}
+	}
+
+	// sending is best effort, the notification is already saved
+	if delivery.Sends() {
+		if err = t.outbox.Notify(noti, delivery.Channels); err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to queue notification", "err": err})
+		}
+	}
+	return nil
+}
+
+func (t TaskService) saveNotification(ctx context.Context, noti *model.Notification) error {
 	tx, err := t.repo.GetBunDB().BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		log.Printf("err %v", err)
@@ -376,16 +426,26 @@ func (t TaskService) CreateNotification(ctx context.Context, lessorId, pid strin
 		return err
 	}
 
-	if err = tx.NewInsert().Model(&noti).Returning("*").Scan(ctx, &noti); err != nil {
+	err = tx.NewInsert().Model(noti).Returning("*").Scan(ctx, noti)
+	if err == nil {
+		err = dac.PublishNotification(ctx, tx, *noti)
+	}
+
+	if err == nil {
+		err = dac.EnqueueWebhook(ctx, tx, noti.LessorId, model.NotificationCreatedHook, *noti)
+	}
+
+	if err != nil {
 		log.Printf("err %v", err)
-		if err = tx.Rollback(); err != nil {
-			log.Printf("err %v", err)
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("err %v", rbErr)
 			t.logger.Zlog(map[string]interface{}{
 				"msg": "could not rollback notification",
-				"err": err,
+				"err": rbErr,
 			})
-			return err
+			return rbErr
 		}
+		return err
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -401,58 +461,62 @@ func (t TaskService) CreateNotification(ctx context.Context, lessorId, pid strin
 
 func NewTaskFrmRequest(data dtos.TaskRequest) *model.Task {
 	return &model.Task{
-		LessorId:      utils.ParseUuid(data.LessorId),
-		PropertyId:    utils.ParseUuid(data.PropertyId),
-		WorkerId:      utils.ParseUuid(data.WorkerId),
-		Name:          data.Name,
-		Category:      model.TaskCategory(data.Category),
-		Priority:      model.PriorityLevel(data.Priority),
-		Details:       data.Details,
-		Notes:         data.Notes,
-		ScheduledAt:   data.ScheduledAt,
-		ActualCost:    data.ActualCost,
-		EstimatedCost: data.EstimateCost,
-		Image:         data.Image,
+		LessorId:         utils.ParseUuid(data.LessorId),
+		PropertyId:       utils.ParseUuid(data.PropertyId),
+		WorkerId:         utils.ParseUuid(data.WorkerId),
+		Name:             data.Name,
+		Category:         model.TaskCategory(data.Category),
+		Priority:         model.PriorityLevel(data.Priority),
+		Details:          data.Details,
+		Notes:            data.Notes,
+		ScheduledAt:      data.ScheduledAt,
+		EstimatedMinutes: data.EstimatedMinutes,
+		Appointment:      data.Appointment,
+		FlatFee:          data.FlatFee,
+		BilledAmount:     data.BilledAmount,
+		EstimatedCost:    data.EstimateCost,
+		Image:            data.Image,
 	}
 }
 
 func newTaskFrmPtrRequest(data *dtos.TaskRequest) *model.Task {
 	return &model.Task{
-		LessorId:      utils.ParseUuid(data.LessorId),
-		PropertyId:    utils.ParseUuid(data.PropertyId),
-		WorkerId:      utils.ParseUuid(data.WorkerId),
-		Category:      model.TaskCategory(data.Category),
-		Name:          data.Name,
-		Priority:      model.PriorityLevel(data.Priority),
-		Details:       data.Details,
-		Notes:         data.Notes,
-		ScheduledAt:   data.ScheduledAt,
-		EstimatedCost: data.EstimateCost,
-		ActualCost:    data.ActualCost,
-		Image:         data.Image,
+		LessorId:         utils.ParseUuid(data.LessorId),
+		PropertyId:       utils.ParseUuid(data.PropertyId),
+		WorkerId:         utils.ParseUuid(data.WorkerId),
+		Category:         model.TaskCategory(data.Category),
+		Name:             data.Name,
+		Priority:         model.PriorityLevel(data.Priority),
+		Details:          data.Details,
+		Notes:            data.Notes,
+		ScheduledAt:      data.ScheduledAt,
+		EstimatedMinutes: data.EstimatedMinutes,
+		Appointment:      data.Appointment,
+		FlatFee:          data.FlatFee,
+		BilledAmount:     data.BilledAmount,
+		EstimatedCost:    data.EstimateCost,
+		Image:            data.Image,
 	}
 }
 
+// lifecycle timestamps are left out, they only change through the status transitions
 func newTaskFrmModRequest(data *dtos.TaskModRequest) *model.Task {
 	return &model.Task{
-		LessorId:      utils.ParseUuid(data.LessorId),
-		Tid:           utils.ParseUuid(data.Tid),
-		Name:          data.Name,
-		PropertyId:    utils.ParseUuid(data.PropertyId),
-		Category:      model.TaskCategory(data.Category),
-		WorkerId:      utils.ParseUuid(data.WorkerId),
-		Priority:      model.PriorityLevel(data.Priority),
-		Details:       data.Details,
-		Notes:         data.Notes,
-		ScheduledAt:   data.ScheduledAt,
-		StartedAt:     data.StartedAt,
-		CompletedAt:   data.CompletedAt,
-		PausedAt:      data.PausedAt,
-		PausedReason:  data.PausedReason,
-		FailedAt:      data.FailedAt,
-		FailedReason:  data.FailedReason,
-		EstimatedCost: data.EstimatedCost,
-		ActualCost:    data.ActualCost,
-		Image:         data.Image,
+		LessorId:         utils.ParseUuid(data.LessorId),
+		Tid:              utils.ParseUuid(data.Tid),
+		Name:             data.Name,
+		PropertyId:       utils.ParseUuid(data.PropertyId),
+		Category:         model.TaskCategory(data.Category),
+		WorkerId:         utils.ParseUuid(data.WorkerId),
+		Priority:         model.PriorityLevel(data.Priority),
+		Details:          data.Details,
+		Notes:            data.Notes,
+		ScheduledAt:      data.ScheduledAt,
+		EstimatedMinutes: data.EstimatedMinutes,
+		Appointment:      data.Appointment,
+		FlatFee:          data.FlatFee,
+		BilledAmount:     data.BilledAmount,
+		EstimatedCost:    data.EstimatedCost,
+		Image:            data.Image,
 	}
 }
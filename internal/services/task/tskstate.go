@@ -0,0 +1,34 @@
+package task
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+)
+
+// transitions lists every status a task can move to from its current status,
+// finished and failed tasks are terminal
+var transitions = map[model.TaskStatus][]model.TaskStatus{
+	model.Scheduled: {model.Started, model.Failed},
+	model.Started:   {model.Paused, model.Finished, model.Failed},
+	model.Paused:    {model.Started, model.Failed},
+	model.Finished:  {},
+	model.Failed:    {},
+}
+
+type ErrInvalidTransition struct {
+	From model.TaskStatus
+	To   model.TaskStatus
+}
+
+func (e ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("task cannot move from %v to %v", e.From, e.To)
+}
+
+func CanTransition(from, to model.TaskStatus) error {
+	if !slices.Contains(transitions[from], to) {
+		return ErrInvalidTransition{From: from, To: to}
+	}
+	return nil
+}
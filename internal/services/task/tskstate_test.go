@@ -0,0 +1,40 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+)
+
+func TestCanTransition(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		from    model.TaskStatus
+		to      model.TaskStatus
+		allowed bool
+	}{
+		{"start scheduled task", model.Scheduled, model.Started, true},
+		{"pause started task", model.Started, model.Paused, true},
+		{"unpause paused task", model.Paused, model.Started, true},
+		{"complete started task", model.Started, model.Finished, true},
+		{"fail paused task", model.Paused, model.Failed, true},
+		{"complete task never started", model.Scheduled, model.Finished, false},
+		{"complete paused task", model.Paused, model.Finished, false},
+		{"unpause failed task", model.Failed, model.Started, false},
+		{"restart finished task", model.Finished, model.Started, false},
+		{"unknown status", model.TaskStatus(""), model.Started, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := CanTransition(test.from, test.to)
+			if test.allowed && err != nil {
+				t.Errorf("expected %v -> %v to be allowed, got %v", test.from, test.to, err)
+			}
+
+			var invalid ErrInvalidTransition
+			if !test.allowed && !errors.As(err, &invalid) {
+				t.Errorf("expected %v -> %v to be rejected, got %v", test.from, test.to, err)
+			}
+		})
+	}
+}
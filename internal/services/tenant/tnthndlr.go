@@ -0,0 +1,304 @@
+package tenant
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/ztype"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type TenantHandler struct {
+	TenantService
+}
+
+func NewHandler(service TenantService) TenantHandler {
+	return TenantHandler{
+		TenantService: service,
+	}
+}
+
+func (t TenantHandler) HandlerName() string {
+	return "Tenant"
+}
+
+func (t TenantHandler) HandleInviteTenant(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		payload := &dtos.TenantRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to parse request body", "err": err})
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		invite, err := t.InviteTenant(r.Context(), payload)
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to invite tenant", "err": err})
+			log.Printf("failed to invite tenant %v", err)
+			utils.WriteErr(w, tenantErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"invite":  invite,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TenantHandler) HandleResendInvite(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tenant id in url"))
+			return
+		}
+
+		invite, err := t.ResendInvite(r.Context(), id)
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to resend tenant invite", "err": err})
+			log.Printf("failed to resend tenant invite %v", err)
+			utils.WriteErr(w, tenantErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"invite":  invite,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TenantHandler) HandleGetTenant(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tenant id in url"))
+			return
+		}
+
+		tnt, err := t.GetTenant(r.Context(), filters.Filter{Identifier: id, Page: 1})
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to fetch tenant", "err": err})
+			log.Printf("failed to fetch tenant %v", err)
+			utils.WriteErr(w, tenantErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"tenant":  tnt,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TenantHandler) HandleGetTenants(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		fltr, err := filters.GenFilter(r)
+
+		if err != nil {
+			log.Printf("failed to gen filter %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		tenants, err := t.GetTenants(r.Context(), fltr)
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to fetch tenants", "err": err})
+			log.Printf("failed to fetch tenants %v", err)
+			utils.WriteErr(w, tenantErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"tenants": tenants,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TenantHandler) HandleGetHome(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		home, err := t.GetHome(r.Context())
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to fetch tenant home", "err": err})
+			log.Printf("failed to fetch tenant home %v", err)
+			utils.WriteErr(w, tenantErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"tenant":  home,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TenantHandler) HandleUpdateTenant(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tenant id in url"))
+			return
+		}
+
+		payload := &dtos.TenantModRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to parse request body", "err": err})
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		tnt, err := t.ModifyTenant(r.Context(), id, payload)
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to update tenant", "err": err})
+			log.Printf("failed to update tenant %v", err)
+			utils.WriteErr(w, tenantErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"tenant":  tnt,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TenantHandler) HandleDeleteTenant(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": timeoutErr})
+		utils.WriteErr(w, http.StatusRequestTimeout, timeoutErr)
+	default:
+		id := r.PathValue("id")
+		if id == "" {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("invalid request missing tenant id in url"))
+			return
+		}
+
+		err := t.RemoveTenant(r.Context(), id)
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to remove tenant", "err": err})
+			log.Printf("failed to remove tenant %v", err)
+			utils.WriteErr(w, tenantErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"tenantId": id,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func tenantErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
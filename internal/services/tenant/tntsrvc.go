@@ -0,0 +1,223 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// Tenants stores tenants and their invites, dac.TenantRepo satisfies it
+type Tenants interface {
+	Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error)
+	FetchAll(ctx context.Context, fltr filters.Filter) ([]model.Tenant, error)
+	Invite(ctx context.Context, usr *model.User, tnt *model.Tenant, invite *model.TenantInvite) error
+	ReissueInvite(ctx context.Context, invite *model.TenantInvite) error
+	Update(ctx context.Context, tnt any) (interface{}, error)
+	Delete(ctx context.Context, tnt any) error
+}
+
+type TenantService struct {
+	repo   Tenants
+	logger *crane.Zlogrus
+}
+
+func (t TenantService) ServiceName() string {
+	return "Tenant"
+}
+
+func NewTenantService(repo dac.TenantRepo, logr *crane.Zlogrus) TenantService {
+	return TenantService{
+		repo:   &repo,
+		logger: logr,
+	}
+}
+
+func (t TenantService) GetTenant(ctx context.Context, fltr filters.Filter) (*dtos.TenantResponse, error) {
+	tnt, err := t.fetch(ctx, fltr.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewTenantResponse(tnt)
+	return &response, nil
+}
+
+func (t TenantService) GetTenants(ctx context.Context, fltr filters.Filter) ([]dtos.TenantResponse, error) {
+	tenants, err := t.repo.FetchAll(ctx, fltr)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.TenantResponse, 0, len(tenants))
+	for _, tnt := range tenants {
+		responses = append(responses, dtos.NewTenantResponse(tnt))
+	}
+
+	return responses, nil
+}
+
+// GetHome is the signed in tenant's own tenancy with their lessor's contact details
+func (t TenantService) GetHome(ctx context.Context) (*dtos.TenantHomeResponse, error) {
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return nil, dac.ErrMissingScope{Err: err}
+	}
+
+	tnt, err := t.fetch(ctx, claims.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewTenantHomeResponse(tnt)
+	return &response, nil
+}
+
+// InviteTenant adds the tenant with an inactive user, the returned token is what the
+// tenant signs up with to choose their username and password
+func (t TenantService) InviteTenant(ctx context.Context, tdata *dtos.TenantRequest) (*dtos.TenantInviteResponse, error) {
+	uid := uuid.New()
+	usr := &model.User{
+		Uid:         uid,
+		FirstName:   tdata.FirstName,
+		LastName:    tdata.LastName,
+		Email:       tdata.Email,
+		Phone:       tdata.Phone,
+		ProfileType: string(auth.Tenant),
+		Username:    placeholderUsername(uid),
+		IsActive:    false,
+	}
+
+	tnt := &model.Tenant{
+		Uid:         uid,
+		LessorId:    utils.ParseUuid(tdata.LessorId),
+		PropertyId:  utils.ParseUuid(tdata.PropertyId),
+		MoveInDate:  tdata.MoveInDate,
+		MoveOutDate: tdata.MoveOutDate,
+	}
+
+	token, invite, err := newInvite(uid, tnt.LessorId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.repo.Invite(ctx, usr, tnt, invite); err != nil {
+		return nil, err
+	}
+
+	return &dtos.TenantInviteResponse{
+		Tenant:      dtos.NewTenantResponse(*tnt),
+		InviteToken: token,
+		ExpiresAt:   invite.ExpiresAt,
+	}, nil
+}
+
+// ResendInvite replaces a tenant's open invite with a new token, tenants that already
+// signed up can't be invited again
+func (t TenantService) ResendInvite(ctx context.Context, uid string) (*dtos.TenantInviteResponse, error) {
+	tnt, err := t.fetch(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if tnt.User != nil && tnt.User.IsActive {
+		return nil, services.ErrInvalidRequest{
+			ServiceType: t.ServiceName(),
+			RequestType: "invite",
+			Err:         errors.New("tenant has already accepted their invite"),
+		}
+	}
+
+	token, invite, err := newInvite(tnt.Uid, tnt.LessorId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.repo.ReissueInvite(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	return &dtos.TenantInviteResponse{
+		Tenant:      dtos.NewTenantResponse(tnt),
+		InviteToken: token,
+		ExpiresAt:   invite.ExpiresAt,
+	}, nil
+}
+
+func (t TenantService) ModifyTenant(ctx context.Context, uid string, req *dtos.TenantModRequest) (*dtos.TenantResponse, error) {
+	tid := utils.ParseUuid(uid)
+	if tid == uuid.Nil {
+		return nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "update", Err: errors.New("invalid tenant id")}
+	}
+
+	updated, err := t.repo.Update(ctx, model.Tenant{
+		Uid:         tid,
+		PropertyId:  utils.ParseUuid(req.PropertyId),
+		MoveInDate:  req.MoveInDate,
+		MoveOutDate: req.MoveOutDate,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	tnt, ok := updated.(model.Tenant)
+	if !ok {
+		return nil, cmerr.ErrUnexpectedData{Wanted: model.Tenant{}, Got: updated}
+	}
+
+	response := dtos.NewTenantResponse(tnt)
+	return &response, nil
+}
+
+func (t TenantService) RemoveTenant(ctx context.Context, uid string) error {
+	tid := utils.ParseUuid(uid)
+	if tid == uuid.Nil {
+		return services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "delete", Err: errors.New("invalid tenant id")}
+	}
+
+	return t.repo.Delete(ctx, model.Tenant{Uid: tid})
+}
+
+func (t TenantService) fetch(ctx context.Context, uid string) (model.Tenant, error) {
+	found, err := t.repo.Fetch(ctx, filters.Filter{Identifier: uid, Page: 1})
+	if err != nil {
+		return model.Tenant{}, err
+	}
+
+	tnt, ok := found.(model.Tenant)
+	if !ok {
+		return model.Tenant{}, cmerr.ErrUnexpectedData{Wanted: model.Tenant{}, Got: found}
+	}
+
+	return tnt, nil
+}
+
+func newInvite(uid uuid.UUID, lessorId uuid.UUID) (string, *model.TenantInvite, error) {
+	token, expiresAt, err := auth.GenerateInviteToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, &model.TenantInvite{
+		Uid:       uid,
+		LessorId:  lessorId,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// placeholderUsername holds the unique username column until the tenant picks their own
+func placeholderUsername(uid uuid.UUID) string {
+	return "invited-" + strings.ReplaceAll(uid.String(), "-", "")[:20]
+}
@@ -0,0 +1,202 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/google/uuid"
+)
+
+// memoryTenants keeps tenants and invites in memory and follows the same scope rules as
+// dac.TenantRepo, properties maps each property to the lessor that owns it
+type memoryTenants struct {
+	properties map[uuid.UUID]uuid.UUID
+	tenants    map[uuid.UUID]model.Tenant
+	invites    map[uuid.UUID]model.TenantInvite
+}
+
+func newMemoryTenants() *memoryTenants {
+	return &memoryTenants{
+		properties: map[uuid.UUID]uuid.UUID{},
+		tenants:    map[uuid.UUID]model.Tenant{},
+		invites:    map[uuid.UUID]model.TenantInvite{},
+	}
+}
+
+func (m *memoryTenants) Fetch(ctx context.Context, fltr filters.Filter) (interface{}, error) {
+	scope, err := dac.NewTenantScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tnt, ok := m.tenants[uuid.MustParse(fltr.Identifier)]
+	if !ok {
+		return nil, dac.ErrNoResults{Identifier: fltr.Identifier}
+	}
+
+	if !scope.Owns(tnt.LessorId) {
+		return nil, dac.ErrCrossTenant{Model: "Tenant", Identifier: fltr.Identifier}
+	}
+
+	return tnt, nil
+}
+
+func (m *memoryTenants) FetchAll(ctx context.Context, fltr filters.Filter) ([]model.Tenant, error) {
+	return nil, nil
+}
+
+func (m *memoryTenants) Invite(ctx context.Context, usr *model.User, tnt *model.Tenant, invite *model.TenantInvite) error {
+	scope, err := dac.NewTenantScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !scope.Owns(tnt.LessorId) {
+		return dac.ErrCrossTenant{Model: "Tenant", Identifier: tnt.LessorId.String()}
+	}
+
+	if m.properties[tnt.PropertyId] != tnt.LessorId {
+		return dac.ErrCrossTenant{Model: "Tenant", Identifier: tnt.PropertyId.String()}
+	}
+
+	tnt.User = usr
+	m.tenants[tnt.Uid] = *tnt
+	m.invites[invite.Uid] = *invite
+	return nil
+}
+
+func (m *memoryTenants) ReissueInvite(ctx context.Context, invite *model.TenantInvite) error {
+	m.invites[invite.Uid] = *invite
+	return nil
+}
+
+func (m *memoryTenants) Update(ctx context.Context, tnt any) (interface{}, error) {
+	return tnt, nil
+}
+
+func (m *memoryTenants) Delete(ctx context.Context, tnt any) error {
+	return nil
+}
+
+func lessorCtx(lessorId uuid.UUID) context.Context {
+	return auth.WithClaims(context.Background(), &auth.UserClaims{Id: lessorId.String(), Role: string(auth.Alessor)})
+}
+
+func TestInviteTenant(t *testing.T) {
+	repo := newMemoryTenants()
+	srvc := TenantService{repo: repo}
+	lessorId, propertyId := uuid.New(), uuid.New()
+	repo.properties[propertyId] = lessorId
+
+	invited, err := srvc.InviteTenant(lessorCtx(lessorId), &dtos.TenantRequest{
+		FirstName:  "Jo",
+		LastName:   "Tenant",
+		Email:      "jo@test.com",
+		LessorId:   lessorId.String(),
+		PropertyId: propertyId.String(),
+	})
+
+	if err != nil {
+		t.Fatalf("failed to invite tenant %v", err)
+	}
+
+	tnt := repo.tenants[uuid.MustParse(invited.Tenant.Uid)]
+	if tnt.User == nil || tnt.User.IsActive || tnt.User.Password != "" {
+		t.Errorf("expected the invited tenant's user to be inactive without a password, got %+v", tnt.User)
+	}
+
+	invite := repo.invites[tnt.Uid]
+	if invite.TokenHash != auth.HashToken(invited.InviteToken) {
+		t.Error("expected only the hash of the returned token to be stored")
+	}
+
+	if invite.LessorId != lessorId {
+		t.Errorf("expected the invite to be for lessor %v, got %v", lessorId, invite.LessorId)
+	}
+
+	// the accept query only matches invites that haven't expired, so the expiry is what ends the token
+	if ttl := time.Until(invite.ExpiresAt); ttl <= 0 || ttl > auth.InviteTokenTTL {
+		t.Errorf("expected the invite to expire within %v, expires in %v", auth.InviteTokenTTL, ttl)
+	}
+
+	if !invited.ExpiresAt.Equal(invite.ExpiresAt) {
+		t.Errorf("expected the response to expire at %v, got %v", invite.ExpiresAt, invited.ExpiresAt)
+	}
+}
+
+func TestInviteTenantCrossLessor(t *testing.T) {
+	repo := newMemoryTenants()
+	srvc := TenantService{repo: repo}
+	lessorId, otherLessor := uuid.New(), uuid.New()
+	otherProperty := uuid.New()
+	repo.properties[otherProperty] = otherLessor
+
+	var crossTenant dac.ErrCrossTenant
+
+	_, err := srvc.InviteTenant(lessorCtx(lessorId), &dtos.TenantRequest{LessorId: lessorId.String(), PropertyId: otherProperty.String()})
+	if !errors.As(err, &crossTenant) {
+		t.Errorf("expected inviting a tenant to another lessor's property to fail with %T, got %v", crossTenant, err)
+	}
+
+	_, err = srvc.InviteTenant(lessorCtx(lessorId), &dtos.TenantRequest{LessorId: otherLessor.String(), PropertyId: otherProperty.String()})
+	if !errors.As(err, &crossTenant) {
+		t.Errorf("expected inviting a tenant for another lessor to fail with %T, got %v", crossTenant, err)
+	}
+
+	if len(repo.tenants) != 0 || len(repo.invites) != 0 {
+		t.Errorf("expected nothing to be saved, got %v tenants and %v invites", len(repo.tenants), len(repo.invites))
+	}
+}
+
+func TestResendInvite(t *testing.T) {
+	repo := newMemoryTenants()
+	srvc := TenantService{repo: repo}
+	lessorId, propertyId := uuid.New(), uuid.New()
+	repo.properties[propertyId] = lessorId
+	ctx := lessorCtx(lessorId)
+
+	invited, err := srvc.InviteTenant(ctx, &dtos.TenantRequest{LessorId: lessorId.String(), PropertyId: propertyId.String()})
+	if err != nil {
+		t.Fatalf("failed to invite tenant %v", err)
+	}
+
+	uid := uuid.MustParse(invited.Tenant.Uid)
+	resent, err := srvc.ResendInvite(ctx, uid.String())
+	if err != nil {
+		t.Fatalf("failed to resend invite %v", err)
+	}
+
+	if resent.InviteToken == invited.InviteToken {
+		t.Fatal("expected a resent invite to have a new token")
+	}
+
+	if repo.invites[uid].TokenHash != auth.HashToken(resent.InviteToken) {
+		t.Error("expected the new token to replace the old one")
+	}
+
+	t.Run("accepted", func(t *testing.T) {
+		tnt := repo.tenants[uid]
+		tnt.User.IsActive = true
+		repo.tenants[uid] = tnt
+
+		var invalid services.ErrInvalidRequest
+		if _, err := srvc.ResendInvite(ctx, uid.String()); !errors.As(err, &invalid) {
+			t.Errorf("expected a tenant who signed up to not be invited again, got %v", err)
+		}
+	})
+
+	t.Run("other lessor", func(t *testing.T) {
+		var crossTenant dac.ErrCrossTenant
+		if _, err := srvc.ResendInvite(lessorCtx(uuid.New()), uid.String()); !errors.As(err, &crossTenant) {
+			t.Errorf("expected another lessor's resend to fail with %T, got %v", crossTenant, err)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package texts
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/sms"
+	"github.com/Z3DRP/lessor-service/internal/ztype"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// maxWebhookBody caps how much of a callback is read before the signature is checked
+const maxWebhookBody = 1 << 20
+
+// emptyReply tells the provider not to text anything back, the carrier already confirms
+// STOP and START
+const emptyReply = `<?xml version="1.0" encoding="UTF-8"?><Response></Response>`
+
+type SmsHandler struct {
+	SmsService
+}
+
+func NewHandler(service SmsService) SmsHandler {
+	return SmsHandler{
+		SmsService: service,
+	}
+}
+
+func (s SmsHandler) HandlerName() string {
+	return "Sms"
+}
+
+func (s SmsHandler) HandleGetMessages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		s.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		msgs, err := s.GetMessages(r.Context(), r.PathValue("id"))
+
+		if err != nil {
+			log.Printf("failed to fetch sms messages %v", err)
+			utils.WriteErr(w, smsErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"messages": msgs,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleStatusWebhook takes delivery callbacks from the sms provider, it isn't behind auth
+// so every callback has to carry a valid signature
+func (s SmsHandler) HandleStatusWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		s.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBody)
+		if err := r.ParseForm(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		updated, err := s.HandleStatus(r.Context(), r.URL.RequestURI(), r.Header, r.PostForm)
+
+		if err != nil {
+			s.logger.LogFields(logrus.Fields{"msg": "failed to handle sms status webhook", "err": err})
+			log.Printf("failed to handle sms status webhook %v", err)
+			utils.WriteErr(w, smsErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"updated": updated,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleInboundWebhook takes replies texted to our number, the provider expects an xml
+// reply so a successful one gets an empty response instead of json
+func (s SmsHandler) HandleInboundWebhook(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		s.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBody)
+		if err := r.ParseForm(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.HandleInbound(r.Context(), r.URL.RequestURI(), r.Header, r.PostForm); err != nil {
+			s.logger.LogFields(logrus.Fields{"msg": "failed to handle inbound sms", "err": err})
+			log.Printf("failed to handle inbound sms %v", err)
+			utils.WriteErr(w, smsErrStatus(err), err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte(emptyReply)); err != nil {
+			log.Printf("failed writing xml response %v", err)
+		}
+	}
+}
+
+func smsErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	if errors.Is(err, sms.ErrInvalidSignature) {
+		return http.StatusUnauthorized
+	}
+
+	if errors.Is(err, sms.ErrNotConfigured) {
+		return http.StatusServiceUnavailable
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
@@ -0,0 +1,104 @@
+package texts
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/sms"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type SmsService struct {
+	repo     dac.SmsRepo
+	provider sms.Provider
+	logger   *crane.Zlogrus
+}
+
+func (s SmsService) ServiceName() string {
+	return "Sms"
+}
+
+func NewSmsService(repo dac.SmsRepo, provider sms.Provider, logr *crane.Zlogrus) SmsService {
+	return SmsService{
+		repo:     repo,
+		provider: provider,
+		logger:   logr,
+	}
+}
+
+func (s SmsService) GetMessages(ctx context.Context, lessorId string) ([]dtos.SmsMessageResponse, error) {
+	id, err := uuid.Parse(lessorId)
+	if err != nil {
+		return nil, services.ErrInvalidRequest{ServiceType: s.ServiceName(), RequestType: "messages", Err: err}
+	}
+
+	msgs, err := s.repo.FetchMessages(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dtos.SmsMessageResponse, 0, len(msgs))
+	for _, m := range msgs {
+		res = append(res, dtos.NewSmsMessageResponse(m))
+	}
+
+	return res, nil
+}
+
+// HandleStatus applies a verified delivery callback, callbacks for texts this service
+// didn't send or that are older than what is stored are ignored
+func (s SmsService) HandleStatus(ctx context.Context, requestUri string, header http.Header, form url.Values) (bool, error) {
+	if err := s.provider.VerifyWebhook(requestUri, header, form); err != nil {
+		return false, err
+	}
+
+	event, err := s.provider.ParseStatus(requestUri, form)
+	if err != nil {
+		return false, services.ErrInvalidRequest{ServiceType: s.ServiceName(), RequestType: "status webhook", Err: err}
+	}
+
+	if event.Status == "" || (event.Reference == "" && event.ProviderId == "") {
+		return false, nil
+	}
+
+	// a reference that isn't ours falls back to the provider id
+	ref, _ := uuid.Parse(event.Reference)
+	return s.repo.ApplyStatus(auth.WithSystem(ctx), ref, event.ProviderId, event.Status, event.Error)
+}
+
+// HandleInbound records STOP and START replies, anything else a user texts back is only logged
+func (s SmsService) HandleInbound(ctx context.Context, requestUri string, header http.Header, form url.Values) error {
+	if err := s.provider.VerifyWebhook(requestUri, header, form); err != nil {
+		return err
+	}
+
+	inbound, err := s.provider.ParseInbound(form)
+	if err != nil {
+		return services.ErrInvalidRequest{ServiceType: s.ServiceName(), RequestType: "inbound webhook", Err: err}
+	}
+
+	phone, err := sms.Normalize(inbound.From)
+	if err != nil {
+		return services.ErrInvalidRequest{ServiceType: s.ServiceName(), RequestType: "inbound webhook", Err: err}
+	}
+
+	optOut, optIn := sms.Keyword(inbound.Body)
+	switch {
+	case optOut:
+		err = s.repo.OptOut(ctx, phone, strings.ToUpper(strings.TrimSpace(inbound.Body)))
+	case optIn:
+		err = s.repo.OptIn(ctx, phone)
+	default:
+		s.logger.LogFields(logrus.Fields{"msg": "ignoring inbound sms", "providerId": inbound.ProviderId})
+	}
+
+	return err
+}
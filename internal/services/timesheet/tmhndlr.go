@@ -0,0 +1,318 @@
+package timesheet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/Z3DRP/lessor-service/internal/ztype"
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type TimesheetHandler struct {
+	TimesheetService
+}
+
+func NewHandler(service TimesheetService) TimesheetHandler {
+	return TimesheetHandler{
+		TimesheetService: service,
+	}
+}
+
+func (t TimesheetHandler) HandlerName() string {
+	return "Timesheet"
+}
+
+func (t TimesheetHandler) HandleClockIn(w http.ResponseWriter, r *http.Request) {
+	t.handleClock(w, r, "clock in", t.ClockIn)
+}
+
+func (t TimesheetHandler) HandleClockOut(w http.ResponseWriter, r *http.Request) {
+	t.handleClock(w, r, "clock out", t.ClockOut)
+}
+
+func (t TimesheetHandler) HandleStartBreak(w http.ResponseWriter, r *http.Request) {
+	t.handleClock(w, r, "start break", func(ctx context.Context, tid string, _ string) (*dtos.TimeEntryResponse, error) {
+		return t.StartBreak(ctx, tid)
+	})
+}
+
+func (t TimesheetHandler) HandleEndBreak(w http.ResponseWriter, r *http.Request) {
+	t.handleClock(w, r, "end break", func(ctx context.Context, tid string, _ string) (*dtos.TimeEntryResponse, error) {
+		return t.EndBreak(ctx, tid)
+	})
+}
+
+func (t TimesheetHandler) HandleGetTaskEntries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		sheet, err := t.GetTaskEntries(r.Context(), r.PathValue("id"))
+
+		if err != nil {
+			log.Printf("failed to fetch task time entries %v", err)
+			utils.WriteErr(w, timesheetErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"timesheet": sheet,
+			"success":   true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TimesheetHandler) HandleGetTimesheet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		query := r.URL.Query()
+		sheet, err := t.GetTimesheet(r.Context(), r.PathValue("id"), query.Get("from"), query.Get("to"), query.Get("status"))
+
+		if err != nil {
+			log.Printf("failed to fetch worker timesheet %v", err)
+			utils.WriteErr(w, timesheetErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"timesheet": sheet,
+			"success":   true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TimesheetHandler) HandleAdjustEntry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.TimeAdjustRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		entry, err := t.AdjustEntry(r.Context(), r.PathValue("id"), *payload)
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to adjust time entry", "err": err})
+			log.Printf("failed to adjust time entry %v", err)
+			utils.WriteErr(w, timesheetErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"entry":   entry,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TimesheetHandler) HandleApproveEntry(w http.ResponseWriter, r *http.Request) {
+	t.handleReview(w, r, "approve", t.ApproveEntry)
+}
+
+func (t TimesheetHandler) HandleRejectEntry(w http.ResponseWriter, r *http.Request) {
+	t.handleReview(w, r, "reject", t.RejectEntry)
+}
+
+func (t TimesheetHandler) HandleApproveTimesheet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.TimesheetApproveRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		approved, err := t.ApproveTimesheet(r.Context(), r.PathValue("id"), *payload)
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to approve timesheet", "err": err})
+			log.Printf("failed to approve timesheet %v", err)
+			utils.WriteErr(w, timesheetErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"workerId": r.PathValue("id"),
+			"approved": approved,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// handleClock serves the worker's clock actions on a task, the notes body is optional
+func (t TimesheetHandler) handleClock(w http.ResponseWriter, r *http.Request, action string, clock func(context.Context, string, string) (*dtos.TimeEntryResponse, error)) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.ClockRequest{}
+
+		if err := parseOptional(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		entry, err := clock(r.Context(), r.PathValue("id"), payload.Notes)
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to " + action, "err": err})
+			log.Printf("failed to %v %v", action, err)
+			utils.WriteErr(w, timesheetErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"entry":   entry,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (t TimesheetHandler) handleReview(w http.ResponseWriter, r *http.Request, action string, review func(context.Context, string, string) (*dtos.TimeEntryResponse, error)) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		t.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.TimeReviewRequest{}
+
+		if err := parseOptional(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		entry, err := review(r.Context(), r.PathValue("id"), payload.Note)
+
+		if err != nil {
+			t.logger.LogFields(logrus.Fields{"msg": "failed to " + action + " time entry", "err": err})
+			log.Printf("failed to %v time entry %v", action, err)
+			utils.WriteErr(w, timesheetErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"entry":   entry,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// parseOptional treats an empty body as an empty payload
+func parseOptional(r *http.Request, payload any) error {
+	if err := utils.ParseJSON(r, payload); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func timesheetErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	if errors.Is(err, dac.ErrNotOnCrew) {
+		return http.StatusForbidden
+	}
+
+	switch {
+	case errors.Is(err, dac.ErrAlreadyClockedIn), errors.Is(err, dac.ErrNotClockedIn), errors.Is(err, dac.ErrBreakState),
+		errors.Is(err, dac.ErrEntryOverlap), errors.Is(err, dac.ErrEntryReviewed), errors.Is(err, dac.ErrEntryNotPending),
+		errors.Is(err, dac.ErrTaskClosed):
+		return http.StatusConflict
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
@@ -0,0 +1,282 @@
+package timesheet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// maxTimesheetDays keeps a timesheet to roughly a pay period or two
+const maxTimesheetDays = 62
+
+type TimesheetService struct {
+	repo   dac.TimeRepo
+	logger *crane.Zlogrus
+}
+
+func (t TimesheetService) ServiceName() string {
+	return "Timesheet"
+}
+
+func NewTimesheetService(repo dac.TimeRepo, logr *crane.Zlogrus) TimesheetService {
+	return TimesheetService{
+		repo:   repo,
+		logger: logr,
+	}
+}
+
+func (t TimesheetService) ClockIn(ctx context.Context, tid string, notes string) (*dtos.TimeEntryResponse, error) {
+	id, err := t.parseId(tid, "clock in")
+	if err != nil {
+		return nil, err
+	}
+
+	return t.respond(t.repo.ClockIn(ctx, id, notes))
+}
+
+func (t TimesheetService) ClockOut(ctx context.Context, tid string, notes string) (*dtos.TimeEntryResponse, error) {
+	id, err := t.parseId(tid, "clock out")
+	if err != nil {
+		return nil, err
+	}
+
+	return t.respond(t.repo.ClockOut(ctx, id, notes))
+}
+
+func (t TimesheetService) StartBreak(ctx context.Context, tid string) (*dtos.TimeEntryResponse, error) {
+	id, err := t.parseId(tid, "start break")
+	if err != nil {
+		return nil, err
+	}
+
+	return t.respond(t.repo.StartBreak(ctx, id))
+}
+
+func (t TimesheetService) EndBreak(ctx context.Context, tid string) (*dtos.TimeEntryResponse, error) {
+	id, err := t.parseId(tid, "end break")
+	if err != nil {
+		return nil, err
+	}
+
+	return t.respond(t.repo.EndBreak(ctx, id))
+}
+
+// GetTaskEntries is the labor logged against a task, a worker only sees their own entries
+func (t TimesheetService) GetTaskEntries(ctx context.Context, tid string) (*dtos.TimesheetResponse, error) {
+	id, err := t.parseId(tid, "task entries")
+	if err != nil {
+		return nil, err
+	}
+
+	query := dac.TimeQuery{TaskId: id}
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return nil, dac.ErrMissingScope{Err: err}
+	}
+
+	if claims.HasRole(auth.Worker) {
+		if query.WorkerId, err = uuid.Parse(claims.Id); err != nil {
+			return nil, dac.ErrMissingScope{Err: err}
+		}
+	}
+
+	entries, err := t.repo.FetchEntries(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	response := newTimesheet(entries, time.Now())
+	response.TaskId = id.String()
+	if query.WorkerId != uuid.Nil {
+		response.WorkerId = query.WorkerId.String()
+	}
+
+	return &response, nil
+}
+
+// GetTimesheet is the worker's entries clocked in between from and to, both take RFC3339 or a
+// plain date and default to the last week. status narrows it to one review state
+func (t TimesheetService) GetTimesheet(ctx context.Context, workerId string, from string, to string, status string) (*dtos.TimesheetResponse, error) {
+	wid, err := t.workerFor(ctx, workerId, "timesheet")
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := t.timesheetRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	entryStatus := model.TimeEntryStatus(status)
+	switch entryStatus {
+	case "", model.EntryOpen, model.EntryPending, model.EntryApproved, model.EntryRejected:
+	default:
+		return nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "timesheet", Err: fmt.Errorf("invalid status %v", status)}
+	}
+
+	entries, err := t.repo.FetchEntries(ctx, dac.TimeQuery{WorkerId: wid, From: start, To: end, Status: entryStatus})
+	if err != nil {
+		return nil, err
+	}
+
+	response := newTimesheet(entries, time.Now())
+	response.WorkerId = wid.String()
+	response.From = &start
+	response.To = &end
+	return &response, nil
+}
+
+func (t TimesheetService) AdjustEntry(ctx context.Context, eid string, adjust dtos.TimeAdjustRequest) (*dtos.TimeEntryResponse, error) {
+	id, err := t.parseId(eid, "adjust")
+	if err != nil {
+		return nil, err
+	}
+
+	return t.respond(t.repo.Adjust(ctx, id, adjust.ClockIn, adjust.ClockOut, adjust.BreakMinutes, adjust.Note))
+}
+
+func (t TimesheetService) ApproveEntry(ctx context.Context, eid string, note string) (*dtos.TimeEntryResponse, error) {
+	return t.review(ctx, eid, model.EntryApproved, note)
+}
+
+func (t TimesheetService) RejectEntry(ctx context.Context, eid string, note string) (*dtos.TimeEntryResponse, error) {
+	return t.review(ctx, eid, model.EntryRejected, note)
+}
+
+// ApproveTimesheet approves everything the worker has waiting for review in the range
+func (t TimesheetService) ApproveTimesheet(ctx context.Context, workerId string, approve dtos.TimesheetApproveRequest) (int, error) {
+	wid, err := t.parseId(workerId, "approve timesheet")
+	if err != nil {
+		return 0, err
+	}
+
+	return t.repo.ApproveRange(ctx, wid, approve.From, approve.To, approve.Note)
+}
+
+func (t TimesheetService) review(ctx context.Context, eid string, status model.TimeEntryStatus, note string) (*dtos.TimeEntryResponse, error) {
+	id, err := t.parseId(eid, string(status))
+	if err != nil {
+		return nil, err
+	}
+
+	return t.respond(t.repo.Review(ctx, id, status, note))
+}
+
+func (t TimesheetService) respond(entry model.TimeEntry, err error) (*dtos.TimeEntryResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewTimeEntryResponse(entry, time.Now())
+	return &response, nil
+}
+
+func (t TimesheetService) parseId(id string, requestType string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: requestType, Err: err}
+	}
+	return parsed, nil
+}
+
+// workerFor keeps a worker to their own timesheet
+func (t TimesheetService) workerFor(ctx context.Context, workerId string, requestType string) (uuid.UUID, error) {
+	wid, err := t.parseId(workerId, requestType)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return uuid.Nil, dac.ErrMissingScope{Err: err}
+	}
+
+	if claims.HasRole(auth.Worker) && claims.Id != wid.String() {
+		return uuid.Nil, dac.ErrCrossTenant{Model: "Worker", Identifier: workerId}
+	}
+
+	return wid, nil
+}
+
+func (t TimesheetService) timesheetRange(from string, to string) (time.Time, time.Time, error) {
+	invalid := func(err error) error {
+		return services.ErrInvalidRequest{ServiceType: t.ServiceName(), RequestType: "timesheet", Err: err}
+	}
+
+	end := time.Now().UTC()
+	if to != "" {
+		parsed, err := parseTime(to)
+		if err != nil {
+			return time.Time{}, time.Time{}, invalid(err)
+		}
+		end = parsed
+	}
+
+	start := end.AddDate(0, 0, -7)
+	if from != "" {
+		parsed, err := parseTime(from)
+		if err != nil {
+			return time.Time{}, time.Time{}, invalid(err)
+		}
+		start = parsed
+	}
+
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, invalid(errors.New("to must be after from"))
+	}
+
+	if end.Sub(start) > maxTimesheetDays*24*time.Hour {
+		return time.Time{}, time.Time{}, invalid(fmt.Errorf("timesheets can only cover %v days at a time", maxTimesheetDays))
+	}
+
+	return start, end, nil
+}
+
+// newTimesheet totals the entries by review state, open entries count up to now and rejected
+// time stays out of the overall hours
+func newTimesheet(entries []model.TimeEntry, now time.Time) dtos.TimesheetResponse {
+	response := dtos.TimesheetResponse{Entries: make([]dtos.TimeEntryResponse, 0, len(entries))}
+	minutes := make(map[model.TimeEntryStatus]int64)
+
+	for _, e := range entries {
+		response.Entries = append(response.Entries, dtos.NewTimeEntryResponse(e, now))
+		minutes[e.Status] += int64(e.Minutes(now))
+	}
+
+	hours := func(m int64) decimal.Decimal {
+		return decimal.NewFromInt(m).Div(decimal.NewFromInt(60)).Round(2)
+	}
+
+	response.Totals = dtos.TimeTotals{
+		Hours:         hours(minutes[model.EntryOpen] + minutes[model.EntryPending] + minutes[model.EntryApproved]),
+		OpenHours:     hours(minutes[model.EntryOpen]),
+		PendingHours:  hours(minutes[model.EntryPending]),
+		ApprovedHours: hours(minutes[model.EntryApproved]),
+		RejectedHours: hours(minutes[model.EntryRejected]),
+	}
+
+	return response
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 or YYYY-MM-DD", value)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,62 @@
+package timesheet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestNewTimesheet(t *testing.T) {
+	start := time.Date(2025, time.March, 3, 8, 0, 0, 0, time.UTC)
+	now := start.Add(10 * time.Hour)
+	entry := func(in time.Duration, out time.Duration, breaks int, status model.TimeEntryStatus) model.TimeEntry {
+		e := model.TimeEntry{Eid: uuid.New(), ClockIn: start.Add(in), BreakMinutes: breaks, Status: status}
+		if out > 0 {
+			e.ClockOut = start.Add(out)
+		}
+		return e
+	}
+
+	entries := []model.TimeEntry{
+		entry(0, 4*time.Hour, 30, model.EntryApproved),
+		entry(5*time.Hour, 7*time.Hour, 0, model.EntryPending),
+		entry(7*time.Hour, 8*time.Hour, 0, model.EntryRejected),
+		entry(9*time.Hour, 0, 0, model.EntryOpen),
+	}
+
+	sheet := newTimesheet(entries, now)
+
+	if len(sheet.Entries) != len(entries) {
+		t.Fatalf("expected %v entries, got %v", len(entries), len(sheet.Entries))
+	}
+
+	checks := []struct {
+		name string
+		got  decimal.Decimal
+		want string
+	}{
+		{"approved", sheet.Totals.ApprovedHours, "3.5"},
+		{"pending", sheet.Totals.PendingHours, "2"},
+		{"rejected", sheet.Totals.RejectedHours, "1"},
+		{"open", sheet.Totals.OpenHours, "1"},
+		{"total", sheet.Totals.Hours, "6.5"},
+	}
+
+	for _, c := range checks {
+		if !c.got.Equal(decimal.RequireFromString(c.want)) {
+			t.Errorf("expected %v hours %v, got %v", c.name, c.want, c.got)
+		}
+	}
+
+	t.Run("running break", func(t *testing.T) {
+		e := entry(9*time.Hour, 0, 0, model.EntryOpen)
+		e.BreakStartedAt = start.Add(9*time.Hour + 45*time.Minute)
+
+		if got := e.Minutes(now); got != 45 {
+			t.Errorf("expected running break to stop the clock at 45 minutes, got %v", got)
+		}
+	})
+}
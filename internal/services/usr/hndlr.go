@@ -1,6 +1,7 @@
 package usr
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"github.com/Z3DRP/lessor-service/internal/dtos"
 	"github.com/Z3DRP/lessor-service/internal/filters"
 	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
 	"github.com/Z3DRP/lessor-service/internal/services/alssr"
 	"github.com/Z3DRP/lessor-service/internal/ztype"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
@@ -62,7 +64,7 @@ func (u UserHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	authenticated, user, err := u.AuthenticateUser(r.Context(), creds)
 
 	if err != nil {
-		utils.WriteErr(w, http.StatusInternalServerError, err)
+		utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 		log.Printf("auth err %v", err)
 		return
 	}
@@ -73,7 +75,15 @@ func (u UserHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := auth.GenerateToken(user.Uid.String(), creds.Email, user.ProfileType)
+	// the lessor the user belongs to is carried in the token so repos can scope queries
+	lessorId, err := u.TokenLessor(r.Context(), &user)
+	if err != nil {
+		log.Printf("error in handler for worker data fetch %v", err)
+		utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	res, err := u.newSession(r.Context(), &user, lessorId.String(), creds.DeviceId)
 
 	if err != nil {
 		log.Printf("error generating token %v\n", err)
@@ -92,21 +102,11 @@ func (u UserHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	// })
 
 	uDto := dtos.NewSigninResponse(&user)
-	if user.ProfileType == "worker" {
-		var workerLessorId uuid.UUID
-		workerLessorId, err = u.GetWorkerLessor(r.Context(), &user)
-		if err != nil {
-			log.Printf("error in handler for worker data fetch %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
-			return
-		}
-		uDto.LessorId = workerLessorId
+	if user.ProfileType == "worker" || user.ProfileType == "tenant" {
+		uDto.LessorId = lessorId
 	}
 
-	res := ztype.JsonResponse{
-		"accessToken": token,
-		"user":        uDto,
-	}
+	res["user"] = uDto
 
 	if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
 		u.logger.MustDebug(err.Error())
@@ -155,11 +155,11 @@ func (u UserHandler) HandleSignUp(w http.ResponseWriter, r *http.Request) {
 
 		if err != nil {
 			log.Printf("failed to create alessor profile %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
-		token, err := auth.GenerateToken(user.Uid.String(), user.Email, user.ProfileType)
+		res, err := u.newSession(r.Context(), user, user.Uid.String(), payload.DeviceId)
 
 		if err != nil {
 			u.logger.MustDebug(fmt.Sprintf("auth error: %v", err))
@@ -168,12 +168,7 @@ func (u UserHandler) HandleSignUp(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		usrDto := dtos.NewSigninResponse(user)
-
-		res := ztype.JsonResponse{
-			"accessToken": token,
-			"user":        usrDto,
-		}
+		res["user"] = dtos.NewSigninResponse(user)
 
 		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
 			u.logger.MustDebug(fmt.Sprintf("json encoding err %v", err))
@@ -184,36 +179,30 @@ func (u UserHandler) HandleSignUp(w http.ResponseWriter, r *http.Request) {
 }
 
 func (u UserHandler) HandleSignUpWorker(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 	select {
 	case <-r.Context().Done():
 		timeoutErr := utils.ErrRequestTimeout{Request: r}
 		u.logger.MustDebug(timeoutErr.Error())
-		log.Println("request timeout")
 		utils.WriteErr(w, http.StatusRequestTimeout, &timeoutErr)
 	default:
-		// TODO change defalting to text communication preference
-		log.Println("worker signup ep")
 		var payload dtos.WorkerUserSignupRequest
-		w.Header().Set("Content-Type", "application/json")
 
 		if err := utils.ParseJSON(r, &payload); err != nil {
-			u.logger.MustDebug("failed to parse request body")
 			utils.WriteErr(w, http.StatusBadRequest, err)
-			log.Println(err)
 			return
 		}
 
 		if err := payload.Validate(); err != nil {
-			u.logger.MustDebug(fmt.Sprintf("user create payload failed validation, %v", err))
+			u.logger.MustDebug(fmt.Sprintf("worker signup payload failed validation, %v", err))
 			utils.WriteErr(w, http.StatusBadRequest, err)
-			log.Println(err)
 			return
 		}
 
 		signupRequest := dtos.UserSignupRequest{
 			FirstName:   payload.FirstName,
 			LastName:    payload.LastName,
-			ProfileType: payload.ProfileType,
+			ProfileType: string(auth.Worker),
 			Username:    payload.Username,
 			Password:    payload.Password,
 			Phone:       payload.Phone,
@@ -225,49 +214,185 @@ func (u UserHandler) HandleSignUpWorker(w http.ResponseWriter, r *http.Request)
 		if err != nil {
 			u.logger.MustDebug(fmt.Sprintf("database err %v", err))
 			utils.WriteErr(w, http.StatusBadRequest, err)
-			log.Println(err)
 			return
 		}
 
-		// create a initial alessor profile for the user
-		log.Println("")
-		log.Println("")
-		log.Printf("payload %+v", payload)
-		_, err = u.CreateWorker(r.Context(), user, payload)
-
-		if err != nil {
+		if _, err = u.CreateWorker(r.Context(), user, payload); err != nil {
 			log.Printf("failed to create worker profile %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
-		token, err := auth.GenerateToken(user.Uid.String(), user.Email, user.ProfileType)
-		log.Printf("Generating token for UID: %s, Email: %s, Role: %s", user.Uid.String(), user.Email, user.ProfileType)
+		lessorId := utils.ParseUuid(payload.LessorId)
+		res, err := u.newSession(r.Context(), user, lessorId.String(), payload.DeviceId)
 
 		if err != nil {
 			u.logger.MustDebug(fmt.Sprintf("auth error: %v", err))
 			utils.WriteErr(w, http.StatusInternalServerError, err)
-			log.Println("auth err")
 			return
 		}
 
-		usrDto := dtos.NewWorkerSignUpResponse(user, utils.ParseUuid(payload.LessorId))
+		res["user"] = dtos.NewWorkerSignUpResponse(user, lessorId)
 
-		res := ztype.JsonResponse{
-			"accessToken": token,
-			"user":        usrDto,
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("error encoding json: %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+// HandleSignUpTenant finishes the invite a lessor sent, the tenant's user already exists
+// so this only sets their credentials and signs them in
+func (u UserHandler) HandleSignUpTenant(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		timeoutErr := utils.ErrRequestTimeout{Request: r}
+		u.logger.MustDebug(timeoutErr.Error())
+		utils.WriteErr(w, http.StatusRequestTimeout, &timeoutErr)
+	default:
+		var payload dtos.TenantSignupRequest
+
+		if err := utils.ParseJSON(r, &payload); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			u.logger.MustDebug(fmt.Sprintf("tenant signup payload failed validation, %v", err))
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := u.AcceptTenantInvite(r.Context(), payload)
+
+		if err != nil {
+			u.logger.MustDebug(fmt.Sprintf("failed to accept tenant invite %v", err))
+			utils.WriteErr(w, signupErrStatus(err), err)
+			return
+		}
+
+		lessorId, err := u.TokenLessor(r.Context(), user)
+		if err != nil {
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
+			return
+		}
+
+		res, err := u.newSession(r.Context(), user, lessorId.String(), payload.DeviceId)
+
+		if err != nil {
+			u.logger.MustDebug(fmt.Sprintf("auth error: %v", err))
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+			return
 		}
 
-		log.Printf("returning login res: %+v", res)
+		res["user"] = dtos.NewWorkerSignUpResponse(user, lessorId)
 
 		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
-			u.logger.MustDebug(fmt.Sprintf("json encoding err %v", err))
 			log.Printf("error encoding json: %v", err)
 			utils.WriteErr(w, http.StatusInternalServerError, err)
 		}
 	}
 }
 
+func (u UserHandler) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var payload dtos.RefreshTokenRequest
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteErr(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := payload.Validate(); err != nil {
+		utils.WriteErr(w, http.StatusBadRequest, err)
+		return
+	}
+
+	user, refreshToken, err := u.RotateRefreshToken(r.Context(), payload.RefreshToken)
+	if err != nil {
+		u.logger.MustDebug(fmt.Sprintf("refresh token rejected, %v", err))
+		utils.WriteErr(w, tokenErrStatus(err), errors.New("unauthorized"))
+		return
+	}
+
+	lessorId, err := u.TokenLessor(r.Context(), &user)
+	if err != nil {
+		utils.WriteErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	token, err := auth.GenerateToken(user.Uid.String(), user.Email, user.ProfileType, lessorId.String())
+	if err != nil {
+		utils.WriteErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := ztype.JsonResponse{
+		"accessToken":  token,
+		"refreshToken": refreshToken,
+	}
+
+	if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+		utils.WriteErr(w, http.StatusInternalServerError, err)
+	}
+}
+
+func (u UserHandler) HandleSignOut(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var payload dtos.RefreshTokenRequest
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteErr(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := payload.Validate(); err != nil {
+		utils.WriteErr(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := u.RevokeRefreshToken(r.Context(), payload.RefreshToken); err != nil {
+		u.logger.MustDebug(fmt.Sprintf("failed to revoke refresh token, %v", err))
+		utils.WriteErr(w, tokenErrStatus(err), err)
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, ztype.JsonResponse{"success": true}); err != nil {
+		utils.WriteErr(w, http.StatusInternalServerError, err)
+	}
+}
+
+// newSession creates the access token and a refresh token for the device signing in,
+// devices that do not send an id are given one to send back on their next sign in
+func (u UserHandler) newSession(ctx context.Context, user *model.User, lessorId string, deviceId string) (ztype.JsonResponse, error) {
+	if deviceId == "" {
+		deviceId = uuid.NewString()
+	}
+
+	token, err := auth.GenerateToken(user.Uid.String(), user.Email, user.ProfileType, lessorId)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := u.IssueRefreshToken(ctx, user.Uid, deviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	return ztype.JsonResponse{
+		"accessToken":  token,
+		"refreshToken": refreshToken,
+		"deviceId":     deviceId,
+	}, nil
+}
+
+func tokenErrStatus(err error) int {
+	var invalidToken auth.ErrInvalidToken
+	if errors.As(err, &invalidToken) {
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
 func (u UserHandler) HandleGetDetails(w http.ResponseWriter, r *http.Request) {
 	select {
 	case <-r.Context().Done():
@@ -391,7 +516,7 @@ func (u UserHandler) HandleGetUsers(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 			u.logger.MustDebug(fmt.Sprintf("database err, %v", err))
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -436,7 +561,7 @@ func (u UserHandler) HandleGetUser(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			u.logger.MustDebug(fmt.Sprintf("database errr, %v", err))
-			utils.WriteErr(w, http.StatusBadRequest, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusBadRequest), err)
 			return
 		}
 
@@ -469,6 +594,13 @@ func (u UserHandler) HandleUpdateUser(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// the user being changed is the one in the url, never one named in the body
+		if payload.Uid != "" && payload.Uid != r.PathValue("id") {
+			utils.WriteErr(w, http.StatusBadRequest, errors.New("user id in body does not match the url"))
+			return
+		}
+		payload.Uid = r.PathValue("id")
+
 		if err := payload.Validate(); err != nil {
 			u.logger.MustDebug(fmt.Sprintf("user dto failed validation, %v", err))
 			utils.WriteErr(w, http.StatusBadRequest, err)
@@ -478,7 +610,7 @@ func (u UserHandler) HandleUpdateUser(w http.ResponseWriter, r *http.Request) {
 		updateUsr, err := u.ModifyUser(r.Context(), payload)
 		if err != nil {
 			u.logger.MustDebug(fmt.Sprintf("database err, %v", err))
-			utils.WriteErr(w, http.StatusBadRequest, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusBadRequest), err)
 			return
 		}
 
@@ -525,7 +657,7 @@ func (u UserHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
 
 		if err = u.DeleteUsr(r.Context(), req); err != nil {
 			u.logger.MustDebug(fmt.Sprintf("database err, %v", err))
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -539,3 +671,17 @@ func (u UserHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+func signupErrStatus(err error) int {
+	if errors.Is(err, dac.ErrInviteInvalid) {
+		return http.StatusUnauthorized
+	}
+
+	// the username is the only unique column an invited user sets themselves
+	var updateFailed dac.ErrUpdateFailed
+	if errors.As(err, &updateFailed) {
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
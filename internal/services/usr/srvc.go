@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/Z3DRP/lessor-service/internal/auth"
 	"github.com/Z3DRP/lessor-service/internal/cmerr"
@@ -18,19 +19,45 @@ import (
 	"github.com/google/uuid"
 )
 
+// RefreshTokens stores refresh tokens by their hash, dac.RefreshTokenRepo satisfies it
+type RefreshTokens interface {
+	FetchByHash(ctx context.Context, hash string) (model.RefreshToken, error)
+	Insert(ctx context.Context, token any) (interface{}, error)
+	Rotate(ctx context.Context, current model.RefreshToken, next *model.RefreshToken) error
+	RevokeFamily(ctx context.Context, familyId uuid.UUID) error
+}
+
+// TokenUsers loads the user a refresh token belongs to, dac.UserRepo satisfies it
+type TokenUsers interface {
+	GetTokenUser(ctx context.Context, uid uuid.UUID) (model.User, error)
+}
+
+// UserWriter saves changes to a user, dac.UserRepo satisfies it
+type UserWriter interface {
+	Update(ctx context.Context, usr any) (interface{}, error)
+}
+
 type UserService struct {
-	repo   dac.UserRepo
-	logger *crane.Zlogrus
+	repo    dac.UserRepo
+	users   TokenUsers
+	writer  UserWriter
+	tokens  RefreshTokens
+	tenants dac.TenantRepo
+	logger  *crane.Zlogrus
 }
 
 func (p UserService) ServiceName() string {
 	return "User"
 }
 
-func NewUserService(repo dac.UserRepo, logr *crane.Zlogrus) UserService {
+func NewUserService(repo dac.UserRepo, tokens RefreshTokens, tenants dac.TenantRepo, logr *crane.Zlogrus) UserService {
 	return UserService{
-		repo:   repo,
-		logger: logr,
+		repo:    repo,
+		users:   &repo,
+		writer:  &repo,
+		tokens:  tokens,
+		tenants: tenants,
+		logger:  logr,
 	}
 }
 
@@ -57,6 +84,11 @@ func (u UserService) AuthenticateUser(ctx context.Context, fltr filters.Filterer
 		return false, model.User{}, cmerr.ErrUnexpectedData{Wanted: model.User{}, Got: usr}
 	}
 
+	// invited tenants have no password until they accept and removed tenants are deactivated
+	if !user.IsActive {
+		return false, model.User{}, nil
+	}
+
 	isMatch, err := auth.VerifyHash(user.Password, credentials.Password)
 	if err != nil {
 		return false, model.User{}, err
@@ -66,7 +98,11 @@ func (u UserService) AuthenticateUser(ctx context.Context, fltr filters.Filterer
 }
 
 func (u UserService) ValidateClaims(ctx context.Context, token string) (model.User, error) {
-	claims := auth.ParseAuthToken(token)
+	claims, err := auth.ParseAuthToken(token)
+	if err != nil {
+		return model.User{}, err
+	}
+
 	user, err := u.GetUsr(ctx, filters.Filter{Identifier: claims.Id})
 
 	if err != nil {
@@ -125,22 +161,16 @@ func (a *UserService) CreateAlessor(ctx context.Context, usr *model.User) (model
 }
 
 func (a *UserService) CreateWorker(ctx context.Context, usr *model.User, nwWorker dtos.WorkerUserSignupRequest) (model.Worker, error) {
-	// TODO: update the defaults here for payrate and payment method needs to come from somwhere frontend will have to be updated
 	wrkr := model.Worker{
 		Uid:           usr.Uid,
 		StartDate:     nwWorker.StartDate,
 		Title:         nwWorker.Title,
 		LessorId:      utils.ParseUuid(nwWorker.LessorId),
 		PayRate:       nwWorker.PayRate,
-		PaymentMethod: model.MethodOfPayment(nwWorker.PaymentMethod), //default to cash payment for now
+		PaymentMethod: model.MethodOfPayment(nwWorker.PaymentMethod),
 	}
 
-	log.Println("")
-	log.Println("")
-	log.Printf("worker start date %v", wrkr.StartDate)
-	log.Println("")
 	worker, err := a.repo.InsertWorker(ctx, wrkr)
-
 	if err != nil {
 		log.Printf("failed worker creation in service: %v", err)
 		return model.Worker{}, err
@@ -165,6 +195,148 @@ func (a *UserService) GetWorkerLessor(ctx context.Context, usr *model.User) (uui
 	return worker.LessorId, nil
 }
 
+func (a *UserService) GetTenantLessor(ctx context.Context, usr *model.User) (uuid.UUID, error) {
+	tenant, err := a.repo.GetTenant(ctx, usr.Uid)
+
+	if err != nil {
+		log.Printf("failed to get tenant data for signin %v", err)
+		return uuid.Nil, err
+	}
+
+	return tenant.LessorId, nil
+}
+
+// TokenLessor is the lessor a user's token is scoped to, alessors are their own lessor
+func (a *UserService) TokenLessor(ctx context.Context, usr *model.User) (uuid.UUID, error) {
+	switch usr.ProfileType {
+	case "worker":
+		return a.GetWorkerLessor(ctx, usr)
+	case "tenant":
+		return a.GetTenantLessor(ctx, usr)
+	default:
+		return usr.Uid, nil
+	}
+}
+
+// IssueRefreshToken starts a new token family for the device, any token the device
+// already held is revoked
+func (u UserService) IssueRefreshToken(ctx context.Context, uid uuid.UUID, deviceId string) (string, error) {
+	familyId, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+
+	token, tkn, err := newRefreshToken(uid, familyId, deviceId)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = u.tokens.Insert(ctx, tkn); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken trades a refresh token for a new one in the same family. A token
+// that was already rotated means it leaked so the whole family is revoked
+func (u UserService) RotateRefreshToken(ctx context.Context, refreshToken string) (model.User, string, error) {
+	current, err := u.lookupRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return model.User{}, "", err
+	}
+
+	if !current.RotatedAt.IsZero() {
+		return model.User{}, "", u.revokeReused(ctx, current)
+	}
+
+	if !current.RevokedAt.IsZero() || current.Expired() {
+		return model.User{}, "", auth.ErrInvalidToken{Err: errors.New("refresh token is no longer valid")}
+	}
+
+	user, err := u.users.GetTokenUser(ctx, current.Uid)
+	if err != nil {
+		var noResults dac.ErrNoResults
+		if errors.As(err, &noResults) {
+			return model.User{}, "", auth.ErrInvalidToken{Err: err}
+		}
+		return model.User{}, "", err
+	}
+
+	if !user.IsActive {
+		return model.User{}, "", auth.ErrInvalidToken{Err: errors.New("user is not active")}
+	}
+
+	token, next, err := newRefreshToken(current.Uid, current.FamilyId, current.DeviceId)
+	if err != nil {
+		return model.User{}, "", err
+	}
+
+	if err = u.tokens.Rotate(ctx, current, next); err != nil {
+		if errors.Is(err, dac.ErrTokenReused) {
+			return model.User{}, "", u.revokeReused(ctx, current)
+		}
+		return model.User{}, "", err
+	}
+
+	return user, token, nil
+}
+
+func (u UserService) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	current, err := u.lookupRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return u.tokens.RevokeFamily(ctx, current.FamilyId)
+}
+
+func (u UserService) lookupRefreshToken(ctx context.Context, refreshToken string) (model.RefreshToken, error) {
+	claims, err := auth.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	current, err := u.tokens.FetchByHash(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		var noResults dac.ErrNoResults
+		if errors.As(err, &noResults) {
+			return model.RefreshToken{}, auth.ErrInvalidToken{Err: err}
+		}
+		return model.RefreshToken{}, err
+	}
+
+	if current.Uid.String() != claims.Subject {
+		return model.RefreshToken{}, auth.ErrInvalidToken{Err: errors.New("refresh token subject mismatch")}
+	}
+
+	return current, nil
+}
+
+func (u UserService) revokeReused(ctx context.Context, tkn model.RefreshToken) error {
+	u.logger.MustWarn(fmt.Sprintf("refresh token reuse detected for user %v device %v, revoking family %v", tkn.Uid, tkn.DeviceId, tkn.FamilyId))
+	if err := u.tokens.RevokeFamily(ctx, tkn.FamilyId); err != nil {
+		return err
+	}
+
+	return auth.ErrInvalidToken{Err: dac.ErrTokenReused}
+}
+
+func newRefreshToken(uid uuid.UUID, familyId uuid.UUID, deviceId string) (string, *model.RefreshToken, error) {
+	token, expiresAt, err := auth.GenerateRefreshToken(uid.String())
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, &model.RefreshToken{
+		Uid:       uid,
+		FamilyId:  familyId,
+		DeviceId:  deviceId,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
 func (p UserService) GetUsrs(ctx context.Context, fltr filters.Filter) ([]model.User, error) {
 	prfls, err := p.repo.FetchAll(ctx, fltr)
 
@@ -205,6 +377,24 @@ func (u UserService) CreateUsr(ctx context.Context, udata dtos.UserSignupRequest
 	return user, nil
 }
 
+// AcceptTenantInvite activates the user a lessor invited with the username and password
+// the tenant chose, the invite token can only be used once
+func (u UserService) AcceptTenantInvite(ctx context.Context, req dtos.TenantSignupRequest) (*model.User, error) {
+	hashPwd, err := auth.HashString(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("could not create user safely, %v", err)
+	}
+
+	user, err := u.tenants.AcceptInvite(ctx, auth.HashToken(req.InviteToken), req.Username, hashPwd, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ModifyUser lets workers and tenants change their own user, admins and lessors can edit
+// the users in their scope but a password is only ever changed by its owner
 func (p UserService) ModifyUser(ctx context.Context, pdto dtos.UserRequest) (model.User, error) {
 	pf := newUser(pdto)
 
@@ -212,7 +402,27 @@ func (p UserService) ModifyUser(ctx context.Context, pdto dtos.UserRequest) (mod
 		return model.User{}, services.ErrInvalidRequest{ServiceType: p.ServiceName(), RequestType: "Update", Err: nil}
 	}
 
-	prfl, err := p.repo.Update(ctx, pf)
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return model.User{}, dac.ErrMissingScope{Err: err}
+	}
+
+	self := claims.Id == pf.Uid.String()
+	if !self && !claims.HasRole(auth.Admin) && !claims.HasRole(auth.Alessor) {
+		return model.User{}, dac.ErrCrossTenant{Model: "User", Identifier: pf.Uid.String()}
+	}
+
+	if pf.Password != "" {
+		if !self {
+			return model.User{}, dac.ErrCrossTenant{Model: "User Password", Identifier: pf.Uid.String()}
+		}
+
+		if pf.Password, err = auth.HashString(pf.Password); err != nil {
+			return model.User{}, fmt.Errorf("could not update user safely, %v", err)
+		}
+	}
+
+	prfl, err := p.writer.Update(ctx, *pf)
 	if err != nil {
 		return model.User{}, err
 	}
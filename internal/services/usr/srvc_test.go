@@ -0,0 +1,242 @@
+package usr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/cmerr"
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+// TestMain runs from a temp dir with a config file so refresh tokens are signed the same
+// way they are for the server
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "usr")
+	if err != nil {
+		panic(err)
+	}
+
+	if err = os.MkdirAll(filepath.Join(dir, "config"), 0o755); err != nil {
+		panic(err)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "config", "config.yaml"), []byte("authKey: test\n"), 0o644); err != nil {
+		panic(err)
+	}
+
+	if err = os.Chdir(dir); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// memoryTokens keeps refresh tokens by hash and follows the same rules as dac.RefreshTokenRepo
+type memoryTokens struct {
+	byHash map[string]*model.RefreshToken
+	nextId int64
+}
+
+func (m *memoryTokens) FetchByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+	tkn, ok := m.byHash[hash]
+	if !ok {
+		return model.RefreshToken{}, dac.ErrNoResults{Identifier: hash}
+	}
+	return *tkn, nil
+}
+
+func (m *memoryTokens) Insert(ctx context.Context, token any) (interface{}, error) {
+	tkn := token.(*model.RefreshToken)
+	m.nextId++
+	tkn.Id = m.nextId
+	m.byHash[tkn.TokenHash] = tkn
+	return tkn, nil
+}
+
+func (m *memoryTokens) Rotate(ctx context.Context, current model.RefreshToken, next *model.RefreshToken) error {
+	stored := m.byHash[current.TokenHash]
+	if !stored.RotatedAt.IsZero() || !stored.RevokedAt.IsZero() {
+		return dac.ErrTokenReused
+	}
+
+	stored.RotatedAt = time.Now()
+	_, err := m.Insert(ctx, next)
+	return err
+}
+
+func (m *memoryTokens) RevokeFamily(ctx context.Context, familyId uuid.UUID) error {
+	for _, tkn := range m.byHash {
+		if tkn.FamilyId == familyId && tkn.RevokedAt.IsZero() {
+			tkn.RevokedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+type tokenUser model.User
+
+func (u tokenUser) GetTokenUser(ctx context.Context, uid uuid.UUID) (model.User, error) {
+	if uid != u.Uid {
+		return model.User{}, dac.ErrNoResults{Identifier: uid.String()}
+	}
+	return model.User(u), nil
+}
+
+// memoryWriter keeps the last user saved and takes the same model.User value dac.UserRepo does
+type memoryWriter struct {
+	saved model.User
+}
+
+func (m *memoryWriter) Update(ctx context.Context, usr any) (interface{}, error) {
+	pf, ok := usr.(model.User)
+	if !ok {
+		return nil, cmerr.ErrUnexpectedData{Wanted: model.User{}, Got: usr}
+	}
+
+	m.saved = pf
+	return pf, nil
+}
+
+func testService(t *testing.T) (UserService, *memoryTokens, model.User) {
+	user := model.User{Uid: uuid.New(), Email: "worker@test.com", ProfileType: "worker", IsActive: true}
+	tokens := &memoryTokens{byHash: map[string]*model.RefreshToken{}}
+	logger := crane.NewLogger(crane.NewLogFile(crane.WithFilename(filepath.Join(t.TempDir(), "usr.log"))))
+
+	return UserService{users: tokenUser(user), tokens: tokens, logger: logger}, tokens, user
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	srvc, tokens, user := testService(t)
+	ctx := context.Background()
+
+	first, err := srvc.IssueRefreshToken(ctx, user.Uid, "phone")
+	if err != nil {
+		t.Fatalf("failed to issue refresh token %v", err)
+	}
+
+	rotatedFor, second, err := srvc.RotateRefreshToken(ctx, first)
+	if err != nil {
+		t.Fatalf("failed to rotate refresh token %v", err)
+	}
+
+	if rotatedFor.Uid != user.Uid {
+		t.Errorf("expected token to be rotated for %v, got %v", user.Uid, rotatedFor.Uid)
+	}
+
+	if second == first {
+		t.Fatal("expected rotation to return a new token")
+	}
+
+	old := tokens.byHash[auth.HashToken(first)]
+	next := tokens.byHash[auth.HashToken(second)]
+	if next == nil {
+		t.Fatal("expected the new token to be stored")
+	}
+
+	if next.FamilyId != old.FamilyId || next.DeviceId != old.DeviceId {
+		t.Errorf("expected the new token in family %v for device %v, got family %v device %v", old.FamilyId, old.DeviceId, next.FamilyId, next.DeviceId)
+	}
+
+	if old.RotatedAt.IsZero() {
+		t.Error("expected the old token to be marked rotated")
+	}
+
+	if _, _, err = srvc.RotateRefreshToken(ctx, second); err != nil {
+		t.Errorf("expected the new token to rotate, got %v", err)
+	}
+}
+
+func TestRotateReusedRefreshToken(t *testing.T) {
+	srvc, tokens, user := testService(t)
+	ctx := context.Background()
+
+	first, err := srvc.IssueRefreshToken(ctx, user.Uid, "phone")
+	if err != nil {
+		t.Fatalf("failed to issue refresh token %v", err)
+	}
+
+	other, err := srvc.IssueRefreshToken(ctx, user.Uid, "laptop")
+	if err != nil {
+		t.Fatalf("failed to issue refresh token %v", err)
+	}
+
+	_, second, err := srvc.RotateRefreshToken(ctx, first)
+	if err != nil {
+		t.Fatalf("failed to rotate refresh token %v", err)
+	}
+
+	_, _, err = srvc.RotateRefreshToken(ctx, first)
+	var invalid auth.ErrInvalidToken
+	if !errors.As(err, &invalid) || !errors.Is(invalid.Err, dac.ErrTokenReused) {
+		t.Fatalf("expected reusing a rotated token to fail with %v, got %v", dac.ErrTokenReused, err)
+	}
+
+	family := tokens.byHash[auth.HashToken(first)].FamilyId
+	for _, tkn := range tokens.byHash {
+		if tkn.FamilyId == family && tkn.RevokedAt.IsZero() {
+			t.Errorf("expected token %v in the reused family to be revoked", tkn.Id)
+		}
+	}
+
+	if _, _, err = srvc.RotateRefreshToken(ctx, second); !errors.As(err, &invalid) {
+		t.Errorf("expected the newest token in the family to be revoked, got %v", err)
+	}
+
+	if _, _, err = srvc.RotateRefreshToken(ctx, other); err != nil {
+		t.Errorf("expected another device's token to still rotate, got %v", err)
+	}
+}
+
+func TestModifyUser(t *testing.T) {
+	srvc, _, user := testService(t)
+	writer := &memoryWriter{}
+	srvc.writer = writer
+
+	self := auth.WithClaims(context.Background(), &auth.UserClaims{Id: user.Uid.String(), Role: string(auth.Worker)})
+	updated, err := srvc.ModifyUser(self, dtos.UserRequest{Uid: user.Uid.String(), FirstName: "Sam", Password: "new-password"})
+	if err != nil {
+		t.Fatalf("failed to modify user %v", err)
+	}
+
+	if updated.Uid != user.Uid || updated.FirstName != "Sam" {
+		t.Errorf("expected user %v to be renamed Sam, got %v %v", user.Uid, updated.Uid, updated.FirstName)
+	}
+
+	if match, _ := auth.VerifyHash(writer.saved.Password, "new-password"); !match {
+		t.Error("expected the new password to be hashed before it's saved")
+	}
+
+	t.Run("other users", func(t *testing.T) {
+		other := auth.WithClaims(context.Background(), &auth.UserClaims{Id: uuid.NewString(), Role: string(auth.Worker)})
+		_, err := srvc.ModifyUser(other, dtos.UserRequest{Uid: user.Uid.String(), FirstName: "Sam"})
+
+		var crossTenant dac.ErrCrossTenant
+		if !errors.As(err, &crossTenant) {
+			t.Errorf("expected a worker changing another user to fail with %T, got %v", crossTenant, err)
+		}
+	})
+
+	t.Run("lessor changing a password", func(t *testing.T) {
+		lessor := auth.WithClaims(context.Background(), &auth.UserClaims{Id: uuid.NewString(), Role: string(auth.Alessor)})
+		if _, err := srvc.ModifyUser(lessor, dtos.UserRequest{Uid: user.Uid.String(), FirstName: "Sam"}); err != nil {
+			t.Errorf("expected a lessor to edit a user in their scope, got %v", err)
+		}
+
+		_, err := srvc.ModifyUser(lessor, dtos.UserRequest{Uid: user.Uid.String(), Password: "new-password"})
+		var crossTenant dac.ErrCrossTenant
+		if !errors.As(err, &crossTenant) {
+			t.Errorf("expected only the owner to change a password, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/auth"
+	"github.com/Z3DRP/lessor-service/internal/availability"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/Z3DRP/lessor-service/internal/services"
+	"github.com/google/uuid"
+)
+
+// maxAvailabilityDays keeps a single availability lookup to about a month of slots
+const maxAvailabilityDays = 31
+
+// GetAvailability returns the worker's free slots between from and to, both take RFC3339 or a
+// plain date and default to the next week starting now
+func (w WorkerService) GetAvailability(ctx context.Context, workerId string, from string, to string) (*dtos.AvailabilityResponse, error) {
+	wid, err := w.workerFor(ctx, workerId, "availability")
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := w.availabilityRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	wc, err := w.availability.FetchCalendar(ctx, wid, start, end, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := availability.NewCalendar(wc)
+	response := dtos.AvailabilityResponse{
+		WorkerId: wid.String(),
+		Timezone: cal.Location.String(),
+		From:     start,
+		To:       end,
+		Hours:    make([]dtos.WorkingHoursDto, 0, len(wc.Hours)),
+		TimeOff:  make([]dtos.TimeOffResponse, 0, len(wc.TimeOff)),
+		Booked:   cal.Booked(),
+		Free:     cal.Free(start, end),
+	}
+
+	for _, h := range wc.Hours {
+		response.Hours = append(response.Hours, dtos.NewWorkingHoursDto(h))
+	}
+
+	for _, off := range wc.TimeOff {
+		response.TimeOff = append(response.TimeOff, dtos.NewTimeOffResponse(off))
+	}
+
+	return &response, nil
+}
+
+// SetWorkingHours replaces the worker's whole week, sending no hours clears them and leaves
+// the worker bookable at any time
+func (w WorkerService) SetWorkingHours(ctx context.Context, workerId string, req *dtos.WorkingHoursRequest) ([]dtos.WorkingHoursDto, error) {
+	wid, err := w.workerFor(ctx, workerId, "working hours")
+	if err != nil {
+		return nil, err
+	}
+
+	hours := make([]model.WorkingHours, 0, len(req.Hours))
+	for _, h := range req.Hours {
+		start, end, err := h.Minutes()
+		if err != nil {
+			return nil, services.ErrInvalidRequest{ServiceType: w.ServiceName(), RequestType: "working hours", Err: err}
+		}
+		hours = append(hours, model.WorkingHours{Weekday: time.Weekday(h.Weekday), StartMinute: start, EndMinute: end})
+	}
+
+	if err = w.availability.ReplaceHours(ctx, wid, req.Timezone, hours); err != nil {
+		return nil, err
+	}
+
+	response := make([]dtos.WorkingHoursDto, 0, len(hours))
+	for _, h := range hours {
+		response = append(response, dtos.NewWorkingHoursDto(h))
+	}
+
+	return response, nil
+}
+
+func (w WorkerService) AddTimeOff(ctx context.Context, workerId string, req *dtos.TimeOffRequest) (*dtos.TimeOffResponse, error) {
+	wid, err := w.workerFor(ctx, workerId, "time off")
+	if err != nil {
+		return nil, err
+	}
+
+	off := model.TimeOff{
+		Oid:      uuid.New(),
+		WorkerId: wid,
+		StartAt:  req.StartAt,
+		EndAt:    req.EndAt,
+		Reason:   req.Reason,
+	}
+
+	if err = w.availability.InsertTimeOff(ctx, &off); err != nil {
+		return nil, err
+	}
+
+	response := dtos.NewTimeOffResponse(off)
+	return &response, nil
+}
+
+func (w WorkerService) RemoveTimeOff(ctx context.Context, workerId string, offId string) error {
+	wid, err := w.workerFor(ctx, workerId, "time off")
+	if err != nil {
+		return err
+	}
+
+	oid, err := uuid.Parse(offId)
+	if err != nil {
+		return services.ErrInvalidRequest{ServiceType: w.ServiceName(), RequestType: "time off", Err: err}
+	}
+
+	return w.availability.DeleteTimeOff(ctx, wid, oid)
+}
+
+// workerFor parses the worker id and keeps workers to their own calendar
+func (w WorkerService) workerFor(ctx context.Context, workerId string, requestType string) (uuid.UUID, error) {
+	wid, err := uuid.Parse(workerId)
+	if err != nil {
+		return uuid.Nil, services.ErrInvalidRequest{ServiceType: w.ServiceName(), RequestType: requestType, Err: err}
+	}
+
+	claims, err := auth.ClaimsFromContext(ctx)
+	if err != nil {
+		return uuid.Nil, dac.ErrMissingScope{Err: err}
+	}
+
+	if claims.HasRole(auth.Worker) && claims.Id != wid.String() {
+		return uuid.Nil, dac.ErrCrossTenant{Model: "Worker", Identifier: workerId}
+	}
+
+	return wid, nil
+}
+
+func (w WorkerService) availabilityRange(from string, to string) (time.Time, time.Time, error) {
+	invalid := func(err error) error {
+		return services.ErrInvalidRequest{ServiceType: w.ServiceName(), RequestType: "availability", Err: err}
+	}
+
+	start := time.Now().UTC()
+	if from != "" {
+		t, err := parseTime(from)
+		if err != nil {
+			return time.Time{}, time.Time{}, invalid(err)
+		}
+		start = t
+	}
+
+	end := start.AddDate(0, 0, 7)
+	if to != "" {
+		t, err := parseTime(to)
+		if err != nil {
+			return time.Time{}, time.Time{}, invalid(err)
+		}
+		end = t
+	}
+
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, invalid(errors.New("to must be after from"))
+	}
+
+	if end.Sub(start) > maxAvailabilityDays*24*time.Hour {
+		return time.Time{}, time.Time{}, invalid(fmt.Errorf("availability can only be looked up %v days at a time", maxAvailabilityDays))
+	}
+
+	return start, end, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 or YYYY-MM-DD", value)
+	}
+
+	return t, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/Z3DRP/lessor-service/internal/dac"
 	"github.com/Z3DRP/lessor-service/internal/dtos"
 	"github.com/Z3DRP/lessor-service/internal/filters"
+	"github.com/Z3DRP/lessor-service/internal/services"
 	"github.com/Z3DRP/lessor-service/internal/ztype"
 	"github.com/Z3DRP/lessor-service/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -49,7 +50,7 @@ func (wk WorkerHandler) HandleCreateWorker(w http.ResponseWriter, r *http.Reques
 		if err != nil {
 			wk.logger.LogFields(logrus.Fields{"msg": "failed to create worker", "err": err})
 			log.Printf("failed to create worker db err %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -98,7 +99,7 @@ func (wk WorkerHandler) HandleGetWorker(w http.ResponseWriter, r *http.Request)
 			}
 			wk.logger.LogFields(logrus.Fields{"msg": "database err", "err": err})
 			log.Printf("database err %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -146,7 +147,7 @@ func (wk WorkerHandler) HandleGetWorkers(w http.ResponseWriter, r *http.Request)
 				}
 			}
 			log.Printf("database err failed to fetch workers %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -191,7 +192,7 @@ func (wk WorkerHandler) HandleUpdateWorker(w http.ResponseWriter, r *http.Reques
 
 		if err != nil {
 			log.Printf("database error failed to update worker %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -223,7 +224,7 @@ func (wk WorkerHandler) HandleDeleteWorker(w http.ResponseWriter, r *http.Reques
 
 		if err != nil {
 			log.Printf("database err failed to delete worker %v", err)
-			utils.WriteErr(w, http.StatusInternalServerError, err)
+			utils.WriteErr(w, services.StatusFor(err, http.StatusInternalServerError), err)
 			return
 		}
 
@@ -238,3 +239,156 @@ func (wk WorkerHandler) HandleDeleteWorker(w http.ResponseWriter, r *http.Reques
 		}
 	}
 }
+
+func (wk WorkerHandler) HandleGetAvailability(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		wk.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		query := r.URL.Query()
+		avail, err := wk.GetAvailability(r.Context(), r.PathValue("id"), query.Get("from"), query.Get("to"))
+
+		if err != nil {
+			log.Printf("failed to fetch worker availability %v", err)
+			utils.WriteErr(w, workerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"availability": avail,
+			"success":      true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (wk WorkerHandler) HandleSetWorkingHours(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		wk.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.WorkingHoursRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		hours, err := wk.SetWorkingHours(r.Context(), r.PathValue("id"), payload)
+
+		if err != nil {
+			log.Printf("failed to set working hours %v", err)
+			utils.WriteErr(w, workerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"timezone": payload.Timezone,
+			"hours":    hours,
+			"success":  true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (wk WorkerHandler) HandleAddTimeOff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		wk.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		payload := &dtos.TimeOffRequest{}
+
+		if err := utils.ParseJSON(r, payload); err != nil {
+			log.Printf("failed to parse request body %v", err)
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := payload.Validate(); err != nil {
+			utils.WriteErr(w, http.StatusBadRequest, err)
+			return
+		}
+
+		off, err := wk.AddTimeOff(r.Context(), r.PathValue("id"), payload)
+
+		if err != nil {
+			log.Printf("failed to add time off %v", err)
+			utils.WriteErr(w, workerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"timeOff": off,
+			"success": true,
+		}
+
+		if err = utils.WriteJSON(w, http.StatusCreated, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func (wk WorkerHandler) HandleRemoveTimeOff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-r.Context().Done():
+		err := utils.ErrRequestTimeout{Request: r}
+		wk.logger.LogFields(logrus.Fields{"msg": "request timeout", "err": err})
+		utils.WriteErr(w, http.StatusRequestTimeout, err)
+	default:
+		offId := r.PathValue("offId")
+		if err := wk.RemoveTimeOff(r.Context(), r.PathValue("id"), offId); err != nil {
+			log.Printf("failed to remove time off %v", err)
+			utils.WriteErr(w, workerErrStatus(err), err)
+			return
+		}
+
+		res := ztype.JsonResponse{
+			"timeOffId": offId,
+			"success":   true,
+		}
+
+		if err := utils.WriteJSON(w, http.StatusOK, res); err != nil {
+			log.Printf("failed writing json response %v", err)
+			utils.WriteErr(w, http.StatusInternalServerError, err)
+		}
+	}
+}
+
+func workerErrStatus(err error) int {
+	var invalidReq services.ErrInvalidRequest
+	if errors.As(err, &invalidReq) {
+		return http.StatusBadRequest
+	}
+
+	var noResults dac.ErrNoResults
+	if errors.As(err, &noResults) {
+		return http.StatusNotFound
+	}
+
+	return services.StatusFor(err, http.StatusInternalServerError)
+}
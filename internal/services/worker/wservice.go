@@ -19,8 +19,9 @@ import (
 )
 
 type WorkerService struct {
-	repo   dac.WorkerRepo
-	logger *crane.Zlogrus
+	repo         dac.WorkerRepo
+	availability dac.AvailabilityRepo
+	logger       *crane.Zlogrus
 	//s3Actor api.FilePersister
 }
 
@@ -29,9 +30,10 @@ func (p WorkerService) ServiceName() string {
 }
 
 // TODO when adding images will have to pass in s3Actor
-func NewWorkerService(repo dac.WorkerRepo, logr *crane.Zlogrus) WorkerService {
+func NewWorkerService(repo dac.WorkerRepo, avail dac.AvailabilityRepo, logr *crane.Zlogrus) WorkerService {
 	return WorkerService{
-		repo: repo,
+		repo:         repo,
+		availability: avail,
 		//s3Actor: actr,
 		logger: logr,
 	}
@@ -0,0 +1,160 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+const (
+	fakeSignatureHeader = "X-Fake-Signature"
+	fakeOptedOutCode    = "OPTED_OUT"
+)
+
+type fakeText struct {
+	msg       Message
+	id        string
+	reference string
+}
+
+// Fake is an in memory provider for tests and local development, it records every text and
+// refuses numbers that replied STOP to it the way a carrier would
+type Fake struct {
+	secret  string
+	mu      *sync.Mutex
+	sent    *[]fakeText
+	blocked map[string]bool
+}
+
+func NewFake(secret string) Fake {
+	return Fake{
+		secret:  secret,
+		mu:      &sync.Mutex{},
+		sent:    &[]fakeText{},
+		blocked: make(map[string]bool),
+	}
+}
+
+func (f Fake) Name() string {
+	return "fake"
+}
+
+func (f Fake) Send(ctx context.Context, msg Message) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.blocked[msg.To] {
+		return Result{}, ErrProviderFailed{Provider: f.Name(), Status: http.StatusBadRequest, Code: fakeOptedOutCode, Err: fmt.Errorf("%v has opted out", msg.To)}
+	}
+
+	text := fakeText{msg: msg, id: uuid.NewString(), reference: msg.Reference}
+	*f.sent = append(*f.sent, text)
+	return Result{ProviderId: text.id, Status: model.SmsSent}, nil
+}
+
+// Sent returns every text sent so far, oldest first
+func (f Fake) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	msgs := make([]Message, 0, len(*f.sent))
+	for _, text := range *f.sent {
+		msgs = append(msgs, text.msg)
+	}
+	return msgs
+}
+
+// Deliver returns the status callback the fake would have sent for a text
+func (f Fake) Deliver(providerId string, status model.SmsStatus) (string, url.Values, http.Header, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, text := range *f.sent {
+		if text.id != providerId {
+			continue
+		}
+
+		requestUri := "/webhooks/sms/status?ref=" + url.QueryEscape(text.reference)
+		form := url.Values{"MessageSid": {text.id}, "MessageStatus": {string(status)}}
+		return requestUri, form, f.header(requestUri, form), nil
+	}
+
+	return "", nil, nil, fmt.Errorf("text %v does not exist", providerId)
+}
+
+// Reply returns the inbound callback for a text from the number, a STOP reply also blocks
+// the number like a carrier would
+func (f Fake) Reply(from string, body string) (string, url.Values, http.Header) {
+	f.mu.Lock()
+	if optOut, optIn := Keyword(body); optOut {
+		f.blocked[from] = true
+	} else if optIn {
+		delete(f.blocked, from)
+	}
+	f.mu.Unlock()
+
+	requestUri := "/webhooks/sms/inbound"
+	form := url.Values{"MessageSid": {uuid.NewString()}, "From": {from}, "Body": {body}}
+	return requestUri, form, f.header(requestUri, form)
+}
+
+func (f Fake) VerifyWebhook(requestUri string, header http.Header, form url.Values) error {
+	signature, err := hex.DecodeString(header.Get(fakeSignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal(signature, f.sign(requestUri, form)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (f Fake) ParseStatus(requestUri string, form url.Values) (StatusEvent, error) {
+	parsed, err := url.ParseRequestURI(requestUri)
+	if err != nil {
+		return StatusEvent{}, fmt.Errorf("failed to parse callback url %v", err)
+	}
+
+	return StatusEvent{
+		ProviderId: form.Get("MessageSid"),
+		Reference:  parsed.Query().Get("ref"),
+		Status:     model.SmsStatus(form.Get("MessageStatus")),
+	}, nil
+}
+
+func (f Fake) ParseInbound(form url.Values) (Inbound, error) {
+	if form.Get("From") == "" {
+		return Inbound{}, fmt.Errorf("inbound message has no sender")
+	}
+
+	return Inbound{ProviderId: form.Get("MessageSid"), From: form.Get("From"), Body: form.Get("Body")}, nil
+}
+
+func (f Fake) OptedOut(err error) bool {
+	var failed ErrProviderFailed
+	return errors.As(err, &failed) && failed.Code == fakeOptedOutCode
+}
+
+func (f Fake) header(requestUri string, form url.Values) http.Header {
+	header := http.Header{}
+	header.Set(fakeSignatureHeader, hex.EncodeToString(f.sign(requestUri, form)))
+	return header
+}
+
+func (f Fake) sign(requestUri string, form url.Values) []byte {
+	mac := hmac.New(sha256.New, []byte(f.secret))
+	mac.Write([]byte(requestUri))
+	mac.Write([]byte(form.Encode()))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,155 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+)
+
+const maxBodyLength = 320
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	ErrNotConfigured    = errors.New("sms provider is not configured")
+	ErrInvalidPhone     = errors.New("phone number can not be texted")
+)
+
+// ErrProviderFailed is returned when the provider refuses or can't send a message, Status is
+// the http status the provider answered with and Code its own error code when it sent one
+type ErrProviderFailed struct {
+	Provider string
+	Status   int
+	Code     string
+	Err      error
+}
+
+func (e ErrProviderFailed) Error() string {
+	return fmt.Sprintf("%v request failed %v %v: %v", e.Provider, e.Status, e.Code, e.Err)
+}
+
+func (e ErrProviderFailed) Unwrap() error {
+	return e.Err
+}
+
+// Retryable is false when sending again would be refused the same way, the provider
+// rejecting the request itself is permanent unless it was rate limiting
+func (e ErrProviderFailed) Retryable() bool {
+	return e.Status == 0 || e.Status == http.StatusTooManyRequests || e.Status >= http.StatusInternalServerError
+}
+
+// Message is a text to a single E.164 number, Reference is handed back on status callbacks
+type Message struct {
+	To        string
+	Body      string
+	Reference string
+}
+
+type Result struct {
+	ProviderId string
+	Status     model.SmsStatus
+}
+
+// StatusEvent is a delivery update, events with no status are ignored
+type StatusEvent struct {
+	ProviderId string
+	Reference  string
+	Status     model.SmsStatus
+	Error      string
+}
+
+// Inbound is a text a user sent back to our number
+type Inbound struct {
+	ProviderId string
+	From       string
+	Body       string
+}
+
+// Provider sends texts and takes the callbacks that come back. Callbacks are form posts
+// signed over the url they were sent to, requestUri is the path and query they arrived on
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, msg Message) (Result, error)
+	VerifyWebhook(requestUri string, header http.Header, form url.Values) error
+	ParseStatus(requestUri string, form url.Values) (StatusEvent, error)
+	ParseInbound(form url.Values) (Inbound, error)
+	// OptedOut reports whether the send failed because the number has blocked us
+	OptedOut(err error) bool
+}
+
+// NewProvider picks the provider from SMS_PROVIDER, twilio is the default and fake is
+// only meant for local development
+func NewProvider() Provider {
+	switch strings.ToLower(os.Getenv("SMS_PROVIDER")) {
+	case "fake":
+		log.Printf("WARNING using the fake sms provider no texts will be sent")
+		return NewFake(os.Getenv("SMS_WEBHOOK_KEY"))
+	default:
+		twilio := NewTwilio(TwilioConfig{
+			BaseUrl:    os.Getenv("TWILIO_BASE_URL"),
+			AccountSid: os.Getenv("TWILIO_ACCOUNT_SID"),
+			AuthToken:  os.Getenv("TWILIO_AUTH_TOKEN"),
+			From:       os.Getenv("TWILIO_FROM_NUMBER"),
+			WebhookUrl: os.Getenv("SMS_WEBHOOK_URL"),
+		})
+		if twilio.cfg.AccountSid == "" || twilio.cfg.AuthToken == "" {
+			log.Printf("WARNING twilio credentials are not set texts will fail")
+		}
+		return twilio
+	}
+}
+
+// Normalize turns a stored phone number into E.164, ten digit numbers are taken as US
+func Normalize(phone string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+
+	switch {
+	case len(digits) == 10 && !strings.HasPrefix(strings.TrimSpace(phone), "+"):
+		return "+1" + digits, nil
+	case len(digits) == 11 && digits[0] == '1':
+		return "+" + digits, nil
+	case strings.HasPrefix(strings.TrimSpace(phone), "+") && len(digits) >= 8 && len(digits) <= 15:
+		return "+" + digits, nil
+	default:
+		return "", ErrInvalidPhone
+	}
+}
+
+// Keyword reads the carrier keywords out of a reply, anything else is left alone
+func Keyword(body string) (optOut bool, optIn bool) {
+	switch strings.ToUpper(strings.TrimSpace(body)) {
+	case "STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT", "OPTOUT", "REVOKE":
+		return true, false
+	case "START", "YES", "UNSTOP":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// NotificationBody is the text version of a notification, long messages are cut short
+// since the full one is in the app
+func NotificationBody(n model.Notification) string {
+	body := fmt.Sprintf("Lessor: %v", n.Title)
+	if n.Message != "" {
+		body = fmt.Sprintf("%v - %v", body, n.Message)
+	}
+
+	if runes := []rune(body); len(runes) > maxBodyLength {
+		body = string(runes[:maxBodyLength-3]) + "..."
+	}
+
+	return body + " Reply STOP to opt out."
+}
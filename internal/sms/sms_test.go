@@ -0,0 +1,82 @@
+package sms
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, test := range []struct {
+		phone string
+		want  string
+		err   bool
+	}{
+		{"555-123-4567", "+15551234567", false},
+		{"(555) 123 4567", "+15551234567", false},
+		{"15551234567", "+15551234567", false},
+		{"+44 20 7946 0958", "+442079460958", false},
+		{"12345", "", true},
+		{"", "", true},
+	} {
+		got, err := Normalize(test.phone)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("%q expected %q err %v, got %q %v", test.phone, test.want, test.err, got, err)
+		}
+	}
+}
+
+func TestKeyword(t *testing.T) {
+	for body, want := range map[string][2]bool{
+		"STOP":        {true, false},
+		" stop\n":     {true, false},
+		"Unsubscribe": {true, false},
+		"start":       {false, true},
+		"stop please": {false, false},
+		"thanks!":     {false, false},
+	} {
+		optOut, optIn := Keyword(body)
+		if optOut != want[0] || optIn != want[1] {
+			t.Errorf("%q expected opt out %v opt in %v, got %v %v", body, want[0], want[1], optOut, optIn)
+		}
+	}
+}
+
+func TestNotificationBody(t *testing.T) {
+	body := NotificationBody(model.Notification{Title: "Rent due", Message: strings.Repeat("x", 500)})
+	if !strings.HasPrefix(body, "Lessor: Rent due - x") || !strings.HasSuffix(body, "... Reply STOP to opt out.") {
+		t.Errorf("expected a cut short body with the opt out line, got %q", body)
+	}
+}
+
+func TestTwilioVerifyWebhook(t *testing.T) {
+	twilio := NewTwilio(TwilioConfig{AuthToken: "token", WebhookUrl: "https://lessor.test/"})
+	requestUri := "/webhooks/sms/status?ref=abc"
+	form := url.Values{"MessageStatus": {"delivered"}, "MessageSid": {"SM1"}, "AccountSid": {"AC1"}}
+
+	mac := hmac.New(sha1.New, []byte("token"))
+	mac.Write([]byte("https://lessor.test/webhooks/sms/status?ref=abc" + "AccountSidAC1" + "MessageSidSM1" + "MessageStatusdelivered"))
+	header := http.Header{}
+	header.Set(twilioSignatureHeader, base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	if err := twilio.VerifyWebhook(requestUri, header, form); err != nil {
+		t.Errorf("expected a valid signature got %v", err)
+	}
+
+	form.Set("MessageStatus", "failed")
+	if err := twilio.VerifyWebhook(requestUri, header, form); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected a changed form to fail got %v", err)
+	}
+
+	event, err := twilio.ParseStatus(requestUri, url.Values{"MessageSid": {"SM1"}, "MessageStatus": {"undelivered"}, "ErrorCode": {"30003"}})
+	if err != nil || event.Reference != "abc" || event.Status != model.SmsFailed || event.Error == "" {
+		t.Errorf("expected a failed event for abc, got %+v %v", event, err)
+	}
+}
@@ -0,0 +1,206 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+)
+
+const (
+	twilioBaseUrl         = "https://api.twilio.com"
+	twilioSignatureHeader = "X-Twilio-Signature"
+	twilioStatusPath      = "/webhooks/sms/status"
+	twilioTimeout         = 20 * time.Second
+	// twilioUnsubscribed is the error twilio sends when the number replied STOP to us
+	twilioUnsubscribed = "21610"
+)
+
+// TwilioConfig holds the account credentials, WebhookUrl is the public base url of this
+// service exactly as twilio calls it since it is part of the signed payload
+type TwilioConfig struct {
+	BaseUrl    string
+	AccountSid string
+	AuthToken  string
+	From       string
+	WebhookUrl string
+}
+
+type Twilio struct {
+	cfg    TwilioConfig
+	client *http.Client
+}
+
+func NewTwilio(cfg TwilioConfig) Twilio {
+	if cfg.BaseUrl == "" {
+		cfg.BaseUrl = twilioBaseUrl
+	}
+	cfg.WebhookUrl = strings.TrimRight(cfg.WebhookUrl, "/")
+
+	return Twilio{
+		cfg:    cfg,
+		client: &http.Client{Timeout: twilioTimeout},
+	}
+}
+
+func (t Twilio) Name() string {
+	return "twilio"
+}
+
+type twilioMessage struct {
+	Sid    string `json:"sid"`
+	Status string `json:"status"`
+}
+
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (t Twilio) Send(ctx context.Context, msg Message) (Result, error) {
+	if t.cfg.AccountSid == "" || t.cfg.AuthToken == "" || t.cfg.From == "" {
+		return Result{}, ErrNotConfigured
+	}
+
+	form := url.Values{}
+	form.Set("To", msg.To)
+	form.Set("From", t.cfg.From)
+	form.Set("Body", msg.Body)
+	if t.cfg.WebhookUrl != "" {
+		form.Set("StatusCallback", fmt.Sprintf("%v%v?ref=%v", t.cfg.WebhookUrl, twilioStatusPath, url.QueryEscape(msg.Reference)))
+	}
+
+	endpoint := fmt.Sprintf("%v/2010-04-01/Accounts/%v/Messages.json", t.cfg.BaseUrl, url.PathEscape(t.cfg.AccountSid))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Result{}, err
+	}
+	req.SetBasicAuth(t.cfg.AccountSid, t.cfg.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return Result{}, ErrProviderFailed{Provider: t.Name(), Err: err}
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return Result{}, ErrProviderFailed{Provider: t.Name(), Status: res.StatusCode, Err: err}
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		var failure twilioError
+		_ = json.Unmarshal(body, &failure)
+		return Result{}, ErrProviderFailed{
+			Provider: t.Name(),
+			Status:   res.StatusCode,
+			Code:     strconv.Itoa(failure.Code),
+			Err:      fmt.Errorf("%v", failure.Message),
+		}
+	}
+
+	var sent twilioMessage
+	if err = json.Unmarshal(body, &sent); err != nil {
+		return Result{}, ErrProviderFailed{Provider: t.Name(), Status: res.StatusCode, Err: fmt.Errorf("failed to decode response %v", err)}
+	}
+
+	return Result{ProviderId: sent.Sid, Status: twilioStatus(sent.Status)}, nil
+}
+
+// VerifyWebhook checks the base64 hmac-sha1 twilio sends, it is signed over the full url
+// followed by each form field name and value sorted by name
+func (t Twilio) VerifyWebhook(requestUri string, header http.Header, form url.Values) error {
+	if t.cfg.AuthToken == "" || t.cfg.WebhookUrl == "" {
+		return ErrNotConfigured
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(header.Get(twilioSignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal(signature, t.sign(t.cfg.WebhookUrl+requestUri, form)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (t Twilio) sign(fullUrl string, form url.Values) []byte {
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha1.New, []byte(t.cfg.AuthToken))
+	mac.Write([]byte(fullUrl))
+	for _, key := range keys {
+		for _, value := range form[key] {
+			mac.Write([]byte(key + value))
+		}
+	}
+
+	return mac.Sum(nil)
+}
+
+func (t Twilio) ParseStatus(requestUri string, form url.Values) (StatusEvent, error) {
+	parsed, err := url.ParseRequestURI(requestUri)
+	if err != nil {
+		return StatusEvent{}, fmt.Errorf("failed to parse callback url %v", err)
+	}
+
+	event := StatusEvent{
+		ProviderId: form.Get("MessageSid"),
+		Reference:  parsed.Query().Get("ref"),
+		Status:     twilioStatus(form.Get("MessageStatus")),
+	}
+
+	if code := form.Get("ErrorCode"); code != "" && event.Status == model.SmsFailed {
+		event.Error = "twilio error " + code
+	}
+
+	return event, nil
+}
+
+func (t Twilio) ParseInbound(form url.Values) (Inbound, error) {
+	if form.Get("From") == "" {
+		return Inbound{}, fmt.Errorf("inbound message has no sender")
+	}
+
+	return Inbound{ProviderId: form.Get("MessageSid"), From: form.Get("From"), Body: form.Get("Body")}, nil
+}
+
+func (t Twilio) OptedOut(err error) bool {
+	var failed ErrProviderFailed
+	return errors.As(err, &failed) && failed.Code == twilioUnsubscribed
+}
+
+func twilioStatus(status string) model.SmsStatus {
+	switch status {
+	case "accepted", "scheduled", "queued":
+		return model.SmsQueued
+	case "sending", "sent":
+		return model.SmsSent
+	case "delivered", "read":
+		return model.SmsDelivered
+	case "undelivered", "failed", "canceled":
+		return model.SmsFailed
+	default:
+		return ""
+	}
+}
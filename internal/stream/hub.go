@@ -0,0 +1,440 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/dtos"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+const (
+	subscriberBuffer = 64
+	replayLimit      = 100
+	defaultHeartbeat = 25 * time.Second
+	loadTimeout      = 10 * time.Second
+)
+
+var (
+	ErrHubClosed      = errors.New("notification stream is closed")
+	ErrSlowSubscriber = errors.New("notification stream subscriber fell behind")
+)
+
+// Source loads what is pushed, dac.StreamRepo satisfies it
+type Source interface {
+	Notification(ctx context.Context, id int64) (model.Notification, error)
+	TaskEvent(ctx context.Context, id int64) (model.TaskEvent, error)
+	Crew(ctx context.Context, taskId uuid.UUID) ([]uuid.UUID, error)
+	Latest(ctx context.Context) (int64, int64, error)
+	NotificationsSince(ctx context.Context, uid uuid.UUID, after int64, limit int) ([]model.Notification, error)
+	TaskEventsSince(ctx context.Context, uid uuid.UUID, after int64, limit int) ([]model.TaskEvent, error)
+}
+
+// Listener receives the signals other replicas publish, *pgdriver.Listener satisfies it
+type Listener interface {
+	Listen(ctx context.Context, channels ...string) error
+	Channel(opts ...pgdriver.ChannelOption) <-chan pgdriver.Notification
+	Close() error
+}
+
+// Writer puts events on the wire, the sse and websocket connections each have one
+type Writer interface {
+	Send(id Cursor, e Event) error
+	Heartbeat() error
+}
+
+type EventType string
+
+const (
+	NotificationEvent EventType = "notification"
+	TaskStatusEvent   EventType = "task"
+	// ReadyEvent follows the replay so a client always has an id to resume from
+	ReadyEvent EventType = "ready"
+	// ResetEvent means too much was missed to replay and the client should reload
+	ResetEvent EventType = "reset"
+)
+
+type Event struct {
+	Type EventType
+	Seq  int64
+	At   time.Time
+	Data any
+}
+
+// Cursor is the event id clients get, the last notification and task event they have seen
+type Cursor struct {
+	Notification int64
+	Task         int64
+}
+
+func ParseCursor(id string) (Cursor, error) {
+	notification, task, ok := strings.Cut(id, "-")
+	if !ok {
+		return Cursor{}, fmt.Errorf("invalid event id %q", id)
+	}
+
+	n, err := strconv.ParseInt(notification, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid event id %q", id)
+	}
+
+	t, err := strconv.ParseInt(task, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid event id %q", id)
+	}
+
+	return Cursor{Notification: n, Task: t}, nil
+}
+
+func (c Cursor) String() string {
+	return fmt.Sprintf("%d-%d", c.Notification, c.Task)
+}
+
+func (c Cursor) seen(e Event) bool {
+	switch e.Type {
+	case NotificationEvent:
+		return e.Seq <= c.Notification
+	case TaskStatusEvent:
+		return e.Seq <= c.Task
+	default:
+		return false
+	}
+}
+
+func (c *Cursor) advance(e Event) {
+	switch {
+	case e.Type == NotificationEvent && e.Seq > c.Notification:
+		c.Notification = e.Seq
+	case e.Type == TaskStatusEvent && e.Seq > c.Task:
+		c.Task = e.Seq
+	}
+}
+
+type subscriber struct {
+	uid     uuid.UUID
+	events  chan Event
+	dropped chan struct{}
+}
+
+// Hub pushes new notifications and task status changes to the users connected to this
+// replica. Every replica listens on the same postgres channel so it doesn't matter which
+// one wrote the row, and a client that reconnects with its last event id is sent what it
+// missed first
+type Hub struct {
+	source    Source
+	listener  Listener
+	heartbeat time.Duration
+	logger    *crane.Zlogrus
+
+	mu     sync.RWMutex
+	subs   map[uuid.UUID]map[*subscriber]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewHub(source Source, listener Listener, logr *crane.Zlogrus) *Hub {
+	return &Hub{
+		source:    source,
+		listener:  listener,
+		heartbeat: defaultHeartbeat,
+		logger:    logr,
+		subs:      make(map[uuid.UUID]map[*subscriber]struct{}),
+	}
+}
+
+// Start listens for signals, a failed LISTEN is logged since the listener keeps retrying it
+func (h *Hub) Start(ctx context.Context) {
+	if err := h.listener.Listen(ctx, dac.StreamChannel); err != nil {
+		h.logger.LogFields(logrus.Fields{"msg": "failed to listen for stream signals, retrying", "err": err})
+	}
+
+	signals := h.listener.Channel()
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		for n := range signals {
+			var signal dac.StreamSignal
+			if err := json.Unmarshal([]byte(n.Payload), &signal); err != nil {
+				h.logger.LogFields(logrus.Fields{"msg": "ignoring malformed stream signal", "err": err})
+				continue
+			}
+			h.dispatch(signal)
+		}
+	}()
+}
+
+// Stop disconnects everyone, clients reconnect to another replica and resume there
+func (h *Hub) Stop() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+
+	h.closed = true
+	for _, subs := range h.subs {
+		for sub := range subs {
+			close(sub.dropped)
+		}
+	}
+	h.subs = make(map[uuid.UUID]map[*subscriber]struct{})
+	h.mu.Unlock()
+
+	err := h.listener.Close()
+	h.wg.Wait()
+	return err
+}
+
+// Serve streams the user's events to the writer until the context ends, lastEventId is
+// the id of the last event the client saw on a previous connection
+func (h *Hub) Serve(ctx context.Context, uid uuid.UUID, lastEventId string, w Writer) error {
+	// subscribing before reading the cursor means nothing is missed in between, whatever
+	// arrives that the replay already covered is skipped
+	sub, err := h.subscribe(uid)
+	if err != nil {
+		return err
+	}
+	defer h.unsubscribe(sub)
+
+	cursor, err := h.replay(ctx, uid, lastEventId, w)
+	if err != nil {
+		return err
+	}
+
+	floor := cursor
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.dropped:
+			if h.isClosed() {
+				return nil
+			}
+			return ErrSlowSubscriber
+		case e := <-sub.events:
+			if floor.seen(e) {
+				continue
+			}
+
+			cursor.advance(e)
+			if err = w.Send(cursor, e); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err = w.Heartbeat(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (h *Hub) replay(ctx context.Context, uid uuid.UUID, lastEventId string, w Writer) (Cursor, error) {
+	if lastEventId == "" {
+		cursor, err := h.latest(ctx)
+		if err != nil {
+			return Cursor{}, err
+		}
+		return cursor, w.Send(cursor, Event{Type: ReadyEvent})
+	}
+
+	cursor, err := ParseCursor(lastEventId)
+	if err != nil {
+		return h.reset(ctx, w)
+	}
+
+	notis, err := h.source.NotificationsSince(ctx, uid, cursor.Notification, replayLimit)
+	if err != nil {
+		return Cursor{}, err
+	}
+
+	tasks, err := h.source.TaskEventsSince(ctx, uid, cursor.Task, replayLimit)
+	if err != nil {
+		return Cursor{}, err
+	}
+
+	if len(notis) >= replayLimit || len(tasks) >= replayLimit {
+		return h.reset(ctx, w)
+	}
+
+	backlog := make([]Event, 0, len(notis)+len(tasks))
+	for _, n := range notis {
+		backlog = append(backlog, notificationEvent(n))
+	}
+	for _, t := range tasks {
+		backlog = append(backlog, taskEvent(t))
+	}
+	sort.SliceStable(backlog, func(i, j int) bool { return backlog[i].At.Before(backlog[j].At) })
+
+	for _, e := range backlog {
+		cursor.advance(e)
+		if err = w.Send(cursor, e); err != nil {
+			return Cursor{}, err
+		}
+	}
+
+	return cursor, w.Send(cursor, Event{Type: ReadyEvent})
+}
+
+func (h *Hub) reset(ctx context.Context, w Writer) (Cursor, error) {
+	cursor, err := h.latest(ctx)
+	if err != nil {
+		return Cursor{}, err
+	}
+	return cursor, w.Send(cursor, Event{Type: ResetEvent})
+}
+
+func (h *Hub) latest(ctx context.Context) (Cursor, error) {
+	n, t, err := h.source.Latest(ctx)
+	if err != nil {
+		return Cursor{}, err
+	}
+	return Cursor{Notification: n, Task: t}, nil
+}
+
+// dispatch only loads the row when someone it is for is connected here
+func (h *Hub) dispatch(signal dac.StreamSignal) {
+	ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+	defer cancel()
+
+	switch signal.Kind {
+	case dac.StreamNotification:
+		if !h.listening(signal.UserId) {
+			return
+		}
+
+		n, err := h.source.Notification(ctx, signal.Id)
+		if err != nil {
+			h.logger.LogFields(logrus.Fields{"msg": "failed to load streamed notification", "id": signal.Id, "err": err})
+			return
+		}
+		h.deliver(notificationEvent(n), signal.UserId)
+	case dac.StreamTask:
+		if !h.listening() {
+			return
+		}
+
+		crew, err := h.source.Crew(ctx, signal.TaskId)
+		if err != nil {
+			h.logger.LogFields(logrus.Fields{"msg": "failed to load task crew", "taskId": signal.TaskId, "err": err})
+			return
+		}
+
+		audience := append(crew, signal.LessorId)
+		if !h.listening(audience...) {
+			return
+		}
+
+		e, err := h.source.TaskEvent(ctx, signal.Id)
+		if err != nil {
+			h.logger.LogFields(logrus.Fields{"msg": "failed to load streamed task event", "id": signal.Id, "err": err})
+			return
+		}
+		h.deliver(taskEvent(e), audience...)
+	}
+}
+
+// listening reports whether any of the users are connected, or anyone at all without users
+func (h *Hub) listening(uids ...uuid.UUID) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(uids) == 0 {
+		return len(h.subs) > 0
+	}
+
+	for _, uid := range uids {
+		if len(h.subs[uid]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver never blocks, a subscriber whose buffer is full is dropped and resumes when its
+// client reconnects
+func (h *Hub) deliver(e Event, uids ...uuid.UUID) {
+	var slow []*subscriber
+	sent := make(map[uuid.UUID]bool, len(uids))
+
+	h.mu.RLock()
+	for _, uid := range uids {
+		if sent[uid] {
+			continue
+		}
+		sent[uid] = true
+
+		for sub := range h.subs[uid] {
+			select {
+			case sub.events <- e:
+			default:
+				slow = append(slow, sub)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		h.logger.LogFields(logrus.Fields{"msg": "dropping slow stream subscriber", "uid": sub.uid})
+		h.unsubscribe(sub)
+	}
+}
+
+func (h *Hub) subscribe(uid uuid.UUID) (*subscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+
+	sub := &subscriber{uid: uid, events: make(chan Event, subscriberBuffer), dropped: make(chan struct{})}
+	if h.subs[uid] == nil {
+		h.subs[uid] = make(map[*subscriber]struct{})
+	}
+	h.subs[uid][sub] = struct{}{}
+	return sub, nil
+}
+
+func (h *Hub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub.uid][sub]; !ok {
+		return
+	}
+
+	close(sub.dropped)
+	delete(h.subs[sub.uid], sub)
+	if len(h.subs[sub.uid]) == 0 {
+		delete(h.subs, sub.uid)
+	}
+}
+
+func (h *Hub) isClosed() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.closed
+}
+
+func notificationEvent(n model.Notification) Event {
+	return Event{Type: NotificationEvent, Seq: int64(n.Id), At: n.CreatedAt, Data: dtos.GenNotificationDto(n)}
+}
+
+func taskEvent(e model.TaskEvent) Event {
+	return Event{Type: TaskStatusEvent, Seq: e.Id, At: e.CreatedAt, Data: dtos.NewTaskEventResponse(e)}
+}
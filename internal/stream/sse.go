@@ -0,0 +1,68 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// retryMillis is how long a browser waits before reconnecting a dropped stream
+const retryMillis = 3000
+
+type sseWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+// ServeSSE streams the user's events as server sent events, browsers send Last-Event-ID
+// on their own when they reconnect
+func ServeSSE(w http.ResponseWriter, r *http.Request, hub *Hub, uid uuid.UUID) error {
+	rc := http.NewResponseController(w)
+	// the server's write timeout would otherwise cut every stream off
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// stops nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", retryMillis); err != nil {
+		return err
+	}
+
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+
+	return hub.Serve(r.Context(), uid, lastEventId, sseWriter{w: w, rc: rc})
+}
+
+func (s sseWriter) Send(id Cursor, e Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintf(s.w, "id: %v\nevent: %v\ndata: %s\n\n", id, e.Type, data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// Heartbeat is a comment line, it keeps proxies from closing an idle stream
+func (s sseWriter) Heartbeat() error {
+	if _, err := io.WriteString(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
@@ -0,0 +1,249 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/crane"
+	"github.com/Z3DRP/lessor-service/internal/dac"
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+type source struct {
+	notifications []model.Notification
+	events        []model.TaskEvent
+	crew          []uuid.UUID
+	// rows created after this arrive live instead of being replayed
+	replayedTo time.Time
+}
+
+func (s source) Notification(ctx context.Context, id int64) (model.Notification, error) {
+	for _, n := range s.notifications {
+		if int64(n.Id) == id {
+			return n, nil
+		}
+	}
+	return model.Notification{}, dac.ErrNoResults{Identifier: "notification"}
+}
+
+func (s source) TaskEvent(ctx context.Context, id int64) (model.TaskEvent, error) {
+	for _, e := range s.events {
+		if e.Id == id {
+			return e, nil
+		}
+	}
+	return model.TaskEvent{}, dac.ErrNoResults{Identifier: "task event"}
+}
+
+func (s source) Crew(ctx context.Context, taskId uuid.UUID) ([]uuid.UUID, error) {
+	return s.crew, nil
+}
+
+func (s source) Latest(ctx context.Context) (int64, int64, error) {
+	return 10, 10, nil
+}
+
+func (s source) NotificationsSince(ctx context.Context, uid uuid.UUID, after int64, limit int) ([]model.Notification, error) {
+	var notis []model.Notification
+	for _, n := range s.notifications {
+		if int64(n.Id) > after && n.Recipient() == uid && !n.CreatedAt.After(s.replayedTo) {
+			notis = append(notis, n)
+		}
+	}
+	return notis, nil
+}
+
+func (s source) TaskEventsSince(ctx context.Context, uid uuid.UUID, after int64, limit int) ([]model.TaskEvent, error) {
+	var events []model.TaskEvent
+	for _, e := range s.events {
+		if e.Id > after && !e.CreatedAt.After(s.replayedTo) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+type listener struct {
+	signals chan pgdriver.Notification
+}
+
+func (l listener) Listen(ctx context.Context, channels ...string) error {
+	return nil
+}
+
+func (l listener) Channel(opts ...pgdriver.ChannelOption) <-chan pgdriver.Notification {
+	return l.signals
+}
+
+func (l listener) Close() error {
+	close(l.signals)
+	return nil
+}
+
+func (l listener) signal(t *testing.T, signal dac.StreamSignal) {
+	payload, err := json.Marshal(signal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.signals <- pgdriver.Notification{Channel: dac.StreamChannel, Payload: string(payload)}
+}
+
+type sent struct {
+	id  string
+	typ EventType
+}
+
+type writer chan sent
+
+func (w writer) Send(id Cursor, e Event) error {
+	w <- sent{id: id.String(), typ: e.Type}
+	return nil
+}
+
+func (w writer) Heartbeat() error {
+	return nil
+}
+
+func (w writer) expect(t *testing.T, want ...sent) {
+	t.Helper()
+	for _, exp := range want {
+		select {
+		case got := <-w:
+			if got != exp {
+				t.Fatalf("expected %+v got %+v", exp, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %+v", exp)
+		}
+	}
+}
+
+func testLogger(t *testing.T) *crane.Zlogrus {
+	return crane.NewLogger(crane.NewLogFile(crane.WithFilename(filepath.Join(t.TempDir(), "stream.log"))))
+}
+
+func TestCursor(t *testing.T) {
+	c, err := ParseCursor("12-40")
+	if err != nil || c != (Cursor{Notification: 12, Task: 40}) || c.String() != "12-40" {
+		t.Errorf("expected 12-40, got %v %v", c, err)
+	}
+
+	for _, id := range []string{"", "12", "a-1", "1-b"} {
+		if _, err := ParseCursor(id); err == nil {
+			t.Errorf("expected %q to be rejected", id)
+		}
+	}
+}
+
+func TestHubServe(t *testing.T) {
+	user, lessor, other := uuid.New(), uuid.New(), uuid.New()
+	start := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	src := source{
+		notifications: []model.Notification{
+			{Id: 2, UserId: user, LessorId: lessor, CreatedAt: start},
+			{Id: 4, UserId: user, LessorId: lessor, CreatedAt: start.Add(3 * time.Minute)},
+			{Id: 5, UserId: other, LessorId: lessor, CreatedAt: start.Add(4 * time.Minute)},
+		},
+		events: []model.TaskEvent{
+			{Id: 3, LessorId: lessor, EventType: model.TaskStarted, CreatedAt: start.Add(time.Minute)},
+			{Id: 6, LessorId: lessor, EventType: model.TaskCompleted, CreatedAt: start.Add(5 * time.Minute)},
+		},
+		crew:       []uuid.UUID{user},
+		replayedTo: start.Add(time.Minute),
+	}
+
+	ln := listener{signals: make(chan pgdriver.Notification, 8)}
+	hub := NewHub(src, ln, testLogger(t))
+	hub.Start(context.Background())
+
+	w := make(writer, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- hub.Serve(ctx, user, "1-1", w) }()
+
+	w.expect(t, sent{"2-1", NotificationEvent}, sent{"2-3", TaskStatusEvent}, sent{"2-3", ReadyEvent})
+
+	// the first notification was already replayed and the other one isn't theirs
+	ln.signal(t, dac.StreamSignal{Kind: dac.StreamNotification, Id: 2, UserId: user})
+	ln.signal(t, dac.StreamSignal{Kind: dac.StreamNotification, Id: 5, UserId: other})
+	ln.signal(t, dac.StreamSignal{Kind: dac.StreamNotification, Id: 4, UserId: user})
+	ln.signal(t, dac.StreamSignal{Kind: dac.StreamTask, Id: 6, LessorId: lessor})
+	w.expect(t, sent{"4-3", NotificationEvent}, sent{"4-6", TaskStatusEvent})
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hub.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hub.Serve(context.Background(), user, "", w); err != ErrHubClosed {
+		t.Errorf("expected a stopped hub to refuse subscribers, got %v", err)
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	hub := NewHub(source{}, listener{signals: make(chan pgdriver.Notification)}, testLogger(t))
+	uid := uuid.New()
+	sub, err := hub.subscribe(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range subscriberBuffer + 1 {
+		hub.deliver(Event{Type: NotificationEvent, Seq: int64(i)}, uid)
+	}
+
+	select {
+	case <-sub.dropped:
+	default:
+		t.Errorf("expected the subscriber to be dropped once its buffer filled")
+	}
+
+	if hub.listening(uid) {
+		t.Errorf("expected a dropped subscriber to be removed")
+	}
+}
+
+func TestWebSocketFrames(t *testing.T) {
+	// the example handshake from RFC 6455
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected accept key %v", got)
+	}
+
+	mask := [4]byte{1, 2, 3, 4}
+	for _, size := range []int{5, 300} {
+		payload := bytes.Repeat([]byte("x"), size)
+		header := frameHeader(opPing, size)
+		header[1] |= 0x80
+
+		frame := bytes.NewBuffer(header)
+		frame.Write(mask[:])
+		for i, b := range payload {
+			frame.WriteByte(b ^ mask[i%4])
+		}
+
+		op, got, err := readFrame(frame)
+		if err != nil || op != opPing || !bytes.Equal(got, payload) {
+			t.Errorf("%v byte frame: expected a ping with the payload, got %v %q %v", size, op, got, err)
+		}
+	}
+
+	if _, _, err := readFrame(bytes.NewReader(frameHeader(opText, 3))); err != ErrUnmaskedFrame {
+		t.Errorf("expected an unmasked frame to be refused, got %v", err)
+	}
+
+	big := binary.BigEndian.AppendUint16([]byte{0x80 | opText, 0x80 | 126}, maxClientFrame+1)
+	if _, _, err := readFrame(bytes.NewReader(big)); err != ErrFrameTooLarge {
+		t.Errorf("expected a large frame to be refused, got %v", err)
+	}
+}
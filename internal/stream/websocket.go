@@ -0,0 +1,262 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// the stream only pushes so this is the small part of RFC 6455 a server needs to send text
+// frames and answer the control frames a client sends back
+const (
+	wsGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+
+	// clients have nothing to send but control frames so anything larger is refused
+	maxClientFrame = 4096
+	wsWriteWait    = 10 * time.Second
+
+	closeNormal    = 1000
+	closeGoingAway = 1001
+)
+
+var (
+	ErrNotWebSocket  = errors.New("request is not a websocket upgrade")
+	ErrFrameTooLarge = errors.New("websocket frame is too large")
+	ErrUnmaskedFrame = errors.New("websocket client frames must be masked")
+)
+
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex
+}
+
+type wsMessage struct {
+	Id   string    `json:"id"`
+	Type EventType `json:"type"`
+	Data any       `json:"data"`
+}
+
+// IsWebSocket reports whether the request asks to be upgraded
+func IsWebSocket(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// ServeWebSocket streams the same events as ServeSSE over a websocket, browsers can't set
+// headers on one so the last event id comes from the lastEventId query parameter
+func ServeWebSocket(w http.ResponseWriter, r *http.Request, hub *Hub, uid uuid.UUID) error {
+	c, err := upgrade(w, r)
+	if err != nil {
+		return err
+	}
+	defer c.conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	go func() {
+		defer cancel()
+		c.readLoop(2 * hub.heartbeat)
+	}()
+
+	err = hub.Serve(ctx, uid, r.URL.Query().Get("lastEventId"), c)
+
+	code := closeNormal
+	if err != nil {
+		code = closeGoingAway
+	}
+	_ = c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, uint16(code)))
+	return err
+}
+
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !IsWebSocket(r) || key == "" {
+		utils.WriteErr(w, http.StatusBadRequest, ErrNotWebSocket)
+		return nil, ErrNotWebSocket
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		err := fmt.Errorf("unsupported websocket version %q", r.Header.Get("Sec-WebSocket-Version"))
+		utils.WriteErr(w, http.StatusUpgradeRequired, err)
+		return nil, err
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		utils.WriteErr(w, http.StatusInternalServerError, err)
+		return nil, err
+	}
+
+	// a hijacked connection keeps the server's deadlines, the read loop and writes set their own
+	if err = conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &wsConn{conn: conn, rw: rw}
+	_, err = fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err == nil {
+		err = rw.Flush()
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *wsConn) Send(id Cursor, e Event) error {
+	msg, err := json.Marshal(wsMessage{Id: id.String(), Type: e.Type, Data: e.Data})
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(opText, msg)
+}
+
+func (c *wsConn) Heartbeat() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// readLoop answers pings and closes, a client that goes quiet for longer than the timeout
+// stopped answering our pings and is treated as gone
+func (c *wsConn) readLoop(timeout time.Duration) {
+	for {
+		if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return
+		}
+
+		op, payload, err := readFrame(c.rw.Reader)
+		if err != nil {
+			return
+		}
+
+		switch op {
+		case opPing:
+			if err = c.writeFrame(opPong, payload); err != nil {
+				return
+			}
+		case opClose:
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			_ = c.writeFrame(opClose, payload)
+			return
+		}
+	}
+}
+
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait)); err != nil {
+		return err
+	}
+
+	if _, err := c.rw.Write(frameHeader(op, len(payload))); err != nil {
+		return err
+	}
+
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// frameHeader is a final unmasked frame header, servers never mask what they send
+func frameHeader(op byte, n int) []byte {
+	header := []byte{0x80 | op}
+	switch {
+	case n < 126:
+		return append(header, byte(n))
+	case n <= 0xFFFF:
+		return binary.BigEndian.AppendUint16(append(header, 126), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(header, 127), uint64(n))
+	}
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+
+	op := head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return 0, nil, ErrUnmaskedFrame
+	}
+
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if n > maxClientFrame {
+		return 0, nil, ErrFrameTooLarge
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + wsGuid))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerContains(h http.Header, name string, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+const (
+	SignatureHeader = "X-Lessor-Signature"
+	EventHeader     = "X-Lessor-Event"
+	DeliveryHeader  = "X-Lessor-Delivery"
+
+	// MaxAttempts are spread over about two hours before a delivery gives up
+	MaxAttempts = 8
+	// DisableAfter is how many deliveries in a row can give up before the subscription is disabled
+	DisableAfter = 5
+
+	baseBackoff = time.Minute
+	maxBackoff  = 2 * time.Hour
+	sendTimeout = 10 * time.Second
+	// only the start of a response is kept in the delivery log
+	maxResponse = 512
+	secretBytes = 32
+)
+
+var ErrInvalidSignature = errors.New("webhook signature does not match")
+
+// ErrBlockedAddress keeps webhooks off our own network, the lessor can read the start of every
+// response so posting to an internal address would let them probe it
+var ErrBlockedAddress = errors.New("webhook address is not publicly routable")
+
+// Outcome is what a delivery attempt means for the subscription
+type Outcome int
+
+const (
+	Retry Outcome = iota
+	Delivered
+	GaveUp
+)
+
+// Envelope is the body every webhook is posted with, Id stays the same across retries and
+// redeliveries so receivers can drop duplicates
+type Envelope struct {
+	Id        uuid.UUID          `json:"id"`
+	Event     model.WebhookEvent `json:"event"`
+	LessorId  uuid.UUID          `json:"lessorId"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Data      any                `json:"data"`
+}
+
+func NewPayload(event model.WebhookEvent, lessorId uuid.UUID, data any) (json.RawMessage, error) {
+	return json.Marshal(Envelope{Id: uuid.New(), Event: event, LessorId: lessorId, CreatedAt: time.Now(), Data: data})
+}
+
+func NewSecret() (string, error) {
+	secret := make([]byte, secretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// Sign is the signature header value, an hmac-sha256 of the timestamp and body so a
+// captured request can't be replayed later with a new timestamp
+func Sign(secret string, at time.Time, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", at.Unix(), hex.EncodeToString(mac(secret, at.Unix(), body)))
+}
+
+// Verify checks a signature header the way a receiver should, it is used to test endpoints
+func Verify(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts int64
+	var sig []byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			ts, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			sig, _ = hex.DecodeString(value)
+		}
+	}
+
+	if ts == 0 || sig == nil || now.Sub(time.Unix(ts, 0)).Abs() > tolerance {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal(sig, mac(secret, ts, body)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mac(secret string, ts int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "%d.", ts)
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Backoff doubles the wait after each failed attempt up to maxBackoff
+func Backoff(attempt int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
+
+// internalPrefixes aren't covered by netip's checks but reach internal services on many cloud
+// networks, carrier grade NAT and the NAT64 prefix that embeds an IPv4 address
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// Blocked is whether webhooks are never posted to the address, that is private, loopback, link
+// local (which includes cloud metadata at 169.254.169.254), multicast, unspecified and the
+// internalPrefixes addresses
+func Blocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+
+	for _, prefix := range internalPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+type Sender struct {
+	client *http.Client
+}
+
+func NewSender() *Sender {
+	return newSender(Blocked)
+}
+
+// newSender checks every address it connects to after the host is resolved so a hostname
+// can't be pointed at an internal address once the url has been validated
+func newSender(blocked func(netip.Addr) bool) *Sender {
+	dialer := &net.Dialer{
+		Timeout: sendTimeout,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if blocked(addrPort.Addr()) {
+				return fmt.Errorf("%w: %v", ErrBlockedAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed in place of the lessor's host and skip the address check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Sender{client: &http.Client{
+		Timeout:   sendTimeout,
+		Transport: transport,
+		// a redirect is reported as it is rather than followed somewhere the lessor didn't register
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Send posts the delivery once, a failure is recorded in the attempt rather than returned
+func (s *Sender) Send(ctx context.Context, sub model.WebhookSubscription, d model.WebhookDelivery) model.WebhookAttempt {
+	start := time.Now()
+	attempt := model.WebhookAttempt{At: start}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Lessor-Webhooks/1.0")
+	req.Header.Set(EventHeader, string(d.Event))
+	req.Header.Set(DeliveryHeader, d.Did.String())
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, start, d.Payload))
+
+	res, err := s.client.Do(req)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxResponse))
+	attempt.StatusCode = res.StatusCode
+	// jsonb refuses nul characters so they are dropped along with anything that isn't utf-8
+	attempt.Response = strings.ReplaceAll(strings.ToValidUTF8(string(body), ""), "\x00", "")
+	return attempt
+}
+
+// Settle records the attempt on the delivery and schedules the next one when it failed
+func Settle(d *model.WebhookDelivery, attempt model.WebhookAttempt) Outcome {
+	d.Attempts = append(d.Attempts, attempt)
+	d.StatusCode = attempt.StatusCode
+
+	switch {
+	case attempt.StatusCode >= 200 && attempt.StatusCode < 300:
+		d.Status = model.DeliverySucceeded
+		d.DeliveredAt = attempt.At
+		d.NextAttemptAt = time.Time{}
+		return Delivered
+	case len(d.Attempts) >= MaxAttempts:
+		d.Status = model.DeliveryFailed
+		d.NextAttemptAt = time.Time{}
+		return GaveUp
+	default:
+		d.Status = model.DeliveryPending
+		d.NextAttemptAt = attempt.At.Add(Backoff(len(d.Attempts)))
+		return Retry
+	}
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z3DRP/lessor-service/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestSignature(t *testing.T) {
+	at := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	body := []byte(`{"event":"task.completed"}`)
+	header := Sign("whsec_test", at, body)
+
+	if err := Verify("whsec_test", header, body, 5*time.Minute, at.Add(time.Minute)); err != nil {
+		t.Errorf("expected the signature to verify, got %v", err)
+	}
+
+	for name, check := range map[string]func() error{
+		"wrong secret":  func() error { return Verify("whsec_other", header, body, 5*time.Minute, at) },
+		"changed body":  func() error { return Verify("whsec_test", header, []byte(`{}`), 5*time.Minute, at) },
+		"too old":       func() error { return Verify("whsec_test", header, body, 5*time.Minute, at.Add(time.Hour)) },
+		"missing parts": func() error { return Verify("whsec_test", "v1=abc", body, 5*time.Minute, at) },
+	} {
+		if check() != ErrInvalidSignature {
+			t.Errorf("%v: expected the signature to be refused", name)
+		}
+	}
+}
+
+func TestSettle(t *testing.T) {
+	at := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	d := &model.WebhookDelivery{Status: model.DeliveryPending}
+
+	for i := 1; i < MaxAttempts; i++ {
+		if outcome := Settle(d, model.WebhookAttempt{At: at, StatusCode: http.StatusBadGateway}); outcome != Retry {
+			t.Fatalf("attempt %v: expected a retry got %v", i, outcome)
+		}
+
+		if want := at.Add(Backoff(i)); !d.NextAttemptAt.Equal(want) {
+			t.Errorf("attempt %v: expected the next attempt at %v got %v", i, want, d.NextAttemptAt)
+		}
+	}
+
+	if outcome := Settle(d, model.WebhookAttempt{At: at, Error: "connection refused"}); outcome != GaveUp || d.Status != model.DeliveryFailed {
+		t.Errorf("expected the last attempt to give up, got %v %v", outcome, d.Status)
+	}
+
+	ok := &model.WebhookDelivery{Status: model.DeliveryPending}
+	if outcome := Settle(ok, model.WebhookAttempt{At: at, StatusCode: http.StatusNoContent}); outcome != Delivered || !ok.DeliveredAt.Equal(at) {
+		t.Errorf("expected a 204 to be delivered, got %v %+v", outcome, ok)
+	}
+
+	if Backoff(1) != baseBackoff || Backoff(20) != maxBackoff {
+		t.Errorf("unexpected backoff %v %v", Backoff(1), Backoff(20))
+	}
+}
+
+func TestSend(t *testing.T) {
+	sub := model.WebhookSubscription{Secret: "whsec_test"}
+	d := model.WebhookDelivery{Did: uuid.New(), Event: model.TaskCompletedHook, Payload: []byte(`{"id":"1"}`)}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(sub.Secret, r.Header.Get(SignatureHeader), body, time.Minute, time.Now()); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Header.Get(EventHeader) != string(d.Event) || r.Header.Get(DeliveryHeader) != d.Did.String() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	// the test server is on loopback so only this sender is allowed to reach it
+	local := newSender(func(netip.Addr) bool { return false })
+
+	sub.Url = server.URL
+	if attempt := local.Send(context.Background(), sub, d); attempt.StatusCode != http.StatusOK || attempt.Response != "ok" {
+		t.Errorf("expected a signed delivery to be accepted, got %+v", attempt)
+	}
+
+	sub.Url = server.URL + "/moved"
+	if attempt := local.Send(context.Background(), sub, d); attempt.StatusCode != http.StatusFound {
+		t.Errorf("expected the redirect to be reported rather than followed, got %+v", attempt)
+	}
+
+	sub.Url = "http://127.0.0.1:1"
+	if attempt := local.Send(context.Background(), sub, d); attempt.StatusCode != 0 || attempt.Error == "" {
+		t.Errorf("expected a refused connection to be recorded, got %+v", attempt)
+	}
+
+	for _, url := range []string{server.URL, "http://localhost" + strings.TrimPrefix(server.URL, "http://127.0.0.1"), "http://169.254.169.254/latest/meta-data"} {
+		sub.Url = url
+		if attempt := NewSender().Send(context.Background(), sub, d); attempt.StatusCode != 0 || !strings.Contains(attempt.Error, ErrBlockedAddress.Error()) {
+			t.Errorf("expected %v to be blocked, got %+v", url, attempt)
+		}
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	for _, test := range []struct {
+		addr    string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"224.0.0.1", true},
+		{"100.64.0.1", true},
+		{"100.127.255.254", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"64:ff9b::808:808", true},
+		{"100.128.0.1", false},
+		{"8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+	} {
+		if got := Blocked(netip.MustParseAddr(test.addr)); got != test.blocked {
+			t.Errorf("expected %v blocked to be %v, got %v", test.addr, test.blocked, got)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
@@ -37,6 +38,23 @@ type Location struct {
 	Longitude float64
 }
 
+// Known is false for the zero location, addresses that were never geocoded have no lat lng
+func (l Location) Known() bool {
+	return l.Latitude != 0 || l.Longitude != 0
+}
+
+const earthRadiusKm = 6371.0
+
+// Distance is the straight line (great circle) distance between a and b in kilometers
+func Distance(a Location, b Location) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
 type GeoCoder interface {
 	GeoCode(addr GAddress) Location
 }
@@ -122,6 +122,19 @@ func ParseFile(r *http.Request) (multipart.File, *multipart.FileHeader, error) {
 	return file, header, nil
 }
 
+// ParseFiles returns every file sent under field, callers open and close each header
+func ParseFiles(r *http.Request, field string) ([]*multipart.FileHeader, error) {
+	if err := r.ParseMultipartForm(maxSize); err != nil {
+		return nil, api.ErrMaxSize{Err: err}
+	}
+
+	if r.MultipartForm == nil {
+		return nil, nil
+	}
+
+	return r.MultipartForm.File[field], nil
+}
+
 func ParseFloatOrZero(s string) (float64, error) {
 	if s == "" {
 		return 0, nil